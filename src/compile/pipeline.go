@@ -1,7 +1,9 @@
 package compile
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"strings"
 
 	"zenith/compiler"
@@ -16,7 +18,9 @@ type CompilationResult struct {
 	// Intermediate representations
 	Tokens lexer.TokenStream
 	AST    parser.ParserNode
-	SemCU  *zsm.SemCompilationUnit
+	// Units are the compilation units of all source files (in order)
+	Units []parser.CompilationUnit
+	SemCU *zsm.SemCompilationUnit
 
 	// Per-function CFG and analysis results
 	FunctionCFGs     map[string]*cfg.CFG
@@ -26,6 +30,12 @@ type CompilationResult struct {
 
 	// Machine code
 	Instructions map[string][]cfg.MachineInstruction
+	// Constant data (string literals), emitted after the code
+	DataSection *cfg.DataSection
+	// Function placement ordered by address ('@org' functions at their fixed address)
+	Layout []*cfg.FunctionLayout
+	// Absolute label references a loader fixes up (position-independent code only)
+	Relocations []cfg.Relocation
 
 	// Error tracking
 	Diagnostics    []*compiler.Diagnostic
@@ -45,9 +55,31 @@ type CompilationResult struct {
 type PipelineOptions struct {
 	// for now...
 	Source string
+	// Sources are the files of a multi-file program (used when Source is empty)
+	Sources []SourceFile
 
 	// Target architecture
 	TargetArch string // "z80", etc.
+	// Allow undocumented target instructions (off for strict targets)
+	AllowUndocumented bool
+	// Call '__overflow_trap' when an addition or subtraction overflows (debug builds)
+	CheckedArithmetic bool
+	// How string literals are stored (null-terminated by default)
+	StringFormat zsm.StringFormat
+	// Start address of the code (functions without '@org'), the ROM start when zero
+	Origin uint16
+	// Target memory the program must fit in (not checked when the sizes are zero)
+	Memory cfg.MemoryMap
+	// Root functions of the program: functions they do not (transitively) call are not compiled.
+	// Empty keeps all functions.
+	EntryPoints []string
+	// Validate the program entry point (the first EntryPoints function): on by default, off for library builds
+	RequireEntryPoint bool
+	// Reads the files embedded with '@include_bin' (from disk by default)
+	FileResolver zsm.FileResolver
+	// Position-independent code: the absolute label references are listed in the relocations
+	// for a loader that places the code at an address known at runtime
+	PositionIndependent bool
 
 	// Pipeline control flags
 	StopAfterLex                  bool
@@ -66,8 +98,11 @@ type PipelineOptions struct {
 // DefaultPipelineOptions returns default pipeline options
 func DefaultPipelineOptions() *PipelineOptions {
 	return &PipelineOptions{
-		TargetArch: "z80",
-		Verbose:    false,
+		TargetArch:        "z80",
+		EntryPoints:       []string{"main"},
+		RequireEntryPoint: true,
+		FileResolver:      os.ReadFile,
+		Verbose:           false,
 	}
 }
 
@@ -92,6 +127,8 @@ func Pipeline(opts *PipelineOptions) (*CompilationResult, error) {
 
 	if opts.Source != "" {
 		tokenizer = lexer.TokenizerFromReader(strings.NewReader(opts.Source))
+	} else if len(opts.Sources) > 0 {
+		return pipelineSources(opts, result)
 	} else {
 		return result, fmt.Errorf("no source provided")
 	}
@@ -132,11 +169,47 @@ func Pipeline(opts *PipelineOptions) (*CompilationResult, error) {
 		return result, fmt.Errorf("parser did not return CompilationUnit")
 	}
 
+	result.Units = []parser.CompilationUnit{compilationUnit}
+
 	if opts.StopAfterParse {
 		result.Success = true
 		return result, nil
 	}
+	return pipelineUnits(opts, result)
+}
+
+// pipelineSources parses the source files of a multi-file program and compiles them together
+func pipelineSources(opts *PipelineOptions, result *CompilationResult) (*CompilationResult, error) {
+	if opts.Verbose {
+		fmt.Printf("==> Stage 1+2: Lexical and Syntax Analysis of %d files\n", len(opts.Sources))
+	}
+
+	units, parserErrors := ParseSources(opts.Sources)
+	result.Units = units
+	if len(units) > 0 {
+		result.AST = units[0]
+	}
+	result.Diagnostics = append(result.Diagnostics, parserErrors...)
+
+	if len(parserErrors) > 0 {
+		if opts.Verbose {
+			fmt.Printf("Parser found %d errors\n", len(parserErrors))
+			for _, err := range parserErrors {
+				fmt.Printf("  %s\n", err.Error())
+			}
+		}
+		return result, fmt.Errorf("parsing failed with %d errors", len(parserErrors))
+	}
+
+	if opts.StopAfterLex || opts.StopAfterParse {
+		result.Success = true
+		return result, nil
+	}
+	return pipelineUnits(opts, result)
+}
 
+// pipelineUnits runs the stages after parsing on the compilation units in the result
+func pipelineUnits(opts *PipelineOptions, result *CompilationResult) (*CompilationResult, error) {
 	// ==========================================================================
 	// Stage 3: Semantic Analysis & IR Generation
 	// ==========================================================================
@@ -144,10 +217,28 @@ func Pipeline(opts *PipelineOptions) (*CompilationResult, error) {
 		fmt.Println("==> Stage 3: Semantic Analysis & IR Generation")
 	}
 
-	analyzer := zsm.NewSemanticAnalyzer()
-	semCompilationUnit, semanticErrors := analyzer.Analyze(compilationUnit)
+	analyzerOpts := zsm.SemanticAnalyzerOptions{
+		StringFormat: opts.StringFormat,
+		FileResolver: opts.FileResolver,
+	}
+	if opts.RequireEntryPoint {
+		analyzerOpts.EntryPoint = zsm.DefaultEntryPoint
+		if len(opts.EntryPoints) > 0 {
+			analyzerOpts.EntryPoint = opts.EntryPoints[0]
+		}
+	}
+	analyzer := zsm.NewSemanticAnalyzerWithOptions(analyzerOpts)
+	semCompilationUnit, semanticErrors := analyzer.AnalyzeUnits(result.Units)
 	result.SemCU = semCompilationUnit
 	result.SemanticErrors = semanticErrors
+	if semCompilationUnit != nil {
+		result.Diagnostics = append(result.Diagnostics, semCompilationUnit.Warnings...)
+		if opts.Verbose {
+			for _, warning := range semCompilationUnit.Warnings {
+				fmt.Printf("  warning: %s\n", warning.Error())
+			}
+		}
+	}
 
 	if len(semanticErrors) > 0 {
 		if opts.Verbose {
@@ -159,6 +250,12 @@ func Pipeline(opts *PipelineOptions) (*CompilationResult, error) {
 		return result, fmt.Errorf("semantic analysis failed with %d errors", len(semanticErrors))
 	}
 
+	// Drop the functions that are never called
+	removed := zsm.EliminateDeadFunctions(semCompilationUnit, opts.EntryPoints)
+	if opts.Verbose && len(removed) > 0 {
+		fmt.Printf("  Removed %d unreachable functions: %s\n", len(removed), strings.Join(removed, ", "))
+	}
+
 	if opts.StopAfterSemantic {
 		result.Success = true
 		return result, nil
@@ -171,10 +268,15 @@ func Pipeline(opts *PipelineOptions) (*CompilationResult, error) {
 		fmt.Println("==> Stage 4: Control Flow Graph Construction")
 	}
 
-	cfgBuilder := cfg.NewCFGBuilder()
 	for _, decl := range semCompilationUnit.Declarations {
 		if fnDecl, ok := decl.(*zsm.SemFunctionDecl); ok {
-			functionCFG := cfgBuilder.BuildCFG(fnDecl)
+			// a new builder per function, the builder collects the blocks of one function
+			functionCFG, err := cfg.NewCFGBuilder().BuildCFG(fnDecl)
+			if err != nil {
+				result.CodeGenErrors = append(result.CodeGenErrors, err)
+				return result, fmt.Errorf("control flow graph construction failed: %w", err)
+			}
+			functionCFG.SplitCriticalEdges()
 			result.FunctionCFGs[fnDecl.Name] = functionCFG
 
 			if opts.Verbose {
@@ -199,24 +301,81 @@ func Pipeline(opts *PipelineOptions) (*CompilationResult, error) {
 	vrAlloc := cfg.NewVirtualRegisterAllocator()
 	result.VRAllocator = vrAlloc
 
-	// Collect CFGs from result.FunctionCFGs map into a slice
+	// Collect the CFGs in declaration order: the data labels are numbered in selection order
 	cfgs := make([]*cfg.CFG, 0, len(result.FunctionCFGs))
-	for _, funcCFG := range result.FunctionCFGs {
-		cfgs = append(cfgs, funcCFG)
+	for _, decl := range semCompilationUnit.Declarations {
+		if fnDecl, ok := decl.(*zsm.SemFunctionDecl); ok {
+			cfgs = append(cfgs, result.FunctionCFGs[fnDecl.Name])
+		}
 	}
 
 	// TODO: Allow different selectors based on target architecture
 	if opts.TargetArch != "z80" {
 		return result, fmt.Errorf("unsupported target architecture: %s", opts.TargetArch)
 	}
-	selector := cfg.NewInstructionSelectorZ80(vrAlloc)
+	selector := cfg.NewInstructionSelectorZ80WithOptions(vrAlloc, cfg.InstructionSelectorZ80Options{
+		AllowUndocumented: opts.AllowUndocumented,
+		CheckedArithmetic: opts.CheckedArithmetic,
+	})
 	result.SelectorForTarget = selector
+	result.DataSection = cfg.NewDataSection()
+	result.DataSection.StringFormat = opts.StringFormat
+	// Global variables with a constant initializer are stored pre-initialized with the constant data,
+	// the code reads them in memory. The variables the code writes are copied to RAM at startup.
+	if err := result.DataSection.AddVariables(semCompilationUnit.Declarations); err != nil {
+		result.CodeGenErrors = append(result.CodeGenErrors, err)
+		return result, fmt.Errorf("data section failed: %w", err)
+	}
 	// Run instruction selection on the CFGs (modifies CFGs in-place, adds MachineInstructions)
-	err := cfg.SelectInstructions(cfgs, vrAlloc, selector)
+	err := cfg.SelectInstructionsWithData(cfgs, vrAlloc, selector, result.DataSection)
 	if err != nil {
 		result.CodeGenErrors = append(result.CodeGenErrors, err)
 		return result, fmt.Errorf("instruction selection failed: %w", err)
 	}
+	if written := result.DataSection.WrittenVariables(); len(written) > 0 {
+		var entryCFG *cfg.CFG
+		if len(opts.EntryPoints) > 0 {
+			entryCFG = result.FunctionCFGs[opts.EntryPoints[0]]
+		}
+		if entryCFG == nil {
+			err := fmt.Errorf("global variable '%s' is written but there is no entry point to initialize it", written[0].Label)
+			result.CodeGenErrors = append(result.CodeGenErrors, err)
+			return result, fmt.Errorf("instruction selection failed: %w", err)
+		}
+		if err := cfg.SelectGlobalsInit(entryCFG, result.DataSection, selector); err != nil {
+			result.CodeGenErrors = append(result.CodeGenErrors, err)
+			return result, fmt.Errorf("instruction selection failed: %w", err)
+		}
+	}
+
+	// Rewrite uses of constant VRs into immediate instruction forms, remove dead flag settings,
+	// dead instructions and copies, then reorder instructions to shorten live ranges
+	for fnName, funcCFG := range result.FunctionCFGs {
+		count := cfg.PropagateConstants(funcCFG)
+		if opts.Verbose && count > 0 {
+			fmt.Printf("  Propagated %d constants in function '%s'\n", count, fnName)
+		}
+
+		deadFlags := cfg.EliminateDeadFlags(funcCFG)
+		if opts.Verbose && deadFlags > 0 {
+			fmt.Printf("  Removed %d dead flag settings in function '%s'\n", deadFlags, fnName)
+		}
+
+		removed := cfg.EliminateDeadInstructions(funcCFG)
+		if opts.Verbose && removed > 0 {
+			fmt.Printf("  Removed %d dead instructions in function '%s'\n", removed, fnName)
+		}
+
+		coalesced := cfg.CoalesceCopies(funcCFG)
+		if opts.Verbose && coalesced > 0 {
+			fmt.Printf("  Coalesced %d register copies in function '%s'\n", coalesced, fnName)
+		}
+
+		scheduled := cfg.ScheduleInstructions(funcCFG)
+		if opts.Verbose && scheduled > 0 {
+			fmt.Printf("  Scheduled %d instructions in function '%s'\n", scheduled, fnName)
+		}
+	}
 
 	totalInstrs := make([]cfg.MachineInstruction, 0)
 	for _, funcCFG := range result.FunctionCFGs {
@@ -293,16 +452,23 @@ func Pipeline(opts *PipelineOptions) (*CompilationResult, error) {
 
 	// Create register allocator with target registers
 	allocator := cfg.NewRegisterAllocator(selector.GetTargetRegisters())
+	// '@fast' functions allocate from the alternate register set
+	shadowAllocator := cfg.NewRegisterAllocator(selector.GetCallingConvention().GetShadowRegisters())
 
 	for fnName, fnCFG := range result.FunctionCFGs {
 		interference := result.InterferenceInfo[fnName]
 
+		fnAllocator := allocator
+		if fnCFG.UsesShadowRegisters {
+			fnAllocator = shadowAllocator
+		}
+
 		// Run register allocation (assigns PhysicalReg to each VirtualRegister)
-		needsSecondPass := allocator.Allocate(fnCFG, interference)
+		needsSecondPass := fnAllocator.Allocate(fnCFG, interference)
 
 		// If there are unallocated VRs, run second pass to resolve them
 		if needsSecondPass {
-			err := allocator.ResolveUnallocated(fnCFG, interference, selector)
+			err := fnAllocator.ResolveUnallocated(fnCFG, interference, selector)
 			if err != nil {
 				result.CodeGenErrors = append(result.CodeGenErrors, err)
 				return result, fmt.Errorf("failed to resolve unallocated VRs for %s: %w", fnName, err)
@@ -332,18 +498,77 @@ func Pipeline(opts *PipelineOptions) (*CompilationResult, error) {
 	// ==========================================================================
 	// Stage 9: Code Generation (emit final instructions)
 	// ==========================================================================
-	// Extract instructions from each function's CFG with physical registers assigned
+	// Jumps within reach use the shorter relative form (JR), before the sizes are laid out
+	for _, funcCFG := range cfgs {
+		if relaxed := funcCFG.RelaxJumps(); opts.Verbose && relaxed > 0 {
+			fmt.Printf("  Relaxed %d jumps to JR in function '%s'\n", relaxed, funcCFG.FunctionName)
+		}
+	}
+	// Extract instructions from each function's CFG with physical registers assigned, in code order
 	allInstructions := []cfg.MachineInstruction{}
-	for _, funcCFG := range result.FunctionCFGs {
-		funcInstructions := funcCFG.GetAllInstructions()
+	for _, funcCFG := range cfgs {
+		funcInstructions := funcCFG.CodeInstructions()
 		result.Instructions[funcCFG.FunctionName] = funcInstructions
 		allInstructions = append(allInstructions, funcInstructions...)
 	}
 	result.Instructions["<all>"] = allInstructions
 
+	origin := opts.Origin
+	if origin == 0 {
+		origin = opts.Memory.ROMStart
+	}
+	// Lay out the functions in declaration order
+	layout, err := cfg.LayoutFunctions(cfgs, origin)
+	if err != nil {
+		result.CodeGenErrors = append(result.CodeGenErrors, err)
+		return result, fmt.Errorf("code layout failed: %w", err)
+	}
+	result.Layout = layout
+	if err := result.DataSection.ResolveAddresses(layout); err != nil {
+		result.CodeGenErrors = append(result.CodeGenErrors, err)
+		return result, fmt.Errorf("code layout failed: %w", err)
+	}
+
+	if opts.PositionIndependent {
+		// the addresses after inline assembly (and the data) are not known
+		for _, functionLayout := range layout {
+			if functionLayout.Unsized {
+				err := fmt.Errorf("function '%s' has inline assembly of unknown size: the code cannot be relocated", functionLayout.CFG.FunctionName)
+				result.CodeGenErrors = append(result.CodeGenErrors, err)
+				return result, fmt.Errorf("code layout failed: %w", err)
+			}
+		}
+		result.Relocations = cfg.Relocations(layout, result.DataSection)
+	}
+
+	// Check the program fits the target memory
+	memoryErrors := opts.Memory.Check(layout, result.DataSection.Size(), variablesSize(semCompilationUnit, result.DataSection))
+	if len(memoryErrors) > 0 {
+		result.CodeGenErrors = append(result.CodeGenErrors, memoryErrors...)
+		return result, fmt.Errorf("program does not fit the target memory: %w", errors.Join(memoryErrors...))
+	}
+
 	// ==========================================================================
 	// Pipeline Complete
 	// ==========================================================================
 	result.Success = true
 	return result, nil
 }
+
+// variablesSize returns the size in bytes of the global variables (array data included).
+// Pre-initialized variables are stored in the data section instead, the written ones have a copy in RAM.
+func variablesSize(semCU *zsm.SemCompilationUnit, data *cfg.DataSection) int {
+	size := data.VariablesSize()
+	for _, decl := range semCU.Declarations {
+		varDecl, ok := decl.(*zsm.SemVariableDecl)
+		if !ok || varDecl.TypeInfo == nil || data.Find(varDecl.Symbol.Name) != nil {
+			continue
+		}
+		if arrayType, ok := varDecl.TypeInfo.(*zsm.ArrayType); ok && arrayType.Length() > 0 {
+			size += int(arrayType.DataSize())
+		} else {
+			size += int(varDecl.TypeInfo.Size())
+		}
+	}
+	return size
+}
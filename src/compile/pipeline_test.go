@@ -2,14 +2,21 @@ package compile
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"zenith/compiler/cfg"
+	"zenith/compiler/zsm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func RunPipeline(t *testing.T, source string) *CompilationResult {
 	opts := DefaultPipelineOptions()
 	opts.Source = source
 	opts.TargetArch = "z80"
+	// the test programs are fragments: no entry point required
+	opts.RequireEntryPoint = false
 	//opts.Verbose = true
 
 	result, err := Pipeline(opts)
@@ -73,6 +80,7 @@ func Test_Pipeline_SimpleFunction(t *testing.T) {
 	`
 
 	opts := DefaultPipelineOptions()
+	opts.RequireEntryPoint = false
 	opts.Source = sourceCode
 	opts.TargetArch = "z80"
 
@@ -234,3 +242,789 @@ func Test_Pipeline_Struct(t *testing.T) {
 
 	RunPipeline(t, sourceCode)
 }
+
+func Test_Pipeline_FastFunctionUsesShadowRegisters(t *testing.T) {
+	sourceCode := `@fast
+	tick: () {
+		if 3 > 2 {
+			tick()
+		}
+	}`
+
+	result := RunPipeline(t, sourceCode)
+	require.True(t, result.Success)
+
+	fnCFG := result.FunctionCFGs["tick"]
+	require.NotNil(t, fnCFG)
+	assert.True(t, fnCFG.UsesShadowRegisters)
+
+	entry := fnCFG.Entry.MachineInstructions
+	require.GreaterOrEqual(t, len(entry), 2)
+	assert.True(t, strings.HasPrefix(entry[0].String(), "EXX"), entry[0].String())
+	assert.True(t, strings.HasPrefix(entry[1].String(), "EX "), entry[1].String())
+
+	allocated := 0
+	for _, instr := range fnCFG.GetAllInstructions() {
+		vrs := append([]*cfg.VirtualRegister{instr.GetResult()}, instr.GetOperands()...)
+		for _, vr := range vrs {
+			if vr == nil || vr.PhysicalReg == nil || vr.PhysicalReg == &cfg.RegSP {
+				continue
+			}
+			allocated++
+			assert.True(t, strings.HasSuffix(vr.PhysicalReg.Name, "'"),
+				"%s should be allocated from the shadow registers, got %s", vr.String(), vr.PhysicalReg.Name)
+		}
+	}
+	assert.Greater(t, allocated, 0)
+}
+
+func Test_Pipeline_InterruptHandler(t *testing.T) {
+	sourceCode := `@interrupt
+	onTimer: () {
+		if 3 > 2 {
+			onTimer()
+		}
+	}`
+
+	result := RunPipeline(t, sourceCode)
+	require.True(t, result.Success)
+
+	fnCFG := result.FunctionCFGs["onTimer"]
+	require.NotNil(t, fnCFG)
+	entry := fnCFG.Entry.MachineInstructions
+	require.NotEmpty(t, entry)
+	assert.True(t, strings.HasPrefix(entry[0].String(), "PUSH"), entry[0].String())
+	exit := fnCFG.Exit.MachineInstructions
+	require.NotEmpty(t, exit)
+	assert.Equal(t, "RETI ", exit[len(exit)-1].String())
+}
+
+func Test_Pipeline_StringLiteralData(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `main: () {
+		msg: = "hi"
+	}`
+	opts.StopAfterInstructionSelection = true
+
+	result, err := Pipeline(opts)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+
+	require.NotNil(t, result.DataSection)
+	var sb strings.Builder
+	require.NoError(t, result.DataSection.Emit(&sb))
+	assert.Equal(t, "data.0:\n    .db 0x68, 0x69, 0x00\n", sb.String())
+}
+
+func Test_Pipeline_StringLiteralData_DeclarationOrder(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `main: () {
+		first()
+		second()
+		msg: = "m"
+	}
+	first: () {
+		msg: = "a"
+	}
+	second: () {
+		msg: = "b"
+	}`
+	opts.StopAfterInstructionSelection = true
+
+	// the data labels are numbered in declaration order, every time
+	for range 10 {
+		result, err := Pipeline(opts)
+		require.NoError(t, err)
+		require.True(t, result.Success)
+
+		var sb strings.Builder
+		require.NoError(t, result.DataSection.Emit(&sb))
+		assert.Equal(t, "data.0:\n    .db 0x6D, 0x00\ndata.1:\n    .db 0x61, 0x00\ndata.2:\n    .db 0x62, 0x00\n", sb.String())
+	}
+}
+
+func Test_Pipeline_IncludeBinData(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `main: () {
+		tiles: = @include_bin("tiles.bin")
+		first: = tiles[0]
+	}`
+	opts.FileResolver = func(path string) ([]byte, error) {
+		if path != "tiles.bin" {
+			return nil, fmt.Errorf("file not found")
+		}
+		return []byte{0x00, 0x11, 0x22, 0x33, 0x44}, nil
+	}
+	opts.StopAfterInstructionSelection = true
+
+	result, err := Pipeline(opts)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+
+	// the file bytes are stored as they are: no terminator or length prefix
+	require.Len(t, result.DataSection.Items, 1)
+	assert.Equal(t, []byte{0x00, 0x11, 0x22, 0x33, 0x44}, result.DataSection.Items[0].Bytes)
+}
+
+func Test_Pipeline_DataBytes(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.RequireEntryPoint = false
+	opts.Source = `sine: = @db(0, 49, 90, 117, 127)
+main: () u8 {
+	ret sine[3]
+}`
+	opts.StopAfterInstructionSelection = true
+
+	result, err := Pipeline(opts)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+
+	// the bytes are stored as they are, labeled with the variable name
+	var sb strings.Builder
+	require.NoError(t, result.DataSection.Emit(&sb))
+	assert.Equal(t, "sine:\n    .db 0x00, 0x31, 0x5A, 0x75, 0x7F\n", sb.String())
+}
+
+func Test_Pipeline_StringLiteralData_LengthPrefixed(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `main: () {
+		msg: = "hi"
+	}`
+	opts.StringFormat = zsm.StringLengthPrefixed
+	opts.StopAfterInstructionSelection = true
+
+	result, err := Pipeline(opts)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+
+	require.Len(t, result.DataSection.Items, 1)
+	assert.Equal(t, []byte{0x02, 0x68, 0x69}, result.DataSection.Items[0].Bytes)
+}
+
+func Test_Pipeline_InlineAsm(t *testing.T) {
+	asmText := "\n\t\tdi\n\t\tld a, {0}\n\t\thalt\n\t"
+	sourceCode := "stop: () {\n\tasm {" + asmText + "}\n}"
+
+	result := RunPipeline(t, sourceCode)
+	require.True(t, result.Success)
+
+	var found bool
+	for _, instr := range result.FunctionCFGs["stop"].GetAllInstructions() {
+		if instr.String() == asmText {
+			found = true
+		}
+	}
+	assert.True(t, found, "inline asm text should be emitted verbatim")
+}
+
+func Test_Pipeline_OrgFunctionListing(t *testing.T) {
+	sourceCode := `main: () {
+	}
+	@org(0x0038) @interrupt
+	onTimer: () {
+	}`
+
+	result := RunPipeline(t, sourceCode)
+	require.True(t, result.Success)
+
+	var sb strings.Builder
+	require.NoError(t, cfg.WriteListing(&sb, result.Layout))
+	listing := sb.String()
+	assert.Contains(t, listing, "0000  main:\n")
+	assert.Contains(t, listing, "0038  onTimer:\n")
+	// the interrupt handler prologue is at the vector address
+	assert.Contains(t, listing, "0038  onTimer:\n0038      PUSH ")
+}
+
+func Test_Pipeline_MemoryMap_Origin(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `main: () {
+	}`
+	opts.Memory = cfg.MemoryMap{ROMStart: 0x4000, ROMSize: 1024}
+
+	result, err := Pipeline(opts)
+
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	var sb strings.Builder
+	require.NoError(t, cfg.WriteListing(&sb, result.Layout))
+	assert.Contains(t, sb.String(), "4000  main:\n")
+}
+
+func Test_Pipeline_MemoryMap_ROMOverBudget_Error(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `main: () {
+		helper()
+	}
+	helper: () {
+	}`
+	opts.Memory = cfg.MemoryMap{ROMStart: 0x0000, ROMSize: 2}
+
+	result, err := Pipeline(opts)
+
+	require.Error(t, err)
+	assert.False(t, result.Success)
+	require.NotEmpty(t, result.CodeGenErrors)
+	assert.Contains(t, result.CodeGenErrors[0].Error(), "ROM is 2 bytes")
+}
+
+func Test_Pipeline_MemoryMap_RAMOverBudget_Error(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `count: u16
+	total: u16
+	main: () {
+	}`
+	opts.Memory = cfg.MemoryMap{RAMStart: 0x8000, RAMSize: 3}
+
+	result, err := Pipeline(opts)
+
+	require.Error(t, err)
+	require.NotEmpty(t, result.CodeGenErrors)
+	assert.Equal(t, "variables are 4 bytes, RAM is 3 bytes", result.CodeGenErrors[0].Error())
+}
+
+func Test_Pipeline_RelaxedJumps(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.RequireEntryPoint = false
+	opts.Source = `max: (a: u8, b: u8) u8 {
+		if a > b {
+			ret a
+		} else {
+			ret b
+		}
+	}
+	main: () u8 {
+		ret max(1, 2)
+	}`
+
+	result, err := Pipeline(opts)
+
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	// all jumps of the small function are in reach of JR,
+	// the instructions of the result are the relaxed instructions in code order
+	assert.Equal(t, result.FunctionCFGs["max"].CodeInstructions(), result.Instructions["max"])
+	var jumps []string
+	for _, instr := range result.Instructions["max"] {
+		if text := instr.String(); strings.HasPrefix(text, "JP ") || strings.HasPrefix(text, "JR ") {
+			jumps = append(jumps, text[:2])
+		}
+	}
+	require.NotEmpty(t, jumps)
+	assert.NotContains(t, jumps, "JP")
+}
+
+func Test_Pipeline_PositionIndependent(t *testing.T) {
+	source := `counter: u8 = 0
+	main: () {
+		tick()
+		counter = 5
+	}
+	tick: () {
+	}`
+
+	tests := []struct {
+		name                string
+		positionIndependent bool
+		expected            []string
+	}{
+		{"absolute", false, nil},
+		{"position-independent", true, []string{"counter.init", "counter", "tick", "counter"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultPipelineOptions()
+			opts.Source = source
+			opts.Origin = 0x8000
+			opts.PositionIndependent = tt.positionIndependent
+
+			result, err := Pipeline(opts)
+
+			require.NoError(t, err)
+			require.True(t, result.Success)
+			var targets []string
+			for _, relocation := range result.Relocations {
+				targets = append(targets, relocation.Target)
+				assert.GreaterOrEqual(t, relocation.Address, uint16(0x8000))
+			}
+			assert.Equal(t, tt.expected, targets)
+		})
+	}
+}
+
+func Test_Pipeline_WrittenGlobal(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `g: u8 = 0
+	main: () {
+		g = 1
+	}`
+
+	result, err := Pipeline(opts)
+
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	item := result.DataSection.Find("g")
+	require.NotNil(t, item)
+	assert.True(t, item.Written)
+
+	// the initial value is copied to RAM at startup, the store writes the RAM copy
+	var listing []string
+	for _, instr := range result.Instructions["main"] {
+		listing = append(listing, strings.TrimSpace(instr.String()))
+	}
+	require.GreaterOrEqual(t, len(listing), 4)
+	assert.Contains(t, listing[0], "g.init")
+	assert.Contains(t, listing[1], "g")
+	assert.NotContains(t, listing[1], "g.init")
+	assert.Contains(t, listing[3], "LDIR")
+	var stores []string
+	for _, line := range listing[4:] {
+		if strings.Contains(line, "g") {
+			stores = append(stores, line)
+		}
+	}
+	require.NotEmpty(t, stores)
+	for _, line := range stores {
+		assert.NotContains(t, line, "g.init")
+	}
+
+	var data strings.Builder
+	require.NoError(t, result.DataSection.Emit(&data))
+	assert.Contains(t, data.String(), "g.init:\n")
+	var variables strings.Builder
+	require.NoError(t, result.DataSection.EmitVariables(&variables))
+	assert.Equal(t, "g:\n    .ds 1\n", variables.String())
+}
+
+func Test_Pipeline_WrittenGlobal_SetBit(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `flags: u8 = 0
+	main: () {
+		@setbit(3, flags)
+	}`
+
+	result, err := Pipeline(opts)
+
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	item := result.DataSection.Find("flags")
+	require.NotNil(t, item)
+	assert.True(t, item.Written)
+	var set bool
+	for _, instr := range result.Instructions["main"] {
+		set = set || strings.HasPrefix(strings.TrimSpace(instr.String()), "SET")
+	}
+	assert.True(t, set, "the bit is set in memory")
+}
+
+func Test_Pipeline_WrittenGlobal_NoEntryPoint_Error(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `g: u8 = 0
+	main: () {
+		g = 1
+	}`
+	opts.EntryPoints = nil
+	opts.RequireEntryPoint = false
+
+	_, err := Pipeline(opts)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "global variable 'g' is written")
+}
+
+func Test_Pipeline_AddressOfTable(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `table: u16[] = [@addressof(f), @addressof(g)]
+	main: () {
+	}
+	f: () {
+	}
+	g: () {
+	}`
+	opts.Origin = 0x8000
+
+	result, err := Pipeline(opts)
+
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	addresses := map[string]uint16{}
+	for _, layout := range result.Layout {
+		addresses[layout.CFG.FunctionName] = layout.Address
+	}
+	f, g := addresses["f"], addresses["g"]
+	require.NotZero(t, f)
+	require.NotZero(t, g)
+	table := result.DataSection.Find("table")
+	require.NotNil(t, table)
+	assert.Equal(t, []byte{byte(f), byte(f >> 8), byte(g), byte(g >> 8)}, table.Bytes)
+}
+
+func Test_Pipeline_AddressOfTable_PositionIndependent(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `table: u16[] = [@addressof(f), @addressof(g)]
+	main: () {
+	}
+	f: () {
+	}
+	g: () {
+	}`
+	opts.Origin = 0x8000
+	opts.PositionIndependent = true
+
+	result, err := Pipeline(opts)
+
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	// the table is the only data, placed after the code
+	end := 0
+	for _, layout := range result.Layout {
+		end = max(end, layout.End())
+	}
+	expected := []cfg.Relocation{
+		{Address: uint16(end), Target: "f"},
+		{Address: uint16(end + 2), Target: "g"},
+	}
+	assert.Equal(t, expected, result.Relocations)
+}
+
+func Test_Pipeline_GlobalConstantData(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `counter: u16 = 300
+	limit: u8 = 10 + 30
+	pending: u8
+	main: () {
+	}`
+
+	result, err := Pipeline(opts)
+
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	counter := result.DataSection.Find("counter")
+	require.NotNil(t, counter)
+	assert.Equal(t, []byte{0x2C, 0x01}, counter.Bytes)
+	limit := result.DataSection.Find("limit")
+	require.NotNil(t, limit)
+	assert.Equal(t, []byte{40}, limit.Bytes)
+	// no initial value: not in the data section
+	assert.Nil(t, result.DataSection.Find("pending"))
+}
+
+func Test_Pipeline_GlobalBitSet(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `flags: u8 = 0
+	main: () {
+		flags |= 0x04
+	}`
+
+	result, err := Pipeline(opts)
+
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	var set []cfg.MachineInstruction
+	for _, instr := range result.FunctionCFGs["main"].GetAllInstructions() {
+		if strings.HasPrefix(instr.String(), "SET ") {
+			set = append(set, instr)
+		}
+	}
+	// SET 2, (HL) with HL the address of flags
+	require.Len(t, set, 1)
+	operands := set[0].GetOperands()
+	require.Len(t, operands, 2)
+	assert.Equal(t, int32(2), operands[0].Value)
+	assert.Equal(t, &cfg.RegHL, operands[1].PhysicalReg)
+}
+
+func Test_Pipeline_MultipleSources(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Sources = []SourceFile{
+		{Name: "main.zen", Code: `import "math"
+		main: () {
+			x: = double(21)
+		}`},
+		{Name: "math.zen", Code: `export double: (v: u8) u8 {
+			ret v + v
+		}`},
+	}
+	opts.StopAfterInstructionSelection = true
+
+	result, err := Pipeline(opts)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+
+	require.Len(t, result.Units, 2)
+	assert.Contains(t, result.FunctionCFGs, "main")
+	assert.Contains(t, result.FunctionCFGs, "double")
+	assert.Contains(t, result.SemCU.CallGraph.GetCallees("main"), "double")
+}
+
+func Test_Pipeline_MultipleSources_NotExported(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Sources = []SourceFile{
+		{Name: "main.zen", Code: `main: () {
+			helper()
+		}`},
+		{Name: "lib.zen", Code: `helper: () {
+		}`},
+	}
+
+	result, err := Pipeline(opts)
+	require.Error(t, err)
+	require.Len(t, result.SemanticErrors, 1)
+	assert.Contains(t, result.SemanticErrors[0].Error(), "function 'helper' is not exported")
+}
+
+func Test_Pipeline_MultipleSources_Duplicate(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.RequireEntryPoint = false
+	opts.Sources = []SourceFile{
+		{Name: "first.zen", Code: `helper: () {
+		}`},
+		{Name: "second.zen", Code: `helper: () {
+		}`},
+	}
+
+	result, err := Pipeline(opts)
+	require.Error(t, err)
+	require.Len(t, result.SemanticErrors, 1)
+	assert.Equal(t, "second.zen", result.SemanticErrors[0].Source.Name)
+}
+
+func Test_Pipeline_UnreachableFunctionsRemoved(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `main: () {
+		first()
+	}
+	first: () {
+		second()
+	}
+	second: () {
+	}
+	unused: () {
+	}`
+	opts.StopAfterInstructionSelection = true
+
+	result, err := Pipeline(opts)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+
+	assert.Contains(t, result.FunctionCFGs, "main")
+	assert.Contains(t, result.FunctionCFGs, "first")
+	assert.Contains(t, result.FunctionCFGs, "second")
+	assert.NotContains(t, result.FunctionCFGs, "unused")
+}
+
+func Test_Pipeline_NoEntryPointsKeepsAllFunctions(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `main: () {
+	}
+	unused: () {
+	}`
+	opts.EntryPoints = nil
+	opts.StopAfterInstructionSelection = true
+
+	result, err := Pipeline(opts)
+	require.NoError(t, err)
+	assert.Contains(t, result.FunctionCFGs, "unused")
+}
+
+func Test_Pipeline_RequireEntryPoint(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `start: () {
+	}`
+	opts.EntryPoints = []string{"start"}
+	opts.RequireEntryPoint = true
+	opts.StopAfterSemantic = true
+
+	result, err := Pipeline(opts)
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+}
+
+func Test_Pipeline_RequireEntryPoint_Missing(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `helper: () {
+	}`
+	opts.StopAfterSemantic = true
+
+	result, err := Pipeline(opts)
+	require.Error(t, err)
+	require.Len(t, result.SemanticErrors, 1)
+	assert.Contains(t, result.SemanticErrors[0].Error(), "entry point 'main' is not declared")
+}
+
+func Test_Pipeline_NestedMemberAccess(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.RequireEntryPoint = false
+	opts.Source = `struct Point {
+		x: u8,
+		y: u8
+	}
+	struct Line {
+		start: Point,
+		end: Point
+	}
+	endY: (line: Line) u8 {
+		ret line.end.y
+	}`
+	opts.EntryPoints = nil
+	opts.StopAfterInstructionSelection = true
+
+	result, err := Pipeline(opts)
+	require.NoError(t, err)
+
+	// a single load from line + 3, the Point is not loaded first
+	incCount := 0
+	for _, block := range result.FunctionCFGs["endY"].Blocks {
+		for _, instr := range block.MachineInstructions {
+			if strings.HasPrefix(instr.String(), "INC ") {
+				incCount++
+			}
+		}
+	}
+	assert.Equal(t, 3, incCount)
+}
+
+func Test_Pipeline_JumpTableData(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `tick: () {
+}
+main: () {
+	dispatch(2)
+}
+dispatch: (x: u8) {
+	select x {
+		case 0 {
+			tick()
+		}
+		case 1 {
+			tick()
+		}
+		case 2 {
+			tick()
+		}
+		case 3 {
+			tick()
+		}
+	}
+}`
+
+	result, err := Pipeline(opts)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+
+	// the table the code loads is emitted with the data, one block address per case
+	item := result.DataSection.Find("dispatch.jumptable.0")
+	require.NotNil(t, item)
+	require.Len(t, item.Bytes, 8)
+	var dispatch *cfg.FunctionLayout
+	for _, layout := range result.Layout {
+		if layout.CFG.FunctionName == "dispatch" {
+			dispatch = layout
+		}
+	}
+	require.NotNil(t, dispatch)
+	for i := 0; i < len(item.Bytes); i += 2 {
+		address := int(item.Bytes[i]) | int(item.Bytes[i+1])<<8
+		assert.Greater(t, address, int(dispatch.Address))
+		assert.Less(t, address, dispatch.End())
+	}
+
+	var sb strings.Builder
+	require.NoError(t, result.DataSection.Emit(&sb))
+	assert.Contains(t, sb.String(), "dispatch.jumptable.0:\n")
+}
+
+func Test_Pipeline_MemSet_VariableLength(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `clear: (p: u16, n: u16) {
+		@memset(p, 0, n)
+	}
+	main: () {
+		clear(0x4000, 10)
+	}`
+
+	result, err := Pipeline(opts)
+
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	var opcodes []string
+	for _, instr := range result.Instructions["clear"] {
+		opcodes = append(opcodes, strings.Fields(instr.String())[0])
+	}
+	// the tests of the length (0 and 1) stay in front of the instructions they skip
+	assert.Equal(t, []string{
+		"LD", "LD", "LD", "OR", "JR",
+		"LD", "DEC", "LD", "OR", "JR",
+		"LD", "INC", "LDIR", "JR", "RET",
+	}, opcodes)
+}
+
+func Test_Pipeline_MemChr_VariableLength(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `find: (p: u16, n: u16) u16 {
+		ret @memchr(p, 13, n)
+	}
+	main: () {
+		find(0x4000, 10)
+	}`
+
+	result, err := Pipeline(opts)
+
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	var opcodes []string
+	for _, instr := range result.Instructions["find"] {
+		opcodes = append(opcodes, strings.Fields(instr.String())[0])
+	}
+	// a length of 0 jumps to LD HL, 0 without searching
+	assert.Equal(t, []string{
+		"LD", "LD", "LD", "OR", "JR",
+		"LD", "CPIR", "DEC", "JR", "LD", "JR", "RET",
+	}, opcodes)
+}
+
+func Test_Pipeline_MemSet_LengthFromCall(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `count: () u16 {
+		ret 5
+	}
+	main: () {
+		@memset(0x4000, 0, count())
+	}`
+
+	result, err := Pipeline(opts)
+
+	// the tests of BC read B and C as they are after the call
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	var opcodes []string
+	for _, instr := range result.Instructions["main"] {
+		opcodes = append(opcodes, strings.Fields(instr.String())[0])
+	}
+	assert.Contains(t, opcodes, "LDIR")
+}
+
+func Test_Pipeline_CheckedMultiply(t *testing.T) {
+	opts := DefaultPipelineOptions()
+	opts.Source = `mul: (a: i16, b: i16) i16 {
+		ret a * b
+	}
+	main: () {
+		mul(300, 300)
+	}`
+	opts.CheckedArithmetic = true
+
+	result, err := Pipeline(opts)
+
+	// the high word is read from DE as the runtime helper left it
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	var traps int
+	for _, instr := range result.Instructions["mul"] {
+		if strings.Contains(instr.String(), "__overflow_trap") {
+			traps++
+		}
+	}
+	assert.Equal(t, 2, traps)
+}
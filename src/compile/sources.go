@@ -0,0 +1,45 @@
+package compile
+
+import (
+	"fmt"
+	"zenith/compiler"
+	"zenith/compiler/lexer"
+	"zenith/compiler/parser"
+)
+
+// SourceFile is the (in-memory) code of one source file of a program
+// The name (without extension) is the module name used by 'import'.
+type SourceFile struct {
+	Name string
+	Code string
+}
+
+// ParseSources tokenizes and parses each source file into its own compilation unit
+// The units are returned in the order of the files, with the diagnostics of all files.
+func ParseSources(files []SourceFile) ([]parser.CompilationUnit, []*compiler.Diagnostic) {
+	units := make([]parser.CompilationUnit, 0, len(files))
+	var diagnostics []*compiler.Diagnostic
+
+	for _, file := range files {
+		source := &compiler.Source{Name: file.Name}
+		tokenizer := lexer.TokenizerFromString(file.Code)
+		tokens := lexer.NewTokenStream(tokenizer.Tokens(), 100)
+
+		node, errors := parser.Parse(source, tokens)
+		diagnostics = append(diagnostics, errors...)
+		if node == nil {
+			// empty file
+			continue
+		}
+
+		unit, ok := node.(parser.CompilationUnit)
+		if !ok {
+			diagnostics = append(diagnostics, compiler.NewDiagnostic(source,
+				fmt.Sprintf("parser did not return a compilation unit for '%s'", file.Name),
+				compiler.Location{}, compiler.PipelineParser, compiler.SeverityError))
+			continue
+		}
+		units = append(units, unit)
+	}
+	return units, diagnostics
+}
@@ -8,6 +8,15 @@ type CallingConvention interface {
 	// If useStack is false, parameter is in the returned register
 	GetParameterLocation(paramIndex int, paramSize RegisterSize) (register *Register, stackOffset uint8, useStack bool)
 
+	// GetRegisterParameterCount returns how many (leading) parameters are passed in registers
+	// The remaining parameters are pushed on the stack by the caller (last to first),
+	// the caller also removes them after the call returns.
+	GetRegisterParameterCount() int
+
+	// GetStackParameterSize returns the number of bytes the caller pushes
+	// for a call with paramCount parameters (and removes after the call)
+	GetStackParameterSize(paramCount int) uint16
+
 	// GetReturnValueRegister returns the register used for return values
 	// For multi-value returns or large types, may need extension
 	GetReturnValueRegister(returnSize RegisterSize) *Register
@@ -16,10 +25,18 @@ type CallingConvention interface {
 	// These registers may be clobbered by the callee
 	GetCallerSavedRegisters() []*Register
 
+	// GetClobberedRegisters returns the registers a call to the function (or runtime helper) may change,
+	// values that are live across the call cannot be kept in them
+	GetClobberedRegisters(functionName string) []*Register
+
 	// GetCalleeSavedRegisters returns registers that callee must preserve
 	// If callee uses these, it must save/restore them in prologue/epilogue
 	GetCalleeSavedRegisters() []*Register
 
+	// GetShadowRegisters returns the alternate register set a function can switch to
+	// Returns nil if the architecture has no alternate registers
+	GetShadowRegisters() []*Register
+
 	// GetStackAlignment returns the required stack alignment in bytes
 	GetStackAlignment() int
 
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_Z80CallingConvention_FirstParam16Bit(t *testing.T) {
@@ -49,6 +50,39 @@ func Test_Z80CallingConvention_FourthParamOnStack(t *testing.T) {
 	assert.Equal(t, 2, int(offset), "Stack offset should account for return address")
 }
 
+func Test_Z80CallingConvention_StackParamsUseFullSlots(t *testing.T) {
+	cc := NewCallingConventionZ80()
+
+	// 8-bit stack params are pushed as a register pair as well
+	_, offset3, useStack3 := cc.GetParameterLocation(3, Bits8)
+	_, offset4, useStack4 := cc.GetParameterLocation(4, Bits8)
+
+	assert.True(t, useStack3)
+	assert.True(t, useStack4)
+	assert.Equal(t, 2, int(offset3))
+	assert.Equal(t, 4, int(offset4))
+	assert.Equal(t, uint16(4), cc.GetStackParameterSize(5))
+	assert.Equal(t, uint16(0), cc.GetStackParameterSize(3))
+}
+
+func Test_Z80CallingConvention_RegisterParameterCount(t *testing.T) {
+	cc := NewCallingConventionZ80WithRegisterParameters(1)
+	assert.Equal(t, 1, cc.GetRegisterParameterCount())
+
+	reg, _, useStack := cc.GetParameterLocation(0, Bits16)
+	assert.False(t, useStack)
+	assert.Equal(t, "HL", reg.Name)
+
+	reg, offset, useStack := cc.GetParameterLocation(1, Bits16)
+	assert.True(t, useStack, "Second param should be on stack")
+	assert.Nil(t, reg)
+	assert.Equal(t, 2, int(offset))
+	assert.Equal(t, uint16(4), cc.GetStackParameterSize(3))
+
+	assert.Equal(t, 3, NewCallingConventionZ80().GetRegisterParameterCount())
+	assert.Equal(t, 3, NewCallingConventionZ80WithRegisterParameters(5).GetRegisterParameterCount())
+}
+
 func Test_Z80CallingConvention_ReturnValue8Bit(t *testing.T) {
 	cc := NewCallingConventionZ80()
 
@@ -96,6 +130,61 @@ func Test_Z80CallingConvention_CallerSavedRegisters(t *testing.T) {
 	assert.True(t, hasBC, "BC should be caller-saved")
 }
 
+func Test_Z80CallingConvention_ClobberedRegisters(t *testing.T) {
+	cc := NewCallingConventionZ80()
+
+	// runtime helpers change only the registers they use
+	mul8 := cc.GetClobberedRegisters("__mul8")
+	assert.Contains(t, mul8, &RegHL)
+	assert.NotContains(t, mul8, &RegC)
+	assert.Empty(t, cc.GetClobberedRegisters("__overflow_trap"))
+
+	// functions change all caller-saved registers
+	assert.Equal(t, cc.GetCallerSavedRegisters(), cc.GetClobberedRegisters("print"))
+}
+
+// allocateAcrossMultiply allocates a value that is live across a multiply (runtime helper call)
+func allocateAcrossMultiply(t *testing.T, size RegisterSize) (*VirtualRegister, *InterferenceGraph) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	value := vrAlloc.Allocate(Z80Registers8)
+	block.MachineInstructions = append(block.MachineInstructions,
+		newInstruction(Z80_LD_R_N, value, vrAlloc.AllocateImmediate(5, Bits8)))
+
+	left := vrAlloc.AllocateImmediate(3, size)
+	right := vrAlloc.AllocateImmediate(7, size)
+	product, err := selector.SelectMultiply(left, right, false)
+	require.NoError(t, err)
+
+	// both are used after the call
+	sum := vrAlloc.Allocate(Z80RegA)
+	block.MachineInstructions = append(block.MachineInstructions,
+		newInstruction(Z80_LD_R_R, sum, value),
+		newInstruction(Z80_LD_RR_NN, vrAlloc.Allocate(Z80Registers16), product))
+
+	fnCFG := &CFG{FunctionName: "test", Blocks: []*BasicBlock{block}, Entry: block}
+	ig := BuildInterferenceGraph(fnCFG, ComputeLiveness(fnCFG))
+	NewRegisterAllocator(Z80Registers).Allocate(fnCFG, ig)
+	return value, ig
+}
+
+func Test_RegisterAllocator_LiveAcrossMul16(t *testing.T) {
+	value, ig := allocateAcrossMultiply(t, Bits16)
+
+	// __mul16 changes all general purpose registers: the value is not kept in a register
+	assert.Contains(t, ig.GetClobbers(value.ID), &RegBC)
+	assert.Equal(t, CandidateRegister, value.Type)
+	assert.Nil(t, value.PhysicalReg)
+}
+
+func Test_RegisterAllocator_LiveAcrossMul8(t *testing.T) {
+	value, _ := allocateAcrossMultiply(t, Bits8)
+
+	// __mul8 preserves C
+	require.Equal(t, AllocatedRegister, value.Type)
+	assert.Equal(t, &RegC, value.PhysicalReg)
+}
+
 // TODO: Update these tests to work with new VirtualRegister-based allocation API
 /*
 func Test_RegisterAllocator_WithCallingConvention(t *testing.T) {
@@ -21,6 +21,40 @@ var RegAF = Register{Name: "AF", Size: 16,
 	Composition: []*Register{&RegF, &RegA}, RegisterId: 3}
 var RegSP = Register{Name: "SP", Size: 16, RegisterId: 3}
 
+// 8-bit index register halves (undocumented)
+// They take the encoding of H and L behind a DD (IX) or FD (IY) prefix.
+var RegIXH = Register{Name: "IXH", Size: 8, RegisterId: 4}
+var RegIXL = Register{Name: "IXL", Size: 8, RegisterId: 5}
+var RegIYH = Register{Name: "IYH", Size: 8, RegisterId: 4}
+var RegIYL = Register{Name: "IYL", Size: 8, RegisterId: 5}
+
+// 16-bit index registers
+var RegIX = Register{Name: "IX", Size: 16,
+	Composition: []*Register{&RegIXL, &RegIXH}, RegisterId: 2}
+var RegIY = Register{Name: "IY", Size: 16,
+	Composition: []*Register{&RegIYL, &RegIYH}, RegisterId: 2}
+
+// 8-bit alternate (shadow) registers, swapped in with EXX / EX AF,AF'
+// They share the encoding of the main registers (only one set is active).
+var RegAShadow = Register{Name: "A'", Size: 8, RegisterId: 7}
+var RegBShadow = Register{Name: "B'", Size: 8, RegisterId: 0}
+var RegCShadow = Register{Name: "C'", Size: 8, RegisterId: 1}
+var RegDShadow = Register{Name: "D'", Size: 8, RegisterId: 2}
+var RegEShadow = Register{Name: "E'", Size: 8, RegisterId: 3}
+var RegHShadow = Register{Name: "H'", Size: 8, RegisterId: 4}
+var RegLShadow = Register{Name: "L'", Size: 8, RegisterId: 5}
+var RegFShadow = Register{Name: "F'", Size: 8, RegisterId: 6}
+
+// 16-bit alternate (shadow) register pairs
+var RegBCShadow = Register{Name: "BC'", Size: 16,
+	Composition: []*Register{&RegCShadow, &RegBShadow}, RegisterId: 0}
+var RegDEShadow = Register{Name: "DE'", Size: 16,
+	Composition: []*Register{&RegEShadow, &RegDShadow}, RegisterId: 1}
+var RegHLShadow = Register{Name: "HL'", Size: 16,
+	Composition: []*Register{&RegLShadow, &RegHShadow}, RegisterId: 2}
+var RegAFShadow = Register{Name: "AF'", Size: 16,
+	Composition: []*Register{&RegFShadow, &RegAShadow}, RegisterId: 3}
+
 // Z80Registers defines the available registers for Z80 architecture
 // Includes both single 8-bit registers and 16-bit register pairs
 var Z80Registers = []*Register{
@@ -53,6 +87,34 @@ var Z80RegistersPP = []*Register{
 	&RegBC, &RegDE,
 }
 
+// Z80RegistersShadow defines the registers available to a function
+// that runs on the alternate register set (SP is not swapped)
+var Z80RegistersShadow = []*Register{
+	&RegAShadow, &RegBShadow, &RegCShadow, &RegDShadow, &RegEShadow, &RegHShadow, &RegLShadow,
+	&RegBCShadow, &RegDEShadow, &RegHLShadow, &RegAFShadow, &RegSP,
+}
+
+// z80ShadowOf maps each main register to its alternate counterpart
+var z80ShadowOf = map[*Register]*Register{
+	&RegA: &RegAShadow, &RegB: &RegBShadow, &RegC: &RegCShadow, &RegD: &RegDShadow,
+	&RegE: &RegEShadow, &RegH: &RegHShadow, &RegL: &RegLShadow, &RegF: &RegFShadow,
+	&RegBC: &RegBCShadow, &RegDE: &RegDEShadow, &RegHL: &RegHLShadow, &RegAF: &RegAFShadow,
+}
+
+// ShadowRegisters maps the registers to their alternate set counterparts
+// registers without an alternate (SP) are returned as is
+func ShadowRegisters(regs []*Register) []*Register {
+	shadows := make([]*Register, 0, len(regs))
+	for _, reg := range regs {
+		if shadow, ok := z80ShadowOf[reg]; ok {
+			shadows = append(shadows, shadow)
+		} else {
+			shadows = append(shadows, reg)
+		}
+	}
+	return shadows
+}
+
 // AsPairs splits a 16-bit register into its low and high byte registers
 // if the register is not a pair, returns the register itself as low and nil as high
 func (reg *Register) AsPairs() (lowReg *Register, highReg *Register) {
@@ -82,9 +144,16 @@ func ToPairs(regs []*Register) (lowRegs []*Register, highRegs []*Register) {
 	return lowRegs, highRegs
 }
 
+// z80RegisterParameters is the maximum number of parameters passed in registers (HL, DE, BC)
+const z80RegisterParameters = 3
+
+// z80StackSlotSize is the size of a stack parameter: PUSH/POP always transfer a register pair
+const z80StackSlotSize = 2
+
 // callingConventionZ80 implements a standard calling convention for Z80
 type callingConventionZ80 struct {
-	registers []*Register
+	registers      []*Register
+	registerParams int
 }
 
 // NewZ80CallingConvention creates a Z80 calling convention
@@ -93,6 +162,8 @@ type callingConventionZ80 struct {
 //   - 2nd 16-bit or two 8-bit: DE (D=high byte, E=low byte)
 //   - 3rd 16-bit or two 8-bit: BC (B=high byte, C=low byte)
 //   - Additional params: Stack (growing downward)
+//     the caller pushes them last to first (2 bytes each, 8-bit in the low byte)
+//     and removes them after the call
 //
 // Return values:
 //   - 8-bit: A
@@ -101,8 +172,16 @@ type callingConventionZ80 struct {
 // Caller-saved (volatile): AF, BC, DE, HL
 // Callee-saved (non-volatile): IX, IY (if available)
 func NewCallingConventionZ80() CallingConvention {
+	return NewCallingConventionZ80WithRegisterParameters(z80RegisterParameters)
+}
+
+// NewCallingConventionZ80WithRegisterParameters creates a Z80 calling convention
+// that passes only the first 'count' (0-3) parameters in registers, the rest on the stack
+func NewCallingConventionZ80WithRegisterParameters(count int) CallingConvention {
+	count = max(0, min(count, z80RegisterParameters))
 	return &callingConventionZ80{
-		registers: Z80Registers,
+		registers:      Z80Registers,
+		registerParams: count,
 	}
 }
 
@@ -111,6 +190,13 @@ func (cc *callingConventionZ80) GetParameterLocation(paramIndex int, paramSize R
 	// For 8-bit params, use the low byte of the pair
 	var regName string
 
+	if paramIndex >= cc.registerParams {
+		// Stack parameters start after return address (2 bytes)
+		// Stack grows downward, params accessed as [SP + offset]
+		// Each parameter takes a full slot (pushed as a register pair)
+		return nil, uint8(z80StackSlotSize + (paramIndex-cc.registerParams)*z80StackSlotSize), true
+	}
+
 	if paramSize == 16 {
 		// 16-bit parameters
 		switch paramIndex {
@@ -120,10 +206,6 @@ func (cc *callingConventionZ80) GetParameterLocation(paramIndex int, paramSize R
 			regName = "DE"
 		case 2:
 			regName = "BC"
-		default:
-			// Stack parameters start after return address (2 bytes)
-			// Stack grows downward, params accessed as [SP + offset]
-			return nil, uint8(2 + (paramIndex-3)*2), true
 		}
 	} else {
 		// 8-bit parameters use low byte of register pairs
@@ -134,9 +216,6 @@ func (cc *callingConventionZ80) GetParameterLocation(paramIndex int, paramSize R
 			regName = "E"
 		case 2:
 			regName = "C"
-		default:
-			// Stack parameters
-			return nil, uint8(2 + (paramIndex-3)*1), true
 		}
 	}
 
@@ -151,6 +230,17 @@ func (cc *callingConventionZ80) GetParameterLocation(paramIndex int, paramSize R
 	return nil, uint8(2 + paramIndex*2), true
 }
 
+func (cc *callingConventionZ80) GetRegisterParameterCount() int {
+	return cc.registerParams
+}
+
+func (cc *callingConventionZ80) GetStackParameterSize(paramCount int) uint16 {
+	if paramCount <= cc.registerParams {
+		return 0
+	}
+	return uint16((paramCount - cc.registerParams) * z80StackSlotSize)
+}
+
 func (cc *callingConventionZ80) GetReturnValueRegister(returnSize RegisterSize) *Register {
 	var regName string
 	if returnSize == 8 {
@@ -185,12 +275,53 @@ func (cc *callingConventionZ80) GetCallerSavedRegisters() []*Register {
 	return callerSaved
 }
 
+// z80HelperClobbers are the registers the runtime helpers change (their result registers included).
+// This is the contract for the runtime library: a helper may not change other registers.
+var z80HelperClobbers = map[string][]*Register{
+	// __mul8(A, L) -> HL: B counts the bits
+	"__mul8":  {&RegA, &RegB, &RegDE, &RegHL},
+	"__imul8": {&RegA, &RegB, &RegDE, &RegHL},
+	// __mul16(HL, DE) -> HLDE
+	"__mul16":  {&RegA, &RegBC, &RegDE, &RegHL},
+	"__imul16": {&RegA, &RegBC, &RegDE, &RegHL},
+	// __div8(HL, DE) -> A and __div16(HL, DE) -> HL
+	"__div8":   {&RegA, &RegBC, &RegDE, &RegHL},
+	"__idiv8":  {&RegA, &RegBC, &RegDE, &RegHL},
+	"__div16":  {&RegA, &RegBC, &RegDE, &RegHL},
+	"__idiv16": {&RegA, &RegBC, &RegDE, &RegHL},
+	// __mod8(HL, DE) -> A and __mod16(HL, DE) -> HL
+	"__mod8":   {&RegA, &RegBC, &RegDE, &RegHL},
+	"__imod8":  {&RegA, &RegBC, &RegDE, &RegHL},
+	"__mod16":  {&RegA, &RegBC, &RegDE, &RegHL},
+	"__imod16": {&RegA, &RegBC, &RegDE, &RegHL},
+	// __shl(HL, DE) and __shr(HL, DE) -> A or HL: E counts the shifts
+	"__shl8":  {&RegA, &RegDE, &RegHL},
+	"__shl16": {&RegA, &RegDE, &RegHL},
+	"__shr8":  {&RegA, &RegDE, &RegHL},
+	"__shr16": {&RegA, &RegDE, &RegHL},
+	// checked arithmetic: the trap preserves all registers
+	"__overflow_trap": {},
+}
+
+func (cc *callingConventionZ80) GetClobberedRegisters(functionName string) []*Register {
+	if clobbers, ok := z80HelperClobbers[functionName]; ok {
+		return clobbers
+	}
+	// functions do not preserve any of the general purpose registers
+	return cc.GetCallerSavedRegisters()
+}
+
 func (cc *callingConventionZ80) GetCalleeSavedRegisters() []*Register {
 	// Callee must preserve: IX, IY (if we use them)
 	// For now, return empty since we're not using IX/IY
 	return []*Register{}
 }
 
+func (cc *callingConventionZ80) GetShadowRegisters() []*Register {
+	// EXX and EX AF,AF' swap in the alternate set, SP is shared
+	return Z80RegistersShadow
+}
+
 func (cc *callingConventionZ80) GetStackAlignment() int {
 	// Z80 doesn't have strict alignment requirements
 	return 1
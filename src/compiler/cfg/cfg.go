@@ -28,10 +28,14 @@ const (
 	LabelForBody
 	LabelForInc
 	LabelForExit
+	LabelDoBody
+	LabelDoCond
+	LabelDoExit
 	LabelSelectCase
 	LabelSelectElse
 	LabelSelectMerge
 	LabelUnreachable
+	LabelEdgeSplit
 )
 
 // String returns the string representation of a BlockLabel
@@ -61,6 +65,12 @@ func (l BlockLabel) String() string {
 		return "for.inc"
 	case LabelForExit:
 		return "for.exit"
+	case LabelDoBody:
+		return "do.body"
+	case LabelDoCond:
+		return "do.cond"
+	case LabelDoExit:
+		return "do.exit"
 	case LabelSelectCase:
 		return "select.case"
 	case LabelSelectElse:
@@ -69,6 +79,8 @@ func (l BlockLabel) String() string {
 		return "select.merge"
 	case LabelUnreachable:
 		return "unreachable"
+	case LabelEdgeSplit:
+		return "edge.split"
 	default:
 		return "unknown"
 	}
@@ -96,17 +108,37 @@ type CFG struct {
 	FunctionDecl *zsm.SemFunctionDecl // Original function declaration (for parameters, return type)
 	FrameLayout  *FrameLayout         // Stack frame layout for symbol-backed slots
 	StackOffset  uint16               // Current stack offset for spills
+	// UsesShadowRegisters is set for '@fast' functions that run on the alternate register set
+	UsesShadowRegisters bool
+	// JumpTables are the address tables emitted as data for dense select statements
+	JumpTables []*JumpTable
+}
+
+// JumpTable is a table of block addresses indexed by a (rebased) select value
+type JumpTable struct {
+	Label   string        // data label of the table
+	Targets []*BasicBlock // target block per index (out-of-range cases go to the default target)
 }
 
 // ============================================================================
 // CFG Builder - Transforms IR to CFG
 // ============================================================================
 
+// Bounds on the blocks and edges built for one function. A well-formed function stays far below;
+// a malformed (cyclic) semantic tree hits them instead of recursing forever.
+const (
+	maxBlocksPerFunction = 10000
+	maxEdgesPerFunction  = 4 * maxBlocksPerFunction
+)
+
 // CFGBuilder builds a control flow graph from IR
 type CFGBuilder struct {
 	nextBlockID  int
+	edgeCount    int
 	blocks       []*BasicBlock
 	currentBlock *BasicBlock
+	functionName string
+	err          error // set when a bound is exceeded, stops processing statements
 }
 
 // NewCFGBuilder creates a new CFG builder
@@ -117,8 +149,11 @@ func NewCFGBuilder() *CFGBuilder {
 	}
 }
 
-// BuildCFG transforms a function's IR into a CFG
-func (b *CFGBuilder) BuildCFG(funcDecl *zsm.SemFunctionDecl) *CFG {
+// BuildCFG transforms a function's IR into a CFG.
+// It returns an internal error when the function exceeds the block or edge bounds.
+func (b *CFGBuilder) BuildCFG(funcDecl *zsm.SemFunctionDecl) (*CFG, error) {
+	b.functionName = funcDecl.Name
+
 	// Create entry block (reserved for prologue only)
 	entry := b.newBlock(LabelEntry, -1)
 
@@ -139,10 +174,14 @@ func (b *CFGBuilder) BuildCFG(funcDecl *zsm.SemFunctionDecl) *CFG {
 
 	// Connect current block to exit if it doesn't already have successors
 	// (handles implicit return at end of function)
-	if len(b.currentBlock.Successors) == 0 {
+	if len(b.currentBlock.Successors) == 0 && !b.blockTerminates(b.currentBlock) {
 		b.addEdge(b.currentBlock, exit)
 	}
 
+	if b.err != nil {
+		return nil, b.err
+	}
+
 	return &CFG{
 		Entry:        entry,
 		Exit:         exit,
@@ -150,22 +189,27 @@ func (b *CFGBuilder) BuildCFG(funcDecl *zsm.SemFunctionDecl) *CFG {
 		FunctionName: funcDecl.Name,
 		FunctionDecl: funcDecl,
 		FrameLayout:  NewFrameLayout(),
-	}
+	}, nil
 }
 
-// blockTerminates checks if a block ends with a return statement
+// blockTerminates checks if a block ends with a return statement (or a '@noreturn' call)
+// or cannot be reached at all, so it does not fall through to a following block
 func (b *CFGBuilder) blockTerminates(block *BasicBlock) bool {
+	if len(block.Predecessors) == 0 && block.Label != LabelEntry {
+		return true
+	}
 	if len(block.Instructions) == 0 {
 		return false
 	}
-	lastInstr := block.Instructions[len(block.Instructions)-1]
-	_, isReturn := lastInstr.(*zsm.SemReturn)
-	return isReturn
+	return zsm.Terminates(block.Instructions[len(block.Instructions)-1])
 }
 
 // newBlock creates a new basic block
 // If referenceID >= 0, stores it as LabelID for uniqueness
 func (b *CFGBuilder) newBlock(label BlockLabel, referenceID int) *BasicBlock {
+	if b.nextBlockID >= maxBlocksPerFunction && b.err == nil {
+		b.err = fmt.Errorf("internal error: function '%s' exceeds %d blocks, the semantic tree is malformed", b.functionName, maxBlocksPerFunction)
+	}
 	block := &BasicBlock{
 		ID:                  b.nextBlockID,
 		Label:               label,
@@ -190,6 +234,10 @@ func (b *BasicBlock) GetFullLabel() string {
 
 // addEdge adds a control flow edge between two blocks
 func (b *CFGBuilder) addEdge(from, to *BasicBlock) {
+	b.edgeCount++
+	if b.edgeCount > maxEdgesPerFunction && b.err == nil {
+		b.err = fmt.Errorf("internal error: function '%s' exceeds %d edges, the semantic tree is malformed", b.functionName, maxEdgesPerFunction)
+	}
 	from.Successors = append(from.Successors, to)
 	to.Predecessors = append(to.Predecessors, from)
 }
@@ -197,6 +245,9 @@ func (b *CFGBuilder) addEdge(from, to *BasicBlock) {
 // processBlock processes an IR block and builds CFG blocks
 func (b *CFGBuilder) processBlock(block *zsm.SemBlock, exitBlock *BasicBlock) {
 	for _, stmt := range block.Statements {
+		if b.err != nil {
+			return
+		}
 		b.processStatement(stmt, exitBlock)
 	}
 }
@@ -212,9 +263,21 @@ func (b *CFGBuilder) processStatement(stmt zsm.SemStatement, exitBlock *BasicBlo
 		// Assignments are simple statements
 		b.currentBlock.Instructions = append(b.currentBlock.Instructions, s)
 
+	case *zsm.SemMultiAssignment:
+		b.currentBlock.Instructions = append(b.currentBlock.Instructions, s)
+
 	case *zsm.SemExpressionStmt:
 		// Expression statements (e.g., function calls)
 		b.currentBlock.Instructions = append(b.currentBlock.Instructions, s)
+		if zsm.Terminates(s) {
+			// A '@noreturn' call does not flow anywhere: the code after it
+			// goes into a block without predecessors, which is not selected
+			b.currentBlock = b.newBlock(LabelUnreachable, b.currentBlock.ID)
+		}
+
+	case *zsm.SemInlineAsm:
+		// Inline assembly does not change the control flow (as far as we know)
+		b.currentBlock.Instructions = append(b.currentBlock.Instructions, s)
 
 	case *zsm.SemReturn:
 		// Return statement - add to current block and connect to exit
@@ -223,11 +286,23 @@ func (b *CFGBuilder) processStatement(stmt zsm.SemStatement, exitBlock *BasicBlo
 		// Note: currentBlock now terminates, don't create a new block
 
 	case *zsm.SemIf:
-		b.processIf(s, exitBlock)
+		if selectStmt := matchIfChain(s); selectStmt != nil {
+			// compiled as the equivalent (dense) select
+			b.processSelect(selectStmt, exitBlock)
+		} else {
+			b.processIf(s, exitBlock)
+		}
 
 	case *zsm.SemFor:
 		b.processFor(s, exitBlock)
 
+	case *zsm.SemForRange:
+		// same as the counted loop
+		b.processFor(s.Loop, exitBlock)
+
+	case *zsm.SemDoWhile:
+		b.processDoWhile(s, exitBlock)
+
 	case *zsm.SemSelect:
 		b.processSelect(s, exitBlock)
 
@@ -334,6 +409,9 @@ func (b *CFGBuilder) processIf(ifStmt *zsm.SemIf, exitBlock *BasicBlock) {
 //	| [inc]
 //	|    |
 //	+----+
+//
+// A loop that counts an 8-bit variable down to zero (i > 0; i--)
+// loops back from [inc] to [body] while the counter is not zero: [inc] -> [body], [exit]
 func (b *CFGBuilder) processFor(forStmt *zsm.SemFor, exitBlock *BasicBlock) {
 	// Process initializer in current block
 	if forStmt.Initializer != nil {
@@ -356,25 +434,97 @@ func (b *CFGBuilder) processFor(forStmt *zsm.SemFor, exitBlock *BasicBlock) {
 
 	// Create increment block
 	incBlock := b.newBlock(LabelForInc, condBlock.ID)
-	b.addEdge(b.currentBlock, incBlock)
-	if forStmt.Increment != nil {
-		// Store increment as an expression statement
-		incBlock.Instructions = append(incBlock.Instructions, &zsm.SemExpressionStmt{
-			Expression: forStmt.Increment,
-		})
+	if !b.blockTerminates(b.currentBlock) {
+		b.addEdge(b.currentBlock, incBlock)
+	}
+
+	var countDown *countDownBranch
+	if increment := forStmt.Increment; increment != nil {
+		countDown = matchCountDown(increment, forStmt.Condition)
+		if countDown != nil {
+			// the condition is only tested before the first iteration
+			incBlock.Instructions = append(incBlock.Instructions, countDown)
+		} else {
+			incBlock.Instructions = append(incBlock.Instructions, increment)
+		}
 	}
 
 	// Loop back to condition
-	b.addEdge(incBlock, condBlock)
+	if countDown == nil {
+		b.addEdge(incBlock, condBlock)
+	}
 
 	// Create exit block (for loop exit)
 	loopExitBlock := b.newBlock(LabelForExit, condBlock.ID)
 	b.addEdge(condBlock, loopExitBlock)
 
+	// Loop back to the body while the counter is not zero
+	if countDown != nil {
+		b.addEdge(incBlock, bodyBlock)
+		b.addEdge(incBlock, loopExitBlock)
+	}
+
 	// Continue from loop exit
 	b.currentBlock = loopExitBlock
 }
 
+// processDoWhile processes a do-while loop, the body is entered unconditionally
+// and the condition is tested at the bottom
+//
+//	    |
+//	    v
+//	+>[body]
+//	|   |
+//	|   v
+//	+-[cond]
+//	    |
+//	    v
+//	  [exit]
+func (b *CFGBuilder) processDoWhile(doStmt *zsm.SemDoWhile, exitBlock *BasicBlock) {
+	// Create body block, entered from the current block
+	entryBlock := b.currentBlock
+	bodyBlock := b.newBlock(LabelDoBody, entryBlock.ID)
+	b.addEdge(entryBlock, bodyBlock)
+	b.currentBlock = bodyBlock
+	if doStmt.Body != nil {
+		b.processBlock(doStmt.Body, exitBlock)
+	}
+
+	// Create condition block at the end of the body
+	condBlock := b.newBlock(LabelDoCond, bodyBlock.ID)
+	if !b.blockTerminates(b.currentBlock) {
+		b.addEdge(b.currentBlock, condBlock)
+	}
+
+	// counting down to zero: the decrement at the end of the body moves into the condition
+	if countDown := b.matchDoCountDown(doStmt); countDown != nil {
+		b.currentBlock.Instructions = b.currentBlock.Instructions[:len(b.currentBlock.Instructions)-1]
+		condBlock.Instructions = append(condBlock.Instructions, countDown)
+	} else {
+		condBlock.Instructions = append(condBlock.Instructions, doStmt)
+	}
+
+	// Loop back to the body while the condition holds
+	b.addEdge(condBlock, bodyBlock)
+
+	// Create exit block (do loop exit)
+	loopExitBlock := b.newBlock(LabelDoExit, bodyBlock.ID)
+	b.addEdge(condBlock, loopExitBlock)
+
+	// Continue from loop exit
+	b.currentBlock = loopExitBlock
+}
+
+// matchDoCountDown checks if the body of the do-while loop (ending in the current block)
+// ends with the decrement of the counter the condition compares to zero
+func (b *CFGBuilder) matchDoCountDown(doStmt *zsm.SemDoWhile) *countDownBranch {
+	instructions := b.currentBlock.Instructions
+	if len(instructions) == 0 || doStmt.Condition == nil {
+		return nil
+	}
+	return matchCountDown(instructions[len(instructions)-1], doStmt.Condition)
+}
+
 // processSelect processes a select statement, creating blocks for each case
 //
 //	        [expr]
@@ -399,7 +549,9 @@ func (b *CFGBuilder) processSelect(selectStmt *zsm.SemSelect, exitBlock *BasicBl
 		b.addEdge(exprBlock, caseBlock)
 		b.currentBlock = caseBlock
 		b.processBlock(caseStmt.Body, exitBlock)
-		b.addEdge(b.currentBlock, mergeBlock)
+		if !b.blockTerminates(b.currentBlock) {
+			b.addEdge(b.currentBlock, mergeBlock)
+		}
 	}
 
 	// Process else block if present
@@ -408,7 +560,9 @@ func (b *CFGBuilder) processSelect(selectStmt *zsm.SemSelect, exitBlock *BasicBl
 		b.addEdge(exprBlock, elseBlock)
 		b.currentBlock = elseBlock
 		b.processBlock(selectStmt.Else, exitBlock)
-		b.addEdge(b.currentBlock, mergeBlock)
+		if !b.blockTerminates(b.currentBlock) {
+			b.addEdge(b.currentBlock, mergeBlock)
+		}
 	} else {
 		// If no else, fall through to merge
 		b.addEdge(exprBlock, mergeBlock)
@@ -444,6 +598,75 @@ func (cfg *CFG) String() string {
 	return sb.String()
 }
 
+// SplitCriticalEdges inserts an empty block on every critical edge:
+// an edge from a block with multiple successors to a block with multiple predecessors.
+// The new block only jumps to the original successor, giving spill and phi-resolution
+// code a place that is executed on that edge alone.
+// Returns the number of edges that were split.
+func (cfg *CFG) SplitCriticalEdges() int {
+	nextID := 0
+	for _, block := range cfg.Blocks {
+		if block.ID >= nextID {
+			nextID = block.ID + 1
+		}
+	}
+
+	split := 0
+	// only iterate the original blocks, split blocks are appended
+	blocks := cfg.Blocks
+	for _, pred := range blocks {
+		if len(pred.Successors) < 2 {
+			continue
+		}
+		for i, succ := range pred.Successors {
+			if len(succ.Predecessors) < 2 {
+				continue
+			}
+
+			edgeBlock := &BasicBlock{
+				ID:                  nextID,
+				Label:               LabelEdgeSplit,
+				LabelID:             nextID,
+				Instructions:        []zsm.SemStatement{},
+				MachineInstructions: []MachineInstruction{},
+				Successors:          []*BasicBlock{succ},
+				Predecessors:        []*BasicBlock{pred},
+			}
+			nextID++
+
+			// keep edge order: successor and predecessor indices are significant for branches
+			pred.Successors[i] = edgeBlock
+			for j, p := range succ.Predecessors {
+				if p == pred {
+					succ.Predecessors[j] = edgeBlock
+					break
+				}
+			}
+
+			// after instruction selection: retarget the branches and jump on to the successor
+			if len(pred.MachineInstructions) > 0 {
+				retargetBranches(pred, succ, edgeBlock)
+				edgeBlock.MachineInstructions = append(edgeBlock.MachineInstructions, newJump(Z80_JP_NN, succ))
+			}
+
+			cfg.Blocks = append(cfg.Blocks, edgeBlock)
+			split++
+		}
+	}
+	return split
+}
+
+// retargetBranches replaces the from-target of all branches in block with to
+func retargetBranches(block, from, to *BasicBlock) {
+	for _, instr := range block.MachineInstructions {
+		for i, target := range instr.GetTargetBlocks() {
+			if target == from {
+				instr.SetTargetBlock(i, to)
+			}
+		}
+	}
+}
+
 // GetAllInstructions collects all machine instructions from all blocks in the CFG
 func (cfg *CFG) GetAllInstructions() []MachineInstruction {
 	var instructions []MachineInstruction
@@ -454,20 +677,23 @@ func (cfg *CFG) GetAllInstructions() []MachineInstruction {
 }
 
 // BuildCFGs builds CFGs for all functions in a compilation unit
-func BuildCFGs(compilationUnit *zsm.SemCompilationUnit) []*CFG {
+func BuildCFGs(compilationUnit *zsm.SemCompilationUnit) ([]*CFG, error) {
 	var cfgs []*CFG
 	builder := NewCFGBuilder()
 
 	for _, decl := range compilationUnit.Declarations {
 		if funcDecl, ok := decl.(*zsm.SemFunctionDecl); ok {
-			cfg := builder.BuildCFG(funcDecl)
+			cfg, err := builder.BuildCFG(funcDecl)
+			if err != nil {
+				return nil, err
+			}
 			cfgs = append(cfgs, cfg)
 			// Reset builder for next function
 			builder = NewCFGBuilder()
 		}
 	}
 
-	return cfgs
+	return cfgs, nil
 }
 
 func DumpCFG(fnName string, fnCFG *CFG, dumpInstructions func([]MachineInstruction)) {
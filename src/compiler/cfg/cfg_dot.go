@@ -0,0 +1,91 @@
+package cfg
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// dotMaxInstructions is the number of machine instructions shown per block node
+const dotMaxInstructions = 3
+
+// ToDOT writes the CFG as a Graphviz digraph (for debugging)
+// Entry and exit blocks are highlighted, back edges are drawn dashed.
+func (cfg *CFG) ToDOT(w io.Writer) error {
+	backEdges := cfg.findBackEdges()
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("digraph %q {\n", cfg.FunctionName))
+	sb.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+
+	for _, block := range cfg.Blocks {
+		sb.WriteString(fmt.Sprintf("  b%d [label=\"%s\"", block.ID, dotBlockLabel(block)))
+		switch block {
+		case cfg.Entry:
+			sb.WriteString(", style=filled, fillcolor=palegreen")
+		case cfg.Exit:
+			sb.WriteString(", style=filled, fillcolor=lightpink")
+		}
+		sb.WriteString("];\n")
+	}
+
+	for _, block := range cfg.Blocks {
+		for _, succ := range block.Successors {
+			sb.WriteString(fmt.Sprintf("  b%d -> b%d", block.ID, succ.ID))
+			if backEdges[[2]int{block.ID, succ.ID}] {
+				sb.WriteString(" [style=dashed, color=red]")
+			}
+			sb.WriteString(";\n")
+		}
+	}
+	sb.WriteString("}\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// dotBlockLabel builds the (escaped) node label: id, label and the first instructions
+func dotBlockLabel(block *BasicBlock) string {
+	lines := []string{fmt.Sprintf("%d: %s", block.ID, block.GetFullLabel())}
+	for i, instr := range block.MachineInstructions {
+		if i == dotMaxInstructions {
+			lines = append(lines, "...")
+			break
+		}
+		lines = append(lines, instr.String())
+	}
+
+	for i, line := range lines {
+		line = strings.ReplaceAll(line, "\\", "\\\\")
+		lines[i] = strings.ReplaceAll(line, "\"", "\\\"")
+	}
+	return strings.Join(lines, "\\l") + "\\l"
+}
+
+// findBackEdges returns the edges (from, to block ID) that point back
+// to a block on the current depth-first search path from the entry block
+func (cfg *CFG) findBackEdges() map[[2]int]bool {
+	backEdges := make(map[[2]int]bool)
+	if cfg.Entry == nil {
+		return backEdges
+	}
+
+	visited := make(map[*BasicBlock]bool)
+	onPath := make(map[*BasicBlock]bool)
+	var visit func(block *BasicBlock)
+	visit = func(block *BasicBlock) {
+		visited[block] = true
+		onPath[block] = true
+		for _, succ := range block.Successors {
+			if onPath[succ] {
+				backEdges[[2]int{block.ID, succ.ID}] = true
+			} else if !visited[succ] {
+				visit(succ)
+			}
+		}
+		onPath[block] = false
+	}
+	visit(cfg.Entry)
+
+	return backEdges
+}
@@ -0,0 +1,58 @@
+package cfg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CFG_ToDOT_IfElse(t *testing.T) {
+	code := `main: (x: u8) {
+		if x > 10 {
+			x = 1
+		} else {
+			x = 2
+		}
+	}`
+	cfg := buildCFGFromCode(t, code)
+
+	var sb strings.Builder
+	err := cfg.ToDOT(&sb)
+	require.NoError(t, err)
+	dot := sb.String()
+
+	assert.True(t, strings.HasPrefix(dot, `digraph "main" {`))
+	// entry, exit, function, then, else, merge
+	for _, label := range []string{"entry", "exit", "function.0", "if.then.2", "if.else.2", "if.merge.2"} {
+		assert.Contains(t, dot, label)
+	}
+	assert.Contains(t, dot, "fillcolor=palegreen")
+	assert.Contains(t, dot, "fillcolor=lightpink")
+
+	// entry->function, function->then, function->else, then->merge, else->merge, merge->exit
+	assert.Equal(t, 6, strings.Count(dot, "->"))
+	assert.NotContains(t, dot, "style=dashed")
+}
+
+func Test_CFG_ToDOT_BackEdge(t *testing.T) {
+	code := `main: () {
+		for i: = 0; i < 10; i + 1 {
+		}
+	}`
+	cfg := buildCFGFromCode(t, code)
+	condBlock := findBlockByLabel(cfg, LabelForCond)
+	incBlock := findBlockByLabel(cfg, LabelForInc)
+	require.NotNil(t, condBlock)
+	require.NotNil(t, incBlock)
+
+	var sb strings.Builder
+	require.NoError(t, cfg.ToDOT(&sb))
+	dot := sb.String()
+
+	// only the increment -> condition edge loops back
+	assert.Equal(t, 1, strings.Count(dot, "style=dashed"))
+	assert.Contains(t, dot, fmt.Sprintf("b%d -> b%d [style=dashed, color=red];", incBlock.ID, condBlock.ID))
+}
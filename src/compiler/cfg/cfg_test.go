@@ -12,8 +12,8 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// Helper function to build a CFG from code
-func buildCFGFromCode(t *testing.T, code string) *CFG {
+// Helper function to analyze code into the semantic tree
+func analyzeCFGCode(t *testing.T, code string) *zsm.SemCompilationUnit {
 	// Tokenize
 	tokens := lexer.OpenTokenStream(code)
 
@@ -33,6 +33,12 @@ func buildCFGFromCode(t *testing.T, code string) *CFG {
 	}
 	require.Equal(t, 0, len(semErrors))
 	require.Greater(t, len(semCU.Declarations), 0)
+	return semCU
+}
+
+// Helper function to build a CFG from code
+func buildCFGFromCode(t *testing.T, code string) *CFG {
+	semCU := analyzeCFGCode(t, code)
 
 	// Get function declaration
 	funcDecl, ok := semCU.Declarations[0].(*zsm.SemFunctionDecl)
@@ -40,7 +46,8 @@ func buildCFGFromCode(t *testing.T, code string) *CFG {
 
 	// Build CFG
 	builder := NewCFGBuilder()
-	cfg := builder.BuildCFG(funcDecl)
+	cfg, err := builder.BuildCFG(funcDecl)
+	require.NoError(t, err)
 	require.NotNil(t, cfg)
 
 	return cfg
@@ -329,6 +336,41 @@ func Test_CFG_ForLoop(t *testing.T) {
 	assert.Contains(t, cfg.Exit.Predecessors, exitBlock)
 }
 
+func Test_CFG_ForRange(t *testing.T) {
+	code := `main: () {
+		for i in 0..10 {
+			x: = i
+		}
+	}`
+	cfg := buildCFGFromCode(t, code)
+
+	firstBlock := findBlockByLabel(cfg, LabelFunction)
+	condBlock := findBlockByLabel(cfg, LabelForCond)
+	bodyBlock := findBlockByLabel(cfg, LabelForBody)
+	incBlock := findBlockByLabel(cfg, LabelForInc)
+	require.NotNil(t, firstBlock)
+	require.NotNil(t, condBlock)
+	require.NotNil(t, bodyBlock)
+	require.NotNil(t, incBlock)
+
+	// the loop variable is declared before the condition
+	require.Len(t, firstBlock.Instructions, 1)
+	varDecl, ok := firstBlock.Instructions[0].(*zsm.SemVariableDecl)
+	require.True(t, ok)
+	loopVar := varDecl.Symbol
+	assert.Equal(t, "i", loopVar.Name)
+
+	// the loop variable is in scope in the body
+	require.Len(t, bodyBlock.Instructions, 1)
+	bodyDecl := bodyBlock.Instructions[0].(*zsm.SemVariableDecl)
+	ref, ok := bodyDecl.Initializer.(*zsm.SemSymbolRef)
+	require.True(t, ok)
+	assert.Same(t, loopVar, ref.Symbol)
+
+	assert.Contains(t, bodyBlock.Successors, incBlock)
+	assert.Contains(t, incBlock.Successors, condBlock)
+}
+
 func Test_CFG_ForLoopOnlyCondition(t *testing.T) {
 	code := `main: () {
 		for true {
@@ -369,6 +411,103 @@ func Test_CFG_ForLoopOnlyCondition(t *testing.T) {
 	assert.Contains(t, condBlock.Predecessors, incBlock)
 }
 
+func Test_CFG_DoWhileLoop(t *testing.T) {
+	code := `main: () {
+		i: = 10
+		do {
+			i = i - 1
+		} while i > 0
+	}`
+	cfg := buildCFGFromCode(t, code)
+
+	firstBlock := findBlockByLabel(cfg, LabelFunction)
+	bodyBlock := findBlockByLabel(cfg, LabelDoBody)
+	condBlock := findBlockByLabel(cfg, LabelDoCond)
+	exitBlock := findBlockByLabel(cfg, LabelDoExit)
+
+	require.NotNil(t, firstBlock)
+	require.NotNil(t, bodyBlock)
+	require.NotNil(t, condBlock)
+	require.NotNil(t, exitBlock)
+
+	// firstBlock -> body (unconditionally, no test before the body)
+	assert.Equal(t, []*BasicBlock{bodyBlock}, firstBlock.Successors)
+	// body -> cond
+	assert.Equal(t, []*BasicBlock{condBlock}, bodyBlock.Successors)
+	// cond -> body (back edge), cond -> exit
+	assert.Equal(t, []*BasicBlock{bodyBlock, exitBlock}, condBlock.Successors)
+	assert.ElementsMatch(t, []*BasicBlock{firstBlock, condBlock}, bodyBlock.Predecessors)
+	// exit -> cfg.Exit
+	assert.Contains(t, exitBlock.Successors, cfg.Exit)
+
+	loops := cfg.FindLoops()
+	require.Len(t, loops, 1)
+	assert.Equal(t, bodyBlock, loops[0].Header)
+	assert.True(t, loops[0].IsBackEdge(condBlock, bodyBlock))
+}
+
+func Test_CFG_ForLoopCountDown(t *testing.T) {
+	code := `main: () {
+		for i: = 10; i > 0; i-- {
+			x: = i
+		}
+	}`
+	cfg := buildCFGFromCode(t, code)
+
+	condBlock := findBlockByLabel(cfg, LabelForCond)
+	bodyBlock := findBlockByLabel(cfg, LabelForBody)
+	incBlock := findBlockByLabel(cfg, LabelForInc)
+	exitBlock := findBlockByLabel(cfg, LabelForExit)
+
+	require.NotNil(t, condBlock)
+	require.NotNil(t, bodyBlock)
+	require.NotNil(t, incBlock)
+	require.NotNil(t, exitBlock)
+
+	// the condition is only tested before the first iteration
+	assert.Equal(t, []*BasicBlock{bodyBlock, exitBlock}, condBlock.Successors)
+	// inc -> body (back edge), inc -> exit
+	assert.Equal(t, []*BasicBlock{bodyBlock, exitBlock}, incBlock.Successors)
+	assert.NotContains(t, condBlock.Predecessors, incBlock)
+	require.Len(t, incBlock.Instructions, 1)
+	assert.IsType(t, &countDownBranch{}, incBlock.Instructions[0])
+}
+
+func Test_CFG_DoWhileLoopCountDown(t *testing.T) {
+	code := `main: () {
+		i: = 10
+		do {
+			x: = i
+			i = i - 1
+		} while i <> 0
+	}`
+	cfg := buildCFGFromCode(t, code)
+
+	bodyBlock := findBlockByLabel(cfg, LabelDoBody)
+	condBlock := findBlockByLabel(cfg, LabelDoCond)
+	require.NotNil(t, bodyBlock)
+	require.NotNil(t, condBlock)
+
+	// the decrement moves from the body into the condition
+	assert.Len(t, bodyBlock.Instructions, 1)
+	require.Len(t, condBlock.Instructions, 1)
+	assert.IsType(t, &countDownBranch{}, condBlock.Instructions[0])
+}
+
+func Test_CFG_ForLoopCountDown_Signed(t *testing.T) {
+	code := `main: () {
+		for i: i8 = 10; i > 0; i-- {
+			x: = i
+		}
+	}`
+	cfg := buildCFGFromCode(t, code)
+
+	// only unsigned 8-bit counters count down to zero
+	incBlock := findBlockByLabel(cfg, LabelForInc)
+	require.NotNil(t, incBlock)
+	assert.Contains(t, incBlock.Successors, findBlockByLabel(cfg, LabelForCond))
+}
+
 // ============================================================================
 // Select Statement Tests
 // ============================================================================
@@ -615,6 +754,144 @@ func Test_CFG_ReturnInBranch(t *testing.T) {
 	assert.Contains(t, cfg.Exit.Predecessors, mergeBlock)
 }
 
+func Test_CFG_NoReturnCall(t *testing.T) {
+	code := `main: (x: u8) u8 {
+		if x > 10 {
+			panic()
+			x = 42
+		}
+		ret x
+	}
+	@noreturn
+	panic: () {
+		@halt()
+	}`
+	cfg := buildCFGFromCode(t, code)
+
+	thenBlock := findBlockByLabel(cfg, LabelIfThen)
+	mergeBlock := findBlockByLabel(cfg, LabelIfMerge)
+	deadBlock := findBlockByLabel(cfg, LabelUnreachable)
+	require.NotNil(t, thenBlock)
+	require.NotNil(t, mergeBlock)
+	require.NotNil(t, deadBlock)
+
+	// the call does not flow anywhere, the code after it is unreachable
+	assert.Equal(t, 1, len(thenBlock.Instructions))
+	assert.Empty(t, thenBlock.Successors)
+	assert.Empty(t, deadBlock.Predecessors)
+	assert.Empty(t, deadBlock.Successors)
+	assert.Equal(t, 1, len(deadBlock.Instructions))
+	assert.NotContains(t, mergeBlock.Predecessors, deadBlock)
+
+	vrAlloc := NewVirtualRegisterAllocator()
+	selector := NewInstructionSelectorZ80(vrAlloc)
+	err := SelectInstructions([]*CFG{cfg}, vrAlloc, selector)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Z80Opcode{Z80_CALL_NN}, opcodesOf(thenBlock.MachineInstructions))
+	assert.Empty(t, deadBlock.MachineInstructions)
+}
+
+func Test_CFG_NoReturnFunction(t *testing.T) {
+	code := `@noreturn
+	reset: () {
+		x: u8 = 0
+		@poke(0x4000, x)
+		@halt()
+	}`
+	cfg := buildCFGFromCode(t, code)
+
+	vrAlloc := NewVirtualRegisterAllocator()
+	selector := NewInstructionSelectorZ80(vrAlloc)
+	err := SelectInstructions([]*CFG{cfg}, vrAlloc, selector)
+	require.NoError(t, err)
+
+	// no epilogue and no RET
+	assert.Empty(t, cfg.Exit.MachineInstructions)
+}
+
+func Test_CFG_ReturnPathsShareExit(t *testing.T) {
+	code := `main: (x: u8) u8 {
+		if x > 10 {
+			ret 42
+		}
+		ret x
+	}`
+	cfg := buildCFGFromCode(t, code)
+
+	thenBlock := findBlockByLabel(cfg, LabelIfThen)
+	mergeBlock := findBlockByLabel(cfg, LabelIfMerge)
+	require.NotNil(t, thenBlock)
+	require.NotNil(t, mergeBlock)
+
+	// both returns only flow to the single exit block
+	assert.Equal(t, []*BasicBlock{cfg.Exit}, thenBlock.Successors)
+	assert.Equal(t, []*BasicBlock{cfg.Exit}, mergeBlock.Successors)
+
+	vrAlloc := NewVirtualRegisterAllocator()
+	selector := NewInstructionSelectorZ80(vrAlloc)
+	err := SelectInstructions([]*CFG{cfg}, vrAlloc, selector)
+	require.NoError(t, err)
+
+	// both returns jump to the exit block
+	for _, block := range []*BasicBlock{thenBlock, mergeBlock} {
+		require.NotEmpty(t, block.MachineInstructions)
+		last := block.MachineInstructions[len(block.MachineInstructions)-1]
+		assert.Equal(t, Z80_JP_NN, last.(*machineInstructionZ80).opcode)
+		assert.Equal(t, []*BasicBlock{cfg.Exit}, last.GetTargetBlocks())
+	}
+
+	// RET is emitted once, in the exit block
+	rets := 0
+	for _, block := range cfg.Blocks {
+		for _, instr := range block.MachineInstructions {
+			if instr.(*machineInstructionZ80).opcode == Z80_RET {
+				rets++
+				assert.Equal(t, cfg.Exit, block)
+			}
+		}
+	}
+	assert.Equal(t, 1, rets)
+}
+
+func Test_CFG_ReturnInLoopBody(t *testing.T) {
+	code := `main: () u8 {
+		for i: = 0; i < 10; i + 1 {
+			ret i
+		}
+		ret 0
+	}`
+	cfg := buildCFGFromCode(t, code)
+
+	bodyBlock := findBlockByLabel(cfg, LabelForBody)
+	incBlock := findBlockByLabel(cfg, LabelForInc)
+	require.NotNil(t, bodyBlock)
+	require.NotNil(t, incBlock)
+
+	// the returning body does not continue to the increment
+	assert.Equal(t, []*BasicBlock{cfg.Exit}, bodyBlock.Successors)
+	assert.Empty(t, incBlock.Predecessors)
+}
+
+func Test_CFG_CyclicTreeIsBounded(t *testing.T) {
+	// a malformed tree: the if is nested in its own then-block
+	body := &zsm.SemBlock{}
+	ifStmt := &zsm.SemIf{ThenBlock: body}
+	body.Statements = []zsm.SemStatement{ifStmt}
+	fn := &zsm.SemFunctionDecl{Name: "cyclic", Body: body}
+
+	builder := NewCFGBuilder()
+	cfg, err := builder.BuildCFG(fn)
+	require.Error(t, err)
+	assert.Nil(t, cfg)
+	assert.Contains(t, err.Error(), "internal error: function 'cyclic' exceeds")
+	// the nesting that hit the bound still closes its merge blocks while unwinding
+	assert.LessOrEqual(t, len(builder.blocks), 2*maxBlocksPerFunction)
+
+	_, err = BuildCFGs(&zsm.SemCompilationUnit{Declarations: []zsm.SemDeclaration{fn}})
+	assert.Error(t, err)
+}
+
 // ============================================================================
 // Complex CFG Tests
 // ============================================================================
@@ -881,3 +1158,106 @@ func blockIDs(blocks []*BasicBlock) []int {
 	}
 	return ids
 }
+
+// ============================================================================
+// Critical Edge Splitting Tests
+// ============================================================================
+
+// Helper to build a diamond CFG: entry -> (left, right) -> merge
+// with an additional edge from left to right
+func buildDiamondCFG() (*CFG, []*BasicBlock) {
+	blocks := make([]*BasicBlock, 4)
+	for i := range blocks {
+		blocks[i] = newTestBlock()
+		blocks[i].ID = i
+		blocks[i].LabelID = -1
+	}
+	link := func(from, to *BasicBlock) {
+		from.Successors = append(from.Successors, to)
+		to.Predecessors = append(to.Predecessors, from)
+	}
+
+	entry, left, right, merge := blocks[0], blocks[1], blocks[2], blocks[3]
+	link(entry, left)
+	link(entry, right)
+	link(left, merge)
+	link(left, right)
+	link(right, merge)
+
+	return &CFG{Entry: entry, Blocks: blocks}, blocks
+}
+
+func Test_CFG_SplitCriticalEdges_Diamond(t *testing.T) {
+	cfg, blocks := buildDiamondCFG()
+	entry, left, right, merge := blocks[0], blocks[1], blocks[2], blocks[3]
+
+	// critical: entry->right, left->merge, left->right
+	count := cfg.SplitCriticalEdges()
+
+	assert.Equal(t, 3, count)
+	assert.Len(t, cfg.Blocks, 7)
+
+	// entry->left is not critical (left has a single predecessor)
+	assert.Equal(t, left, entry.Successors[0])
+
+	split := entry.Successors[1]
+	assert.Equal(t, LabelEdgeSplit, split.Label)
+	assert.Equal(t, []*BasicBlock{entry}, split.Predecessors)
+	assert.Equal(t, []*BasicBlock{right}, split.Successors)
+	assert.Empty(t, split.MachineInstructions)
+
+	for _, succ := range left.Successors {
+		assert.Equal(t, LabelEdgeSplit, succ.Label)
+	}
+	assert.Equal(t, merge, left.Successors[0].Successors[0])
+	assert.Equal(t, right, left.Successors[1].Successors[0])
+
+	// predecessor order is preserved
+	assert.Equal(t, []*BasicBlock{split, left.Successors[1]}, right.Predecessors)
+	assert.Equal(t, []*BasicBlock{left.Successors[0], right}, merge.Predecessors)
+
+	// no critical edges remain
+	assert.Equal(t, 0, cfg.SplitCriticalEdges())
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6}, blockIDs(cfg.Blocks))
+}
+
+func Test_CFG_SplitCriticalEdges_RetargetsBranches(t *testing.T) {
+	cfg, blocks := buildDiamondCFG()
+	entry, left, right := blocks[0], blocks[1], blocks[2]
+	entry.MachineInstructions = []MachineInstruction{
+		newJumpWithCondition(Cond_Z, left, right),
+	}
+
+	cfg.SplitCriticalEdges()
+
+	split := entry.Successors[1]
+	assert.Equal(t, []*BasicBlock{left, split}, entry.MachineInstructions[0].GetTargetBlocks())
+	require.Len(t, split.MachineInstructions, 1)
+	assert.Equal(t, []*BasicBlock{right}, split.MachineInstructions[0].GetTargetBlocks())
+}
+
+func Test_CFG_SplitCriticalEdges_IfWithoutElse(t *testing.T) {
+	code := `test: (x: u8) {
+		if x > 0 {
+			x = 1
+		}
+		x = 2
+	}`
+
+	cfg := buildCFGFromCode(t, code)
+	cond := findBlockByLabel(cfg, LabelFunction)
+	merge := findBlockByLabel(cfg, LabelIfMerge)
+	require.NotNil(t, cond)
+	require.NotNil(t, merge)
+	require.Len(t, cond.Successors, 2)
+	require.Equal(t, merge, cond.Successors[1])
+
+	count := cfg.SplitCriticalEdges()
+
+	assert.Equal(t, 1, count)
+	split := cond.Successors[1]
+	assert.Equal(t, LabelEdgeSplit, split.Label)
+	assert.Equal(t, []*BasicBlock{merge}, split.Successors)
+	assert.Contains(t, merge.Predecessors, split)
+	assert.NotContains(t, merge.Predecessors, cond)
+}
@@ -0,0 +1,146 @@
+package cfg
+
+// CoalesceCopies merges the source and destination VirtualRegisters of register copies (LD r, r')
+// when they do not interfere, and removes the copy: the value is produced directly where it is used.
+// The merged VirtualRegister is restricted to the registers allowed by both.
+// Liveness and interference are recomputed after each merge.
+// Returns the number of removed copies.
+func CoalesceCopies(cfg *CFG) int {
+	total := 0
+	for coalesceCopy(cfg) {
+		total++
+	}
+	return total
+}
+
+// coalesceCopy merges the registers of the first copy that can be coalesced
+// Returns false when no copy can be coalesced.
+func coalesceCopy(cfg *CFG) bool {
+	interference := BuildInterferenceGraph(cfg, ComputeLiveness(cfg))
+
+	for _, block := range cfg.Blocks {
+		for i, instr := range block.MachineInstructions {
+			dst, src, ok := copyRegisters(instr)
+			if !ok || interference.Interferes(dst.ID, src.ID) || interferesImplicitly(cfg, dst, src) {
+				continue
+			}
+			allowed, ok := intersectAllowedSets(dst.AllowedSet, src.AllowedSet)
+			if !ok {
+				continue
+			}
+
+			// keep the (variable) name for debugging, two variables are not merged
+			keep, drop := src, dst
+			if dst.Name != "" {
+				if src.Name != "" {
+					continue
+				}
+				keep, drop = dst, src
+			}
+
+			keep.AllowedSet = allowed
+			replaceRegister(cfg, drop, keep)
+			block.MachineInstructions = append(block.MachineInstructions[:i], block.MachineInstructions[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// copyRegisters returns the destination and source of a register-to-register copy
+// that can be coalesced: both unallocated candidates of the same size.
+func copyRegisters(instr MachineInstruction) (dst, src *VirtualRegister, ok bool) {
+	z80Instr, isZ80 := instr.(*machineInstructionZ80)
+	if !isZ80 || z80Instr.opcode != Z80_LD_R_R || len(z80Instr.operands) != 1 {
+		return nil, nil, false
+	}
+
+	dst, src = z80Instr.result, z80Instr.operands[0]
+	if dst == nil || src == nil || dst == src ||
+		dst.Type != CandidateRegister || src.Type != CandidateRegister || dst.Size != src.Size {
+		return nil, nil, false
+	}
+	return dst, src, true
+}
+
+// interferesImplicitly returns true if one register is defined while the other is only live
+// for the implicit read of a read-modify-write instruction (ADD A, r).
+// Liveness analysis does not track these reads of the result register.
+func interferesImplicitly(cfg *CFG, a, b *VirtualRegister) bool {
+	for _, block := range cfg.Blocks {
+		instructions := block.MachineInstructions
+		for i, instr := range instructions {
+			result := instr.GetResult()
+			if (result != a && result != b) || !readsResult(instr) {
+				continue
+			}
+			other := a
+			if result == a {
+				other = b
+			}
+			// the result is live back to its previous definition
+			for j := i - 1; j >= 0; j-- {
+				defined := instructions[j].GetResult()
+				if defined == result {
+					break
+				}
+				if defined == other {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// readsResult returns true if the instruction also reads its result register
+func readsResult(instr MachineInstruction) bool {
+	desc := descriptorOf(instr)
+	if desc == nil {
+		return false
+	}
+	for _, dep := range desc.Dependencies {
+		if dep.Access&AccessWrite != 0 {
+			return dep.Access == AccessReadWrite
+		}
+	}
+	return false
+}
+
+// intersectAllowedSets returns the registers allowed by both sets (an empty set allows any register)
+// Returns false when no register is allowed by both.
+func intersectAllowedSets(a, b []*Register) ([]*Register, bool) {
+	if len(a) == 0 {
+		return b, true
+	}
+	if len(b) == 0 {
+		return a, true
+	}
+
+	var allowed []*Register
+	for _, reg := range a {
+		for _, other := range b {
+			if reg == other {
+				allowed = append(allowed, reg)
+				break
+			}
+		}
+	}
+	return allowed, len(allowed) > 0
+}
+
+// replaceRegister rewrites all uses and definitions of a VirtualRegister in the function
+func replaceRegister(cfg *CFG, from, to *VirtualRegister) {
+	for _, block := range cfg.Blocks {
+		for _, instr := range block.MachineInstructions {
+			if instr.GetResult() == from {
+				instr.SetResult(to)
+			}
+			for i, operand := range instr.GetOperands() {
+				if operand == from {
+					instr.SetOperand(i, to)
+				}
+			}
+		}
+	}
+}
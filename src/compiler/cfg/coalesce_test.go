@@ -0,0 +1,88 @@
+package cfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CoalesceCopies_NonInterfering(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	x := vrAlloc.AllocateNamed("x", Z80Registers8)
+	vrA := vrAlloc.Allocate(Z80RegA)
+	result := vrAlloc.Allocate(Z80Registers8)
+	vrHL := vrAlloc.Allocate(Z80RegHL)
+
+	block := newTestBlock()
+	block.MachineInstructions = []MachineInstruction{
+		newInstruction(Z80_LD_R_R, vrA, x),
+		newInstruction(Z80_ADD_A_R, vrA, x),
+		// staging copy: vrA is not used after it
+		newInstruction(Z80_LD_R_R, result, vrA),
+		newInstruction(Z80_LD_HL_R, vrHL, result),
+	}
+	cfg := &CFG{Blocks: []*BasicBlock{block}, Entry: block}
+
+	coalesced := CoalesceCopies(cfg)
+
+	assert.Equal(t, 1, coalesced)
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_ADD_A_R, Z80_LD_HL_R}, opcodesOf(block.MachineInstructions))
+	// the use of the copy is rewritten to the source, which keeps its register
+	assert.Same(t, vrA, block.MachineInstructions[2].GetOperands()[0])
+	assert.Equal(t, Z80RegA, vrA.AllowedSet)
+}
+
+func Test_CoalesceCopies_Interfering(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	x := vrAlloc.AllocateNamed("x", Z80Registers8)
+	value := vrAlloc.Allocate(Z80Registers8)
+	copied := vrAlloc.Allocate(Z80Registers8)
+	vrA := vrAlloc.Allocate(Z80RegA)
+	vrHL := vrAlloc.Allocate(Z80RegHL)
+
+	block := newTestBlock()
+	block.MachineInstructions = []MachineInstruction{
+		newInstruction(Z80_LD_R_R, value, x),
+		newInstruction(Z80_LD_R_R, copied, value),
+		// both the copy and its source change after the copy
+		newInstruction(Z80_INC_R, copied, copied),
+		newInstruction(Z80_LD_R_R, vrA, value),
+		newInstruction(Z80_ADD_A_R, vrA, copied),
+		newInstruction(Z80_LD_HL_R, vrHL, vrA),
+	}
+	cfg := &CFG{Blocks: []*BasicBlock{block}, Entry: block}
+
+	coalesced := CoalesceCopies(cfg)
+
+	// x => value => vrA are coalesced, value => copied is not (both live at the ADD)
+	assert.Equal(t, 2, coalesced)
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_INC_R, Z80_ADD_A_R, Z80_LD_HL_R}, opcodesOf(block.MachineInstructions))
+	assert.Same(t, copied, block.MachineInstructions[0].GetResult())
+	assert.Same(t, x, block.MachineInstructions[0].GetOperands()[0])
+	assert.Same(t, x, block.MachineInstructions[2].GetResult())
+	assert.Equal(t, Z80RegA, x.AllowedSet)
+}
+
+func Test_CoalesceCopies_IncompatibleRegisters(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	x := vrAlloc.AllocateNamed("x", Z80Registers8)
+	vrA := vrAlloc.Allocate(Z80RegA)
+	vrL := vrAlloc.Allocate([]*Register{&RegL})
+	vrHL := vrAlloc.Allocate(Z80RegHL)
+
+	block := newTestBlock()
+	block.MachineInstructions = []MachineInstruction{
+		newInstruction(Z80_LD_R_R, vrA, x),
+		newInstruction(Z80_ADD_A_R, vrA, x),
+		// A and L cannot be the same register
+		newInstruction(Z80_LD_R_R, vrL, vrA),
+		newInstruction(Z80_LD_HL_R, vrHL, vrL),
+	}
+	cfg := &CFG{Blocks: []*BasicBlock{block}, Entry: block}
+
+	assert.Equal(t, 0, CoalesceCopies(cfg))
+	assert.Len(t, block.MachineInstructions, 4)
+}
@@ -0,0 +1,83 @@
+package cfg
+
+// immediateForms maps register-operand opcodes to their immediate-operand variant
+var immediateForms = map[Z80Opcode]Z80Opcode{
+	Z80_ADD_A_R: Z80_ADD_A_N,
+	Z80_ADC_A_R: Z80_ADC_A_N,
+	Z80_SUB_R:   Z80_SUB_N,
+	Z80_SBC_A_R: Z80_SBC_A_N,
+	Z80_AND_R:   Z80_AND_N,
+	Z80_OR_R:    Z80_OR_N,
+	Z80_XOR_R:   Z80_XOR_N,
+	Z80_CP_R:    Z80_CP_N,
+}
+
+// PropagateConstants rewrites instructions that use a VirtualRegister known to hold a constant
+// into their immediate form (e.g. LD r, 5; ADD A, r => ADD A, 5).
+// A VirtualRegister holds a constant when its only definition in the function is LD r, N.
+// Returns the number of rewritten instructions.
+func PropagateConstants(cfg *CFG) int {
+	constants := findConstantRegisters(cfg)
+
+	rewritten := 0
+	for _, block := range cfg.Blocks {
+		for _, instr := range block.MachineInstructions {
+			z80Instr, ok := instr.(*machineInstructionZ80)
+			if !ok || len(z80Instr.operands) != 1 {
+				continue
+			}
+			immOpcode, ok := immediateForms[z80Instr.opcode]
+			if !ok {
+				continue
+			}
+			if _, ok := Z80InstrDescriptors[immOpcode]; !ok {
+				continue
+			}
+
+			operand := z80Instr.operands[0]
+			if operand.Type != ImmediateValue {
+				imm, ok := constants[operand.ID]
+				if !ok {
+					continue
+				}
+				operand = imm
+			}
+
+			z80Instr.opcode = immOpcode
+			z80Instr.operands[0] = operand
+			rewritten++
+		}
+	}
+	return rewritten
+}
+
+// findConstantRegisters returns the VirtualRegisters (by ID) that are defined exactly once
+// in the function by loading an immediate value, mapped to that immediate.
+func findConstantRegisters(cfg *CFG) map[int]*VirtualRegister {
+	definitions := make(map[int]int)
+	constants := make(map[int]*VirtualRegister)
+
+	for _, block := range cfg.Blocks {
+		for _, instr := range block.MachineInstructions {
+			result := instr.GetResult()
+			if result == nil || !shouldTrackForLiveness(result) {
+				continue
+			}
+			definitions[result.ID]++
+
+			z80Instr, ok := instr.(*machineInstructionZ80)
+			if ok && z80Instr.opcode == Z80_LD_R_N &&
+				len(z80Instr.operands) == 1 && z80Instr.operands[0].Type == ImmediateValue {
+				constants[result.ID] = z80Instr.operands[0]
+			}
+		}
+	}
+
+	// any redefinition invalidates the constant
+	for vrID := range constants {
+		if definitions[vrID] != 1 {
+			delete(constants, vrID)
+		}
+	}
+	return constants
+}
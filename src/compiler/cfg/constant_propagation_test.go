@@ -0,0 +1,79 @@
+package cfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper to build a single block function: result = x + c with c loaded as constant
+func newConstantAddCFG(vrAlloc *VirtualRegisterAllocator) (*CFG, *VirtualRegister) {
+	x := vrAlloc.AllocateNamed("x", Z80Registers8)
+	c := vrAlloc.Allocate(Z80Registers8)
+	vrA := vrAlloc.Allocate(Z80RegA)
+	result := vrAlloc.Allocate(Z80Registers8)
+
+	block := newTestBlock()
+	block.MachineInstructions = []MachineInstruction{
+		newInstruction(Z80_LD_R_N, c, vrAlloc.AllocateImmediate(5, Bits8)),
+		newInstruction(Z80_LD_R_R, vrA, x),
+		newInstruction(Z80_ADD_A_R, vrA, c),
+		newInstruction(Z80_LD_R_R, result, vrA),
+	}
+
+	return &CFG{
+		FunctionName: "addConst",
+		Blocks:       []*BasicBlock{block},
+		Entry:        block,
+	}, c
+}
+
+func Test_PropagateConstants_AddImmediate(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+	fnCFG, _ := newConstantAddCFG(vrAlloc)
+
+	count := PropagateConstants(fnCFG)
+
+	assert.Equal(t, 1, count)
+	block := fnCFG.Entry
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_N, Z80_LD_R_R, Z80_ADD_A_N, Z80_LD_R_R},
+		opcodesOf(block.MachineInstructions))
+
+	add := block.MachineInstructions[2]
+	require.Len(t, add.GetOperands(), 1)
+	assert.Equal(t, ImmediateValue, add.GetOperands()[0].Type)
+	assert.Equal(t, int32(5), add.GetOperands()[0].Value)
+}
+
+func Test_PropagateConstants_Redefined(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+	fnCFG, c := newConstantAddCFG(vrAlloc)
+
+	// c is assigned again in another block: no longer a constant
+	other := newTestBlock()
+	other.ID = 1
+	other.MachineInstructions = []MachineInstruction{
+		newInstruction(Z80_LD_R_R, c, vrAlloc.Allocate(Z80Registers8)),
+	}
+	fnCFG.Blocks = append(fnCFG.Blocks, other)
+
+	count := PropagateConstants(fnCFG)
+
+	assert.Equal(t, 0, count)
+	assert.Equal(t, Z80_ADD_A_R, fnCFG.Entry.MachineInstructions[2].(*machineInstructionZ80).opcode)
+}
+
+func Test_PropagateConstants_ImmediateOperand(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	x := vrAlloc.Allocate(Z80Registers8)
+	one := vrAlloc.AllocateImmediate(1, Bits8)
+	_, err := selector.SelectSubtract(x, one)
+	require.NoError(t, err)
+
+	count := PropagateConstants(&CFG{Blocks: []*BasicBlock{block}, Entry: block})
+
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_SUB_N, Z80_LD_R_R}, opcodesOf(block.MachineInstructions))
+}
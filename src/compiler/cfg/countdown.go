@@ -0,0 +1,111 @@
+package cfg
+
+import (
+	"fmt"
+	"zenith/compiler/parser"
+	"zenith/compiler/zsm"
+)
+
+// maxCountDownLoopSize is the largest loop (in bytes) that is closed with a relative jump (DJNZ).
+// The relative jump reaches 126 bytes back, the margin leaves room for spill code added by register allocation.
+const maxCountDownLoopSize = 96
+
+// countDownBranch is the back edge of a loop that counts an 8-bit variable down to zero:
+// the decrement of the counter followed by the loop condition (counter > 0 or counter <> 0).
+// It is selected as a single decrement-and-jump-if-not-zero.
+type countDownBranch struct {
+	Counter *zsm.Symbol
+}
+
+func (n *countDownBranch) ASTNode() parser.ParserNode { return nil }
+
+// matchCountDown checks if the decrement and loop condition count the same variable down to zero
+// Returns nil if they do not.
+func matchCountDown(decrement zsm.SemStatement, condition zsm.SemExpression) *countDownBranch {
+	counter := decrementedSymbol(decrement)
+	if counter == nil || counter != countedSymbol(condition) {
+		return nil
+	}
+	return &countDownBranch{Counter: counter}
+}
+
+// decrementedSymbol returns the unsigned 8-bit variable decremented by one
+// by the statement (counter-- or counter = counter - 1)
+func decrementedSymbol(stmt zsm.SemStatement) *zsm.Symbol {
+	switch s := stmt.(type) {
+	case *zsm.SemExpressionStmt:
+		if op, ok := s.Expression.(*zsm.SemUnaryOp); ok && op.Op == zsm.OpDecrement {
+			return counterSymbol(op.Operand)
+		}
+	case *zsm.SemAssignment:
+		op, ok := s.Value.(*zsm.SemBinaryOp)
+		if ok && op.Op == zsm.OpSubtract && isConstant(op.Right, 1) {
+			if counter := counterSymbol(op.Left); counter == s.Target {
+				return counter
+			}
+		}
+	}
+	return nil
+}
+
+// countedSymbol returns the unsigned 8-bit variable the condition compares to zero
+// (counter > 0 or counter <> 0, both are true while the counter is not zero)
+func countedSymbol(condition zsm.SemExpression) *zsm.Symbol {
+	op, ok := condition.(*zsm.SemBinaryOp)
+	if !ok || (op.Op != zsm.OpGreaterThan && op.Op != zsm.OpNotEqual) || !isConstant(op.Right, 0) {
+		return nil
+	}
+	return counterSymbol(op.Left)
+}
+
+// counterSymbol returns the variable if the expression is a u8 variable
+func counterSymbol(expr zsm.SemExpression) *zsm.Symbol {
+	ref, ok := expr.(*zsm.SemSymbolRef)
+	if !ok || ref.Symbol.Kind != zsm.SymbolVariable || ref.Symbol.Type != zsm.U8Type {
+		return nil
+	}
+	return ref.Symbol
+}
+
+// isConstant checks if the expression is the constant integer value
+func isConstant(expr zsm.SemExpression, value int) bool {
+	constant, ok := zsm.ConstantValue(expr)
+	return ok && constant == value
+}
+
+// selectCountDownBranch decrements the counter and jumps back to the loop body while it is not zero
+// Successors: [0] = body (back edge), [1] = exit
+func (ctx *InstructionSelectionContext) selectCountDownBranch(branch *countDownBranch, block *BasicBlock) error {
+	counterVR, ok := ctx.symbolToVReg[branch.Counter]
+	if !ok {
+		return fmt.Errorf("undefined variable: %s", branch.Counter.Name)
+	}
+	body := block.Successors[0]
+	branchCtx := NewExprContextBranch(body, block.Successors[1])
+	return ctx.selector.SelectDecrementJumpNotZero(branchCtx, counterVR, ctx.isNearBackJump(body, block))
+}
+
+// isNearBackJump checks if the code from the target block up to the end of the block
+// (the blocks in code order) fits a relative jump back
+func (ctx *InstructionSelectionContext) isNearBackJump(target, block *BasicBlock) bool {
+	size := 0
+	counting := false
+	for _, b := range ctx.currentCFG.Blocks {
+		counting = counting || b == target
+		if !counting {
+			continue
+		}
+		for _, instr := range b.MachineInstructions {
+			instrSize, ok := encodedSize(instr)
+			if !ok {
+				// inline assembly: size unknown
+				return false
+			}
+			size += instrSize
+		}
+		if b == block {
+			return size <= maxCountDownLoopSize
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package cfg
+
+// EstimateCycles returns the minimum and maximum number of cycles (T-states)
+// of a call to the function: the shortest and longest path from the entry to the exit block.
+// Loop bodies are counted once, the estimate is per iteration (loop back edges are not followed).
+// Conditional branches that take extra cycles when taken (JR cc, CALL cc, RET cc, DJNZ)
+// count as not taken for the minimum and as taken for the maximum.
+func (cfg *CFG) EstimateCycles() (minCycles, maxCycles int) {
+	if cfg.Entry == nil {
+		return 0, 0
+	}
+
+	loops := cfg.FindLoops()
+	isBackEdge := func(from, to *BasicBlock) bool {
+		for _, loop := range loops {
+			if loop.IsBackEdge(from, to) {
+				return true
+			}
+		}
+		return false
+	}
+
+	type pathCycles struct{ min, max int }
+	paths := make(map[*BasicBlock]pathCycles)
+	visiting := make(map[*BasicBlock]bool)
+
+	var estimate func(block *BasicBlock) pathCycles
+	estimate = func(block *BasicBlock) pathCycles {
+		if path, ok := paths[block]; ok {
+			return path
+		}
+		visiting[block] = true
+
+		blockMin, blockMax := blockCycles(block)
+		var next *pathCycles
+		for _, succ := range block.Successors {
+			if visiting[succ] || isBackEdge(block, succ) {
+				continue
+			}
+			path := estimate(succ)
+			if next == nil {
+				next = &pathCycles{path.min, path.max}
+				continue
+			}
+			next.min = min(next.min, path.min)
+			next.max = max(next.max, path.max)
+		}
+
+		path := pathCycles{blockMin, blockMax}
+		if next != nil {
+			path.min += next.min
+			path.max += next.max
+		}
+
+		visiting[block] = false
+		paths[block] = path
+		return path
+	}
+
+	path := estimate(cfg.Entry)
+	return path.min, path.max
+}
+
+// blockCycles returns the cycles of the block's machine instructions,
+// with conditional branches not taken (min) and taken (max).
+func blockCycles(block *BasicBlock) (minCycles, maxCycles int) {
+	for _, instr := range block.MachineInstructions {
+		desc := descriptorOf(instr)
+		if desc == nil {
+			continue
+		}
+		minCycles += int(desc.Cycles)
+		maxCycles += int(desc.Cycles) + int(desc.CyclesTaken)
+	}
+	return minCycles, maxCycles
+}
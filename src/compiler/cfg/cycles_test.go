@@ -0,0 +1,77 @@
+package cfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// selectCFGFromCode builds the CFG of the first function and selects its instructions
+func selectCFGFromCode(t *testing.T, code string) *CFG {
+	fnCFG := buildCFGFromCode(t, code)
+	vrAlloc := NewVirtualRegisterAllocator()
+	ctx := NewInstructionSelectionContext(NewInstructionSelectorZ80(vrAlloc), vrAlloc)
+	require.NoError(t, ctx.selectCFG(fnCFG))
+	return fnCFG
+}
+
+func Test_EstimateCycles_StraightLine(t *testing.T) {
+	fnCFG := selectCFGFromCode(t, `add: (a: u8, b: u8) u8 {
+		ret a + b
+	}`)
+
+	expected := 0
+	for _, instr := range fnCFG.CodeInstructions() {
+		expected += int(instr.GetCost().Cycles)
+	}
+
+	minCycles, maxCycles := fnCFG.EstimateCycles()
+	assert.Greater(t, expected, 0)
+	assert.Equal(t, expected, minCycles)
+	assert.Equal(t, expected, maxCycles)
+}
+
+func Test_EstimateCycles_Conditional(t *testing.T) {
+	fnCFG := selectCFGFromCode(t, `clamp: (a: u8) u8 {
+		if a > 100 {
+			b: = a * 3
+			ret b + 1
+		}
+		ret a
+	}`)
+
+	minCycles, maxCycles := fnCFG.EstimateCycles()
+	assert.Greater(t, minCycles, 0)
+	assert.Less(t, minCycles, maxCycles)
+}
+
+func Test_EstimateCycles_LoopPerIteration(t *testing.T) {
+	fnCFG := selectCFGFromCode(t, `count: () {
+		for i: = 10; i > 0; i-- {
+			x: = i
+		}
+	}`)
+
+	// the back edge is not followed: the body counts once
+	minCycles, maxCycles := fnCFG.EstimateCycles()
+	assert.Greater(t, minCycles, 0)
+	assert.LessOrEqual(t, minCycles, maxCycles)
+
+	body := findBlockByLabel(fnCFG, LabelForBody)
+	require.NotNil(t, body)
+	_, bodyCycles := blockCycles(body)
+	assert.GreaterOrEqual(t, maxCycles, bodyCycles)
+}
+
+func Test_BlockCycles_BranchTaken(t *testing.T) {
+	block := newTestBlock()
+	block.MachineInstructions = []MachineInstruction{
+		newInstruction(Z80_DJNZ_E, nil, nil),
+	}
+
+	// DJNZ: 8 cycles falling through, 13 when taken
+	minCycles, maxCycles := blockCycles(block)
+	assert.Equal(t, 8, minCycles)
+	assert.Equal(t, 13, maxCycles)
+}
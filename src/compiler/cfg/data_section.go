@@ -0,0 +1,290 @@
+package cfg
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"zenith/compiler/zsm"
+)
+
+// dataBytesPerLine is the number of bytes emitted per .db directive
+const dataBytesPerLine = 16
+
+// DataItem is a labeled block of constant bytes in the data section
+type DataItem struct {
+	Label string
+	Bytes []byte
+	// Addresses are the function addresses in the bytes, filled in after layout
+	Addresses []DataAddress
+	// Variable is the global variable stored in the item, nil for other data
+	Variable *zsm.Symbol
+	// JumpTable is the table of block addresses stored in the item, filled in after layout
+	JumpTable *JumpTable
+	// Written is set when the code writes the variable: the variable lives in RAM (under the label)
+	// and the bytes are its initial value in ROM (under the init label), copied at startup
+	Written bool
+}
+
+// InitLabel returns the label of the initial value of a written variable
+func (item *DataItem) InitLabel() string {
+	return item.Label + ".init"
+}
+
+// DataAddress is a 2-byte (little-endian) function address in a data item
+type DataAddress struct {
+	Offset   int
+	Function string
+}
+
+// DataSection collects the constant data (string literals) the code refers to by label.
+// Identical data is stored only once.
+type DataSection struct {
+	Items []*DataItem
+	// StringFormat is how string literals are stored (terminator or length prefix)
+	StringFormat zsm.StringFormat
+	byContent    map[string]*DataItem
+}
+
+// NewDataSection creates an empty data section
+func NewDataSection() *DataSection {
+	return &DataSection{
+		Items:     []*DataItem{},
+		byContent: make(map[string]*DataItem),
+	}
+}
+
+// Add stores the bytes in the data section and returns their label
+func (d *DataSection) Add(data []byte) string {
+	if item, ok := d.byContent[string(data)]; ok {
+		return item.Label
+	}
+
+	item := &DataItem{
+		Label: fmt.Sprintf("data.%d", len(d.Items)),
+		Bytes: data,
+	}
+	d.Items = append(d.Items, item)
+	d.byContent[string(data)] = item
+	return item.Label
+}
+
+// AddString stores the characters of a string in the string format and returns their label
+func (d *DataSection) AddString(chars []byte) string {
+	return d.Add(d.StringFormat.Encode(chars))
+}
+
+// AddJumpTable stores the jump table under its label, room for a 2-byte block address per entry
+func (d *DataSection) AddJumpTable(table *JumpTable) {
+	d.Items = append(d.Items, &DataItem{
+		Label:     table.Label,
+		Bytes:     make([]byte, 2*len(table.Targets)),
+		JumpTable: table,
+	})
+}
+
+// AddVariables stores the global variables with a constant initializer under the variable name:
+// the value is in the image, no code initializes it. Numbers, bools, arrays of them (tables) and bytes are stored,
+// the '@addressof' values are filled in by ResolveAddresses.
+// Variables without a constant initializer are not stored.
+func (d *DataSection) AddVariables(declarations []zsm.SemDeclaration) error {
+	for _, decl := range declarations {
+		varDecl, ok := decl.(*zsm.SemVariableDecl)
+		if !ok || varDecl.Initializer == nil || varDecl.TypeInfo == nil {
+			continue
+		}
+		item, ok, err := newVariableItem(varDecl)
+		if err != nil {
+			return err
+		}
+		if ok {
+			d.Items = append(d.Items, item)
+		}
+	}
+	return nil
+}
+
+// newVariableItem encodes the initial value of the variable (little-endian), leaving room for the function addresses.
+// Returns false when the initializer is not constant.
+func newVariableItem(varDecl *zsm.SemVariableDecl) (*DataItem, bool, error) {
+	name := varDecl.Symbol.Name
+	elements := []zsm.SemExpression{varDecl.Initializer}
+	elementSize := int(varDecl.TypeInfo.Size())
+	if initializer, ok := varDecl.Initializer.(*zsm.SemArrayInitializer); ok {
+		elements = initializer.Elements
+		elementSize = 1
+		if arrayType, ok := initializer.Type().(*zsm.ArrayType); ok && arrayType.ElementType() != nil {
+			elementSize = int(arrayType.ElementType().Size())
+		}
+	}
+
+	// embedded bytes ('@include_bin', '@db') are stored as they are
+	if constant, ok := varDecl.Initializer.(*zsm.SemConstant); ok {
+		if data, ok := constant.Value.([]byte); ok {
+			return &DataItem{Label: name, Bytes: data, Variable: varDecl.Symbol}, true, nil
+		}
+	}
+
+	item := &DataItem{Label: name, Bytes: []byte{}, Variable: varDecl.Symbol}
+	for _, element := range elements {
+		if function, ok := zsm.AddressOfFunction(element); ok {
+			if elementSize != 2 {
+				return nil, false, fmt.Errorf("address of '%s' does not fit '%s'", function, name)
+			}
+			item.Addresses = append(item.Addresses, DataAddress{Offset: len(item.Bytes), Function: function})
+			item.Bytes = append(item.Bytes, 0, 0)
+			continue
+		}
+
+		value, ok := zsm.ConstantValue(element)
+		if !ok {
+			return nil, false, nil
+		}
+		number, ok := value.(int)
+		if !ok {
+			number = zsm.BoolValue(value.(bool))
+		}
+		for i := range elementSize {
+			item.Bytes = append(item.Bytes, byte(number>>(8*i)))
+		}
+	}
+	return item, true, nil
+}
+
+// ResolveAddresses fills in the function addresses and the jump table block addresses of the data items
+// from the code layout
+func (d *DataSection) ResolveAddresses(layouts []*FunctionLayout) error {
+	addresses := make(map[string]uint16, len(layouts))
+	blockAddresses := make(map[*BasicBlock]uint16)
+	for _, layout := range layouts {
+		addresses[layout.CFG.FunctionName] = layout.Address
+		instructions := layout.CFG.CodeInstructions()
+		starts := layout.CFG.blockStarts()
+		for block, offset := range layout.CFG.blockOffsets() {
+			// the address of a block after inline assembly is not known
+			if !hasUnsized(instructions, 0, starts[block]) {
+				blockAddresses[block] = layout.Address + uint16(offset)
+			}
+		}
+	}
+
+	for _, item := range d.Items {
+		for _, ref := range item.Addresses {
+			address, ok := addresses[ref.Function]
+			if !ok {
+				return fmt.Errorf("data '%s' refers to function '%s' that is not compiled", item.Label, ref.Function)
+			}
+			item.Bytes[ref.Offset] = byte(address)
+			item.Bytes[ref.Offset+1] = byte(address >> 8)
+		}
+		if item.JumpTable == nil {
+			continue
+		}
+		for i, target := range item.JumpTable.Targets {
+			address, ok := blockAddresses[target]
+			if !ok {
+				return fmt.Errorf("jump table '%s' refers to a block without a known address", item.Label)
+			}
+			item.Bytes[2*i] = byte(address)
+			item.Bytes[2*i+1] = byte(address >> 8)
+		}
+	}
+	return nil
+}
+
+// Find returns the data item with the label or nil if not found
+func (d *DataSection) Find(label string) *DataItem {
+	for _, item := range d.Items {
+		if item.Label == label {
+			return item
+		}
+	}
+	return nil
+}
+
+// FindVariable returns the data item that stores the global variable or nil if it is not stored
+func (d *DataSection) FindVariable(symbol *zsm.Symbol) *DataItem {
+	for _, item := range d.Items {
+		if item.Variable == symbol {
+			return item
+		}
+	}
+	return nil
+}
+
+// WrittenVariables returns the items of the global variables the code writes, in the order of their RAM copies
+func (d *DataSection) WrittenVariables() []*DataItem {
+	var written []*DataItem
+	for _, item := range d.Items {
+		if item.Written {
+			written = append(written, item)
+		}
+	}
+	return written
+}
+
+// VariablesSize returns the number of bytes of the RAM copies of the written variables
+func (d *DataSection) VariablesSize() int {
+	size := 0
+	for _, item := range d.WrittenVariables() {
+		size += len(item.Bytes)
+	}
+	return size
+}
+
+// Size returns the number of bytes in the data section
+func (d *DataSection) Size() int {
+	size := 0
+	for _, item := range d.Items {
+		size += len(item.Bytes)
+	}
+	return size
+}
+
+// Emit writes the data section as labeled .db directives (placed after the code).
+// The initial values of the written variables follow the other data, one after the other (copied in one block).
+func (d *DataSection) Emit(w io.Writer) error {
+	var sb strings.Builder
+	for _, item := range d.emitOrder() {
+		label := item.Label
+		if item.Written {
+			label = item.InitLabel()
+		}
+		sb.WriteString(label)
+		sb.WriteString(":\n")
+		for start := 0; start < len(item.Bytes); start += dataBytesPerLine {
+			end := min(start+dataBytesPerLine, len(item.Bytes))
+			values := make([]string, 0, end-start)
+			for _, b := range item.Bytes[start:end] {
+				values = append(values, fmt.Sprintf("0x%02X", b))
+			}
+			sb.WriteString("    .db ")
+			sb.WriteString(strings.Join(values, ", "))
+			sb.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// emitOrder returns the items in the order they are emitted: the initial values of the written variables last
+func (d *DataSection) emitOrder() []*DataItem {
+	items := make([]*DataItem, 0, len(d.Items))
+	for _, item := range d.Items {
+		if !item.Written {
+			items = append(items, item)
+		}
+	}
+	return append(items, d.WrittenVariables()...)
+}
+
+// EmitVariables writes the RAM copies of the written variables as labeled .ds directives
+func (d *DataSection) EmitVariables(w io.Writer) error {
+	var sb strings.Builder
+	for _, item := range d.WrittenVariables() {
+		sb.WriteString(fmt.Sprintf("%s:\n    .ds %d\n", item.Label, len(item.Bytes)))
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,275 @@
+package cfg
+
+import (
+	"strings"
+	"testing"
+	"zenith/compiler/zsm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DataSection_Add(t *testing.T) {
+	data := NewDataSection()
+
+	hi := data.Add([]byte("hi"))
+	bye := data.Add([]byte("bye"))
+
+	assert.Equal(t, "data.0", hi)
+	assert.Equal(t, "data.1", bye)
+	require.Len(t, data.Items, 2)
+	assert.Equal(t, []byte("bye"), data.Find(bye).Bytes)
+	assert.Nil(t, data.Find("data.2"))
+}
+
+func Test_DataSection_Add_Deduplicates(t *testing.T) {
+	data := NewDataSection()
+
+	first := data.Add([]byte("hi"))
+	second := data.Add([]byte("hi"))
+
+	assert.Equal(t, first, second)
+	assert.Len(t, data.Items, 1)
+}
+
+func Test_DataSection_Emit(t *testing.T) {
+	data := NewDataSection()
+	data.Add([]byte("hi"))
+	data.Add([]byte("0123456789ABCDEFG"))
+
+	var sb strings.Builder
+	require.NoError(t, data.Emit(&sb))
+
+	expected := "data.0:\n" +
+		"    .db 0x68, 0x69\n" +
+		"data.1:\n" +
+		"    .db 0x30, 0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46\n" +
+		"    .db 0x47\n"
+	assert.Equal(t, expected, sb.String())
+}
+
+func Test_InstructionSelection_StringLiteralData(t *testing.T) {
+	code := `main: () {
+		msg: = "hi"
+	}`
+	fnCFG := buildCFGFromCode(t, code)
+	vrAlloc := NewVirtualRegisterAllocator()
+	data := NewDataSection()
+
+	err := SelectInstructionsWithData([]*CFG{fnCFG}, vrAlloc, NewInstructionSelectorZ80(vrAlloc), data)
+	require.NoError(t, err)
+
+	require.Len(t, data.Items, 1)
+	assert.Equal(t, "data.0", data.Items[0].Label)
+	// null-terminated by default
+	assert.Equal(t, []byte{0x68, 0x69, 0x00}, data.Items[0].Bytes)
+
+	// the code loads the address of the label
+	var loadsLabel bool
+	for _, instr := range fnCFG.GetAllInstructions() {
+		z80Instr := instr.(*machineInstructionZ80)
+		if z80Instr.opcode == Z80_LD_RR_NN && z80Instr.comment == "data.0" {
+			loadsLabel = true
+		}
+	}
+	assert.True(t, loadsLabel)
+}
+
+func Test_InstructionSelection_AddressOf(t *testing.T) {
+	code := `main: () {
+		handler: = @addressof(main)
+	}`
+	fnCFG := buildCFGFromCode(t, code)
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	err := SelectInstructions([]*CFG{fnCFG}, vrAlloc, NewInstructionSelectorZ80(vrAlloc))
+	require.NoError(t, err)
+
+	// the function label is resolved when the code is laid out
+	var loadsLabel bool
+	for _, instr := range fnCFG.GetAllInstructions() {
+		z80Instr := instr.(*machineInstructionZ80)
+		if z80Instr.opcode == Z80_LD_RR_NN && z80Instr.comment == "main" {
+			loadsLabel = true
+		}
+	}
+	assert.True(t, loadsLabel)
+}
+
+func Test_DataSection_AddString(t *testing.T) {
+	tests := []struct {
+		format   zsm.StringFormat
+		expected []byte
+	}{
+		{zsm.StringNullTerminated, []byte{0x68, 0x69, 0x00}},
+		{zsm.StringLengthPrefixed, []byte{0x02, 0x68, 0x69}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			data := NewDataSection()
+			data.StringFormat = tt.format
+
+			label := data.AddString([]byte("hi"))
+			assert.Equal(t, tt.expected, data.Find(label).Bytes)
+		})
+	}
+}
+
+// newAddressOf creates '@addressof(function)'
+func newAddressOf(function string) *zsm.SemFunctionCall {
+	return &zsm.SemFunctionCall{
+		Function:  &zsm.Symbol{Name: "@addressof"},
+		Arguments: []zsm.SemExpression{&zsm.SemSymbolRef{Symbol: &zsm.Symbol{Name: function}}},
+		TypeInfo:  u16Type(),
+	}
+}
+
+// newTableDecl creates a global array variable with the initializer elements
+func newTableDecl(name string, elementType zsm.Type, elements ...zsm.SemExpression) *zsm.SemVariableDecl {
+	arrayType := zsm.NewArrayType(elementType, uint16(len(elements)))
+	return &zsm.SemVariableDecl{
+		Symbol:      &zsm.Symbol{Name: name, Type: arrayType},
+		Initializer: &zsm.SemArrayInitializer{Elements: elements, TypeInfo: arrayType},
+		TypeInfo:    arrayType,
+	}
+}
+
+func Test_DataSection_AddVariables(t *testing.T) {
+	data := NewDataSection()
+	declarations := []zsm.SemDeclaration{
+		&zsm.SemVariableDecl{
+			Symbol:      &zsm.Symbol{Name: "counter", Type: u16Type()},
+			Initializer: newSemConstant(300, u16Type()),
+			TypeInfo:    u16Type(),
+		},
+		&zsm.SemVariableDecl{
+			Symbol:      &zsm.Symbol{Name: "enabled", Type: zsm.BitType},
+			Initializer: newSemConstant(true, zsm.BitType),
+			TypeInfo:    zsm.BitType,
+		},
+		// no initializer: not stored
+		&zsm.SemVariableDecl{
+			Symbol:   &zsm.Symbol{Name: "total", Type: u8Type()},
+			TypeInfo: u8Type(),
+		},
+	}
+
+	require.NoError(t, data.AddVariables(declarations))
+
+	require.Len(t, data.Items, 2)
+	assert.Equal(t, []byte{0x2C, 0x01}, data.Find("counter").Bytes)
+	assert.Equal(t, []byte{0x01}, data.Find("enabled").Bytes)
+}
+
+func Test_DataSection_AddVariables_NotConstant(t *testing.T) {
+	data := NewDataSection()
+	count := &zsm.Symbol{Name: "count", Type: u8Type()}
+	declarations := []zsm.SemDeclaration{
+		&zsm.SemVariableDecl{
+			Symbol:      &zsm.Symbol{Name: "next", Type: u8Type()},
+			Initializer: newSemBinaryOp(zsm.OpAdd, &zsm.SemSymbolRef{Symbol: count}, newSemConstant(1, u8Type()), u8Type()),
+			TypeInfo:    u8Type(),
+		},
+	}
+
+	require.NoError(t, data.AddVariables(declarations))
+
+	assert.Empty(t, data.Items)
+}
+
+func Test_DataSection_AddVariables_Tables(t *testing.T) {
+	data := NewDataSection()
+	declarations := []zsm.SemDeclaration{
+		newTableDecl("bytes", u8Type(), newSemConstant(1, u8Type()), newSemConstant(0xFF, u8Type())),
+		newTableDecl("words", u16Type(), newSemConstant(0x1234, u16Type())),
+	}
+
+	require.NoError(t, data.AddVariables(declarations))
+
+	assert.Equal(t, []byte{0x01, 0xFF}, data.Find("bytes").Bytes)
+	assert.Equal(t, []byte{0x34, 0x12}, data.Find("words").Bytes)
+}
+
+func Test_DataSection_AddVariables_Bytes(t *testing.T) {
+	data := NewDataSection()
+	bytesType := zsm.NewArrayType(u8Type(), 3)
+	declarations := []zsm.SemDeclaration{
+		&zsm.SemVariableDecl{
+			Symbol:      &zsm.Symbol{Name: "sine", Type: bytesType},
+			Initializer: newSemConstant([]byte{0x00, 0x7F, 0x81}, bytesType),
+			TypeInfo:    bytesType,
+		},
+	}
+
+	require.NoError(t, data.AddVariables(declarations))
+
+	require.Len(t, data.Items, 1)
+	assert.Equal(t, []byte{0x00, 0x7F, 0x81}, data.Find("sine").Bytes)
+}
+
+func Test_DataSection_ResolveAddresses(t *testing.T) {
+	data := NewDataSection()
+	table := newTableDecl("table", u16Type(), newAddressOf("f"), newSemConstant(0, u16Type()), newAddressOf("g"))
+	require.NoError(t, data.AddVariables([]zsm.SemDeclaration{table}))
+	layouts, err := LayoutFunctions([]*CFG{newLayoutCFG("f", nil, 3), newLayoutCFG("g", nil, 2)}, 0x8000)
+	require.NoError(t, err)
+
+	require.NoError(t, data.ResolveAddresses(layouts))
+
+	assert.Equal(t, []byte{0x00, 0x80, 0x00, 0x00, 0x03, 0x80}, data.Find("table").Bytes)
+}
+
+func Test_DataSection_ResolveAddresses_NotCompiled_Error(t *testing.T) {
+	data := NewDataSection()
+	require.NoError(t, data.AddVariables([]zsm.SemDeclaration{newTableDecl("table", u16Type(), newAddressOf("h"))}))
+
+	err := data.ResolveAddresses(nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "function 'h' that is not compiled")
+}
+
+func Test_DataSection_ResolveAddresses_JumpTable(t *testing.T) {
+	data := NewDataSection()
+	fnCFG := newLayoutCFG("f", nil, 3)
+	caseBlock := newTestBlock()
+	caseBlock.MachineInstructions = []MachineInstruction{newInstruction0(Z80_NOP)}
+	fnCFG.Blocks = append(fnCFG.Blocks, caseBlock)
+	data.AddJumpTable(&JumpTable{Label: "f.jumptable.0", Targets: []*BasicBlock{caseBlock, fnCFG.Entry}})
+	layouts, err := LayoutFunctions([]*CFG{fnCFG}, 0x8000)
+	require.NoError(t, err)
+
+	require.NoError(t, data.ResolveAddresses(layouts))
+
+	// the entries are the block addresses, counted in the data size
+	assert.Equal(t, []byte{0x03, 0x80, 0x00, 0x80}, data.Find("f.jumptable.0").Bytes)
+	assert.Equal(t, 4, data.Size())
+}
+
+func Test_DataSection_ResolveAddresses_JumpTable_AfterInlineAsm_Error(t *testing.T) {
+	data := NewDataSection()
+	fnCFG := newLayoutCFG("f", nil, 1)
+	fnCFG.Entry.MachineInstructions = append(fnCFG.Entry.MachineInstructions, newInlineAsm("halt"))
+	caseBlock := newTestBlock()
+	caseBlock.MachineInstructions = []MachineInstruction{newInstruction0(Z80_NOP)}
+	fnCFG.Blocks = append(fnCFG.Blocks, caseBlock)
+	data.AddJumpTable(&JumpTable{Label: "f.jumptable.0", Targets: []*BasicBlock{caseBlock}})
+	layouts, err := LayoutFunctions([]*CFG{fnCFG}, 0x8000)
+	require.NoError(t, err)
+
+	err = data.ResolveAddresses(layouts)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "without a known address")
+}
+
+func Test_DataSection_ResolveAddresses_JumpTable_NotLaidOut_Error(t *testing.T) {
+	data := NewDataSection()
+	data.AddJumpTable(&JumpTable{Label: "f.jumptable.0", Targets: []*BasicBlock{newTestBlock()}})
+
+	err := data.ResolveAddresses(nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "jump table 'f.jumptable.0'")
+}
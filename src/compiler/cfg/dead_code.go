@@ -0,0 +1,102 @@
+package cfg
+
+// allFlags is the set of all Z80 flags (used for dynamic flag dependencies)
+const allFlags = InstrFlagC | InstrFlagN | InstrFlagPV | InstrFlagH | InstrFlagZ | InstrFlagS
+
+// EliminateDeadInstructions removes instructions whose result is never used
+// and that have no side effects (stores, calls, branches, I/O or flags read later on).
+// Liveness is recomputed until no more instructions can be removed.
+// Returns the number of removed instructions.
+func EliminateDeadInstructions(cfg *CFG) int {
+	total := 0
+	for {
+		liveness := ComputeLiveness(cfg)
+		removed := 0
+		for _, block := range cfg.Blocks {
+			removed += eliminateDeadInBlock(block, liveness.LiveOut[block.ID])
+		}
+		if removed == 0 {
+			return total
+		}
+		total += removed
+	}
+}
+
+// eliminateDeadInBlock walks the block backwards, tracking live VRs and needed flags
+func eliminateDeadInBlock(block *BasicBlock, liveOut map[int]bool) int {
+	live := make(map[int]bool, len(liveOut))
+	for vrID := range liveOut {
+		live[vrID] = true
+	}
+	// flags are produced and consumed within a block (compare + branch)
+	neededFlags := InstrFlagNone
+
+	kept := make([]MachineInstruction, 0, len(block.MachineInstructions))
+	removed := 0
+	for i := len(block.MachineInstructions) - 1; i >= 0; i-- {
+		instr := block.MachineInstructions[i]
+		desc := descriptorOf(instr)
+
+		result := instr.GetResult()
+		if isRemovable(instr, desc) && !live[result.ID] && desc.AffectedFlags&neededFlags == 0 {
+			removed++
+			continue
+		}
+
+		if result != nil && shouldTrackForLiveness(result) {
+			delete(live, result.ID)
+		}
+		for _, operand := range instr.GetOperands() {
+			if operand != nil && shouldTrackForLiveness(operand) {
+				live[operand.ID] = true
+			}
+		}
+		if desc != nil {
+			neededFlags &^= desc.AffectedFlags
+			if desc.DependentFlags&InstrFlagDynamic != 0 {
+				neededFlags = allFlags
+			} else {
+				neededFlags |= desc.DependentFlags
+			}
+		}
+
+		kept = append(kept, instr)
+	}
+
+	if removed > 0 {
+		// restore original order
+		for l, r := 0, len(kept)-1; l < r; l, r = l+1, r-1 {
+			kept[l], kept[r] = kept[r], kept[l]
+		}
+		block.MachineInstructions = kept
+	}
+	return removed
+}
+
+// descriptorOf returns the instruction descriptor or nil if unknown
+func descriptorOf(instr MachineInstruction) *InstrDescriptor {
+	z80Instr, ok := instr.(*machineInstructionZ80)
+	if !ok {
+		return nil
+	}
+	return Z80InstrDescriptors[z80Instr.opcode]
+}
+
+// isRemovable checks if the instruction only computes its result register.
+// Results bound to a single physical register are kept,
+// they may be consumed implicitly (call arguments, return value).
+func isRemovable(instr MachineInstruction, desc *InstrDescriptor) bool {
+	result := instr.GetResult()
+	if desc == nil || result == nil || result.Type != CandidateRegister || len(result.AllowedSet) == 1 {
+		return false
+	}
+
+	switch desc.Category {
+	case CatLoad:
+		return true
+	case CatMove, CatArithmetic, CatBitwise:
+		// memory operands write back to memory
+		return desc.AddressingMode&(AddrIndirect|AddrIndexed) == 0
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package cfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EliminateDeadInstructions_UnusedValue(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	x := vrAlloc.AllocateNamed("x", Z80Registers8)
+	unused := vrAlloc.Allocate(Z80Registers8)
+	unusedSum := vrAlloc.Allocate(Z80Registers8)
+	used := vrAlloc.Allocate(Z80Registers8)
+	vrHL := vrAlloc.Allocate(Z80RegHL)
+
+	block := newTestBlock()
+	block.MachineInstructions = []MachineInstruction{
+		// computed but never used
+		newInstruction(Z80_LD_R_R, unused, x),
+		newInstruction(Z80_ADD_A_R, unusedSum, unused),
+		// stored to memory
+		newInstruction(Z80_LD_R_R, used, x),
+		newInstruction(Z80_LD_HL_R, vrHL, used),
+	}
+	cfg := &CFG{Blocks: []*BasicBlock{block}, Entry: block}
+
+	removed := EliminateDeadInstructions(cfg)
+
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_LD_HL_R}, opcodesOf(block.MachineInstructions))
+	assert.Equal(t, used, block.MachineInstructions[0].GetResult())
+}
+
+func Test_EliminateDeadInstructions_UsedInSuccessor(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	x := vrAlloc.AllocateNamed("x", Z80Registers8)
+	value := vrAlloc.Allocate(Z80Registers8)
+	vrHL := vrAlloc.Allocate(Z80RegHL)
+
+	block0 := newTestBlock()
+	block0.MachineInstructions = []MachineInstruction{
+		newInstruction(Z80_LD_R_R, value, x),
+	}
+	block1 := newTestBlock()
+	block1.ID = 1
+	block1.MachineInstructions = []MachineInstruction{
+		newInstruction(Z80_LD_HL_R, vrHL, value),
+	}
+	block0.Successors = []*BasicBlock{block1}
+	block1.Predecessors = []*BasicBlock{block0}
+	cfg := &CFG{Blocks: []*BasicBlock{block0, block1}, Entry: block0}
+
+	removed := EliminateDeadInstructions(cfg)
+
+	assert.Equal(t, 0, removed)
+	assert.Len(t, block0.MachineInstructions, 1)
+}
+
+func Test_EliminateDeadInstructions_KeepsSideEffects(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	x := vrAlloc.AllocateNamed("x", Z80Registers8)
+	arg := vrAlloc.Allocate(Z80RegL)
+	flags := vrAlloc.Allocate(Z80Registers8)
+	target := newTestBlock()
+
+	block := newTestBlock()
+	block.MachineInstructions = []MachineInstruction{
+		// call argument: consumed implicitly by the call
+		newInstruction(Z80_LD_R_R, arg, x),
+		newCall("print"),
+		// result unused, but the branch reads the flags
+		newInstruction(Z80_OR_R, flags, x),
+		newJumpWithCondition(Cond_Z, target, target),
+	}
+	cfg := &CFG{Blocks: []*BasicBlock{block}, Entry: block}
+
+	removed := EliminateDeadInstructions(cfg)
+
+	assert.Equal(t, 0, removed)
+	assert.Len(t, block.MachineInstructions, 4)
+}
@@ -0,0 +1,95 @@
+package cfg
+
+// ComputeFlagLiveness computes the flags that are read after each block (block ID -> flags),
+// before they are set again: by the instructions of its successors (conditional jumps).
+func ComputeFlagLiveness(cfg *CFG) map[int]InstrFlags {
+	liveIn := make(map[int]InstrFlags, len(cfg.Blocks))
+	liveOut := make(map[int]InstrFlags, len(cfg.Blocks))
+
+	changed := true
+	for changed {
+		changed = false
+		// Process blocks in reverse order (better convergence)
+		for i := len(cfg.Blocks) - 1; i >= 0; i-- {
+			block := cfg.Blocks[i]
+
+			out := InstrFlagNone
+			for _, succ := range block.Successors {
+				out |= liveIn[succ.ID]
+			}
+			in := out
+			for j := len(block.MachineInstructions) - 1; j >= 0; j-- {
+				in = flagsLiveBefore(block.MachineInstructions[j], in)
+			}
+
+			if in != liveIn[block.ID] || out != liveOut[block.ID] {
+				changed = true
+				liveIn[block.ID] = in
+				liveOut[block.ID] = out
+			}
+		}
+	}
+	return liveOut
+}
+
+// flagsLiveBefore returns the flags that are read after the instruction (live)
+// and are still live before it: the live flags it does not set and the flags it reads.
+// Unknown instructions (inline assembly) read all flags.
+func flagsLiveBefore(instr MachineInstruction, live InstrFlags) InstrFlags {
+	desc := descriptorOf(instr)
+	if desc == nil {
+		return allFlags
+	}
+	live &^= desc.AffectedFlags
+	if desc.DependentFlags&InstrFlagDynamic != 0 {
+		return allFlags
+	}
+	return live | desc.DependentFlags
+}
+
+// EliminateDeadFlags removes instructions that only set flags (compare, bit test, clear carry)
+// when no instruction reads those flags before they are set again.
+// Returns the number of removed instructions.
+func EliminateDeadFlags(cfg *CFG) int {
+	liveOut := ComputeFlagLiveness(cfg)
+
+	removed := 0
+	for _, block := range cfg.Blocks {
+		live := liveOut[block.ID]
+		kept := make([]MachineInstruction, 0, len(block.MachineInstructions))
+		for i := len(block.MachineInstructions) - 1; i >= 0; i-- {
+			instr := block.MachineInstructions[i]
+			if desc := descriptorOf(instr); isFlagOnly(instr) && desc.AffectedFlags&live == 0 {
+				removed++
+				continue
+			}
+			live = flagsLiveBefore(instr, live)
+			kept = append(kept, instr)
+		}
+
+		if len(kept) < len(block.MachineInstructions) {
+			// restore original order
+			for l, r := 0, len(kept)-1; l < r; l, r = l+1, r-1 {
+				kept[l], kept[r] = kept[r], kept[l]
+			}
+			block.MachineInstructions = kept
+		}
+	}
+	return removed
+}
+
+// isFlagOnly checks if the instruction has no other effect than setting flags:
+// CP, BIT, SCF and AND/OR of a register with itself (OR A clears carry).
+func isFlagOnly(instr MachineInstruction) bool {
+	z80Instr, ok := instr.(*machineInstructionZ80)
+	if !ok {
+		return false
+	}
+	switch z80Instr.opcode {
+	case Z80_CP_R, Z80_CP_N, Z80_BIT_B_R, Z80_SCF:
+		return true
+	case Z80_AND_R, Z80_OR_R:
+		return len(z80Instr.operands) == 1 && z80Instr.operands[0] == z80Instr.result
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package cfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EliminateDeadFlags_UnusedCompare(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	x := vrAlloc.AllocateNamed("x", Z80RegA)
+	y := vrAlloc.AllocateNamed("y", Z80Registers8)
+	vrHL := vrAlloc.Allocate(Z80RegHL)
+
+	block := newTestBlock()
+	block.MachineInstructions = []MachineInstruction{
+		// the result of the compare is never branched on
+		newInstruction(Z80_CP_R, x, y),
+		newInstruction(Z80_LD_HL_R, vrHL, x),
+	}
+	cfg := &CFG{Blocks: []*BasicBlock{block}, Entry: block}
+
+	removed := EliminateDeadFlags(cfg)
+
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, []Z80Opcode{Z80_LD_HL_R}, opcodesOf(block.MachineInstructions))
+}
+
+func Test_EliminateDeadFlags_OverwrittenCompare(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	x := vrAlloc.AllocateNamed("x", Z80RegA)
+	y := vrAlloc.AllocateNamed("y", Z80Registers8)
+	z := vrAlloc.AllocateNamed("z", Z80Registers8)
+
+	trueBlock := &BasicBlock{ID: 1}
+	falseBlock := &BasicBlock{ID: 2}
+	block := newTestBlock()
+	block.MachineInstructions = []MachineInstruction{
+		// the flags of the first compare are overwritten by the second
+		newInstruction(Z80_CP_R, x, y),
+		newInstruction(Z80_CP_R, x, z),
+		newJumpWithCondition(Cond_Z, trueBlock, falseBlock),
+	}
+	block.Successors = []*BasicBlock{trueBlock, falseBlock}
+	cfg := &CFG{Blocks: []*BasicBlock{block, trueBlock, falseBlock}, Entry: block}
+
+	removed := EliminateDeadFlags(cfg)
+
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, []Z80Opcode{Z80_CP_R, Z80_JP_CC_NN}, opcodesOf(block.MachineInstructions))
+	assert.Equal(t, z, block.MachineInstructions[0].GetOperands()[0])
+}
+
+func Test_EliminateDeadFlags_LiveInSuccessor(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	x := vrAlloc.AllocateNamed("x", Z80RegA)
+	y := vrAlloc.AllocateNamed("y", Z80Registers8)
+	zero := vrAlloc.AllocateImmediate(0, Bits8)
+
+	block0 := newTestBlock()
+	block0.MachineInstructions = []MachineInstruction{
+		newInstruction(Z80_CP_R, x, y),
+	}
+	// the carry of the compare is read in the next block
+	block1 := &BasicBlock{ID: 1, MachineInstructions: []MachineInstruction{
+		newInstruction(Z80_ADC_A_N, x, zero),
+	}}
+	block0.Successors = []*BasicBlock{block1}
+	cfg := &CFG{Blocks: []*BasicBlock{block0, block1}, Entry: block0}
+
+	assert.Equal(t, InstrFlagC, ComputeFlagLiveness(cfg)[block0.ID]&InstrFlagC)
+
+	removed := EliminateDeadFlags(cfg)
+
+	assert.Equal(t, 0, removed)
+	assert.Equal(t, []Z80Opcode{Z80_CP_R}, opcodesOf(block0.MachineInstructions))
+}
+
+func Test_EliminateDeadFlags_ClearCarry(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	vrA := vrAlloc.Allocate(Z80RegA)
+	vrHL := vrAlloc.Allocate(Z80RegHL)
+	vrDE := vrAlloc.Allocate(Z80RegDE)
+
+	block := newTestBlock()
+	block.MachineInstructions = []MachineInstruction{
+		// OR A clears the carry for SBC: kept
+		newInstruction(Z80_OR_R, vrA, vrA),
+		newInstruction(Z80_SBC_HL_RR, vrHL, vrDE),
+		// ADD HL, rr does not read the carry: removed
+		newInstruction(Z80_OR_R, vrA, vrA),
+		newInstruction(Z80_ADD_HL_RR, vrHL, vrDE),
+	}
+	cfg := &CFG{Blocks: []*BasicBlock{block}, Entry: block}
+
+	removed := EliminateDeadFlags(cfg)
+
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, []Z80Opcode{Z80_OR_R, Z80_SBC_HL_RR, Z80_ADD_HL_RR}, opcodesOf(block.MachineInstructions))
+}
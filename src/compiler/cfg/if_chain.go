@@ -0,0 +1,67 @@
+package cfg
+
+import "zenith/compiler/zsm"
+
+// matchIfChain checks if the if statement and its elsif branches compare the same 8-bit variable
+// for equality with distinct constants (if x == 1 {} elsif x == 2 {} ...) that are dense enough for a jump table.
+// Returns the equivalent select statement (the else block becomes the select else) or nil if they do not.
+func matchIfChain(ifStmt *zsm.SemIf) *zsm.SemSelect {
+	if len(ifStmt.ElsifBlocks)+1 < jumpTableMinCases {
+		return nil
+	}
+
+	subject, value, ok := matchEqualsConstant(ifStmt.Condition)
+	if !ok {
+		return nil
+	}
+	cases := []*zsm.SemSelectCase{{Value: value, Body: ifStmt.ThenBlock}}
+
+	for _, elsif := range ifStmt.ElsifBlocks {
+		symbol, value, ok := matchEqualsConstant(elsif.Condition)
+		if !ok || symbol.Symbol != subject.Symbol {
+			return nil
+		}
+		cases = append(cases, &zsm.SemSelectCase{Value: value, Body: elsif.ThenBlock})
+	}
+
+	values := make([]int, len(cases))
+	seen := make(map[int]bool, len(cases))
+	for i, caseStmt := range cases {
+		values[i] = caseStmt.Value.(*zsm.SemConstant).Value.(int)
+		if seen[values[i]] {
+			return nil
+		}
+		seen[values[i]] = true
+	}
+	if _, _, dense := denseCaseRange(values); !dense {
+		return nil
+	}
+
+	return &zsm.SemSelect{
+		Expression: subject,
+		Cases:      cases,
+		Else:       ifStmt.ElseBlock,
+	}
+}
+
+// matchEqualsConstant returns the variable and the constant of a condition
+// that compares an 8-bit integer variable for equality with an integer constant (x == 5)
+func matchEqualsConstant(condition zsm.SemExpression) (*zsm.SemSymbolRef, *zsm.SemConstant, bool) {
+	op, ok := condition.(*zsm.SemBinaryOp)
+	if !ok || op.Op != zsm.OpEqual {
+		return nil, nil, false
+	}
+	ref, ok := op.Left.(*zsm.SemSymbolRef)
+	if !ok || ref.Symbol.Kind != zsm.SymbolVariable || ref.Symbol.Type == nil ||
+		ref.Symbol.Type.Size() != 1 || ref.Symbol.Type == zsm.BitType {
+		return nil, nil, false
+	}
+	constant, ok := op.Right.(*zsm.SemConstant)
+	if !ok {
+		return nil, nil, false
+	}
+	if _, ok := constant.Value.(int); !ok {
+		return nil, nil, false
+	}
+	return ref, constant, true
+}
@@ -80,22 +80,62 @@ const (
 	Z80_CP_HL  Z80Opcode = 0x00BE // CP (HL)
 
 	// Bitwise (CB prefix instructions)
-	Z80_BIT_B_R Z80Opcode = 0xCB40 // BIT b, r (test bit) - CB prefix
-	Z80_SET_B_R Z80Opcode = 0xCBC0 // SET b, r (set bit) - CB prefix
-	Z80_RES_B_R Z80Opcode = 0xCB80 // RES b, r (reset bit) - CB prefix
+	Z80_BIT_B_R  Z80Opcode = 0xCB40 // BIT b, r (test bit) - CB prefix
+	Z80_BIT_B_HL Z80Opcode = 0xCB46 // BIT b, (HL) (test bit in memory) - CB prefix
+	Z80_SET_B_R  Z80Opcode = 0xCBC0 // SET b, r (set bit) - CB prefix
+	Z80_RES_B_R  Z80Opcode = 0xCB80 // RES b, r (reset bit) - CB prefix
+	Z80_SET_B_HL Z80Opcode = 0xCBC6 // SET b, (HL) (set bit in memory) - CB prefix
+	Z80_RES_B_HL Z80Opcode = 0xCB86 // RES b, (HL) (reset bit in memory) - CB prefix
 
 	// Rotate/Shift (CB prefix instructions)
-	Z80_RLC_R Z80Opcode = 0xCB00 // RLC r (rotate left circular) - CB prefix
-	Z80_RRC_R Z80Opcode = 0xCB08 // RRC r (rotate right circular) - CB prefix
-	Z80_RL_R  Z80Opcode = 0xCB10 // RL r (rotate left through carry) - CB prefix
-	Z80_RR_R  Z80Opcode = 0xCB18 // RR r (rotate right through carry) - CB prefix
-	Z80_SLA_R Z80Opcode = 0xCB20 // SLA r (shift left arithmetic) - CB prefix
-	Z80_SRA_R Z80Opcode = 0xCB28 // SRA r (shift right arithmetic) - CB prefix
-	Z80_SRL_R Z80Opcode = 0xCB38 // SRL r (shift right logical) - CB prefix
+	Z80_RLC_R  Z80Opcode = 0xCB00 // RLC r (rotate left circular) - CB prefix
+	Z80_RRC_R  Z80Opcode = 0xCB08 // RRC r (rotate right circular) - CB prefix
+	Z80_RL_R   Z80Opcode = 0xCB10 // RL r (rotate left through carry) - CB prefix
+	Z80_RR_R   Z80Opcode = 0xCB18 // RR r (rotate right through carry) - CB prefix
+	Z80_SLA_R  Z80Opcode = 0xCB20 // SLA r (shift left arithmetic) - CB prefix
+	Z80_SRA_R  Z80Opcode = 0xCB28 // SRA r (shift right arithmetic) - CB prefix
+	Z80_SRL_R  Z80Opcode = 0xCB38 // SRL r (shift right logical) - CB prefix
+	Z80_RLC_HL Z80Opcode = 0xCB06 // RLC (HL) (rotate memory left circular) - CB prefix
+	Z80_RRC_HL Z80Opcode = 0xCB0E // RRC (HL) (rotate memory right circular) - CB prefix
+	Z80_RL_HL  Z80Opcode = 0xCB16 // RL (HL) (rotate memory left through carry) - CB prefix
+	Z80_RR_HL  Z80Opcode = 0xCB1E // RR (HL) (rotate memory right through carry) - CB prefix
+	Z80_SLA_HL Z80Opcode = 0xCB26 // SLA (HL) (shift memory left arithmetic) - CB prefix
+	Z80_SRA_HL Z80Opcode = 0xCB2E // SRA (HL) (shift memory right arithmetic) - CB prefix
+	Z80_SRL_HL Z80Opcode = 0xCB3E // SRL (HL) (shift memory right logical) - CB prefix
+
+	// Undocumented (only selected when the selector allows undocumented instructions)
+	Z80_SLL_R    Z80Opcode = 0xCB30 // SLL r (shift left, bit 0 set) - CB prefix
+	Z80_LD_XR_XR Z80Opcode = 0xDD40 // LD r, r' (r/r' incl. IXH, IXL) - DD prefix
+	Z80_LD_XR_N  Z80Opcode = 0xDD06 // LD IXH/IXL, n - DD prefix
+	Z80_ADD_A_XR Z80Opcode = 0xDD84 // ADD A, IXH/IXL - DD prefix
+	Z80_SUB_XR   Z80Opcode = 0xDD94 // SUB IXH/IXL - DD prefix
+	Z80_AND_XR   Z80Opcode = 0xDDA4 // AND IXH/IXL - DD prefix
+	Z80_XOR_XR   Z80Opcode = 0xDDAC // XOR IXH/IXL - DD prefix
+	Z80_OR_XR    Z80Opcode = 0xDDB4 // OR IXH/IXL - DD prefix
+	Z80_CP_XR    Z80Opcode = 0xDDBC // CP IXH/IXL - DD prefix
+	Z80_INC_XR   Z80Opcode = 0xDD24 // INC IXH/IXL - DD prefix
+	Z80_DEC_XR   Z80Opcode = 0xDD25 // DEC IXH/IXL - DD prefix
+	Z80_LD_YR_YR Z80Opcode = 0xFD40 // LD r, r' (r/r' incl. IYH, IYL) - FD prefix
+	Z80_LD_YR_N  Z80Opcode = 0xFD06 // LD IYH/IYL, n - FD prefix
+	Z80_ADD_A_YR Z80Opcode = 0xFD84 // ADD A, IYH/IYL - FD prefix
+	Z80_SUB_YR   Z80Opcode = 0xFD94 // SUB IYH/IYL - FD prefix
+	Z80_AND_YR   Z80Opcode = 0xFDA4 // AND IYH/IYL - FD prefix
+	Z80_XOR_YR   Z80Opcode = 0xFDAC // XOR IYH/IYL - FD prefix
+	Z80_OR_YR    Z80Opcode = 0xFDB4 // OR IYH/IYL - FD prefix
+	Z80_CP_YR    Z80Opcode = 0xFDBC // CP IYH/IYL - FD prefix
+	Z80_INC_YR   Z80Opcode = 0xFD24 // INC IYH/IYL - FD prefix
+	Z80_DEC_YR   Z80Opcode = 0xFD25 // DEC IYH/IYL - FD prefix
+
+	// Pseudo instructions
+	Z80_INLINE_ASM Z80Opcode = 0xFF00 // asm { ... } text, emitted verbatim
 
 	// Stack
 	Z80_PUSH_QQ Z80Opcode = 0x00C5 // PUSH qq
 	Z80_POP_QQ  Z80Opcode = 0x00C1 // POP qq
+	Z80_PUSH_IX Z80Opcode = 0xDDE5 // PUSH IX - DD prefix
+	Z80_POP_IX  Z80Opcode = 0xDDE1 // POP IX - DD prefix
+	Z80_PUSH_IY Z80Opcode = 0xFDE5 // PUSH IY - FD prefix
+	Z80_POP_IY  Z80Opcode = 0xFDE1 // POP IY - FD prefix
 
 	// Jump/Branch
 	Z80_JP_NN    Z80Opcode = 0x00C3 // JP nn (unconditional jump)
@@ -123,14 +163,27 @@ const (
 	Z80_NOP  Z80Opcode = 0x0000 // NOP
 	Z80_HALT Z80Opcode = 0x0076 // HALT
 	Z80_NEG  Z80Opcode = 0xED44 // NEG (two's complement negate A) - ED prefix
+	Z80_SCF  Z80Opcode = 0x0037 // SCF (set carry flag)
 	Z80_CCF  Z80Opcode = 0x003F // CCF (complement carry flag)
 
+	// Exchange
+	Z80_EX_DE_HL Z80Opcode = 0x00EB // EX DE, HL (exchange DE and HL)
+	Z80_EX_AF_AF Z80Opcode = 0x0008 // EX AF, AF' (exchange AF and AF')
+	Z80_EXX      Z80Opcode = 0x00D9 // EXX (exchange BC, DE, HL with BC', DE', HL')
+
+	// Block compare
+	Z80_CPIR Z80Opcode = 0xEDB1 // CPIR (compare A with (HL), HL++, BC-- until found or BC = 0) - ED prefix
+	Z80_CPDR Z80Opcode = 0xEDB9 // CPDR (compare A with (HL), HL--, BC-- until found or BC = 0) - ED prefix
+
+	// Block transfer
+	Z80_LDIR Z80Opcode = 0xEDB0 // LDIR (copy (HL) to (DE), HL++, DE++, BC-- until BC = 0) - ED prefix
+	Z80_LDDR Z80Opcode = 0xEDB8 // LDDR (copy (HL) to (DE), HL--, DE--, BC-- until BC = 0) - ED prefix
+
 	// others...
-	// EX AF, AF' (exchange AF and AF')
 	// EX DE, HL (exchange DE and HL)
 	// EX (SP), HL (exchange HL with value at SP)
-	// LDD, LDI, LDDR, LDIR (block transfer instructions) - ED prefix
-	// CPI, CPD, CPIR, CPDR (block compare instructions) - ED prefix
+	// LDD, LDI (block transfer instructions) - ED prefix
+	// CPI, CPD (block compare instructions) - ED prefix
 	// INI, IND, INIR, INDR (block input instructions) - ED prefix
 	// OUTI, OUTD, OTIR, OTDR (block output instructions) - ED prefix
 )
@@ -238,6 +291,10 @@ type InstrDescriptor struct {
 	EncodingReg2SL uint8 // Shift left of operand register-id #2 in opcode encoding
 	Prefix1        uint8 // Instruction prefix #1 byte (0 if none)
 	Prefix2        uint8 // Instruction prefix #2 byte (0 if none)
+
+	// Undocumented instructions are only selected when explicitly allowed
+	// (some Z80 clones do not implement them)
+	Undocumented bool
 }
 
 func HasDependency(deps []InstrDependency, operandType OperandType) bool {
@@ -375,28 +432,32 @@ func (op Z80Opcode) String() string {
 		return "RET"
 	case Z80_RET_CC:
 		return "RET"
-	// case Z80_RST:
-	// 	return "RST"
+	case Z80_RETI:
+		return "RETI"
+	case Z80_RETN:
+		return "RETN"
+	case Z80_RST_P:
+		return "RST"
 
 	// Stack
-	case Z80_PUSH_QQ:
+	case Z80_PUSH_QQ, Z80_PUSH_IX, Z80_PUSH_IY:
 		return "PUSH"
-	case Z80_POP_QQ:
+	case Z80_POP_QQ, Z80_POP_IX, Z80_POP_IY:
 		return "POP"
 
 	// Bit Operations
 	case Z80_BIT_B_R:
 		return "BIT"
-	// case Z80_BIT_B_HL:
-	// 	return "BIT"
+	case Z80_BIT_B_HL:
+		return "BIT"
 	case Z80_SET_B_R:
 		return "SET"
-	// case Z80_SET_B_HL:
-	// 	return "SET"
+	case Z80_SET_B_HL:
+		return "SET"
 	case Z80_RES_B_R:
 		return "RES"
-	// case Z80_RES_B_HL:
-	// 	return "RES"
+	case Z80_RES_B_HL:
+		return "RES"
 
 	// Rotate/Shift
 	// case Z80_RLCA:
@@ -409,32 +470,58 @@ func (op Z80Opcode) String() string {
 	// 	return "RRA"
 	case Z80_RLC_R:
 		return "RLC"
-	// case Z80_RLC_HL:
-	// 	return "RLC"
+	case Z80_RLC_HL:
+		return "RLC"
 	case Z80_RL_R:
 		return "RL"
-	// case Z80_RL_HL:
-	// 	return "RL"
+	case Z80_RL_HL:
+		return "RL"
 	case Z80_RRC_R:
 		return "RRC"
-	// case Z80_RRC_HL:
-	// 	return "RRC"
+	case Z80_RRC_HL:
+		return "RRC"
 	case Z80_RR_R:
 		return "RR"
-	// case Z80_RR_HL:
-	// 	return "RR"
+	case Z80_RR_HL:
+		return "RR"
 	case Z80_SLA_R:
 		return "SLA"
-	// case Z80_SLA_HL:
-	// 	return "SLA"
+	case Z80_SLA_HL:
+		return "SLA"
 	case Z80_SRA_R:
 		return "SRA"
-	// case Z80_SRA_HL:
-	// 	return "SRA"
+	case Z80_SRA_HL:
+		return "SRA"
 	case Z80_SRL_R:
 		return "SRL"
-	// case Z80_SRL_HL:
-	// 	return "SRL"
+	case Z80_SRL_HL:
+		return "SRL"
+
+	// Pseudo
+	case Z80_INLINE_ASM:
+		return "ASM"
+
+	// Undocumented
+	case Z80_SLL_R:
+		return "SLL"
+	case Z80_LD_XR_XR, Z80_LD_XR_N, Z80_LD_YR_YR, Z80_LD_YR_N:
+		return "LD"
+	case Z80_ADD_A_XR, Z80_ADD_A_YR:
+		return "ADD"
+	case Z80_SUB_XR, Z80_SUB_YR:
+		return "SUB"
+	case Z80_AND_XR, Z80_AND_YR:
+		return "AND"
+	case Z80_XOR_XR, Z80_XOR_YR:
+		return "XOR"
+	case Z80_OR_XR, Z80_OR_YR:
+		return "OR"
+	case Z80_CP_XR, Z80_CP_YR:
+		return "CP"
+	case Z80_INC_XR, Z80_INC_YR:
+		return "INC"
+	case Z80_DEC_XR, Z80_DEC_YR:
+		return "DEC"
 
 	// Misc
 	case Z80_NOP:
@@ -445,12 +532,24 @@ func (op Z80Opcode) String() string {
 		return "DI"
 	case Z80_EI:
 		return "EI"
-	// case Z80_EX_DE_HL:
-	// 	return "EX"
-	// case Z80_EX_AF_AF:
-	// 	return "EX"
-	// case Z80_EXX:
-	// 	return "EXX"
+	case Z80_NEG:
+		return "NEG"
+	case Z80_SCF:
+		return "SCF"
+	case Z80_CCF:
+		return "CCF"
+	case Z80_EX_DE_HL, Z80_EX_AF_AF:
+		return "EX"
+	case Z80_EXX:
+		return "EXX"
+	case Z80_CPIR:
+		return "CPIR"
+	case Z80_CPDR:
+		return "CPDR"
+	case Z80_LDIR:
+		return "LDIR"
+	case Z80_LDDR:
+		return "LDDR"
 	// case Z80_EX_SP_HL:
 	// 	return "EX"
 
@@ -459,6 +558,15 @@ func (op Z80Opcode) String() string {
 	}
 }
 
+// Encoding returns the 3-bit cc field of the conditional instructions (NZ=0 ... M=7)
+// Shifted into the opcode by the descriptor's EncodingReg1SL: JP PE, nn = 0xC2 | 5<<3.
+func (cc ConditionCode) Encoding() uint8 {
+	if cc == Cond_None {
+		return 0
+	}
+	return uint8(cc - Cond_NZ)
+}
+
 // String returns a human-readable name for the condition code
 func (cc ConditionCode) String() string {
 	switch cc {
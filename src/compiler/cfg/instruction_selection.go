@@ -2,6 +2,8 @@ package cfg
 
 import (
 	"fmt"
+	"math/bits"
+	"strings"
 	"zenith/compiler/zsm"
 )
 
@@ -25,6 +27,18 @@ type InstructionSelectionContext struct {
 
 	// Current basic block being processed
 	currentBlock *BasicBlock
+
+	// Constant data (string literals) referenced by the code
+	dataSection *DataSection
+
+	// 'ret call()' statements selected as a jump to the callee
+	tailCalls []tailCall
+}
+
+// tailCall is a 'ret call()' selected as a jump at the end of the block
+type tailCall struct {
+	block *BasicBlock
+	call  *zsm.SemFunctionCall
 }
 
 // NewInstructionSelectionContext creates a new context for instruction selection
@@ -35,6 +49,7 @@ func NewInstructionSelectionContext(selector InstructionSelector, vrAlloc *Virtu
 		callingConvention: selector.GetCallingConvention(),
 		symbolToVReg:      make(map[*zsm.Symbol]*VirtualRegister),
 		exprToVReg:        make(map[zsm.SemExpression]*VirtualRegister),
+		dataSection:       NewDataSection(),
 	}
 }
 
@@ -42,9 +57,16 @@ func NewInstructionSelectionContext(selector InstructionSelector, vrAlloc *Virtu
 // Takes a slice of CFGs and populates their MachineInstructions fields
 // Returns the same CFGs with machine instructions added
 func SelectInstructions(cfgs []*CFG, vrAlloc *VirtualRegisterAllocator, selector InstructionSelector) error {
-	// Process each CFG with the shared allocator
+	return SelectInstructionsWithData(cfgs, vrAlloc, selector, NewDataSection())
+}
+
+// SelectInstructionsWithData generates machine instructions for pre-built CFGs
+// and collects the constant data the instructions refer to in the data section
+func SelectInstructionsWithData(cfgs []*CFG, vrAlloc *VirtualRegisterAllocator, selector InstructionSelector, dataSection *DataSection) error {
+	// Process each CFG with the shared allocator and data section
 	for _, cfg := range cfgs {
 		ctx := NewInstructionSelectionContext(selector, vrAlloc)
+		ctx.dataSection = dataSection
 
 		if err := ctx.selectCFG(cfg); err != nil {
 			return fmt.Errorf("selecting instructions for function %s: %w", cfg.FunctionName, err)
@@ -54,12 +76,33 @@ func SelectInstructions(cfgs []*CFG, vrAlloc *VirtualRegisterAllocator, selector
 	return nil
 }
 
+// SelectGlobalsInit copies the initial values of the global variables the code writes to RAM
+// at the start of the (entry) function, before its prologue.
+// The initial values are stored one after the other in the data section, as are their RAM copies: one block copy.
+func SelectGlobalsInit(entry *CFG, dataSection *DataSection, selector InstructionSelector) error {
+	written := dataSection.WrittenVariables()
+	if len(written) == 0 {
+		return nil
+	}
+
+	prologue := entry.Entry.MachineInstructions
+	entry.Entry.MachineInstructions = nil
+	selector.SetCurrentBlock(entry.Entry)
+	if err := selector.SelectCopyData(written[0].InitLabel(), written[0].Label, uint16(dataSection.VariablesSize())); err != nil {
+		return err
+	}
+	entry.Entry.MachineInstructions = append(entry.Entry.MachineInstructions, prologue...)
+	return nil
+}
+
 // selectCFG processes a single CFG and generates instructions for all its blocks
 func (ctx *InstructionSelectionContext) selectCFG(cfg *CFG) error {
 	ctx.currentCFG = cfg
+	ctx.tailCalls = nil
 	ctx.allocateFrameSlots()
 
 	// Allocate VirtualRegisters for parameters based on calling convention
+	var stackParams []*zsm.Symbol
 	if cfg.FunctionDecl != nil {
 		for i, param := range cfg.FunctionDecl.Parameters {
 			regSize := RegisterSize(param.Type.Size() * 8) // Convert bytes to bits
@@ -69,9 +112,11 @@ func (ctx *InstructionSelectionContext) selectCFG(cfg *CFG) error {
 
 			if useStack {
 				// Parameter is on the stack - allocate VirtualRegister with stack home
+				// The offset is moved above the local frame once the frame is complete (placeStackParameters)
 				// The register allocator can use this stack location for spilling
 				vr := ctx.vrAlloc.AllocateOnStack(param.Name, regSize, stackOffset)
 				ctx.symbolToVReg[param] = vr
+				stackParams = append(stackParams, param)
 
 				// Note: We don't eagerly load from stack here. The VirtualRegister
 				// represents the parameter value, and the register allocator will
@@ -96,9 +141,26 @@ func (ctx *InstructionSelectionContext) selectCFG(cfg *CFG) error {
 			return err
 		}
 	}
-
-	// check if function needs stack frame
+	if err := ctx.placeStackParameters(stackParams); err != nil {
+		return err
+	}
 	if ctx.currentCFG.FrameLayout.nextOffset > 0 {
+		if err := ctx.undoTailCalls(); err != nil {
+			return err
+		}
+	}
+
+	fn := cfg.FunctionDecl
+	// '@fast' functions switch to the alternate register set in the prologue/epilogue
+	fast := fn != nil && fn.HasAttribute(zsm.AttributeFast)
+	// interrupt handlers preserve the registers in the prologue/epilogue
+	nmi := fn != nil && fn.HasAttribute(zsm.AttributeNMI)
+	interrupt := nmi || (fn != nil && fn.HasAttribute(zsm.AttributeInterrupt))
+	// '@noreturn' functions never reach the exit block: no epilogue and no RET
+	noReturn := fn != nil && fn.HasAttribute(zsm.AttributeNoReturn)
+
+	// check if function needs stack frame, register set switch or register saves
+	if ctx.currentCFG.FrameLayout.nextOffset > 0 || fast || interrupt {
 		// Generate prologue in the reserved entry block
 		// Note: Prologue emits instructions to currentBlock, so we set it to entry
 		ctx.selector.SetCurrentBlock(cfg.Entry)
@@ -106,12 +168,67 @@ func (ctx *InstructionSelectionContext) selectCFG(cfg *CFG) error {
 
 		// Generate epilogue in the reserved exit block
 		// The exit block is reached by all return statements
-		ctx.selector.SetCurrentBlock(cfg.Exit)
-		ctx.selector.SelectFunctionEpilogue(cfg.FunctionDecl, ctx.currentCFG.FrameLayout.nextOffset)
+		if !noReturn {
+			ctx.selector.SetCurrentBlock(cfg.Exit)
+			ctx.selector.SelectFunctionEpilogue(cfg.FunctionDecl, ctx.currentCFG.FrameLayout.nextOffset)
+		}
+	}
+
+	// The single RET of the function (return values are already in the return register)
+	ctx.selector.SetCurrentBlock(cfg.Exit)
+	var err error
+	switch {
+	case noReturn:
+		// the exit block stays empty
+	case interrupt:
+		err = ctx.selector.SelectInterruptReturn(nmi)
+	default:
+		err = ctx.selector.SelectReturn(nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	if fast {
+		cfg.UsesShadowRegisters = true
+		remapToShadowRegisters(cfg)
 	}
 	return nil
 }
 
+// placeStackParameters moves the stack parameters above the local frame: the prologue moves SP below it.
+// The frame is complete after the blocks are selected, selecting a block may add slots (array data).
+func (ctx *InstructionSelectionContext) placeStackParameters(params []*zsm.Symbol) error {
+	for _, param := range params {
+		vr := ctx.symbolToVReg[param]
+		frameOffset := uint16(vr.Value) + ctx.currentCFG.FrameLayout.nextOffset
+		if frameOffset > 0xFF {
+			return fmt.Errorf("parameter '%s' is out of reach of the stack frame (offset %d)", param.Name, frameOffset)
+		}
+		vr.Value = int32(frameOffset)
+	}
+	return nil
+}
+
+// remapToShadowRegisters constrains all VRs of the function to the alternate register set
+func remapToShadowRegisters(cfg *CFG) {
+	remapped := make(map[*VirtualRegister]bool)
+	remap := func(vr *VirtualRegister) {
+		if vr == nil || remapped[vr] || len(vr.AllowedSet) == 0 {
+			return
+		}
+		remapped[vr] = true
+		vr.AllowedSet = ShadowRegisters(vr.AllowedSet)
+	}
+
+	for _, instr := range cfg.GetAllInstructions() {
+		remap(instr.GetResult())
+		for _, operand := range instr.GetOperands() {
+			remap(operand)
+		}
+	}
+}
+
 func (ctx *InstructionSelectionContext) allocateFrameSlots() {
 	for _, block := range ctx.currentCFG.Blocks {
 		for _, stmt := range block.Instructions {
@@ -172,7 +289,7 @@ func (ctx *InstructionSelectionContext) generateBlockTransition(block *BasicBloc
 			// Successors: [0] = then, [1] = else/merge
 			if len(block.Successors) >= 2 {
 				branchCtx := NewExprContextBranch(block.Successors[0], block.Successors[1])
-				_, err := ctx.selectExpressionWithContext(branchCtx, stmt.Condition)
+				_, err := ctx.selectCondition(branchCtx, stmt.Condition)
 				return err
 			}
 
@@ -180,7 +297,7 @@ func (ctx *InstructionSelectionContext) generateBlockTransition(block *BasicBloc
 			// Similar to SemIf
 			if len(block.Successors) >= 2 {
 				branchCtx := NewExprContextBranch(block.Successors[0], block.Successors[1])
-				_, err := ctx.selectExpressionWithContext(branchCtx, stmt.Condition)
+				_, err := ctx.selectCondition(branchCtx, stmt.Condition)
 				return err
 			}
 
@@ -190,7 +307,7 @@ func (ctx *InstructionSelectionContext) generateBlockTransition(block *BasicBloc
 				// Successors: [0] = body, [1] = exit
 				if len(block.Successors) >= 2 {
 					branchCtx := NewExprContextBranch(block.Successors[0], block.Successors[1])
-					_, err := ctx.selectExpressionWithContext(branchCtx, stmt.Condition)
+					_, err := ctx.selectCondition(branchCtx, stmt.Condition)
 					return err
 				}
 			} else {
@@ -201,7 +318,25 @@ func (ctx *InstructionSelectionContext) generateBlockTransition(block *BasicBloc
 				}
 			}
 
+		case *countDownBranch:
+			if len(block.Successors) >= 2 {
+				return ctx.selectCountDownBranch(stmt, block)
+			}
+
+		case *zsm.SemDoWhile:
+			// Successors: [0] = body (back edge), [1] = exit
+			if len(block.Successors) >= 2 {
+				branchCtx := NewExprContextBranch(block.Successors[0], block.Successors[1])
+				_, err := ctx.selectCondition(branchCtx, stmt.Condition)
+				return err
+			}
+
 		case *zsm.SemSelect:
+			// Dense selects jump through a table instead of comparing each case
+			if base, table := ctx.jumpTableFor(stmt, block); table != nil {
+				return ctx.selectJumpTable(stmt, block, base, table)
+			}
+
 			// Select statement - generate comparison and branches for each case
 			// Note: stmt.Expression will be re-evaluated for each case comparison
 			// TODO: Optimize by evaluating once and passing VR to comparison
@@ -224,7 +359,7 @@ func (ctx *InstructionSelectionContext) generateBlockTransition(block *BasicBloc
 			}
 
 		case *zsm.SemReturn:
-			// Return already handled in selectReturn
+			// Jump to the exit block already emitted by selectReturn
 			return nil
 		}
 	}
@@ -238,6 +373,86 @@ func (ctx *InstructionSelectionContext) generateBlockTransition(block *BasicBloc
 	return nil
 }
 
+// jumpTableMinCases is the minimum number of cases for a select to use a jump table
+const jumpTableMinCases = 4
+
+// jumpTableFor returns the table base value and the jump table for a dense select statement.
+// The select is dense when all (8-bit) case values are distinct constants
+// and at least half of the table entries have a case of their own.
+// Returns a nil table when the select should compare each case.
+func (ctx *InstructionSelectionContext) jumpTableFor(stmt *zsm.SemSelect, block *BasicBlock) (int, *JumpTable) {
+	if len(stmt.Cases) < jumpTableMinCases || len(block.Successors) != len(stmt.Cases)+1 ||
+		stmt.Expression.Type() == nil || stmt.Expression.Type().Size() != 1 {
+		return 0, nil
+	}
+
+	values := make([]int, len(stmt.Cases))
+	for i, caseStmt := range stmt.Cases {
+		constant, ok := caseStmt.Value.(*zsm.SemConstant)
+		if !ok {
+			return 0, nil
+		}
+		value, ok := constant.Value.(int)
+		if !ok {
+			return 0, nil
+		}
+		values[i] = value
+	}
+
+	base, size, dense := denseCaseRange(values)
+	if !dense {
+		return 0, nil
+	}
+
+	// cases without a value of their own continue with the else (or merge) block
+	defaultTarget := block.Successors[len(stmt.Cases)]
+	targets := make([]*BasicBlock, size)
+	for i := range targets {
+		targets[i] = defaultTarget
+	}
+	for i, value := range values {
+		if targets[value-base] != defaultTarget {
+			return 0, nil // duplicate case value
+		}
+		targets[value-base] = block.Successors[i]
+	}
+
+	return base, &JumpTable{
+		Label:   fmt.Sprintf("%s.jumptable.%d", ctx.currentCFG.FunctionName, len(ctx.currentCFG.JumpTables)),
+		Targets: targets,
+	}
+}
+
+// denseCaseRange returns the lowest case value and the number of table entries from there up to the highest value.
+// The range is dense when it fits an 8-bit index and at least half of its entries have a case value.
+func denseCaseRange(values []int) (int, int, bool) {
+	base, last := values[0], values[0]
+	for _, value := range values {
+		base = min(base, value)
+		last = max(last, value)
+	}
+	size := last - base + 1
+	return base, size, size <= 256 && size <= 2*len(values)
+}
+
+// selectJumpTable evaluates the select expression once and jumps through the table
+func (ctx *InstructionSelectionContext) selectJumpTable(stmt *zsm.SemSelect, block *BasicBlock, base int, table *JumpTable) error {
+	index, err := ctx.selectExpression(stmt.Expression)
+	if err != nil {
+		return err
+	}
+
+	defaultTarget := block.Successors[len(stmt.Cases)]
+	err = ctx.selector.SelectJumpTable(index, base, table, defaultTarget)
+	if err != nil {
+		return err
+	}
+	ctx.currentCFG.JumpTables = append(ctx.currentCFG.JumpTables, table)
+	// the table is data after the code, its entries are filled in after layout
+	ctx.dataSection.AddJumpTable(table)
+	return nil
+}
+
 // selectStatement processes a single statement
 func (ctx *InstructionSelectionContext) selectStatement(stmt zsm.SemStatement) error {
 	switch s := stmt.(type) {
@@ -246,6 +461,8 @@ func (ctx *InstructionSelectionContext) selectStatement(stmt zsm.SemStatement) e
 
 	case *zsm.SemAssignment:
 		return ctx.selectAssignment(s)
+	case *zsm.SemMultiAssignment:
+		return ctx.selectMultiAssignment(s)
 
 	case *zsm.SemExpressionStmt:
 		// Evaluate expression for side effects
@@ -255,7 +472,10 @@ func (ctx *InstructionSelectionContext) selectStatement(stmt zsm.SemStatement) e
 	case *zsm.SemReturn:
 		return ctx.selectReturn(s)
 
-	case *zsm.SemIf, *zsm.SemElsif, *zsm.SemFor, *zsm.SemSelect:
+	case *zsm.SemInlineAsm:
+		return ctx.selector.SelectInlineAsm(s.Text)
+
+	case *zsm.SemIf, *zsm.SemElsif, *zsm.SemFor, *zsm.SemDoWhile, *zsm.SemSelect, *countDownBranch:
 		// Control flow statements are handled by generateBlockTransition
 		// Don't process them here as they're only for branching
 		return nil
@@ -326,6 +546,11 @@ func (ctx *InstructionSelectionContext) selectVariableDecl(decl *zsm.SemVariable
 			return err
 		}
 
+		initVR, err = ctx.selectConversion(initVR, decl.Initializer.Type(), decl.TypeInfo)
+		if err != nil {
+			return err
+		}
+
 		// Generate move instruction
 		// For arrays, this moves the pointer from the initializer to the variable
 		err = ctx.selector.SelectMove(vrVar, initVR, regSize)
@@ -342,6 +567,10 @@ func (ctx *InstructionSelectionContext) selectAssignment(assign *zsm.SemAssignme
 	// Get the target variable's VirtualRegister
 	targetVR, ok := ctx.symbolToVReg[assign.Target]
 	if !ok {
+		if item := ctx.dataSection.FindVariable(assign.Target); item != nil {
+			item.Written = true
+			return ctx.selectMemoryAssignment(assign, item.Label)
+		}
 		return fmt.Errorf("undefined variable: %s", assign.Target.Name)
 	}
 
@@ -351,14 +580,223 @@ func (ctx *InstructionSelectionContext) selectAssignment(assign *zsm.SemAssignme
 		return err
 	}
 
+	valueVR, err = ctx.selectConversion(valueVR, assign.Value.Type(), assign.Target.Type)
+	if err != nil {
+		return err
+	}
+
 	// Generate move instruction
 	regSize := RegisterSize(assign.Target.Type.Size() * 8)
 	err = ctx.selector.SelectMove(targetVR, valueVR, regSize)
 	return err
 }
 
+// selectMultiAssignment processes a multiple assignment (a, b = 1, 2).
+// All values are evaluated before any target is stored.
+func (ctx *InstructionSelectionContext) selectMultiAssignment(assign *zsm.SemMultiAssignment) error {
+	// a, b = b, a: exchange the registers in place
+	if left, right, ok := ctx.swappedRegisters(assign); ok {
+		return ctx.selector.SelectSwap(left, right, RegisterSize(assign.Targets[0].Type.Size()*8))
+	}
+
+	values := make([]*VirtualRegister, len(assign.Values))
+	for i, value := range assign.Values {
+		valueVR, err := ctx.selectExpression(value)
+		if err != nil {
+			return err
+		}
+		valueVR, err = ctx.selectConversion(valueVR, value.Type(), assign.Targets[i].Type)
+		if err != nil {
+			return err
+		}
+		// the register of another target may be assigned before this value is stored
+		if ctx.isTargetRegister(valueVR, assign.Targets, i) {
+			if valueVR, err = ctx.selector.SelectCopy(valueVR); err != nil {
+				return err
+			}
+		}
+		values[i] = valueVR
+	}
+
+	for i, target := range assign.Targets {
+		regSize := RegisterSize(target.Type.Size() * 8)
+		if targetVR, ok := ctx.symbolToVReg[target]; ok {
+			if err := ctx.selector.SelectMove(targetVR, values[i], regSize); err != nil {
+				return err
+			}
+			continue
+		}
+		item := ctx.dataSection.FindVariable(target)
+		if item == nil {
+			return fmt.Errorf("undefined variable: %s", target.Name)
+		}
+		item.Written = true
+		address, err := ctx.selector.SelectLoadDataAddress(item.Label)
+		if err != nil {
+			return err
+		}
+		if err := ctx.selector.SelectStore(address, values[i], 0, regSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// swappedRegisters checks if the assignment exchanges two variables held in registers (a, b = b, a)
+func (ctx *InstructionSelectionContext) swappedRegisters(assign *zsm.SemMultiAssignment) (*VirtualRegister, *VirtualRegister, bool) {
+	if len(assign.Targets) != 2 || assign.Targets[0].Type.Size() != assign.Targets[1].Type.Size() {
+		return nil, nil, false
+	}
+	for i, value := range assign.Values {
+		ref, ok := value.(*zsm.SemSymbolRef)
+		if !ok || ref.Symbol != assign.Targets[1-i] {
+			return nil, nil, false
+		}
+	}
+	left, okLeft := ctx.symbolToVReg[assign.Targets[0]]
+	right, okRight := ctx.symbolToVReg[assign.Targets[1]]
+	if !okLeft || !okRight || left.Type != CandidateRegister || right.Type != CandidateRegister {
+		return nil, nil, false
+	}
+	return left, right, true
+}
+
+// isTargetRegister checks if the register holds one of the targets, other than the target at the index
+func (ctx *InstructionSelectionContext) isTargetRegister(vr *VirtualRegister, targets []*zsm.Symbol, index int) bool {
+	for i, target := range targets {
+		if i != index && ctx.symbolToVReg[target] == vr {
+			return true
+		}
+	}
+	return false
+}
+
+// selectMemoryAssignment stores the value in the global variable at the data label.
+// Setting or clearing a single bit of an 8-bit variable (flags |= 0x04, flags &= 0xFB)
+// changes the byte in memory in place (SET b, (HL) / RES b, (HL)),
+// as does shifting it by one bit (x = x << 1: SLA (HL), x = x >> 1: SRL/SRA (HL)).
+func (ctx *InstructionSelectionContext) selectMemoryAssignment(assign *zsm.SemAssignment, label string) error {
+	regSize := RegisterSize(assign.Target.Type.Size() * 8)
+	if bit, set, ok := singleBitUpdate(assign); ok && regSize == 8 {
+		address, err := ctx.selector.SelectLoadDataAddress(label)
+		if err != nil {
+			return err
+		}
+		if set {
+			return ctx.selector.SelectBitSetMemory(bit, address)
+		}
+		return ctx.selector.SelectBitResetMemory(bit, address)
+	}
+	if op, ok := singleShiftUpdate(assign); ok && regSize == 8 {
+		address, err := ctx.selector.SelectLoadDataAddress(label)
+		if err != nil {
+			return err
+		}
+		if op.Op == zsm.OpShl {
+			return ctx.selector.SelectShiftLeftMemory(address)
+		}
+		return ctx.selector.SelectShiftRightMemory(address, zsm.IsSigned(assign.Target.Type))
+	}
+
+	// read (the target is loaded from memory), modify, store
+	valueVR, err := ctx.selectExpression(assign.Value)
+	if err != nil {
+		return err
+	}
+	valueVR, err = ctx.selectConversion(valueVR, assign.Value.Type(), assign.Target.Type)
+	if err != nil {
+		return err
+	}
+	address, err := ctx.selector.SelectLoadDataAddress(label)
+	if err != nil {
+		return err
+	}
+	return ctx.selector.SelectStore(address, valueVR, 0, regSize)
+}
+
+// singleBitUpdate checks if the assignment sets (x = x | mask) or clears (x = x & mask)
+// a single bit of the target with a constant mask (x |= 1 << n).
+// Returns the bit index and true to set the bit, false to clear it.
+func singleBitUpdate(assign *zsm.SemAssignment) (uint8, bool, bool) {
+	op, ok := assign.Value.(*zsm.SemBinaryOp)
+	if !ok || (op.Op != zsm.OpBitwiseOr && op.Op != zsm.OpBitwiseAnd) {
+		return 0, false, false
+	}
+	if ref, ok := op.Left.(*zsm.SemSymbolRef); !ok || ref.Symbol != assign.Target {
+		return 0, false, false
+	}
+	value, ok := zsm.ConstantValue(op.Right)
+	if !ok {
+		return 0, false, false
+	}
+	mask, ok := value.(int)
+	if !ok {
+		return 0, false, false
+	}
+
+	set := op.Op == zsm.OpBitwiseOr
+	if !set {
+		// the cleared bit is the one missing from the mask
+		mask = ^mask
+	}
+	mask &= 0xFF
+	if mask == 0 || mask&(mask-1) != 0 {
+		return 0, false, false
+	}
+	return uint8(bits.TrailingZeros8(uint8(mask))), set, true
+}
+
+// singleShiftUpdate checks if the assignment shifts the target by one bit (x = x << 1, x = x >> 1).
+// Returns the shift operation.
+func singleShiftUpdate(assign *zsm.SemAssignment) (*zsm.SemBinaryOp, bool) {
+	op, ok := assign.Value.(*zsm.SemBinaryOp)
+	if !ok || (op.Op != zsm.OpShl && op.Op != zsm.OpShr) {
+		return nil, false
+	}
+	if ref, ok := op.Left.(*zsm.SemSymbolRef); !ok || ref.Symbol != assign.Target {
+		return nil, false
+	}
+	value, ok := zsm.ConstantValue(op.Right)
+	if !ok || value != 1 {
+		return nil, false
+	}
+	return op, true
+}
+
+// selectConversion widens or truncates a primitive value to the size of the target type
+func (ctx *InstructionSelectionContext) selectConversion(value *VirtualRegister, from, to zsm.Type) (*VirtualRegister, error) {
+	fromType, okFrom := from.(*zsm.PrimitiveType)
+	toType, okTo := to.(*zsm.PrimitiveType)
+	if !okFrom || !okTo {
+		return value, nil
+	}
+
+	fromSize := RegisterSize(fromType.Size() * 8)
+	toSize := RegisterSize(toType.Size() * 8)
+	switch {
+	case fromSize < toSize:
+		return ctx.selector.SelectWiden(value, fromSize, toSize, zsm.IsSigned(fromType))
+	case fromSize > toSize:
+		return ctx.selector.SelectTruncate(value, fromSize, toSize)
+	}
+	return value, nil
+}
+
 // selectReturn processes a return statement
 func (ctx *InstructionSelectionContext) selectReturn(ret *zsm.SemReturn) error {
+	if call, ok := ret.Value.(*zsm.SemFunctionCall); ok && ctx.isTailCall(call) {
+		argVRs, err := ctx.selectCallArguments(nil, call)
+		if err != nil {
+			return err
+		}
+		// the callee returns to our caller directly
+		if err := ctx.selector.SelectTailCall(call.Function.Name, argVRs); err != nil {
+			return err
+		}
+		ctx.tailCalls = append(ctx.tailCalls, tailCall{block: ctx.currentBlock, call: call})
+		return nil
+	}
+
 	if ret.Value != nil {
 		// Evaluate return value
 		valueVR, err := ctx.selectExpression(ret.Value)
@@ -383,12 +821,63 @@ func (ctx *InstructionSelectionContext) selectReturn(ret *zsm.SemReturn) error {
 			return err
 		}
 
-		// Generate return with value in correct register
-		return ctx.selector.SelectReturn(returnVR)
+		return ctx.selectReturnJump(returnVR)
+	}
+
+	return ctx.selectReturnJump(nil)
+}
+
+// isTailCall checks if 'ret call()' can jump to the callee instead of calling it.
+// The callee's return value must match and there must be nothing left to do after the call:
+// no epilogue (frame, register set, interrupt) and no stack arguments to clean up.
+// A frame added after the tail call is selected undoes it (undoTailCalls).
+func (ctx *InstructionSelectionContext) isTailCall(call *zsm.SemFunctionCall) bool {
+	if ctx.currentCFG == nil || ctx.currentCFG.FunctionDecl == nil || strings.HasPrefix(call.Function.Name, "@") {
+		return false
 	}
 
-	// Generate void return
-	return ctx.selector.SelectReturn(nil)
+	fn := ctx.currentCFG.FunctionDecl
+	if fn.ReturnType == nil || call.Type() == nil || fn.ReturnType.Name() != call.Type().Name() {
+		return false
+	}
+	if ctx.currentCFG.FrameLayout.nextOffset > 0 || len(fn.Attributes) > 0 {
+		return false
+	}
+	return len(call.Arguments) <= ctx.callingConvention.GetRegisterParameterCount()
+}
+
+// undoTailCalls turns the tail calls back into a call and a return:
+// a block selected after the tail call added a frame, the epilogue must run after the call.
+// The arguments are already in the convention's registers, only the jump is replaced.
+func (ctx *InstructionSelectionContext) undoTailCalls() error {
+	for _, tail := range ctx.tailCalls {
+		instrs := tail.block.MachineInstructions
+		jump := instrs[len(instrs)-1]
+		tail.block.MachineInstructions = instrs[:len(instrs)-1]
+
+		ctx.currentBlock = tail.block
+		ctx.selector.SetCurrentBlock(tail.block)
+		returnSize := RegisterSize(tail.call.Type().Size() * 8)
+		result, err := ctx.selector.SelectCall(tail.call.Function.Name, jump.GetOperands(), returnSize)
+		if err != nil {
+			return err
+		}
+		// the callee returns its value in our return register
+		if err := ctx.selectReturnJump(result); err != nil {
+			return err
+		}
+	}
+	ctx.tailCalls = nil
+	return nil
+}
+
+// selectReturnJump routes a return through the function's exit block, so the epilogue and RET are emitted only once.
+// Without a CFG (standalone selection) the return is emitted directly.
+func (ctx *InstructionSelectionContext) selectReturnJump(returnVR *VirtualRegister) error {
+	if ctx.currentCFG != nil && ctx.currentCFG.Exit != nil {
+		return ctx.selector.SelectJump(ctx.currentCFG.Exit)
+	}
+	return ctx.selector.SelectReturn(returnVR)
 }
 
 // selectExpression processes an expression and returns its result VirtualRegister
@@ -443,9 +932,55 @@ func (ctx *InstructionSelectionContext) selectExpressionWithContext(exprCtx *Exp
 	return resultVR, nil
 }
 
+// selectCondition evaluates a condition (if, elsif, for and logical operands)
+// A bool value (variable, call result) does not branch itself: it branches when non-zero.
+func (ctx *InstructionSelectionContext) selectCondition(exprCtx *ExprContext, expr zsm.SemExpression) (*VirtualRegister, error) {
+	resultVR, err := ctx.selectExpressionWithContext(exprCtx, expr)
+	if err != nil || exprCtx == nil || exprCtx.Mode != BranchMode || resultVR == nil || branchesOnCondition(expr) {
+		return resultVR, err
+	}
+
+	zero, err := ctx.selector.SelectLoadConstant(0, resultVR.Size)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.selector.SelectNotEqual(exprCtx, resultVR, zero)
+}
+
+// branchesOnCondition checks if an expression emits its own conditional branch in BranchMode
+func branchesOnCondition(expr zsm.SemExpression) bool {
+	switch e := expr.(type) {
+	case *zsm.SemBinaryOp:
+		// comparisons and logical operators
+		return e.Op >= zsm.OpEqual
+	case *zsm.SemUnaryOp:
+		return e.Op == zsm.OpLogicalNot || e.Op == zsm.OpToBool
+	case *zsm.SemFunctionCall:
+		return e.Function.Name == "@bit" || e.Function.Name == "@overflow"
+	default:
+		return false
+	}
+}
+
 // selectConstant loads a constant value
 func (ctx *InstructionSelectionContext) selectConstant(constant *zsm.SemConstant) (*VirtualRegister, error) {
+	// string literals are stored in the data section, the constant is their address
+	if literal, ok := constant.Value.(string); ok {
+		label := ctx.dataSection.AddString([]byte(literal))
+		return ctx.selector.SelectLoadDataAddress(label)
+	}
+	// embedded files (@include_bin) are stored as they are
+	if data, ok := constant.Value.([]byte); ok {
+		label := ctx.dataSection.Add(data)
+		return ctx.selector.SelectLoadDataAddress(label)
+	}
+
 	regSize := RegisterSize(constant.Type().Size() * 8)
+	// decimal literals are stored as packed BCD in their decimal type
+	if decimal, ok := constant.Value.(zsm.Decimal); ok {
+		value, _ := decimal.BCD(constant.Type())
+		return ctx.selector.SelectLoadConstant(value, regSize)
+	}
 	return ctx.selector.SelectLoadConstant(constant.Value, regSize)
 }
 
@@ -453,20 +988,32 @@ func (ctx *InstructionSelectionContext) selectConstant(constant *zsm.SemConstant
 func (ctx *InstructionSelectionContext) selectSymbolRef(ref *zsm.SemSymbolRef) (*VirtualRegister, error) {
 	// Look up the VirtualRegister for this symbol
 	vr, ok := ctx.symbolToVReg[ref.Symbol]
-	if !ok {
-		return nil, fmt.Errorf("undefined variable: %s", ref.Symbol.Name)
+	if ok {
+		return vr, nil
 	}
-	return vr, nil
+	// global variables stored in the data section are read from memory
+	if item := ctx.dataSection.FindVariable(ref.Symbol); item != nil {
+		address, err := ctx.selector.SelectLoadDataAddress(item.Label)
+		if err != nil {
+			return nil, err
+		}
+		// an array is referred to by its address
+		if _, ok := ref.Symbol.Type.(*zsm.ArrayType); ok {
+			return address, nil
+		}
+		return ctx.selector.SelectLoad(address, 0, RegisterSize(ref.Symbol.Type.Size()*8))
+	}
+	return nil, fmt.Errorf("undefined variable: %s", ref.Symbol.Name)
 }
 
 // selectBinaryOp processes binary operations
 func (ctx *InstructionSelectionContext) selectBinaryOp(exprCtx *ExprContext, op *zsm.SemBinaryOp) (*VirtualRegister, error) {
 	// Handle logical operators specially - they take expressions, not VRs
 	if op.Op == zsm.OpLogicalAnd {
-		return ctx.selector.SelectLogicalAnd(exprCtx, op.Left, op.Right, ctx.selectExpressionWithContext)
+		return ctx.selector.SelectLogicalAnd(exprCtx, op.Left, op.Right, ctx.selectCondition)
 	}
 	if op.Op == zsm.OpLogicalOr {
-		return ctx.selector.SelectLogicalOr(exprCtx, op.Left, op.Right, ctx.selectExpressionWithContext)
+		return ctx.selector.SelectLogicalOr(exprCtx, op.Left, op.Right, ctx.selectCondition)
 	}
 
 	leftVR, err := ctx.selectExpressionWithContext(exprCtx, op.Left)
@@ -481,16 +1028,29 @@ func (ctx *InstructionSelectionContext) selectBinaryOp(exprCtx *ExprContext, op
 	// Dispatch to appropriate selector method
 	switch op.Op {
 	case zsm.OpAdd:
-		return ctx.selector.SelectAdd(leftVR, rightVR)
+		result, err := ctx.selector.SelectAdd(leftVR, rightVR)
+		if err != nil {
+			return nil, err
+		}
+		// checked arithmetic: trap on overflow
+		return result, ctx.selector.SelectOverflowTrap(isSignedOperation(op))
 
 	case zsm.OpSubtract:
-		return ctx.selector.SelectSubtract(leftVR, rightVR)
+		result, err := ctx.selector.SelectSubtract(leftVR, rightVR)
+		if err != nil {
+			return nil, err
+		}
+		return result, ctx.selector.SelectOverflowTrap(isSignedOperation(op))
 
 	case zsm.OpMultiply:
-		return ctx.selector.SelectMultiply(leftVR, rightVR)
+		// checked by SelectMultiply: 8-bit products always fit their 16 bits,
+		// 16-bit products trap when the high word is more than the sign extension
+		return ctx.selector.SelectMultiply(leftVR, rightVR, isSignedOperation(op))
 
 	case zsm.OpDivide:
-		return ctx.selector.SelectDivide(leftVR, rightVR)
+		return ctx.selector.SelectDivide(leftVR, rightVR, isSignedOperation(op))
+	case zsm.OpModulo:
+		return ctx.selector.SelectModulo(leftVR, rightVR, isSignedOperation(op))
 
 	case zsm.OpBitwiseAnd:
 		return ctx.selector.SelectBitwiseAnd(leftVR, rightVR)
@@ -500,6 +1060,11 @@ func (ctx *InstructionSelectionContext) selectBinaryOp(exprCtx *ExprContext, op
 	case zsm.OpBitwiseXor:
 		return ctx.selector.SelectBitwiseXor(leftVR, rightVR)
 
+	case zsm.OpShl:
+		return ctx.selector.SelectShiftLeft(leftVR, rightVR)
+	case zsm.OpShr:
+		return ctx.selector.SelectShiftRight(leftVR, rightVR)
+
 	case zsm.OpEqual:
 		return ctx.selector.SelectEqual(exprCtx, leftVR, rightVR)
 
@@ -522,11 +1087,28 @@ func (ctx *InstructionSelectionContext) selectBinaryOp(exprCtx *ExprContext, op
 	}
 }
 
+// isSignedOperation checks if either operand of the binary operation has a signed type
+func isSignedOperation(op *zsm.SemBinaryOp) bool {
+	return zsm.IsSigned(op.Left.Type()) || zsm.IsSigned(op.Right.Type())
+}
+
 // selectUnaryOp processes unary operations
 func (ctx *InstructionSelectionContext) selectUnaryOp(exprCtx *ExprContext, op *zsm.SemUnaryOp) (*VirtualRegister, error) {
 	// Handle LogicalNot specially - it takes expressions
 	if op.Op == zsm.OpLogicalNot {
-		return ctx.selector.SelectLogicalNot(exprCtx, op.Operand, ctx.selectExpressionWithContext)
+		return ctx.selector.SelectLogicalNot(exprCtx, op.Operand, ctx.selectCondition)
+	}
+	// x? is the non-zero test of a condition: x <> 0
+	if op.Op == zsm.OpToBool {
+		operandVR, err := ctx.selectExpression(op.Operand)
+		if err != nil {
+			return nil, err
+		}
+		zero, err := ctx.selector.SelectLoadConstant(0, operandVR.Size)
+		if err != nil {
+			return nil, err
+		}
+		return ctx.selector.SelectNotEqual(exprCtx, operandVR, zero)
 	}
 
 	// Other unary ops need VR operand
@@ -552,6 +1134,71 @@ func (ctx *InstructionSelectionContext) selectUnaryOp(exprCtx *ExprContext, op *
 
 // selectFunctionCall processes function calls
 func (ctx *InstructionSelectionContext) selectFunctionCall(exprCtx *ExprContext, call *zsm.SemFunctionCall) (*VirtualRegister, error) {
+	// intrinsics lowered directly to instructions
+	switch call.Function.Name {
+	case "@bit", "@setbit", "@resetbit":
+		return ctx.selectBitIntrinsic(exprCtx, call)
+	case "@peek", "@poke":
+		return ctx.selectMemoryIntrinsic(call)
+	case "@memchr":
+		return ctx.selectMemChrIntrinsic(call)
+	case "@memset":
+		return nil, ctx.selectMemSetIntrinsic(call)
+	case "@overflow":
+		return ctx.selectOverflowIntrinsic(exprCtx, call)
+	case "@assert":
+		// checked by the semantic analyzer
+		return nil, nil
+	case "@sizeof":
+		size, ok := zsm.ConstantValue(call)
+		if !ok {
+			return nil, fmt.Errorf("'@sizeof' requires a type or a value")
+		}
+		return ctx.selector.SelectLoadConstant(size, Bits16)
+	case "@addressof":
+		// the function label is resolved when the code is laid out
+		function, ok := zsm.AddressOfFunction(call)
+		if !ok {
+			return nil, fmt.Errorf("'@addressof' requires a function name")
+		}
+		return ctx.selector.SelectLoadDataAddress(function)
+	case "@halt":
+		return nil, ctx.selector.SelectHalt()
+	case "@nop":
+		return nil, ctx.selector.SelectNop()
+	case "@rst":
+		if len(call.Arguments) != 1 {
+			return nil, fmt.Errorf("'@rst' expects 1 argument, got %d", len(call.Arguments))
+		}
+		vector, ok := zsm.RestartVector(call.Arguments[0])
+		if !ok {
+			return nil, fmt.Errorf("vector of '@rst' must be a constant 0x00, 0x08, ... 0x38")
+		}
+		return nil, ctx.selector.SelectRestart(vector)
+	}
+
+	argVRs, err := ctx.selectCallArguments(exprCtx, call)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get return size
+	returnSize := RegisterSize(0)
+	if call.Type() != nil {
+		returnSize = RegisterSize(call.Type().Size() * 8)
+	}
+
+	// Generate call
+	return ctx.selector.SelectCall(call.Function.Name, argVRs, returnSize)
+}
+
+// selectCallArguments evaluates the arguments of a function call
+func (ctx *InstructionSelectionContext) selectCallArguments(exprCtx *ExprContext, call *zsm.SemFunctionCall) ([]*VirtualRegister, error) {
+	var paramTypes []zsm.Type
+	if fnType, ok := call.Function.Type.(*zsm.FunctionType); ok {
+		paramTypes = fnType.Parameters()
+	}
+
 	// Evaluate arguments with parameter symbols for proper stack tracking
 	argVRs := make([]*VirtualRegister, len(call.Arguments))
 	for i, arg := range call.Arguments {
@@ -568,29 +1215,175 @@ func (ctx *InstructionSelectionContext) selectFunctionCall(exprCtx *ExprContext,
 		if err != nil {
 			return nil, err
 		}
+		// a bool (comparison) or u8 passed as a u16 has its high byte zeroed
+		if i < len(paramTypes) && arg.Type() != nil {
+			vr, err = ctx.selectConversion(vr, arg.Type(), paramTypes[i])
+			if err != nil {
+				return nil, err
+			}
+		}
 		argVRs[i] = vr
 	}
+	return argVRs, nil
+}
 
-	// Get return size
-	returnSize := RegisterSize(0)
-	if call.Type() != nil {
-		returnSize = RegisterSize(call.Type().Size() * 8)
+// selectBitIntrinsic lowers @bit, @setbit and @resetbit to the CB bit instructions
+func (ctx *InstructionSelectionContext) selectBitIntrinsic(exprCtx *ExprContext, call *zsm.SemFunctionCall) (*VirtualRegister, error) {
+	if len(call.Arguments) != 2 {
+		return nil, fmt.Errorf("'%s' expects 2 arguments, got %d", call.Function.Name, len(call.Arguments))
+	}
+	bit, ok := zsm.BitIndex(call.Arguments[0])
+	if !ok {
+		return nil, fmt.Errorf("bit index of '%s' must be a constant 0-7", call.Function.Name)
+	}
+	// a global variable in memory is tested and changed in place (BIT/SET/RES b, (HL))
+	if item := ctx.memoryVariable(call.Arguments[1]); item != nil {
+		address, err := ctx.selector.SelectLoadDataAddress(item.Label)
+		if err != nil {
+			return nil, err
+		}
+		switch call.Function.Name {
+		case "@bit":
+			return ctx.selector.SelectBitTestMemory(exprCtx, bit, address)
+		case "@setbit":
+			item.Written = true
+			return nil, ctx.selector.SelectBitSetMemory(bit, address)
+		default:
+			item.Written = true
+			return nil, ctx.selector.SelectBitResetMemory(bit, address)
+		}
+	}
+	valueVR, err := ctx.selectExpression(call.Arguments[1])
+	if err != nil {
+		return nil, err
 	}
 
-	// Generate call
-	return ctx.selector.SelectCall(call.Function.Name, argVRs, returnSize)
+	switch call.Function.Name {
+	case "@bit":
+		return ctx.selector.SelectBitTest(exprCtx, bit, valueVR)
+	case "@setbit":
+		return ctx.selector.SelectBitSet(bit, valueVR)
+	default:
+		return ctx.selector.SelectBitReset(bit, valueVR)
+	}
+}
+
+// memoryVariable returns the data item of an 8-bit global variable reference
+// that is stored in the data section (not held in a register)
+func (ctx *InstructionSelectionContext) memoryVariable(expr zsm.SemExpression) *DataItem {
+	ref, ok := expr.(*zsm.SemSymbolRef)
+	if !ok || ref.Symbol.Type == nil || ref.Symbol.Type.Size() != 1 {
+		return nil
+	}
+	if _, ok := ctx.symbolToVReg[ref.Symbol]; ok {
+		return nil
+	}
+	return ctx.dataSection.FindVariable(ref.Symbol)
+}
+
+// selectOverflowIntrinsic lowers @overflow(a + b) to the arithmetic followed by a P/V flag test
+func (ctx *InstructionSelectionContext) selectOverflowIntrinsic(exprCtx *ExprContext, call *zsm.SemFunctionCall) (*VirtualRegister, error) {
+	if len(call.Arguments) != 1 {
+		return nil, fmt.Errorf("'@overflow' expects 1 argument, got %d", len(call.Arguments))
+	}
+	op, ok := call.Arguments[0].(*zsm.SemBinaryOp)
+	if !ok || (op.Op != zsm.OpAdd && op.Op != zsm.OpSubtract) {
+		return nil, fmt.Errorf("'@overflow' requires an addition or subtraction")
+	}
+
+	leftVR, err := ctx.selectExpression(op.Left)
+	if err != nil {
+		return nil, err
+	}
+	rightVR, err := ctx.selectExpression(op.Right)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.selector.SelectOverflow(exprCtx, leftVR, rightVR, op.Op == zsm.OpSubtract)
+}
+
+// selectMemoryIntrinsic lowers @peek and @poke to absolute memory loads and stores
+func (ctx *InstructionSelectionContext) selectMemoryIntrinsic(call *zsm.SemFunctionCall) (*VirtualRegister, error) {
+	if len(call.Arguments) == 0 {
+		return nil, fmt.Errorf("'%s' expects an address argument", call.Function.Name)
+	}
+	addressVR, err := ctx.selectExpression(call.Arguments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if call.Function.Name == "@peek" {
+		return ctx.selector.SelectPeek(addressVR)
+	}
+
+	if len(call.Arguments) != 2 {
+		return nil, fmt.Errorf("'%s' expects 2 arguments, got %d", call.Function.Name, len(call.Arguments))
+	}
+	valueVR, err := ctx.selectExpression(call.Arguments[1])
+	if err != nil {
+		return nil, err
+	}
+	return nil, ctx.selector.SelectPoke(addressVR, valueVR)
+}
+
+// selectMemChrIntrinsic lowers @memchr(ptr, value, len) to a block search (CPIR)
+func (ctx *InstructionSelectionContext) selectMemChrIntrinsic(call *zsm.SemFunctionCall) (*VirtualRegister, error) {
+	if len(call.Arguments) != 3 {
+		return nil, fmt.Errorf("'@memchr' expects 3 arguments, got %d", len(call.Arguments))
+	}
+	addressVR, err := ctx.selectExpression(call.Arguments[0])
+	if err != nil {
+		return nil, err
+	}
+	valueVR, err := ctx.selectExpression(call.Arguments[1])
+	if err != nil {
+		return nil, err
+	}
+	lengthVR, err := ctx.selectExpression(call.Arguments[2])
+	if err != nil {
+		return nil, err
+	}
+	lengthVR, err = ctx.selectConversion(lengthVR, call.Arguments[2].Type(), zsm.U16Type)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.selector.SelectMemChr(addressVR, valueVR, lengthVR)
+}
+
+// selectMemSetIntrinsic lowers @memset(ptr, value, len) to stores or a block copy (LDIR)
+func (ctx *InstructionSelectionContext) selectMemSetIntrinsic(call *zsm.SemFunctionCall) error {
+	if len(call.Arguments) != 3 {
+		return fmt.Errorf("'@memset' expects 3 arguments, got %d", len(call.Arguments))
+	}
+	addressVR, err := ctx.selectExpression(call.Arguments[0])
+	if err != nil {
+		return err
+	}
+	valueVR, err := ctx.selectExpression(call.Arguments[1])
+	if err != nil {
+		return err
+	}
+	lengthVR, err := ctx.selectExpression(call.Arguments[2])
+	if err != nil {
+		return err
+	}
+	lengthVR, err = ctx.selectConversion(lengthVR, call.Arguments[2].Type(), zsm.U16Type)
+	if err != nil {
+		return err
+	}
+	return ctx.selector.SelectMemSet(addressVR, valueVR, lengthVR)
 }
 
 // selectMemberAccess processes struct member access
 func (ctx *InstructionSelectionContext) selectMemberAccess(access *zsm.SemMemberAccess) (*VirtualRegister, error) {
-	// Get the object
-	objectVR, err := ctx.selectExpression(*access.Object)
+	// Nested struct members are loaded from the outer object: 'line.start.y' => line + offset
+	object, offset := access.BaseOffset()
+	objectVR, err := ctx.selectExpression(object)
 	if err != nil {
 		return nil, err
 	}
 
 	// Load member at offset
-	offset := access.Field.Offset
 	regSize := RegisterSize(access.Type().Size() * 8)
 	return ctx.selector.SelectLoad(objectVR, offset, regSize)
 }
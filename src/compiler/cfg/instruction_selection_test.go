@@ -1,6 +1,9 @@
 package cfg
 
 import (
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
 	"zenith/compiler/zsm"
 
@@ -125,9 +128,12 @@ func Test_InstructionSelection_BinaryOp_AllOperators(t *testing.T) {
 		{"Subtract", zsm.OpSubtract},
 		{"Multiply", zsm.OpMultiply},
 		{"Divide", zsm.OpDivide},
+		{"Modulo", zsm.OpModulo},
 		{"BitwiseAnd", zsm.OpBitwiseAnd},
 		{"BitwiseOr", zsm.OpBitwiseOr},
 		{"BitwiseXor", zsm.OpBitwiseXor},
+		{"ShiftLeft", zsm.OpShl},
+		{"ShiftRight", zsm.OpShr},
 		{"Equal", zsm.OpEqual},
 		{"NotEqual", zsm.OpNotEqual},
 		{"LessThan", zsm.OpLessThan},
@@ -168,6 +174,46 @@ func Test_InstructionSelection_BinaryOp_AllOperators(t *testing.T) {
 	}
 }
 
+// Test that operand signedness selects the signed runtime helpers
+func Test_InstructionSelection_BinaryOp_SignedDivide(t *testing.T) {
+	tests := []struct {
+		name   string
+		typ    zsm.Type
+		helper string
+	}{
+		{"u8", zsm.U8Type, "__div8"},
+		{"i8", zsm.I8Type, "__idiv8"},
+		{"u16", zsm.U16Type, "__div16"},
+		{"i16", zsm.I16Type, "__idiv16"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := newTestBlock()
+
+			vrAlloc := NewVirtualRegisterAllocator()
+			selector := NewInstructionSelectorZ80(vrAlloc)
+			selector.SetCurrentBlock(block)
+			ctx := NewInstructionSelectionContext(selector, vrAlloc)
+			ctx.currentBlock = block
+
+			sym := &zsm.Symbol{Name: "x", Type: tt.typ}
+			regs := Z80Registers8
+			if tt.typ.Size() == 2 {
+				regs = Z80Registers16
+			}
+			ctx.symbolToVReg[sym] = vrAlloc.AllocateNamed("x", regs)
+
+			// x / 3 (the literal is unsigned, x decides)
+			binaryOp := newSemBinaryOp(zsm.OpDivide, &zsm.SemSymbolRef{Symbol: sym}, newSemConstant(3, u8Type()), tt.typ)
+			_, err := ctx.selectBinaryOp(nil, binaryOp)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.helper, calledHelper(block.MachineInstructions))
+		})
+	}
+}
+
 // Test logical AND and OR with proper branch contexts
 func Test_InstructionSelection_LogicalOperators(t *testing.T) {
 	tests := []struct {
@@ -411,6 +457,479 @@ func Test_InstructionSelection_FunctionCall(t *testing.T) {
 	assert.NotEmpty(t, instructions)
 }
 
+// Test @setbit lowering to SET b, r on the variable register
+func Test_InstructionSelection_SetBitIntrinsic(t *testing.T) {
+	block := newTestBlock()
+
+	vrAlloc := NewVirtualRegisterAllocator()
+	selector := NewInstructionSelectorZ80(vrAlloc)
+	selector.SetCurrentBlock(block)
+	ctx := NewInstructionSelectionContext(selector, vrAlloc)
+	ctx.currentBlock = block
+
+	flags := &zsm.Symbol{Name: "flags", Kind: zsm.SymbolVariable, Type: u8Type()}
+	flagsVR := vrAlloc.AllocateNamed("flags", Z80Registers8)
+	ctx.symbolToVReg[flags] = flagsVR
+
+	// @setbit(3, flags)
+	call := &zsm.SemFunctionCall{
+		Function: &zsm.Symbol{Name: "@setbit", Kind: zsm.SymbolType, Type: zsm.SetBitFnType},
+		Arguments: []zsm.SemExpression{
+			newSemConstant(3, u8Type()),
+			&zsm.SemSymbolRef{Symbol: flags},
+		},
+	}
+
+	vr, err := ctx.selectFunctionCall(nil, call)
+
+	require.NoError(t, err)
+	assert.Equal(t, flagsVR, vr)
+	require.Len(t, block.MachineInstructions, 1)
+
+	set := block.MachineInstructions[0].(*machineInstructionZ80)
+	assert.Equal(t, Z80_SET_B_R, set.opcode)
+	assert.Equal(t, flagsVR, set.GetResult())
+	require.Len(t, set.GetOperands(), 2)
+	assert.Equal(t, ImmediateValue, set.GetOperands()[0].Type)
+	assert.Equal(t, int32(3), set.GetOperands()[0].Value)
+	assert.Equal(t, flagsVR, set.GetOperands()[1])
+}
+
+// selectGlobalsCode runs instruction selection on the function of the code
+// with the global variables stored in the data section
+func selectGlobalsCode(t *testing.T, code string) []*machineInstructionZ80 {
+	semCU := analyzeCFGCode(t, code)
+	data := NewDataSection()
+	require.NoError(t, data.AddVariables(semCU.Declarations))
+
+	var fnCFG *CFG
+	for _, decl := range semCU.Declarations {
+		if fn, ok := decl.(*zsm.SemFunctionDecl); ok {
+			var err error
+			fnCFG, err = NewCFGBuilder().BuildCFG(fn)
+			require.NoError(t, err)
+		}
+	}
+	require.NotNil(t, fnCFG)
+
+	vrAlloc := NewVirtualRegisterAllocator()
+	ctx := NewInstructionSelectionContext(NewInstructionSelectorZ80(vrAlloc), vrAlloc)
+	ctx.dataSection = data
+	require.NoError(t, ctx.selectCFG(fnCFG))
+
+	var instrs []*machineInstructionZ80
+	for _, block := range fnCFG.Blocks {
+		if block != fnCFG.Entry && block != fnCFG.Exit {
+			for _, instr := range block.MachineInstructions {
+				instrs = append(instrs, instr.(*machineInstructionZ80))
+			}
+		}
+	}
+	return instrs
+}
+
+// Test a subscript of a global array in the data section indexing from its label
+func Test_InstructionSelection_GlobalArray_Subscript(t *testing.T) {
+	code := `sine: = @db(0, 49, 90, 117, 127)
+	main: () u8 {
+		ret sine[3]
+	}`
+
+	var loadsLabel bool
+	for _, instr := range selectGlobalsCode(t, code) {
+		if instr.opcode == Z80_LD_RR_NN && instr.comment == "sine" {
+			loadsLabel = true
+		}
+	}
+	assert.True(t, loadsLabel)
+}
+
+// Test a single-bit compound assignment on a global in memory lowering to SET/RES b, (HL)
+func Test_InstructionSelection_CompoundAssignment_MemoryBit(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		opcode    Z80Opcode
+		bit       int32
+	}{
+		{"set", "flags |= 0x04", Z80_SET_B_HL, 2},
+		{"set shifted", "flags |= 1 << 7", Z80_SET_B_HL, 7},
+		{"reset", "flags &= 0xFB", Z80_RES_B_HL, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := "flags: u8 = 0\nmain: () {\n\t" + tt.statement + "\n}"
+			instrs := selectGlobalsCode(t, code)
+
+			var update *machineInstructionZ80
+			for _, instr := range instrs {
+				assert.NotEqual(t, Z80_LD_HL_R, instr.opcode, "no store of a loaded value")
+				if instr.opcode == tt.opcode {
+					update = instr
+				}
+			}
+			require.NotNil(t, update)
+			assert.Nil(t, update.GetResult())
+			require.Len(t, update.GetOperands(), 2)
+			assert.Equal(t, tt.bit, update.GetOperands()[0].Value)
+			assert.Equal(t, Z80RegHL, update.GetOperands()[1].AllowedSet)
+		})
+	}
+}
+
+// Test @setbit/@resetbit on a global in memory lowering to SET/RES b, (HL)
+func Test_InstructionSelection_BitIntrinsic_MemoryGlobal(t *testing.T) {
+	tests := []struct {
+		name   string
+		call   string
+		opcode Z80Opcode
+	}{
+		{"setbit", "@setbit(3, flags)", Z80_SET_B_HL},
+		{"resetbit", "@resetbit(3, flags)", Z80_RES_B_HL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := "flags: u8 = 0\nmain: () {\n\t" + tt.call + "\n}"
+			instrs := selectGlobalsCode(t, code)
+
+			var update *machineInstructionZ80
+			for _, instr := range instrs {
+				if instr.opcode == tt.opcode {
+					update = instr
+				}
+			}
+			require.NotNil(t, update, "the bit is changed in memory")
+			require.Len(t, update.GetOperands(), 2)
+			assert.Equal(t, int32(3), update.GetOperands()[0].Value)
+			assert.Equal(t, Z80RegHL, update.GetOperands()[1].AllowedSet)
+		})
+	}
+}
+
+// Test a compound assignment on a global in memory that is not a single bit: load, modify, store
+func Test_InstructionSelection_CompoundAssignment_Memory(t *testing.T) {
+	code := `flags: u8 = 0
+	main: () {
+		flags |= 0x06
+	}`
+	opcodes := []Z80Opcode{}
+	for _, instr := range selectGlobalsCode(t, code) {
+		opcodes = append(opcodes, instr.opcode)
+	}
+
+	assert.Contains(t, opcodes, Z80_LD_R_HL)
+	assert.Contains(t, opcodes, Z80_LD_HL_R)
+	assert.NotContains(t, opcodes, Z80_SET_B_HL)
+}
+
+// Test a one-bit shift of a global in memory lowering to SLA/SRL/SRA (HL)
+func Test_InstructionSelection_Assignment_MemoryShift(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   string
+		opcode Z80Opcode
+	}{
+		{"left", "value: u8 = 1\nmain: () {\n\tvalue = value << 1\n}", Z80_SLA_HL},
+		{"right", "value: u8 = 1\nmain: () {\n\tvalue = value >> 1\n}", Z80_SRL_HL},
+		{"right signed", "value: i8 = 1\nmain: () {\n\tvalue = value >> 1\n}", Z80_SRA_HL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var shift *machineInstructionZ80
+			for _, instr := range selectGlobalsCode(t, tt.code) {
+				assert.NotEqual(t, Z80_LD_HL_R, instr.opcode, "no store of a loaded value")
+				if instr.opcode == tt.opcode {
+					shift = instr
+				}
+			}
+			require.NotNil(t, shift)
+			assert.Nil(t, shift.GetResult())
+			require.Len(t, shift.GetOperands(), 1)
+			assert.Equal(t, Z80RegHL, shift.GetOperands()[0].AllowedSet)
+		})
+	}
+}
+
+// Test @bit on a global in memory lowering to BIT b, (HL)
+func Test_InstructionSelection_BitIntrinsic_Memory(t *testing.T) {
+	code := `flags: u8 = 0
+	main: () {
+		if @bit(3, flags) {
+			flags = 0
+		}
+	}`
+
+	var bit *machineInstructionZ80
+	for _, instr := range selectGlobalsCode(t, code) {
+		assert.NotEqual(t, Z80_BIT_B_R, instr.opcode)
+		if instr.opcode == Z80_BIT_B_HL {
+			bit = instr
+		}
+	}
+	require.NotNil(t, bit)
+	require.Len(t, bit.GetOperands(), 2)
+	assert.Equal(t, int32(3), bit.GetOperands()[0].Value)
+	assert.Equal(t, Z80RegHL, bit.GetOperands()[1].AllowedSet)
+}
+
+// Test a swap of two 16-bit variables lowering to EX DE, HL without a temporary
+func Test_InstructionSelection_MultiAssignment_Swap16(t *testing.T) {
+	code := `main: () {
+		a: u16 = 0x1234
+		b: u16 = 0x5678
+		a, b = b, a
+	}`
+
+	var exchange *machineInstructionZ80
+	for _, instr := range selectGlobalsCode(t, code) {
+		assert.NotEqual(t, Z80_LD_R_R, instr.opcode, "no copy into a temporary")
+		if instr.opcode == Z80_EX_DE_HL {
+			exchange = instr
+		}
+	}
+	require.NotNil(t, exchange)
+	assert.Equal(t, Z80RegDE, exchange.GetResult().AllowedSet)
+	require.Len(t, exchange.GetOperands(), 1)
+	assert.Equal(t, Z80RegHL, exchange.GetOperands()[0].AllowedSet)
+}
+
+// Test a swap of two 8-bit variables through a temporary register
+func Test_InstructionSelection_MultiAssignment_Swap8(t *testing.T) {
+	code := `main: () {
+		a: u8 = 1
+		b: u8 = 2
+		a, b = b, a
+	}`
+
+	loads := 0
+	for _, instr := range selectGlobalsCode(t, code) {
+		assert.NotEqual(t, Z80_EX_DE_HL, instr.opcode)
+		if instr.opcode == Z80_LD_R_R {
+			loads++
+		}
+	}
+	// LD tmp, a; LD a, b; LD b, tmp
+	assert.Equal(t, 3, loads)
+}
+
+// Test a multiple assignment that reads another target: the value is copied before the stores
+func Test_InstructionSelection_MultiAssignment_CopiesTargets(t *testing.T) {
+	code := `main: () {
+		a: u8 = 1
+		b: u8 = 2
+		c: u8 = 3
+		a, b, c = b, c, 4
+	}`
+	semCU := analyzeCFGCode(t, code)
+	fn := semCU.Declarations[0].(*zsm.SemFunctionDecl)
+	fnCFG, err := NewCFGBuilder().BuildCFG(fn)
+	require.NoError(t, err)
+
+	vrAlloc := NewVirtualRegisterAllocator()
+	ctx := NewInstructionSelectionContext(NewInstructionSelectorZ80(vrAlloc), vrAlloc)
+	require.NoError(t, ctx.selectCFG(fnCFG))
+
+	var copies []*machineInstructionZ80
+	for _, block := range fnCFG.Blocks {
+		for _, instr := range block.MachineInstructions {
+			if instr := instr.(*machineInstructionZ80); instr.opcode == Z80_LD_R_R {
+				copies = append(copies, instr)
+			}
+		}
+	}
+	// b and c are copied, the constant 4 is not
+	require.Len(t, copies, 2)
+	symbols := fn.Body.Statements[3].(*zsm.SemMultiAssignment).Targets
+	assert.Equal(t, ctx.symbolToVReg[symbols[1]], copies[0].GetOperands()[0])
+	assert.Equal(t, ctx.symbolToVReg[symbols[2]], copies[1].GetOperands()[0])
+}
+
+// Test @bit lowering to BIT b, r with the result in A
+func Test_InstructionSelection_BitIntrinsic(t *testing.T) {
+	block := newTestBlock()
+
+	vrAlloc := NewVirtualRegisterAllocator()
+	selector := NewInstructionSelectorZ80(vrAlloc)
+	selector.SetCurrentBlock(block)
+	ctx := NewInstructionSelectionContext(selector, vrAlloc)
+	ctx.currentBlock = block
+
+	flags := &zsm.Symbol{Name: "flags", Kind: zsm.SymbolVariable, Type: u8Type()}
+	flagsVR := vrAlloc.AllocateNamed("flags", Z80Registers8)
+	ctx.symbolToVReg[flags] = flagsVR
+
+	// @bit(7, flags)
+	call := &zsm.SemFunctionCall{
+		Function: &zsm.Symbol{Name: "@bit", Kind: zsm.SymbolType, Type: zsm.BitFnType},
+		Arguments: []zsm.SemExpression{
+			newSemConstant(7, u8Type()),
+			&zsm.SemSymbolRef{Symbol: flags},
+		},
+		TypeInfo: zsm.BitType,
+	}
+
+	vr, err := ctx.selectFunctionCall(nil, call)
+
+	require.NoError(t, err)
+	assert.Equal(t, Z80RegA, vr.AllowedSet)
+	require.NotEmpty(t, block.MachineInstructions)
+
+	bit := block.MachineInstructions[0].(*machineInstructionZ80)
+	assert.Equal(t, Z80_BIT_B_R, bit.opcode)
+	assert.Nil(t, bit.GetResult())
+	assert.Equal(t, int32(7), bit.GetOperands()[0].Value)
+	assert.Equal(t, flagsVR, bit.GetOperands()[1])
+}
+
+// Test @peek with a constant address lowering to LD A, (NN)
+func Test_InstructionSelection_PeekIntrinsic(t *testing.T) {
+	block := newTestBlock()
+
+	vrAlloc := NewVirtualRegisterAllocator()
+	selector := NewInstructionSelectorZ80(vrAlloc)
+	selector.SetCurrentBlock(block)
+	ctx := NewInstructionSelectionContext(selector, vrAlloc)
+	ctx.currentBlock = block
+
+	// @peek(0x4000)
+	call := &zsm.SemFunctionCall{
+		Function:  &zsm.Symbol{Name: "@peek", Kind: zsm.SymbolType, Type: zsm.PeekFnType},
+		Arguments: []zsm.SemExpression{newSemConstant(0x4000, u16Type())},
+		TypeInfo:  u8Type(),
+	}
+
+	vr, err := ctx.selectFunctionCall(nil, call)
+
+	require.NoError(t, err)
+	assert.Equal(t, Z80RegA, vr.AllowedSet)
+	require.Len(t, block.MachineInstructions, 1)
+
+	load := block.MachineInstructions[0].(*machineInstructionZ80)
+	assert.Equal(t, Z80_LD_A_NN, load.opcode)
+	assert.Equal(t, vr, load.GetResult())
+	assert.Equal(t, ImmediateValue, load.GetOperands()[0].Type)
+	assert.Equal(t, int32(0x4000), load.GetOperands()[0].Value)
+}
+
+// Test @poke with a constant address lowering to LD A, n; LD (NN), A
+func Test_InstructionSelection_PokeIntrinsic(t *testing.T) {
+	block := newTestBlock()
+
+	vrAlloc := NewVirtualRegisterAllocator()
+	selector := NewInstructionSelectorZ80(vrAlloc)
+	selector.SetCurrentBlock(block)
+	ctx := NewInstructionSelectionContext(selector, vrAlloc)
+	ctx.currentBlock = block
+
+	// @poke(0x4000, 7)
+	call := &zsm.SemFunctionCall{
+		Function: &zsm.Symbol{Name: "@poke", Kind: zsm.SymbolType, Type: zsm.PokeFnType},
+		Arguments: []zsm.SemExpression{
+			newSemConstant(0x4000, u16Type()),
+			newSemConstant(7, u8Type()),
+		},
+	}
+
+	vr, err := ctx.selectFunctionCall(nil, call)
+
+	require.NoError(t, err)
+	assert.Nil(t, vr)
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_N, Z80_LD_NN_A}, opcodesOf(block.MachineInstructions))
+
+	store := block.MachineInstructions[1].(*machineInstructionZ80)
+	assert.Equal(t, int32(0x4000), store.GetOperands()[0].Value)
+	assert.Equal(t, Z80RegA, store.GetOperands()[1].AllowedSet)
+}
+
+// Test @halt lowering to HALT
+func Test_InstructionSelection_HaltIntrinsic(t *testing.T) {
+	block := newTestBlock()
+
+	vrAlloc := NewVirtualRegisterAllocator()
+	selector := NewInstructionSelectorZ80(vrAlloc)
+	selector.SetCurrentBlock(block)
+	ctx := NewInstructionSelectionContext(selector, vrAlloc)
+	ctx.currentBlock = block
+
+	call := &zsm.SemFunctionCall{
+		Function:  &zsm.Symbol{Name: "@halt", Kind: zsm.SymbolType, Type: zsm.HaltFnType},
+		Arguments: []zsm.SemExpression{},
+	}
+
+	vr, err := ctx.selectFunctionCall(nil, call)
+
+	require.NoError(t, err)
+	assert.Nil(t, vr)
+	assert.Equal(t, []Z80Opcode{Z80_HALT}, opcodesOf(block.MachineInstructions))
+	assert.Equal(t, "HALT", Z80_HALT.String())
+}
+
+// Test @rst lowering to the one-byte RST p instruction
+func Test_InstructionSelection_RestartIntrinsic(t *testing.T) {
+	block := newTestBlock()
+
+	vrAlloc := NewVirtualRegisterAllocator()
+	selector := NewInstructionSelectorZ80(vrAlloc)
+	selector.SetCurrentBlock(block)
+	ctx := NewInstructionSelectionContext(selector, vrAlloc)
+	ctx.currentBlock = block
+
+	// @rst(0x10)
+	call := &zsm.SemFunctionCall{
+		Function:  &zsm.Symbol{Name: "@rst", Kind: zsm.SymbolType, Type: zsm.RstFnType},
+		Arguments: []zsm.SemExpression{newSemConstant(0x10, u8Type())},
+	}
+
+	_, err := ctx.selectFunctionCall(nil, call)
+
+	require.NoError(t, err)
+	require.Len(t, block.MachineInstructions, 1)
+	rst := block.MachineInstructions[0].(*machineInstructionZ80)
+	assert.Equal(t, Z80_RST_P, rst.opcode)
+	assert.Equal(t, InstructionCost{Cycles: 11, Size: 1}, rst.GetCost())
+
+	// RST 10h encodes as 0xD7: p (=2) shifted into bits 3-5 of 0xC7
+	desc := Z80InstrDescriptors[Z80_RST_P]
+	p := uint8(rst.GetOperands()[0].Value)
+	assert.Equal(t, uint8(0xD7), uint8(rst.opcode)|p<<desc.EncodingReg1SL)
+}
+
+// Test @assert producing no code (it is checked at compile time)
+func Test_InstructionSelection_AssertIntrinsic(t *testing.T) {
+	opcodes := selectFunctionCode(t, `check: () {
+		@assert(1 + 1 == 2)
+	}`)
+
+	assert.Equal(t, []Z80Opcode{Z80_JP_NN}, opcodes)
+}
+
+// Test @rst rejecting a vector that is not a page-zero restart address
+func Test_InstructionSelection_RestartIntrinsic_InvalidVector(t *testing.T) {
+	block := newTestBlock()
+
+	vrAlloc := NewVirtualRegisterAllocator()
+	selector := NewInstructionSelectorZ80(vrAlloc)
+	selector.SetCurrentBlock(block)
+	ctx := NewInstructionSelectionContext(selector, vrAlloc)
+	ctx.currentBlock = block
+
+	// @rst(0x05)
+	call := &zsm.SemFunctionCall{
+		Function:  &zsm.Symbol{Name: "@rst", Kind: zsm.SymbolType, Type: zsm.RstFnType},
+		Arguments: []zsm.SemExpression{newSemConstant(0x05, u8Type())},
+	}
+
+	_, err := ctx.selectFunctionCall(nil, call)
+
+	require.Error(t, err)
+	assert.Empty(t, block.MachineInstructions)
+
+	assert.Error(t, selector.SelectRestart(0x40))
+}
+
 // Test expression caching
 func Test_InstructionSelection_ExpressionCaching(t *testing.T) {
 	block := newTestBlock()
@@ -512,11 +1031,12 @@ func Test_InstructionSelection_Function_WithParameters(t *testing.T) {
 
 	// Build CFG
 	builder := NewCFGBuilder()
-	cfg := builder.BuildCFG(fn)
+	cfg, err := builder.BuildCFG(fn)
+	require.NoError(t, err)
 	require.NotNil(t, cfg)
 
 	selector := NewInstructionSelectorZ80(vrAlloc)
-	err := SelectInstructions([]*CFG{cfg}, vrAlloc, selector)
+	err = SelectInstructions([]*CFG{cfg}, vrAlloc, selector)
 	require.NoError(t, err)
 
 	// Check that instructions were generated
@@ -546,12 +1066,13 @@ func Test_SelectInstructions_Simple(t *testing.T) {
 	}
 
 	// Build CFGs first
-	cfgs := BuildCFGs(compilationUnit)
+	cfgs, err := BuildCFGs(compilationUnit)
+	require.NoError(t, err)
 	require.Len(t, cfgs, 1)
 
 	// Then select instructions
 	selector := NewInstructionSelectorZ80(vrAlloc)
-	err := SelectInstructions(cfgs, vrAlloc, selector)
+	err = SelectInstructions(cfgs, vrAlloc, selector)
 	require.NoError(t, err)
 	require.Len(t, cfgs, 1)
 
@@ -689,3 +1210,740 @@ func Test_InstructionSelection_16BitOperations(t *testing.T) {
 	instructions := block.MachineInstructions
 	assert.NotEmpty(t, instructions)
 }
+
+// Test '@fast' function switches register sets and uses the shadow registers
+func Test_SelectInstructions_FastFunction(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	fn := &zsm.SemFunctionDecl{
+		Name:       "isr",
+		Attributes: []string{"fast"},
+		Parameters: []*zsm.Symbol{},
+		Body: &zsm.SemBlock{
+			Statements: []zsm.SemStatement{
+				&zsm.SemVariableDecl{
+					Symbol:      &zsm.Symbol{Name: "x", Type: u8Type()},
+					Initializer: &zsm.SemConstant{Value: 42, TypeInfo: u8Type()},
+					TypeInfo:    u8Type(),
+				},
+			},
+		},
+	}
+
+	cfgs, err := BuildCFGs(&zsm.SemCompilationUnit{Declarations: []zsm.SemDeclaration{fn}})
+	require.NoError(t, err)
+	require.Len(t, cfgs, 1)
+
+	selector := NewInstructionSelectorZ80(vrAlloc)
+	err = SelectInstructions(cfgs, vrAlloc, selector)
+	require.NoError(t, err)
+
+	fnCFG := cfgs[0]
+	assert.True(t, fnCFG.UsesShadowRegisters)
+
+	// switch to the alternate set before the frame is set up, and back after it is torn down
+	assert.Equal(t, []Z80Opcode{Z80_EXX, Z80_EX_AF_AF, Z80_LD_HL_NN, Z80_ADD_HL_RR, Z80_LD_SP_HL},
+		opcodesOf(fnCFG.Entry.MachineInstructions))
+	assert.Equal(t, []Z80Opcode{Z80_LD_HL_NN, Z80_ADD_HL_RR, Z80_LD_SP_HL, Z80_EX_AF_AF, Z80_EXX, Z80_RET},
+		opcodesOf(fnCFG.Exit.MachineInstructions))
+
+	// all register constraints are remapped to the alternate set (SP is shared)
+	for _, instr := range fnCFG.GetAllInstructions() {
+		vrs := append([]*VirtualRegister{instr.GetResult()}, instr.GetOperands()...)
+		for _, vr := range vrs {
+			if vr == nil {
+				continue
+			}
+			for _, reg := range vr.AllowedSet {
+				assert.Contains(t, Z80RegistersShadow, reg, "%s in %s", reg.Name, instr.String())
+			}
+		}
+	}
+
+	// the allocator picks from the shadow registers
+	liveness := ComputeLiveness(fnCFG)
+	interference := BuildInterferenceGraph(fnCFG, liveness)
+	allocator := NewRegisterAllocator(selector.GetCallingConvention().GetShadowRegisters())
+	allocator.Allocate(fnCFG, interference)
+	for _, vr := range vrAlloc.GetAll() {
+		if vr.PhysicalReg != nil {
+			assert.Contains(t, Z80RegistersShadow, vr.PhysicalReg, vr.String())
+		}
+	}
+}
+
+func Test_ShadowRegisters_Mapping(t *testing.T) {
+	shadows := ShadowRegisters([]*Register{&RegA, &RegHL, &RegSP})
+
+	require.Len(t, shadows, 3)
+	assert.Equal(t, &RegAShadow, shadows[0])
+	assert.Equal(t, &RegHLShadow, shadows[1])
+	assert.Equal(t, &RegSP, shadows[2])
+	assert.Equal(t, []*Register{&RegLShadow, &RegHShadow}, RegHLShadow.Composition)
+}
+
+// Test interrupt handlers save all registers and return with RETI/RETN
+func Test_SelectInstructions_InterruptHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		attribute string
+		ret       []Z80Opcode
+	}{
+		{"interrupt", zsm.AttributeInterrupt, []Z80Opcode{Z80_EI, Z80_RETI}},
+		{"nmi", zsm.AttributeNMI, []Z80Opcode{Z80_RETN}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vrAlloc := NewVirtualRegisterAllocator()
+			fn := &zsm.SemFunctionDecl{
+				Name:       "handler",
+				Attributes: []string{tt.attribute},
+				Parameters: []*zsm.Symbol{},
+				Body:       &zsm.SemBlock{},
+			}
+
+			cfgs, err := BuildCFGs(&zsm.SemCompilationUnit{Declarations: []zsm.SemDeclaration{fn}})
+			require.NoError(t, err)
+			require.Len(t, cfgs, 1)
+			err = SelectInstructions(cfgs, vrAlloc, NewInstructionSelectorZ80(vrAlloc))
+			require.NoError(t, err)
+
+			entry := cfgs[0].Entry.MachineInstructions
+			assert.Equal(t, []Z80Opcode{Z80_PUSH_QQ, Z80_PUSH_QQ, Z80_PUSH_QQ, Z80_PUSH_QQ}, opcodesOf(entry))
+			saved := make([]*Register, 0, len(entry))
+			for _, instr := range entry {
+				saved = append(saved, instr.GetOperands()[0].AllowedSet...)
+			}
+			assert.Equal(t, []*Register{&RegAF, &RegBC, &RegDE, &RegHL}, saved)
+
+			expected := append([]Z80Opcode{Z80_POP_QQ, Z80_POP_QQ, Z80_POP_QQ, Z80_POP_QQ}, tt.ret...)
+			exit := cfgs[0].Exit.MachineInstructions
+			assert.Equal(t, expected, opcodesOf(exit))
+			assert.Equal(t, []*Register{&RegHL}, exit[0].GetResult().AllowedSet)
+		})
+	}
+}
+
+// Test '@fast' interrupt handlers switch register sets instead of saving registers
+func Test_SelectInstructions_FastInterruptHandler(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+	fn := &zsm.SemFunctionDecl{
+		Name:       "handler",
+		Attributes: []string{zsm.AttributeFast, zsm.AttributeInterrupt},
+		Parameters: []*zsm.Symbol{},
+		Body:       &zsm.SemBlock{},
+	}
+
+	cfgs, err := BuildCFGs(&zsm.SemCompilationUnit{Declarations: []zsm.SemDeclaration{fn}})
+	require.NoError(t, err)
+	require.Len(t, cfgs, 1)
+	err = SelectInstructions(cfgs, vrAlloc, NewInstructionSelectorZ80(vrAlloc))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Z80Opcode{Z80_EXX, Z80_EX_AF_AF}, opcodesOf(cfgs[0].Entry.MachineInstructions))
+	assert.Equal(t, []Z80Opcode{Z80_EX_AF_AF, Z80_EXX, Z80_EI, Z80_RETI}, opcodesOf(cfgs[0].Exit.MachineInstructions))
+}
+
+// Test the callee reads the stack parameters where the caller pushed them
+func Test_SelectInstructions_StackParameterFrameOffsets(t *testing.T) {
+	code := `five: (a: u16, b: u16, c: u16, d: u16, e: u8) u16 {
+		x: u8 = 1
+		ret a
+	}`
+	fnCFG := buildCFGFromCode(t, code)
+
+	vrAlloc := NewVirtualRegisterAllocator()
+	ctx := NewInstructionSelectionContext(NewInstructionSelectorZ80(vrAlloc), vrAlloc)
+	require.NoError(t, ctx.selectCFG(fnCFG))
+
+	params := fnCFG.FunctionDecl.Parameters
+	frameSize := fnCFG.FrameLayout.nextOffset
+	require.Equal(t, uint16(1), frameSize)
+
+	for i, param := range params[:3] {
+		assert.Equal(t, AllocatedRegister, ctx.symbolToVReg[param].Type, "param %d", i)
+	}
+
+	// return address (2) + pushed slots (2 each), moved up by the local frame
+	d := ctx.symbolToVReg[params[3]]
+	e := ctx.symbolToVReg[params[4]]
+	assert.Equal(t, StackLocation, d.Type)
+	assert.Equal(t, StackLocation, e.Type)
+	assert.Equal(t, int32(frameSize)+2, d.Value)
+	assert.Equal(t, int32(frameSize)+4, e.Value)
+}
+
+// Test the stack parameters are placed above the complete frame, slots added while selecting included
+func Test_SelectInstructions_StackParameters_AfterFrameSlots(t *testing.T) {
+	fnCFG := buildCFGFromCode(t, `five: (a: u16, b: u16, c: u16, d: u16) u16 {
+		ret a
+	}`)
+	vrAlloc := NewVirtualRegisterAllocator()
+	ctx := NewInstructionSelectionContext(NewInstructionSelectorZ80(vrAlloc), vrAlloc)
+	ctx.currentCFG = fnCFG
+	d := fnCFG.FunctionDecl.Parameters[3]
+	ctx.symbolToVReg[d] = vrAlloc.AllocateOnStack(d.Name, Bits16, 2)
+
+	// a slot added after the parameter was allocated
+	fnCFG.FrameLayout.AddSlot(&zsm.Symbol{Name: "data"}, 3)
+	require.NoError(t, ctx.placeStackParameters([]*zsm.Symbol{d}))
+
+	assert.Equal(t, int32(5), ctx.symbolToVReg[d].Value)
+}
+
+func Test_SelectInstructions_StackParameters_OutOfReach(t *testing.T) {
+	fnCFG := buildCFGFromCode(t, `five: (a: u16, b: u16, c: u16, d: u16) u16 {
+		ret a
+	}`)
+	vrAlloc := NewVirtualRegisterAllocator()
+	ctx := NewInstructionSelectionContext(NewInstructionSelectorZ80(vrAlloc), vrAlloc)
+	ctx.currentCFG = fnCFG
+	d := fnCFG.FunctionDecl.Parameters[3]
+	ctx.symbolToVReg[d] = vrAlloc.AllocateOnStack(d.Name, Bits16, 2)
+	fnCFG.FrameLayout.AddSlot(&zsm.Symbol{Name: "data"}, 254)
+
+	err := ctx.placeStackParameters([]*zsm.Symbol{d})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "parameter 'd' is out of reach")
+}
+
+// selectFunctionCode runs instruction selection on the first function of the code
+// selectFunctionCode runs instruction selection on the first function of the code
+// and returns the opcodes of the function body (entry and exit excluded)
+func selectFunctionCode(t *testing.T, code string) []Z80Opcode {
+	fnCFG := buildCFGFromCode(t, code)
+	vrAlloc := NewVirtualRegisterAllocator()
+	ctx := NewInstructionSelectionContext(NewInstructionSelectorZ80(vrAlloc), vrAlloc)
+	require.NoError(t, ctx.selectCFG(fnCFG))
+
+	var instrs []MachineInstruction
+	for _, block := range fnCFG.Blocks {
+		if block != fnCFG.Entry && block != fnCFG.Exit {
+			instrs = append(instrs, block.MachineInstructions...)
+		}
+	}
+	return opcodesOf(instrs)
+}
+
+func Test_InstructionSelection_TailCall(t *testing.T) {
+	code := `twice: (v: u8) u8 {
+		ret helper(v)
+	}
+	helper: (v: u8) u8 {
+		ret v
+	}`
+
+	opcodes := selectFunctionCode(t, code)
+
+	assert.Contains(t, opcodes, Z80_JP_NN)
+	assert.NotContains(t, opcodes, Z80_CALL_NN)
+}
+
+func Test_InstructionSelection_TailCall_NotApplicable(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"stack frame", `twice: (v: u8) u8 {
+			x: u8 = v
+			ret helper(x)
+		}
+		helper: (v: u8) u8 {
+			ret v
+		}`},
+		{"return type mismatch", `twice: (v: u8) u16 {
+			ret helper(v)
+		}
+		helper: (v: u8) u8 {
+			ret v
+		}`},
+		{"stack arguments", `twice: (v: u8) u8 {
+			ret helper(v, v, v, v)
+		}
+		helper: (a: u8, b: u8, c: u8, d: u8) u8 {
+			ret a
+		}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opcodes := selectFunctionCode(t, tt.code)
+			assert.Contains(t, opcodes, Z80_CALL_NN)
+		})
+	}
+}
+
+func Test_InstructionSelection_TailCall_FrameAddedLater(t *testing.T) {
+	fnCFG := selectCFGFromCode(t, `pick: (v: u8) u8 {
+		if v > 2 {
+			ret helper(v)
+		}
+		sum([1, 2, 3])
+		ret 0
+	}
+	helper: (v: u8) u8 {
+		ret v
+	}
+	sum: (values: u8[]) {
+	}`)
+
+	// the array argument adds a frame after 'ret helper(v)' is selected:
+	// the helper is called, the epilogue runs before the RET
+	require.Greater(t, fnCFG.FrameLayout.nextOffset, uint16(0))
+	for _, instr := range fnCFG.CodeInstructions() {
+		z80Instr := instr.(*machineInstructionZ80)
+		if z80Instr.comment == "helper" {
+			assert.Equal(t, Z80_CALL_NN, z80Instr.opcode)
+		}
+	}
+	assert.Contains(t, opcodesOf(fnCFG.Exit.MachineInstructions), Z80_LD_SP_HL)
+}
+
+func Test_InstructionSelection_ComparisonArgument_Widened(t *testing.T) {
+	fnCFG := selectCFGFromCode(t, `less: (a: u8, b: u8) u16 {
+		ret widen(a < b)
+	}
+	widen: (v: u16) u16 {
+		ret v
+	}`)
+	instrs := fnCFG.CodeInstructions()
+	opcodes := opcodesOf(instrs)
+
+	// the bool in A is widened to the u16 parameter: the high byte is zeroed
+	adc := slices.Index(opcodes, Z80_ADC_A_N)
+	require.GreaterOrEqual(t, adc, 0)
+	require.Greater(t, len(opcodes), adc+2)
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_N, Z80_LD_R_R}, opcodes[adc+1:adc+3])
+
+	_, highRegs := ToPairs(Z80RegistersPP)
+	zeroHigh := instrs[adc+1].(*machineInstructionZ80)
+	assert.Equal(t, highRegs, zeroHigh.result.AllowedSet)
+	assert.Equal(t, int32(0), zeroHigh.operands[0].Value)
+}
+
+func Test_InstructionSelection_BoolCondition(t *testing.T) {
+	fnCFG := buildCFGFromCode(t, `check: (flag: bit) {
+		if flag {
+			x: = 1
+		}
+	}`)
+	vrAlloc := NewVirtualRegisterAllocator()
+	ctx := NewInstructionSelectionContext(NewInstructionSelectorZ80(vrAlloc), vrAlloc)
+	require.NoError(t, ctx.selectCFG(fnCFG))
+
+	condBlock := findBlockByLabel(fnCFG, LabelFunction)
+	require.NotNil(t, condBlock)
+	instrs := condBlock.MachineInstructions
+	require.NotEmpty(t, instrs)
+
+	// a bool variable branches when non-zero
+	assert.Contains(t, opcodesOf(instrs), Z80_CP_N)
+	jump, ok := instrs[len(instrs)-1].(*machineInstructionZ80)
+	require.True(t, ok)
+	assert.Equal(t, Z80_JP_CC_NN, jump.opcode)
+	assert.Equal(t, Cond_NZ, jump.conditionCode)
+}
+
+// selectCheckedFunctionCode selects the function with (or without) checked arithmetic
+func selectCheckedFunctionCode(t *testing.T, code string, checked bool) []MachineInstruction {
+	fnCFG := buildCFGFromCode(t, code)
+	vrAlloc := NewVirtualRegisterAllocator()
+	selector := NewInstructionSelectorZ80WithOptions(vrAlloc, InstructionSelectorZ80Options{CheckedArithmetic: checked})
+	ctx := NewInstructionSelectionContext(selector, vrAlloc)
+	require.NoError(t, ctx.selectCFG(fnCFG))
+	return fnCFG.CodeInstructions()
+}
+
+func Test_InstructionSelection_CheckedArithmetic(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		operation Z80Opcode
+		condition ConditionCode
+	}{
+		{"signed add", "add: (a: i8, b: i8) i8 {\n\tret a + b\n}", Z80_ADD_A_R, Cond_PE},
+		{"unsigned add", "add: (a: u16, b: u16) u16 {\n\tret a + b\n}", Z80_ADC_HL_RR, Cond_C},
+		{"unsigned add immediate", "inc: (a: u16) u16 {\n\tret a + 1\n}", Z80_ADC_HL_RR, Cond_C},
+		{"signed subtract", "sub: (a: i16, b: i16) i16 {\n\tret a - b\n}", Z80_SBC_HL_RR, Cond_PE},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instrs := selectCheckedFunctionCode(t, tt.code, true)
+			opcodes := opcodesOf(instrs)
+
+			// the trap is called right after the operation (and the copy of its result)
+			op := slices.Index(opcodes, tt.operation)
+			require.GreaterOrEqual(t, op, 0)
+			trap := slices.Index(opcodes, Z80_CALL_CC_NN)
+			require.Equal(t, op+2, trap)
+			call := instrs[trap].(*machineInstructionZ80)
+			assert.Equal(t, tt.condition, call.conditionCode)
+			assert.Equal(t, "__overflow_trap", call.comment)
+
+			unchecked := opcodesOf(selectCheckedFunctionCode(t, tt.code, false))
+			assert.NotContains(t, unchecked, Z80_CALL_CC_NN)
+		})
+	}
+}
+
+func Test_InstructionSelection_CheckedMultiply(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		check []Z80Opcode
+	}{
+		// the high word (DE) must be 0
+		{"unsigned", "mul: (a: u16, b: u16) u16 {\n\tret a * b\n}", []Z80Opcode{
+			Z80_LD_R_R, Z80_OR_R, Z80_CALL_CC_NN,
+		}},
+		// the high word (DE) must be the sign extension of H
+		{"signed", "mul: (a: i16, b: i16) i16 {\n\tret a * b\n}", []Z80Opcode{
+			Z80_LD_R_R, Z80_ADD_A_R, Z80_SBC_A_R, Z80_CP_R, Z80_CALL_CC_NN, Z80_CP_R, Z80_CALL_CC_NN,
+		}},
+		// the product of two 8-bit values always fits 16 bits
+		{"8-bit", "mul: (a: u8, b: u8) u16 {\n\tret a * b\n}", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instrs := selectCheckedFunctionCode(t, tt.code, true)
+			opcodes := opcodesOf(instrs)
+
+			call := slices.Index(opcodes, Z80_CALL_NN)
+			require.GreaterOrEqual(t, call, 0)
+			if tt.check == nil {
+				assert.NotContains(t, opcodes, Z80_CALL_CC_NN)
+				return
+			}
+			require.GreaterOrEqual(t, len(opcodes), call+1+len(tt.check))
+			assert.Equal(t, tt.check, opcodes[call+1:call+1+len(tt.check)])
+			trap := instrs[call+len(tt.check)].(*machineInstructionZ80)
+			assert.Equal(t, Cond_NZ, trap.conditionCode)
+			assert.Equal(t, "__overflow_trap", trap.comment)
+
+			unchecked := opcodesOf(selectCheckedFunctionCode(t, tt.code, false))
+			assert.NotContains(t, unchecked, Z80_CALL_CC_NN)
+		})
+	}
+}
+
+func Test_InstructionSelection_UnaryPlus(t *testing.T) {
+	plus := selectFunctionCode(t, `same: (x: i8) i8 {
+		ret +x
+	}`)
+	plain := selectFunctionCode(t, `same: (x: i8) i8 {
+		ret x
+	}`)
+	// no instructions for the unary plus
+	assert.Equal(t, plain, plus)
+
+	minus := selectFunctionCode(t, `negate: (x: i8) i8 {
+		ret -x
+	}`)
+	assert.Contains(t, minus, Z80_NEG)
+	assert.NotContains(t, plus, Z80_NEG)
+}
+
+func Test_InstructionSelection_ToBool(t *testing.T) {
+	opcodes := selectFunctionCode(t, `nonZero: (x: u8) bit {
+		ret x?
+	}`)
+
+	// x <> 0 as a value: CP 0 and the NZ flag into A
+	assert.Contains(t, opcodes, Z80_CP_N)
+	assert.NotContains(t, opcodes, Z80_JP_CC_NN)
+}
+
+func Test_InstructionSelection_StoreBool(t *testing.T) {
+	fnCFG := selectCFGFromCode(t, `flags: () bit {
+		on: = true
+		off: = false
+		ret on == off
+	}`)
+
+	// bools are stored as 0 or 1
+	var values []int32
+	for _, instr := range fnCFG.GetAllInstructions() {
+		for _, operand := range instr.(*machineInstructionZ80).operands {
+			if operand.Type == ImmediateValue && operand.Size == Bits8 {
+				values = append(values, operand.Value)
+			}
+		}
+	}
+	assert.Contains(t, values, int32(1))
+	assert.Contains(t, values, int32(0))
+	for _, value := range values {
+		assert.True(t, value == 0 || value == 1, "bool value %d", value)
+	}
+}
+
+func Test_InstructionSelection_ToBoolCondition(t *testing.T) {
+	fnCFG := selectCFGFromCode(t, `check: (x: u16) {
+		if x? {
+			y: = 1
+		}
+	}`)
+
+	condBlock := findBlockByLabel(fnCFG, LabelFunction)
+	require.NotNil(t, condBlock)
+	instrs := condBlock.MachineInstructions
+	require.NotEmpty(t, instrs)
+
+	// branches on the non-zero test itself
+	jump, ok := instrs[len(instrs)-1].(*machineInstructionZ80)
+	require.True(t, ok)
+	assert.Equal(t, Z80_JP_CC_NN, jump.opcode)
+	assert.Equal(t, Cond_NZ, jump.conditionCode)
+}
+
+func Test_InstructionSelection_DoWhile(t *testing.T) {
+	fnCFG := selectCFGFromCode(t, `count: () {
+		i: = 10
+		do {
+			i = i - 1
+		} while i > 5
+	}`)
+
+	// the body is entered without testing the condition
+	firstBlock := findBlockByLabel(fnCFG, LabelFunction)
+	require.NotNil(t, firstBlock)
+	// LD i, 10; JP body
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_N, Z80_JP_NN}, opcodesOf(firstBlock.MachineInstructions))
+
+	// the condition at the bottom branches back to the body
+	condBlock := findBlockByLabel(fnCFG, LabelDoCond)
+	require.NotNil(t, condBlock)
+	instrs := condBlock.MachineInstructions
+	require.NotEmpty(t, instrs)
+	assert.Contains(t, opcodesOf(instrs), Z80_CP_N)
+	jump, ok := instrs[len(instrs)-1].(*machineInstructionZ80)
+	require.True(t, ok)
+	assert.Equal(t, Z80_JP_CC_NN, jump.opcode)
+	assert.Equal(t, findBlockByLabel(fnCFG, LabelDoBody), jump.GetTargetBlocks()[0])
+}
+
+func Test_InstructionSelection_ForCountDown_DJNZ(t *testing.T) {
+	fnCFG := selectCFGFromCode(t, `count: () {
+		for i: = 10; i > 0; i-- {
+			x: = i
+		}
+	}`)
+
+	// the decrement and test close the loop with a single DJNZ back to the body
+	incBlock := findBlockByLabel(fnCFG, LabelForInc)
+	require.NotNil(t, incBlock)
+	require.Len(t, incBlock.MachineInstructions, 1)
+	djnz, ok := incBlock.MachineInstructions[0].(*machineInstructionZ80)
+	require.True(t, ok)
+	assert.Equal(t, Z80_DJNZ_E, djnz.opcode)
+	assert.Equal(t, findBlockByLabel(fnCFG, LabelForBody), djnz.GetTargetBlocks()[0])
+	assert.Equal(t, findBlockByLabel(fnCFG, LabelForExit), djnz.GetTargetBlocks()[1])
+	// the counter is kept in B
+	assert.Equal(t, []*Register{&RegB}, djnz.result.AllowedSet)
+}
+
+func Test_InstructionSelection_ForCountDown_InlineAsm(t *testing.T) {
+	fnCFG := selectCFGFromCode(t, `count: () {
+		for i: = 10; i > 0; i-- {
+			asm {
+				nop
+			}
+		}
+	}`)
+
+	// the size of the inline assembly is unknown: DJNZ may not reach back to the body
+	incBlock := findBlockByLabel(fnCFG, LabelForInc)
+	require.NotNil(t, incBlock)
+	assert.Equal(t, []Z80Opcode{Z80_DEC_R, Z80_JP_CC_NN}, opcodesOf(incBlock.MachineInstructions))
+}
+
+func Test_InstructionSelection_DoWhileCountDown_DJNZ(t *testing.T) {
+	fnCFG := selectCFGFromCode(t, `count: () {
+		i: = 10
+		do {
+			x: = i
+			i = i - 1
+		} while i <> 0
+	}`)
+
+	condBlock := findBlockByLabel(fnCFG, LabelDoCond)
+	require.NotNil(t, condBlock)
+	assert.Equal(t, []Z80Opcode{Z80_DJNZ_E}, opcodesOf(condBlock.MachineInstructions))
+	assert.Equal(t, findBlockByLabel(fnCFG, LabelDoBody), condBlock.MachineInstructions[0].GetTargetBlocks()[0])
+}
+
+// selectCases builds a select over x with a case per value
+func selectCases(values ...int) string {
+	var sb strings.Builder
+	sb.WriteString("dispatch: (x: u8) {\n\tselect x {\n")
+	for i, value := range values {
+		fmt.Fprintf(&sb, "\t\tcase %d {\n\t\t\ta%d: = %d\n\t\t}\n", value, i, value)
+	}
+	sb.WriteString("\t\telse {\n\t\t\tb: = 0\n\t\t}\n\t}\n}")
+	return sb.String()
+}
+
+func Test_InstructionSelection_Select_JumpTable(t *testing.T) {
+	fnCFG := buildCFGFromCode(t, selectCases(0, 1, 2, 3, 4, 5, 6, 7))
+	vrAlloc := NewVirtualRegisterAllocator()
+	ctx := NewInstructionSelectionContext(NewInstructionSelectorZ80(vrAlloc), vrAlloc)
+	require.NoError(t, ctx.selectCFG(fnCFG))
+
+	selectBlock := findBlockByLabel(fnCFG, LabelFunction)
+	require.NotNil(t, selectBlock)
+	opcodes := opcodesOf(selectBlock.MachineInstructions)
+
+	// one range check instead of eight compares
+	assert.Contains(t, opcodes, Z80_JP_HL)
+	cpCount := 0
+	for _, opcode := range opcodes {
+		if opcode == Z80_CP_N || opcode == Z80_CP_R {
+			cpCount++
+		}
+	}
+	assert.Equal(t, 1, cpCount)
+
+	require.Len(t, fnCFG.JumpTables, 1)
+	table := fnCFG.JumpTables[0]
+	assert.Equal(t, "dispatch.jumptable.0", table.Label)
+	require.Len(t, table.Targets, 8)
+	for i, target := range table.Targets {
+		assert.Equal(t, selectBlock.Successors[i], target)
+		assert.Equal(t, LabelSelectCase, target.Label)
+	}
+
+	jump := selectBlock.MachineInstructions[len(selectBlock.MachineInstructions)-1]
+	assert.Equal(t, table.Targets, jump.GetTargetBlocks())
+}
+
+func Test_InstructionSelection_Select_JumpTableWithGaps(t *testing.T) {
+	fnCFG := buildCFGFromCode(t, selectCases(10, 11, 13, 15))
+	vrAlloc := NewVirtualRegisterAllocator()
+	ctx := NewInstructionSelectionContext(NewInstructionSelectorZ80(vrAlloc), vrAlloc)
+	require.NoError(t, ctx.selectCFG(fnCFG))
+
+	require.Len(t, fnCFG.JumpTables, 1)
+	selectBlock := findBlockByLabel(fnCFG, LabelFunction)
+	elseBlock := findBlockByLabel(fnCFG, LabelSelectElse)
+	require.NotNil(t, elseBlock)
+
+	// 10..15: the missing values 12 and 14 go to else
+	targets := fnCFG.JumpTables[0].Targets
+	require.Len(t, targets, 6)
+	assert.Equal(t, selectBlock.Successors[0], targets[0])
+	assert.Equal(t, selectBlock.Successors[1], targets[1])
+	assert.Equal(t, elseBlock, targets[2])
+	assert.Equal(t, selectBlock.Successors[2], targets[3])
+	assert.Equal(t, elseBlock, targets[4])
+	assert.Equal(t, selectBlock.Successors[3], targets[5])
+
+	// rebased on the lowest case value
+	assert.Contains(t, opcodesOf(selectBlock.MachineInstructions), Z80_SUB_N)
+}
+
+func Test_InstructionSelection_Select_NoJumpTable(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []int
+	}{
+		{"too few cases", []int{0, 1, 2}},
+		{"sparse values", []int{0, 50, 100, 200}},
+		{"duplicate values", []int{0, 1, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fnCFG := buildCFGFromCode(t, selectCases(tt.values...))
+			vrAlloc := NewVirtualRegisterAllocator()
+			ctx := NewInstructionSelectionContext(NewInstructionSelectorZ80(vrAlloc), vrAlloc)
+			require.NoError(t, ctx.selectCFG(fnCFG))
+
+			assert.Empty(t, fnCFG.JumpTables)
+			selectBlock := findBlockByLabel(fnCFG, LabelFunction)
+			assert.NotContains(t, opcodesOf(selectBlock.MachineInstructions), Z80_JP_HL)
+		})
+	}
+}
+
+// ifChain builds an if with an elsif per additional condition over x and y
+func ifChain(conditions ...string) string {
+	var sb strings.Builder
+	sb.WriteString("dispatch: (x: u8, y: u8) {\n")
+	for i, condition := range conditions {
+		if i == 0 {
+			fmt.Fprintf(&sb, "\tif %s {\n", condition)
+		} else {
+			fmt.Fprintf(&sb, "\t} elsif %s {\n", condition)
+		}
+		fmt.Fprintf(&sb, "\t\ta%d: = %d\n", i, i)
+	}
+	sb.WriteString("\t} else {\n\t\tb: = 0\n\t}\n}")
+	return sb.String()
+}
+
+func Test_InstructionSelection_IfChain_JumpTable(t *testing.T) {
+	fnCFG := buildCFGFromCode(t, ifChain("x == 1", "x == 2", "x == 3", "x == 4"))
+	vrAlloc := NewVirtualRegisterAllocator()
+	ctx := NewInstructionSelectionContext(NewInstructionSelectorZ80(vrAlloc), vrAlloc)
+	require.NoError(t, ctx.selectCFG(fnCFG))
+
+	// lowered like the equivalent select
+	assert.Nil(t, findBlockByLabel(fnCFG, LabelElsifCond))
+	elseBlock := findBlockByLabel(fnCFG, LabelSelectElse)
+	require.NotNil(t, elseBlock)
+
+	require.Len(t, fnCFG.JumpTables, 1)
+	selectBlock := findBlockByLabel(fnCFG, LabelFunction)
+	assert.Contains(t, opcodesOf(selectBlock.MachineInstructions), Z80_JP_HL)
+	targets := fnCFG.JumpTables[0].Targets
+	require.Len(t, targets, 4)
+	for i, target := range targets {
+		assert.Equal(t, selectBlock.Successors[i], target)
+		assert.Equal(t, LabelSelectCase, target.Label)
+	}
+}
+
+func Test_InstructionSelection_IfChain_Compares(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []string
+	}{
+		{"other variable", []string{"x == 1", "x == 2", "y == 3", "x == 4"}},
+		{"not equality", []string{"x == 1", "x == 2", "x > 3", "x == 4"}},
+		{"not constant", []string{"x == 1", "x == 2", "x == y", "x == 4"}},
+		{"duplicate values", []string{"x == 1", "x == 2", "x == 2", "x == 4"}},
+		{"sparse values", []string{"x == 0", "x == 50", "x == 100", "x == 200"}},
+		{"too few branches", []string{"x == 1", "x == 2", "x == 3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fnCFG := buildCFGFromCode(t, ifChain(tt.conditions...))
+			vrAlloc := NewVirtualRegisterAllocator()
+			ctx := NewInstructionSelectionContext(NewInstructionSelectorZ80(vrAlloc), vrAlloc)
+			require.NoError(t, ctx.selectCFG(fnCFG))
+
+			assert.Empty(t, fnCFG.JumpTables)
+			assert.NotNil(t, findBlockByLabel(fnCFG, LabelIfThen))
+			assert.NotNil(t, findBlockByLabel(fnCFG, LabelElsifCond))
+			assert.Nil(t, findBlockByLabel(fnCFG, LabelSelectCase))
+		})
+	}
+}
+
+func Test_InstructionSelection_OverflowCondition(t *testing.T) {
+	code := `checked: (a: i8, b: i8) i8 {
+		if @overflow(a + b) {
+			ret 127
+		}
+		ret a + b
+	}`
+
+	opcodes := selectFunctionCode(t, code)
+
+	assert.Contains(t, opcodes, Z80_ADD_A_R)
+	assert.Contains(t, opcodes, Z80_JP_CC_NN)
+}
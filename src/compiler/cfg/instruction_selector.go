@@ -119,10 +119,16 @@ type InstructionSelector interface {
 	SelectSubtract(left, right *VirtualRegister) (*VirtualRegister, error)
 
 	// SelectMultiply generates instructions for multiplication (a * b)
-	SelectMultiply(left, right *VirtualRegister) (*VirtualRegister, error)
+	// signed: operands are signed (i8/i16) and need the signed runtime helper
+	SelectMultiply(left, right *VirtualRegister, signed bool) (*VirtualRegister, error)
 
 	// SelectDivide generates instructions for division (a / b)
-	SelectDivide(left, right *VirtualRegister) (*VirtualRegister, error)
+	// signed: operands are signed (i8/i16) and need the signed runtime helper
+	SelectDivide(left, right *VirtualRegister, signed bool) (*VirtualRegister, error)
+
+	// SelectModulo generates instructions for the remainder of a division (a % b)
+	// signed: operands are signed (i8/i16) and need the signed runtime helper
+	SelectModulo(left, right *VirtualRegister, signed bool) (*VirtualRegister, error)
 
 	// SelectNegate generates instructions for negation (-a)
 	SelectNegate(operand *VirtualRegister) (*VirtualRegister, error)
@@ -151,9 +157,19 @@ type InstructionSelector interface {
 	// SelectShiftLeft generates instructions for left shift (a << b)
 	SelectShiftLeft(value, amount *VirtualRegister) (*VirtualRegister, error)
 
+	// SelectShiftLeftSetBit generates instructions for a left shift that sets bit 0 ((a << 1) | 1)
+	SelectShiftLeftSetBit(value *VirtualRegister) (*VirtualRegister, error)
+
 	// SelectShiftRight generates instructions for right shift (a >> b)
 	SelectShiftRight(value, amount *VirtualRegister) (*VirtualRegister, error)
 
+	// SelectShiftLeftMemory generates instructions to shift the byte at the address left by one bit in place
+	SelectShiftLeftMemory(address *VirtualRegister) error
+
+	// SelectShiftRightMemory generates instructions to shift the byte at the address right by one bit in place
+	// signed: keeps the sign bit (arithmetic shift)
+	SelectShiftRightMemory(address *VirtualRegister, signed bool) error
+
 	// SelectLogicalAnd generates instructions for logical AND (a && b)
 	// ctx: evaluation context (enables short-circuit evaluation in BranchMode)
 	// left, right: the operand expressions (not yet evaluated)
@@ -172,6 +188,31 @@ type InstructionSelector interface {
 	// evaluateExpr: callback to evaluate sub-expressions with context
 	SelectLogicalNot(ctx *ExprContext, operand zsm.SemExpression, evaluateExpr func(*ExprContext, zsm.SemExpression) (*VirtualRegister, error)) (*VirtualRegister, error)
 
+	// ============================================================================
+	// Bit Operations
+	// ============================================================================
+
+	// SelectBitTest generates instructions to test a single bit (0-7) of value
+	// ctx: evaluation context (BranchMode branches to TrueBlock when the bit is set)
+	// Returns a virtual register containing boolean result (0 or 1) in ValueMode
+	SelectBitTest(ctx *ExprContext, bit uint8, value *VirtualRegister) (*VirtualRegister, error)
+
+	// SelectBitTestMemory generates instructions to test a single bit (0-7) of the byte at the address
+	// ctx: evaluation context (BranchMode branches to TrueBlock when the bit is set)
+	SelectBitTestMemory(ctx *ExprContext, bit uint8, address *VirtualRegister) (*VirtualRegister, error)
+
+	// SelectBitSet generates instructions to set a single bit (0-7) of value in place
+	SelectBitSet(bit uint8, value *VirtualRegister) (*VirtualRegister, error)
+
+	// SelectBitReset generates instructions to reset a single bit (0-7) of value in place
+	SelectBitReset(bit uint8, value *VirtualRegister) (*VirtualRegister, error)
+
+	// SelectBitSetMemory generates instructions to set a single bit (0-7) of the byte at the address
+	SelectBitSetMemory(bit uint8, address *VirtualRegister) error
+
+	// SelectBitResetMemory generates instructions to reset a single bit (0-7) of the byte at the address
+	SelectBitResetMemory(bit uint8, address *VirtualRegister) error
+
 	// ============================================================================
 	// Comparison Operations
 	// ============================================================================
@@ -196,6 +237,36 @@ type InstructionSelector interface {
 	// SelectGreaterEqual generates instructions for greater-or-equal comparison (a >= b)
 	SelectGreaterEqual(ctx *ExprContext, left, right *VirtualRegister) (*VirtualRegister, error)
 
+	// ============================================================================
+	// Flag Operations
+	// ============================================================================
+
+	// SelectSetCarry generates instructions to set the carry flag
+	SelectSetCarry() error
+
+	// SelectOverflow generates the addition (or subtraction) and tests for signed overflow
+	// Only BranchMode is supported: branches to the true block on overflow
+	SelectOverflow(ctx *ExprContext, left, right *VirtualRegister, subtract bool) (*VirtualRegister, error)
+
+	// SelectOverflowTrap generates the check of the preceding addition or subtraction (checked arithmetic):
+	// calls the overflow trap routine on signed overflow or unsigned carry. No instructions when unchecked.
+	SelectOverflowTrap(signed bool) error
+
+	// SelectClearCarry generates instructions to clear the carry flag
+	// accumulator: register already holding the accumulator (may be nil).
+	// When present the cheaper 'OR A' is used, otherwise 'SCF; CCF' which leaves A untouched.
+	SelectClearCarry(accumulator *VirtualRegister) error
+
+	// ============================================================================
+	// CPU Control
+	// ============================================================================
+
+	// SelectHalt generates instructions to suspend the CPU until the next interrupt
+	SelectHalt() error
+
+	// SelectNop generates a no-operation (timing padding)
+	SelectNop() error
+
 	// ============================================================================
 	// Memory Operations
 	// ============================================================================
@@ -214,6 +285,25 @@ type InstructionSelector interface {
 	// SelectStoreSequential generates instructions to store to memory sequentially
 	SelectStoreSequential(address *VirtualRegister, value *VirtualRegister, increment uint16, size RegisterSize) error
 
+	// SelectPeek generates instructions to read the byte at an absolute memory address
+	SelectPeek(address *VirtualRegister) (*VirtualRegister, error)
+
+	// SelectPoke generates instructions to write a byte to an absolute memory address
+	SelectPoke(address *VirtualRegister, value *VirtualRegister) error
+
+	// SelectMemChr generates instructions to search the length bytes at address for the byte value
+	// Returns the address of the first match, 0 when the value is not found (or the length is 0)
+	SelectMemChr(address, value, length *VirtualRegister) (*VirtualRegister, error)
+
+	// SelectMemSet generates instructions to fill the length bytes at address with the byte value
+	SelectMemSet(address, value, length *VirtualRegister) error
+
+	// SelectCopyData generates instructions to copy size bytes from the source label to the target label
+	SelectCopyData(source, target string, size uint16) error
+
+	// SelectLoadDataAddress generates instructions to load the address of a data section label (or function)
+	SelectLoadDataAddress(label string) (*VirtualRegister, error)
+
 	// SelectLoadStackAddress generates instructions to load the address of a stack location
 	SelectLoadStackAddress(stackOffset uint16) (*VirtualRegister, error)
 
@@ -228,22 +318,64 @@ type InstructionSelector interface {
 
 	// Move register value -of size- from source to target
 	SelectMove(target *VirtualRegister, source *VirtualRegister, size RegisterSize) error
+
+	// SelectCopy generates instructions to copy the value into a new virtual register
+	// (the copy keeps the value when the source register is assigned)
+	SelectCopy(value *VirtualRegister) (*VirtualRegister, error)
+
+	// SelectSwap generates instructions to exchange the values -of size- of two registers (a, b = b, a)
+	SelectSwap(left, right *VirtualRegister, size RegisterSize) error
+
+	// ============================================================================
+	// Conversion Operations
+	// ============================================================================
+
+	// SelectWiden generates instructions to extend value from fromSize to toSize
+	// signed: sign-extend the value (i8), otherwise the high byte is zeroed (u8)
+	SelectWiden(value *VirtualRegister, fromSize, toSize RegisterSize, signed bool) (*VirtualRegister, error)
+
+	// SelectTruncate generates instructions to narrow value from fromSize to toSize (drops the high byte)
+	SelectTruncate(value *VirtualRegister, fromSize, toSize RegisterSize) (*VirtualRegister, error)
+
 	// ============================================================================
 	// Control Flow
 	// ============================================================================
 
+	// SelectInlineAsm passes the assembly text through to the instruction stream verbatim
+	SelectInlineAsm(text string) error
+
 	// SelectJump generates an unconditional jump to a basic block
 	SelectJump(target *BasicBlock) error
 
+	// SelectDecrementJumpNotZero decrements the 8-bit counter and jumps to the true block while it is not zero
+	// near: the true block is within relative jump range
+	SelectDecrementJumpNotZero(ctx *ExprContext, counter *VirtualRegister, near bool) error
+
+	// SelectJumpTable generates an indexed jump through the table
+	// index - base selects the table entry, values outside the table jump to defaultTarget
+	SelectJumpTable(index *VirtualRegister, base int, table *JumpTable, defaultTarget *BasicBlock) error
+
 	// SelectCall generates a function call
 	// returnSize is the size of the return value in bits (0 for void functions)
 	// Returns the virtual register containing the return value (nil if void)
 	SelectCall(functionName string, args []*VirtualRegister, returnSize RegisterSize) (*VirtualRegister, error)
 
+	// SelectTailCall generates a jump to a function (instead of a call and return)
+	// The callee returns directly to our caller, args must all be passed in registers
+	SelectTailCall(functionName string, args []*VirtualRegister) error
+
+	// SelectRestart generates a call to a page-zero restart vector
+	// vector is the target address (0x00, 0x08, ... 0x38)
+	SelectRestart(vector uint8) error
+
 	// SelectReturn generates a return statement
 	// value is nil for void functions
 	SelectReturn(value *VirtualRegister) error
 
+	// SelectInterruptReturn generates the return from an interrupt handler
+	// nonMaskable selects the return from a non-maskable interrupt (NMI)
+	SelectInterruptReturn(nonMaskable bool) error
+
 	// ============================================================================
 	// Function Management
 	// ============================================================================
@@ -320,6 +452,13 @@ type MachineInstruction interface {
 	// Returns n blocks for multi-way branches (select/case/else - in order, else always last)
 	GetTargetBlocks() []*BasicBlock
 
+	// SetTargetBlock updates a branch target block (used when splitting edges)
+	SetTargetBlock(index int, block *BasicBlock)
+
+	// GetClobbers returns the physical registers the instruction changes besides its result (calls)
+	// Returns nil for most instructions
+	GetClobbers() []*Register
+
 	// returns the cost metrics for this instruction
 	GetCost() InstructionCost
 
@@ -2,6 +2,7 @@ package cfg
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"zenith/compiler/zsm"
 )
@@ -11,6 +12,18 @@ type instructionSelectorZ80 struct {
 	vrAlloc           *VirtualRegisterAllocator
 	currentBlock      *BasicBlock // Current block for instruction emission
 	callingConvention CallingConvention
+	allowUndocumented bool // select undocumented instructions (SLL, index register halves)
+	checkedArithmetic bool // call the overflow trap when an addition or subtraction overflows
+}
+
+// InstructionSelectorZ80Options configures the Z80 instruction selector
+type InstructionSelectorZ80Options struct {
+	// AllowUndocumented enables the undocumented instructions (SLL, IXH/IXL/IYH/IYL).
+	// Leave disabled for strict targets (some clones do not implement them).
+	AllowUndocumented bool
+	// CheckedArithmetic calls the '__overflow_trap' runtime routine when an addition, subtraction or multiplication overflows.
+	// For debug builds: it costs a CALL cc after each operation.
+	CheckedArithmetic bool
 }
 
 var Z80RegA = []*Register{&RegA}
@@ -27,12 +40,25 @@ var Z80RegSP = []*Register{&RegSP}
 
 // NewInstructionSelectorZ80 creates a new InstructionSelector for the Z80
 func NewInstructionSelectorZ80(vrAlloc *VirtualRegisterAllocator) InstructionSelector {
+	return NewInstructionSelectorZ80WithOptions(vrAlloc, InstructionSelectorZ80Options{})
+}
+
+// NewInstructionSelectorZ80WithOptions creates a new InstructionSelector for the Z80 with the specified options
+func NewInstructionSelectorZ80WithOptions(vrAlloc *VirtualRegisterAllocator, options InstructionSelectorZ80Options) InstructionSelector {
 	return &instructionSelectorZ80{
 		vrAlloc:           vrAlloc,
 		callingConvention: NewCallingConventionZ80(),
+		allowUndocumented: options.AllowUndocumented,
+		checkedArithmetic: options.CheckedArithmetic,
 	}
 }
 
+// canSelect checks if the opcode may be emitted for the target
+func (z *instructionSelectorZ80) canSelect(opcode Z80Opcode) bool {
+	desc, ok := Z80InstrDescriptors[opcode]
+	return ok && (!desc.Undocumented || z.allowUndocumented)
+}
+
 // ============================================================================
 // Arithmetic Operations
 // ============================================================================
@@ -63,12 +89,21 @@ func (z *instructionSelectorZ80) SelectAdd(left, right *VirtualRegister) (*Virtu
 		result = z.vrAlloc.Allocate(Z80Registers8)
 		z.emit(newInstruction(Z80_LD_R_R, result, vrA))
 	case 16:
-		// TODO: refactor to handle immediate 16-bit addition
+		if z.checkedArithmetic {
+			// ADD HL, rr (and INC HL) do not set the overflow flag
+			return z.emitAddWithOverflow16(left, right)
+		}
 		// 16-bit add: ADD HL, rr
 		result = z.vrAlloc.Allocate(Z80Registers16)
 		vrHL := z.vrAlloc.Allocate(Z80RegHL)
-		z.emit(newInstruction(Z80_LD_RR_NN, vrHL, left))
-		z.emit(newInstruction(Z80_ADD_HL_RR, vrHL, right))
+		if isImm {
+			// INC HL for small constants, otherwise LD rr, NN + ADD HL, rr
+			z.emit(newInstruction(Z80_LD_RR_NN, vrHL, reg))
+			z.emitAddOffsetToHL(vrHL, uint16(imm.Value))
+		} else {
+			z.emit(newInstruction(Z80_LD_RR_NN, vrHL, left))
+			z.emit(newInstruction(Z80_ADD_HL_RR, vrHL, right))
+		}
 		z.emit(newInstruction(Z80_LD_RR_NN, result, vrHL))
 	default:
 		return nil, fmt.Errorf("unsupported size for ADD: %d", size)
@@ -82,11 +117,11 @@ func (z *instructionSelectorZ80) SelectSubtract(left, right *VirtualRegister) (*
 	size := largestSize(left, right)
 	var result *VirtualRegister
 
-	vrA := z.vrAlloc.Allocate(Z80RegA)
 	switch size {
 	case 8:
 		result = z.vrAlloc.Allocate(Z80Registers8)
 		// 8-bit subtract: SUB uses A register implicitly
+		vrA := z.vrAlloc.Allocate(Z80RegA)
 		z.emit(newInstruction(Z80_LD_R_R, vrA, left))
 		z.emit(newInstruction(Z80_SUB_R, vrA, right))
 		z.emit(newInstruction(Z80_LD_R_R, result, vrA))
@@ -95,8 +130,10 @@ func (z *instructionSelectorZ80) SelectSubtract(left, right *VirtualRegister) (*
 		result = z.vrAlloc.Allocate(Z80Registers16)
 		vrHL := z.vrAlloc.Allocate(Z80RegHL)
 		z.emit(newInstruction(Z80_LD_RR_NN, vrHL, left))
-		// Clear carry flag first (OR A)
-		z.emit(newInstruction(Z80_OR_R, vrA, vrA))
+		// Clear carry flag first (A is not involved: SCF; CCF)
+		if err := z.SelectClearCarry(nil); err != nil {
+			return nil, err
+		}
 		z.emit(newInstruction(Z80_SBC_HL_RR, vrHL, right))
 		z.emit(newInstruction(Z80_LD_RR_NN, result, vrHL))
 	default:
@@ -109,7 +146,8 @@ func (z *instructionSelectorZ80) SelectSubtract(left, right *VirtualRegister) (*
 // SelectMultiply generates instructions for multiplication (a * b)
 // Z80 has no multiply instruction - call runtime helper
 // Intrinsic calling convention: __mul8(A, L) -> HL (16-bit), __mul16(HL, DE) -> HLDE (32-bit)
-func (z *instructionSelectorZ80) SelectMultiply(left, right *VirtualRegister) (*VirtualRegister, error) {
+// Signed operands use __imul8 and __imul16 with the same convention
+func (z *instructionSelectorZ80) SelectMultiply(left, right *VirtualRegister, signed bool) (*VirtualRegister, error) {
 	var result *VirtualRegister
 
 	// Call multiply runtime helper based on operand size
@@ -119,29 +157,70 @@ func (z *instructionSelectorZ80) SelectMultiply(left, right *VirtualRegister) (*
 		// __mul8: params in A and L, result in HL (16-bit)
 		z.emitLoadIntoReg8(left, Z80RegA)
 		z.emitLoadIntoReg8(right, Z80RegL)
-		callInstr := newCall("__mul8")
+		callInstr := newCall(runtimeHelperName("mul8", signed))
 		result = z.vrAlloc.Allocate(Z80RegHL)
 		callInstr.result = result
-		z.emit(callInstr)
+		z.emitCall(callInstr)
 	} else {
 		// __mul16: params in HL and DE, result in HLDE (32-bit)
 		left, right = orderToMatchRegisters(left, right, &RegHL)
 		z.emitLoadIntoReg16(left, Z80RegHL)
 		z.emitLoadIntoReg16(right, Z80RegDE)
-		callInstr := newCall("__mul16")
+		callInstr := newCall(runtimeHelperName("mul16", signed))
 		result = z.vrAlloc.Allocate(Z80RegHL)
 		// TODO: implement 32-bit registers.
 		callInstr.result = result
-		z.emit(callInstr)
+		z.emitCall(callInstr)
+		z.emitMultiplyOverflowTrap(result, signed)
 	}
 
 	return result, nil
 }
 
-// SelectDivide generates instructions for division (a / b)
+// emitMultiplyOverflowTrap calls the overflow trap when the 16-bit product does not fit (checked arithmetic):
+// the high word in DE must be 0 (unsigned) or the sign extension of H (signed)
+func (z *instructionSelectorZ80) emitMultiplyOverflowTrap(product *VirtualRegister, signed bool) {
+	if !z.checkedArithmetic {
+		return
+	}
+	vrA := z.vrAlloc.Allocate(Z80RegA)
+	vrD := z.vrAlloc.Allocate(Z80RegD)
+	vrE := z.vrAlloc.Allocate(Z80RegE)
+	if !signed {
+		// LD A, D; OR E; CALL NZ, __overflow_trap
+		z.emit(newInstruction(Z80_LD_R_R, vrA, vrD))
+		z.emit(newInstruction(Z80_OR_R, vrA, vrE))
+		z.emitOverflowTrapCall(Cond_NZ)
+		return
+	}
+	// LD A, H; ADD A, A; SBC A, A; CP D; CALL NZ, __overflow_trap; CP E; CALL NZ, __overflow_trap
+	z.emit(newInstruction(Z80_LD_R_R, vrA, z.vrAlloc.Allocate(Z80RegH)))
+	// the sign of H into the carry, then A = 0 or 0xFF
+	z.emit(newInstruction(Z80_ADD_A_R, vrA, vrA))
+	z.emit(newInstruction(Z80_SBC_A_R, vrA, vrA))
+	z.emit(newInstruction(Z80_CP_R, vrA, vrD))
+	z.emitOverflowTrapCall(Cond_NZ)
+	z.emit(newInstruction(Z80_CP_R, vrA, vrE))
+	z.emitOverflowTrapCall(Cond_NZ)
+}
+
+// SelectDivide generates instructions for division (a / b)// SelectDivide generates instructions for division (a / b)
 // Z80 has no divide instruction - call runtime helper
 // Intrinsic calling convention: __div8(HL, DE) -> A, __div16(HL, DE) -> HL
-func (z *instructionSelectorZ80) SelectDivide(left, right *VirtualRegister) (*VirtualRegister, error) {
+// Signed operands use __idiv8 and __idiv16 with the same convention
+func (z *instructionSelectorZ80) SelectDivide(left, right *VirtualRegister, signed bool) (*VirtualRegister, error) {
+	return z.emitDivisionCall("div", left, right, signed)
+}
+
+// SelectModulo generates instructions for the remainder of a division (a % b)
+// Runtime helpers with the convention of division: __mod8(HL, DE) -> A, __mod16(HL, DE) -> HL
+// Signed operands use __imod8 and __imod16
+func (z *instructionSelectorZ80) SelectModulo(left, right *VirtualRegister, signed bool) (*VirtualRegister, error) {
+	return z.emitDivisionCall("mod", left, right, signed)
+}
+
+// emitDivisionCall calls the division runtime helper of the operation (div or mod) for the operand size
+func (z *instructionSelectorZ80) emitDivisionCall(operation string, left, right *VirtualRegister, signed bool) (*VirtualRegister, error) {
 	size := largestSize(left, right)
 	// call parameters
 	z.emitLoadIntoReg16(left, Z80RegHL)
@@ -151,17 +230,17 @@ func (z *instructionSelectorZ80) SelectDivide(left, right *VirtualRegister) (*Vi
 	var callInstr *machineInstructionZ80
 
 	if size == 8 {
-		// __div8: params in HL and DE, result in A
-		callInstr = newCall("__div8")
+		// params in HL and DE, result in A
+		callInstr = newCall(runtimeHelperName(operation+"8", signed))
 		result = z.vrAlloc.Allocate(Z80RegA)
 	} else {
-		// __div16: params in HL and DE, result in HL
-		callInstr = newCall("__div16")
+		// params in HL and DE, result in HL
+		callInstr = newCall(runtimeHelperName(operation+"16", signed))
 		result = z.vrAlloc.Allocate(Z80RegHL)
 	}
 
 	callInstr.result = result
-	z.emit(callInstr)
+	z.emitCall(callInstr)
 	return result, nil
 }
 
@@ -181,28 +260,20 @@ func (z *instructionSelectorZ80) SelectNegate(operand *VirtualRegister) (*Virtua
 
 func (z *instructionSelectorZ80) SelectIncrement(operand *VirtualRegister) (*VirtualRegister, error) {
 	size := operand.Size
-	var result *VirtualRegister
-	if size == 8 {
-		result, _ := z.allocateRegistersFor(Z80_INC_R)
-		z.emit(newInstruction(Z80_INC_R, result, result))
-	} else {
+	if size != 8 {
 		return nil, fmt.Errorf("unsupported size for INCREMENT: %d", size)
 	}
 
-	return result, nil
+	return z.emitStaged(Z80_INC_R, operand)
 }
 
 func (z *instructionSelectorZ80) SelectDecrement(operand *VirtualRegister) (*VirtualRegister, error) {
 	size := operand.Size
-	var result *VirtualRegister
-	if size == 8 {
-		result, _ := z.allocateRegistersFor(Z80_DEC_R)
-		z.emit(newInstruction(Z80_DEC_R, result, result))
-	} else {
+	if size != 8 {
 		return nil, fmt.Errorf("unsupported size for DECREMENT: %d", size)
 	}
 
-	return result, nil
+	return z.emitStaged(Z80_DEC_R, operand)
 }
 
 // ============================================================================
@@ -298,10 +369,29 @@ func (z *instructionSelectorZ80) SelectShiftLeft(value, amount *VirtualRegister)
 	var result *VirtualRegister
 	if size == 8 {
 		result = z.vrAlloc.Allocate(Z80RegA)
-		z.emit(newCall("__shl8"))
+		z.emitCall(newCall("__shl8"))
 	} else {
 		result = z.vrAlloc.Allocate(Z80RegHL)
-		z.emit(newCall("__shl16"))
+		z.emitCall(newCall("__shl16"))
+	}
+
+	return result, nil
+}
+
+// SelectShiftLeftSetBit generates instructions for a left shift that sets bit 0 ((a << 1) | 1)
+func (z *instructionSelectorZ80) SelectShiftLeftSetBit(value *VirtualRegister) (*VirtualRegister, error) {
+	if value.Size != 8 {
+		return nil, fmt.Errorf("unsupported size for SHIFT LEFT SET BIT: %d", value.Size)
+	}
+
+	result := z.vrAlloc.Allocate(Z80Registers8)
+	z.emit(newInstruction(Z80_LD_R_R, result, value))
+	if z.canSelect(Z80_SLL_R) {
+		z.emit(newInstructionResult(Z80_SLL_R, result))
+	} else {
+		// bit 0 is clear after SLA
+		z.emit(newInstructionResult(Z80_SLA_R, result))
+		z.emit(newInstructionResult(Z80_INC_R, result))
 	}
 
 	return result, nil
@@ -321,15 +411,33 @@ func (z *instructionSelectorZ80) SelectShiftRight(value *VirtualRegister, amount
 	var result *VirtualRegister
 	if size == 8 {
 		result = z.vrAlloc.Allocate(Z80RegA)
-		z.emit(newCall("__shr8"))
+		z.emitCall(newCall("__shr8"))
 	} else {
 		result = z.vrAlloc.Allocate(Z80RegHL)
-		z.emit(newCall("__shr16"))
+		z.emitCall(newCall("__shr16"))
 	}
 
 	return result, nil
 }
 
+// SelectShiftLeftMemory generates instructions to shift a byte in memory left (SLA (HL))
+func (z *instructionSelectorZ80) SelectShiftLeftMemory(address *VirtualRegister) error {
+	vrHL := z.emitAddressIntoHL(address)
+	z.emit(newInstructionOperand(Z80_SLA_HL, vrHL))
+	return nil
+}
+
+// SelectShiftRightMemory generates instructions to shift a byte in memory right (SRA/SRL (HL))
+func (z *instructionSelectorZ80) SelectShiftRightMemory(address *VirtualRegister, signed bool) error {
+	opcode := Z80_SRL_HL
+	if signed {
+		opcode = Z80_SRA_HL
+	}
+	vrHL := z.emitAddressIntoHL(address)
+	z.emit(newInstructionOperand(opcode, vrHL))
+	return nil
+}
+
 // SelectLogicalAnd generates instructions for logical AND (a && b)
 func (z *instructionSelectorZ80) SelectLogicalAnd(ctx *ExprContext, left, right zsm.SemExpression, evaluateExpr func(*ExprContext, zsm.SemExpression) (*VirtualRegister, error)) (*VirtualRegister, error) {
 	// In BranchMode: implement short-circuit evaluation
@@ -366,7 +474,7 @@ func (z *instructionSelectorZ80) SelectLogicalAnd(ctx *ExprContext, left, right
 
 	z.emit(newInstruction(Z80_LD_RR_NN, vrHL, leftVR))
 	z.emit(newInstruction(Z80_LD_RR_NN, vrDE, rightVR))
-	z.emit(newCall("__logical_and"))
+	z.emitCall(newCall("__logical_and"))
 
 	result := z.vrAlloc.Allocate(Z80RegA)
 	return result, nil
@@ -404,7 +512,7 @@ func (z *instructionSelectorZ80) SelectLogicalOr(ctx *ExprContext, left, right z
 
 	z.emit(newInstruction(Z80_LD_RR_NN, vrHL, leftVR))
 	z.emit(newInstruction(Z80_LD_RR_NN, vrDE, rightVR))
-	z.emit(newCall("__logical_or"))
+	z.emitCall(newCall("__logical_or"))
 
 	result := z.vrAlloc.Allocate(Z80RegA)
 	return result, nil
@@ -419,27 +527,172 @@ func (z *instructionSelectorZ80) SelectLogicalNot(ctx *ExprContext, operand zsm.
 		return evaluateExpr(invertedCtx, operand)
 	}
 
-	// ValueMode: use runtime helper
+	// ValueMode: a bool is 0 or 1, flip bit 0
 	operandVR, err := evaluateExpr(ctx, operand)
 	if err != nil {
 		return nil, err
 	}
 
-	vrHL := z.vrAlloc.Allocate(Z80RegHL)
-	z.emit(newInstruction(Z80_LD_RR_NN, vrHL, operandVR))
-	z.emit(newCall("__logical_not"))
+	return z.emitStaged(Z80_XOR_N, operandVR, z.vrAlloc.AllocateImmediate(1, 8))
+}
 
-	result := z.vrAlloc.Allocate(Z80RegA)
+// ============================================================================
+// Bit Operations
+// ============================================================================
+
+// SelectBitTest generates instructions to test a bit (BIT b, r)
+// BIT sets the Z flag when the bit is 0.
+func (z *instructionSelectorZ80) SelectBitTest(ctx *ExprContext, bit uint8, value *VirtualRegister) (*VirtualRegister, error) {
+	if value.Size != 8 {
+		return nil, fmt.Errorf("unsupported size for BIT: %d", value.Size)
+	}
+	z.emit(newBitInstruction(Z80_BIT_B_R, nil, z.vrAlloc.AllocateImmediate(int32(bit), Bits8), value))
+
+	// In BranchMode: emit conditional branch (NZ for bit set)
+	if ctx != nil && ctx.Mode == BranchMode {
+		z.emit(newJumpWithCondition(Cond_NZ, ctx.TrueBlock, ctx.FalseBlock))
+		return value, nil
+	}
+
+	return z.emitFlagToRegA(Cond_NZ)
+}
+
+// SelectBitTestMemory generates instructions to test a bit in memory (BIT b, (HL))
+// BIT sets the Z flag when the bit is 0.
+func (z *instructionSelectorZ80) SelectBitTestMemory(ctx *ExprContext, bit uint8, address *VirtualRegister) (*VirtualRegister, error) {
+	vrHL := z.emitAddressIntoHL(address)
+	z.emit(newBitInstruction(Z80_BIT_B_HL, nil, z.vrAlloc.AllocateImmediate(int32(bit), Bits8), vrHL))
+
+	// In BranchMode: emit conditional branch (NZ for bit set)
+	if ctx != nil && ctx.Mode == BranchMode {
+		z.emit(newJumpWithCondition(Cond_NZ, ctx.TrueBlock, ctx.FalseBlock))
+		return vrHL, nil
+	}
+
+	return z.emitFlagToRegA(Cond_NZ)
+}
+
+// SelectOverflow generates the addition (or subtraction) and branches on the P/V flag
+// The P/V flag is set (parity even) on signed overflow: JP PE, overflow.
+// There is no relative jump on P/V, so the flag cannot be converted to a value (yet).
+func (z *instructionSelectorZ80) SelectOverflow(ctx *ExprContext, left, right *VirtualRegister, subtract bool) (*VirtualRegister, error) {
+	if ctx == nil || ctx.Mode != BranchMode {
+		return nil, fmt.Errorf("'@overflow' can only be used as a condition")
+	}
+
+	var result *VirtualRegister
+	var err error
+	switch {
+	case subtract:
+		// SUB and SBC HL, rr set the overflow flag
+		result, err = z.SelectSubtract(left, right)
+	case largestSize(left, right) == 8:
+		result, err = z.SelectAdd(left, right)
+	default:
+		result, err = z.emitAddWithOverflow16(left, right)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	z.emit(newJumpWithCondition(Cond_PE, ctx.TrueBlock, ctx.FalseBlock))
 	return result, nil
 }
 
+// overflowTrap is the runtime routine called by checked arithmetic
+const overflowTrap = "__overflow_trap"
+
+// SelectOverflowTrap calls the overflow trap when the preceding addition or subtraction overflowed:
+// CALL PE (signed) or CALL C (unsigned), only with checked arithmetic
+func (z *instructionSelectorZ80) SelectOverflowTrap(signed bool) error {
+	if !z.checkedArithmetic {
+		return nil
+	}
+	condition := Cond_C
+	if signed {
+		condition = Cond_PE
+	}
+	z.emitOverflowTrapCall(condition)
+	return nil
+}
+
+// emitOverflowTrapCall calls the overflow trap when the condition holds: CALL cc, __overflow_trap
+func (z *instructionSelectorZ80) emitOverflowTrapCall(condition ConditionCode) {
+	z.emitCall(&machineInstructionZ80{
+		opcode:        Z80_CALL_CC_NN,
+		conditionCode: condition,
+		comment:       overflowTrap,
+	})
+}
+
+// emitAddWithOverflow16 adds with ADC HL, rr (carry cleared),
+// ADD HL, rr does not set the overflow flag.
+// A constant (of any size) is loaded into a pair: LD rr, nn.
+func (z *instructionSelectorZ80) emitAddWithOverflow16(left, right *VirtualRegister) (*VirtualRegister, error) {
+	imm, reg, isImm := orderImmediateFirst(left, right)
+	if isImm {
+		left = reg
+		right = z.vrAlloc.AllocateImmediate(imm.Value, Bits16)
+	}
+	if left.Size != 16 || right.Size != 16 {
+		return nil, fmt.Errorf("unsupported operand sizes for ADC: %d, %d", left.Size, right.Size)
+	}
+	result := z.vrAlloc.Allocate(Z80Registers16)
+	vrHL := z.vrAlloc.Allocate(Z80RegHL)
+	z.emit(newInstruction(Z80_LD_RR_NN, vrHL, left))
+	if right.Type == ImmediateValue {
+		vrRR := z.vrAlloc.Allocate(Z80RegistersPP)
+		z.emit(newInstruction(Z80_LD_RR_NN, vrRR, right))
+		right = vrRR
+	}
+	if err := z.SelectClearCarry(nil); err != nil {
+		return nil, err
+	}
+	z.emit(newInstruction(Z80_ADC_HL_RR, vrHL, right))
+	z.emit(newInstruction(Z80_LD_RR_NN, result, vrHL))
+	return result, nil
+}
+
+// SelectBitSet generates instructions to set a bit (SET b, r)
+func (z *instructionSelectorZ80) SelectBitSet(bit uint8, value *VirtualRegister) (*VirtualRegister, error) {
+	if value.Size != 8 {
+		return nil, fmt.Errorf("unsupported size for SET: %d", value.Size)
+	}
+	z.emit(newBitInstruction(Z80_SET_B_R, value, z.vrAlloc.AllocateImmediate(int32(bit), Bits8), value))
+	return value, nil
+}
+
+// SelectBitReset generates instructions to reset a bit (RES b, r)
+func (z *instructionSelectorZ80) SelectBitReset(bit uint8, value *VirtualRegister) (*VirtualRegister, error) {
+	if value.Size != 8 {
+		return nil, fmt.Errorf("unsupported size for RES: %d", value.Size)
+	}
+	z.emit(newBitInstruction(Z80_RES_B_R, value, z.vrAlloc.AllocateImmediate(int32(bit), Bits8), value))
+	return value, nil
+}
+
+// SelectBitSetMemory generates instructions to set a bit in memory (SET b, (HL))
+func (z *instructionSelectorZ80) SelectBitSetMemory(bit uint8, address *VirtualRegister) error {
+	vrHL := z.emitAddressIntoHL(address)
+	z.emit(newBitInstruction(Z80_SET_B_HL, nil, z.vrAlloc.AllocateImmediate(int32(bit), Bits8), vrHL))
+	return nil
+}
+
+// SelectBitResetMemory generates instructions to reset a bit in memory (RES b, (HL))
+func (z *instructionSelectorZ80) SelectBitResetMemory(bit uint8, address *VirtualRegister) error {
+	vrHL := z.emitAddressIntoHL(address)
+	z.emit(newBitInstruction(Z80_RES_B_HL, nil, z.vrAlloc.AllocateImmediate(int32(bit), Bits8), vrHL))
+	return nil
+}
+
 // ============================================================================
 // Comparison Operations
 // ============================================================================
 
 // SelectEqual generates instructions for equality comparison (a == b)
 func (z *instructionSelectorZ80) SelectEqual(ctx *ExprContext, left, right *VirtualRegister) (*VirtualRegister, error) {
-	result, err := z.emitCompare(left, right)
+	// (in)equality is symmetric: the operands can be swapped
+	result, _, err := z.emitCompare(left, right, true)
 	if err != nil {
 		return nil, err
 	}
@@ -455,7 +708,8 @@ func (z *instructionSelectorZ80) SelectEqual(ctx *ExprContext, left, right *Virt
 
 // SelectNotEqual generates instructions for inequality comparison (a != b)
 func (z *instructionSelectorZ80) SelectNotEqual(ctx *ExprContext, left, right *VirtualRegister) (*VirtualRegister, error) {
-	result, err := z.emitCompare(left, right)
+	// (in)equality is symmetric: the operands can be swapped
+	result, _, err := z.emitCompare(left, right, true)
 	if err != nil {
 		return nil, err
 	}
@@ -471,7 +725,7 @@ func (z *instructionSelectorZ80) SelectNotEqual(ctx *ExprContext, left, right *V
 
 // SelectLessThan generates instructions for less-than comparison (a < b)
 func (z *instructionSelectorZ80) SelectLessThan(ctx *ExprContext, left, right *VirtualRegister) (*VirtualRegister, error) {
-	result, err := z.emitCompare(left, right)
+	result, _, err := z.emitCompare(left, right, false)
 	if err != nil {
 		return nil, err
 	}
@@ -487,7 +741,7 @@ func (z *instructionSelectorZ80) SelectLessThan(ctx *ExprContext, left, right *V
 
 // SelectGreaterThan generates instructions for greater-than comparison (a > b)
 func (z *instructionSelectorZ80) SelectGreaterThan(ctx *ExprContext, left, right *VirtualRegister) (*VirtualRegister, error) {
-	result, err := z.emitCompare(left, right)
+	result, _, err := z.emitCompare(left, right, false)
 	if err != nil {
 		return nil, err
 	}
@@ -503,11 +757,20 @@ func (z *instructionSelectorZ80) SelectGreaterThan(ctx *ExprContext, left, right
 
 // SelectLessEqual generates instructions for less-or-equal comparison (a <= b)
 func (z *instructionSelectorZ80) SelectLessEqual(ctx *ExprContext, left, right *VirtualRegister) (*VirtualRegister, error) {
-	result, err := z.emitCompare(left, right)
+	result, swapped, err := z.emitCompare(left, right, true)
 	if err != nil {
 		return nil, err
 	}
 
+	// swapped: a <= b is compared as b >= a (NC)
+	if swapped {
+		if ctx != nil && ctx.Mode == BranchMode {
+			z.emit(newJumpWithCondition(Cond_NC, ctx.TrueBlock, ctx.FalseBlock))
+			return result, nil
+		}
+		return z.emitFlagToRegA(Cond_NC)
+	}
+
 	// In BranchMode: emit conditional branch (C or Z for <= unsigned)
 	if ctx != nil && ctx.Mode == BranchMode {
 		z.emit(newJumpWithCondition(Cond_Z, ctx.TrueBlock, nil))
@@ -520,7 +783,7 @@ func (z *instructionSelectorZ80) SelectLessEqual(ctx *ExprContext, left, right *
 
 // SelectGreaterEqual generates instructions for greater-or-equal comparison (a >= b)
 func (z *instructionSelectorZ80) SelectGreaterEqual(ctx *ExprContext, left, right *VirtualRegister) (*VirtualRegister, error) {
-	result, err := z.emitCompare(left, right)
+	result, _, err := z.emitCompare(left, right, false)
 	if err != nil {
 		return nil, err
 	}
@@ -535,6 +798,46 @@ func (z *instructionSelectorZ80) SelectGreaterEqual(ctx *ExprContext, left, righ
 	return nil, fmt.Errorf("Value Mode not implemented for greater-equal.")
 }
 
+// ============================================================================
+// Flag Operations
+// ============================================================================
+
+// SelectSetCarry generates instructions to set the carry flag (SCF)
+func (z *instructionSelectorZ80) SelectSetCarry() error {
+	z.emit(newInstruction0(Z80_SCF))
+	return nil
+}
+
+// SelectClearCarry generates instructions to clear the carry flag
+// OR A (4 T-states) when A is already in use, SCF;CCF (8 T-states) otherwise.
+// SCF;CCF does not read A and preserves the Z, S and P/V flags.
+func (z *instructionSelectorZ80) SelectClearCarry(accumulator *VirtualRegister) error {
+	if accumulator != nil {
+		z.emit(newInstruction(Z80_OR_R, accumulator, accumulator))
+		return nil
+	}
+
+	z.emit(newInstruction0(Z80_SCF))
+	z.emit(newInstruction0(Z80_CCF))
+	return nil
+}
+
+// ============================================================================
+// CPU Control
+// ============================================================================
+
+// SelectHalt generates instructions to suspend the CPU until the next interrupt (HALT)
+func (z *instructionSelectorZ80) SelectHalt() error {
+	z.emit(newInstruction0(Z80_HALT))
+	return nil
+}
+
+// SelectNop generates a no-operation (NOP)
+func (z *instructionSelectorZ80) SelectNop() error {
+	z.emit(newInstruction0(Z80_NOP))
+	return nil
+}
+
 // ============================================================================
 // Memory Operations
 // ============================================================================
@@ -545,7 +848,7 @@ func (z *instructionSelectorZ80) SelectLoad(address *VirtualRegister, offset uin
 
 	switch size {
 	case 8:
-		vrHL := z.emitLoadIntoReg16(address, Z80RegHL)
+		vrHL := z.emitAddressIntoHL(address)
 		z.emitAddOffsetToHL(vrHL, offset)
 
 		result = z.vrAlloc.Allocate(Z80Registers8)
@@ -592,7 +895,7 @@ func (z *instructionSelectorZ80) SelectLoadIndexed(address *VirtualRegister, ind
 
 // SelectStore generates instructions to store to memory
 func (z *instructionSelectorZ80) SelectStore(address *VirtualRegister, value *VirtualRegister, offset uint16, size RegisterSize) error {
-	vrHL := z.emitLoadIntoReg16(address, Z80RegHL)
+	vrHL := z.emitAddressIntoHL(address)
 	z.emitAddOffsetToHL(vrHL, offset)
 
 	switch size {
@@ -621,6 +924,216 @@ func (z *instructionSelectorZ80) SelectStore(address *VirtualRegister, value *Vi
 	return nil // store has no result
 }
 
+// SelectPeek generates instructions to read a byte from memory into A
+// LD A, (NN) for a constant address, LD A, (HL) otherwise
+func (z *instructionSelectorZ80) SelectPeek(address *VirtualRegister) (*VirtualRegister, error) {
+	result := z.vrAlloc.Allocate(Z80RegA)
+	if address.Type == ImmediateValue {
+		z.emit(newInstruction(Z80_LD_A_NN, result, address))
+		return result, nil
+	}
+
+	vrHL := z.emitLoadIntoReg16(address, Z80RegHL)
+	z.emit(newInstruction(Z80_LD_R_HL, result, vrHL))
+	return result, nil
+}
+
+// SelectPoke generates instructions to write a byte to memory
+// LD (NN), A for a constant address, LD (HL), r otherwise
+func (z *instructionSelectorZ80) SelectPoke(address *VirtualRegister, value *VirtualRegister) error {
+	if address.Type == ImmediateValue {
+		vrA := z.emitLoadIntoReg8(value, Z80RegA)
+		z.emit(&machineInstructionZ80{
+			opcode:   Z80_LD_NN_A,
+			operands: []*VirtualRegister{address, vrA},
+		})
+		return nil
+	}
+
+	return z.SelectStore(address, value, 0, Bits8)
+}
+
+// SelectMemChr searches memory with CPIR: LD HL, address; LD BC, length; LD A, value; CPIR
+// CPIR stops one past the match with the Z flag set: DEC HL; JR Z, found; LD HL, 0
+// A runtime length is tested for 0 first: LD A, B; OR C; JR Z, not found (CPIR would search 64K)
+func (z *instructionSelectorZ80) SelectMemChr(address, value, length *VirtualRegister) (*VirtualRegister, error) {
+	if value.Size != Bits8 || length.Size != Bits16 {
+		return nil, fmt.Errorf("unsupported operand sizes for CPIR: %d, %d", value.Size, length.Size)
+	}
+	if length.Type != ImmediateValue {
+		return z.selectMemChrRuntime(address, value, length), nil
+	}
+
+	// CPIR modifies HL and BC: always work on copies
+	vrHL := z.vrAlloc.Allocate(Z80RegHL)
+	z.emit(newInstruction(Z80_LD_RR_NN, vrHL, address))
+	vrBC := z.vrAlloc.Allocate(Z80RegBC)
+	z.emit(newInstruction(Z80_LD_RR_NN, vrBC, length))
+	vrA := z.emitLoadIntoReg8(value, Z80RegA)
+	if vrA == nil {
+		return nil, fmt.Errorf("cannot load %s into A", value.String())
+	}
+	z.emitCPIR(vrHL, vrBC, vrA)
+	return vrHL, nil
+}
+
+// selectMemChrRuntime searches memory with a length only known at runtime: LD BC, length; LD HL, address;
+// LD A, B; OR C; JR Z, not found; LD A, value; CPIR; DEC HL; JR Z, found; LD HL, 0
+func (z *instructionSelectorZ80) selectMemChrRuntime(address, value, length *VirtualRegister) *VirtualRegister {
+	opcode := Z80_LD_R_N
+	if value.Type != ImmediateValue {
+		// the value may be in A, B or C (a parameter): copy it before they are loaded
+		vrValue := z.vrAlloc.Allocate(Z80Registers8)
+		z.emit(newInstruction(Z80_LD_R_R, vrValue, value))
+		value = vrValue
+		opcode = Z80_LD_R_R
+	}
+
+	// CPIR modifies HL and BC: always work on copies, the length first: it may be in HL (a call result)
+	vrBC := z.vrAlloc.Allocate(Z80RegBC)
+	z.emit(newInstruction(Z80_LD_RR_NN, vrBC, length))
+	vrHL := z.vrAlloc.Allocate(Z80RegHL)
+	z.emit(newInstruction(Z80_LD_RR_NN, vrHL, address))
+	z.emitTestZeroBC()
+	vrSkip := z.vrAlloc.AllocateImmediate(4, Bits8)
+	z.emit(newBranchInternal(Cond_Z, vrSkip)) // 4: jump to LD HL, 0
+
+	vrA := z.vrAlloc.Allocate(Z80RegA)
+	z.emit(newInstruction(opcode, vrA, value))
+	z.emitCPIR(vrHL, vrBC, vrA)
+	return vrHL
+}
+
+// emitCPIR searches for A and leaves the address of the match in HL, 0 when not found:
+// CPIR; DEC HL; JR Z, found; LD HL, 0
+func (z *instructionSelectorZ80) emitCPIR(vrHL, vrBC, vrA *VirtualRegister) {
+	z.emit(&machineInstructionZ80{
+		opcode:   Z80_CPIR,
+		result:   vrHL,
+		operands: []*VirtualRegister{vrBC, vrA},
+	})
+	// DEC rr does not change the flags
+	z.emit(newInstructionResult(Z80_DEC_RR, vrHL))
+	vrOne := z.vrAlloc.AllocateImmediate(1, Bits8)
+	z.emit(newBranchInternal(Cond_Z, vrOne)) // 1: jump over LD HL, 0
+	vrNull := z.vrAlloc.AllocateImmediate(0, Bits16)
+	z.emit(newInstruction(Z80_LD_RR_NN, vrHL, vrNull))
+}
+
+// maxUnrolledMemSet is the largest constant length filled with separate stores:
+// LD (HL), n; INC HL is shorter and faster than setting up LDIR up to 4 bytes
+const maxUnrolledMemSet = 4
+
+// SelectMemSet fills memory with the byte value.
+// Small constant lengths are unrolled: LD HL, address; LD (HL), value; INC HL; LD (HL), value...
+// Otherwise the first byte is copied onto the next by LDIR: LD (HL), value; LD DE, HL; INC DE; LD BC, length-1; LDIR
+// A runtime length is tested for 0 (nothing is stored) and 1 (only the first byte is stored), LDIR would wrap around.
+func (z *instructionSelectorZ80) SelectMemSet(address, value, length *VirtualRegister) error {
+	if value.Size != Bits8 || length.Size != Bits16 {
+		return fmt.Errorf("unsupported operand sizes for memset: %d, %d", value.Size, length.Size)
+	}
+	if length.Type == ImmediateValue && length.Value == 0 {
+		return nil
+	}
+
+	var opcode Z80Opcode
+	if value.Type == ImmediateValue {
+		opcode = Z80_LD_HL_N
+	} else {
+		opcode = Z80_LD_HL_R
+	}
+
+	if length.Type != ImmediateValue {
+		z.selectMemSetRuntime(address, opcode, value, length)
+		return nil
+	}
+
+	// the fill increments HL: always work on a copy
+	vrHL := z.vrAlloc.Allocate(Z80RegHL)
+	z.emit(newInstruction(Z80_LD_RR_NN, vrHL, address))
+	z.emit(newInstruction(opcode, vrHL, value))
+
+	if length.Value <= maxUnrolledMemSet {
+		for i := int32(1); i < length.Value; i++ {
+			z.emit(newInstructionResult(Z80_INC_RR, vrHL))
+			z.emit(newInstruction(opcode, vrHL, value))
+		}
+		return nil
+	}
+
+	vrDE := z.vrAlloc.Allocate(Z80RegDE)
+	z.emit(newInstruction(Z80_LD_RR_NN, vrDE, vrHL))
+	z.emit(newInstructionResult(Z80_INC_RR, vrDE))
+	vrBC := z.vrAlloc.Allocate(Z80RegBC)
+	z.emit(newInstruction(Z80_LD_RR_NN, vrBC, z.vrAlloc.AllocateImmediate(length.Value-1, Bits16)))
+	z.emit(&machineInstructionZ80{
+		opcode:   Z80_LDIR,
+		operands: []*VirtualRegister{vrHL, vrDE, vrBC},
+	})
+	return nil
+}
+
+// selectMemSetRuntime fills memory with a length only known at runtime: LD BC, length; LD HL, address;
+// LD A, B; OR C; JR Z, done; LD (HL), value; DEC BC; LD A, B; OR C; JR Z, done; LD DE, HL; INC DE; LDIR
+func (z *instructionSelectorZ80) selectMemSetRuntime(address *VirtualRegister, opcode Z80Opcode, value, length *VirtualRegister) {
+	if value.Type != ImmediateValue {
+		// the value may be in A, B or C (a parameter): copy it before they are loaded
+		vrValue := z.vrAlloc.Allocate(Z80Registers8)
+		z.emit(newInstruction(Z80_LD_R_R, vrValue, value))
+		value = vrValue
+	}
+
+	// the length first: it may be in HL (a call result)
+	vrBC := z.vrAlloc.Allocate(Z80RegBC)
+	z.emit(newInstruction(Z80_LD_RR_NN, vrBC, length))
+	// the fill increments HL: always work on a copy
+	vrHL := z.vrAlloc.Allocate(Z80RegHL)
+	z.emit(newInstruction(Z80_LD_RR_NN, vrHL, address))
+	z.emitTestZeroBC()
+	vrSkipAll := z.vrAlloc.AllocateImmediate(8, Bits8)
+	z.emit(newBranchInternal(Cond_Z, vrSkipAll)) // 8: jump over the rest of the fill
+
+	z.emit(newInstruction(opcode, vrHL, value))
+	// DEC rr does not change the flags
+	z.emit(newInstructionResult(Z80_DEC_RR, vrBC))
+	z.emitTestZeroBC()
+	vrSkipCopy := z.vrAlloc.AllocateImmediate(3, Bits8)
+	z.emit(newBranchInternal(Cond_Z, vrSkipCopy)) // 3: jump over LD DE, HL; INC DE; LDIR
+
+	vrDE := z.vrAlloc.Allocate(Z80RegDE)
+	z.emit(newInstruction(Z80_LD_RR_NN, vrDE, vrHL))
+	z.emit(newInstructionResult(Z80_INC_RR, vrDE))
+	z.emit(&machineInstructionZ80{
+		opcode:   Z80_LDIR,
+		operands: []*VirtualRegister{vrHL, vrDE, vrBC},
+	})
+}
+
+// emitTestZeroBC sets the Z flag when BC is zero: LD A, B; OR C
+func (z *instructionSelectorZ80) emitTestZeroBC() {
+	vrA := z.vrAlloc.Allocate(Z80RegA)
+	z.emit(newInstruction(Z80_LD_R_R, vrA, z.vrAlloc.Allocate(Z80RegB)))
+	z.emit(newInstruction(Z80_OR_R, vrA, z.vrAlloc.Allocate(Z80RegC)))
+}
+
+// SelectCopyData copies the bytes with a block copy: LD HL, source; LD DE, target; LD BC, size; LDIR
+func (z *instructionSelectorZ80) SelectCopyData(source, target string, size uint16) error {
+	if size == 0 {
+		return nil
+	}
+	vrHL := z.vrAlloc.Allocate(Z80RegHL)
+	z.emit(newLoadAddress(vrHL, source))
+	vrDE := z.vrAlloc.Allocate(Z80RegDE)
+	z.emit(newLoadAddress(vrDE, target))
+	vrBC := z.vrAlloc.Allocate(Z80RegBC)
+	z.emit(newInstruction(Z80_LD_RR_NN, vrBC, z.vrAlloc.AllocateImmediate(int32(size), Bits16)))
+	z.emit(&machineInstructionZ80{
+		opcode:   Z80_LDIR,
+		operands: []*VirtualRegister{vrHL, vrDE, vrBC},
+	})
+	return nil
+}
+
 func (z *instructionSelectorZ80) SelectStoreSequential(address *VirtualRegister, value *VirtualRegister, increment uint16, size RegisterSize) error {
 	vrHL := z.emitLoadIntoReg16(address, Z80RegHL)
 	z.emitAddOffsetToHL(vrHL, increment)
@@ -653,11 +1166,26 @@ func (z *instructionSelectorZ80) SelectStoreSequential(address *VirtualRegister,
 
 // SelectLoadConstant generates instructions to load an immediate value
 func (z *instructionSelectorZ80) SelectLoadConstant(value interface{}, size RegisterSize) (*VirtualRegister, error) {
-	val := value.(int)
+	var val int
+	switch v := value.(type) {
+	case int:
+		val = v
+	case bool:
+		val = zsm.BoolValue(v)
+	default:
+		return nil, fmt.Errorf("unsupported constant value: %v", value)
+	}
 	result := z.vrAlloc.AllocateImmediate(int32(val), size)
 	return result, nil
 }
 
+// SelectLoadDataAddress generates instructions to load the address of a data section label
+func (z *instructionSelectorZ80) SelectLoadDataAddress(label string) (*VirtualRegister, error) {
+	result := z.vrAlloc.Allocate(Z80Registers16)
+	z.emit(newLoadAddress(result, label))
+	return result, nil
+}
+
 // SelectLoadStackAddress generates instructions to compute the address of a stack location
 // Returns a VR containing SP + stackOffset
 func (z *instructionSelectorZ80) SelectLoadStackAddress(stackOffset uint16) (*VirtualRegister, error) {
@@ -710,6 +1238,111 @@ func (z *instructionSelectorZ80) SelectMove(target *VirtualRegister, source *Vir
 	return nil
 }
 
+// SelectCopy generates instructions to copy a register value into a new register
+func (z *instructionSelectorZ80) SelectCopy(value *VirtualRegister) (*VirtualRegister, error) {
+	switch value.Size {
+	case 8:
+		result := z.vrAlloc.Allocate(Z80Registers8)
+		z.emit(newInstruction(Z80_LD_R_R, result, value))
+		return result, nil
+	case 16:
+		// LD lo, value[lo]; LD hi, value[hi]
+		result := z.vrAlloc.Allocate(Z80Registers16)
+		loRegsValue, hiRegsValue := ToPairs(value.AllowedSet)
+		loRegsResult, hiRegsResult := ToPairs(result.AllowedSet)
+		z.emit(newInstruction(Z80_LD_R_R, z.vrAlloc.Allocate(loRegsResult), z.vrAlloc.Allocate(loRegsValue)))
+		z.emit(newInstruction(Z80_LD_R_R, z.vrAlloc.Allocate(hiRegsResult), z.vrAlloc.Allocate(hiRegsValue)))
+		return result, nil
+	}
+	return nil, fmt.Errorf("unsupported size for copy: %d", value.Size)
+}
+
+// SelectSwap generates instructions to exchange two register values
+// 16-bit: the values are kept in DE and HL and exchanged with EX DE, HL (no temporary).
+// 8-bit: LD tmp, left; LD left, right; LD right, tmp
+func (z *instructionSelectorZ80) SelectSwap(left, right *VirtualRegister, size RegisterSize) error {
+	switch size {
+	case 8:
+		tmp, err := z.SelectCopy(left)
+		if err != nil {
+			return err
+		}
+		z.emit(newInstruction(Z80_LD_R_R, left, right))
+		z.emit(newInstruction(Z80_LD_R_R, right, tmp))
+		return nil
+	case 16:
+		if right.HasRegister(&RegDE) && left.HasRegister(&RegHL) {
+			left, right = right, left
+		}
+		if !left.HasRegister(&RegDE) || !right.HasRegister(&RegHL) {
+			return fmt.Errorf("16-bit swap of values not in DE and HL not yet implemented")
+		}
+		left.AllowedSet = Z80RegDE
+		right.AllowedSet = Z80RegHL
+		z.emit(newInstruction(Z80_EX_DE_HL, left, right))
+		return nil
+	}
+	return fmt.Errorf("unsupported size for swap: %d", size)
+}
+
+// ============================================================================
+// Conversion Operations
+// ============================================================================
+
+// SelectWiden generates instructions to extend an 8-bit value to 16-bit
+// Unsigned: LD lo, value; LD hi, 0
+// Signed:   LD A, value; ADD A, A (sign into carry); SBC A, A (0 or FFh); LD hi, A; LD lo, value
+func (z *instructionSelectorZ80) SelectWiden(value *VirtualRegister, fromSize, toSize RegisterSize, signed bool) (*VirtualRegister, error) {
+	if fromSize != Bits8 || toSize != Bits16 {
+		return nil, fmt.Errorf("unsupported widening from %d to %d bits", fromSize, toSize)
+	}
+
+	if value.Type == ImmediateValue {
+		extended := value.Value & 0xFF
+		if signed {
+			extended = int32(int8(value.Value))
+		}
+		return z.vrAlloc.AllocateImmediate(extended, toSize), nil
+	}
+
+	result := z.vrAlloc.Allocate(Z80RegistersPP)
+	loRegs, hiRegs := ToPairs(result.AllowedSet)
+	vrHi := z.vrAlloc.Allocate(hiRegs)
+
+	if signed {
+		vrA := z.vrAlloc.Allocate(Z80RegA)
+		z.emit(newInstruction(Z80_LD_R_R, vrA, value))
+		z.emit(newInstruction(Z80_ADD_A_R, vrA, vrA))
+		z.emit(newInstruction(Z80_SBC_A_R, vrA, vrA))
+		z.emit(newInstruction(Z80_LD_R_R, vrHi, vrA))
+	} else {
+		vrZero := z.vrAlloc.AllocateImmediate(0, Bits8)
+		z.emit(newInstruction(Z80_LD_R_N, vrHi, vrZero))
+	}
+
+	vrLo := z.vrAlloc.Allocate(loRegs)
+	z.emit(newInstruction(Z80_LD_R_R, vrLo, value))
+	return result, nil
+}
+
+// SelectTruncate generates instructions to narrow a 16-bit value to 8-bit
+// The low byte of the register pair is kept, the high byte is dropped
+func (z *instructionSelectorZ80) SelectTruncate(value *VirtualRegister, fromSize, toSize RegisterSize) (*VirtualRegister, error) {
+	if fromSize != Bits16 || toSize != Bits8 {
+		return nil, fmt.Errorf("unsupported truncation from %d to %d bits", fromSize, toSize)
+	}
+
+	if value.Type == ImmediateValue {
+		return z.vrAlloc.AllocateImmediate(value.Value&0xFF, toSize), nil
+	}
+
+	loRegs, _ := ToPairs(value.AllowedSet)
+	vrLo := z.vrAlloc.Allocate(loRegs)
+	result := z.vrAlloc.Allocate(Z80Registers8)
+	z.emit(newInstruction(Z80_LD_R_R, result, vrLo))
+	return result, nil
+}
+
 // ============================================================================
 // Control Flow
 // ============================================================================
@@ -720,25 +1353,285 @@ func (z *instructionSelectorZ80) SelectJump(target *BasicBlock) error {
 	return nil
 }
 
+// SelectDecrementJumpNotZero decrements the 8-bit counter and jumps to the true block while it is not zero
+// DJNZ when the counter can be kept in B and the jump is near, DEC r; JP NZ otherwise
+func (z *instructionSelectorZ80) SelectDecrementJumpNotZero(ctx *ExprContext, counter *VirtualRegister, near bool) error {
+	if ctx == nil || ctx.Mode != BranchMode {
+		return fmt.Errorf("decrement and jump requires branch mode")
+	}
+	if counter.Size != 8 {
+		return fmt.Errorf("unsupported size for decrement and jump: %d", counter.Size)
+	}
+
+	if near && counter.Type == CandidateRegister && counter.HasRegister(&RegB) {
+		// DJNZ decrements B
+		counter.AllowedSet = []*Register{&RegB}
+		z.emit(&machineInstructionZ80{
+			opcode:        Z80_DJNZ_E,
+			result:        counter,
+			operands:      []*VirtualRegister{counter},
+			branchTargets: []*BasicBlock{ctx.TrueBlock, ctx.FalseBlock},
+		})
+		return nil
+	}
+
+	z.emit(newInstruction(Z80_DEC_R, counter, counter))
+	z.emit(newJumpWithCondition(Cond_NZ, ctx.TrueBlock, ctx.FalseBlock))
+	return nil
+}
+
+// SelectInlineAsm passes the assembly text through to the instruction stream verbatim
+func (z *instructionSelectorZ80) SelectInlineAsm(text string) error {
+	z.emit(newInlineAsm(text))
+	return nil
+}
+
+// SelectJumpTable generates an indexed jump through the table
+//
+//	LD A, index
+//	SUB base
+//	CP n            ; n = number of table entries
+//	JP NC, default
+//	LD L, A
+//	LD H, 0
+//	ADD HL, HL      ; entries are 2-byte addresses
+//	LD DE, table
+//	ADD HL, DE
+//	LD A, (HL)
+//	INC HL
+//	LD H, (HL)
+//	LD L, A
+//	JP (HL)
+func (z *instructionSelectorZ80) SelectJumpTable(index *VirtualRegister, base int, table *JumpTable, defaultTarget *BasicBlock) error {
+	if index.Size != Bits8 {
+		return fmt.Errorf("unsupported size for jump table index: %d", index.Size)
+	}
+	if len(table.Targets) == 0 || len(table.Targets) > 256 {
+		return fmt.Errorf("invalid jump table size: %d", len(table.Targets))
+	}
+
+	vrA := z.vrAlloc.Allocate(Z80RegA)
+	if index.Type == ImmediateValue {
+		z.emit(newInstruction(Z80_LD_R_N, vrA, index))
+	} else {
+		z.emit(newInstruction(Z80_LD_R_R, vrA, index))
+	}
+	if base&0xFF != 0 {
+		vrBase := z.vrAlloc.AllocateImmediate(int32(base&0xFF), Bits8)
+		z.emit(newInstruction(Z80_SUB_N, vrA, vrBase))
+	}
+
+	// unsigned compare also catches values below base (they wrapped around)
+	if len(table.Targets) < 256 {
+		vrCount := z.vrAlloc.AllocateImmediate(int32(len(table.Targets)), Bits8)
+		z.emit(newInstruction(Z80_CP_N, vrA, vrCount))
+		jumpDefault := newJump(Z80_JP_CC_NN, defaultTarget)
+		jumpDefault.conditionCode = Cond_NC
+		z.emit(jumpDefault)
+	}
+
+	// HL = table + index * 2
+	vrL := z.vrAlloc.Allocate(Z80RegL)
+	z.emit(newInstruction(Z80_LD_R_R, vrL, vrA))
+	vrH := z.vrAlloc.Allocate(Z80RegH)
+	z.emit(newInstruction(Z80_LD_R_N, vrH, z.vrAlloc.AllocateImmediate(0, Bits8)))
+	vrHL := z.vrAlloc.Allocate(Z80RegHL)
+	z.emit(newInstruction(Z80_ADD_HL_RR, vrHL, vrHL))
+	vrTable := z.vrAlloc.Allocate(Z80RegDE)
+	z.emit(newLoadAddress(vrTable, table.Label))
+	z.emit(newInstruction(Z80_ADD_HL_RR, vrHL, vrTable))
+
+	// HL = (HL)
+	vrLo := z.vrAlloc.Allocate(Z80RegA)
+	z.emit(newInstruction(Z80_LD_R_HL, vrLo, vrHL))
+	z.emit(newInstruction(Z80_INC_RR, vrHL, vrHL))
+	vrHi := z.vrAlloc.Allocate(Z80RegH)
+	z.emit(newInstruction(Z80_LD_R_HL, vrHi, vrHL))
+	vrTarget := z.vrAlloc.Allocate(Z80RegL)
+	z.emit(newInstruction(Z80_LD_R_R, vrTarget, vrLo))
+
+	// the jump shares the targets with the table, retargeting one updates both
+	jump := newInstructionOperand(Z80_JP_HL, vrHL)
+	jump.branchTargets = table.Targets
+	z.emit(jump)
+	return nil
+}
+
 // SelectCall generates a function call
 func (z *instructionSelectorZ80) SelectCall(functionName string, args []*VirtualRegister, returnSize RegisterSize) (*VirtualRegister, error) {
 	// Set up arguments according to calling convention
-	// For now, assume simple convention: pass in registers/stack
+
+	// Stack arguments are pushed last to first,
+	// the first stack argument ends up right above the return address.
+	// They go first, pushing may use the argument registers.
+	for i := len(args) - 1; i >= 0; i-- {
+		if _, _, useStack := z.callingConvention.GetParameterLocation(i, args[i].Size); useStack {
+			if err := z.emitPushArgument(args[i]); err != nil {
+				return nil, fmt.Errorf("argument %d of call to '%s': %w", i, functionName, err)
+			}
+		}
+	}
+
+	argRegs, err := z.emitRegisterArguments(functionName, args)
+	if err != nil {
+		return nil, err
+	}
 
 	callInstr := newCall(functionName)
+	// The CALL uses the argument registers (keeps them live up to the call)
+	callInstr.operands = argRegs
 
 	// Get return value if non-void
+	var result *VirtualRegister
 	if returnSize > 0 {
 		returnReg := z.callingConvention.GetReturnValueRegister(returnSize)
-		result := z.vrAlloc.Allocate([]*Register{returnReg})
+		result = z.vrAlloc.Allocate([]*Register{returnReg})
 		// Associate the result VR with the CALL instruction for proper liveness tracking
 		callInstr.result = result
-		z.emit(callInstr)
-		return result, nil
 	}
+	z.emitCall(callInstr)
 
-	z.emit(callInstr)
-	return nil, nil
+	// The caller removes the stack arguments
+	z.emitStackCleanup(z.callingConvention.GetStackParameterSize(len(args)))
+	return result, nil
+}
+
+// SelectTailCall generates a jump to a function: JP nn
+// The callee's RET returns to our caller.
+func (z *instructionSelectorZ80) SelectTailCall(functionName string, args []*VirtualRegister) error {
+	if z.callingConvention.GetStackParameterSize(len(args)) > 0 {
+		return fmt.Errorf("tail call to '%s' cannot pass arguments on the stack", functionName)
+	}
+
+	argRegs, err := z.emitRegisterArguments(functionName, args)
+	if err != nil {
+		return err
+	}
+
+	jumpInstr := newTailJump(functionName)
+	// The JP uses the argument registers (keeps them live up to the jump)
+	jumpInstr.operands = argRegs
+	z.emit(jumpInstr)
+	return nil
+}
+
+// emitRegisterArguments moves the register arguments into the convention's registers.
+// The moves are a parallel copy: an argument held in the register of an earlier argument
+// (g(y, x) with x in HL and y in DE) is copied before the earlier argument overwrites it.
+func (z *instructionSelectorZ80) emitRegisterArguments(functionName string, args []*VirtualRegister) ([]*VirtualRegister, error) {
+	regs := make([]*Register, len(args))
+	for i, arg := range args {
+		if reg, _, useStack := z.callingConvention.GetParameterLocation(i, arg.Size); !useStack {
+			regs[i] = reg
+		}
+	}
+
+	values := make([]*VirtualRegister, len(args))
+	for i, arg := range args {
+		values[i] = arg
+		if regs[i] != nil && isArgumentRegister(arg, regs[:i]) {
+			values[i] = z.emitCopyArgument(arg)
+		}
+	}
+
+	argRegs := make([]*VirtualRegister, 0, len(args))
+	for i, reg := range regs {
+		if reg == nil {
+			continue
+		}
+		vrArg, err := z.emitMoveArgument(values[i], reg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d of call to '%s': %w", i, functionName, err)
+		}
+		argRegs = append(argRegs, vrArg)
+	}
+	return argRegs, nil
+}
+
+// isArgumentRegister checks if the argument is fixed to (part of) one of the argument registers
+func isArgumentRegister(arg *VirtualRegister, regs []*Register) bool {
+	if len(arg.AllowedSet) != 1 {
+		return false
+	}
+	for _, reg := range regs {
+		if reg != nil && registersOverlap(arg.AllowedSet[0], reg) {
+			return true
+		}
+	}
+	return false
+}
+
+// emitCopyArgument copies an argument to a register of the allocator's choice
+func (z *instructionSelectorZ80) emitCopyArgument(arg *VirtualRegister) *VirtualRegister {
+	if arg.Size == Bits8 {
+		tmp := z.vrAlloc.Allocate(Z80Registers8)
+		z.emit(newInstruction(Z80_LD_R_R, tmp, arg))
+		return tmp
+	}
+	tmp := z.vrAlloc.Allocate(Z80Registers16)
+	z.emit(newInstruction(Z80_LD_RR_NN, tmp, arg))
+	return tmp
+}
+
+// emitMoveArgument loads an argument into its parameter register
+func (z *instructionSelectorZ80) emitMoveArgument(arg *VirtualRegister, reg *Register) (*VirtualRegister, error) {
+	if len(arg.AllowedSet) == 1 && arg.AllowedSet[0] == reg {
+		return arg, nil // already in place
+	}
+
+	vrArg := z.vrAlloc.Allocate([]*Register{reg})
+	switch {
+	case arg.Type == ImmediateValue && arg.Size == Bits8:
+		z.emit(newInstruction(Z80_LD_R_N, vrArg, arg))
+	case arg.Type == ImmediateValue:
+		z.emit(newInstruction(Z80_LD_RR_NN, vrArg, arg))
+	case arg.Type != CandidateRegister && arg.Type != AllocatedRegister:
+		return nil, fmt.Errorf("cannot move %s into %s", arg.String(), reg.Name)
+	case arg.Size == Bits8:
+		z.emit(newInstruction(Z80_LD_R_R, vrArg, arg))
+	default:
+		z.emit(newInstruction(Z80_LD_RR_NN, vrArg, arg))
+	}
+	return vrArg, nil
+}
+
+// emitPushArgument pushes an argument on the stack (8-bit values in the low byte)
+func (z *instructionSelectorZ80) emitPushArgument(arg *VirtualRegister) error {
+	value := arg
+	if arg.Size == Bits8 {
+		widened, err := z.SelectWiden(arg, Bits8, Bits16, false)
+		if err != nil {
+			return err
+		}
+		value = widened
+	}
+
+	vrPair := z.emitLoadIntoReg16(value, Z80Registers16)
+	if vrPair == nil {
+		return fmt.Errorf("cannot push %s", arg.String())
+	}
+	z.emit(newInstruction(Z80_PUSH_QQ, nil, vrPair))
+	return nil
+}
+
+// emitStackCleanup removes the pushed stack arguments after a call
+// POP into BC/DE is smaller and faster than adjusting SP and keeps the return value (A/HL) intact.
+func (z *instructionSelectorZ80) emitStackCleanup(size uint16) {
+	for slot := uint16(0); slot < size; slot += z80StackSlotSize {
+		vrDiscard := z.vrAlloc.Allocate(Z80RegistersPP)
+		z.emit(newInstructionResult(Z80_POP_QQ, vrDiscard))
+	}
+}
+
+// SelectRestart generates a one-byte call to a restart vector (RST p)
+// The operand is p, the vector divided by 8, as encoded in the opcode.
+func (z *instructionSelectorZ80) SelectRestart(vector uint8) error {
+	if vector&0x07 != 0 || vector > 0x38 {
+		return fmt.Errorf("invalid restart vector: 0x%02X", vector)
+	}
+	vrP := z.vrAlloc.AllocateImmediate(int32(vector>>3), Bits8)
+	z.emit(newInstructionOperand(Z80_RST_P, vrP))
+	return nil
 }
 
 // SelectReturn generates a return statement
@@ -748,12 +1641,40 @@ func (z *instructionSelectorZ80) SelectReturn(value *VirtualRegister) error {
 	return nil
 }
 
+// SelectInterruptReturn generates the return from an interrupt handler
+// Maskable interrupts are re-enabled before RETI, RETN restores the interrupt state itself.
+func (z *instructionSelectorZ80) SelectInterruptReturn(nonMaskable bool) error {
+	if nonMaskable {
+		z.emit(newInstruction0(Z80_RETN))
+		return nil
+	}
+	z.emit(newInstruction0(Z80_EI))
+	z.emit(newInstruction0(Z80_RETI))
+	return nil
+}
+
 // ============================================================================
 // Function Management
 // ============================================================================
 
 // SelectFunctionPrologue generates function entry code
+// '@fast' functions switch to the alternate register set first,
+// other interrupt handlers save all registers on the stack.
 func (z *instructionSelectorZ80) SelectFunctionPrologue(fn *zsm.SemFunctionDecl, frameSize uint16) error {
+	if fn != nil && fn.HasAttribute(zsm.AttributeFast) {
+		z.emit(newInstruction0(Z80_EXX))
+		z.emit(newExchangeAF())
+	} else if isInterruptHandler(fn) {
+		// registers are allocated later, so save them all
+		for _, reg := range z.interruptSavedRegisters() {
+			vrReg := z.vrAlloc.Allocate([]*Register{reg})
+			z.emit(newInstruction(pushOpcode(reg), nil, vrReg))
+		}
+	}
+	if frameSize == 0 {
+		return nil
+	}
+
 	// Allocate stack frame size needed
 	vrHL := z.vrAlloc.Allocate(Z80RegHL)
 	vrSP := z.vrAlloc.Allocate(Z80RegSP)
@@ -766,17 +1687,88 @@ func (z *instructionSelectorZ80) SelectFunctionPrologue(fn *zsm.SemFunctionDecl,
 }
 
 // SelectFunctionEpilogue generates function exit code
+// '@fast' functions switch back to the main register set last,
+// other interrupt handlers restore all registers from the stack.
 func (z *instructionSelectorZ80) SelectFunctionEpilogue(fn *zsm.SemFunctionDecl, frameSize uint16) error {
-	// Deallocate stack frame size
-	vrHL := z.vrAlloc.Allocate(Z80RegHL)
-	vrSP := z.vrAlloc.Allocate(Z80RegSP)
-	vrSize := z.vrAlloc.AllocateImmediate(int32(frameSize), Bits16)
-	z.emit(newInstruction(Z80_LD_HL_NN, vrHL, vrSize))
-	z.emit(newInstruction(Z80_ADD_HL_RR, vrHL, vrSP))
-	z.emit(newInstruction(Z80_LD_SP_HL, vrSP, vrHL))
+	if frameSize > 0 {
+		// a 16-bit return value is in HL: keep it in DE during the teardown
+		returnsHL := fn != nil && fn.ReturnType != nil && fn.ReturnType.Size() == 2
+		if returnsHL {
+			z.emitExchangeDEHL()
+		}
+		// Deallocate stack frame size
+		vrHL := z.vrAlloc.Allocate(Z80RegHL)
+		vrSP := z.vrAlloc.Allocate(Z80RegSP)
+		vrSize := z.vrAlloc.AllocateImmediate(int32(frameSize), Bits16)
+		z.emit(newInstruction(Z80_LD_HL_NN, vrHL, vrSize))
+		z.emit(newInstruction(Z80_ADD_HL_RR, vrHL, vrSP))
+		z.emit(newInstruction(Z80_LD_SP_HL, vrSP, vrHL))
+		if returnsHL {
+			z.emitExchangeDEHL()
+		}
+	}
+
+	if fn != nil && fn.HasAttribute(zsm.AttributeFast) {
+		z.emit(newExchangeAF())
+		z.emit(newInstruction0(Z80_EXX))
+	} else if isInterruptHandler(fn) {
+		saved := z.interruptSavedRegisters()
+		for i := len(saved) - 1; i >= 0; i-- {
+			vrReg := z.vrAlloc.Allocate([]*Register{saved[i]})
+			z.emit(newInstructionResult(popOpcode(saved[i]), vrReg))
+		}
+	}
 	return nil
 }
 
+// emitExchangeDEHL swaps DE and HL (EX DE, HL)
+func (z *instructionSelectorZ80) emitExchangeDEHL() {
+	vrDE := z.vrAlloc.Allocate(Z80RegDE)
+	vrHL := z.vrAlloc.Allocate(Z80RegHL)
+	z.emit(newInstruction(Z80_EX_DE_HL, vrDE, vrHL))
+}
+
+// interruptSavedRegisters are pushed (in order) by the prologue of an interrupt handler
+var interruptSavedRegisters = []*Register{&RegAF, &RegBC, &RegDE, &RegHL}
+
+// interruptSavedRegisters returns the registers an interrupt handler saves.
+// The index registers are only used by the undocumented instructions (IXH/IXL/IYH/IYL).
+func (z *instructionSelectorZ80) interruptSavedRegisters() []*Register {
+	if !z.allowUndocumented {
+		return interruptSavedRegisters
+	}
+	return append(slices.Clone(interruptSavedRegisters), &RegIX, &RegIY)
+}
+
+// pushOpcode returns the PUSH instruction for the register pair
+func pushOpcode(reg *Register) Z80Opcode {
+	switch reg {
+	case &RegIX:
+		return Z80_PUSH_IX
+	case &RegIY:
+		return Z80_PUSH_IY
+	default:
+		return Z80_PUSH_QQ
+	}
+}
+
+// popOpcode returns the POP instruction for the register pair
+func popOpcode(reg *Register) Z80Opcode {
+	switch reg {
+	case &RegIX:
+		return Z80_POP_IX
+	case &RegIY:
+		return Z80_POP_IY
+	default:
+		return Z80_POP_QQ
+	}
+}
+
+// isInterruptHandler checks if the function is entered by an (non-)maskable interrupt
+func isInterruptHandler(fn *zsm.SemFunctionDecl) bool {
+	return fn != nil && (fn.HasAttribute(zsm.AttributeInterrupt) || fn.HasAttribute(zsm.AttributeNMI))
+}
+
 // ============================================================================
 // Register Management
 // ============================================================================
@@ -821,33 +1813,104 @@ func (z *instructionSelectorZ80) GetTargetRegisters() []*Register {
 // Z80-specific helper types
 // ============================================================================
 
-// allocateRegistersFor creates VRs with constraints from instruction opcode
-// Returns (result, operand) - either can be nil if not applicable
-func (z *instructionSelectorZ80) allocateRegistersFor(opcode Z80Opcode) (result *VirtualRegister, operand *VirtualRegister) {
-	desc := Z80InstrDescriptors[opcode]
+// emitStaged emits the instruction for opcode, using its descriptor Dependencies
+// to move the operands into the registers the instruction allows.
+// The operands are the value of a read-write destination (first, if any) and the source (if any).
+// A read-write destination is always copied into a new VR (the instruction overwrites it),
+// a source is only loaded when it is not already restricted to the allowed registers.
+// Implicit read dependencies (CP reads A) and block instructions are not supported.
+// Returns the VR holding the result (nil when the instruction has no destination).
+func (z *instructionSelectorZ80) emitStaged(opcode Z80Opcode, operands ...*VirtualRegister) (*VirtualRegister, error) {
+	desc, ok := Z80InstrDescriptors[opcode]
+	if !ok {
+		return nil, fmt.Errorf("no descriptor for %s", opcode)
+	}
+
+	var dest, source *InstrDependency
+	for i := range desc.Dependencies {
+		dep := &desc.Dependencies[i]
+		isRegister := isRegisterOperand(dep.Type) || (dep.Type == OpNone && len(dep.Registers) > 0)
+		isConstant := dep.Type == OpConstant8 || dep.Type == OpConstant16
+
+		switch {
+		case isRegister && dep.Access != AccessRead:
+			if dest != nil {
+				return nil, fmt.Errorf("%s has more than one destination", opcode)
+			}
+			dest = dep
+		case isRegister || isConstant:
+			if source != nil {
+				return nil, fmt.Errorf("%s has more than one source", opcode)
+			}
+			source = dep
+		}
+	}
+
+	expected := 0
+	if dest != nil && dest.Access == AccessReadWrite {
+		expected++
+	}
+	if source != nil {
+		expected++
+	}
+	if len(operands) != expected {
+		return nil, fmt.Errorf("%s expects %d operands, got %d", opcode, expected, len(operands))
+	}
 
-	for _, dep := range desc.Dependencies {
-		// Only care about register dependencies
-		if dep.Type != OpRegister && dep.Type != OpRegisterPairPP &&
-			dep.Type != OpRegisterPairQQ && dep.Type != OpRegisterPairRR {
-			continue
+	var result *VirtualRegister
+	if dest != nil {
+		result = z.vrAlloc.Allocate(dest.Registers)
+		if dest.Access == AccessReadWrite {
+			if err := z.emitCopy(result, operands[0]); err != nil {
+				return nil, err
+			}
+			operands = operands[1:]
 		}
+	}
 
-		switch dep.Access {
-		case AccessWrite, AccessReadWrite:
-			// This is a result/destination - allocate new VR
-			if len(dep.Registers) > 0 && result == nil {
-				result = z.vrAlloc.Allocate(dep.Registers)
+	var sourceVR *VirtualRegister
+	if source != nil {
+		sourceVR = operands[0]
+		switch {
+		case source.Type == OpConstant8 || source.Type == OpConstant16:
+			if sourceVR.Type != ImmediateValue {
+				return nil, fmt.Errorf("%s requires a constant operand", opcode)
 			}
-		case AccessRead:
-			// This is an operand - ensure it's constrained correctly
-			if operand == nil {
-				operand = z.vrAlloc.Allocate(dep.Registers)
+		case !sourceVR.WithinRegisters(source.Registers):
+			sourceVR = z.vrAlloc.Allocate(source.Registers)
+			if err := z.emitCopy(sourceVR, operands[0]); err != nil {
+				return nil, err
 			}
 		}
 	}
 
-	return result, operand
+	z.emit(newInstruction(opcode, result, sourceVR))
+	return result, nil
+}
+
+// emitCopy loads the value (register or immediate) into the target VR of the same size
+func (z *instructionSelectorZ80) emitCopy(target, value *VirtualRegister) error {
+	switch {
+	case target.Size == 8 && value.Type == ImmediateValue:
+		z.emit(newInstruction(Z80_LD_R_N, target, value))
+	case target.Size == 8 && value.Size == 8:
+		z.emit(newInstruction(Z80_LD_R_R, target, value))
+	case target.Size == 16 && (value.Type == ImmediateValue || value.Size == 16):
+		z.emit(newInstruction(Z80_LD_RR_NN, target, value))
+	default:
+		return fmt.Errorf("cannot load %d-bit value into %d-bit register", value.Size, target.Size)
+	}
+	return nil
+}
+
+// isRegisterOperand returns true for the operand types that encode a register (pair)
+func isRegisterOperand(operandType OperandType) bool {
+	switch operandType {
+	case OpRegister, OpRegisterPairRR, OpRegisterPairQQ, OpRegisterPairPP:
+		return true
+	default:
+		return false
+	}
 }
 
 func (z *instructionSelectorZ80) emitLoadIntoReg8(value *VirtualRegister, targetRegs []*Register) *VirtualRegister {
@@ -919,6 +1982,16 @@ func (z *instructionSelectorZ80) emitLoadIntoReg16(value *VirtualRegister, targe
 	return vrTarget
 }
 
+// emitAddressIntoHL returns the address in HL for the (HL) addressing forms:
+// an address that may be in HL is kept in HL, other addresses are loaded into HL
+func (z *instructionSelectorZ80) emitAddressIntoHL(address *VirtualRegister) *VirtualRegister {
+	if address.Type == CandidateRegister && address.HasRegister(&RegHL) {
+		address.AllowedSet = Z80RegHL
+		return address
+	}
+	return z.emitLoadIntoReg16(address, Z80RegHL)
+}
+
 func (z *instructionSelectorZ80) emitStoreOnStack(value *VirtualRegister, stackTarget *VirtualRegister) *VirtualRegister {
 
 	vrHL := z.vrAlloc.Allocate(Z80RegHL)
@@ -988,29 +2061,43 @@ func (z *instructionSelectorZ80) emitAddOffsetToHL(vrHL *VirtualRegister, offset
 // emitCompare emits instructions to compare two VirtualRegisters
 // Returns a VirtualRegister containing the comparison result (if needed)
 // Sets flags accordingly
-func (z *instructionSelectorZ80) emitCompare(left, right *VirtualRegister) (*VirtualRegister, error) {
+// When canSwap is set, the 8-bit operands are swapped if that saves loading A
+// (an immediate left operand or a right operand already in A).
+// Returns swapped=true if the flags are those of comparing right with left, the caller inverts the condition.
+func (z *instructionSelectorZ80) emitCompare(left, right *VirtualRegister, canSwap bool) (result *VirtualRegister, swapped bool, err error) {
 	regSize := largestSize(left, right)
 
 	switch regSize {
 	case 8:
-		var opcode Z80Opcode
-		if left.Type == ImmediateValue {
-			// CP N, r
-			opcode = Z80_LD_R_N
-		} else {
-			// CP r, r
-			opcode = Z80_LD_R_R
+		if canSwap && !left.IsRegister(&RegA) &&
+			((left.Type == ImmediateValue && right.Type != ImmediateValue) || right.IsRegister(&RegA)) {
+			left, right = right, left
+			swapped = true
+		}
+
+		// no need to load A when the left operand already is in A
+		vrA := left
+		if !left.IsRegister(&RegA) {
+			var opcode Z80Opcode
+			if left.Type == ImmediateValue {
+				// CP N, r
+				opcode = Z80_LD_R_N
+			} else {
+				// CP r, r
+				opcode = Z80_LD_R_R
+			}
+			vrA = z.vrAlloc.Allocate(Z80RegA)
+			z.emit(newInstruction(opcode, vrA, left))
 		}
-		vrA := z.vrAlloc.Allocate(Z80RegA)
-		z.emit(newInstruction(opcode, vrA, left))
 
+		var opcode Z80Opcode
 		if right.Type == ImmediateValue {
 			opcode = Z80_CP_N
 		} else {
 			opcode = Z80_CP_R
 		}
 		z.emit(newInstruction(opcode, vrA, right))
-		return vrA, nil
+		return vrA, swapped, nil
 	case 16:
 		// ld hl, reg
 		vrHL := z.emitLoadIntoReg16(left, Z80RegHL)
@@ -1019,19 +2106,22 @@ func (z *instructionSelectorZ80) emitCompare(left, right *VirtualRegister) (*Vir
 
 		// or a(, a) - clears carry flag
 		vrA := z.vrAlloc.Allocate(Z80RegA)
-		z.emit(newInstructionResult(Z80_OR_R, vrA))
+		if err := z.SelectClearCarry(vrA); err != nil {
+			return nil, false, err
+		}
 		// sbc hl, bc|de
 		z.emit(newInstruction(Z80_SBC_HL_RR, vrHL, vrDE))
 		// add hl, bc|de
 		z.emit(newInstruction(Z80_ADD_HL_RR, vrHL, vrDE))
 		// c and z flags set accordingly
-		return vrHL, nil
+		return vrHL, false, nil
 	default:
-		return nil, fmt.Errorf("unsupported size for COMPARE: %d", regSize)
+		return nil, false, fmt.Errorf("unsupported size for COMPARE: %d", regSize)
 	}
 }
 
-// emitFlagToRegA converts a CPU flag to a boolean in register A (0 or 1)
+// emitFlagToRegA converts a CPU flag to a boolean in register A.
+// The result is always 0 or 1, the only valid values of a bool.
 func (z *instructionSelectorZ80) emitFlagToRegA(conditionCode ConditionCode) (*VirtualRegister, error) {
 	result := z.vrAlloc.Allocate(Z80RegA)
 
@@ -1040,9 +2130,14 @@ func (z *instructionSelectorZ80) emitFlagToRegA(conditionCode ConditionCode) (*V
 	// do not use 'xor a' here, as it clears flags
 	switch conditionCode {
 	case Cond_Z, Cond_NZ:
+		// skip the INC when the condition is false
+		skip := Cond_NZ
+		if conditionCode == Cond_NZ {
+			skip = Cond_Z
+		}
 		vrOne := z.vrAlloc.AllocateImmediate(1, 8)
 		z.emit(newInstruction(Z80_LD_R_N, result, vrZero))
-		z.emit(newBranchInternal(conditionCode, vrOne)) // 1: jump over next instruction
+		z.emit(newBranchInternal(skip, vrOne)) // 1: jump over next instruction
 		z.emit(newInstructionResult(Z80_INC_R, result))
 	case Cond_C:
 		z.emit(newInstruction(Z80_LD_R_N, result, vrZero))
@@ -1099,6 +2194,7 @@ type machineInstructionZ80 struct {
 	conditionCode ConditionCode
 	branchTargets []*BasicBlock
 	comment       string
+	clobbers      []*Register // registers changed by the called function
 }
 
 // newInstruction creates a new Z80 instruction
@@ -1113,6 +2209,16 @@ func newInstruction(opcode Z80Opcode, result, operand *VirtualRegister) *machine
 		operands: operands,
 	}
 }
+
+// newBitInstruction creates a CB bit instruction (BIT/SET/RES b, r)
+// operands follow the descriptor: bit index, register
+func newBitInstruction(opcode Z80Opcode, result, bit, operand *VirtualRegister) *machineInstructionZ80 {
+	return &machineInstructionZ80{
+		opcode:   opcode,
+		result:   result,
+		operands: []*VirtualRegister{bit, operand},
+	}
+}
 func newInstructionResult(opcode Z80Opcode, result *VirtualRegister) *machineInstructionZ80 {
 	return &machineInstructionZ80{
 		opcode: opcode,
@@ -1153,6 +2259,14 @@ func newJump(opcode Z80Opcode, target *BasicBlock) *machineInstructionZ80 {
 	}
 }
 
+// runtimeHelperName returns the name of a runtime helper routine (e.g. __mul8 or __imul8 when signed)
+func runtimeHelperName(operation string, signed bool) string {
+	if signed {
+		return "__i" + operation
+	}
+	return "__" + operation
+}
+
 // TODO: target block? or do we resolve them seperately after instruction selection?
 // newCall creates a function call
 func newCall(functionName string) *machineInstructionZ80 {
@@ -1162,6 +2276,48 @@ func newCall(functionName string) *machineInstructionZ80 {
 	}
 }
 
+// emitCall emits a call and records the registers the called function changes (calling convention)
+func (z *instructionSelectorZ80) emitCall(callInstr *machineInstructionZ80) {
+	callInstr.clobbers = z.callingConvention.GetClobberedRegisters(callInstr.comment)
+	z.emit(callInstr)
+}
+
+// newTailJump creates a jump to a function (tail call)
+// function name stored in the comment, like newCall
+func newTailJump(functionName string) *machineInstructionZ80 {
+	return &machineInstructionZ80{
+		opcode:  Z80_JP_NN,
+		comment: functionName,
+	}
+}
+
+// newLoadAddress creates a load of a label address (LD rr, label)
+// label stored in the comment, like newCall
+func newLoadAddress(result *VirtualRegister, label string) *machineInstructionZ80 {
+	return &machineInstructionZ80{
+		opcode:  Z80_LD_RR_NN,
+		result:  result,
+		comment: label,
+	}
+}
+
+// newInlineAsm creates a pass-through pseudo-instruction for inline assembly
+// text stored in the comment and written as is
+func newInlineAsm(text string) *machineInstructionZ80 {
+	return &machineInstructionZ80{
+		opcode:  Z80_INLINE_ASM,
+		comment: text,
+	}
+}
+
+// newExchangeAF creates EX AF,AF' (operands are implicit, shown for readability)
+func newExchangeAF() *machineInstructionZ80 {
+	return &machineInstructionZ80{
+		opcode:  Z80_EX_AF_AF,
+		comment: "AF, AF'",
+	}
+}
+
 // Implement MachineInstruction interface
 
 func (z *machineInstructionZ80) GetResult() *VirtualRegister {
@@ -1190,6 +2346,10 @@ func (z *machineInstructionZ80) GetCategory() InstrCategory {
 	return CatOther
 }
 
+func (z *machineInstructionZ80) GetClobbers() []*Register {
+	return z.clobbers
+}
+
 func (z *machineInstructionZ80) GetAddressingMode() AddressingMode {
 	if desc, ok := Z80InstrDescriptors[z.opcode]; ok {
 		return desc.AddressingMode
@@ -1204,6 +2364,12 @@ func (z *machineInstructionZ80) GetTargetBlocks() []*BasicBlock {
 	return z.branchTargets
 }
 
+func (z *machineInstructionZ80) SetTargetBlock(index int, block *BasicBlock) {
+	if index < len(z.branchTargets) {
+		z.branchTargets[index] = block
+	}
+}
+
 func (z *machineInstructionZ80) GetCost() InstructionCost {
 	cycles := uint8(0)
 	bytes := uint8(0)
@@ -1219,7 +2385,22 @@ func (z *machineInstructionZ80) GetCost() InstructionCost {
 	return InstructionCost{Cycles: cycles, Size: bytes}
 }
 
+// EncodedSize returns the size of the instruction in bytes (prefixes, opcode, immediate and displacement),
+// without encoding it. Returns false when the size is unknown: inline assembly is not assembled.
+func (z *machineInstructionZ80) EncodedSize() (int, bool) {
+	if z.opcode == Z80_INLINE_ASM {
+		return 0, false
+	}
+	if desc, ok := Z80InstrDescriptors[z.opcode]; ok {
+		return int(desc.Size), true
+	}
+	return 0, false
+}
+
 func (z *machineInstructionZ80) String() string {
+	if z.opcode == Z80_INLINE_ASM {
+		return z.comment
+	}
 
 	var builder strings.Builder
 	builder.WriteString(z.opcode.String())
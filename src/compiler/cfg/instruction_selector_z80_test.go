@@ -0,0 +1,924 @@
+package cfg
+
+import (
+	"testing"
+	"zenith/compiler/zsm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper to create a Z80 selector emitting into a fresh test block
+func newTestSelectorZ80() (*instructionSelectorZ80, *VirtualRegisterAllocator, *BasicBlock) {
+	block := newTestBlock()
+	vrAlloc := NewVirtualRegisterAllocator()
+	selector := NewInstructionSelectorZ80(vrAlloc).(*instructionSelectorZ80)
+	selector.SetCurrentBlock(block)
+	return selector, vrAlloc, block
+}
+
+// Helper to extract the opcodes of the emitted machine instructions
+func opcodesOf(instructions []MachineInstruction) []Z80Opcode {
+	opcodes := make([]Z80Opcode, 0, len(instructions))
+	for _, instr := range instructions {
+		opcodes = append(opcodes, instr.(*machineInstructionZ80).opcode)
+	}
+	return opcodes
+}
+
+func Test_InstrDescriptor_SCF(t *testing.T) {
+	desc, ok := Z80InstrDescriptors[Z80_SCF]
+	require.True(t, ok)
+
+	assert.Equal(t, Z80_SCF, desc.Opcode)
+	assert.Equal(t, uint8(4), desc.Cycles)
+	assert.Equal(t, uint8(1), desc.Size)
+	assert.Equal(t, InstrFlagN|InstrFlagH|InstrFlagC, desc.AffectedFlags)
+	assert.Equal(t, InstrFlagNone, desc.DependentFlags)
+	assert.Equal(t, "SCF", Z80_SCF.String())
+}
+
+func Test_InstrDescriptor_CCF(t *testing.T) {
+	desc, ok := Z80InstrDescriptors[Z80_CCF]
+	require.True(t, ok)
+
+	assert.Equal(t, Z80_CCF, desc.Opcode)
+	assert.Equal(t, uint8(4), desc.Cycles)
+	assert.Equal(t, uint8(1), desc.Size)
+	assert.Equal(t, InstrFlagN|InstrFlagH|InstrFlagC, desc.AffectedFlags)
+	// complement reads the current carry
+	assert.Equal(t, InstrFlagC, desc.DependentFlags)
+	assert.Equal(t, "CCF", Z80_CCF.String())
+}
+
+func Test_SelectorZ80_SetCarry(t *testing.T) {
+	selector, _, block := newTestSelectorZ80()
+
+	err := selector.SelectSetCarry()
+
+	require.NoError(t, err)
+	assert.Equal(t, []Z80Opcode{Z80_SCF}, opcodesOf(block.MachineInstructions))
+}
+
+func Test_SelectorZ80_ClearCarry(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	// without accumulator: SCF; CCF
+	err := selector.SelectClearCarry(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Z80Opcode{Z80_SCF, Z80_CCF}, opcodesOf(block.MachineInstructions))
+
+	// with accumulator: OR A
+	block.MachineInstructions = nil
+	vrA := vrAlloc.Allocate(Z80RegA)
+	err = selector.SelectClearCarry(vrA)
+	require.NoError(t, err)
+	require.Len(t, block.MachineInstructions, 1)
+	assert.Equal(t, []Z80Opcode{Z80_OR_R}, opcodesOf(block.MachineInstructions))
+	assert.Equal(t, vrA, block.MachineInstructions[0].GetResult())
+}
+
+func Test_SelectorZ80_Subtract16_ClearsCarryBeforeSBC(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	left := vrAlloc.Allocate(Z80RegHL)
+	right := vrAlloc.Allocate(Z80RegDE)
+
+	result, err := selector.SelectSubtract(left, right)
+
+	require.NoError(t, err)
+	assert.Equal(t, RegisterSize(16), result.Size)
+	assert.Equal(t, []Z80Opcode{Z80_LD_RR_NN, Z80_SCF, Z80_CCF, Z80_SBC_HL_RR, Z80_LD_RR_NN},
+		opcodesOf(block.MachineInstructions))
+
+	sbc := block.MachineInstructions[3]
+	assert.Equal(t, right, sbc.GetOperands()[0])
+}
+
+func Test_SelectorZ80_Add16_Immediate(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	x := vrAlloc.Allocate(Z80Registers16)
+	four := vrAlloc.AllocateImmediate(4, Bits16)
+
+	result, err := selector.SelectAdd(x, four)
+
+	require.NoError(t, err)
+	assert.Equal(t, RegisterSize(16), result.Size)
+	// LD HL, x; LD rr, 4; ADD HL, rr; LD result, HL
+	assert.Equal(t, []Z80Opcode{Z80_LD_RR_NN, Z80_LD_RR_NN, Z80_ADD_HL_RR, Z80_LD_RR_NN},
+		opcodesOf(block.MachineInstructions))
+
+	ldImm := block.MachineInstructions[1]
+	assert.Equal(t, ImmediateValue, ldImm.GetOperands()[0].Type)
+	assert.Equal(t, int32(4), ldImm.GetOperands()[0].Value)
+	assert.Equal(t, Z80RegistersPP, ldImm.GetResult().AllowedSet)
+}
+
+func Test_SelectorZ80_Add16_SmallImmediate(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	x := vrAlloc.Allocate(Z80Registers16)
+	two := vrAlloc.AllocateImmediate(2, Bits16)
+
+	// immediate on the left is ordered first
+	_, err := selector.SelectAdd(two, x)
+
+	require.NoError(t, err)
+	// LD HL, x; INC HL; INC HL; LD result, HL
+	assert.Equal(t, []Z80Opcode{Z80_LD_RR_NN, Z80_INC_HL, Z80_INC_HL, Z80_LD_RR_NN},
+		opcodesOf(block.MachineInstructions))
+	assert.Equal(t, x, block.MachineInstructions[0].GetOperands()[0])
+}
+
+func Test_SelectorZ80_Add16_Registers(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	x := vrAlloc.Allocate(Z80Registers16)
+	y := vrAlloc.Allocate(Z80Registers16)
+
+	_, err := selector.SelectAdd(x, y)
+
+	require.NoError(t, err)
+	// LD HL, x; ADD HL, y; LD result, HL
+	assert.Equal(t, []Z80Opcode{Z80_LD_RR_NN, Z80_ADD_HL_RR, Z80_LD_RR_NN},
+		opcodesOf(block.MachineInstructions))
+	assert.Equal(t, y, block.MachineInstructions[1].GetOperands()[0])
+}
+
+func Test_SelectorZ80_Widen_Unsigned(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	x := vrAlloc.Allocate(Z80Registers8)
+
+	result, err := selector.SelectWiden(x, Bits8, Bits16, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, RegisterSize(16), result.Size)
+	// LD hi, 0; LD lo, x
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_N, Z80_LD_R_R}, opcodesOf(block.MachineInstructions))
+
+	ldHi := block.MachineInstructions[0]
+	_, hiRegs := ToPairs(result.AllowedSet)
+	assert.Equal(t, hiRegs, ldHi.GetResult().AllowedSet)
+	assert.Equal(t, int32(0), ldHi.GetOperands()[0].Value)
+	assert.Equal(t, x, block.MachineInstructions[1].GetOperands()[0])
+}
+
+func Test_SelectorZ80_Widen_Signed(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	x := vrAlloc.Allocate(Z80Registers8)
+
+	result, err := selector.SelectWiden(x, Bits8, Bits16, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, RegisterSize(16), result.Size)
+	// LD A, x; ADD A, A; SBC A, A; LD hi, A; LD lo, x
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_ADD_A_R, Z80_SBC_A_R, Z80_LD_R_R, Z80_LD_R_R},
+		opcodesOf(block.MachineInstructions))
+
+	// high byte receives the sign mask from A
+	ldHi := block.MachineInstructions[3]
+	_, hiRegs := ToPairs(result.AllowedSet)
+	assert.Equal(t, hiRegs, ldHi.GetResult().AllowedSet)
+	assert.Equal(t, Z80RegA, ldHi.GetOperands()[0].AllowedSet)
+}
+
+func Test_SelectorZ80_MemChr(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	address := vrAlloc.Allocate(Z80RegistersPP)
+	value := vrAlloc.AllocateImmediate(0x0D, Bits8)
+	length := vrAlloc.AllocateImmediate(80, Bits16)
+
+	result, err := selector.SelectMemChr(address, value, length)
+
+	require.NoError(t, err)
+	// LD HL, address; LD BC, length; LD A, value; CPIR; DEC HL; JR Z, +1; LD HL, 0
+	assert.Equal(t, []Z80Opcode{
+		Z80_LD_RR_NN, Z80_LD_RR_NN, Z80_LD_R_N, Z80_CPIR, Z80_DEC_RR, Z80_JR_CC_E, Z80_LD_RR_NN,
+	}, opcodesOf(block.MachineInstructions))
+	assert.Equal(t, Z80RegHL, result.AllowedSet)
+
+	// the address is copied: CPIR modifies HL
+	assert.NotEqual(t, address, result)
+	assert.Equal(t, address, block.MachineInstructions[0].GetOperands()[0])
+	assert.Equal(t, Z80RegBC, block.MachineInstructions[1].GetResult().AllowedSet)
+	assert.Equal(t, Z80RegA, block.MachineInstructions[2].GetResult().AllowedSet)
+
+	// not found: HL = 0
+	skip := block.MachineInstructions[5].(*machineInstructionZ80)
+	assert.Equal(t, Cond_Z, skip.conditionCode)
+	assert.Equal(t, int32(1), skip.operands[0].Value)
+	assert.Equal(t, result, block.MachineInstructions[6].GetResult())
+	assert.Equal(t, int32(0), block.MachineInstructions[6].GetOperands()[0].Value)
+}
+
+func Test_SelectorZ80_MemChr_VariableLength(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	address := vrAlloc.Allocate(Z80RegistersPP)
+	value := vrAlloc.AllocateImmediate(0x0D, Bits8)
+	length := vrAlloc.Allocate(Z80Registers16)
+
+	result, err := selector.SelectMemChr(address, value, length)
+
+	require.NoError(t, err)
+	// a length of 0 is not searched (CPIR would search 64K): HL = 0
+	assert.Equal(t, []Z80Opcode{
+		Z80_LD_RR_NN, Z80_LD_RR_NN, Z80_LD_R_R, Z80_OR_R, Z80_JR_CC_E,
+		Z80_LD_R_N, Z80_CPIR, Z80_DEC_RR, Z80_JR_CC_E, Z80_LD_RR_NN,
+	}, opcodesOf(block.MachineInstructions))
+	assert.Equal(t, length, block.MachineInstructions[0].GetOperands()[0])
+	instructions := block.MachineInstructions
+	skip := instructions[4].(*machineInstructionZ80)
+	assert.Equal(t, Cond_Z, skip.conditionCode)
+	assert.Equal(t, int32(len(instructions)-6), skip.operands[0].Value)
+	assert.Equal(t, result, instructions[len(instructions)-1].GetResult())
+}
+
+func Test_SelectorZ80_MemSet_Unrolled(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	address := vrAlloc.Allocate(Z80RegistersPP)
+	value := vrAlloc.AllocateImmediate(0x20, Bits8)
+	length := vrAlloc.AllocateImmediate(3, Bits16)
+
+	err := selector.SelectMemSet(address, value, length)
+
+	require.NoError(t, err)
+	// LD HL, address; LD (HL), value; INC HL; LD (HL), value; INC HL; LD (HL), value
+	assert.Equal(t, []Z80Opcode{
+		Z80_LD_RR_NN, Z80_LD_HL_N, Z80_INC_RR, Z80_LD_HL_N, Z80_INC_RR, Z80_LD_HL_N,
+	}, opcodesOf(block.MachineInstructions))
+	for _, instr := range block.MachineInstructions[1:] {
+		assert.Equal(t, Z80RegHL, instr.GetResult().AllowedSet)
+	}
+}
+
+func Test_SelectorZ80_MemSet_Ldir(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	address := vrAlloc.Allocate(Z80RegistersPP)
+	value := vrAlloc.Allocate(Z80Registers8)
+	length := vrAlloc.AllocateImmediate(100, Bits16)
+
+	err := selector.SelectMemSet(address, value, length)
+
+	require.NoError(t, err)
+	// LD HL, address; LD (HL), value; LD DE, HL; INC DE; LD BC, length-1; LDIR
+	assert.Equal(t, []Z80Opcode{
+		Z80_LD_RR_NN, Z80_LD_HL_R, Z80_LD_RR_NN, Z80_INC_RR, Z80_LD_RR_NN, Z80_LDIR,
+	}, opcodesOf(block.MachineInstructions))
+
+	vrHL := block.MachineInstructions[0].GetResult()
+	assert.Equal(t, value, block.MachineInstructions[1].GetOperands()[0])
+	assert.Equal(t, Z80RegDE, block.MachineInstructions[2].GetResult().AllowedSet)
+	assert.Equal(t, vrHL, block.MachineInstructions[2].GetOperands()[0])
+	// the first byte is already stored
+	ldBC := block.MachineInstructions[4]
+	assert.Equal(t, Z80RegBC, ldBC.GetResult().AllowedSet)
+	assert.Equal(t, int32(99), ldBC.GetOperands()[0].Value)
+}
+
+func Test_SelectorZ80_MemSet_VariableLength(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	address := vrAlloc.Allocate(Z80RegistersPP)
+	value := vrAlloc.AllocateImmediate(0, Bits8)
+	length := vrAlloc.Allocate(Z80Registers16)
+
+	err := selector.SelectMemSet(address, value, length)
+
+	require.NoError(t, err)
+	// the length is not known: nothing is stored for 0, only the first byte for 1
+	assert.Equal(t, []Z80Opcode{
+		Z80_LD_RR_NN, Z80_LD_RR_NN, Z80_LD_R_R, Z80_OR_R, Z80_JR_CC_E,
+		Z80_LD_HL_N, Z80_DEC_RR, Z80_LD_R_R, Z80_OR_R, Z80_JR_CC_E,
+		Z80_LD_RR_NN, Z80_INC_RR, Z80_LDIR,
+	}, opcodesOf(block.MachineInstructions))
+	assert.Equal(t, length, block.MachineInstructions[0].GetOperands()[0])
+	// both skips end after LDIR
+	instructions := block.MachineInstructions
+	assert.Equal(t, int32(len(instructions)-5), instructions[4].GetOperands()[0].Value)
+	assert.Equal(t, int32(len(instructions)-10), instructions[9].GetOperands()[0].Value)
+}
+
+func Test_SelectorZ80_MemSet_ZeroLength(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	address := vrAlloc.Allocate(Z80RegistersPP)
+	value := vrAlloc.AllocateImmediate(0, Bits8)
+	length := vrAlloc.AllocateImmediate(0, Bits16)
+
+	require.NoError(t, selector.SelectMemSet(address, value, length))
+	assert.Empty(t, block.MachineInstructions)
+}
+
+func Test_SelectorZ80_Widen_Immediate(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	minusOne := vrAlloc.AllocateImmediate(0xFF, Bits8)
+
+	unsigned, err := selector.SelectWiden(minusOne, Bits8, Bits16, false)
+	require.NoError(t, err)
+	assert.Equal(t, int32(0x00FF), unsigned.Value)
+
+	signed, err := selector.SelectWiden(minusOne, Bits8, Bits16, true)
+	require.NoError(t, err)
+	assert.Equal(t, int32(-1), signed.Value)
+	assert.Equal(t, RegisterSize(16), signed.Size)
+
+	assert.Empty(t, block.MachineInstructions)
+}
+
+func Test_SelectorZ80_Truncate(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	x := vrAlloc.Allocate(Z80RegHL)
+
+	result, err := selector.SelectTruncate(x, Bits16, Bits8)
+
+	require.NoError(t, err)
+	assert.Equal(t, RegisterSize(8), result.Size)
+	// LD r, L
+	require.Equal(t, []Z80Opcode{Z80_LD_R_R}, opcodesOf(block.MachineInstructions))
+	assert.Equal(t, []*Register{&RegL}, block.MachineInstructions[0].GetOperands()[0].AllowedSet)
+
+	_, err = selector.SelectTruncate(x, Bits8, Bits16)
+	assert.Error(t, err)
+}
+
+// Helper to find the runtime helper called by the emitted instructions
+func calledHelper(instructions []MachineInstruction) string {
+	for _, instr := range instructions {
+		z80Instr := instr.(*machineInstructionZ80)
+		if z80Instr.opcode == Z80_CALL_NN {
+			return z80Instr.comment
+		}
+	}
+	return ""
+}
+
+func Test_SelectorZ80_MultiplyDivide_Helpers(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     RegisterSize
+		signed   bool
+		multiply string
+		divide   string
+		modulo   string
+	}{
+		{"u8", Bits8, false, "__mul8", "__div8", "__mod8"},
+		{"i8", Bits8, true, "__imul8", "__idiv8", "__imod8"},
+		{"u16", Bits16, false, "__mul16", "__div16", "__mod16"},
+		{"i16", Bits16, true, "__imul16", "__idiv16", "__imod16"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regs := Z80Registers8
+			if tt.size == Bits16 {
+				regs = Z80Registers16
+			}
+
+			selector, vrAlloc, block := newTestSelectorZ80()
+			_, err := selector.SelectMultiply(vrAlloc.Allocate(regs), vrAlloc.Allocate(regs), tt.signed)
+			require.NoError(t, err)
+			assert.Equal(t, tt.multiply, calledHelper(block.MachineInstructions))
+
+			selector, vrAlloc, block = newTestSelectorZ80()
+			_, err = selector.SelectDivide(vrAlloc.Allocate(regs), vrAlloc.Allocate(regs), tt.signed)
+			require.NoError(t, err)
+			assert.Equal(t, tt.divide, calledHelper(block.MachineInstructions))
+
+			selector, vrAlloc, block = newTestSelectorZ80()
+			_, err = selector.SelectModulo(vrAlloc.Allocate(regs), vrAlloc.Allocate(regs), tt.signed)
+			require.NoError(t, err)
+			assert.Equal(t, tt.modulo, calledHelper(block.MachineInstructions))
+		})
+	}
+}
+
+func Test_SelectorZ80_Call_PushesStackArguments(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	args := []*VirtualRegister{
+		vrAlloc.Allocate(Z80RegHL),
+		vrAlloc.Allocate(Z80RegDE),
+		vrAlloc.Allocate(Z80RegBC),
+		vrAlloc.AllocateImmediate(0x1234, Bits16),
+		vrAlloc.AllocateImmediate(7, Bits8),
+	}
+
+	result, err := selector.SelectCall("five", args, Bits16)
+
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	// last argument is pushed first, the caller pops the 2 stack slots after the call
+	assert.Equal(t, []Z80Opcode{Z80_LD_RR_NN, Z80_PUSH_QQ, Z80_LD_RR_NN, Z80_PUSH_QQ, Z80_CALL_NN, Z80_POP_QQ, Z80_POP_QQ},
+		opcodesOf(block.MachineInstructions))
+
+	instrs := block.MachineInstructions
+	assert.Equal(t, int32(7), instrs[0].GetOperands()[0].Value)
+	assert.Equal(t, RegisterSize(16), instrs[0].GetOperands()[0].Size)
+	assert.Equal(t, instrs[0].GetResult(), instrs[1].GetOperands()[0])
+	assert.Equal(t, int32(0x1234), instrs[2].GetOperands()[0].Value)
+	assert.Equal(t, instrs[2].GetResult(), instrs[3].GetOperands()[0])
+
+	// the cleanup does not touch the return value
+	assert.Equal(t, Z80RegistersPP, instrs[5].GetResult().AllowedSet)
+	assert.Equal(t, Z80RegHL, result.AllowedSet)
+}
+
+func Test_SelectorZ80_Call_RegisterArgumentsOnly(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	args := []*VirtualRegister{vrAlloc.Allocate(Z80RegL), vrAlloc.Allocate(Z80RegE)}
+
+	_, err := selector.SelectCall("two", args, 0)
+
+	require.NoError(t, err)
+	assert.Equal(t, []Z80Opcode{Z80_CALL_NN}, opcodesOf(block.MachineInstructions))
+}
+
+func Test_SelectorZ80_Call_MovesArgumentsIntoRegisters(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	x := vrAlloc.Allocate(Z80Registers8)
+	y := vrAlloc.AllocateImmediate(0x1234, Bits16)
+
+	_, err := selector.SelectCall("two", []*VirtualRegister{x, y}, Bits8)
+
+	require.NoError(t, err)
+	// LD L, x; LD DE, 1234h; CALL two
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_LD_RR_NN, Z80_CALL_NN}, opcodesOf(block.MachineInstructions))
+
+	instrs := block.MachineInstructions
+	assert.Equal(t, Z80RegL, instrs[0].GetResult().AllowedSet)
+	assert.Equal(t, x, instrs[0].GetOperands()[0])
+	assert.Equal(t, Z80RegDE, instrs[1].GetResult().AllowedSet)
+	assert.Equal(t, y, instrs[1].GetOperands()[0])
+
+	// the call uses the argument registers
+	assert.Equal(t, []*VirtualRegister{instrs[0].GetResult(), instrs[1].GetResult()}, instrs[2].GetOperands())
+}
+
+func Test_SelectorZ80_TailCall(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	x := vrAlloc.Allocate(Z80Registers8)
+
+	err := selector.SelectTailCall("helper", []*VirtualRegister{x})
+
+	require.NoError(t, err)
+	// LD L, x; JP helper
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_JP_NN}, opcodesOf(block.MachineInstructions))
+	jump := block.MachineInstructions[1]
+	assert.Equal(t, "helper", jump.(*machineInstructionZ80).comment)
+	assert.Empty(t, jump.GetTargetBlocks())
+	assert.Equal(t, []*VirtualRegister{block.MachineInstructions[0].GetResult()}, jump.GetOperands())
+}
+
+func Test_SelectorZ80_TailCall_StackArguments_Error(t *testing.T) {
+	selector, vrAlloc, _ := newTestSelectorZ80()
+
+	args := make([]*VirtualRegister, 4)
+	for i := range args {
+		args[i] = vrAlloc.AllocateImmediate(int32(i), Bits8)
+	}
+
+	err := selector.SelectTailCall("helper", args)
+	assert.Error(t, err)
+}
+
+func Test_SelectorZ80_ShiftLeftSetBit_Undocumented(t *testing.T) {
+	block := newTestBlock()
+	vrAlloc := NewVirtualRegisterAllocator()
+	selector := NewInstructionSelectorZ80WithOptions(vrAlloc, InstructionSelectorZ80Options{AllowUndocumented: true})
+	selector.SetCurrentBlock(block)
+
+	x := vrAlloc.Allocate(Z80Registers8)
+	result, err := selector.SelectShiftLeftSetBit(x)
+
+	require.NoError(t, err)
+	// LD r, x; SLL r
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_SLL_R}, opcodesOf(block.MachineInstructions))
+	assert.Equal(t, result, block.MachineInstructions[1].GetResult())
+}
+
+func Test_SelectorZ80_ShiftLeftSetBit_Documented(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	x := vrAlloc.Allocate(Z80Registers8)
+	_, err := selector.SelectShiftLeftSetBit(x)
+
+	require.NoError(t, err)
+	// LD r, x; SLA r; INC r
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_SLA_R, Z80_INC_R}, opcodesOf(block.MachineInstructions))
+	assert.NotContains(t, opcodesOf(block.MachineInstructions), Z80_SLL_R)
+}
+
+func Test_SelectorZ80_ShiftLeftSetBit_16bit_Error(t *testing.T) {
+	selector, vrAlloc, _ := newTestSelectorZ80()
+
+	x := vrAlloc.Allocate(Z80Registers16)
+	_, err := selector.SelectShiftLeftSetBit(x)
+	assert.Error(t, err)
+}
+
+func Test_SelectorZ80_JumpTable(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	x := vrAlloc.Allocate(Z80Registers8)
+	targets := []*BasicBlock{newTestBlock(), newTestBlock(), newTestBlock()}
+	defaultTarget := newTestBlock()
+	table := &JumpTable{Label: "fn.jumptable.0", Targets: targets}
+
+	err := selector.SelectJumpTable(x, 2, table, defaultTarget)
+
+	require.NoError(t, err)
+	assert.Equal(t, []Z80Opcode{
+		Z80_LD_R_R, Z80_SUB_N, Z80_CP_N, Z80_JP_CC_NN, // range check
+		Z80_LD_R_R, Z80_LD_R_N, Z80_ADD_HL_RR, Z80_LD_RR_NN, Z80_ADD_HL_RR, // HL = table + index * 2
+		Z80_LD_R_HL, Z80_INC_RR, Z80_LD_R_HL, Z80_LD_R_R, // HL = (HL)
+		Z80_JP_HL,
+	}, opcodesOf(block.MachineInstructions))
+
+	rangeCheck := block.MachineInstructions[3].(*machineInstructionZ80)
+	assert.Equal(t, Cond_NC, rangeCheck.conditionCode)
+	assert.Equal(t, []*BasicBlock{defaultTarget}, rangeCheck.GetTargetBlocks())
+	assert.Equal(t, "fn.jumptable.0", block.MachineInstructions[7].(*machineInstructionZ80).comment)
+	assert.Equal(t, targets, block.MachineInstructions[13].GetTargetBlocks())
+}
+
+func Test_SelectorZ80_JumpTable_16bit_Error(t *testing.T) {
+	selector, vrAlloc, _ := newTestSelectorZ80()
+
+	x := vrAlloc.Allocate(Z80Registers16)
+	table := &JumpTable{Label: "fn.jumptable.0", Targets: []*BasicBlock{newTestBlock()}}
+
+	err := selector.SelectJumpTable(x, 0, table, newTestBlock())
+	assert.Error(t, err)
+}
+
+func Test_SelectorZ80_DecrementJumpNotZero_Near(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	counter := vrAlloc.Allocate(Z80Registers8)
+	body, exit := newTestBlock(), newTestBlock()
+
+	err := selector.SelectDecrementJumpNotZero(NewExprContextBranch(body, exit), counter, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, []Z80Opcode{Z80_DJNZ_E}, opcodesOf(block.MachineInstructions))
+	assert.Equal(t, []*BasicBlock{body, exit}, block.MachineInstructions[0].GetTargetBlocks())
+	assert.Equal(t, []*Register{&RegB}, counter.AllowedSet)
+}
+
+func Test_SelectorZ80_DecrementJumpNotZero_Far(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	counter := vrAlloc.Allocate(Z80Registers8)
+	body, exit := newTestBlock(), newTestBlock()
+
+	err := selector.SelectDecrementJumpNotZero(NewExprContextBranch(body, exit), counter, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, []Z80Opcode{Z80_DEC_R, Z80_JP_CC_NN}, opcodesOf(block.MachineInstructions))
+	jump := block.MachineInstructions[1].(*machineInstructionZ80)
+	assert.Equal(t, Cond_NZ, jump.conditionCode)
+	assert.Equal(t, []*BasicBlock{body, exit}, jump.GetTargetBlocks())
+	assert.Equal(t, Z80Registers8, counter.AllowedSet)
+}
+
+func Test_SelectorZ80_InlineAsm(t *testing.T) {
+	selector, _, block := newTestSelectorZ80()
+
+	err := selector.SelectInlineAsm("\n\tdi\n\thalt\n")
+
+	require.NoError(t, err)
+	assert.Equal(t, []Z80Opcode{Z80_INLINE_ASM}, opcodesOf(block.MachineInstructions))
+	assert.Equal(t, "\n\tdi\n\thalt\n", block.MachineInstructions[0].String())
+}
+
+func Test_SelectorZ80_Overflow_Add8(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+	trueBlock, falseBlock := newTestBlock(), newTestBlock()
+
+	a := vrAlloc.Allocate(Z80Registers8)
+	b := vrAlloc.Allocate(Z80Registers8)
+	_, err := selector.SelectOverflow(NewExprContextBranch(trueBlock, falseBlock), a, b, false)
+
+	require.NoError(t, err)
+	// LD A, a; ADD A, b; LD r, A; JP PE, true
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_ADD_A_R, Z80_LD_R_R, Z80_JP_CC_NN}, opcodesOf(block.MachineInstructions))
+	jump := block.MachineInstructions[3].(*machineInstructionZ80)
+	assert.Equal(t, Cond_PE, jump.conditionCode)
+	assert.Equal(t, []*BasicBlock{trueBlock, falseBlock}, jump.GetTargetBlocks())
+}
+
+func Test_SelectorZ80_Overflow_Add16(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	a := vrAlloc.Allocate(Z80Registers16)
+	b := vrAlloc.Allocate(Z80Registers16)
+	_, err := selector.SelectOverflow(NewExprContextBranch(newTestBlock(), newTestBlock()), a, b, false)
+
+	require.NoError(t, err)
+	// ADD HL, rr does not set P/V: carry cleared + ADC HL, rr instead
+	opcodes := opcodesOf(block.MachineInstructions)
+	assert.Contains(t, opcodes, Z80_ADC_HL_RR)
+	assert.NotContains(t, opcodes, Z80_ADD_HL_RR)
+	assert.Equal(t, Z80_JP_CC_NN, opcodes[len(opcodes)-1])
+}
+
+func Test_SelectorZ80_Overflow_Subtract16(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	a := vrAlloc.Allocate(Z80Registers16)
+	b := vrAlloc.Allocate(Z80Registers16)
+	_, err := selector.SelectOverflow(NewExprContextBranch(newTestBlock(), newTestBlock()), a, b, true)
+
+	require.NoError(t, err)
+	assert.Contains(t, opcodesOf(block.MachineInstructions), Z80_SBC_HL_RR)
+	last := block.MachineInstructions[len(block.MachineInstructions)-1].(*machineInstructionZ80)
+	assert.Equal(t, Cond_PE, last.conditionCode)
+}
+
+func Test_SelectorZ80_Overflow_ValueMode_Error(t *testing.T) {
+	selector, vrAlloc, _ := newTestSelectorZ80()
+
+	a := vrAlloc.Allocate(Z80Registers8)
+	b := vrAlloc.Allocate(Z80Registers8)
+	_, err := selector.SelectOverflow(nil, a, b, false)
+
+	assert.Error(t, err)
+}
+
+func Test_SelectorZ80_Compare_LeftInA(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	a := vrAlloc.Allocate(Z80RegA)
+	b := vrAlloc.Allocate(Z80Registers8)
+	_, err := selector.SelectLessThan(NewExprContextBranch(newTestBlock(), newTestBlock()), a, b)
+
+	require.NoError(t, err)
+	// no LD A, a: CP b; JP C, true
+	assert.Equal(t, []Z80Opcode{Z80_CP_R, Z80_JP_CC_NN}, opcodesOf(block.MachineInstructions))
+	assert.Same(t, a, block.MachineInstructions[0].GetResult())
+}
+
+func Test_SelectorZ80_Compare_RightInA_Swapped(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	a := vrAlloc.Allocate(Z80Registers8)
+	b := vrAlloc.Allocate(Z80RegA)
+	_, err := selector.SelectEqual(NewExprContextBranch(newTestBlock(), newTestBlock()), a, b)
+
+	require.NoError(t, err)
+	// no LD A, b: CP a; JP Z, true
+	assert.Equal(t, []Z80Opcode{Z80_CP_R, Z80_JP_CC_NN}, opcodesOf(block.MachineInstructions))
+	assert.Same(t, a, block.MachineInstructions[0].GetOperands()[0])
+	jump := block.MachineInstructions[1].(*machineInstructionZ80)
+	assert.Equal(t, Cond_Z, jump.conditionCode)
+}
+
+func Test_SelectorZ80_Compare_RightInA_NotSwapped(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	a := vrAlloc.Allocate(Z80Registers8)
+	b := vrAlloc.Allocate(Z80RegA)
+	_, err := selector.SelectLessThan(NewExprContextBranch(newTestBlock(), newTestBlock()), a, b)
+
+	require.NoError(t, err)
+	// a < b has no single flag condition when swapped: LD A, a; CP b
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_CP_R, Z80_JP_CC_NN}, opcodesOf(block.MachineInstructions))
+}
+
+func Test_SelectorZ80_Compare_ImmediateLeft_LessEqual(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+	trueBlock, falseBlock := newTestBlock(), newTestBlock()
+
+	n := vrAlloc.AllocateImmediate(10, Bits8)
+	b := vrAlloc.Allocate(Z80Registers8)
+	_, err := selector.SelectLessEqual(NewExprContextBranch(trueBlock, falseBlock), n, b)
+
+	require.NoError(t, err)
+	// 10 <= b is compared as b >= 10: LD A, b; CP 10; JP NC, true
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_CP_N, Z80_JP_CC_NN}, opcodesOf(block.MachineInstructions))
+	jump := block.MachineInstructions[2].(*machineInstructionZ80)
+	assert.Equal(t, Cond_NC, jump.conditionCode)
+	assert.Equal(t, []*BasicBlock{trueBlock, falseBlock}, jump.GetTargetBlocks())
+}
+
+func Test_SelectorZ80_InterruptPrologue_IndexRegisters(t *testing.T) {
+	block := newTestBlock()
+	vrAlloc := NewVirtualRegisterAllocator()
+	selector := NewInstructionSelectorZ80WithOptions(vrAlloc, InstructionSelectorZ80Options{AllowUndocumented: true})
+	selector.SetCurrentBlock(block)
+	fn := &zsm.SemFunctionDecl{Name: "handler", Attributes: []string{zsm.AttributeInterrupt}}
+
+	require.NoError(t, selector.SelectFunctionPrologue(fn, 0))
+	// index register halves can be allocated: save IX and IY too
+	assert.Equal(t, []Z80Opcode{Z80_PUSH_QQ, Z80_PUSH_QQ, Z80_PUSH_QQ, Z80_PUSH_QQ, Z80_PUSH_IX, Z80_PUSH_IY},
+		opcodesOf(block.MachineInstructions))
+
+	block.MachineInstructions = nil
+	require.NoError(t, selector.SelectFunctionEpilogue(fn, 0))
+	assert.Equal(t, []Z80Opcode{Z80_POP_IY, Z80_POP_IX, Z80_POP_QQ, Z80_POP_QQ, Z80_POP_QQ, Z80_POP_QQ},
+		opcodesOf(block.MachineInstructions))
+}
+
+func Test_SelectorZ80_EmitStaged_LoadsIntoAllowedRegister(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	left := vrAlloc.Allocate([]*Register{&RegB})
+	right := vrAlloc.Allocate([]*Register{&RegIXH})
+
+	result, err := selector.emitStaged(Z80_ADD_A_R, left, right)
+
+	require.NoError(t, err)
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_LD_R_R, Z80_ADD_A_R}, opcodesOf(block.MachineInstructions))
+	assert.Equal(t, Z80RegA, result.AllowedSet)
+	loadA := block.MachineInstructions[0].(*machineInstructionZ80)
+	assert.Equal(t, result, loadA.result)
+	assert.Equal(t, []*VirtualRegister{left}, loadA.operands)
+	loadRight := block.MachineInstructions[1].(*machineInstructionZ80)
+	assert.Equal(t, []*VirtualRegister{right}, loadRight.operands)
+	add := block.MachineInstructions[2].(*machineInstructionZ80)
+	assert.Equal(t, []*VirtualRegister{loadRight.result}, add.operands)
+}
+
+func Test_SelectorZ80_EmitStaged_AllowedSourceUsedDirectly(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	left := vrAlloc.Allocate(Z80RegA)
+	right := vrAlloc.Allocate([]*Register{&RegC, &RegE})
+
+	_, err := selector.emitStaged(Z80_ADD_A_R, left, right)
+
+	require.NoError(t, err)
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_ADD_A_R}, opcodesOf(block.MachineInstructions))
+	add := block.MachineInstructions[1].(*machineInstructionZ80)
+	assert.Equal(t, []*VirtualRegister{right}, add.operands)
+}
+
+func Test_SelectorZ80_EmitStaged_Immediate(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	result, err := selector.emitStaged(Z80_INC_R, vrAlloc.AllocateImmediate(41, 8))
+
+	require.NoError(t, err)
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_N, Z80_INC_R}, opcodesOf(block.MachineInstructions))
+	assert.Equal(t, Z80Registers8, result.AllowedSet)
+}
+
+func Test_SelectorZ80_EmitStaged_Errors(t *testing.T) {
+	selector, vrAlloc, _ := newTestSelectorZ80()
+	reg8 := vrAlloc.Allocate(Z80Registers8)
+
+	_, err := selector.emitStaged(Z80_LD_R_N, reg8)
+	assert.ErrorContains(t, err, "requires a constant operand")
+
+	_, err = selector.emitStaged(Z80_ADD_A_R, reg8)
+	assert.ErrorContains(t, err, "expects 2 operands")
+
+	_, err = selector.emitStaged(Z80_CP_R, reg8)
+	assert.ErrorContains(t, err, "more than one source")
+}
+
+func Test_SelectorZ80_Increment(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	operand := vrAlloc.Allocate(Z80Registers8)
+	result, err := selector.SelectIncrement(operand)
+
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_INC_R}, opcodesOf(block.MachineInstructions))
+	inc := block.MachineInstructions[1].(*machineInstructionZ80)
+	assert.Equal(t, result, inc.result)
+}
+
+func Test_SelectorZ80_FlagToBool(t *testing.T) {
+	tests := []struct {
+		condition ConditionCode
+		opcodes   []Z80Opcode
+		skip      ConditionCode
+	}{
+		// LD A, 0; JR NZ, +1; INC A: A is 1 when Z is set
+		{Cond_Z, []Z80Opcode{Z80_LD_R_N, Z80_JR_CC_E, Z80_INC_R}, Cond_NZ},
+		{Cond_NZ, []Z80Opcode{Z80_LD_R_N, Z80_JR_CC_E, Z80_INC_R}, Cond_Z},
+		// LD A, 0; ADC A, 0: A is the carry
+		{Cond_C, []Z80Opcode{Z80_LD_R_N, Z80_ADC_A_N}, Cond_None},
+		// SBC A, A; INC A: A is 1 when there is no carry
+		{Cond_NC, []Z80Opcode{Z80_SBC_A_R, Z80_INC_R}, Cond_None},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition.String(), func(t *testing.T) {
+			selector, _, block := newTestSelectorZ80()
+
+			result, err := selector.emitFlagToRegA(tt.condition)
+
+			require.NoError(t, err)
+			assert.True(t, result.HasRegister(&RegA))
+			assert.Equal(t, tt.opcodes, opcodesOf(block.MachineInstructions))
+			if tt.skip != Cond_None {
+				assert.Equal(t, tt.skip, block.MachineInstructions[1].(*machineInstructionZ80).conditionCode)
+			}
+		})
+	}
+}
+
+func Test_SelectorZ80_LoadConstant_Bool(t *testing.T) {
+	selector, _, _ := newTestSelectorZ80()
+
+	vrTrue, err := selector.SelectLoadConstant(true, Bits8)
+	require.NoError(t, err)
+	vrFalse, err := selector.SelectLoadConstant(false, Bits8)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(1), vrTrue.Value)
+	assert.Equal(t, int32(0), vrFalse.Value)
+}
+
+func Test_SelectorZ80_LogicalNot_Value(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+	operand := vrAlloc.Allocate(Z80Registers8)
+	evaluate := func(*ExprContext, zsm.SemExpression) (*VirtualRegister, error) {
+		return operand, nil
+	}
+
+	result, err := selector.SelectLogicalNot(nil, nil, evaluate)
+
+	// a bool is 0 or 1: XOR 1 flips it
+	require.NoError(t, err)
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_XOR_N}, opcodesOf(block.MachineInstructions))
+	xor := block.MachineInstructions[1].(*machineInstructionZ80)
+	assert.Equal(t, result, xor.result)
+	assert.Equal(t, int32(1), xor.operands[len(xor.operands)-1].Value)
+}
+
+func Test_SelectorZ80_Epilogue_PreservesReturnValue(t *testing.T) {
+	selector, _, block := newTestSelectorZ80()
+	fn := &zsm.SemFunctionDecl{Name: "sum", ReturnType: zsm.U16Type}
+
+	require.NoError(t, selector.SelectFunctionEpilogue(fn, 3))
+	// the 16-bit return value in HL is parked in DE while HL tears down the frame
+	assert.Equal(t, []Z80Opcode{Z80_EX_DE_HL, Z80_LD_HL_NN, Z80_ADD_HL_RR, Z80_LD_SP_HL, Z80_EX_DE_HL},
+		opcodesOf(block.MachineInstructions))
+}
+
+func Test_SelectorZ80_Epilogue_VoidFrame(t *testing.T) {
+	selector, _, block := newTestSelectorZ80()
+	fn := &zsm.SemFunctionDecl{Name: "work"}
+
+	require.NoError(t, selector.SelectFunctionEpilogue(fn, 3))
+	assert.Equal(t, []Z80Opcode{Z80_LD_HL_NN, Z80_ADD_HL_RR, Z80_LD_SP_HL}, opcodesOf(block.MachineInstructions))
+}
+
+func Test_SelectorZ80_Call_SwappedArguments(t *testing.T) {
+	selector, vrAlloc, block := newTestSelectorZ80()
+
+	x := vrAlloc.Allocate(Z80RegHL)
+	y := vrAlloc.Allocate(Z80RegDE)
+
+	_, err := selector.SelectCall("g", []*VirtualRegister{y, x}, 0)
+
+	require.NoError(t, err)
+	// LD tmp, x; LD HL, y; LD DE, tmp; CALL g: x is copied before HL is overwritten
+	assert.Equal(t, []Z80Opcode{Z80_LD_RR_NN, Z80_LD_RR_NN, Z80_LD_RR_NN, Z80_CALL_NN}, opcodesOf(block.MachineInstructions))
+
+	instrs := block.MachineInstructions
+	tmp := instrs[0].GetResult()
+	assert.Equal(t, x, instrs[0].GetOperands()[0])
+	assert.Equal(t, Z80Registers16, tmp.AllowedSet)
+	assert.Equal(t, Z80RegHL, instrs[1].GetResult().AllowedSet)
+	assert.Equal(t, y, instrs[1].GetOperands()[0])
+	assert.Equal(t, Z80RegDE, instrs[2].GetResult().AllowedSet)
+	assert.Equal(t, tmp, instrs[2].GetOperands()[0])
+}
+
+func Test_SelectorZ80_CheckedAdd16_Immediate(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+	selector := NewInstructionSelectorZ80WithOptions(vrAlloc, InstructionSelectorZ80Options{CheckedArithmetic: true})
+	block := newTestBlock()
+	selector.SetCurrentBlock(block)
+
+	a := vrAlloc.Allocate(Z80Registers16)
+	one := vrAlloc.AllocateImmediate(1, Bits8)
+	_, err := selector.SelectAdd(one, a)
+
+	require.NoError(t, err)
+	// LD HL, a; LD rr, 1; SCF; CCF; ADC HL, rr; LD result, HL
+	assert.Equal(t, []Z80Opcode{Z80_LD_RR_NN, Z80_LD_RR_NN, Z80_SCF, Z80_CCF, Z80_ADC_HL_RR, Z80_LD_RR_NN},
+		opcodesOf(block.MachineInstructions))
+	instrs := block.MachineInstructions
+	assert.Equal(t, a, instrs[0].GetOperands()[0])
+	constant := instrs[1].GetOperands()[0]
+	assert.Equal(t, int32(1), constant.Value)
+	assert.Equal(t, RegisterSize(16), constant.Size)
+}
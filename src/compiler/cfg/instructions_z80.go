@@ -994,6 +994,63 @@ var InstrDesc_RES_B_R = InstrDescriptor{
 	Prefix2:        0,
 }
 
+var InstrDesc_BIT_B_HL = InstrDescriptor{
+	Opcode:   Z80_BIT_B_HL,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpBitIndex, Access: AccessRead},
+		{Type: OpRegisterPairRR, Access: AccessRead, Registers: []*Register{&RegHL}},
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN,
+	DependentFlags: InstrFlagNone,
+	Cycles:         12,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 3,
+	EncodingReg2SL: 0,
+	Prefix1:        0xCB,
+	Prefix2:        0,
+}
+
+var InstrDesc_SET_B_HL = InstrDescriptor{
+	Opcode:   Z80_SET_B_HL,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpBitIndex, Access: AccessRead},
+		{Type: OpRegisterPairRR, Access: AccessRead, Registers: []*Register{&RegHL}},
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagNone,
+	DependentFlags: InstrFlagNone,
+	Cycles:         15,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 3,
+	EncodingReg2SL: 0,
+	Prefix1:        0xCB,
+	Prefix2:        0,
+}
+
+var InstrDesc_RES_B_HL = InstrDescriptor{
+	Opcode:   Z80_RES_B_HL,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpBitIndex, Access: AccessRead},
+		{Type: OpRegisterPairRR, Access: AccessRead, Registers: []*Register{&RegHL}},
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagNone,
+	DependentFlags: InstrFlagNone,
+	Cycles:         15,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 3,
+	EncodingReg2SL: 0,
+	Prefix1:        0xCB,
+	Prefix2:        0,
+}
+
 // ============================================================================
 // Rotate/Shift Instructions (CB prefix)
 // ============================================================================
@@ -1124,6 +1181,132 @@ var InstrDesc_SRL_R = InstrDescriptor{
 	Prefix2:        0,
 }
 
+var InstrDesc_RLC_HL = InstrDescriptor{
+	Opcode:   Z80_RLC_HL,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpRegisterPairRR, Access: AccessRead, Registers: []*Register{&RegHL}},
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         15,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xCB,
+	Prefix2:        0,
+}
+
+var InstrDesc_RRC_HL = InstrDescriptor{
+	Opcode:   Z80_RRC_HL,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpRegisterPairRR, Access: AccessRead, Registers: []*Register{&RegHL}},
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         15,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xCB,
+	Prefix2:        0,
+}
+
+var InstrDesc_RL_HL = InstrDescriptor{
+	Opcode:   Z80_RL_HL,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpRegisterPairRR, Access: AccessRead, Registers: []*Register{&RegHL}},
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagC,
+	Cycles:         15,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xCB,
+	Prefix2:        0,
+}
+
+var InstrDesc_RR_HL = InstrDescriptor{
+	Opcode:   Z80_RR_HL,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpRegisterPairRR, Access: AccessRead, Registers: []*Register{&RegHL}},
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagC,
+	Cycles:         15,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xCB,
+	Prefix2:        0,
+}
+
+var InstrDesc_SLA_HL = InstrDescriptor{
+	Opcode:   Z80_SLA_HL,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpRegisterPairRR, Access: AccessRead, Registers: []*Register{&RegHL}},
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         15,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xCB,
+	Prefix2:        0,
+}
+
+var InstrDesc_SRA_HL = InstrDescriptor{
+	Opcode:   Z80_SRA_HL,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpRegisterPairRR, Access: AccessRead, Registers: []*Register{&RegHL}},
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         15,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xCB,
+	Prefix2:        0,
+}
+
+var InstrDesc_SRL_HL = InstrDescriptor{
+	Opcode:   Z80_SRL_HL,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpRegisterPairRR, Access: AccessRead, Registers: []*Register{&RegHL}},
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         15,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xCB,
+	Prefix2:        0,
+}
+
 // ============================================================================
 // Stack Instructions
 // ============================================================================
@@ -1166,6 +1349,82 @@ var InstrDesc_POP_QQ = InstrDescriptor{
 	Prefix2:        0,
 }
 
+var InstrDesc_PUSH_IX = InstrDescriptor{
+	Opcode:   Z80_PUSH_IX,
+	Category: CatStack,
+	Dependencies: []InstrDependency{
+		{Type: OpRegisterPairQQ, Access: AccessRead, Registers: []*Register{&RegIX}},
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegSP}}, // Implicit SP decrement
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagNone,
+	DependentFlags: InstrFlagNone,
+	Cycles:         15,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xDD,
+	Prefix2:        0,
+}
+
+var InstrDesc_POP_IX = InstrDescriptor{
+	Opcode:   Z80_POP_IX,
+	Category: CatStack,
+	Dependencies: []InstrDependency{
+		{Type: OpRegisterPairQQ, Access: AccessWrite, Registers: []*Register{&RegIX}},
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegSP}}, // Implicit SP increment
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagNone,
+	DependentFlags: InstrFlagNone,
+	Cycles:         14,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xDD,
+	Prefix2:        0,
+}
+
+var InstrDesc_PUSH_IY = InstrDescriptor{
+	Opcode:   Z80_PUSH_IY,
+	Category: CatStack,
+	Dependencies: []InstrDependency{
+		{Type: OpRegisterPairQQ, Access: AccessRead, Registers: []*Register{&RegIY}},
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegSP}}, // Implicit SP decrement
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagNone,
+	DependentFlags: InstrFlagNone,
+	Cycles:         15,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xFD,
+	Prefix2:        0,
+}
+
+var InstrDesc_POP_IY = InstrDescriptor{
+	Opcode:   Z80_POP_IY,
+	Category: CatStack,
+	Dependencies: []InstrDependency{
+		{Type: OpRegisterPairQQ, Access: AccessWrite, Registers: []*Register{&RegIY}},
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegSP}}, // Implicit SP increment
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagNone,
+	DependentFlags: InstrFlagNone,
+	Cycles:         14,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xFD,
+	Prefix2:        0,
+}
+
 // ============================================================================
 // Jump/Branch Instructions
 // ============================================================================
@@ -1488,6 +1747,24 @@ var InstrDesc_HALT = InstrDescriptor{
 	Prefix2:        0,
 }
 
+// InstrDesc_INLINE_ASM describes an asm block: nothing is known about it
+// so it is assumed to read and write all flags. Size is unknown (0).
+var InstrDesc_INLINE_ASM = InstrDescriptor{
+	Opcode:         Z80_INLINE_ASM,
+	Category:       CatOther,
+	Dependencies:   []InstrDependency{},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  allFlags,
+	DependentFlags: InstrFlagDynamic,
+	Cycles:         0,
+	CyclesTaken:    0,
+	Size:           0,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0,
+	Prefix2:        0,
+}
+
 var InstrDesc_NEG = InstrDescriptor{
 	Opcode:   Z80_NEG,
 	Category: CatOther,
@@ -1506,15 +1783,33 @@ var InstrDesc_NEG = InstrDescriptor{
 	Prefix2:        0,
 }
 
+var InstrDesc_SCF = InstrDescriptor{
+	Opcode:   Z80_SCF,
+	Category: CatOther,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessReadWrite, Registers: []*Register{&RegF}},
+	},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  InstrFlagN | InstrFlagH | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         4,
+	CyclesTaken:    0,
+	Size:           1,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0,
+	Prefix2:        0,
+}
+
 var InstrDesc_CCF = InstrDescriptor{
 	Opcode:   Z80_CCF,
 	Category: CatOther,
 	Dependencies: []InstrDependency{
 		{Type: OpRegister, Access: AccessReadWrite, Registers: []*Register{&RegF}},
 	},
-	AddressingMode: 0,
+	AddressingMode: AddrImplicit,
 	AffectedFlags:  InstrFlagN | InstrFlagH | InstrFlagC,
-	DependentFlags: InstrFlagNone,
+	DependentFlags: InstrFlagC,
 	Cycles:         4,
 	CyclesTaken:    0,
 	Size:           1,
@@ -1525,12 +1820,579 @@ var InstrDesc_CCF = InstrDescriptor{
 }
 
 // ============================================================================
-// Instruction Descriptor Lookup Table
+// Exchange Instructions
 // ============================================================================
 
-// Z80InstrDescriptors maps opcodes to their instruction descriptors
-var Z80InstrDescriptors = map[Z80Opcode]*InstrDescriptor{
-	// 8-bit Load
+var InstrDesc_EX_DE_HL = InstrDescriptor{
+	Opcode:   Z80_EX_DE_HL,
+	Category: CatOther,
+	Dependencies: []InstrDependency{
+		{Type: OpRegisterPairRR, Access: AccessReadWrite, Registers: []*Register{&RegDE}},
+		{Type: OpRegisterPairRR, Access: AccessReadWrite, Registers: []*Register{&RegHL}},
+	},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  InstrFlagNone,
+	DependentFlags: InstrFlagNone,
+	Cycles:         4,
+	CyclesTaken:    0,
+	Size:           1,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0,
+	Prefix2:        0,
+}
+
+var InstrDesc_EX_AF_AF = InstrDescriptor{
+	Opcode:   Z80_EX_AF_AF,
+	Category: CatOther,
+	Dependencies: []InstrDependency{
+		{Type: OpRegisterPairQQ, Access: AccessReadWrite, Registers: []*Register{&RegAF, &RegAFShadow}},
+	},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  InstrFlagNone,
+	DependentFlags: InstrFlagNone,
+	Cycles:         4,
+	CyclesTaken:    0,
+	Size:           1,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0,
+	Prefix2:        0,
+}
+
+var InstrDesc_EXX = InstrDescriptor{
+	Opcode:   Z80_EXX,
+	Category: CatOther,
+	Dependencies: []InstrDependency{
+		{Type: OpRegisterPairQQ, Access: AccessReadWrite, Registers: []*Register{&RegBC, &RegDE, &RegHL}},
+		{Type: OpRegisterPairQQ, Access: AccessReadWrite, Registers: []*Register{&RegBCShadow, &RegDEShadow, &RegHLShadow}},
+	},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  InstrFlagNone,
+	DependentFlags: InstrFlagNone,
+	Cycles:         4,
+	CyclesTaken:    0,
+	Size:           1,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0,
+	Prefix2:        0,
+}
+
+// ============================================================================
+// Block Compare Instructions
+// ============================================================================
+
+var InstrDesc_CPIR = InstrDescriptor{
+	Opcode:   Z80_CPIR,
+	Category: CatArithmetic,
+	Dependencies: []InstrDependency{
+		{Type: OpNone, Access: AccessRead, Registers: []*Register{&RegA}},       // Implicit value to find
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegHL}}, // Implicit address (incremented)
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegBC}}, // Implicit counter (decremented)
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN,
+	DependentFlags: InstrFlagNone,
+	Cycles:         16,
+	CyclesTaken:    5, // repeated while not found and BC <> 0
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xED,
+	Prefix2:        0,
+}
+
+var InstrDesc_CPDR = InstrDescriptor{
+	Opcode:   Z80_CPDR,
+	Category: CatArithmetic,
+	Dependencies: []InstrDependency{
+		{Type: OpNone, Access: AccessRead, Registers: []*Register{&RegA}},       // Implicit value to find
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegHL}}, // Implicit address (decremented)
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegBC}}, // Implicit counter (decremented)
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN,
+	DependentFlags: InstrFlagNone,
+	Cycles:         16,
+	CyclesTaken:    5, // repeated while not found and BC <> 0
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xED,
+	Prefix2:        0,
+}
+
+// ============================================================================
+// Block Transfer Instructions
+// ============================================================================
+
+var InstrDesc_LDIR = InstrDescriptor{
+	Opcode:   Z80_LDIR,
+	Category: CatStore,
+	Dependencies: []InstrDependency{
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegHL}}, // Implicit source (incremented)
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegDE}}, // Implicit destination (incremented)
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegBC}}, // Implicit counter (decremented)
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagH | InstrFlagPV | InstrFlagN,
+	DependentFlags: InstrFlagNone,
+	Cycles:         16,
+	CyclesTaken:    5, // repeated while BC <> 0
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xED,
+	Prefix2:        0,
+}
+
+var InstrDesc_LDDR = InstrDescriptor{
+	Opcode:   Z80_LDDR,
+	Category: CatStore,
+	Dependencies: []InstrDependency{
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegHL}}, // Implicit source (decremented)
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegDE}}, // Implicit destination (decremented)
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegBC}}, // Implicit counter (decremented)
+	},
+	AddressingMode: AddrIndirect,
+	AffectedFlags:  InstrFlagH | InstrFlagPV | InstrFlagN,
+	DependentFlags: InstrFlagNone,
+	Cycles:         16,
+	CyclesTaken:    5, // repeated while BC <> 0
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xED,
+	Prefix2:        0,
+}
+
+// ============================================================================
+// Undocumented Instructions
+// ============================================================================
+
+var InstrDesc_SLL_R = InstrDescriptor{
+	Opcode:   Z80_SLL_R,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessReadWrite, Registers: []*Register{&RegA, &RegB, &RegC, &RegD, &RegE, &RegH, &RegL}},
+	},
+	AddressingMode: AddrDirect,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xCB,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_LD_XR_XR = InstrDescriptor{
+	Opcode:   Z80_LD_XR_XR,
+	Category: CatMove,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessWrite, Registers: []*Register{&RegA, &RegB, &RegC, &RegD, &RegE, &RegIXH, &RegIXL}},
+		{Type: OpRegister, Access: AccessRead, Registers: []*Register{&RegA, &RegB, &RegC, &RegD, &RegE, &RegIXH, &RegIXL}},
+	},
+	AddressingMode: AddrDirect,
+	AffectedFlags:  InstrFlagNone,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xDD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_LD_XR_N = InstrDescriptor{
+	Opcode:   Z80_LD_XR_N,
+	Category: CatLoad,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessWrite, Registers: []*Register{&RegIXH, &RegIXL}},
+		{Type: OpConstant8, Access: AccessRead},
+	},
+	AddressingMode: AddrImmediate,
+	AffectedFlags:  InstrFlagNone,
+	DependentFlags: InstrFlagNone,
+	Cycles:         11,
+	CyclesTaken:    0,
+	Size:           3,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xDD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_ADD_A_XR = InstrDescriptor{
+	Opcode:   Z80_ADD_A_XR,
+	Category: CatArithmetic,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessReadWrite, Registers: []*Register{&RegA}},
+		{Type: OpRegister, Access: AccessRead, Registers: []*Register{&RegIXH, &RegIXL}},
+	},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xDD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_SUB_XR = InstrDescriptor{
+	Opcode:   Z80_SUB_XR,
+	Category: CatArithmetic,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessRead, Registers: []*Register{&RegIXH, &RegIXL}},
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegA}}, // Implicit A destination
+	},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xDD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_AND_XR = InstrDescriptor{
+	Opcode:   Z80_AND_XR,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessRead, Registers: []*Register{&RegIXH, &RegIXL}},
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegA}}, // Implicit A destination
+	},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xDD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_XOR_XR = InstrDescriptor{
+	Opcode:   Z80_XOR_XR,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessRead, Registers: []*Register{&RegIXH, &RegIXL}},
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegA}}, // Implicit A destination
+	},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xDD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_OR_XR = InstrDescriptor{
+	Opcode:   Z80_OR_XR,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessRead, Registers: []*Register{&RegIXH, &RegIXL}},
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegA}}, // Implicit A destination
+	},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xDD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_CP_XR = InstrDescriptor{
+	Opcode:   Z80_CP_XR,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessRead, Registers: []*Register{&RegIXH, &RegIXL}},
+		{Type: OpNone, Access: AccessRead, Registers: []*Register{&RegA}}, // Implicit A compare source
+	},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xDD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_INC_XR = InstrDescriptor{
+	Opcode:   Z80_INC_XR,
+	Category: CatArithmetic,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessReadWrite, Registers: []*Register{&RegIXH, &RegIXL}},
+	},
+	AddressingMode: AddrDirect,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xDD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_DEC_XR = InstrDescriptor{
+	Opcode:   Z80_DEC_XR,
+	Category: CatArithmetic,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessReadWrite, Registers: []*Register{&RegIXH, &RegIXL}},
+	},
+	AddressingMode: AddrDirect,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xDD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_LD_YR_YR = InstrDescriptor{
+	Opcode:   Z80_LD_YR_YR,
+	Category: CatMove,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessWrite, Registers: []*Register{&RegA, &RegB, &RegC, &RegD, &RegE, &RegIYH, &RegIYL}},
+		{Type: OpRegister, Access: AccessRead, Registers: []*Register{&RegA, &RegB, &RegC, &RegD, &RegE, &RegIYH, &RegIYL}},
+	},
+	AddressingMode: AddrDirect,
+	AffectedFlags:  InstrFlagNone,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xFD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_LD_YR_N = InstrDescriptor{
+	Opcode:   Z80_LD_YR_N,
+	Category: CatLoad,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessWrite, Registers: []*Register{&RegIYH, &RegIYL}},
+		{Type: OpConstant8, Access: AccessRead},
+	},
+	AddressingMode: AddrImmediate,
+	AffectedFlags:  InstrFlagNone,
+	DependentFlags: InstrFlagNone,
+	Cycles:         11,
+	CyclesTaken:    0,
+	Size:           3,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xFD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_ADD_A_YR = InstrDescriptor{
+	Opcode:   Z80_ADD_A_YR,
+	Category: CatArithmetic,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessReadWrite, Registers: []*Register{&RegA}},
+		{Type: OpRegister, Access: AccessRead, Registers: []*Register{&RegIYH, &RegIYL}},
+	},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xFD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_SUB_YR = InstrDescriptor{
+	Opcode:   Z80_SUB_YR,
+	Category: CatArithmetic,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessRead, Registers: []*Register{&RegIYH, &RegIYL}},
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegA}}, // Implicit A destination
+	},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xFD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_AND_YR = InstrDescriptor{
+	Opcode:   Z80_AND_YR,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessRead, Registers: []*Register{&RegIYH, &RegIYL}},
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegA}}, // Implicit A destination
+	},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xFD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_XOR_YR = InstrDescriptor{
+	Opcode:   Z80_XOR_YR,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessRead, Registers: []*Register{&RegIYH, &RegIYL}},
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegA}}, // Implicit A destination
+	},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xFD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_OR_YR = InstrDescriptor{
+	Opcode:   Z80_OR_YR,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessRead, Registers: []*Register{&RegIYH, &RegIYL}},
+		{Type: OpNone, Access: AccessReadWrite, Registers: []*Register{&RegA}}, // Implicit A destination
+	},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xFD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_CP_YR = InstrDescriptor{
+	Opcode:   Z80_CP_YR,
+	Category: CatBitwise,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessRead, Registers: []*Register{&RegIYH, &RegIYL}},
+		{Type: OpNone, Access: AccessRead, Registers: []*Register{&RegA}}, // Implicit A compare source
+	},
+	AddressingMode: AddrImplicit,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN | InstrFlagC,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xFD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_INC_YR = InstrDescriptor{
+	Opcode:   Z80_INC_YR,
+	Category: CatArithmetic,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessReadWrite, Registers: []*Register{&RegIYH, &RegIYL}},
+	},
+	AddressingMode: AddrDirect,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xFD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+var InstrDesc_DEC_YR = InstrDescriptor{
+	Opcode:   Z80_DEC_YR,
+	Category: CatArithmetic,
+	Dependencies: []InstrDependency{
+		{Type: OpRegister, Access: AccessReadWrite, Registers: []*Register{&RegIYH, &RegIYL}},
+	},
+	AddressingMode: AddrDirect,
+	AffectedFlags:  InstrFlagS | InstrFlagZ | InstrFlagH | InstrFlagPV | InstrFlagN,
+	DependentFlags: InstrFlagNone,
+	Cycles:         8,
+	CyclesTaken:    0,
+	Size:           2,
+	EncodingReg1SL: 0,
+	EncodingReg2SL: 0,
+	Prefix1:        0xFD,
+	Prefix2:        0,
+	Undocumented:   true,
+}
+
+// ============================================================================
+// Instruction Descriptor Lookup Table
+// ============================================================================
+
+// Z80InstrDescriptors maps opcodes to their instruction descriptors
+var Z80InstrDescriptors = map[Z80Opcode]*InstrDescriptor{
+	// 8-bit Load
 	Z80_LD_R_R:  &InstrDesc_LD_R_R,
 	Z80_LD_R_N:  &InstrDesc_LD_R_N,
 	Z80_LD_R_HL: &InstrDesc_LD_R_HL,
@@ -1589,22 +2451,36 @@ var Z80InstrDescriptors = map[Z80Opcode]*InstrDescriptor{
 	Z80_CP_HL:  &InstrDesc_CP_HL,
 
 	// Bitwise (CB prefix)
-	Z80_BIT_B_R: &InstrDesc_BIT_B_R,
-	Z80_SET_B_R: &InstrDesc_SET_B_R,
-	Z80_RES_B_R: &InstrDesc_RES_B_R,
+	Z80_BIT_B_R:  &InstrDesc_BIT_B_R,
+	Z80_BIT_B_HL: &InstrDesc_BIT_B_HL,
+	Z80_SET_B_R:  &InstrDesc_SET_B_R,
+	Z80_RES_B_R:  &InstrDesc_RES_B_R,
+	Z80_SET_B_HL: &InstrDesc_SET_B_HL,
+	Z80_RES_B_HL: &InstrDesc_RES_B_HL,
 
 	// Rotate/Shift (CB prefix)
-	Z80_RLC_R: &InstrDesc_RLC_R,
-	Z80_RRC_R: &InstrDesc_RRC_R,
-	Z80_RL_R:  &InstrDesc_RL_R,
-	Z80_RR_R:  &InstrDesc_RR_R,
-	Z80_SLA_R: &InstrDesc_SLA_R,
-	Z80_SRA_R: &InstrDesc_SRA_R,
-	Z80_SRL_R: &InstrDesc_SRL_R,
+	Z80_RLC_R:  &InstrDesc_RLC_R,
+	Z80_RRC_R:  &InstrDesc_RRC_R,
+	Z80_RL_R:   &InstrDesc_RL_R,
+	Z80_RR_R:   &InstrDesc_RR_R,
+	Z80_SLA_R:  &InstrDesc_SLA_R,
+	Z80_SRA_R:  &InstrDesc_SRA_R,
+	Z80_SRL_R:  &InstrDesc_SRL_R,
+	Z80_RLC_HL: &InstrDesc_RLC_HL,
+	Z80_RRC_HL: &InstrDesc_RRC_HL,
+	Z80_RL_HL:  &InstrDesc_RL_HL,
+	Z80_RR_HL:  &InstrDesc_RR_HL,
+	Z80_SLA_HL: &InstrDesc_SLA_HL,
+	Z80_SRA_HL: &InstrDesc_SRA_HL,
+	Z80_SRL_HL: &InstrDesc_SRL_HL,
 
 	// Stack
 	Z80_PUSH_QQ: &InstrDesc_PUSH_QQ,
 	Z80_POP_QQ:  &InstrDesc_POP_QQ,
+	Z80_PUSH_IX: &InstrDesc_PUSH_IX,
+	Z80_POP_IX:  &InstrDesc_POP_IX,
+	Z80_PUSH_IY: &InstrDesc_PUSH_IY,
+	Z80_POP_IY:  &InstrDesc_POP_IY,
 
 	// Jump/Branch
 	Z80_JP_NN:    &InstrDesc_JP_NN,
@@ -1631,5 +2507,43 @@ var Z80InstrDescriptors = map[Z80Opcode]*InstrDescriptor{
 	Z80_NOP:  &InstrDesc_NOP,
 	Z80_HALT: &InstrDesc_HALT,
 	Z80_NEG:  &InstrDesc_NEG,
+	Z80_SCF:  &InstrDesc_SCF,
 	Z80_CCF:  &InstrDesc_CCF,
+
+	// Exchange
+	Z80_EX_DE_HL: &InstrDesc_EX_DE_HL,
+	Z80_EX_AF_AF: &InstrDesc_EX_AF_AF,
+	Z80_EXX:      &InstrDesc_EXX,
+
+	// Block compare
+	Z80_CPIR: &InstrDesc_CPIR,
+	Z80_CPDR: &InstrDesc_CPDR,
+	Z80_LDIR: &InstrDesc_LDIR,
+	Z80_LDDR: &InstrDesc_LDDR,
+
+	// Pseudo
+	Z80_INLINE_ASM: &InstrDesc_INLINE_ASM,
+
+	// Undocumented
+	Z80_SLL_R:    &InstrDesc_SLL_R,
+	Z80_LD_XR_XR: &InstrDesc_LD_XR_XR,
+	Z80_LD_XR_N:  &InstrDesc_LD_XR_N,
+	Z80_ADD_A_XR: &InstrDesc_ADD_A_XR,
+	Z80_SUB_XR:   &InstrDesc_SUB_XR,
+	Z80_AND_XR:   &InstrDesc_AND_XR,
+	Z80_XOR_XR:   &InstrDesc_XOR_XR,
+	Z80_OR_XR:    &InstrDesc_OR_XR,
+	Z80_CP_XR:    &InstrDesc_CP_XR,
+	Z80_INC_XR:   &InstrDesc_INC_XR,
+	Z80_DEC_XR:   &InstrDesc_DEC_XR,
+	Z80_LD_YR_YR: &InstrDesc_LD_YR_YR,
+	Z80_LD_YR_N:  &InstrDesc_LD_YR_N,
+	Z80_ADD_A_YR: &InstrDesc_ADD_A_YR,
+	Z80_SUB_YR:   &InstrDesc_SUB_YR,
+	Z80_AND_YR:   &InstrDesc_AND_YR,
+	Z80_XOR_YR:   &InstrDesc_XOR_YR,
+	Z80_OR_YR:    &InstrDesc_OR_YR,
+	Z80_CP_YR:    &InstrDesc_CP_YR,
+	Z80_INC_YR:   &InstrDesc_INC_YR,
+	Z80_DEC_YR:   &InstrDesc_DEC_YR,
 }
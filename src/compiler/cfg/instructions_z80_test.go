@@ -0,0 +1,302 @@
+package cfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_InstrDescriptors_OpcodeMatchesKey(t *testing.T) {
+	for opcode, desc := range Z80InstrDescriptors {
+		assert.Equal(t, opcode, desc.Opcode, "descriptor key %s", opcode)
+	}
+}
+
+func Test_InstrDescriptors_SLL(t *testing.T) {
+	desc, ok := Z80InstrDescriptors[Z80_SLL_R]
+	require.True(t, ok)
+
+	assert.True(t, desc.Undocumented)
+	assert.Equal(t, "SLL", desc.Opcode.String())
+	assert.Equal(t, uint8(0xCB), desc.Prefix1)
+	assert.Equal(t, uint8(2), desc.Size)
+	assert.Equal(t, uint8(8), desc.Cycles)
+	assert.Equal(t, Z80InstrDescriptors[Z80_SLA_R].AffectedFlags, desc.AffectedFlags)
+}
+
+func Test_InstrDescriptors_IndexHalfRegisters(t *testing.T) {
+	tests := []struct {
+		opcode   Z80Opcode
+		name     string
+		prefix   uint8
+		size     uint8
+		register *Register
+	}{
+		{Z80_LD_XR_XR, "LD", 0xDD, 2, &RegIXH},
+		{Z80_LD_XR_N, "LD", 0xDD, 3, &RegIXL},
+		{Z80_ADD_A_XR, "ADD", 0xDD, 2, &RegIXH},
+		{Z80_CP_XR, "CP", 0xDD, 2, &RegIXL},
+		{Z80_INC_XR, "INC", 0xDD, 2, &RegIXH},
+		{Z80_LD_YR_YR, "LD", 0xFD, 2, &RegIYL},
+		{Z80_SUB_YR, "SUB", 0xFD, 2, &RegIYH},
+		{Z80_XOR_YR, "XOR", 0xFD, 2, &RegIYL},
+		{Z80_DEC_YR, "DEC", 0xFD, 2, &RegIYH},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.opcode.String(), func(t *testing.T) {
+			desc, ok := Z80InstrDescriptors[tt.opcode]
+			require.True(t, ok)
+
+			assert.True(t, desc.Undocumented)
+			assert.Equal(t, tt.name, desc.Opcode.String())
+			assert.Equal(t, tt.prefix, desc.Prefix1)
+			assert.Equal(t, tt.size, desc.Size)
+			assert.True(t, descriptorAllowsRegister(desc, tt.register))
+		})
+	}
+}
+
+func Test_InstrDescriptors_BlockCompare(t *testing.T) {
+	tests := []struct {
+		opcode   Z80Opcode
+		name     string
+		encoding []uint8
+	}{
+		{Z80_CPIR, "CPIR", []uint8{0xED, 0xB1}},
+		{Z80_CPDR, "CPDR", []uint8{0xED, 0xB9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desc, ok := Z80InstrDescriptors[tt.opcode]
+			require.True(t, ok)
+
+			assert.False(t, desc.Undocumented)
+			assert.Equal(t, tt.name, desc.Opcode.String())
+			assert.Equal(t, tt.encoding, []uint8{desc.Prefix1, uint8(desc.Opcode)})
+			assert.Equal(t, uint8(2), desc.Size)
+			assert.Equal(t, uint8(16), desc.Cycles)
+			assert.Equal(t, uint8(21), desc.Cycles+desc.CyclesTaken)
+			// the carry is not affected, Z is set when found
+			assert.Zero(t, desc.AffectedFlags&InstrFlagC)
+			assert.NotZero(t, desc.AffectedFlags&InstrFlagZ)
+			for _, register := range []*Register{&RegA, &RegHL, &RegBC} {
+				assert.True(t, descriptorAllowsRegister(desc, register), register.Name)
+			}
+		})
+	}
+}
+
+func Test_InstrDescriptors_BlockTransfer(t *testing.T) {
+	tests := []struct {
+		opcode   Z80Opcode
+		name     string
+		encoding []uint8
+	}{
+		{Z80_LDIR, "LDIR", []uint8{0xED, 0xB0}},
+		{Z80_LDDR, "LDDR", []uint8{0xED, 0xB8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desc, ok := Z80InstrDescriptors[tt.opcode]
+			require.True(t, ok)
+
+			assert.Equal(t, tt.name, desc.Opcode.String())
+			assert.Equal(t, tt.encoding, []uint8{desc.Prefix1, uint8(desc.Opcode)})
+			assert.Equal(t, uint8(21), desc.Cycles+desc.CyclesTaken)
+			// writes memory: never moved or removed
+			assert.Equal(t, CatStore, desc.Category)
+			assert.Zero(t, desc.AffectedFlags&(InstrFlagC|InstrFlagZ))
+			for _, register := range []*Register{&RegHL, &RegDE, &RegBC} {
+				assert.True(t, descriptorAllowsRegister(desc, register), register.Name)
+			}
+		})
+	}
+}
+
+func Test_InstrDescriptors_BitIndirectHL(t *testing.T) {
+	tests := []struct {
+		opcode   Z80Opcode
+		name     string
+		encoding []uint8 // SET/RES 2, (HL)
+	}{
+		{Z80_SET_B_HL, "SET", []uint8{0xCB, 0xD6}},
+		{Z80_RES_B_HL, "RES", []uint8{0xCB, 0x96}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desc, ok := Z80InstrDescriptors[tt.opcode]
+			require.True(t, ok)
+
+			assert.Equal(t, tt.name, desc.Opcode.String())
+			assert.Equal(t, tt.encoding, []uint8{desc.Prefix1, uint8(desc.Opcode) | 2<<desc.EncodingReg1SL})
+			assert.Equal(t, uint8(2), desc.Size)
+			assert.Equal(t, uint8(15), desc.Cycles)
+			// writes memory: never removed or moved
+			assert.Equal(t, AddrIndirect, desc.AddressingMode)
+			assert.Equal(t, InstrFlagNone, desc.AffectedFlags)
+			assert.True(t, descriptorAllowsRegister(desc, &RegHL))
+		})
+	}
+}
+
+func Test_InstrDescriptors_ShiftIndirectHL(t *testing.T) {
+	tests := []struct {
+		opcode   Z80Opcode
+		register Z80Opcode
+		name     string
+		encoding []uint8
+	}{
+		{Z80_RLC_HL, Z80_RLC_R, "RLC", []uint8{0xCB, 0x06}},
+		{Z80_RRC_HL, Z80_RRC_R, "RRC", []uint8{0xCB, 0x0E}},
+		{Z80_RL_HL, Z80_RL_R, "RL", []uint8{0xCB, 0x16}},
+		{Z80_RR_HL, Z80_RR_R, "RR", []uint8{0xCB, 0x1E}},
+		{Z80_SLA_HL, Z80_SLA_R, "SLA", []uint8{0xCB, 0x26}},
+		{Z80_SRA_HL, Z80_SRA_R, "SRA", []uint8{0xCB, 0x2E}},
+		{Z80_SRL_HL, Z80_SRL_R, "SRL", []uint8{0xCB, 0x3E}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desc, ok := Z80InstrDescriptors[tt.opcode]
+			require.True(t, ok)
+
+			assert.Equal(t, tt.name, desc.Opcode.String())
+			assert.Equal(t, tt.encoding, []uint8{desc.Prefix1, uint8(desc.Opcode)})
+			assert.Equal(t, uint8(2), desc.Size)
+			assert.Equal(t, uint8(15), desc.Cycles)
+			assert.Equal(t, AddrIndirect, desc.AddressingMode)
+			assert.True(t, descriptorAllowsRegister(desc, &RegHL))
+			// same flags as the register form
+			regDesc := Z80InstrDescriptors[tt.register]
+			assert.Equal(t, regDesc.AffectedFlags, desc.AffectedFlags)
+			assert.Equal(t, regDesc.DependentFlags, desc.DependentFlags)
+		})
+	}
+}
+
+func Test_InstrDescriptors_BitTestIndirectHL(t *testing.T) {
+	desc, ok := Z80InstrDescriptors[Z80_BIT_B_HL]
+	require.True(t, ok)
+
+	assert.Equal(t, "BIT", desc.Opcode.String())
+	// BIT 2, (HL)
+	assert.Equal(t, []uint8{0xCB, 0x56}, []uint8{desc.Prefix1, uint8(desc.Opcode) | 2<<desc.EncodingReg1SL})
+	assert.Equal(t, uint8(2), desc.Size)
+	assert.Equal(t, uint8(12), desc.Cycles)
+	assert.Equal(t, Z80InstrDescriptors[Z80_BIT_B_R].AffectedFlags, desc.AffectedFlags)
+}
+
+func Test_InstrDescriptors_DocumentedNotFlagged(t *testing.T) {
+	for _, opcode := range []Z80Opcode{Z80_LD_R_R, Z80_SLA_R, Z80_ADD_A_R, Z80_EXX} {
+		assert.False(t, Z80InstrDescriptors[opcode].Undocumented, opcode.String())
+	}
+}
+
+// Test the size of each descriptor counts the prefix, the opcode and the operand bytes
+func Test_InstrDescriptors_SizeMatchesEncoding(t *testing.T) {
+	for opcode, desc := range Z80InstrDescriptors {
+		if opcode == Z80_INLINE_ASM {
+			continue
+		}
+		size := 1
+		switch opcode >> 8 {
+		case 0xCB, 0xDD, 0xED, 0xFD:
+			size++
+		}
+		for _, dep := range desc.Dependencies {
+			switch dep.Type {
+			case OpConstant8, OpRelExpression, OpDisplacement:
+				size++
+			case OpConstant16:
+				size += 2
+			}
+		}
+		assert.Equal(t, uint8(size), desc.Size, "size of %s (0x%04X)", opcode, uint16(opcode))
+	}
+}
+
+func Test_MachineInstruction_EncodedSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		instr    *machineInstructionZ80
+		expected int
+		known    bool
+	}{
+		{"LD A, n", newInstruction0(Z80_LD_R_N), 2, true},
+		{"LD HL, nn", newInstruction0(Z80_LD_RR_NN), 3, true},
+		{"NEG (ED prefix)", newInstruction0(Z80_NEG), 2, true},
+		{"LD rr, (nn) (ED prefix)", newInstruction0(Z80_LD_RR_NN_ADDR), 4, true},
+		{"JR e", newInstruction0(Z80_JR_E), 2, true},
+		{"inline assembly", newInlineAsm("halt"), 0, false},
+		{"no descriptor", newInstruction0(Z80Opcode(0xFFFF)), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, known := tt.instr.EncodedSize()
+			assert.Equal(t, tt.expected, size)
+			assert.Equal(t, tt.known, known)
+		})
+	}
+}
+
+// Helper to check if any register dependency of the descriptor allows the register
+func descriptorAllowsRegister(desc *InstrDescriptor, register *Register) bool {
+	for _, dep := range desc.Dependencies {
+		for _, reg := range dep.Registers {
+			if reg == register {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func Test_ConditionCode_OverflowEncoding(t *testing.T) {
+	desc := Z80InstrDescriptors[Z80_JP_CC_NN]
+
+	encode := func(cc ConditionCode) uint8 {
+		return uint8(desc.Opcode) | cc.Encoding()<<desc.EncodingReg1SL
+	}
+
+	assert.Equal(t, uint8(0xE2), encode(Cond_PO), "JP PO, nn")
+	assert.Equal(t, uint8(0xEA), encode(Cond_PE), "JP PE, nn")
+	assert.Equal(t, uint8(0xC2), encode(Cond_NZ), "JP NZ, nn")
+	assert.Equal(t, uint8(0xFA), encode(Cond_M), "JP M, nn")
+	assert.Equal(t, InstrFlagPV, GetFlagsForCondition(Cond_PE))
+	assert.Equal(t, InstrFlagPV, GetFlagsForCondition(Cond_PO))
+}
+
+func Test_InstrDescriptors_PushPopIndexRegisters(t *testing.T) {
+	tests := []struct {
+		opcode   Z80Opcode
+		name     string
+		encoding []uint8
+		cycles   uint8
+		register *Register
+	}{
+		{Z80_PUSH_IX, "PUSH", []uint8{0xDD, 0xE5}, 15, &RegIX},
+		{Z80_POP_IX, "POP", []uint8{0xDD, 0xE1}, 14, &RegIX},
+		{Z80_PUSH_IY, "PUSH", []uint8{0xFD, 0xE5}, 15, &RegIY},
+		{Z80_POP_IY, "POP", []uint8{0xFD, 0xE1}, 14, &RegIY},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.opcode.String(), func(t *testing.T) {
+			desc, ok := Z80InstrDescriptors[tt.opcode]
+			require.True(t, ok)
+
+			assert.False(t, desc.Undocumented)
+			assert.Equal(t, tt.name, desc.Opcode.String())
+			assert.Equal(t, tt.encoding, []uint8{desc.Prefix1, uint8(desc.Opcode)})
+			assert.Equal(t, uint8(len(tt.encoding)), desc.Size)
+			assert.Equal(t, tt.cycles, desc.Cycles)
+			assert.True(t, descriptorAllowsRegister(desc, tt.register))
+		})
+	}
+}
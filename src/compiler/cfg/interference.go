@@ -2,6 +2,7 @@ package cfg
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 )
@@ -13,6 +14,8 @@ type InterferenceGraph struct {
 	edges map[int]map[int]bool
 	// All VirtualRegister IDs in the graph
 	nodes map[int]bool
+	// Physical registers changed by calls while the VirtualRegister is live: VR ID -> registers
+	clobbers map[int][]*Register
 	// Instruction-level liveness: map[blockID][instrIdx] -> set of live VR IDs
 	// Computed during BuildInterferenceGraph and reused by ResolveUnallocated
 	InstructionLiveness map[int][]map[int]bool
@@ -23,6 +26,7 @@ func NewInterferenceGraph() *InterferenceGraph {
 	return &InterferenceGraph{
 		edges:               make(map[int]map[int]bool),
 		nodes:               make(map[int]bool),
+		clobbers:            make(map[int][]*Register),
 		InstructionLiveness: make(map[int][]map[int]bool),
 	}
 }
@@ -126,6 +130,21 @@ func (ig *InterferenceGraph) allComponentsOf(components, composites []*Register)
 	return len(components) > 0 // Must have at least one component
 }
 
+// AddClobbers records physical registers the VirtualRegister cannot be allocated to,
+// because a call changes them while the VirtualRegister is live
+func (ig *InterferenceGraph) AddClobbers(vrID int, regs []*Register) {
+	for _, reg := range regs {
+		if !slices.Contains(ig.clobbers[vrID], reg) {
+			ig.clobbers[vrID] = append(ig.clobbers[vrID], reg)
+		}
+	}
+}
+
+// GetClobbers returns the physical registers changed by calls while the VirtualRegister is live
+func (ig *InterferenceGraph) GetClobbers(vrID int) []*Register {
+	return ig.clobbers[vrID]
+}
+
 // Interferes returns true if two VirtualRegisters interfere with each other
 func (ig *InterferenceGraph) Interferes(vr1, vr2 int) bool {
 	if neighbors, exists := ig.edges[vr1]; exists {
@@ -164,6 +183,12 @@ func (ig *InterferenceGraph) GetNodes() []int {
 	return nodes
 }
 
+// isRegisterRead checks if the VirtualRegister reads a physical register as it is (LD A, B after LD BC, nn):
+// bound to one register, never defined and not a (named) parameter. A call before the read does not clobber it.
+func isRegisterRead(vr *VirtualRegister, defined map[int]bool) bool {
+	return vr != nil && len(vr.AllowedSet) == 1 && vr.Name == "" && !defined[vr.ID]
+}
+
 // BuildInterferenceGraph constructs an interference graph from liveness information
 // Two VirtualRegisters interfere if they are both live at the same point in the program
 // Uses instruction-level liveness for precision and considers register composition
@@ -172,10 +197,12 @@ func BuildInterferenceGraph(cfg *CFG, liveness *LivenessInfo) *InterferenceGraph
 
 	// Build a map of VR ID to VR object for composition checking
 	vrMap := make(map[int]*VirtualRegister)
+	defined := make(map[int]bool)
 	for _, block := range cfg.Blocks {
 		for _, instr := range block.MachineInstructions {
 			if result := instr.GetResult(); result != nil {
 				vrMap[result.ID] = result
+				defined[result.ID] = true
 			}
 			for _, operand := range instr.GetOperands() {
 				if operand != nil {
@@ -212,6 +239,18 @@ func BuildInterferenceGraph(cfg *CFG, liveness *LivenessInfo) *InterferenceGraph
 			result := instr.GetResult()
 			operands := instr.GetOperands()
 
+			// VRs live across a call cannot use the registers the callee changes
+			if clobbers := instr.GetClobbers(); len(clobbers) > 0 {
+				for liveVRID := range currentlyLive {
+					if isRegisterRead(vrMap[liveVRID], defined) {
+						continue
+					}
+					if result == nil || liveVRID != result.ID {
+						ig.AddClobbers(liveVRID, clobbers)
+					}
+				}
+			}
+
 			// The defined VR interferes with all VRs that are live AFTER this instruction
 			// (i.e., those in currentlyLive before we remove the result)
 			if result != nil && shouldTrackForLiveness(result) {
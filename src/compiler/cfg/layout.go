@@ -0,0 +1,384 @@
+package cfg
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// addressSpaceSize is the size of the Z80 (16-bit) address space
+const addressSpaceSize = 0x10000
+
+// FunctionLayout is the placement of a function's code in the address space
+type FunctionLayout struct {
+	CFG     *CFG
+	Address uint16
+	Size    int // in bytes, from the instruction descriptors
+	// Unsized is set when the code has instructions of unknown size (inline assembly):
+	// the Size only counts the other instructions
+	Unsized bool
+}
+
+// End returns the first address after the function
+func (l *FunctionLayout) End() int {
+	return int(l.Address) + l.Size
+}
+
+// codeBlocks returns the blocks in code order:
+// the entry block first and the exit block (epilogue + RET) last
+func (cfg *CFG) codeBlocks() []*BasicBlock {
+	var blocks []*BasicBlock
+	if cfg.Entry != nil {
+		blocks = append(blocks, cfg.Entry)
+	}
+	for _, block := range cfg.Blocks {
+		if block != cfg.Entry && block != cfg.Exit {
+			blocks = append(blocks, block)
+		}
+	}
+	if cfg.Exit != nil {
+		blocks = append(blocks, cfg.Exit)
+	}
+	return blocks
+}
+
+// CodeInstructions returns the machine instructions in code order (as they are laid out)
+func (cfg *CFG) CodeInstructions() []MachineInstruction {
+	var instructions []MachineInstruction
+	for _, block := range cfg.codeBlocks() {
+		instructions = append(instructions, block.MachineInstructions...)
+	}
+	return instructions
+}
+
+// CodeSize returns the size of the function's machine code in bytes.
+// Returns false when the size is unknown: inline assembly is not assembled and is not counted.
+func (cfg *CFG) CodeSize() (int, bool) {
+	size := 0
+	known := true
+	for _, instr := range cfg.CodeInstructions() {
+		instrSize, ok := encodedSize(instr)
+		size += instrSize
+		known = known && ok
+	}
+	return size, known
+}
+
+// encodedSize returns the size of the instruction in bytes, false when the size is unknown (inline assembly)
+func encodedSize(instr MachineInstruction) (int, bool) {
+	if z80Instr, ok := instr.(*machineInstructionZ80); ok {
+		return z80Instr.EncodedSize()
+	}
+	return 0, false
+}
+
+// RelaxJumps replaces the jumps to a block within reach of a relative jump by the shorter JR:
+// JP by JR and JP cc by JR cc for the conditions JR supports (NZ, Z, NC, C).
+// A shorter jump only brings other targets closer: after each replacement the jumps are checked again.
+// Returns the number of jumps replaced.
+func (cfg *CFG) RelaxJumps() int {
+	relaxed := 0
+	for cfg.relaxJump() {
+		relaxed++
+	}
+	return relaxed
+}
+
+// relaxJump replaces the first jump that can be relative, returns false when there is none.
+// A jump over code of unknown size (inline assembly) stays absolute.
+func (cfg *CFG) relaxJump() bool {
+	offsets := cfg.blockOffsets()
+	starts := cfg.blockStarts()
+	instructions := cfg.CodeInstructions()
+	offset := 0
+	for i, instr := range instructions {
+		if jump, ok := instr.(*machineInstructionZ80); ok && isRelaxable(jump, offsets) {
+			target := jump.branchTargets[0]
+			// the displacement is relative to the end of the (2-byte) JR
+			displacement := offsets[target] - (offset + 2)
+			if displacement >= -128 && displacement <= 127 && !hasUnsized(instructions, i, starts[target]) {
+				if jump.opcode == Z80_JP_NN {
+					jump.opcode = Z80_JR_E
+				} else {
+					jump.opcode = Z80_JR_CC_E
+				}
+				return true
+			}
+		}
+		size, _ := encodedSize(instr)
+		offset += size
+	}
+	return false
+}
+
+// isRelaxable checks if the instruction is a jump to a block of the function that has a relative form
+func isRelaxable(jump *machineInstructionZ80, offsets map[*BasicBlock]int) bool {
+	if len(jump.branchTargets) == 0 || jump.branchTargets[0] == nil {
+		return false
+	}
+	if _, ok := offsets[jump.branchTargets[0]]; !ok {
+		return false
+	}
+	switch jump.opcode {
+	case Z80_JP_NN:
+		return true
+	case Z80_JP_CC_NN:
+		switch jump.conditionCode {
+		case Cond_NZ, Cond_Z, Cond_NC, Cond_C:
+			return true
+		}
+	}
+	return false
+}
+
+// hasUnsized checks if there is an instruction of unknown size in the code between a jump and its target
+// (indexes in the instructions in code order)
+func hasUnsized(instructions []MachineInstruction, jump, target int) bool {
+	for i := min(jump, target); i < max(jump, target); i++ {
+		if _, ok := encodedSize(instructions[i]); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// blockOffsets returns the offset (in bytes) of each block from the start of the function.
+// Code of unknown size (inline assembly) is not counted: only the distance between blocks
+// without such code in between is known (see hasUnsized).
+func (cfg *CFG) blockOffsets() map[*BasicBlock]int {
+	offsets := make(map[*BasicBlock]int, len(cfg.Blocks))
+	offset := 0
+	for _, block := range cfg.codeBlocks() {
+		offsets[block] = offset
+		for _, instr := range block.MachineInstructions {
+			size, _ := encodedSize(instr)
+			offset += size
+		}
+	}
+	return offsets
+}
+
+// blockStarts returns the index of the first instruction of each block in the instructions in code order
+func (cfg *CFG) blockStarts() map[*BasicBlock]int {
+	starts := make(map[*BasicBlock]int, len(cfg.Blocks))
+	index := 0
+	for _, block := range cfg.codeBlocks() {
+		starts[block] = index
+		index += len(block.MachineInstructions)
+	}
+	return starts
+}
+
+// LayoutFunctions assigns an address to each function.
+// Functions with an '@org(address)' are placed at that address,
+// the other functions are placed in order starting at origin, skipping over the fixed functions.
+// Returns the layouts ordered by address.
+func LayoutFunctions(cfgs []*CFG, origin uint16) ([]*FunctionLayout, error) {
+	var fixed, free []*FunctionLayout
+	for _, cfg := range cfgs {
+		size, known := cfg.CodeSize()
+		layout := &FunctionLayout{CFG: cfg, Size: size, Unsized: !known}
+		if cfg.FunctionDecl != nil && cfg.FunctionDecl.Org != nil {
+			layout.Address = *cfg.FunctionDecl.Org
+			fixed = append(fixed, layout)
+		} else {
+			free = append(free, layout)
+		}
+	}
+
+	sortByAddress(fixed)
+	for i := 1; i < len(fixed); i++ {
+		if fixed[i-1].End() > int(fixed[i].Address) {
+			return nil, fmt.Errorf("function '%s' at 0x%04X overlaps function '%s' at 0x%04X",
+				fixed[i].CFG.FunctionName, fixed[i].Address, fixed[i-1].CFG.FunctionName, fixed[i-1].Address)
+		}
+	}
+
+	cursor := int(origin)
+	for _, layout := range free {
+		// move past fixed functions until the function fits in the gap
+		for _, f := range fixed {
+			if cursor < f.End() && cursor+layout.Size > int(f.Address) {
+				cursor = f.End()
+			}
+		}
+		if cursor >= addressSpaceSize {
+			return nil, fmt.Errorf("function '%s' does not fit in the address space", layout.CFG.FunctionName)
+		}
+		layout.Address = uint16(cursor)
+		cursor += layout.Size
+	}
+
+	layouts := append(fixed, free...)
+	for _, layout := range layouts {
+		if layout.End() > addressSpaceSize {
+			return nil, fmt.Errorf("function '%s' at 0x%04X does not fit in the address space",
+				layout.CFG.FunctionName, layout.Address)
+		}
+	}
+	sortByAddress(layouts)
+	// the end of code of unknown size is not known: only a function at a fixed address can follow it
+	for i, layout := range layouts[:max(len(layouts)-1, 0)] {
+		next := layouts[i+1].CFG.FunctionDecl
+		if layout.Unsized && (next == nil || next.Org == nil) {
+			return nil, fmt.Errorf("function '%s' has inline assembly of unknown size and cannot be followed by function '%s'",
+				layout.CFG.FunctionName, layouts[i+1].CFG.FunctionName)
+		}
+	}
+	return layouts, nil
+}
+
+// Relocation is an absolute address in the code that refers to a label:
+// a loader that places the code at another address adds the difference to the 2 bytes.
+type Relocation struct {
+	// Address of the (little-endian) address bytes in the code
+	Address uint16
+	// Target is the label the address refers to: a function, a data label
+	// or the function itself for a jump within the function
+	Target string
+}
+
+// Relocations returns the absolute label references in the laid out code and data (position-independent code):
+// the jumps that are not relative, the calls and the loads of a label address (LD rr, label) in the code,
+// the function addresses ('@addressof') and the jump table entries in the data (placed after the code).
+// References to '@org' functions are not relocated, they stay at their fixed address.
+func Relocations(layouts []*FunctionLayout, data *DataSection) []Relocation {
+	fixed := make(map[string]bool)
+	for _, layout := range layouts {
+		if layout.CFG.FunctionDecl != nil && layout.CFG.FunctionDecl.Org != nil {
+			fixed[layout.CFG.FunctionName] = true
+		}
+	}
+
+	var relocations []Relocation
+	for _, layout := range layouts {
+		address := int(layout.Address)
+		for _, instr := range layout.CFG.CodeInstructions() {
+			size, _ := encodedSize(instr)
+			if size == 0 {
+				continue
+			}
+			if target, ok := labelReference(instr, layout.CFG.FunctionName); ok && !fixed[target] {
+				// the address is the last 2 bytes of the instruction
+				relocations = append(relocations, Relocation{Address: uint16(address + size - 2), Target: target})
+			}
+			address += size
+		}
+	}
+	if data != nil {
+		relocations = append(relocations, dataRelocations(layouts, data, fixed)...)
+	}
+	return relocations
+}
+
+// dataRelocations returns the absolute addresses in the data items, the data starts at the end of the code.
+// A jump table entry refers to the function of the block.
+func dataRelocations(layouts []*FunctionLayout, data *DataSection, fixed map[string]bool) []Relocation {
+	end := 0
+	blockFunctions := make(map[*BasicBlock]string)
+	for _, layout := range layouts {
+		end = max(end, layout.End())
+		for _, block := range layout.CFG.Blocks {
+			blockFunctions[block] = layout.CFG.FunctionName
+		}
+	}
+
+	var relocations []Relocation
+	address := end
+	for _, item := range data.emitOrder() {
+		for _, ref := range item.Addresses {
+			if !fixed[ref.Function] {
+				relocations = append(relocations, Relocation{Address: uint16(address + ref.Offset), Target: ref.Function})
+			}
+		}
+		if item.JumpTable != nil {
+			for i, target := range item.JumpTable.Targets {
+				if function := blockFunctions[target]; !fixed[function] {
+					relocations = append(relocations, Relocation{Address: uint16(address + 2*i), Target: function})
+				}
+			}
+		}
+		address += len(item.Bytes)
+	}
+	return relocations
+}
+
+// labelReference returns the label of an instruction with an absolute address operand
+func labelReference(instr MachineInstruction, functionName string) (string, bool) {
+	z80Instr, ok := instr.(*machineInstructionZ80)
+	if !ok {
+		return "", false
+	}
+	switch z80Instr.opcode {
+	case Z80_JP_NN, Z80_JP_CC_NN:
+		// a block of the function or a function (tail call)
+		if len(z80Instr.branchTargets) > 0 && z80Instr.branchTargets[0] != nil {
+			return functionName, true
+		}
+		return z80Instr.comment, z80Instr.comment != ""
+	case Z80_CALL_NN, Z80_CALL_CC_NN, Z80_LD_RR_NN:
+		// a constant LD rr, nn has no label
+		return z80Instr.comment, z80Instr.comment != ""
+	}
+	return "", false
+}
+
+// MemoryMap describes the memory of the target:
+// the code and the constant data go in ROM, the variables in RAM.
+// A zero size is not checked.
+type MemoryMap struct {
+	ROMStart uint16
+	ROMSize  int
+	RAMStart uint16
+	RAMSize  int
+}
+
+// Check reports the functions placed before ROM and the program or variables that do not fit.
+// The program runs from the ROM start to the end of the last function, followed by the constant data.
+func (m *MemoryMap) Check(layouts []*FunctionLayout, dataSize int, variablesSize int) []error {
+	var errs []error
+	end := int(m.ROMStart)
+	for _, layout := range layouts {
+		if m.ROMSize > 0 && layout.Address < m.ROMStart {
+			errs = append(errs, fmt.Errorf("function '%s' at 0x%04X is before ROM at 0x%04X",
+				layout.CFG.FunctionName, layout.Address, m.ROMStart))
+		}
+		end = max(end, layout.End())
+	}
+
+	programSize := end - int(m.ROMStart) + dataSize
+	if m.ROMSize > 0 && programSize > m.ROMSize {
+		errs = append(errs, fmt.Errorf("program is %d bytes, ROM is %d bytes", programSize, m.ROMSize))
+	}
+	if m.RAMSize > 0 && variablesSize > m.RAMSize {
+		errs = append(errs, fmt.Errorf("variables are %d bytes, RAM is %d bytes", variablesSize, m.RAMSize))
+	}
+	return errs
+}
+
+func sortByAddress(layouts []*FunctionLayout) {
+	sort.SliceStable(layouts, func(i, j int) bool {
+		return layouts[i].Address < layouts[j].Address
+	})
+}
+
+// WriteListing writes the functions with the address of each instruction
+//
+//	0038  onTimer:
+//	0038      PUSH AF
+func WriteListing(w io.Writer, layouts []*FunctionLayout) error {
+	var sb strings.Builder
+	for _, layout := range layouts {
+		address := int(layout.Address)
+		sb.WriteString(fmt.Sprintf("%04X  %s:\n", address, layout.CFG.FunctionName))
+		for _, instr := range layout.CFG.CodeInstructions() {
+			sb.WriteString(fmt.Sprintf("%04X      %s\n", address, strings.TrimSpace(instr.String())))
+			size, _ := encodedSize(instr)
+			address += size
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,309 @@
+package cfg
+
+import (
+	"strings"
+	"testing"
+	"zenith/compiler/zsm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLayoutCFG creates a function of size NOPs, placed at org when not nil
+func newLayoutCFG(name string, org *uint16, size int) *CFG {
+	block := newTestBlock()
+	for range size {
+		block.MachineInstructions = append(block.MachineInstructions, newInstruction0(Z80_NOP))
+	}
+	return &CFG{
+		Entry:        block,
+		Blocks:       []*BasicBlock{block},
+		FunctionName: name,
+		FunctionDecl: &zsm.SemFunctionDecl{Name: name, Org: org},
+	}
+}
+
+func orgAt(address uint16) *uint16 {
+	return &address
+}
+
+func Test_Layout_Sequential(t *testing.T) {
+	cfgs := []*CFG{
+		newLayoutCFG("first", nil, 3),
+		newLayoutCFG("second", nil, 2),
+	}
+
+	layouts, err := LayoutFunctions(cfgs, 0x8000)
+
+	require.NoError(t, err)
+	require.Len(t, layouts, 2)
+	assert.Equal(t, uint16(0x8000), layouts[0].Address)
+	assert.Equal(t, 3, layouts[0].Size)
+	assert.Equal(t, uint16(0x8003), layouts[1].Address)
+}
+
+func Test_Layout_Org(t *testing.T) {
+	cfgs := []*CFG{
+		newLayoutCFG("main", nil, 0x30),
+		newLayoutCFG("onTimer", orgAt(0x0038), 4),
+		newLayoutCFG("helper", nil, 0x10),
+	}
+
+	layouts, err := LayoutFunctions(cfgs, 0)
+
+	require.NoError(t, err)
+	names := make([]string, len(layouts))
+	for i, layout := range layouts {
+		names[i] = layout.CFG.FunctionName
+	}
+	// helper does not fit before onTimer and is placed after it
+	assert.Equal(t, []string{"main", "onTimer", "helper"}, names)
+	assert.Equal(t, uint16(0x0000), layouts[0].Address)
+	assert.Equal(t, uint16(0x0038), layouts[1].Address)
+	assert.Equal(t, uint16(0x003C), layouts[2].Address)
+}
+
+func Test_Layout_OrgOverlap_Error(t *testing.T) {
+	cfgs := []*CFG{
+		newLayoutCFG("rst38", orgAt(0x0038), 4),
+		newLayoutCFG("rst3A", orgAt(0x003A), 1),
+	}
+
+	_, err := LayoutFunctions(cfgs, 0)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "overlaps")
+}
+
+func Test_Layout_AddressSpaceOverflow_Error(t *testing.T) {
+	cfgs := []*CFG{
+		newLayoutCFG("top", orgAt(0xFFFE), 4),
+	}
+
+	_, err := LayoutFunctions(cfgs, 0)
+
+	assert.Error(t, err)
+}
+
+func Test_Layout_InlineAsm_Last(t *testing.T) {
+	asm := newLayoutCFG("asm", nil, 2)
+	asm.Entry.MachineInstructions = append(asm.Entry.MachineInstructions, newInlineAsm("halt"))
+
+	layouts, err := LayoutFunctions([]*CFG{newLayoutCFG("main", nil, 3), asm}, 0x8000)
+
+	require.NoError(t, err)
+	require.Len(t, layouts, 2)
+	assert.Equal(t, uint16(0x8003), layouts[1].Address)
+	assert.Equal(t, 2, layouts[1].Size)
+	assert.True(t, layouts[1].Unsized)
+	assert.False(t, layouts[0].Unsized)
+}
+
+func Test_Layout_InlineAsm_FollowedByOrg(t *testing.T) {
+	asm := newLayoutCFG("asm", nil, 2)
+	asm.Entry.MachineInstructions = append(asm.Entry.MachineInstructions, newInlineAsm("halt"))
+
+	layouts, err := LayoutFunctions([]*CFG{asm, newLayoutCFG("onTimer", orgAt(0x0038), 1)}, 0)
+
+	require.NoError(t, err)
+	require.Len(t, layouts, 2)
+}
+
+func Test_Layout_InlineAsm_Followed_Error(t *testing.T) {
+	asm := newLayoutCFG("asm", nil, 2)
+	asm.Entry.MachineInstructions = append(asm.Entry.MachineInstructions, newInlineAsm("halt"))
+
+	_, err := LayoutFunctions([]*CFG{asm, newLayoutCFG("main", nil, 3)}, 0x8000)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "function 'asm' has inline assembly of unknown size")
+}
+
+func Test_Layout_Listing(t *testing.T) {
+	layouts, err := LayoutFunctions([]*CFG{newLayoutCFG("onTimer", orgAt(0x0038), 2)}, 0)
+	require.NoError(t, err)
+
+	var sb strings.Builder
+	require.NoError(t, WriteListing(&sb, layouts))
+
+	expected := "0038  onTimer:\n" +
+		"0038      NOP\n" +
+		"0039      NOP\n"
+	assert.Equal(t, expected, sb.String())
+}
+
+// newLoopCFG creates a function with a loop of size NOPs closed by the jump back to the start of the loop
+func newLoopCFG(size int, jump func(loop, exit *BasicBlock) *machineInstructionZ80) (*CFG, *machineInstructionZ80) {
+	entry := &BasicBlock{ID: 0, MachineInstructions: []MachineInstruction{newInstruction0(Z80_NOP)}}
+	loop := &BasicBlock{ID: 1}
+	exit := &BasicBlock{ID: 2, MachineInstructions: []MachineInstruction{newInstruction0(Z80_RET)}}
+	for range size {
+		loop.MachineInstructions = append(loop.MachineInstructions, newInstruction0(Z80_NOP))
+	}
+	back := jump(loop, exit)
+	loop.MachineInstructions = append(loop.MachineInstructions, back)
+	return &CFG{Entry: entry, Exit: exit, Blocks: []*BasicBlock{entry, loop, exit}, FunctionName: "loop"}, back
+}
+
+func Test_RelaxJumps_ShortLoop(t *testing.T) {
+	tests := []struct {
+		name     string
+		jump     func(loop, exit *BasicBlock) *machineInstructionZ80
+		expected Z80Opcode
+	}{
+		{"JP", func(loop, exit *BasicBlock) *machineInstructionZ80 { return newJump(Z80_JP_NN, loop) }, Z80_JR_E},
+		{"JP NZ", func(loop, exit *BasicBlock) *machineInstructionZ80 {
+			return newJumpWithCondition(Cond_NZ, loop, exit)
+		}, Z80_JR_CC_E},
+		{"JP C", func(loop, exit *BasicBlock) *machineInstructionZ80 {
+			return newJumpWithCondition(Cond_C, loop, exit)
+		}, Z80_JR_CC_E},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fnCFG, back := newLoopCFG(4, tt.jump)
+			sizeBefore, _ := fnCFG.CodeSize()
+
+			assert.Equal(t, 1, fnCFG.RelaxJumps())
+			assert.Equal(t, tt.expected, back.opcode)
+			sizeAfter, known := fnCFG.CodeSize()
+			assert.True(t, known)
+			assert.Equal(t, sizeBefore-1, sizeAfter)
+		})
+	}
+}
+
+func Test_RelaxJumps_FarJump(t *testing.T) {
+	// the JR reaches back 128 bytes from its end: 126 NOPs + the JR itself
+	fnCFG, back := newLoopCFG(126, func(loop, exit *BasicBlock) *machineInstructionZ80 { return newJump(Z80_JP_NN, loop) })
+	assert.Equal(t, 1, fnCFG.RelaxJumps())
+	assert.Equal(t, Z80_JR_E, back.opcode)
+
+	fnCFG, back = newLoopCFG(127, func(loop, exit *BasicBlock) *machineInstructionZ80 { return newJump(Z80_JP_NN, loop) })
+	assert.Equal(t, 0, fnCFG.RelaxJumps())
+	assert.Equal(t, Z80_JP_NN, back.opcode)
+}
+
+func Test_RelaxJumps_ConditionWithoutJR(t *testing.T) {
+	// JR has no parity or sign conditions
+	fnCFG, back := newLoopCFG(4, func(loop, exit *BasicBlock) *machineInstructionZ80 {
+		return newJumpWithCondition(Cond_PE, loop, exit)
+	})
+
+	assert.Equal(t, 0, fnCFG.RelaxJumps())
+	assert.Equal(t, Z80_JP_CC_NN, back.opcode)
+}
+
+func Test_RelaxJumps_OverInlineAsm(t *testing.T) {
+	// the size of the inline assembly is unknown: the jump may be out of reach
+	fnCFG, back := newLoopCFG(4, func(loop, exit *BasicBlock) *machineInstructionZ80 { return newJump(Z80_JP_NN, loop) })
+	loop := fnCFG.Blocks[1]
+	loop.MachineInstructions = append([]MachineInstruction{newInlineAsm(".ds 200")}, loop.MachineInstructions...)
+
+	assert.Equal(t, 0, fnCFG.RelaxJumps())
+	assert.Equal(t, Z80_JP_NN, back.opcode)
+
+	// code of unknown size outside the jump does not matter
+	fnCFG, back = newLoopCFG(4, func(loop, exit *BasicBlock) *machineInstructionZ80 { return newJump(Z80_JP_NN, loop) })
+	fnCFG.Entry.MachineInstructions = append(fnCFG.Entry.MachineInstructions, newInlineAsm(".ds 200"))
+
+	assert.Equal(t, 1, fnCFG.RelaxJumps())
+	assert.Equal(t, Z80_JR_E, back.opcode)
+}
+
+func Test_Relocations(t *testing.T) {
+	loop := &BasicBlock{ID: 1, MachineInstructions: []MachineInstruction{newInstruction0(Z80_NOP)}}
+	entry := &BasicBlock{ID: 0, MachineInstructions: []MachineInstruction{
+		newLoadAddress(nil, "data.0"),           // 0x8000 LD rr, label
+		newInstruction0(Z80_LD_RR_NN),           // 0x8003 LD rr, constant
+		newCall("helper"),                       // 0x8006 CALL helper
+		newCall("onTimer"),                      // 0x8009 CALL fixed function
+		newJump(Z80_JP_NN, loop),                // 0x800C JP block
+		newJump(Z80_JR_E, loop),                 // 0x800F JR block
+		newJumpWithCondition(Cond_Z, loop, nil), // 0x8011 JP Z, block
+	}}
+	main := &CFG{Entry: entry, Blocks: []*BasicBlock{entry, loop}, FunctionName: "main",
+		FunctionDecl: &zsm.SemFunctionDecl{Name: "main"}}
+	cfgs := []*CFG{main, newLayoutCFG("helper", nil, 1), newLayoutCFG("onTimer", orgAt(0x0038), 1)}
+
+	layouts, err := LayoutFunctions(cfgs, 0x8000)
+	require.NoError(t, err)
+
+	expected := []Relocation{
+		{Address: 0x8001, Target: "data.0"},
+		{Address: 0x8007, Target: "helper"},
+		{Address: 0x800D, Target: "main"},
+		{Address: 0x8012, Target: "main"},
+	}
+	assert.Equal(t, expected, Relocations(layouts, nil))
+}
+
+func Test_Relocations_Data(t *testing.T) {
+	main := newLayoutCFG("main", nil, 3)
+	helper := newLayoutCFG("helper", nil, 1)
+	cfgs := []*CFG{main, helper, newLayoutCFG("onTimer", orgAt(0x0038), 1)}
+	data := NewDataSection()
+	data.Add([]byte{1, 2, 3})
+	data.Items = append(data.Items, &DataItem{Label: "table", Bytes: make([]byte, 4),
+		Addresses: []DataAddress{{Offset: 0, Function: "helper"}, {Offset: 2, Function: "onTimer"}}})
+	data.AddJumpTable(&JumpTable{Label: "main.jumptable.0", Targets: []*BasicBlock{main.Entry, helper.Entry}})
+
+	layouts, err := LayoutFunctions(cfgs, 0x8000)
+	require.NoError(t, err)
+
+	// the data follows the code (0x8004): the table at 0x8007, the jump table at 0x800B
+	expected := []Relocation{
+		{Address: 0x8007, Target: "helper"},
+		{Address: 0x800B, Target: "main"},
+		{Address: 0x800D, Target: "helper"},
+	}
+	assert.Equal(t, expected, Relocations(layouts, data))
+}
+
+func Test_MemoryMap_Fits(t *testing.T) {
+	memory := MemoryMap{ROMStart: 0x0000, ROMSize: 1024, RAMStart: 0x8000, RAMSize: 256}
+	layouts, err := LayoutFunctions([]*CFG{newLayoutCFG("main", nil, 1000)}, memory.ROMStart)
+	require.NoError(t, err)
+
+	errs := memory.Check(layouts, 24, 256)
+
+	assert.Empty(t, errs)
+}
+
+func Test_MemoryMap_ROMOverBudget_Error(t *testing.T) {
+	memory := MemoryMap{ROMStart: 0x0000, ROMSize: 1024}
+	layouts, err := LayoutFunctions([]*CFG{
+		newLayoutCFG("main", nil, 1000),
+		newLayoutCFG("helper", nil, 150),
+	}, memory.ROMStart)
+	require.NoError(t, err)
+
+	errs := memory.Check(layouts, 50, 0)
+
+	require.Len(t, errs, 1)
+	assert.Equal(t, "program is 1200 bytes, ROM is 1024 bytes", errs[0].Error())
+}
+
+func Test_MemoryMap_RAMOverBudget_Error(t *testing.T) {
+	memory := MemoryMap{RAMStart: 0x8000, RAMSize: 16}
+
+	errs := memory.Check(nil, 0, 20)
+
+	require.Len(t, errs, 1)
+	assert.Equal(t, "variables are 20 bytes, RAM is 16 bytes", errs[0].Error())
+}
+
+func Test_MemoryMap_OrgBeforeROM_Error(t *testing.T) {
+	memory := MemoryMap{ROMStart: 0x0100, ROMSize: 0x100}
+	layouts, err := LayoutFunctions([]*CFG{
+		newLayoutCFG("main", nil, 4),
+		newLayoutCFG("onTimer", orgAt(0x0038), 4),
+	}, memory.ROMStart)
+	require.NoError(t, err)
+
+	errs := memory.Check(layouts, 0, 0)
+
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "function 'onTimer' at 0x0038 is before ROM at 0x0100")
+}
@@ -0,0 +1,172 @@
+package cfg
+
+import "sort"
+
+// Loop represents a natural loop in the CFG
+type Loop struct {
+	// Header is the single entry block of the loop (target of the back edges)
+	Header *BasicBlock
+	// Blocks contains all blocks of the loop, including the header (block ID -> block)
+	Blocks map[int]*BasicBlock
+	// Latches are the sources of the back edges (latch -> header)
+	Latches []*BasicBlock
+}
+
+// Contains checks if the block is part of the loop
+func (l *Loop) Contains(block *BasicBlock) bool {
+	_, ok := l.Blocks[block.ID]
+	return ok
+}
+
+// IsBackEdge checks if the edge from -> to is a back edge of this loop
+func (l *Loop) IsBackEdge(from, to *BasicBlock) bool {
+	if to != l.Header {
+		return false
+	}
+	for _, latch := range l.Latches {
+		if latch == from {
+			return true
+		}
+	}
+	return false
+}
+
+// ComputeDominators computes the dominator set of each block reachable from the entry
+// Returns block ID -> set of block IDs that dominate it (a block dominates itself)
+func ComputeDominators(cfg *CFG) map[int]map[int]bool {
+	dominators := make(map[int]map[int]bool)
+	if cfg.Entry == nil {
+		return dominators
+	}
+
+	reachable := reachableBlocks(cfg.Entry)
+
+	// initialize: entry dominates itself, all other blocks start with all blocks
+	for _, block := range reachable {
+		dom := make(map[int]bool)
+		if block == cfg.Entry {
+			dom[block.ID] = true
+		} else {
+			for _, b := range reachable {
+				dom[b.ID] = true
+			}
+		}
+		dominators[block.ID] = dom
+	}
+
+	// iterate until the sets converge: dom(b) = {b} ∪ ∩ dom(pred)
+	changed := true
+	for changed {
+		changed = false
+		for _, block := range reachable {
+			if block == cfg.Entry {
+				continue
+			}
+
+			var newDom map[int]bool
+			for _, pred := range block.Predecessors {
+				predDom, ok := dominators[pred.ID]
+				if !ok {
+					continue // unreachable predecessor
+				}
+				if newDom == nil {
+					newDom = make(map[int]bool, len(predDom))
+					for id := range predDom {
+						newDom[id] = true
+					}
+					continue
+				}
+				for id := range newDom {
+					if !predDom[id] {
+						delete(newDom, id)
+					}
+				}
+			}
+			if newDom == nil {
+				newDom = make(map[int]bool)
+			}
+			newDom[block.ID] = true
+
+			if !setsEqualInt(dominators[block.ID], newDom) {
+				dominators[block.ID] = newDom
+				changed = true
+			}
+		}
+	}
+
+	return dominators
+}
+
+// FindLoops identifies the natural loops of the CFG
+// A back edge is an edge whose target dominates its source.
+// Back edges to the same header are merged into one loop.
+// Loops are returned ordered by header block ID.
+func (cfg *CFG) FindLoops() []*Loop {
+	dominators := ComputeDominators(cfg)
+	loops := make(map[int]*Loop)
+
+	for _, block := range cfg.Blocks {
+		dom, ok := dominators[block.ID]
+		if !ok {
+			continue // unreachable
+		}
+		for _, succ := range block.Successors {
+			if !dom[succ.ID] {
+				continue
+			}
+
+			loop, exists := loops[succ.ID]
+			if !exists {
+				loop = &Loop{
+					Header: succ,
+					Blocks: map[int]*BasicBlock{succ.ID: succ},
+				}
+				loops[succ.ID] = loop
+			}
+			loop.Latches = append(loop.Latches, block)
+			collectLoopBody(loop, block)
+		}
+	}
+
+	result := make([]*Loop, 0, len(loops))
+	for _, loop := range loops {
+		result = append(result, loop)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Header.ID < result[j].Header.ID
+	})
+	return result
+}
+
+// collectLoopBody adds all blocks that reach the latch without passing the header
+func collectLoopBody(loop *Loop, latch *BasicBlock) {
+	worklist := []*BasicBlock{latch}
+	for len(worklist) > 0 {
+		block := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+
+		if loop.Contains(block) {
+			continue
+		}
+		loop.Blocks[block.ID] = block
+		worklist = append(worklist, block.Predecessors...)
+	}
+}
+
+// reachableBlocks returns the blocks reachable from the entry in depth-first order
+func reachableBlocks(entry *BasicBlock) []*BasicBlock {
+	visited := make(map[*BasicBlock]bool)
+	var order []*BasicBlock
+	var visit func(block *BasicBlock)
+	visit = func(block *BasicBlock) {
+		visited[block] = true
+		order = append(order, block)
+		for _, succ := range block.Successors {
+			if !visited[succ] {
+				visit(succ)
+			}
+		}
+	}
+	visit(entry)
+	return order
+}
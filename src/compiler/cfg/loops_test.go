@@ -0,0 +1,102 @@
+package cfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ComputeDominators_IfElse(t *testing.T) {
+	code := `main: (x: u8) {
+		if x > 10 {
+			x = 1
+		} else {
+			x = 2
+		}
+	}`
+	cfg := buildCFGFromCode(t, code)
+	fnBlock := findBlockByLabel(cfg, LabelFunction)
+	thenBlock := findBlockByLabel(cfg, LabelIfThen)
+	elseBlock := findBlockByLabel(cfg, LabelIfElse)
+	mergeBlock := findBlockByLabel(cfg, LabelIfMerge)
+
+	dominators := ComputeDominators(cfg)
+
+	// entry dominates everything reachable
+	for blockID := range dominators {
+		assert.True(t, dominators[blockID][cfg.Entry.ID])
+	}
+	// merge is dominated by the condition but not by either branch
+	assert.True(t, dominators[mergeBlock.ID][fnBlock.ID])
+	assert.False(t, dominators[mergeBlock.ID][thenBlock.ID])
+	assert.False(t, dominators[mergeBlock.ID][elseBlock.ID])
+	assert.True(t, dominators[thenBlock.ID][thenBlock.ID])
+
+	assert.Empty(t, cfg.FindLoops())
+}
+
+func Test_CFG_FindLoops_ForLoop(t *testing.T) {
+	code := `main: () {
+		for i: = 0; i < 10; i + 1 {
+			if i < 5 {
+				i = 6
+			}
+		}
+	}`
+	cfg := buildCFGFromCode(t, code)
+	condBlock := findBlockByLabel(cfg, LabelForCond)
+	bodyBlock := findBlockByLabel(cfg, LabelForBody)
+	incBlock := findBlockByLabel(cfg, LabelForInc)
+	exitBlock := findBlockByLabel(cfg, LabelForExit)
+	thenBlock := findBlockByLabel(cfg, LabelIfThen)
+	mergeBlock := findBlockByLabel(cfg, LabelIfMerge)
+
+	loops := cfg.FindLoops()
+
+	require.Len(t, loops, 1)
+	loop := loops[0]
+	assert.Equal(t, condBlock, loop.Header)
+	assert.Equal(t, []*BasicBlock{incBlock}, loop.Latches)
+	assert.True(t, loop.IsBackEdge(incBlock, condBlock))
+	assert.False(t, loop.IsBackEdge(condBlock, bodyBlock))
+
+	assert.ElementsMatch(t,
+		[]int{condBlock.ID, bodyBlock.ID, thenBlock.ID, mergeBlock.ID, incBlock.ID},
+		keysOf(loop.Blocks))
+	assert.False(t, loop.Contains(exitBlock))
+	assert.False(t, loop.Contains(cfg.Entry))
+}
+
+func Test_CFG_FindLoops_Nested(t *testing.T) {
+	code := `main: () {
+		for i: = 0; i < 10; i + 1 {
+			for j: = 0; j < 10; j + 1 {
+			}
+		}
+	}`
+	cfg := buildCFGFromCode(t, code)
+
+	loops := cfg.FindLoops()
+
+	require.Len(t, loops, 2)
+	outer, inner := loops[0], loops[1]
+	assert.Equal(t, LabelForCond, outer.Header.Label)
+	assert.Equal(t, LabelForCond, inner.Header.Label)
+
+	// the inner loop is completely part of the outer loop
+	assert.Less(t, len(inner.Blocks), len(outer.Blocks))
+	for _, block := range inner.Blocks {
+		assert.True(t, outer.Contains(block))
+	}
+	assert.False(t, inner.Contains(outer.Header))
+}
+
+// Helper to get the (block) IDs of a map
+func keysOf(blocks map[int]*BasicBlock) []int {
+	ids := make([]int, 0, len(blocks))
+	for id := range blocks {
+		ids = append(ids, id)
+	}
+	return ids
+}
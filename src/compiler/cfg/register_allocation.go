@@ -192,7 +192,7 @@ func (ra *RegisterAllocator) ResolveUnallocated(cfg *CFG, ig *InterferenceGraph,
 				// Pick a register from AllowedSet - these are the ONLY valid registers for this operand
 				// The instruction selector already determined these constraints
 				liveAtInstr := instrLiveness[blockID][instrIdx]
-				targetReg := ra.pickRegisterFromAllowedSetAtPoint(operand, liveAtInstr, cfg)
+				targetReg := ra.pickRegisterFromAllowedSetAtPoint(operand, liveAtInstr, ig.GetClobbers(operand.ID), cfg)
 				if targetReg == nil {
 					// No register from AllowedSet is available - must spill to stack
 					operand.Type = StackLocation
@@ -246,11 +246,17 @@ func (ra *RegisterAllocator) ResolveUnallocated(cfg *CFG, ig *InterferenceGraph,
 
 // pickRegisterFromAllowedSetAtPoint selects a register from AllowedSet that's not used by live VRs
 // Uses precise per-instruction liveness rather than block-level liveness
-func (ra *RegisterAllocator) pickRegisterFromAllowedSetAtPoint(vr *VirtualRegister, liveVRs map[int]bool, cfg *CFG) *Register {
+// Registers changed by calls while the VR is live (clobbers) are never picked
+func (ra *RegisterAllocator) pickRegisterFromAllowedSetAtPoint(vr *VirtualRegister, liveVRs map[int]bool, clobbers []*Register, cfg *CFG) *Register {
+	clobbered := make(map[*Register]bool)
+	for _, reg := range clobbers {
+		markRegisterAsUsed(reg, clobbered, ra.availableRegisters)
+	}
+
 	if len(vr.AllowedSet) == 0 {
 		// No constraints - fall back to any register of matching size
 		for _, reg := range ra.availableRegisters {
-			if reg.Size == int(vr.Size) {
+			if reg.Size == int(vr.Size) && !clobbered[reg] {
 				return reg
 			}
 		}
@@ -260,6 +266,9 @@ func (ra *RegisterAllocator) pickRegisterFromAllowedSetAtPoint(vr *VirtualRegist
 	// Build set of registers used by currently live VRs
 	// Accounts for register composition (HL uses both H and L)
 	usedRegs := make(map[*Register]bool)
+	for reg := range clobbered {
+		usedRegs[reg] = true
+	}
 
 	for _, block := range cfg.Blocks {
 		for _, instr := range block.MachineInstructions {
@@ -290,7 +299,7 @@ func (ra *RegisterAllocator) pickRegisterFromAllowedSetAtPoint(vr *VirtualRegist
 
 	// All AllowedSet registers appear used - pick first one anyway and let moves handle it
 	for _, reg := range vr.AllowedSet {
-		if reg.Size == int(vr.Size) {
+		if reg.Size == int(vr.Size) && !clobbered[reg] {
 			return reg
 		}
 	}
@@ -541,6 +550,10 @@ func (ra *RegisterAllocator) selectRegister(vr *VirtualRegister, ig *Interferenc
 			}
 		}
 	}
+	// registers changed by calls while the VR is live
+	for _, reg := range ig.GetClobbers(vr.ID) {
+		markRegisterAsUsed(reg, usedRegs, ra.availableRegisters)
+	}
 
 	// Filter available registers by size and AllowedSet
 	var candidates []*Register
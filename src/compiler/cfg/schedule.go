@@ -0,0 +1,358 @@
+package cfg
+
+// ScheduleInstructions reorders the independent instructions of each basic block
+// to shorten the live ranges of VirtualRegisters (fewer values live at the same time, fewer spills).
+// Data dependencies (VirtualRegisters) and flag dependencies (descriptors) are respected.
+// Instructions with side effects (memory, stack, calls, branches, I/O, inline assembly) are not moved.
+// Returns the number of instructions that were moved.
+func ScheduleInstructions(cfg *CFG) int {
+	liveness := ComputeLiveness(cfg)
+	moved := 0
+	for _, block := range cfg.Blocks {
+		moved += scheduleBlock(block, liveness.LiveOut[block.ID])
+	}
+	return moved
+}
+
+// scheduledInstr is an instruction in the dependency graph of a block
+type scheduledInstr struct {
+	index        int
+	instr        MachineInstruction
+	uses         []*VirtualRegister
+	defs         []*VirtualRegister
+	successors   []*scheduledInstr
+	predecessors int // number of unscheduled predecessors
+}
+
+// scheduleBlock list-schedules the instructions of the block.
+// From the instructions whose predecessors are scheduled, the one that ends the most live ranges
+// (and starts the fewest) is picked, ties keep the original order.
+func scheduleBlock(block *BasicBlock, liveOut map[int]bool) int {
+	nodes := buildScheduleGraph(block.MachineInstructions)
+	if len(nodes) < 3 {
+		return 0
+	}
+
+	remainingUses := make(map[int]int)
+	live := make(map[int]*VirtualRegister)
+	for _, node := range nodes {
+		for _, use := range node.uses {
+			if remainingUses[use.ID] == 0 && !definedBefore(nodes, node.index, use) {
+				// live into the block
+				live[use.ID] = use
+			}
+			remainingUses[use.ID]++
+		}
+	}
+
+	var ready []*scheduledInstr
+	for _, node := range nodes {
+		if node.predecessors == 0 {
+			ready = append(ready, node)
+		}
+	}
+
+	scheduled := make([]MachineInstruction, 0, len(nodes))
+	moved := 0
+	for len(ready) > 0 {
+		pick := pickReady(ready, live, remainingUses, liveOut)
+		node := ready[pick]
+		ready = append(ready[:pick], ready[pick+1:]...)
+
+		if node.index != len(scheduled) {
+			moved++
+		}
+		scheduled = append(scheduled, node.instr)
+
+		for _, use := range node.uses {
+			remainingUses[use.ID]--
+			if remainingUses[use.ID] == 0 && !liveOut[use.ID] {
+				delete(live, use.ID)
+			}
+		}
+		for _, def := range node.defs {
+			if remainingUses[def.ID] > 0 || liveOut[def.ID] {
+				live[def.ID] = def
+			}
+		}
+
+		for _, succ := range node.successors {
+			succ.predecessors--
+			if succ.predecessors == 0 {
+				ready = append(ready, succ)
+			}
+		}
+	}
+
+	if moved > 0 {
+		block.MachineInstructions = scheduled
+	}
+	return moved
+}
+
+// pickReady returns the position of the ready instruction to schedule next
+func pickReady(ready []*scheduledInstr, live map[int]*VirtualRegister, remainingUses map[int]int, liveOut map[int]bool) int {
+	best := -1
+	bestScore := 0
+	for i, node := range ready {
+		if occupiesPrecolored(node, live, remainingUses, liveOut) {
+			continue
+		}
+		score := 0
+		for _, use := range node.uses {
+			if remainingUses[use.ID] == 1 && !liveOut[use.ID] {
+				score++
+			}
+		}
+		for _, def := range node.defs {
+			if live[def.ID] == nil {
+				score--
+			}
+		}
+		if best < 0 || score > bestScore || (score == bestScore && node.index < ready[best].index) {
+			best, bestScore = i, score
+		}
+	}
+	if best >= 0 {
+		return best
+	}
+
+	// keep the original order
+	best = 0
+	for i, node := range ready {
+		if node.index < ready[best].index {
+			best = i
+		}
+	}
+	return best
+}
+
+// occupiesPrecolored checks if the instruction defines a VirtualRegister bound to a single physical register
+// while another VirtualRegister bound to (part of) that register is still live after the instruction.
+func occupiesPrecolored(node *scheduledInstr, live map[int]*VirtualRegister, remainingUses map[int]int, liveOut map[int]bool) bool {
+	for _, def := range node.defs {
+		if len(def.AllowedSet) != 1 {
+			continue
+		}
+		for _, other := range live {
+			if other == def || len(other.AllowedSet) != 1 || !registersOverlap(def.AllowedSet[0], other.AllowedSet[0]) {
+				continue
+			}
+			if liveOut[other.ID] || remainingUses[other.ID] > usesOf(node, other) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// usesOf counts the uses of the VirtualRegister by the instruction
+func usesOf(node *scheduledInstr, vr *VirtualRegister) int {
+	count := 0
+	for _, use := range node.uses {
+		if use == vr {
+			count++
+		}
+	}
+	return count
+}
+
+// registersOverlap checks if two physical registers share (part of) their storage (A and AF, L and HL)
+func registersOverlap(a, b *Register) bool {
+	if a == b {
+		return true
+	}
+	for _, part := range a.Composition {
+		if registersOverlap(part, b) {
+			return true
+		}
+	}
+	for _, part := range b.Composition {
+		if part == a {
+			return true
+		}
+	}
+	return false
+}
+
+// definedBefore checks if the VirtualRegister is defined by an instruction before the index
+func definedBefore(nodes []*scheduledInstr, index int, vr *VirtualRegister) bool {
+	for _, node := range nodes[:index] {
+		for _, def := range node.defs {
+			if def == vr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// buildScheduleGraph creates the dependency graph of the instructions:
+// an edge from each instruction to the instructions that must stay after it.
+func buildScheduleGraph(instructions []MachineInstruction) []*scheduledInstr {
+	nodes := make([]*scheduledInstr, len(instructions))
+	barriers := make([]bool, len(instructions))
+	skipped := 0
+	for i, instr := range instructions {
+		nodes[i] = &scheduledInstr{index: i, instr: instr}
+		nodes[i].uses, nodes[i].defs = scheduleUsesDefs(instr)
+		// the instructions after a (relative) branch are the ones that are skipped
+		barriers[i] = !isSchedulable(instr) || skipped > 0
+		if skipped > 0 {
+			skipped--
+		}
+		if instr.GetCategory() == CatBranch {
+			skipped = max(skipped, skippedBy(instr))
+		}
+	}
+
+	addEdge := func(from, to int) {
+		nodes[from].successors = append(nodes[from].successors, nodes[to])
+		nodes[to].predecessors++
+	}
+
+	for i := range nodes {
+		for j := 0; j < i; j++ {
+			if barriers[i] || barriers[j] || dependsOn(nodes[i], nodes[j]) {
+				addEdge(j, i)
+			}
+		}
+	}
+	addFlagEdges(instructions, addEdge)
+	return nodes
+}
+
+// skippedBy returns the number of instructions an internal branch jumps over (1 for other branches)
+func skippedBy(instr MachineInstruction) int {
+	operands := instr.GetOperands()
+	if len(instr.GetTargetBlocks()) == 0 && len(operands) == 1 && operands[0] != nil && operands[0].Type == ImmediateValue {
+		return int(operands[0].Value)
+	}
+	return 1
+}
+
+// dependsOn checks if the later instruction reads or writes a VirtualRegister
+// the earlier instruction writes, or writes one the earlier instruction reads
+func dependsOn(later, earlier *scheduledInstr) bool {
+	return intersects(earlier.defs, later.uses) || intersects(earlier.uses, later.defs) || intersects(earlier.defs, later.defs)
+}
+
+// intersects checks if the lists share a VirtualRegister,
+// or VirtualRegisters bound to (part of) the same physical register (B and BC)
+func intersects(a, b []*VirtualRegister) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y || sharesRegister(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sharesRegister checks if both VirtualRegisters are bound to a single, overlapping physical register
+func sharesRegister(x, y *VirtualRegister) bool {
+	return len(x.AllowedSet) == 1 && len(y.AllowedSet) == 1 && registersOverlap(x.AllowedSet[0], y.AllowedSet[0])
+}
+
+// addFlagEdges keeps each flag reader after the instruction that produced its flags,
+// and every other producer of those flags out of the range between them.
+// The flags are assumed to be read after the block (by the branch or a successor).
+func addFlagEdges(instructions []MachineInstruction, addEdge func(from, to int)) {
+	affects := make([]InstrFlags, len(instructions))
+	reads := make([]InstrFlags, len(instructions)+1)
+	for i, instr := range instructions {
+		affects[i], reads[i] = instructionFlags(instr)
+	}
+	reads[len(instructions)] = allFlags
+
+	for r, readFlags := range reads {
+		for flag := InstrFlagC; flag <= InstrFlagS; flag <<= 1 {
+			if readFlags&flag == 0 {
+				continue
+			}
+			producer := -1
+			for p := r - 1; p >= 0; p-- {
+				if affects[p]&flag != 0 {
+					producer = p
+					break
+				}
+			}
+			if producer < 0 {
+				continue
+			}
+			if r < len(instructions) {
+				addEdge(producer, r)
+				for x := r + 1; x < len(instructions); x++ {
+					if affects[x]&flag != 0 {
+						addEdge(r, x)
+					}
+				}
+			}
+			for x := 0; x < producer; x++ {
+				if affects[x]&flag != 0 {
+					addEdge(x, producer)
+				}
+			}
+		}
+	}
+}
+
+// instructionFlags returns the flags the instruction modifies and reads
+// Unknown instructions (inline assembly) modify and read all flags.
+func instructionFlags(instr MachineInstruction) (affects, reads InstrFlags) {
+	desc := descriptorOf(instr)
+	if desc == nil {
+		return allFlags, allFlags
+	}
+	reads = desc.DependentFlags
+	if reads&InstrFlagDynamic != 0 {
+		reads = allFlags
+	}
+	return desc.AffectedFlags, reads
+}
+
+// scheduleUsesDefs returns the VirtualRegisters the instruction reads and writes.
+// The result of an operation (ADD A, r) is also read.
+func scheduleUsesDefs(instr MachineInstruction) (uses, defs []*VirtualRegister) {
+	for _, operand := range instr.GetOperands() {
+		if operand != nil && shouldTrackForLiveness(operand) {
+			uses = append(uses, operand)
+		}
+	}
+	if result := instr.GetResult(); result != nil && shouldTrackForLiveness(result) {
+		defs = append(defs, result)
+		if category := instr.GetCategory(); category != CatLoad && category != CatMove {
+			uses = append(uses, result)
+		}
+	}
+	return uses, defs
+}
+
+// isSchedulable checks if the instruction only computes registers (and flags) and can be moved.
+// Implicit register dependencies other than the accumulator (LDIR, EX) are not tracked.
+func isSchedulable(instr MachineInstruction) bool {
+	desc := descriptorOf(instr)
+	if desc == nil {
+		return false
+	}
+	switch desc.Category {
+	case CatLoad, CatMove, CatArithmetic, CatBitwise:
+	default:
+		return false
+	}
+	if desc.AddressingMode&(AddrIndirect|AddrIndexed) != 0 {
+		return false
+	}
+	for _, dep := range desc.Dependencies {
+		if dep.Type != OpNone {
+			continue
+		}
+		for _, reg := range dep.Registers {
+			if reg != &RegA {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package cfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ScheduleInstructions_ShortensLiveRanges(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	x := vrAlloc.AllocateNamed("x", Z80Registers8)
+	y := vrAlloc.AllocateNamed("y", Z80Registers8)
+	a := vrAlloc.Allocate(Z80Registers8)
+	b := vrAlloc.Allocate(Z80Registers8)
+	c := vrAlloc.Allocate(Z80Registers8)
+	d := vrAlloc.Allocate(Z80Registers8)
+	vrHL := vrAlloc.Allocate(Z80RegHL)
+
+	block := newTestBlock()
+	block.MachineInstructions = []MachineInstruction{
+		// all operands are loaded before they are used: 4 values live
+		newInstruction(Z80_LD_R_R, a, x),
+		newInstruction(Z80_LD_R_R, b, x),
+		newInstruction(Z80_LD_R_R, c, y),
+		newInstruction(Z80_LD_R_R, d, y),
+		newInstruction(Z80_ADD_A_R, a, b),
+		newInstruction(Z80_ADD_A_R, c, d),
+		newInstruction(Z80_LD_HL_R, vrHL, a),
+		newInstruction(Z80_LD_HL_R, vrHL, c),
+	}
+	cfg := &CFG{Blocks: []*BasicBlock{block}, Entry: block}
+
+	moved := ScheduleInstructions(cfg)
+
+	// each sum is computed right after its operands are loaded
+	assert.Equal(t, 3, moved)
+	assert.Equal(t, []Z80Opcode{
+		Z80_LD_R_R, Z80_LD_R_R, Z80_ADD_A_R,
+		Z80_LD_R_R, Z80_LD_R_R, Z80_ADD_A_R,
+		Z80_LD_HL_R, Z80_LD_HL_R,
+	}, opcodesOf(block.MachineInstructions))
+
+	var results []*VirtualRegister
+	for _, instr := range block.MachineInstructions[:6] {
+		results = append(results, instr.GetResult())
+	}
+	assert.Equal(t, []*VirtualRegister{a, b, a, c, d, c}, results)
+}
+
+func Test_ScheduleInstructions_FlagDependency(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	x := vrAlloc.AllocateNamed("x", Z80Registers8)
+	y := vrAlloc.AllocateNamed("y", Z80Registers8)
+	carry := vrAlloc.AllocateNamed("carry", Z80Registers8)
+	total := vrAlloc.AllocateNamed("total", Z80Registers8)
+	a := vrAlloc.Allocate(Z80Registers8)
+	zero := vrAlloc.AllocateImmediate(0, Bits8)
+
+	block := newTestBlock()
+	block.MachineInstructions = []MachineInstruction{
+		newInstruction(Z80_LD_R_R, a, x),
+		newInstruction(Z80_ADD_A_R, a, y),
+		// reads the carry of the addition
+		newInstruction(Z80_ADC_A_N, carry, zero),
+		// ends the live range of 'a', but would overwrite the carry when moved up
+		newInstruction(Z80_ADD_A_R, total, a),
+	}
+	block.Successors = []*BasicBlock{{ID: 1, MachineInstructions: []MachineInstruction{
+		newInstruction(Z80_LD_R_R, x, carry),
+		newInstruction(Z80_LD_R_R, y, total),
+	}}}
+	cfg := &CFG{Blocks: []*BasicBlock{block, block.Successors[0]}, Entry: block}
+
+	moved := ScheduleInstructions(cfg)
+
+	assert.Equal(t, 0, moved)
+	assert.Equal(t, []Z80Opcode{Z80_LD_R_R, Z80_ADD_A_R, Z80_ADC_A_N, Z80_ADD_A_R}, opcodesOf(block.MachineInstructions))
+	assert.Equal(t, carry, block.MachineInstructions[2].GetResult())
+}
+
+func Test_ScheduleGraph_FlagEdges(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	x := vrAlloc.Allocate(Z80Registers8)
+	y := vrAlloc.Allocate(Z80Registers8)
+	z := vrAlloc.Allocate(Z80Registers8)
+	zero := vrAlloc.AllocateImmediate(0, Bits8)
+
+	// independent registers: only the flags order the instructions
+	nodes := buildScheduleGraph([]MachineInstruction{
+		newInstruction(Z80_ADD_A_R, x, x),
+		newInstruction(Z80_CP_R, y, y),
+		newInstruction(Z80_ADC_A_N, z, zero),
+		newInstruction(Z80_INC_R, x, nil),
+	})
+
+	successorsOf := func(node *scheduledInstr) []int {
+		var indexes []int
+		for _, succ := range node.successors {
+			indexes = append(indexes, succ.index)
+		}
+		return indexes
+	}
+	// the earlier producer stays before the compare, the compare before the reader,
+	// the later producer after the reader
+	assert.Contains(t, successorsOf(nodes[0]), 1)
+	assert.Contains(t, successorsOf(nodes[1]), 2)
+	assert.Contains(t, successorsOf(nodes[2]), 3)
+}
+
+func Test_ScheduleGraph_InternalBranchSpan(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	vrBC := vrAlloc.Allocate(Z80RegBC)
+	vrA := vrAlloc.Allocate(Z80RegA)
+	x := vrAlloc.Allocate(Z80Registers8)
+	y := vrAlloc.Allocate(Z80Registers8)
+
+	nodes := buildScheduleGraph([]MachineInstruction{
+		newBranchInternal(Cond_Z, vrAlloc.AllocateImmediate(2, Bits8)),
+		newInstruction(Z80_LD_R_R, x, x),
+		newInstruction(Z80_LD_R_R, y, y),
+		newInstructionResult(Z80_DEC_RR, vrBC),
+		newInstruction(Z80_LD_R_R, vrA, vrAlloc.Allocate(Z80RegB)),
+	})
+
+	successorsOf := func(node *scheduledInstr) []int {
+		var indexes []int
+		for _, succ := range node.successors {
+			indexes = append(indexes, succ.index)
+		}
+		return indexes
+	}
+	// both skipped instructions stay in place, although they are independent
+	assert.Contains(t, successorsOf(nodes[1]), 2)
+	// B is part of BC
+	assert.Contains(t, successorsOf(nodes[3]), 4)
+}
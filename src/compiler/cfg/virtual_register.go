@@ -1,6 +1,9 @@
 package cfg
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+)
 
 type VirtualRegisterType uint8
 
@@ -89,12 +92,33 @@ func (vr *VirtualRegister) MatchAnyRegisters(registers []*Register) bool {
 	return false
 }
 
+// WithinRegisters returns true when every register the VR may end up in is one of the registers
+func (vr *VirtualRegister) WithinRegisters(registers []*Register) bool {
+	switch vr.Type {
+	case AllocatedRegister:
+		return slices.Contains(registers, vr.PhysicalReg)
+	case CandidateRegister:
+		if len(vr.AllowedSet) == 0 {
+			return false
+		}
+		for _, allowed := range vr.AllowedSet {
+			if !slices.Contains(registers, allowed) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// String renders the ID, size and constraints of the VirtualRegister:
+// v7:8{A|B} (candidate), v7:8{A|B}->A (allocated), v9=42 (immediate), v3:16[SP+2] (stack).
+// A named VirtualRegister (variable) is prefixed by its name: 'x' v7:8{A|B}
 func (vr *VirtualRegister) String() string {
-	name := vr.Name
-	if name == "" {
-		name = fmt.Sprintf("VR%d", vr.ID)
-	} else {
-		name = fmt.Sprintf("'%s' VR%d", name, vr.ID)
+	name := fmt.Sprintf("v%d", vr.ID)
+	if vr.Name != "" {
+		name = fmt.Sprintf("'%s' %s", vr.Name, name)
 	}
 	candidates := ""
 	for i, reg := range vr.AllowedSet {
@@ -106,13 +130,13 @@ func (vr *VirtualRegister) String() string {
 
 	switch vr.Type {
 	case AllocatedRegister:
-		return fmt.Sprintf("%s = %s {%s}", name, vr.PhysicalReg.Name, candidates)
+		return fmt.Sprintf("%s:%d{%s}->%s", name, vr.Size, candidates, vr.PhysicalReg.Name)
 	case CandidateRegister:
-		return fmt.Sprintf("%s = {%s}", name, candidates)
+		return fmt.Sprintf("%s:%d{%s}", name, vr.Size, candidates)
 	case ImmediateValue:
-		return fmt.Sprintf("%s = #%d", name, vr.Value)
+		return fmt.Sprintf("%s=%d", name, vr.Value)
 	case StackLocation:
-		return fmt.Sprintf("%s = [SP+%d]", name, vr.Value)
+		return fmt.Sprintf("%s:%d[SP+%d]", name, vr.Size, vr.Value)
 	}
 
 	return name
@@ -0,0 +1,25 @@
+package cfg
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_VirtualRegister_String(t *testing.T) {
+	vrAlloc := NewVirtualRegisterAllocator()
+
+	immediate := vrAlloc.AllocateImmediate(42, Bits8)
+	register := vrAlloc.Allocate(Z80RegA)
+	pair := vrAlloc.Allocate([]*Register{&RegBC, &RegDE})
+	pair.Name = "count"
+
+	assert.Equal(t, fmt.Sprintf("v%d=42", immediate.ID), immediate.String())
+	assert.Equal(t, fmt.Sprintf("v%d:8{A}", register.ID), register.String())
+	assert.Equal(t, fmt.Sprintf("'count' v%d:16{BC|DE}", pair.ID), pair.String())
+
+	register.Type = AllocatedRegister
+	register.PhysicalReg = &RegA
+	assert.Equal(t, fmt.Sprintf("v%d:8{A}->A", register.ID), register.String())
+}
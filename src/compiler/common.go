@@ -2,6 +2,8 @@ package compiler
 
 import "fmt"
 
+// OfType returns the items of the collection that are of type T,
+// in the same order as they appear in the collection.
 func OfType[T interface{}, S any](collection []S) []T {
 	result := make([]T, 0)
 	for _, item := range collection {
@@ -12,9 +14,25 @@ func OfType[T interface{}, S any](collection []S) []T {
 	return result
 }
 
+// OfTypeN returns the n-th (zero based) item of the collection that is of type T.
+// Returns false when the collection has n or fewer items of type T.
+func OfTypeN[T interface{}, S any](collection []S, n int) (T, bool) {
+	for _, item := range collection {
+		if typedItem, ok := any(item).(T); ok {
+			if n == 0 {
+				return typedItem, true
+			}
+			n--
+		}
+	}
+	var none T
+	return none, false
+}
+
 // Go is very liberal in interpreting implemented interfaces and may return unexpected results.
 // This function first selects the correct concrete struct type (T)
 // and then checks if it implements the desired interface (I).
+// The items are returned in the same order as they appear in the collection.
 func OfTypeInterface[T any, I interface{}, S any](collection []S) []I {
 	result := make([]I, 0)
 	for _, item := range collection {
@@ -0,0 +1,29 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_OfType_PreservesOrder(t *testing.T) {
+	collection := []any{1, "a", 2, "b", 3}
+
+	assert.Equal(t, []int{1, 2, 3}, OfType[int](collection))
+	assert.Equal(t, []string{"a", "b"}, OfType[string](collection))
+}
+
+func Test_OfTypeN(t *testing.T) {
+	collection := []any{1, "a", 2, "b", 3}
+
+	second, ok := OfTypeN[string](collection, 1)
+	assert.True(t, ok)
+	assert.Equal(t, "b", second)
+
+	third, ok := OfTypeN[int](collection, 2)
+	assert.True(t, ok)
+	assert.Equal(t, 3, third)
+
+	_, ok = OfTypeN[string](collection, 2)
+	assert.False(t, ok)
+}
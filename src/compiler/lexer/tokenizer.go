@@ -18,8 +18,19 @@ type Tokenizer struct {
 	line       int
 	column     int
 	lastColumn int // for unread
+	asmState   asmState
+	pending    Token // token already scanned, returned by the next parseToken
 }
 
+// asmState tracks an 'asm' block: the body after '{' is scanned as raw text
+type asmState int
+
+const (
+	asmNone    asmState = iota
+	asmKeyword          // 'asm' seen, waiting for '{'
+	asmBody             // '{' seen, next token is the raw body text
+)
+
 func TokenizerFromFile(file *os.File) *Tokenizer {
 	return TokenizerFromReader(bufio.NewReader(file))
 }
@@ -68,6 +79,16 @@ func (t *Tokenizer) Tokens() <-chan Token {
 }
 
 func (t *Tokenizer) parseToken() (Token, error) {
+	if t.pending != nil {
+		token := t.pending
+		t.pending = nil
+		return token, nil
+	}
+	if t.asmState == asmBody {
+		t.asmState = asmNone
+		return t.parseAsmText()
+	}
+
 	r, err := t.read()
 	if r == 0 {
 		return &tokenData{TokenEOF, t.makeLocation(), ""}, nil
@@ -89,9 +110,54 @@ func (t *Tokenizer) parseToken() (Token, error) {
 		token, err = t.parseUnknown(r, location)
 	}
 
+	t.updateAsmState(token)
 	return token, err
 }
 
+// updateAsmState switches to raw text scanning for the body of 'asm' '{'
+func (t *Tokenizer) updateAsmState(token Token) {
+	switch token.Id() {
+	case TokenAsm:
+		t.asmState = asmKeyword
+	case TokenBracesOpen:
+		if t.asmState == asmKeyword {
+			t.asmState = asmBody
+		}
+	case TokenWhitespace, TokenEOL, TokenComment:
+		// allowed between 'asm' and '{'
+	default:
+		t.asmState = asmNone
+	}
+}
+
+// parseAsmText scans the raw text up to the matching (unread) close brace
+// Nested braces are part of the text.
+func (t *Tokenizer) parseAsmText() (Token, error) {
+	r, err := t.read()
+	location := t.makeLocation()
+	t.unread(r)
+
+	var builder strings.Builder
+	depth := 0
+	for {
+		r, err = t.read()
+		if r == 0 || err != nil {
+			return &invalidTokenData{location, builder.String(), TokenAsmText}, err
+		}
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				t.unread(r)
+				return &tokenData{TokenAsmText, location, builder.String()}, nil
+			}
+			depth--
+		}
+		builder.WriteRune(r)
+	}
+}
+
 func (t *Tokenizer) parseIdentifierOrKeyword(first rune, location compiler.Location) (Token, error) {
 	var builder strings.Builder
 	builder.WriteRune(first)
@@ -101,7 +167,8 @@ func (t *Tokenizer) parseIdentifierOrKeyword(first rune, location compiler.Locat
 		if r == 0 {
 			break
 		}
-		if err == nil && (unicode.IsSpace(r) || isPunctuation(r)) {
+		// an underscore continues the identifier (include_bin)
+		if err == nil && (unicode.IsSpace(r) || (isPunctuation(r) && r != '_')) {
 			t.unread(r)
 			break
 		}
@@ -123,6 +190,12 @@ func (t *Tokenizer) parseIdentifierOrKeyword(first rune, location compiler.Locat
 		token = &tokenData{TokenNot, location, idOrKeyword}
 	case "for":
 		token = &tokenData{TokenFor, location, idOrKeyword}
+	case "in":
+		token = &tokenData{TokenIn, location, idOrKeyword}
+	case "do":
+		token = &tokenData{TokenDo, location, idOrKeyword}
+	case "while":
+		token = &tokenData{TokenWhile, location, idOrKeyword}
 	case "if":
 		token = &tokenData{TokenIf, location, idOrKeyword}
 	case "elsif":
@@ -145,6 +218,12 @@ func (t *Tokenizer) parseIdentifierOrKeyword(first rune, location compiler.Locat
 		token = &tokenData{TokenFalse, location, idOrKeyword}
 	case "ret":
 		token = &tokenData{TokenReturn, location, idOrKeyword}
+	case "asm":
+		token = &tokenData{TokenAsm, location, idOrKeyword}
+	case "import":
+		token = &tokenData{TokenImport, location, idOrKeyword}
+	case "export":
+		token = &tokenData{TokenExport, location, idOrKeyword}
 	default:
 		token = &tokenData{TokenIdentifier, location, idOrKeyword}
 	}
@@ -156,7 +235,10 @@ func (t *Tokenizer) parseNumber(first rune, location compiler.Location) (Token,
 	var builder strings.Builder
 	builder.WriteRune(first)
 
-	var isHex = false // allows a-f/A-F
+	var isHex = false      // allows a-f/A-F
+	var isPrefixed = false // 0x or 0b
+	var isDecimal = false  // has a decimal point
+	var hasSuffix = false  // type suffix: 42u16, 10i8
 
 	for {
 		r, err := t.read()
@@ -166,12 +248,35 @@ func (t *Tokenizer) parseNumber(first rune, location compiler.Location) (Token,
 				// this all is to allow for 0xAA and 0b01101011
 				if builder.Len() == 1 && first == '0' && (r == 'x' || r == 'b') {
 					isHex = r == 'x'
+					isPrefixed = true
+					builder.WriteRune(r)
+					continue
+				} else if r == '.' && !isPrefixed && !isDecimal {
+					// a range (0..10) ends the number
+					rangeLocation := t.makeLocation()
+					next, _ := t.read()
+					if next == '.' {
+						t.pending = &tokenData{TokenRange, rangeLocation, ".."}
+						return &tokenData{TokenNumber, location, builder.String()}, nil
+					}
+					t.unread(next)
+					// no decimal point after a type suffix
+					if hasSuffix {
+						t.pending = &tokenData{TokenPeriod, rangeLocation, "."}
+						return &tokenData{TokenNumber, location, builder.String()}, nil
+					}
+					// decimal fixed-point: 1.5
+					isDecimal = true
+					builder.WriteRune(r)
+					continue
+				} else if (r == 'u' || r == 'i') && !isDecimal && !hasSuffix {
+					hasSuffix = true
 					builder.WriteRune(r)
 					continue
-				} else if r == '_' {
+				} else if r == '_' && !hasSuffix {
 					builder.WriteRune(r)
 					continue
-				} else if isHex && isHexLetter(r) {
+				} else if isHex && isHexLetter(r) && !hasSuffix {
 					builder.WriteRune(r)
 					continue
 				}
@@ -216,7 +321,7 @@ func (t *Tokenizer) parsePunctuation(first rune, location compiler.Location) (To
 	case ')':
 		token = &tokenData{TokenParenClose, location, text}
 	case '.':
-		token = &tokenData{TokenPeriod, location, text}
+		token, err = t.parseSingeOrDouble(first, location, TokenPeriod, TokenRange)
 	case ',':
 		token = &tokenData{TokenComma, location, text}
 	case ';':
@@ -224,11 +329,11 @@ func (t *Tokenizer) parsePunctuation(first rune, location compiler.Location) (To
 	case ':':
 		token = &tokenData{TokenColon, location, text}
 	case '=':
-		token = &tokenData{TokenEquals, location, text}
+		token, err = t.parseSingeOrDouble(first, location, TokenEquals, TokenEqualsEquals)
 	case '>':
-		token, err = t.parseGreaterOrGreaterEquals(first, location)
+		token, err = t.parseGreaterOperator(first, location)
 	case '<':
-		token, err = t.parseLessOrLessEqualsOrNotEquals(first, location)
+		token, err = t.parseLessOperator(first, location)
 	case '&':
 		token = &tokenData{TokenAmpersant, location, text}
 	case '#':
@@ -314,22 +419,27 @@ func (t *Tokenizer) parseCommentOrSlash(first rune, location compiler.Location)
 	return &tokenData{TokenComment, location, builder.String()}, err
 }
 
-func (t *Tokenizer) parseGreaterOrGreaterEquals(first rune, location compiler.Location) (Token, error) {
+// parseGreaterOperator: '>' | '>=' | '>>'
+func (t *Tokenizer) parseGreaterOperator(first rune, location compiler.Location) (Token, error) {
 	var builder strings.Builder
 	builder.WriteRune(first)
 	r, err := t.read()
 	if err != nil && err != io.EOF {
 		return &invalidTokenData{location, builder.String(), TokenGreater}, err
 	}
-	if r != '=' {
+	if r != '=' && r != '>' {
 		t.unread(r)
 		return &tokenData{TokenGreater, location, builder.String()}, nil
 	}
 	builder.WriteRune(r)
-	return &tokenData{TokenGreaterOrEquals, location, builder.String()}, nil
+	if r == '=' {
+		return &tokenData{TokenGreaterOrEquals, location, builder.String()}, nil
+	}
+	return &tokenData{TokenShiftRight, location, builder.String()}, nil
 }
 
-func (t *Tokenizer) parseLessOrLessEqualsOrNotEquals(first rune, location compiler.Location) (Token, error) {
+// parseLessOperator: '<' | '<=' | '<>' | '<<'
+func (t *Tokenizer) parseLessOperator(first rune, location compiler.Location) (Token, error) {
 	var builder strings.Builder
 	builder.WriteRune(first)
 
@@ -337,13 +447,16 @@ func (t *Tokenizer) parseLessOrLessEqualsOrNotEquals(first rune, location compil
 	if err != nil {
 		return &invalidTokenData{location, builder.String(), TokenLess}, err
 	}
-	if r != '=' && r != '>' {
+	if r != '=' && r != '>' && r != '<' {
 		t.unread(r)
 		return &tokenData{TokenLess, location, builder.String()}, nil
 	}
 	builder.WriteRune(r)
-	if r == '=' {
+	switch r {
+	case '=':
 		return &tokenData{TokenLessOrEquals, location, builder.String()}, nil
+	case '<':
+		return &tokenData{TokenShiftLeft, location, builder.String()}, nil
 	}
 	return &tokenData{TokenNotEquals, location, builder.String()}, nil
 }
@@ -372,6 +485,14 @@ func (t *Tokenizer) parseEnclosed(first rune, location compiler.Location, tokenI
 		}
 
 		builder.WriteRune(r)
+		// an escaped character does not end the text (decoded by the semantic analyzer)
+		if r == '\\' {
+			r, err = t.read()
+			if err != nil {
+				return &invalidTokenData{location, builder.String(), tokenId}, err
+			}
+			builder.WriteRune(r)
+		}
 	}
 }
 
@@ -2,6 +2,7 @@ package lexer
 
 import (
 	"fmt"
+	"strings"
 
 	"zenith/compiler"
 )
@@ -35,11 +36,14 @@ const (
 	TokenSemiColon               // ;
 	TokenColon                   // :
 	TokenEquals                  // =
+	TokenEqualsEquals            // ==
 	TokenGreater                 // >
 	TokenLess                    // <
 	TokenGreaterOrEquals         // >=
 	TokenLessOrEquals            // <=
 	TokenNotEquals               // <>
+	TokenShiftLeft               // <<
+	TokenShiftRight              // >>
 	TokenAmpersant               // &
 	TokenHash                    // #
 	TokenAtSign                  // @
@@ -53,10 +57,14 @@ const (
 	TokenUnderscore              // _
 	TokenIncrement               // ++
 	TokenDecrement               // --
+	TokenRange                   // ..
 	TokenAnd                     // and
 	TokenOr                      // or
 	TokenNot                     // not
 	TokenFor                     // for
+	TokenIn                      // in
+	TokenDo                      // do
+	TokenWhile                   // while
 	TokenIf                      // if
 	TokenElsif                   // elsif
 	TokenElse                    // else
@@ -69,6 +77,10 @@ const (
 	TokenTrue                    // true
 	TokenFalse                   // false
 	TokenReturn                  // ret
+	TokenAsm                     // asm
+	TokenAsmText                 // raw text of an asm { ... } block
+	TokenImport                  // import
+	TokenExport                  // export
 
 	//TokenDoubleQuote            // "
 	//TokenSingleQuote            // '
@@ -89,6 +101,15 @@ type tokenData struct {
 	text     string
 }
 
+// SplitNumberSuffix splits a number into its value and type suffix: "42u16" is "42" and "u16".
+// The suffix is empty when the number has none.
+func SplitNumberSuffix(text string) (string, string) {
+	if i := strings.IndexAny(text, "ui"); i > 0 {
+		return text[:i], text[i:]
+	}
+	return text, ""
+}
+
 func (t *tokenData) Id() TokenId {
 	return t.id
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_TokenNumber0(t *testing.T) {
@@ -62,6 +63,30 @@ func Test_TokenNumberAndWS(t *testing.T) {
 	assert.Equal(t, TokenEOF, eof.Id())
 }
 
+func Test_TokenDecimalNumber(t *testing.T) {
+	code := "1.5"
+	tokens := RunTokenizer(code)
+
+	first := tokens[0]
+	assert.Equal(t, TokenNumber, first.Id())
+	assert.Equal(t, "1.5", first.Text())
+
+	eof := tokens[1]
+	assert.Equal(t, TokenEOF, eof.Id())
+}
+
+func Test_TokenHexNumberPeriod(t *testing.T) {
+	code := "0x1.5"
+	tokens := RunTokenizer(code)
+
+	// hexadecimal numbers have no decimal point
+	assert.Equal(t, TokenNumber, tokens[0].Id())
+	assert.Equal(t, "0x1", tokens[0].Text())
+	assert.Equal(t, TokenPeriod, tokens[1].Id())
+	assert.Equal(t, TokenNumber, tokens[2].Id())
+	assert.Equal(t, "5", tokens[2].Text())
+}
+
 func Test_TokenHexNumber(t *testing.T) {
 	code := "0xA5_0F"
 	tokens := RunTokenizer(code)
@@ -111,13 +136,130 @@ func Test_TokenPunctuation(t *testing.T) {
 	}
 }
 
+func Test_TokenComparisonAndShift(t *testing.T) {
+	code := "< <= <> << > >= >> = =="
+	tokens := RunTokenizer(code)
+
+	expected := []TokenId{
+		TokenLess, TokenLessOrEquals, TokenNotEquals, TokenShiftLeft,
+		TokenGreater, TokenGreaterOrEquals, TokenShiftRight, TokenEquals, TokenEqualsEquals,
+	}
+
+	ids := []TokenId{}
+	for _, token := range tokens {
+		if token.Id() != TokenWhitespace && token.Id() != TokenEOF {
+			ids = append(ids, token.Id())
+		}
+	}
+	assert.Equal(t, expected, ids)
+}
+
+func Test_TokenRange(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected []TokenId
+		texts    []string
+	}{
+		{"0..10", []TokenId{TokenNumber, TokenRange, TokenNumber}, []string{"0", "..", "10"}},
+		{"lo .. hi", []TokenId{TokenIdentifier, TokenRange, TokenIdentifier}, []string{"lo", "..", "hi"}},
+		{"1.5", []TokenId{TokenNumber}, []string{"1.5"}},
+		{"p.x", []TokenId{TokenIdentifier, TokenPeriod, TokenIdentifier}, []string{"p", ".", "x"}},
+		{"a . . b", []TokenId{TokenIdentifier, TokenPeriod, TokenPeriod, TokenIdentifier}, []string{"a", ".", ".", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			tokens := RunTokenizer(tt.code)
+
+			ids := []TokenId{}
+			texts := []string{}
+			for _, token := range tokens {
+				if token.Id() != TokenWhitespace && token.Id() != TokenEOF {
+					ids = append(ids, token.Id())
+					texts = append(texts, token.Text())
+				}
+			}
+			assert.Equal(t, tt.expected, ids)
+			assert.Equal(t, tt.texts, texts)
+		})
+	}
+}
+
+func Test_TokenRange_Location(t *testing.T) {
+	tests := []struct {
+		code  string
+		index int
+	}{
+		{"0..10", 1},
+		{"lo..hi", 2},
+		{"x: 12 .. 34", 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			tokens := RunTokenizer(tt.code)
+
+			var rangeToken Token
+			for _, token := range tokens {
+				if token.Id() == TokenRange {
+					rangeToken = token
+				}
+			}
+			require.NotNil(t, rangeToken)
+			assert.Equal(t, tt.index, rangeToken.Location().Index)
+			assert.Equal(t, 1, rangeToken.Location().Line)
+			assert.Equal(t, tt.index+1, rangeToken.Location().Column)
+		})
+	}
+}
+
+func Test_TokenNumberSuffix(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected []TokenId
+		texts    []string
+	}{
+		{"42u16", []TokenId{TokenNumber}, []string{"42u16"}},
+		{"0xFFi16", []TokenId{TokenNumber}, []string{"0xFFi16"}},
+		{"0u8..10", []TokenId{TokenNumber, TokenRange, TokenNumber}, []string{"0u8", "..", "10"}},
+		{"5i8.x", []TokenId{TokenNumber, TokenPeriod, TokenIdentifier}, []string{"5i8", ".", "x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			tokens := RunTokenizer(tt.code)
+
+			ids := []TokenId{}
+			texts := []string{}
+			for _, token := range tokens {
+				if token.Id() != TokenWhitespace && token.Id() != TokenEOF {
+					ids = append(ids, token.Id())
+					texts = append(texts, token.Text())
+				}
+			}
+			assert.Equal(t, tt.expected, ids)
+			assert.Equal(t, tt.texts, texts)
+		})
+	}
+}
+
+func Test_SplitNumberSuffix(t *testing.T) {
+	number, suffix := SplitNumberSuffix("42u16")
+	assert.Equal(t, "42", number)
+	assert.Equal(t, "u16", suffix)
+
+	number, suffix = SplitNumberSuffix("0xFF")
+	assert.Equal(t, "0xFF", number)
+	assert.Empty(t, suffix)
+}
+
 func Test_TokenKeywords(t *testing.T) {
-	code := "and or not for if elsif else select case struct const any"
+	code := "and or not for in do while if elsif else select case struct const any import export"
 	tokens := RunTokenizer(code)
 
 	expected := []TokenId{
-		TokenAnd, TokenOr, TokenNot, TokenFor, TokenIf, TokenElsif, TokenElse, TokenSelect,
-		TokenCase, TokenStruct, TokenConst, TokenAny,
+		TokenAnd, TokenOr, TokenNot, TokenFor, TokenIn, TokenDo, TokenWhile, TokenIf, TokenElsif, TokenElse, TokenSelect,
+		TokenCase, TokenStruct, TokenConst, TokenAny, TokenImport, TokenExport,
 	}
 
 	// i += 2 => we skip all the TokenWhitespace between the keywords
@@ -150,6 +292,14 @@ func Test_TokenIdentifierMulitple(t *testing.T) {
 	assert.Equal(t, "ifelsifelse", id2.Text())
 }
 
+func Test_TokenIdentifierUnderscore(t *testing.T) {
+	code := "include_bin"
+	tokens := RunTokenizer(code)
+
+	assert.Equal(t, TokenIdentifier, tokens[0].Id())
+	assert.Equal(t, code, tokens[0].Text())
+}
+
 func Test_TokenString(t *testing.T) {
 	code := "\"string\""
 	tokens := RunTokenizer(code)
@@ -159,6 +309,15 @@ func Test_TokenString(t *testing.T) {
 	assert.Equal(t, code, str1.Text())
 }
 
+func Test_TokenString_Escaped(t *testing.T) {
+	code := `"say \"hi\"\\"`
+	tokens := RunTokenizer(code)
+
+	str1 := tokens[0]
+	assert.Equal(t, TokenString, str1.Id())
+	assert.Equal(t, code, str1.Text())
+}
+
 func Test_TokenChar(t *testing.T) {
 	code := "'c'"
 	tokens := RunTokenizer(code)
@@ -208,3 +367,50 @@ func Test_TokenComment(t *testing.T) {
 	t3 := tokens[2]
 	assert.Equal(t, TokenEOF, t3.Id())
 }
+
+func Test_TokenAsm(t *testing.T) {
+	code := "asm { ld a, (ix+1) ; {nested} }"
+	tokens := RunTokenizer(code)
+
+	assert.Equal(t, TokenAsm, tokens[0].Id())
+	assert.Equal(t, TokenWhitespace, tokens[1].Id())
+	assert.Equal(t, TokenBracesOpen, tokens[2].Id())
+
+	text := tokens[3]
+	assert.Equal(t, TokenAsmText, text.Id())
+	assert.Equal(t, " ld a, (ix+1) ; {nested} ", text.Text())
+
+	assert.Equal(t, TokenBracesClose, tokens[4].Id())
+	assert.Equal(t, TokenEOF, tokens[5].Id())
+}
+
+func Test_TokenAsm_Multiline(t *testing.T) {
+	code := "asm\n{\n\tdi\n\thalt\n}\nx"
+	tokens := RunTokenizer(code)
+
+	assert.Equal(t, TokenAsm, tokens[0].Id())
+	assert.Equal(t, TokenEOL, tokens[1].Id())
+	assert.Equal(t, TokenBracesOpen, tokens[2].Id())
+	assert.Equal(t, TokenAsmText, tokens[3].Id())
+	assert.Equal(t, "\n\tdi\n\thalt\n", tokens[3].Text())
+	assert.Equal(t, TokenBracesClose, tokens[4].Id())
+	assert.Equal(t, TokenEOL, tokens[5].Id())
+	assert.Equal(t, TokenIdentifier, tokens[6].Id())
+}
+
+func Test_TokenAsm_Unterminated(t *testing.T) {
+	code := "asm { nop"
+	tokens := RunTokenizer(code)
+
+	assert.Equal(t, TokenInvalid, tokens[3].Id())
+	assert.Equal(t, " nop", tokens[3].Text())
+}
+
+func Test_TokenAsm_OnlyAfterKeyword(t *testing.T) {
+	code := "x { y }"
+	tokens := RunTokenizer(code)
+
+	for _, token := range tokens {
+		assert.NotEqual(t, TokenAsmText, token.Id())
+	}
+}
@@ -0,0 +1,449 @@
+package parser
+
+import (
+	"strings"
+	"zenith/compiler/lexer"
+)
+
+// ============================================================================
+// Formatter: re-emits canonical Zenith source from the AST
+// ============================================================================
+
+const formatIndent = "\t"
+
+// expression precedence levels (higher binds tighter), matching the parser rules
+const (
+	precNone = iota
+	precLogical
+	precComparison
+	precBitwise
+	precShift
+	precArithmetic
+	precUnaryPrefix
+	precPostfix
+	precPrimary
+)
+
+type formatter struct {
+	builder strings.Builder
+	indent  int
+	// comments already written (trivia is shared between nested nodes)
+	emitted map[lexer.Token]bool
+}
+
+// Format returns the canonical source text for the node.
+// Comments attached as trivia to declarations and statements are preserved.
+func Format(node ParserNode) string {
+	f := &formatter{emitted: make(map[lexer.Token]bool)}
+	if cu, ok := node.(CompilationUnit); ok {
+		f.compilationUnit(cu)
+	} else if expr, ok := node.(Expression); ok {
+		f.write(f.expression(expr, precNone))
+	} else {
+		f.declarationOrStatement(node)
+	}
+	return f.builder.String()
+}
+
+func (f *formatter) write(text string) {
+	f.builder.WriteString(text)
+}
+
+func (f *formatter) newLine() {
+	f.write("\n")
+}
+
+func (f *formatter) writeIndent() {
+	f.write(strings.Repeat(formatIndent, f.indent))
+}
+
+// leadingComments writes the comments before the node each on their own line
+func (f *formatter) leadingComments(node ParserNode) {
+	for _, token := range node.LeadingTrivia() {
+		if token.Id() == lexer.TokenComment && !f.emitted[token] {
+			f.emitted[token] = true
+			f.writeIndent()
+			f.write(token.Text())
+			f.newLine()
+		}
+	}
+}
+
+// trailingComment writes the comment at the end of the node's (last) line
+func (f *formatter) trailingComment(tokens []lexer.Token) {
+	for _, token := range tokens {
+		if token.Id() == lexer.TokenComment && !f.emitted[token] {
+			f.emitted[token] = true
+			f.write(" ")
+			f.write(token.Text())
+		}
+	}
+}
+
+// danglingComments writes the not yet emitted comments in the tokens on their own lines
+func (f *formatter) danglingComments(tokens []lexer.Token) {
+	for _, token := range tokens {
+		if token.Id() == lexer.TokenComment && !f.emitted[token] {
+			f.emitted[token] = true
+			f.writeIndent()
+			f.write(token.Text())
+			f.newLine()
+		}
+	}
+}
+
+// ============================================================================
+// Declarations and statements
+// ============================================================================
+
+func (f *formatter) compilationUnit(cu CompilationUnit) {
+	var previous ParserNode
+	for _, decl := range cu.Declarations() {
+		// separate functions and structs from their neighbors with an empty line
+		if previous != nil && (isBlockDeclaration(previous) || isBlockDeclaration(decl)) {
+			f.newLine()
+		}
+		f.declarationOrStatement(decl)
+		previous = decl
+	}
+}
+
+// writeExport writes the 'export' marker of an exported declaration
+func (f *formatter) writeExport(exported bool) {
+	if exported {
+		f.write("export ")
+	}
+}
+
+func isBlockDeclaration(node ParserNode) bool {
+	switch node.(type) {
+	case FunctionDeclaration, TypeDeclaration:
+		return true
+	}
+	return false
+}
+
+// declarationOrStatement writes a node that occupies its own line(s)
+func (f *formatter) declarationOrStatement(node ParserNode) {
+	f.leadingComments(node)
+	f.writeIndent()
+
+	switch n := node.(type) {
+	case ImportDeclaration:
+		f.write("import \"" + n.ModuleName() + "\"")
+	case VariableDeclaration:
+		f.writeExport(n.IsExported())
+		f.write(f.variableDeclaration(n))
+	case VariableAssignment:
+		f.write(f.variableAssignment(n))
+	case MultiAssignment:
+		f.write(f.multiAssignment(n))
+	case FunctionDeclaration:
+		f.writeExport(n.IsExported())
+		f.functionDeclaration(n)
+	case TypeDeclaration:
+		f.writeExport(n.IsExported())
+		f.typeDeclaration(n)
+	case TypeAlias:
+		f.write("type " + n.Name().Text() + " = " + f.typeRef(n.AliasedType()))
+	case StatementIf:
+		f.statementIf(n)
+	case StatementForRange:
+		f.statementForRange(n)
+	case StatementFor:
+		f.statementFor(n)
+	case StatementDoWhile:
+		f.statementDoWhile(n)
+	case StatementSelect:
+		f.statementSelect(n)
+	case StatementReturn:
+		if n.Value() != nil {
+			f.write("ret " + f.expression(n.Value(), precNone))
+		} else {
+			f.write("ret")
+		}
+	case StatementExpression:
+		f.write(f.expression(n.Expression(), precNone))
+	case InlineAsm:
+		// raw text is written verbatim
+		f.write("asm {" + n.Text() + "}")
+	}
+
+	f.trailingComment(node.TrailingTrivia())
+	f.newLine()
+}
+
+func (f *formatter) variableDeclaration(n VariableDeclaration) string {
+	if n.TypeRef() == nil {
+		return n.Label().Name() + " := " + f.expression(n.Initializer(), precNone)
+	}
+	text := n.Label().Name() + ": " + f.typeRef(n.TypeRef())
+	if n.Initializer() != nil {
+		text += " = " + f.expression(n.Initializer(), precNone)
+	}
+	return text
+}
+
+func (f *formatter) variableAssignment(n VariableAssignment) string {
+	operator := "="
+	if op := n.Operator(); op != nil {
+		operator = op.Text() + "="
+	}
+	return f.expression(n.Target(), precNone) + " " + operator + " " +
+		f.expression(n.Expression(), precNone)
+}
+
+func (f *formatter) multiAssignment(n MultiAssignment) string {
+	targets := make([]string, 0, len(n.Targets()))
+	for _, target := range n.Targets() {
+		targets = append(targets, f.expression(target, precNone))
+	}
+	values := make([]string, 0, len(n.Values()))
+	for _, value := range n.Values() {
+		values = append(values, f.expression(value, precNone))
+	}
+	return strings.Join(targets, ", ") + " = " + strings.Join(values, ", ")
+}
+
+func (f *formatter) functionDeclaration(n FunctionDeclaration) {
+	for _, attribute := range n.Attributes() {
+		f.write("@" + attribute.Text())
+		if arg := n.AttributeArgument(attribute.Text()); arg != nil {
+			f.write("(" + f.expression(arg, precNone) + ")")
+		}
+		f.write(" ")
+	}
+	f.write(n.Label().Name() + ": (")
+	if params := n.Parameters(); params != nil {
+		f.write(f.declarationFields(params))
+	}
+	f.write(")")
+	if n.ReturnType() != nil {
+		f.write(" " + f.typeRef(n.ReturnType()))
+	}
+	f.write(" ")
+	f.codeBlock(n.Body())
+}
+
+func (f *formatter) typeDeclaration(n TypeDeclaration) {
+	f.write("struct " + n.Name().Text() + " {")
+	if fields := n.Fields(); fields != nil && fields.Fields() != nil {
+		f.write(" " + f.declarationFields(fields.Fields()) + " ")
+	}
+	f.write("}")
+}
+
+func (f *formatter) declarationFields(list DeclarationFieldList) string {
+	fields := []string{}
+	for _, field := range list.Fields() {
+		fields = append(fields, field.Label().Name()+": "+f.typeRef(field.TypeRef()))
+	}
+	return strings.Join(fields, ", ")
+}
+
+func (f *formatter) typeRef(n TypeRef) string {
+	text := n.TypeName().Text()
+	if n.IsArray() {
+		text += "["
+		if size := n.ArraySize(); size != nil {
+			text += size.Text()
+		}
+		text += "]"
+	}
+	if n.IsPointer() {
+		text += "*"
+	}
+	return text
+}
+
+// codeBlock writes '{' statements '}' starting on the current line
+func (f *formatter) codeBlock(block CodeBlock) {
+	f.write("{")
+	if block == nil {
+		f.write("}")
+		return
+	}
+
+	f.newLine()
+	f.indent++
+	for _, stmt := range block.Statements() {
+		f.declarationOrStatement(stmt)
+	}
+	f.danglingComments(block.Tokens())
+	f.indent--
+	f.writeIndent()
+	f.write("}")
+}
+
+func (f *formatter) statementIf(n StatementIf) {
+	f.write("if " + f.expression(n.Condition(), precNone) + " ")
+	f.codeBlock(n.ThenBlock())
+	for _, elsif := range n.ElsifClauses() {
+		f.write(" elsif " + f.expression(elsif.Condition(), precNone) + " ")
+		f.codeBlock(elsif.ThenBlock())
+	}
+	if n.ElseBlock() != nil {
+		f.write(" else ")
+		f.codeBlock(n.ElseBlock())
+	}
+}
+
+func (f *formatter) statementFor(n StatementFor) {
+	f.write("for ")
+	switch init := n.Initializer().(type) {
+	case VariableDeclaration:
+		f.write(f.variableDeclaration(init) + "; ")
+	case VariableAssignment:
+		f.write(f.variableAssignment(init) + "; ")
+	}
+	f.write(f.expression(n.Condition(), precNone))
+	switch increment := n.Increment().(type) {
+	case VariableAssignment:
+		f.write("; " + f.variableAssignment(increment))
+	case Expression:
+		f.write("; " + f.expression(increment, precNone))
+	}
+	f.write(" ")
+	f.codeBlock(n.Body())
+}
+
+func (f *formatter) statementForRange(n StatementForRange) {
+	f.write("for " + n.Variable().Text() + " in " + f.expression(n.From(), precNone) +
+		".." + f.expression(n.To(), precNone) + " ")
+	f.codeBlock(n.Body())
+}
+
+func (f *formatter) statementDoWhile(n StatementDoWhile) {
+	f.write("do ")
+	f.codeBlock(n.Body())
+	f.write(" while " + f.expression(n.Condition(), precNone))
+}
+
+func (f *formatter) statementSelect(n StatementSelect) {
+	f.write("select " + f.expression(n.Expression(), precNone) + " {")
+	f.newLine()
+	f.indent++
+	for _, c := range n.Cases() {
+		f.leadingComments(c)
+		f.writeIndent()
+		f.write("case " + f.expression(c.Expression(), precNone) + " ")
+		f.codeBlock(c.Body())
+		f.newLine()
+	}
+	if e := n.Else(); e != nil {
+		f.leadingComments(e)
+		f.writeIndent()
+		f.write("else ")
+		f.codeBlock(e.Body())
+		f.newLine()
+	}
+	f.indent--
+	f.writeIndent()
+	f.write("}")
+}
+
+// ============================================================================
+// Expressions
+// ============================================================================
+
+// precedenceOf returns the binding strength of the expression as parsed
+func precedenceOf(expr Expression) int {
+	switch expr.ExpressionKind() {
+	case ExprBinaryLogical:
+		return precLogical
+	case ExprBinaryComparison:
+		return precComparison
+	case ExprBinaryBitwise:
+		return precBitwise
+	case ExprBinaryShift:
+		return precShift
+	case ExprBinaryArithmetic:
+		return precArithmetic
+	case ExprUnaryPrefixArithmetic, ExprUnaryPrefixBitwise, ExprUnaryPrefixLogical:
+		return precUnaryPrefix
+	case ExprUnaryPostfixArithmetic, ExprUnaryPostfixLogical, ExprMemberAccess, ExprSubscript:
+		return precPostfix
+	case ExprPrecedence:
+		return precedenceOf(expr.(ExpressionPrecedence).Inner())
+	default:
+		return precPrimary
+	}
+}
+
+// expression returns the text for the expression.
+// Parentheses are written only when the context requires a tighter binding (minPrec)
+// than the expression has - redundant parentheses in the source are dropped.
+func (f *formatter) expression(expr Expression, minPrec int) string {
+	if expr == nil {
+		return ""
+	}
+	if p, ok := expr.(ExpressionPrecedence); ok {
+		return f.expression(p.Inner(), minPrec)
+	}
+	text := f.expressionText(expr)
+	if precedenceOf(expr) < minPrec {
+		return "(" + text + ")"
+	}
+	return text
+}
+
+func (f *formatter) expressionText(expr Expression) string {
+	switch n := expr.(type) {
+	case ExpressionOperatorBinary:
+		prec := precedenceOf(n)
+		leftPrec := prec
+		if prec == precComparison {
+			// comparisons do not chain
+			leftPrec = prec + 1
+		}
+		return f.expression(n.Left(), leftPrec) + " " + n.Operator().Text() + " " +
+			f.expression(n.Right(), prec+1)
+	case ExpressionOperatorUnary:
+		if n.UnaryType() == UnaryPostfix {
+			return f.expression(n.Operand(), precPostfix) + n.Operator().Text()
+		}
+		operand := f.expression(n.Operand(), precUnaryPrefix)
+		operator := n.Operator().Text()
+		if n.Operator().Id() == lexer.TokenNot ||
+			strings.HasPrefix(operand, "-") || strings.HasPrefix(operand, "+") {
+			// keep 'not x' and '- -x' apart
+			operator += " "
+		}
+		return operator + operand
+	case ExpressionMemberAccess:
+		return f.expression(n.Object(), precPostfix) + "." + n.Member().Text()
+	case ExpressionSubscript:
+		return f.expression(n.Array(), precPostfix) + "[" + f.expression(n.Index(), precNone) + "]"
+	case ExpressionFunctionInvocation:
+		args := []string{}
+		if n.Arguments() != nil {
+			for _, arg := range n.Arguments().FunctionArguments() {
+				text := f.expression(arg.Expression(), precNone)
+				if arg.Name() != nil {
+					text = arg.Name().Text() + " = " + text
+				}
+				args = append(args, text)
+			}
+		}
+		return n.FunctionName() + "(" + strings.Join(args, ", ") + ")"
+	case ExpressionArrayInitializer:
+		elements := []string{}
+		for _, element := range n.Initializer().Elements() {
+			elements = append(elements, f.expression(element, precNone))
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case ExpressionTypeInitializer:
+		fields := []string{}
+		if list := n.Initializer().Fields(); list != nil {
+			for _, field := range list.Fields() {
+				fields = append(fields, field.Identifier().Text()+" = "+f.expression(field.Expression(), precNone))
+			}
+		}
+		return f.typeRef(n.TypeRef()) + "{" + strings.Join(fields, ", ") + "}"
+	case ExpressionLiteral:
+		return n.Value().Text()
+	case ExpressionIdentifier:
+		return n.Identifier().Text()
+	}
+	return ""
+}
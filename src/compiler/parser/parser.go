@@ -1,16 +1,37 @@
 package parser
 
 import (
+	"cmp"
 	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
 	"zenith/compiler"
 	"zenith/compiler/lexer"
 )
 
+// DefaultMaxRecursionDepth limits the nesting of (mutually) recursive expression rules
+// to protect the Go stack against pathological input.
+const DefaultMaxRecursionDepth = 1000
+
+// ParserOptions configures the parser
+type ParserOptions struct {
+	// MaxRecursionDepth limits the nesting of expression rules:
+	// 0 uses DefaultMaxRecursionDepth, a negative value disables the limit.
+	MaxRecursionDepth int
+}
+
 type parserContext struct {
 	source  *compiler.Source
 	tokens  lexer.TokenStream
 	current lexer.Token
 	errors  []*compiler.Diagnostic
+
+	stream []lexer.Token // all tokens read in stream order, including trivia
+
+	depth        int         // current recursion depth of expression rules
+	maxDepth     int         // maximum recursion depth before bailing out
+	depthErrorAt lexer.Token // token the last nesting error was reported at
 }
 
 func (ctx *parserContext) appendError(errors *[]*compiler.Diagnostic, msg string) {
@@ -60,6 +81,7 @@ func (ctx *parserContext) next(skipEOL bool) lexer.Token {
 			return nil
 		}
 		ctx.current = t
+		ctx.record(t)
 		id := t.Id()
 		if id == lexer.TokenUnknown {
 			ctx.error("unknown token: " + t.Text())
@@ -76,6 +98,14 @@ func (ctx *parserContext) next(skipEOL bool) lexer.Token {
 	}
 }
 
+// record keeps each token once, also when it is read again after backtracking
+func (ctx *parserContext) record(t lexer.Token) {
+	last := len(ctx.stream) - 1
+	if last < 0 || t.Location().Index > ctx.stream[last].Location().Index {
+		ctx.stream = append(ctx.stream, t)
+	}
+}
+
 // checks if the current token matches the given token Id
 func (ctx *parserContext) is(tokenId lexer.TokenId) bool {
 	return tokenId == ctx.current.Id()
@@ -91,6 +121,48 @@ func (ctx *parserContext) isAny(tokenIds []lexer.TokenId) bool {
 	return false
 }
 
+// tokens that start a statement and are safe to resume parsing at
+var statementStartTokens = []lexer.TokenId{
+	lexer.TokenIf, lexer.TokenFor, lexer.TokenDo, lexer.TokenSelect, lexer.TokenReturn, lexer.TokenAsm,
+}
+
+// synchronizeStatement skips tokens after a failed statement (panic-mode recovery)
+// until the end of the line, a closing '}' or the start of a known statement.
+// At least one token is consumed to guarantee progress.
+func (ctx *parserContext) synchronizeStatement() {
+	for {
+		if ctx.next(takeEOL) == nil || ctx.is(lexer.TokenEOF) {
+			return
+		}
+		if ctx.is(lexer.TokenEOL) {
+			ctx.next(skipEOL) // consume eol(s)
+			return
+		}
+		if ctx.is(lexer.TokenBracesClose) || ctx.isAny(statementStartTokens) {
+			return
+		}
+	}
+}
+
+// enterRecursion increments the recursion depth.
+// Returns false (and reports an error once per location) when the maximum depth is exceeded.
+// Only call leaveRecursion when enterRecursion returned true.
+func (ctx *parserContext) enterRecursion() bool {
+	if ctx.maxDepth > 0 && ctx.depth >= ctx.maxDepth {
+		// backtracking may hit the limit multiple times at the same token
+		if ctx.depthErrorAt != ctx.current {
+			ctx.depthErrorAt = ctx.current
+			ctx.error("expression nesting too deep")
+		}
+		return false
+	}
+	ctx.depth++
+	return true
+}
+func (ctx *parserContext) leaveRecursion() {
+	ctx.depth--
+}
+
 // calls each parse function in order until one returns a non-nil node
 // the token stream is rewound between each attempt
 // Prefers nodes without errors; if all have errors, returns the one with fewest errors
@@ -152,19 +224,149 @@ func collectErrors(node ParserNode, errors []*compiler.Diagnostic) []*compiler.D
 	return errors
 }
 
+// CollectDiagnostics returns the errors of the node and all its descendants in source order
+func CollectDiagnostics(root ParserNode) []*compiler.Diagnostic {
+	diagnostics := collectErrors(root, nil)
+	slices.SortStableFunc(diagnostics, func(a, b *compiler.Diagnostic) int {
+		return cmp.Compare(a.Location.Index, b.Location.Index)
+	})
+	return diagnostics
+}
+
+// isTrivia returns true for tokens without syntactic meaning
+func isTrivia(token lexer.Token) bool {
+	switch token.Id() {
+	case lexer.TokenWhitespace, lexer.TokenComment, lexer.TokenEOL:
+		return true
+	}
+	return false
+}
+
+// attachTrivia assigns the whitespace, comments and line endings in the token stream to the nodes.
+// Trivia up to and including the end of the line belongs to the preceding node (trailing),
+// trivia on the following lines belongs to the next node (leading).
+func attachTrivia(root ParserNode, stream []lexer.Token) {
+	positions := make(map[lexer.Token]int, len(stream))
+	for i, token := range stream {
+		positions[token] = i
+	}
+
+	var attach func(node ParserNode)
+	attach = func(node ParserNode) {
+		if node == nil {
+			return
+		}
+		data, ok := node.(interface{ nodeData() *parserNodeData })
+		first, last := significantPositions(node.Tokens(), positions)
+		if ok && first >= 0 {
+			data.nodeData().leadingTrivia = leadingTrivia(stream, first)
+			data.nodeData().trailingTrivia = trailingTrivia(stream, last)
+		}
+		for _, child := range node.Children() {
+			attach(child)
+		}
+	}
+	attach(root)
+}
+
+// significantPositions returns the stream positions of the first and last non-trivia tokens (-1 if none)
+func significantPositions(tokens []lexer.Token, positions map[lexer.Token]int) (first int, last int) {
+	first, last = -1, -1
+	for _, token := range tokens {
+		if isTrivia(token) || token.Id() == lexer.TokenEOF {
+			continue
+		}
+		if pos, ok := positions[token]; ok {
+			if first < 0 {
+				first = pos
+			}
+			last = pos
+		}
+	}
+	return first, last
+}
+
+func leadingTrivia(stream []lexer.Token, first int) []lexer.Token {
+	start := first
+	for start > 0 && isTrivia(stream[start-1]) {
+		start--
+	}
+	if start > 0 {
+		// the part up to the first eol trails the previous token
+		eol := start
+		for eol < first && stream[eol].Id() != lexer.TokenEOL {
+			eol++
+		}
+		if eol == first {
+			return nil
+		}
+		start = eol + 1
+	}
+	if start == first {
+		return nil
+	}
+	return stream[start:first]
+}
+
+func trailingTrivia(stream []lexer.Token, last int) []lexer.Token {
+	end := last + 1
+	for end < len(stream) && isTrivia(stream[end]) {
+		end++
+		if stream[end-1].Id() == lexer.TokenEOL {
+			break
+		}
+	}
+	if end == last+1 {
+		return nil
+	}
+	return stream[last+1 : end]
+}
+
 func Parse(source *compiler.Source, tokens lexer.TokenStream) (ParserNode, []*compiler.Diagnostic) {
-	ctx := parserContext{source, tokens, nil, make([]*compiler.Diagnostic, 0, 10)}
+	return ParseWithOptions(source, tokens, ParserOptions{})
+}
+
+// ParseWithOptions parses the tokens with the specified options
+func ParseWithOptions(source *compiler.Source, tokens lexer.TokenStream, options ParserOptions) (ParserNode, []*compiler.Diagnostic) {
+	maxDepth := options.MaxRecursionDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxRecursionDepth
+	}
+	ctx := parserContext{
+		source:   source,
+		tokens:   tokens,
+		errors:   make([]*compiler.Diagnostic, 0, 10),
+		maxDepth: maxDepth,
+	}
 	if ctx.next(skipEOL) != nil {
 		node := ctx.compilationUnit()
 
 		// Collect all errors from the AST nodes
 		allErrors := collectErrors(node, ctx.errors)
+		attachTrivia(node, ctx.stream)
 
 		return node, allErrors
 	}
 	return nil, ctx.errors
 }
 
+// ParseFile reads, tokenizes and parses the source from the reader.
+// The name is used to attribute diagnostics to the source file.
+// The error is only returned when reading fails; syntax errors are reported as diagnostics.
+func ParseFile(name string, r io.Reader) (ParserNode, []*compiler.Diagnostic, error) {
+	code, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading '%s': %w", name, err)
+	}
+
+	source := &compiler.Source{Name: filepath.Base(name), Path: name}
+	tokenizer := lexer.TokenizerFromString(string(code))
+	tokens := lexer.NewTokenStream(tokenizer.Tokens(), 1024)
+
+	node, diagnostics := Parse(source, tokens)
+	return node, diagnostics, nil
+}
+
 func DumpAST(ast CompilationUnit) {
 	fmt.Println("========== AST ==========")
 	fmt.Printf("Compilation Unit with %d declarations\n", len(ast.Declarations()))
@@ -0,0 +1,226 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper to format the code and check that formatting the result again yields the same text
+func formatCode(t *testing.T, testName string, code string) string {
+	formatted := Format(parseCode(t, testName, code))
+	reformatted := Format(parseCode(t, testName+"(formatted)", formatted))
+	require.Equal(t, formatted, reformatted, "formatting is not idempotent")
+	return formatted
+}
+
+func Test_FormatDeclarations(t *testing.T) {
+	code := `struct Point {
+		x: u8,
+		y:u8
+	}
+	count:u8=5
+	total :=count*2
+	buffer: u8[10]
+	ptr: u8*
+	add: (a:u8,b:u8) u8 {
+		ret a+b
+	}`
+	expected := "struct Point { x: u8, y: u8 }\n" +
+		"\n" +
+		"count: u8 = 5\n" +
+		"total := count * 2\n" +
+		"buffer: u8[10]\n" +
+		"ptr: u8*\n" +
+		"\n" +
+		"add: (a: u8, b: u8) u8 {\n" +
+		"\tret a + b\n" +
+		"}\n"
+
+	assert.Equal(t, expected, formatCode(t, "Test_FormatDeclarations", code))
+}
+
+func Test_FormatStatements(t *testing.T) {
+	code := `main: () {
+		if x > 5 {
+			x -= 1
+		} elsif x > 0 {
+			x = 0
+		} else {
+			ret
+		}
+		for i := 0; i < 10; i++ {
+			arr[i] = i
+		}
+		for k := 0; k < 10; k+=2 {
+			arr[k] = k
+		}
+		for j in 0 .. n+1 {
+			arr[j] = j
+		}
+		do {
+			x -= 1
+		} while x > 0
+		select x {
+			case 1 {
+				foo(1, 2)
+			}
+			else {
+			}
+		}
+	}`
+	expected := "main: () {\n" +
+		"\tif x > 5 {\n" +
+		"\t\tx -= 1\n" +
+		"\t} elsif x > 0 {\n" +
+		"\t\tx = 0\n" +
+		"\t} else {\n" +
+		"\t\tret\n" +
+		"\t}\n" +
+		"\tfor i := 0; i < 10; i++ {\n" +
+		"\t\tarr[i] = i\n" +
+		"\t}\n" +
+		"\tfor k := 0; k < 10; k += 2 {\n" +
+		"\t\tarr[k] = k\n" +
+		"\t}\n" +
+		"\tfor j in 0..n + 1 {\n" +
+		"\t\tarr[j] = j\n" +
+		"\t}\n" +
+		"\tdo {\n" +
+		"\t\tx -= 1\n" +
+		"\t} while x > 0\n" +
+		"\tselect x {\n" +
+		"\t\tcase 1 {\n" +
+		"\t\t\tfoo(1, 2)\n" +
+		"\t\t}\n" +
+		"\t\telse {\n" +
+		"\t\t}\n" +
+		"\t}\n" +
+		"}\n"
+
+	assert.Equal(t, expected, formatCode(t, "Test_FormatStatements", code))
+}
+
+func Test_FormatMultiAssignment(t *testing.T) {
+	code := `main: () {
+		a,b = b,a
+	}`
+	expected := "main: () {\n" +
+		"\ta, b = b, a\n" +
+		"}\n"
+
+	assert.Equal(t, expected, formatCode(t, "Test_FormatMultiAssignment", code))
+}
+
+func Test_FormatExpressionParentheses(t *testing.T) {
+	code := `main: () {
+		a := 1 + (2 & 3)
+		z := (1 + 2) & 3
+		b := ((1 + 2)) + 3
+		c := 1 - (2 - 3)
+		d := (x > 1) and (y < 2)
+		e := -(x + 1)
+		f := (p.items)[2].x
+		g := not (a or b)
+		h := Point{x = 1, y = (2)}
+		i := [1, 2, (3)]
+		j := (x << 1) & (y >> (2 + 1))
+	}`
+	expected := "main: () {\n" +
+		"\ta := 1 + (2 & 3)\n" +
+		"\tz := 1 + 2 & 3\n" +
+		"\tb := 1 + 2 + 3\n" +
+		"\tc := 1 - (2 - 3)\n" +
+		"\td := x > 1 and y < 2\n" +
+		"\te := -(x + 1)\n" +
+		"\tf := p.items[2].x\n" +
+		"\tg := not (a or b)\n" +
+		"\th := Point{x = 1, y = 2}\n" +
+		"\ti := [1, 2, 3]\n" +
+		"\tj := x << 1 & y >> 2 + 1\n" +
+		"}\n"
+
+	assert.Equal(t, expected, formatCode(t, "Test_FormatExpressionParentheses", code))
+}
+
+func Test_FormatComments(t *testing.T) {
+	code := `// the answer
+	answer: u8 = 42   // not 43
+	main: () {
+		// reset
+		answer = 0
+	}`
+	expected := "// the answer\n" +
+		"answer: u8 = 42 // not 43\n" +
+		"\n" +
+		"main: () {\n" +
+		"\t// reset\n" +
+		"\tanswer = 0\n" +
+		"}\n"
+
+	assert.Equal(t, expected, formatCode(t, "Test_FormatComments", code))
+}
+
+func Test_FormatNamedArguments(t *testing.T) {
+	code := `main: () {
+		move(x=1,y = a+1)
+		check((a == 1), a = (b == 2))
+	}`
+	expected := "main: () {\n" +
+		"\tmove(x = 1, y = a + 1)\n" +
+		"\tcheck(a == 1, a = b == 2)\n" +
+		"}\n"
+
+	assert.Equal(t, expected, formatCode(t, "Test_FormatNamedArguments", code))
+}
+
+func Test_FormatFunctionAttributes(t *testing.T) {
+	code := `@fast
+	handler: ( ) {
+	}`
+	expected := "@fast handler: () {\n" +
+		"}\n"
+	assert.Equal(t, expected, formatCode(t, "Test_FormatFunctionAttributes", code))
+}
+
+func Test_FormatImportDeclaration(t *testing.T) {
+	code := `import   "math"
+	main: ( ) {
+	}`
+	expected := "import \"math\"\n" +
+		"\n" +
+		"main: () {\n" +
+		"}\n"
+	assert.Equal(t, expected, formatCode(t, "Test_FormatImportDeclaration", code))
+}
+
+func Test_FormatExportDeclaration(t *testing.T) {
+	code := `export   double: ( v: u8 ) u8 {
+		ret v
+	}`
+	expected := "export double: (v: u8) u8 {\n" +
+		"\tret v\n" +
+		"}\n"
+	assert.Equal(t, expected, formatCode(t, "Test_FormatExportDeclaration", code))
+}
+
+func Test_FormatFunctionAttributeArgument(t *testing.T) {
+	code := `@org( 0x0038 )
+	handler: ( ) {
+	}`
+	expected := "@org(0x0038) handler: () {\n" +
+		"}\n"
+	assert.Equal(t, expected, formatCode(t, "Test_FormatFunctionAttributeArgument", code))
+}
+
+func Test_FormatInlineAsm(t *testing.T) {
+	code := `main: () {
+	asm { di ; halt }
+	}`
+	expected := "main: () {\n" +
+		"\tasm { di ; halt }\n" +
+		"}\n"
+
+	assert.Equal(t, expected, formatCode(t, "Test_FormatInlineAsm", code))
+}
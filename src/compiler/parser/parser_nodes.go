@@ -3,6 +3,8 @@ package parser
 import (
 	"reflect"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 	"zenith/compiler"
 	"zenith/compiler/lexer"
 )
@@ -12,6 +14,12 @@ type ParserNode interface {
 	Children() []ParserNode
 	Tokens() []lexer.Token
 	Errors() []*compiler.Diagnostic
+	// whitespace, comments and line endings before the node (on preceding lines)
+	LeadingTrivia() []lexer.Token
+	// whitespace, comments and line ending after the node (up to the end of the line)
+	TrailingTrivia() []lexer.Token
+	// source positions of the first character and just past the last character of the node (trivia excluded)
+	Span() (start, end compiler.Location)
 }
 
 // Base parser node data structure
@@ -20,6 +28,9 @@ type parserNodeData struct {
 	children []ParserNode
 	tokens   []lexer.Token
 	errors   []*compiler.Diagnostic
+
+	leadingTrivia  []lexer.Token
+	trailingTrivia []lexer.Token
 }
 
 func (n *parserNodeData) Children() []ParserNode {
@@ -38,6 +49,68 @@ func (n *parserNodeData) Source() *compiler.Source {
 	return n.source
 }
 
+func (n *parserNodeData) LeadingTrivia() []lexer.Token {
+	return n.leadingTrivia
+}
+
+func (n *parserNodeData) TrailingTrivia() []lexer.Token {
+	return n.trailingTrivia
+}
+
+func (n *parserNodeData) Span() (start, end compiler.Location) {
+	first, last := -1, -1
+	for i, token := range n.tokens {
+		if isTrivia(token) {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		last = i
+	}
+	if first >= 0 {
+		return n.tokens[first].Location(), tokenEnd(n.tokens[last])
+	}
+
+	// no tokens of its own: span the children
+	found := false
+	for _, child := range n.children {
+		childStart, childEnd := child.Span()
+		if childStart == childEnd {
+			continue
+		}
+		if !found {
+			start = childStart
+			found = true
+		}
+		end = childEnd
+	}
+	return start, end
+}
+
+// TokenSpan returns the source positions of the first character and just past the last character of the token
+func TokenSpan(token lexer.Token) (start, end compiler.Location) {
+	return token.Location(), tokenEnd(token)
+}
+
+// tokenEnd returns the source position just past the last character of the token
+func tokenEnd(token lexer.Token) compiler.Location {
+	end := token.Location()
+	text := token.Text()
+	end.Index += utf8.RuneCountInString(text)
+	if lineStart := strings.LastIndex(text, "\n"); lineStart >= 0 {
+		end.Line += strings.Count(text, "\n")
+		end.Column = utf8.RuneCountInString(text[lineStart+1:]) + 1
+	} else {
+		end.Column += utf8.RuneCountInString(text)
+	}
+	return end
+}
+
+func (n *parserNodeData) nodeData() *parserNodeData {
+	return n
+}
+
 func (n *parserNodeData) tokensOf(tokenId lexer.TokenId) []lexer.Token {
 	result := make([]lexer.Token, 0)
 	for i := 0; i < len(n.tokens); i++ {
@@ -47,6 +120,12 @@ func (n *parserNodeData) tokensOf(tokenId lexer.TokenId) []lexer.Token {
 	}
 	return result
 }
+
+// isExported checks if the declaration starts with the 'export' marker
+func (n *parserNodeData) isExported() bool {
+	return len(n.tokens) > 0 && n.tokens[0].Id() == lexer.TokenExport
+}
+
 func (n *parserNodeData) childrenOf(t reflect.Type) []interface{} {
 	result := make([]interface{}, 0)
 	for i := 0; i < len(n.children); i++ {
@@ -83,6 +162,36 @@ func (n *compilationUnit) Declarations() []ParserNode {
 	return n.parserNodeData.children
 }
 
+// ============================================================================
+// import_declaration: 'import' string
+// ============================================================================
+
+type ImportDeclaration interface {
+	ParserNode
+	// ModuleName returns the imported module name without quotes ('import "math"' => 'math')
+	ModuleName() string
+}
+
+type importDeclaration struct {
+	parserNodeData
+}
+
+func (n *importDeclaration) Children() []ParserNode {
+	return n.parserNodeData.Children()
+}
+
+func (n *importDeclaration) Tokens() []lexer.Token {
+	return n.parserNodeData.Tokens()
+}
+
+func (n *importDeclaration) ModuleName() string {
+	tokens := n.parserNodeData.tokensOf(lexer.TokenString)
+	if len(tokens) == 0 {
+		return ""
+	}
+	return strings.Trim(tokens[0].Text(), "\"")
+}
+
 // ============================================================================
 // code_block: (statement | expression_statement | function_invocation | variable_declaration | variable_assignment)*
 // ============================================================================
@@ -114,6 +223,8 @@ func (n *codeBlock) Statements() []ParserNode {
 
 type VariableDeclaration interface {
 	ParserNode
+	// IsExported returns true for 'export' declarations (usable by other modules)
+	IsExported() bool
 	Label() Label
 	TypeRef() TypeRef
 	Initializer() Expression
@@ -131,6 +242,10 @@ func (n *variableDeclaration) Tokens() []lexer.Token {
 	return n.parserNodeData.Tokens()
 }
 
+func (n *variableDeclaration) IsExported() bool {
+	return n.parserNodeData.isExported()
+}
+
 func (n *variableDeclaration) Label() Label {
 	children := n.parserNodeData.childrenOf(reflect.TypeFor[Label]())
 	if len(children) > 0 {
@@ -156,18 +271,23 @@ func (n *variableDeclaration) Initializer() Expression {
 }
 
 // ============================================================================
-// variable_assignment: identifier (operator_arithmetic | operator_bitwise)? '=' expression
+// variable_assignment: expression_postfix (operator_arithmetic | operator_bitwise)? '=' expression
 // ============================================================================
 
 type VariableAssignment interface {
 	ParserNode
+	// Identifier is the (first) identifier of the target
 	Identifier() lexer.Token
 	Operator() lexer.Token
+	// Target is the assigned lvalue: the first child
+	Target() Expression
+	// Expression is the assigned value (rvalue), not the target: the second child
 	Expression() Expression
 }
 
 type variableAssignment struct {
 	parserNodeData
+	operator lexer.Token // compound operator, nil for '='
 }
 
 func (n *variableAssignment) Children() []ParserNode {
@@ -186,31 +306,81 @@ func (n *variableAssignment) Identifier() lexer.Token {
 	return nil
 }
 
+// Operator returns the operator of a compound assignment (+=), nil for '='
 func (n *variableAssignment) Operator() lexer.Token {
-	// Return compound operator token if present
-	for _, token := range n.parserNodeData.tokens {
-		switch token.Id() {
-		case lexer.TokenPlus, lexer.TokenMinus, lexer.TokenAsterisk, lexer.TokenSlash,
-			lexer.TokenAmpersant, lexer.TokenPipe, lexer.TokenCaret:
-			return token
-		}
+	return n.operator
+}
+
+// Target returns the assigned lvalue (identifier, subscript or member access)
+func (n *variableAssignment) Target() Expression {
+	if len(n.parserNodeData.children) > 0 {
+		return n.parserNodeData.children[0].(Expression)
 	}
 	return nil
 }
 
+// Expression returns the assigned value (rvalue)
 func (n *variableAssignment) Expression() Expression {
-	if len(n.parserNodeData.children) > 0 {
-		return n.parserNodeData.children[0].(Expression)
+	if len(n.parserNodeData.children) > 1 {
+		return n.parserNodeData.children[1].(Expression)
 	}
 	return nil
 }
 
+// ============================================================================
+// multi_assignment: expression_postfix (',' expression_postfix)+ '=' expression (',' expression)+
+// ============================================================================
+
+type MultiAssignment interface {
+	ParserNode
+	Targets() []Expression
+	Values() []Expression
+}
+
+type multiAssignment struct {
+	parserNodeData
+	targetCount int // the children are the targets followed by the values
+}
+
+func (n *multiAssignment) Children() []ParserNode {
+	return n.parserNodeData.Children()
+}
+
+func (n *multiAssignment) Tokens() []lexer.Token {
+	return n.parserNodeData.Tokens()
+}
+
+// Targets returns the assigned lvalues (identifier, subscript or member access)
+func (n *multiAssignment) Targets() []Expression {
+	return expressionsOf(n.parserNodeData.children[:n.targetCount])
+}
+
+// Values returns the assigned values (rvalues), in the order of the targets
+func (n *multiAssignment) Values() []Expression {
+	return expressionsOf(n.parserNodeData.children[n.targetCount:])
+}
+
+func expressionsOf(nodes []ParserNode) []Expression {
+	expressions := make([]Expression, len(nodes))
+	for i, node := range nodes {
+		expressions[i] = node.(Expression)
+	}
+	return expressions
+}
+
 // ============================================================================
 // function_declaration: label '(' declaration_fieldlist? ')' type_ref? '{' code_block '}'
 // ============================================================================
 
 type FunctionDeclaration interface {
 	ParserNode
+	// Attributes returns the attribute names ('@fast' => 'fast') in source order
+	Attributes() []lexer.Token
+	// IsExported returns true for 'export' declarations (usable by other modules)
+	IsExported() bool
+	// AttributeArgument returns the argument of the attribute ('@org(0x38)' => 0x38)
+	// or nil if the attribute is not present or has no argument
+	AttributeArgument(name string) Expression
 	Label() Label
 	Parameters() DeclarationFieldList
 	ReturnType() TypeRef
@@ -219,6 +389,8 @@ type FunctionDeclaration interface {
 
 type functionDeclaration struct {
 	parserNodeData
+	attributes    []lexer.Token
+	attributeArgs []Expression // parallel to attributes, nil when no argument
 }
 
 func (n *functionDeclaration) Children() []ParserNode {
@@ -229,6 +401,23 @@ func (n *functionDeclaration) Tokens() []lexer.Token {
 	return n.parserNodeData.Tokens()
 }
 
+func (n *functionDeclaration) Attributes() []lexer.Token {
+	return n.attributes
+}
+
+func (n *functionDeclaration) IsExported() bool {
+	return n.parserNodeData.isExported()
+}
+
+func (n *functionDeclaration) AttributeArgument(name string) Expression {
+	for i, attribute := range n.attributes {
+		if attribute.Text() == name && i < len(n.attributeArgs) {
+			return n.attributeArgs[i]
+		}
+	}
+	return nil
+}
+
 func (n *functionDeclaration) Label() Label {
 	children := n.parserNodeData.childrenOf(reflect.TypeFor[Label]())
 	if len(children) > 0 {
@@ -262,12 +451,15 @@ func (n *functionDeclaration) Body() CodeBlock {
 }
 
 // ============================================================================
-// function_argumentList: (expression (',' expression)*)?
+// function_argumentList: (function_argument (',' function_argument)*)?
 // ============================================================================
 
 type FunctionArgumentList interface {
 	ParserNode
+	// Arguments returns the argument expressions in source order
 	Arguments() []Expression
+	// FunctionArguments returns the argument nodes, including their optional parameter name
+	FunctionArguments() []FunctionArgument
 }
 
 type functionArgumentList struct {
@@ -283,7 +475,51 @@ func (n *functionArgumentList) Tokens() []lexer.Token {
 }
 
 func (n *functionArgumentList) Arguments() []Expression {
-	return compiler.OfType[Expression](n.parserNodeData.children)
+	args := n.FunctionArguments()
+	expressions := make([]Expression, 0, len(args))
+	for _, arg := range args {
+		expressions = append(expressions, arg.Expression())
+	}
+	return expressions
+}
+
+func (n *functionArgumentList) FunctionArguments() []FunctionArgument {
+	return compiler.OfType[FunctionArgument](n.parserNodeData.children)
+}
+
+// ============================================================================
+// function_argument: (identifier '=')? expression
+// ============================================================================
+
+type FunctionArgument interface {
+	ParserNode
+	// Name returns the parameter name of a named argument (nil when positional)
+	Name() lexer.Token
+	Expression() Expression
+}
+
+type functionArgument struct {
+	parserNodeData
+	name lexer.Token
+}
+
+func (n *functionArgument) Children() []ParserNode {
+	return n.parserNodeData.Children()
+}
+
+func (n *functionArgument) Tokens() []lexer.Token {
+	return n.parserNodeData.Tokens()
+}
+
+func (n *functionArgument) Name() lexer.Token {
+	return n.name
+}
+
+func (n *functionArgument) Expression() Expression {
+	if len(n.parserNodeData.children) > 0 {
+		return n.parserNodeData.children[0].(Expression)
+	}
+	return nil
 }
 
 // ============================================================================
@@ -292,6 +528,8 @@ func (n *functionArgumentList) Arguments() []Expression {
 
 type TypeDeclaration interface {
 	ParserNode
+	// IsExported returns true for 'export' declarations (usable by other modules)
+	IsExported() bool
 	Name() lexer.Token
 	Fields() TypeDeclarationFields
 }
@@ -308,6 +546,10 @@ func (n *typeDeclaration) Tokens() []lexer.Token {
 	return n.parserNodeData.Tokens()
 }
 
+func (n *typeDeclaration) IsExported() bool {
+	return n.parserNodeData.isExported()
+}
+
 func (n *typeDeclaration) Name() lexer.Token {
 	tokens := n.parserNodeData.tokensOf(lexer.TokenIdentifier)
 	if len(tokens) > 0 {
@@ -617,7 +859,7 @@ func (n *declarationField) TypeRef() TypeRef {
 }
 
 // ============================================================================
-// statement: statement_if | statement_for | statement_select | statement_expression
+// statement: statement_if | statement_for | statement_do_while | statement_select | statement_expression
 // ============================================================================
 
 type Statement interface {
@@ -650,6 +892,7 @@ type StatementIf interface {
 
 type statementIf struct {
 	parserNodeData
+	elseBlock CodeBlock // set when an 'else' was parsed, nil otherwise
 }
 
 func (n *statementIf) Children() []ParserNode {
@@ -669,9 +912,8 @@ func (n *statementIf) Condition() Expression {
 }
 
 func (n *statementIf) ThenBlock() CodeBlock {
-	children := n.parserNodeData.childrenOf(reflect.TypeFor[CodeBlock]())
-	if len(children) > 0 {
-		return children[0].(CodeBlock)
+	if block, ok := compiler.OfTypeN[*codeBlock](n.parserNodeData.children, 0); ok {
+		return block
 	}
 	return nil
 }
@@ -681,12 +923,7 @@ func (n *statementIf) ElsifClauses() []StatementElsif {
 }
 
 func (n *statementIf) ElseBlock() CodeBlock {
-	// The else block is distinct from the main then block
-	blocks := compiler.OfTypeInterface[*codeBlock, CodeBlock](n.parserNodeData.children)
-	if len(blocks) > 1 {
-		return blocks[len(blocks)-1]
-	}
-	return nil
+	return n.elseBlock
 }
 
 // ============================================================================
@@ -719,21 +956,22 @@ func (n *statementElsif) Condition() Expression {
 }
 
 func (n *statementElsif) ThenBlock() CodeBlock {
-	if len(n.parserNodeData.children) > 1 {
-		return n.parserNodeData.children[1].(CodeBlock)
+	if block, ok := compiler.OfTypeN[*codeBlock](n.parserNodeData.children, 0); ok {
+		return block
 	}
 	return nil
 }
 
 // ============================================================================
-// statement_for: 'for' (statement_for_init ';')? expression (';' expression)? '{' code_block '}'
+// statement_for: 'for' (statement_for_init ';')? expression (';' (variable_assignment | expression))? '{' code_block '}'
 // ============================================================================
 
 type StatementFor interface {
 	ParserNode
 	Initializer() ParserNode
 	Condition() Expression
-	Increment() Expression
+	// Increment is an Expression (i++) or a VariableAssignment (i += 2)
+	Increment() ParserNode
 	Body() CodeBlock
 }
 
@@ -771,10 +1009,16 @@ func (n *statementFor) Condition() Expression {
 	return nil
 }
 
-func (n *statementFor) Increment() Expression {
-	expressions := compiler.OfType[Expression](n.parserNodeData.children)
-	if len(expressions) > 1 {
-		return expressions[1]
+func (n *statementFor) Increment() ParserNode {
+	// the child between the condition and the body
+	condition := n.Condition()
+	for i, child := range n.parserNodeData.children {
+		if child != condition || i+1 >= len(n.parserNodeData.children) {
+			continue
+		}
+		if increment := n.parserNodeData.children[i+1]; increment != n.Body() {
+			return increment
+		}
 	}
 	return nil
 }
@@ -787,6 +1031,97 @@ func (n *statementFor) Body() CodeBlock {
 	return nil
 }
 
+// ============================================================================
+// statement_for_range: 'for' identifier 'in' expression '..' expression '{' code_block '}'
+// ============================================================================
+
+type StatementForRange interface {
+	ParserNode
+	// Variable is the loop variable declared by the loop
+	Variable() lexer.Token
+	// From is the first value of the range (inclusive)
+	From() Expression
+	// To is the end of the range (exclusive)
+	To() Expression
+	Body() CodeBlock
+}
+
+type statementForRange struct {
+	parserNodeData
+	variable lexer.Token
+}
+
+func (n *statementForRange) Children() []ParserNode {
+	return n.parserNodeData.Children()
+}
+
+func (n *statementForRange) Tokens() []lexer.Token {
+	return n.parserNodeData.Tokens()
+}
+
+func (n *statementForRange) Variable() lexer.Token {
+	return n.variable
+}
+
+func (n *statementForRange) From() Expression {
+	if expr, ok := compiler.OfTypeN[Expression](n.parserNodeData.children, 0); ok {
+		return expr
+	}
+	return nil
+}
+
+func (n *statementForRange) To() Expression {
+	if expr, ok := compiler.OfTypeN[Expression](n.parserNodeData.children, 1); ok {
+		return expr
+	}
+	return nil
+}
+
+func (n *statementForRange) Body() CodeBlock {
+	if block, ok := compiler.OfTypeN[*codeBlock](n.parserNodeData.children, 0); ok {
+		return block
+	}
+	return nil
+}
+
+// ============================================================================
+// statement_do_while: 'do' '{' code_block '}' 'while' expression
+// ============================================================================
+
+type StatementDoWhile interface {
+	ParserNode
+	Body() CodeBlock
+	Condition() Expression
+}
+
+type statementDoWhile struct {
+	parserNodeData
+}
+
+func (n *statementDoWhile) Children() []ParserNode {
+	return n.parserNodeData.Children()
+}
+
+func (n *statementDoWhile) Tokens() []lexer.Token {
+	return n.parserNodeData.Tokens()
+}
+
+func (n *statementDoWhile) Body() CodeBlock {
+	children := compiler.OfTypeInterface[*codeBlock, CodeBlock](n.parserNodeData.children)
+	if len(children) > 0 {
+		return children[0]
+	}
+	return nil
+}
+
+func (n *statementDoWhile) Condition() Expression {
+	expressions := compiler.OfType[Expression](n.parserNodeData.children)
+	if len(expressions) > 0 {
+		return expressions[0]
+	}
+	return nil
+}
+
 // ============================================================================
 // statement_select: 'select' expression '{' statement_select_cases statement_select_else? '}'
 // ============================================================================
@@ -951,6 +1286,36 @@ func (n *statementReturn) Value() Expression {
 	return nil
 }
 
+// ============================================================================
+// statement_asm
+// ============================================================================
+
+type InlineAsm interface {
+	ParserNode
+	Text() string // raw assembly text, passed through verbatim
+}
+
+type inlineAsm struct {
+	parserNodeData
+}
+
+func (n *inlineAsm) Children() []ParserNode {
+	return n.parserNodeData.Children()
+}
+
+func (n *inlineAsm) Tokens() []lexer.Token {
+	return n.parserNodeData.Tokens()
+}
+
+func (n *inlineAsm) Text() string {
+	for _, token := range n.parserNodeData.Tokens() {
+		if token.Id() == lexer.TokenAsmText {
+			return token.Text()
+		}
+	}
+	return ""
+}
+
 // ============================================================================
 // expression (base interface for all expression types)
 // ============================================================================
@@ -962,6 +1327,7 @@ const (
 	ExprMemberAccess
 	ExprSubscript
 	ExprBinaryArithmetic
+	ExprBinaryShift
 	ExprBinaryBitwise
 	ExprBinaryComparison
 	ExprBinaryLogical
@@ -1191,6 +1557,42 @@ func (n *expressionOperatorBinArithmetic) ExpressionKind() ExpressionKind {
 	return ExprBinaryArithmetic
 }
 
+// ============================================================================
+// expression_operator_bin_shift: expression ('<<' | '>>') expression
+// ============================================================================
+
+type ExpressionOperatorBinShift interface {
+	ExpressionOperatorBinary
+}
+
+type expressionOperatorBinShift struct {
+	expressionOperatorBinary
+}
+
+func (n *expressionOperatorBinShift) Children() []ParserNode {
+	return n.expressionOperatorBinary.Children()
+}
+
+func (n *expressionOperatorBinShift) Tokens() []lexer.Token {
+	return n.expressionOperatorBinary.Tokens()
+}
+
+func (n *expressionOperatorBinShift) Left() Expression {
+	return n.expressionOperatorBinary.Left()
+}
+
+func (n *expressionOperatorBinShift) Right() Expression {
+	return n.expressionOperatorBinary.Right()
+}
+
+func (n *expressionOperatorBinShift) Operator() lexer.Token {
+	return n.expressionOperatorBinary.Operator()
+}
+
+func (n *expressionOperatorBinShift) ExpressionKind() ExpressionKind {
+	return ExprBinaryShift
+}
+
 // ============================================================================
 // expression_operator_bin_bitwise: expression operator_bitwise expression
 // ============================================================================
@@ -1564,6 +1966,8 @@ func (n *expressionOperatorUnipostLogical) ExpressionKind() ExpressionKind {
 type ExpressionFunctionInvocation interface {
 	Expression
 	FunctionName() string
+	// the function name identifier (without the '@' of an intrinsic)
+	Name() lexer.Token
 	Arguments() FunctionArgumentList
 	IsIntrinsic() bool
 }
@@ -1596,6 +2000,14 @@ func (n *expressionFunctionInvocation) FunctionName() string {
 	return ""
 }
 
+func (n *expressionFunctionInvocation) Name() lexer.Token {
+	tokens := n.parserNodeData.tokensOf(lexer.TokenIdentifier)
+	if len(tokens) > 0 {
+		return tokens[0]
+	}
+	return nil
+}
+
 func (n *expressionFunctionInvocation) Arguments() FunctionArgumentList {
 	if len(n.parserNodeData.children) > 0 {
 		if args, ok := n.parserNodeData.children[0].(FunctionArgumentList); ok {
@@ -1689,6 +2101,8 @@ type ExpressionLiteral interface {
 	Expression
 	Value() lexer.Token
 	Number() int
+	// NumberSuffix is the type suffix of the number (42u16), empty when it has none
+	NumberSuffix() string
 	String() string
 }
 
@@ -1717,13 +2131,22 @@ func (n *expressionLiteral) Value() lexer.Token {
 
 func (n *expressionLiteral) Number() int {
 	if token := n.Value(); token != nil && token.Id() == lexer.TokenNumber {
-		if num, err := strconv.ParseInt(token.Text(), 0, 64); err == nil {
+		number, _ := lexer.SplitNumberSuffix(token.Text())
+		if num, err := strconv.ParseInt(number, 0, 64); err == nil {
 			return int(num)
 		}
 	}
 	return 0
 }
 
+func (n *expressionLiteral) NumberSuffix() string {
+	if token := n.Value(); token != nil && token.Id() == lexer.TokenNumber {
+		_, suffix := lexer.SplitNumberSuffix(token.Text())
+		return suffix
+	}
+	return ""
+}
+
 func (n *expressionLiteral) String() string {
 	if token := n.Value(); token != nil && token.Id() == lexer.TokenString {
 		return token.Text()
@@ -6,7 +6,7 @@ import (
 )
 
 // ============================================================================
-// compilationUnit: (variable_declaration | function_declaration | type_declaration)*
+// compilationUnit: (import_declaration | variable_declaration | function_declaration | type_declaration)*
 // ============================================================================
 
 func (ctx *parserContext) compilationUnit() ParserNode {
@@ -15,6 +15,7 @@ func (ctx *parserContext) compilationUnit() ParserNode {
 
 	for {
 		node := ctx.parseOr([]func() ParserNode{
+			ctx.importDeclaration,
 			ctx.variableDeclaration,
 			ctx.functionDeclaration,
 			ctx.typeDeclaration,
@@ -35,7 +36,44 @@ func (ctx *parserContext) compilationUnit() ParserNode {
 }
 
 // ============================================================================
-// code_block: (statement | expression_statement | function_invocation | variable_declaration | variable_assignment)*
+// import_declaration: 'import' string
+// ============================================================================
+
+func (ctx *parserContext) importDeclaration() ParserNode {
+	mark := ctx.mark()
+
+	if !ctx.is(lexer.TokenImport) {
+		ctx.gotoMark(mark)
+		return nil
+	}
+	ctx.next(skipEOL) // consume 'import'
+
+	errors := make([]*compiler.Diagnostic, 0)
+	if !ctx.is(lexer.TokenString) {
+		ctx.appendError(&errors, "expected module name string after 'import'")
+	} else {
+		ctx.next(skipEOL) // consume module name
+	}
+
+	return &importDeclaration{
+		parserNodeData: parserNodeData{
+			source:   ctx.source,
+			children: []ParserNode{},
+			tokens:   ctx.fromMark(mark),
+			errors:   errors,
+		},
+	}
+}
+
+// optionalExport consumes the 'export' marker in front of a declaration
+func (ctx *parserContext) optionalExport() {
+	if ctx.is(lexer.TokenExport) {
+		ctx.next(skipEOL) // consume 'export'
+	}
+}
+
+// ============================================================================
+// code_block: (statement | expression_statement | function_invocation | variable_declaration | variable_assignment | multi_assignment)*
 // ============================================================================
 
 func (ctx *parserContext) codeBlock() ParserNode {
@@ -53,12 +91,19 @@ func (ctx *parserContext) codeBlock() ParserNode {
 		node := ctx.parseOr([]func() ParserNode{
 			ctx.variableDeclaration,
 			ctx.variableAssignment,
+			ctx.multiAssignment,
 			// leave statement last.
 			ctx.statement,
 		})
 		if node == nil {
-			// not an error, empty block is valid
-			break
+			// empty block is valid
+			if ctx.is(lexer.TokenBracesClose) || ctx.is(lexer.TokenEOF) {
+				break
+			}
+			// error recovery: report and continue at the next statement
+			ctx.appendError(&errors, "invalid statement at '"+ctx.current.Text()+"'")
+			ctx.synchronizeStatement()
+			continue
 		}
 		children = append(children, node)
 	}
@@ -85,6 +130,7 @@ func (ctx *parserContext) codeBlock() ParserNode {
 // variable_declaration: label type_ref? ('=' expression)?
 func (ctx *parserContext) variableDeclaration() ParserNode {
 	mark := ctx.mark()
+	ctx.optionalExport()
 
 	labelNode := ctx.label()
 	if labelNode == nil {
@@ -141,10 +187,12 @@ func (ctx *parserContext) variableAssignment() ParserNode {
 	}
 
 	// Optional compound operator
+	var operator lexer.Token
 	if ctx.isAny([]lexer.TokenId{
 		lexer.TokenPlus, lexer.TokenMinus, lexer.TokenAsterisk, lexer.TokenSlash,
 		lexer.TokenAmpersant, lexer.TokenPipe, lexer.TokenCaret,
 	}) {
+		operator = ctx.current
 		ctx.next(skipEOL) // consume operator
 	}
 
@@ -166,15 +214,120 @@ func (ctx *parserContext) variableAssignment() ParserNode {
 			children: []ParserNode{lvalue, rvalue},
 			tokens:   ctx.fromMark(mark),
 		},
+		operator: operator,
+	}
+}
+
+// ============================================================================
+// multi_assignment: expression_postfix (',' expression_postfix)+ '=' expression (',' expression)+
+// Note: the number of targets and values is checked by the semantic analysis
+// ============================================================================
+
+func (ctx *parserContext) multiAssignment() ParserNode {
+	mark := ctx.mark()
+
+	targets := []ParserNode{}
+	for {
+		lvalue := ctx.expressionPostfix()
+		if lvalue == nil {
+			ctx.gotoMark(mark)
+			return nil
+		}
+		targets = append(targets, lvalue)
+		if !ctx.is(lexer.TokenComma) {
+			break
+		}
+		ctx.next(skipEOL) // consume ','
+	}
+
+	if len(targets) < 2 || !ctx.is(lexer.TokenEquals) {
+		ctx.gotoMark(mark)
+		return nil
+	}
+	ctx.next(skipEOL) // consume '='
+
+	values := []ParserNode{}
+	errors := make([]*compiler.Diagnostic, 0)
+	for {
+		rvalue := ctx.expression()
+		if rvalue == nil {
+			if len(values) == 0 {
+				ctx.gotoMark(mark)
+				return nil
+			}
+			ctx.appendError(&errors, "expected expression after ','")
+			break
+		}
+		values = append(values, rvalue)
+		if !ctx.is(lexer.TokenComma) {
+			break
+		}
+		ctx.next(skipEOL) // consume ','
+	}
+
+	return &multiAssignment{
+		parserNodeData: parserNodeData{
+			source:   ctx.source,
+			children: append(targets, values...),
+			tokens:   ctx.fromMark(mark),
+			errors:   errors,
+		},
+		targetCount: len(targets),
 	}
 }
 
+// conditionAssignment reports an assignment (x = 5, x += 1) where a condition is expected.
+// Returns true when the assignment was consumed.
+func (ctx *parserContext) conditionAssignment(errors *[]*compiler.Diagnostic) bool {
+	mark := ctx.mark()
+	assignment, ok := ctx.variableAssignment().(VariableAssignment)
+	ctx.gotoMark(mark)
+	if !ok {
+		return false
+	}
+
+	if assignment.Operator() == nil {
+		ctx.appendError(errors, "assignment '=' cannot be used as a condition, use '==' to compare")
+	} else {
+		ctx.appendError(errors, "assignment '"+assignment.Operator().Text()+"=' cannot be used as a condition")
+	}
+	ctx.variableAssignment()
+	return true
+}
+
 // ============================================================================
-// function_declaration: label '(' declaration_fieldlist? ')' type_ref? '{' code_block '}'
+// function_declaration: function_attribute* label '(' declaration_fieldlist? ')' type_ref? '{' code_block '}'
 // ============================================================================
 
 func (ctx *parserContext) functionDeclaration() ParserNode {
 	mark := ctx.mark()
+	ctx.optionalExport()
+
+	// Optional attributes: '@' identifier ('(' expression ')')?
+	attributes := []lexer.Token{}
+	attributeArgs := []Expression{}
+	for ctx.is(lexer.TokenAtSign) {
+		ctx.next(skipEOL) // consume '@'
+		if !ctx.is(lexer.TokenIdentifier) {
+			ctx.gotoMark(mark)
+			return nil
+		}
+		attributes = append(attributes, ctx.current)
+		ctx.next(skipEOL) // consume identifier
+
+		var argument Expression
+		if ctx.is(lexer.TokenParenOpen) {
+			ctx.next(skipEOL) // consume '('
+			expr, ok := ctx.expression().(Expression)
+			if !ok || !ctx.is(lexer.TokenParenClose) {
+				ctx.gotoMark(mark)
+				return nil
+			}
+			argument = expr
+			ctx.next(skipEOL) // consume ')'
+		}
+		attributeArgs = append(attributeArgs, argument)
+	}
 
 	labelNode := ctx.label()
 	if labelNode == nil {
@@ -226,6 +379,8 @@ func (ctx *parserContext) functionDeclaration() ParserNode {
 			tokens:   ctx.fromMark(mark),
 			errors:   errors,
 		},
+		attributes:    attributes,
+		attributeArgs: attributeArgs,
 	}
 }
 
@@ -282,27 +437,27 @@ func (ctx *parserContext) functionInvocation() ParserNode {
 	}
 }
 
-// function_argumentList: (expression (',' expression)*)?
+// function_argumentList: (function_argument (',' function_argument)*)?
 func (ctx *parserContext) functionArgumentList() ParserNode {
 	mark := ctx.mark()
 	children := []ParserNode{}
 
-	expr := ctx.expression()
-	if expr == nil {
+	arg := ctx.functionArgument()
+	if arg == nil {
 		ctx.gotoMark(mark)
 		return nil
 	}
-	children = append(children, expr)
+	children = append(children, arg)
 
 	errors := make([]*compiler.Diagnostic, 0)
 	for ctx.is(lexer.TokenComma) {
 		ctx.next(skipEOL) // consume ','
-		expr := ctx.expression()
-		if expr == nil {
+		arg := ctx.functionArgument()
+		if arg == nil {
 			ctx.appendError(&errors, "expected expression after ','")
 			break
 		}
-		children = append(children, expr)
+		children = append(children, arg)
 	}
 
 	return &functionArgumentList{
@@ -315,12 +470,47 @@ func (ctx *parserContext) functionArgumentList() ParserNode {
 	}
 }
 
+// function_argument: (identifier '=')? expression
+func (ctx *parserContext) functionArgument() ParserNode {
+	mark := ctx.mark()
+
+	// named argument: 'name = expression'
+	var name lexer.Token
+	if ctx.is(lexer.TokenIdentifier) {
+		name = ctx.current
+		ctx.next(skipEOL) // consume identifier
+		if ctx.is(lexer.TokenEquals) {
+			ctx.next(skipEOL) // consume '='
+		} else {
+			// positional argument starting with an identifier
+			name = nil
+			ctx.gotoMark(mark)
+		}
+	}
+
+	expr := ctx.expression()
+	if expr == nil {
+		ctx.gotoMark(mark)
+		return nil
+	}
+
+	return &functionArgument{
+		parserNodeData: parserNodeData{
+			source:   ctx.source,
+			children: []ParserNode{expr},
+			tokens:   ctx.fromMark(mark),
+		},
+		name: name,
+	}
+}
+
 // ============================================================================
 // type_declaration: 'struct' identifier type_declaration_fields
 // ============================================================================
 
 func (ctx *parserContext) typeDeclaration() ParserNode {
 	mark := ctx.mark()
+	ctx.optionalExport()
 
 	if !ctx.is(lexer.TokenStruct) {
 		ctx.gotoMark(mark)
@@ -719,15 +909,18 @@ func (ctx *parserContext) declarationField() ParserNode {
 }
 
 // ============================================================================
-// statement: statement_if | statement_for | statement_select | statement_expression
+// statement: statement_if | statement_for_range | statement_for | statement_do_while | statement_select | statement_return | statement_asm | statement_expression
 // ============================================================================
 
 func (ctx *parserContext) statement() ParserNode {
 	return ctx.parseOr([]func() ParserNode{
 		ctx.statementIf,
+		ctx.statementForRange,
 		ctx.statementFor,
+		ctx.statementDoWhile,
 		ctx.statementSelect,
 		ctx.statementReturn,
+		ctx.statementAsm,
 		ctx.statementExpression,
 	})
 }
@@ -747,11 +940,13 @@ func (ctx *parserContext) statementIf() ParserNode {
 
 	errors := make([]*compiler.Diagnostic, 0)
 	children := []ParserNode{}
-	condition := ctx.expression()
-	if condition == nil {
-		ctx.appendError(&errors, "expected condition after 'if'")
-	} else {
-		children = append(children, condition)
+	if !ctx.conditionAssignment(&errors) {
+		condition := ctx.expression()
+		if condition == nil {
+			ctx.appendError(&errors, "expected condition after 'if'")
+		} else {
+			children = append(children, condition)
+		}
 	}
 
 	thenBlock := ctx.codeBlock()
@@ -770,13 +965,14 @@ func (ctx *parserContext) statementIf() ParserNode {
 	}
 
 	// Optional else clause
+	var elseBlock CodeBlock
 	if ctx.is(lexer.TokenElse) {
 		ctx.next(skipEOL) // consume 'else'
-		elseBlock := ctx.codeBlock()
-		if elseBlock == nil {
+		if block := ctx.codeBlock(); block == nil {
 			ctx.appendError(&errors, "expected code block after 'else'")
 		} else {
-			children = append(children, elseBlock)
+			elseBlock = block.(CodeBlock)
+			children = append(children, block)
 		}
 	}
 
@@ -787,6 +983,7 @@ func (ctx *parserContext) statementIf() ParserNode {
 			tokens:   ctx.fromMark(mark),
 			errors:   errors,
 		},
+		elseBlock: elseBlock,
 	}
 }
 
@@ -802,11 +999,13 @@ func (ctx *parserContext) statementElsif() ParserNode {
 
 	errors := make([]*compiler.Diagnostic, 0)
 	children := []ParserNode{}
-	condition := ctx.expression()
-	if condition == nil {
-		ctx.appendError(&errors, "expected condition after 'elsif'")
-	} else {
-		children = append(children, condition)
+	if !ctx.conditionAssignment(&errors) {
+		condition := ctx.expression()
+		if condition == nil {
+			ctx.appendError(&errors, "expected condition after 'elsif'")
+		} else {
+			children = append(children, condition)
+		}
 	}
 
 	block := ctx.codeBlock()
@@ -827,7 +1026,7 @@ func (ctx *parserContext) statementElsif() ParserNode {
 }
 
 // ============================================================================
-// statement_for: 'for' (statement_for_init ';')? expression (';' expression)? '{' code_block '}'
+// statement_for: 'for' (statement_for_init ';')? expression (';' (variable_assignment | expression))? '{' code_block '}'
 // ============================================================================
 
 func (ctx *parserContext) statementFor() ParserNode {
@@ -844,6 +1043,7 @@ func (ctx *parserContext) statementFor() ParserNode {
 
 	// Optional initializer
 	if !ctx.is(lexer.TokenSemiColon) {
+		initMark := ctx.mark()
 		// Try variable declaration first
 		init := ctx.variableDeclaration()
 		if init != nil {
@@ -865,6 +1065,10 @@ func (ctx *parserContext) statementFor() ParserNode {
 		if init != nil {
 			if ctx.is(lexer.TokenSemiColon) {
 				ctx.next(skipEOL) // consume ';'
+			} else if _, ok := init.(VariableAssignment); ok {
+				// without ';' it is (meant as) the condition
+				ctx.gotoMark(initMark)
+				children = children[:len(children)-1]
 			}
 		}
 	} else {
@@ -872,17 +1076,22 @@ func (ctx *parserContext) statementFor() ParserNode {
 	}
 
 	// Condition (required)
-	condition := ctx.expression()
-	if condition == nil {
-		ctx.appendError(&errors, "expected condition in for loop")
-	} else {
-		children = append(children, condition)
+	if !ctx.conditionAssignment(&errors) {
+		condition := ctx.expression()
+		if condition == nil {
+			ctx.appendError(&errors, "expected condition in for loop")
+		} else {
+			children = append(children, condition)
+		}
 	}
 
-	// Optional increment
+	// Optional increment: an assignment (i += 2) or an expression (i++)
 	if ctx.is(lexer.TokenSemiColon) {
 		ctx.next(skipEOL) // consume ';'
-		increment := ctx.expression()
+		increment := ctx.variableAssignment()
+		if increment == nil {
+			increment = ctx.expression()
+		}
 		if increment != nil {
 			children = append(children, increment)
 		}
@@ -905,6 +1114,119 @@ func (ctx *parserContext) statementFor() ParserNode {
 	}
 }
 
+// ============================================================================
+// statement_for_range: 'for' identifier 'in' expression '..' expression '{' code_block '}'
+// ============================================================================
+
+func (ctx *parserContext) statementForRange() ParserNode {
+	mark := ctx.mark()
+
+	if !ctx.is(lexer.TokenFor) {
+		ctx.gotoMark(mark)
+		return nil
+	}
+	ctx.next(skipEOL) // consume 'for'
+
+	// 'identifier in' distinguishes the range from the three-part for loop
+	if !ctx.is(lexer.TokenIdentifier) {
+		ctx.gotoMark(mark)
+		return nil
+	}
+	variable := ctx.current
+	ctx.next(skipEOL)
+	if !ctx.is(lexer.TokenIn) {
+		ctx.gotoMark(mark)
+		return nil
+	}
+	ctx.next(skipEOL) // consume 'in'
+
+	children := []ParserNode{}
+	errors := make([]*compiler.Diagnostic, 0)
+
+	from := ctx.expression()
+	if from == nil {
+		ctx.appendError(&errors, "expected range start in for loop")
+	} else {
+		children = append(children, from)
+	}
+
+	if !ctx.is(lexer.TokenRange) {
+		ctx.appendError(&errors, "expected '..' in for loop range")
+	} else {
+		ctx.next(skipEOL) // consume '..'
+		to := ctx.expression()
+		if to == nil {
+			ctx.appendError(&errors, "expected range end in for loop")
+		} else {
+			children = append(children, to)
+		}
+	}
+
+	body := ctx.codeBlock()
+	if body == nil {
+		ctx.appendError(&errors, "expected code block in for loop")
+	} else {
+		children = append(children, body)
+	}
+
+	return &statementForRange{
+		parserNodeData: parserNodeData{
+			source:   ctx.source,
+			children: children,
+			tokens:   ctx.fromMark(mark),
+			errors:   errors,
+		},
+		variable: variable,
+	}
+}
+
+// ============================================================================
+// statement_do_while: 'do' '{' code_block '}' 'while' expression
+// ============================================================================
+
+func (ctx *parserContext) statementDoWhile() ParserNode {
+	mark := ctx.mark()
+
+	if !ctx.is(lexer.TokenDo) {
+		ctx.gotoMark(mark)
+		return nil
+	}
+	ctx.next(skipEOL) // consume 'do'
+
+	children := []ParserNode{}
+	errors := make([]*compiler.Diagnostic, 0)
+
+	body := ctx.codeBlock()
+	if body == nil {
+		ctx.appendError(&errors, "expected code block after 'do'")
+	} else {
+		children = append(children, body)
+	}
+
+	if !ctx.is(lexer.TokenWhile) {
+		ctx.appendError(&errors, "expected 'while' after do loop body")
+	} else {
+		ctx.next(skipEOL) // consume 'while'
+		if !ctx.conditionAssignment(&errors) {
+			condition := ctx.expression()
+			if condition == nil {
+				ctx.appendError(&errors, "expected condition after 'while'")
+			} else {
+				children = append(children, condition)
+			}
+		}
+	}
+
+	return &statementDoWhile{
+		parserNodeData: parserNodeData{
+			source:   ctx.source,
+			children: children,
+			tokens:   ctx.fromMark(mark),
+			errors:   errors,
+		},
+	}
+}
+
 // ============================================================================
 // statement_select: 'select' expression '{' statement_select_cases statement_select_else? '}'
 // ============================================================================
@@ -1062,6 +1384,44 @@ func (ctx *parserContext) statementReturn() ParserNode {
 	}
 }
 
+// ============================================================================
+// statement_asm: 'asm' '{' asm_text '}'
+// ============================================================================
+
+func (ctx *parserContext) statementAsm() ParserNode {
+	mark := ctx.mark()
+
+	if !ctx.is(lexer.TokenAsm) {
+		ctx.gotoMark(mark)
+		return nil
+	}
+	ctx.next(skipEOL) // consume 'asm'
+
+	errors := make([]*compiler.Diagnostic, 0)
+	if !ctx.is(lexer.TokenBracesOpen) {
+		ctx.appendError(&errors, "expected '{' after 'asm'")
+	} else {
+		ctx.next(skipEOL) // consume '{'
+		// the lexer returns the body as a single text token
+		if ctx.is(lexer.TokenAsmText) {
+			ctx.next(skipEOL) // consume asm text
+		}
+		if !ctx.is(lexer.TokenBracesClose) {
+			ctx.appendError(&errors, "expected '}' to close asm block")
+		} else {
+			ctx.next(skipEOL) // consume '}'
+		}
+	}
+
+	return &inlineAsm{
+		parserNodeData: parserNodeData{
+			source: ctx.source,
+			tokens: ctx.fromMark(mark),
+			errors: errors,
+		},
+	}
+}
+
 // ============================================================================
 // statement_expression: expression_function_invocation end
 // ============================================================================
@@ -1089,6 +1449,11 @@ func (ctx *parserContext) statementExpression() ParserNode {
 // ============================================================================
 
 func (ctx *parserContext) expression() ParserNode {
+	if !ctx.enterRecursion() {
+		return nil
+	}
+	defer ctx.leaveRecursion()
+
 	// Start with lowest precedence (binary logical)
 	return ctx.expressionBinaryLogical()
 }
@@ -1125,7 +1490,7 @@ func (ctx *parserContext) expressionBinaryLogical() ParserNode {
 	return left
 }
 
-// expressionBinaryComparison: handles '=' | '>' | '<' | '>=' | '<=' | '<>'
+// expressionBinaryComparison: handles '==' | '>' | '<' | '>=' | '<=' | '<>'
 func (ctx *parserContext) expressionBinaryComparison() ParserNode {
 	left := ctx.expressionBinaryBitwise()
 	if left == nil {
@@ -1133,7 +1498,7 @@ func (ctx *parserContext) expressionBinaryComparison() ParserNode {
 	}
 
 	if ctx.isAny([]lexer.TokenId{
-		lexer.TokenEquals, lexer.TokenGreater, lexer.TokenLess,
+		lexer.TokenEqualsEquals, lexer.TokenGreater, lexer.TokenLess,
 		lexer.TokenGreaterOrEquals, lexer.TokenLessOrEquals, lexer.TokenNotEquals,
 	}) {
 		mark := ctx.mark()
@@ -1162,7 +1527,7 @@ func (ctx *parserContext) expressionBinaryComparison() ParserNode {
 
 // expressionBinaryBitwise: handles '&' | '|' | '^'
 func (ctx *parserContext) expressionBinaryBitwise() ParserNode {
-	left := ctx.expressionBinaryArithmetic()
+	left := ctx.expressionBinaryShift()
 	if left == nil {
 		return nil
 	}
@@ -1173,7 +1538,7 @@ func (ctx *parserContext) expressionBinaryBitwise() ParserNode {
 		mark := ctx.mark()
 		ctx.next(skipEOL) // consume operator
 
-		right := ctx.expressionBinaryArithmetic()
+		right := ctx.expressionBinaryShift()
 		if right == nil {
 			// Can't parse right side - rewind to before operator
 			ctx.gotoMark(mark)
@@ -1194,6 +1559,38 @@ func (ctx *parserContext) expressionBinaryBitwise() ParserNode {
 	return left
 }
 
+// expressionBinaryShift: handles '<<' | '>>'
+func (ctx *parserContext) expressionBinaryShift() ParserNode {
+	left := ctx.expressionBinaryArithmetic()
+	if left == nil {
+		return nil
+	}
+
+	for ctx.isAny([]lexer.TokenId{lexer.TokenShiftLeft, lexer.TokenShiftRight}) {
+		mark := ctx.mark()
+		ctx.next(skipEOL) // consume operator
+
+		right := ctx.expressionBinaryArithmetic()
+		if right == nil {
+			// Can't parse right side - rewind to before operator
+			ctx.gotoMark(mark)
+			return nil
+		}
+
+		left = &expressionOperatorBinShift{
+			expressionOperatorBinary: expressionOperatorBinary{
+				parserNodeData: parserNodeData{
+					source:   ctx.source,
+					children: []ParserNode{left, right},
+					tokens:   ctx.fromMark(mark),
+				},
+			},
+		}
+	}
+
+	return left
+}
+
 // expressionBinaryArithmetic: handles '+' | '-' | '*' | '/' | '%'
 func (ctx *parserContext) expressionBinaryArithmetic() ParserNode {
 	left := ctx.expressionUnary()
@@ -1230,6 +1627,11 @@ func (ctx *parserContext) expressionBinaryArithmetic() ParserNode {
 
 // expressionUnary: handles unary prefix and postfix operators
 func (ctx *parserContext) expressionUnary() ParserNode {
+	if !ctx.enterRecursion() {
+		return nil
+	}
+	defer ctx.leaveRecursion()
+
 	// Try unary prefix operators: '-' | '+' | '~' | 'not'
 	if ctx.isAny([]lexer.TokenId{
 		lexer.TokenMinus, lexer.TokenPlus, lexer.TokenTilde, lexer.TokenNot,
@@ -1408,6 +1810,10 @@ func (ctx *parserContext) expressionPrecedence() ParserNode {
 	if !ctx.is(lexer.TokenParenOpen) {
 		return nil
 	}
+	if !ctx.enterRecursion() {
+		return nil
+	}
+	defer ctx.leaveRecursion()
 	ctx.next(skipEOL) // consume '('
 
 	expr := ctx.expression()
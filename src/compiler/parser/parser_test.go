@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"zenith/compiler"
@@ -78,7 +79,10 @@ func Test_ParseVarAssignment(t *testing.T) {
 
 	varAssign, ok := body.Statements()[0].(VariableAssignment)
 	assert.True(t, ok)
-	assert.NotNil(t, varAssign.Expression())
+	require.NotNil(t, varAssign.Target())
+	assert.Equal(t, ExprIdentifier, varAssign.Target().ExpressionKind())
+	require.NotNil(t, varAssign.Expression())
+	assert.Equal(t, ExprLiteral, varAssign.Expression().ExpressionKind())
 }
 
 func Test_ParseFunctionDeclaration(t *testing.T) {
@@ -113,6 +117,79 @@ func Test_ParseFunctionWithReturnType(t *testing.T) {
 	assert.Equal(t, "u16", funcDecl.ReturnType().TypeName().Text())
 }
 
+func Test_ParseFunctionWithAttribute(t *testing.T) {
+	code := `@fast
+	onInterrupt: () {
+	}
+	plain: () {
+	}`
+	cu := parseCode(t, "Test_ParseFunctionWithAttribute", code)
+	require.Equal(t, 2, len(cu.Declarations()))
+
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+	assert.Equal(t, "onInterrupt", funcDecl.Label().Name())
+	require.Len(t, funcDecl.Attributes(), 1)
+	assert.Equal(t, "fast", funcDecl.Attributes()[0].Text())
+
+	plainDecl := cu.Declarations()[1].(FunctionDeclaration)
+	assert.Empty(t, plainDecl.Attributes())
+}
+
+func Test_ParseFunctionWithAttributeArgument(t *testing.T) {
+	code := `@org(0x0038) @interrupt
+	onTimer: () {
+	}`
+	cu := parseCode(t, "Test_ParseFunctionWithAttributeArgument", code)
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+	require.Len(t, funcDecl.Attributes(), 2)
+	assert.Equal(t, "org", funcDecl.Attributes()[0].Text())
+
+	arg, ok := funcDecl.AttributeArgument("org").(ExpressionLiteral)
+	require.True(t, ok)
+	assert.Equal(t, 0x38, arg.Number())
+	assert.Nil(t, funcDecl.AttributeArgument("interrupt"))
+	assert.Nil(t, funcDecl.AttributeArgument("fast"))
+}
+
+func Test_ParseImportDeclaration(t *testing.T) {
+	code := `import "math"
+	main: () {
+	}`
+	cu := parseCode(t, "Test_ParseImportDeclaration", code)
+	require.Equal(t, 2, len(cu.Declarations()))
+
+	imp, ok := cu.Declarations()[0].(ImportDeclaration)
+	require.True(t, ok)
+	assert.Equal(t, "math", imp.ModuleName())
+}
+
+func Test_ParseImportDeclaration_MissingName(t *testing.T) {
+	code := `import main`
+	_, errors := parseCodeError(t, "Test_ParseImportDeclaration_MissingName", code)
+	require.NotEmpty(t, errors)
+	assert.Contains(t, errors[0].Error(), "expected module name")
+}
+
+func Test_ParseExportDeclarations(t *testing.T) {
+	code := `export count: u8 = 0
+	export @fast tick: () {
+	}
+	export struct Point {
+		x: u8
+	}
+	plain: () {
+	}`
+	cu := parseCode(t, "Test_ParseExportDeclarations", code)
+	require.Equal(t, 4, len(cu.Declarations()))
+
+	assert.True(t, cu.Declarations()[0].(VariableDeclaration).IsExported())
+	funcDecl := cu.Declarations()[1].(FunctionDeclaration)
+	assert.True(t, funcDecl.IsExported())
+	assert.Equal(t, "fast", funcDecl.Attributes()[0].Text())
+	assert.True(t, cu.Declarations()[2].(TypeDeclaration).IsExported())
+	assert.False(t, cu.Declarations()[3].(FunctionDeclaration).IsExported())
+}
+
 func Test_ParseStructDeclaration(t *testing.T) {
 	code := `struct Point {
 		x: u8,
@@ -156,6 +233,95 @@ func Test_ParseIfElsifElse(t *testing.T) {
 	assert.True(t, len(ifStmt.Children()) >= 4)
 }
 
+func Test_ParseIfElsifElsifElse_Blocks(t *testing.T) {
+	code := `main: () {
+		if x > 5 {
+			a = 1
+		} elsif x > 3 {
+			a = 2
+			a = 2
+		} elsif x > 0 {
+			a = 3
+			a = 3
+			a = 3
+		} else {
+			a = 4
+			a = 4
+			a = 4
+			a = 4
+		}
+	}`
+	cu := parseCode(t, "Test_ParseIfElsifElsifElse_Blocks", code)
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+	ifStmt := funcDecl.Body().Statements()[0].(StatementIf)
+
+	require.NotNil(t, ifStmt.ThenBlock())
+	assert.Len(t, ifStmt.ThenBlock().Statements(), 1)
+	elsifs := ifStmt.ElsifClauses()
+	require.Len(t, elsifs, 2)
+	assert.NotNil(t, elsifs[0].Condition())
+	assert.Len(t, elsifs[0].ThenBlock().Statements(), 2)
+	assert.NotNil(t, elsifs[1].Condition())
+	assert.Len(t, elsifs[1].ThenBlock().Statements(), 3)
+	require.NotNil(t, ifStmt.ElseBlock())
+	assert.Len(t, ifStmt.ElseBlock().Statements(), 4)
+}
+
+func Test_ParseIfElsifElsif_NoElse(t *testing.T) {
+	code := `main: () {
+		if x > 5 {
+			a = 1
+		} elsif x > 3 {
+			a = 2
+		} elsif x > 0 {
+			a = 3
+		}
+	}`
+	cu := parseCode(t, "Test_ParseIfElsifElsif_NoElse", code)
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+	ifStmt := funcDecl.Body().Statements()[0].(StatementIf)
+
+	assert.Len(t, ifStmt.ElsifClauses(), 2)
+	assert.Nil(t, ifStmt.ElseBlock())
+}
+
+func Test_ParseIfElse_NestedIfElse(t *testing.T) {
+	code := `main: () {
+		if x > 5 {
+			if x > 7 {
+				a = 1
+			} else {
+				a = 2
+			}
+		}
+	}`
+	cu := parseCode(t, "Test_ParseIfElse_NestedIfElse", code)
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+	ifStmt := funcDecl.Body().Statements()[0].(StatementIf)
+
+	assert.Nil(t, ifStmt.ElseBlock())
+	nested := ifStmt.ThenBlock().Statements()[0].(StatementIf)
+	require.NotNil(t, nested.ElseBlock())
+	assert.Len(t, nested.ElseBlock().Statements(), 1)
+}
+
+func Test_ParseIfElsif_NoElse(t *testing.T) {
+	code := `main: () {
+		if x > 5 {
+			a = 1
+		} elsif x > 0 {
+			a = 2
+		}
+	}`
+	cu := parseCode(t, "Test_ParseIfElsif_NoElse", code)
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+	ifStmt := funcDecl.Body().Statements()[0].(StatementIf)
+
+	assert.Len(t, ifStmt.ThenBlock().Statements(), 1)
+	assert.Len(t, ifStmt.ElsifClauses(), 1)
+	assert.Nil(t, ifStmt.ElseBlock())
+}
+
 func Test_ParseForLoop(t *testing.T) {
 	code := `main: () {
 		for i: = 0; i < 10; i++ {
@@ -170,6 +336,227 @@ func Test_ParseForLoop(t *testing.T) {
 	assert.NotNil(t, forStmt)
 }
 
+func Test_ParseForRange(t *testing.T) {
+	code := `main: () {
+		for i in 0..10 {
+			x = i
+		}
+	}`
+	cu := parseCode(t, "Test_ParseForRange", code)
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+	body := funcDecl.Body()
+
+	forRange, ok := body.Statements()[0].(StatementForRange)
+	require.True(t, ok)
+	assert.Equal(t, "i", forRange.Variable().Text())
+	from, ok := forRange.From().(ExpressionLiteral)
+	require.True(t, ok)
+	assert.Equal(t, "0", from.Value().Text())
+	to, ok := forRange.To().(ExpressionLiteral)
+	require.True(t, ok)
+	assert.Equal(t, "10", to.Value().Text())
+	assert.Len(t, forRange.Body().Statements(), 1)
+}
+
+func Test_ParseForRange_Expressions(t *testing.T) {
+	code := `main: () {
+		for i in lo + 1 .. len(arr) {
+		}
+	}`
+	cu := parseCode(t, "Test_ParseForRange_Expressions", code)
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+
+	forRange, ok := funcDecl.Body().Statements()[0].(StatementForRange)
+	require.True(t, ok)
+	_, ok = forRange.From().(ExpressionOperatorBinary)
+	assert.True(t, ok)
+	_, ok = forRange.To().(ExpressionFunctionInvocation)
+	assert.True(t, ok)
+}
+
+func Test_ParseForRange_MissingRange(t *testing.T) {
+	code := `main: () {
+		for i in 10 {
+		}
+	}`
+	_, errors := parseCodeError(t, "Test_ParseForRange_MissingRange", code)
+	require.NotEmpty(t, errors)
+	assert.Contains(t, errors[0].Error(), "expected '..'")
+}
+
+func Test_ParseConditionComparison(t *testing.T) {
+	code := `main: () {
+		if x == 5 {
+			x = 6
+		}
+		for x == 5 {
+			x = 6
+		}
+		do {
+			x = 6
+		} while x == 5
+	}`
+	cu := parseCode(t, "Test_ParseConditionComparison", code)
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+	statements := funcDecl.Body().Statements()
+	require.Len(t, statements, 3)
+
+	// '==' compares
+	ifStmt := statements[0].(StatementIf)
+	_, ok := ifStmt.Condition().(ExpressionOperatorBinary)
+	assert.True(t, ok)
+	forStmt := statements[1].(StatementFor)
+	assert.Nil(t, forStmt.Initializer())
+	_, ok = forStmt.Condition().(ExpressionOperatorBinary)
+	assert.True(t, ok)
+	doWhile := statements[2].(StatementDoWhile)
+	_, ok = doWhile.Condition().(ExpressionOperatorBinary)
+	assert.True(t, ok)
+
+	// '=' assigns
+	assignment, ok := ifStmt.ThenBlock().Statements()[0].(VariableAssignment)
+	require.True(t, ok)
+	assert.Nil(t, assignment.Operator())
+}
+
+func Test_ParseEqualityAndAssignment(t *testing.T) {
+	code := `main: () {
+		x = a == b
+		y: = a == b
+		z = b
+	}`
+	cu := parseCode(t, "Test_ParseEqualityAndAssignment", code)
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+	statements := funcDecl.Body().Statements()
+	require.Len(t, statements, 3)
+
+	assignment, ok := statements[0].(VariableAssignment)
+	require.True(t, ok)
+	comparison, ok := assignment.Expression().(ExpressionOperatorBinary)
+	require.True(t, ok)
+	assert.Equal(t, ExprBinaryComparison, comparison.ExpressionKind())
+	assert.Equal(t, lexer.TokenEqualsEquals, comparison.Operator().Id())
+
+	varDecl, ok := statements[1].(VariableDeclaration)
+	require.True(t, ok)
+	assert.Equal(t, ExprBinaryComparison, varDecl.Initializer().ExpressionKind())
+
+	assignment, ok = statements[2].(VariableAssignment)
+	require.True(t, ok)
+	assert.Equal(t, ExprIdentifier, assignment.Expression().ExpressionKind())
+}
+
+func Test_ParseForIncrementAssignment(t *testing.T) {
+	code := `main: () {
+		for i: = 0; i < 10; i += 2 {
+			x = i
+		}
+		for i < 10 {
+			i += 1
+		}
+	}`
+	cu := parseCode(t, "Test_ParseForIncrementAssignment", code)
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+	statements := funcDecl.Body().Statements()
+
+	forStmt := statements[0].(StatementFor)
+	increment, ok := forStmt.Increment().(VariableAssignment)
+	require.True(t, ok)
+	assert.Equal(t, "+", increment.Operator().Text())
+	assert.Len(t, forStmt.Body().Statements(), 1)
+
+	assert.Nil(t, statements[1].(StatementFor).Increment())
+}
+
+func Test_ParseAssignmentOperator(t *testing.T) {
+	code := `main: () {
+		x = a + 1
+		x += a - 1
+	}`
+	cu := parseCode(t, "Test_ParseAssignmentOperator", code)
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+	statements := funcDecl.Body().Statements()
+
+	assert.Nil(t, statements[0].(VariableAssignment).Operator())
+	assert.Equal(t, "+", statements[1].(VariableAssignment).Operator().Text())
+}
+
+func Test_ParseMultiAssignment(t *testing.T) {
+	code := `main: () {
+		a, b = b, a + 1
+		x, y, z = 1, 2, 3
+	}`
+	cu := parseCode(t, "Test_ParseMultiAssignment", code)
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+	statements := funcDecl.Body().Statements()
+	require.Len(t, statements, 2)
+
+	swap, ok := statements[0].(MultiAssignment)
+	require.True(t, ok)
+	require.Len(t, swap.Targets(), 2)
+	require.Len(t, swap.Values(), 2)
+	assert.Equal(t, "a", swap.Targets()[0].(ExpressionIdentifier).Identifier().Text())
+	assert.Equal(t, "b", swap.Targets()[1].(ExpressionIdentifier).Identifier().Text())
+	assert.Equal(t, "b", swap.Values()[0].(ExpressionIdentifier).Identifier().Text())
+	_, ok = swap.Values()[1].(ExpressionOperatorBinary)
+	assert.True(t, ok)
+
+	triple, ok := statements[1].(MultiAssignment)
+	require.True(t, ok)
+	assert.Len(t, triple.Targets(), 3)
+	assert.Len(t, triple.Values(), 3)
+}
+
+func Test_ParseConditionAssignment_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"if", "main: () {\n if x = 5 {\n x = 6\n }\n}"},
+		{"if compound", "main: () {\n if x += 5 {\n x = 6\n }\n}"},
+		{"elsif", "main: () {\n if x == 1 {\n } elsif x -= 5 {\n x = 6\n }\n}"},
+		{"for", "main: () {\n for x = 5 {\n x = 6\n }\n}"},
+		{"for compound", "main: () {\n for x += 5 {\n x = 6\n }\n}"},
+		{"for condition", "main: () {\n for x: = 0; x = 5; x++ {\n x = 6\n }\n}"},
+		{"do", "main: () {\n do {\n x = 6\n } while x |= 5\n}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errors := parseCodeError(t, "Test_ParseConditionAssignment_Error", tt.code)
+			require.Len(t, errors, 1)
+			assert.Contains(t, errors[0].Error(), "cannot be used as a condition")
+		})
+	}
+}
+
+func Test_ParseDoWhileLoop(t *testing.T) {
+	code := `main: () {
+		do {
+			i = i - 1
+		} while i > 0
+	}`
+	cu := parseCode(t, "Test_ParseDoWhileLoop", code)
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+	body := funcDecl.Body()
+
+	doWhile, ok := body.Statements()[0].(StatementDoWhile)
+	require.True(t, ok)
+	assert.Len(t, doWhile.Body().Statements(), 1)
+	_, ok = doWhile.Condition().(ExpressionOperatorBinary)
+	assert.True(t, ok)
+}
+
+func Test_ParseDoWhileLoop_MissingWhile(t *testing.T) {
+	code := `main: () {
+		do {
+		}
+	}`
+	_, errors := parseCodeError(t, "Test_ParseDoWhileLoop_MissingWhile", code)
+	require.NotEmpty(t, errors)
+	assert.Contains(t, errors[0].Error(), "expected 'while' after do loop body")
+}
+
 func Test_ParseSelectStatement(t *testing.T) {
 	code := `main: () {
 		select value {
@@ -242,6 +629,18 @@ func Test_ParseExpressionBinaryArithmetic(t *testing.T) {
 	assert.NotNil(t, binOp.Right())
 }
 
+func Test_ParseExpressionModulo(t *testing.T) {
+	code := `result: = a % b`
+	cu := parseCode(t, "Test_ParseExpressionModulo", code)
+	varDecl := cu.Declarations()[0].(VariableDeclaration)
+
+	binOp, ok := varDecl.Initializer().(ExpressionOperatorBinArithmetic)
+	require.True(t, ok)
+	assert.Equal(t, lexer.TokenPercent, binOp.Operator().Id())
+	assert.NotNil(t, binOp.Left())
+	assert.NotNil(t, binOp.Right())
+}
+
 func Test_ParseExpressionComplex(t *testing.T) {
 	code := `result: = (a + b) * c - d / 2`
 	cu := parseCode(t, "Test_ParseExpressionComplex", code)
@@ -282,6 +681,35 @@ func Test_ParseExpressionBitwise(t *testing.T) {
 	assert.NotNil(t, bitOp.Right())
 }
 
+func Test_ParseExpressionShift(t *testing.T) {
+	code := `result: = x << 2`
+	cu := parseCode(t, "Test_ParseExpressionShift", code)
+	varDecl := cu.Declarations()[0].(VariableDeclaration)
+
+	shiftOp, ok := varDecl.Initializer().(ExpressionOperatorBinShift)
+	require.True(t, ok)
+	assert.Equal(t, lexer.TokenShiftLeft, shiftOp.Operator().Id())
+	assert.NotNil(t, shiftOp.Left())
+	assert.NotNil(t, shiftOp.Right())
+}
+
+func Test_ParseExpressionShiftPrecedence(t *testing.T) {
+	// arithmetic binds tighter than shift, shift tighter than bitwise
+	code := `a: = x << 1 + 2
+	b: = x & y >> 4`
+	cu := parseCode(t, "Test_ParseExpressionShiftPrecedence", code)
+
+	shiftOp, ok := cu.Declarations()[0].(VariableDeclaration).Initializer().(ExpressionOperatorBinShift)
+	require.True(t, ok)
+	_, ok = shiftOp.Right().(ExpressionOperatorBinArithmetic)
+	assert.True(t, ok, "'1 + 2' is the shift count")
+
+	bitOp, ok := cu.Declarations()[1].(VariableDeclaration).Initializer().(ExpressionOperatorBinBitwise)
+	require.True(t, ok)
+	_, ok = bitOp.Right().(ExpressionOperatorBinShift)
+	assert.True(t, ok, "'y >> 4' is the right operand of '&'")
+}
+
 func Test_ParseExpressionUnaryPrefix(t *testing.T) {
 	code := `neg: = -value`
 	cu := parseCode(t, "Test_ParseExpressionUnaryPrefix", code)
@@ -407,6 +835,27 @@ func Test_ParseStringLiteral(t *testing.T) {
 	assert.NotNil(t, literal)
 }
 
+func Test_ParseDecimalLiteral(t *testing.T) {
+	code := `x: d8 = 1.5`
+	cu := parseCode(t, "Test_ParseDecimalLiteral", code)
+	varDecl := cu.Declarations()[0].(VariableDeclaration)
+
+	literal, ok := varDecl.Initializer().(ExpressionLiteral)
+	require.True(t, ok)
+	assert.Equal(t, "1.5", literal.Value().Text())
+}
+
+func Test_ParseNumberSuffix(t *testing.T) {
+	code := `x: = 0x10u16`
+	cu := parseCode(t, "Test_ParseNumberSuffix", code)
+	varDecl := cu.Declarations()[0].(VariableDeclaration)
+
+	literal, ok := varDecl.Initializer().(ExpressionLiteral)
+	require.True(t, ok)
+	assert.Equal(t, 16, literal.Number())
+	assert.Equal(t, "u16", literal.NumberSuffix())
+}
+
 func Test_ParseBooleanLiteral(t *testing.T) {
 	code := `flag: = true`
 	cu := parseCode(t, "Test_ParseBooleanLiteral", code)
@@ -603,3 +1052,268 @@ func Test_ParseArrayInitializerTrailingComma(t *testing.T) {
 	elements := arrayExpr.Initializer().Elements()
 	assert.Equal(t, 3, len(elements), "Should have 3 elements (trailing comma ignored)")
 }
+
+func Test_ParseExpressionNestingTooDeep(t *testing.T) {
+	depth := 5000
+	code := "test: () {\n x := " + strings.Repeat("(", depth) + "1" + strings.Repeat(")", depth) + "\n}"
+	_, errs := parseCodeError(t, "Test_ParseExpressionNestingTooDeep", code)
+
+	require.NotEmpty(t, errs)
+	count := 0
+	for _, err := range errs {
+		if strings.Contains(err.Error(), "expression nesting too deep") {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+}
+
+func Test_ParseExpressionNestingWithinLimit(t *testing.T) {
+	depth := 100
+	code := "test: () {\n x := " + strings.Repeat("(", depth) + "1" + strings.Repeat(")", depth) + "\n}"
+	cu := parseCode(t, "Test_ParseExpressionNestingWithinLimit", code)
+	assert.Equal(t, 1, len(cu.Declarations()))
+}
+
+func Test_ParseExpressionNesting_Options(t *testing.T) {
+	depth := 100
+	code := "test: () {\n x := " + strings.Repeat("(", depth) + "1" + strings.Repeat(")", depth) + "\n}"
+
+	_, errs := ParseWithOptions(&compiler.Source{Name: "Test_ParseExpressionNesting_Options"},
+		lexer.OpenTokenStream(code), ParserOptions{MaxRecursionDepth: 50})
+	require.NotEmpty(t, errs)
+	assert.Contains(t, errs[0].Error(), "expression nesting too deep")
+
+	_, errs = ParseWithOptions(&compiler.Source{Name: "Test_ParseExpressionNesting_Options"},
+		lexer.OpenTokenStream(code), ParserOptions{MaxRecursionDepth: -1})
+	assert.Empty(t, errs)
+}
+
+func Test_ParseCodeBlockErrorRecovery(t *testing.T) {
+	code := `test: () {
+		a := 1
+		b := ) 2
+		c := 3
+		d := ] 4
+		e := 5
+	}`
+	cu, errs := parseCodeError(t, "Test_ParseCodeBlockErrorRecovery", code)
+
+	require.Equal(t, 2, len(errs), fmt.Sprintf("%v", errs))
+	assert.Contains(t, errs[0].Error(), "invalid statement at 'b'")
+	assert.Equal(t, 3, errs[0].Location.Line)
+	assert.Contains(t, errs[1].Error(), "invalid statement at 'd'")
+	assert.Equal(t, 5, errs[1].Location.Line)
+
+	// valid statements around the errors are still parsed
+	fn, ok := cu.Declarations()[0].(FunctionDeclaration)
+	require.True(t, ok)
+	assert.Equal(t, 3, len(fn.Body().Statements()))
+}
+
+func Test_ParseFileFromReader(t *testing.T) {
+	code := `count: u16 = 42
+	main: () {
+		x := count + 1
+	}`
+	node, errs, err := ParseFile("src/main.zen", strings.NewReader(code))
+
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+	cu, ok := node.(CompilationUnit)
+	require.True(t, ok)
+	assert.Equal(t, 2, len(cu.Declarations()))
+}
+
+func Test_ParseFileDiagnosticSource(t *testing.T) {
+	code := `main: () {
+		b := ) 2
+	}`
+	_, errs, err := ParseFile("src/main.zen", strings.NewReader(code))
+
+	require.NoError(t, err)
+	require.NotEmpty(t, errs)
+	assert.Equal(t, "main.zen", errs[0].Source.Name)
+	assert.Equal(t, "src/main.zen", errs[0].Source.Path)
+	assert.True(t, strings.HasPrefix(errs[0].Error(), "main.zen:2:"))
+}
+
+func triviaText(tokens []lexer.Token) string {
+	var builder strings.Builder
+	for _, token := range tokens {
+		builder.WriteString(token.Text())
+	}
+	return builder.String()
+}
+
+func Test_ParseTriviaCommentBetweenDeclarations(t *testing.T) {
+	code := "a: u8 // first\n// the counter\nb: u16\n"
+	cu := parseCode(t, "Test_ParseTriviaCommentBetweenDeclarations", code)
+	require.Equal(t, 2, len(cu.Declarations()))
+
+	first := cu.Declarations()[0]
+	second := cu.Declarations()[1]
+
+	assert.Empty(t, first.LeadingTrivia())
+	assert.Equal(t, " // first\n", triviaText(first.TrailingTrivia()))
+
+	leading := second.LeadingTrivia()
+	require.Equal(t, 2, len(leading))
+	assert.Equal(t, lexer.TokenComment, leading[0].Id())
+	assert.Equal(t, "// the counter", leading[0].Text())
+	assert.Equal(t, lexer.TokenEOL, leading[1].Id())
+	assert.Equal(t, "\n", triviaText(second.TrailingTrivia()))
+}
+
+func Test_ParseTriviaLeadingFileComment(t *testing.T) {
+	code := "// header\n\nmain: () {\n\tx := 1 // one\n}"
+	cu := parseCode(t, "Test_ParseTriviaLeadingFileComment", code)
+	require.Equal(t, 1, len(cu.Declarations()))
+
+	fn := cu.Declarations()[0].(FunctionDeclaration)
+	assert.Equal(t, "// header\n\n", triviaText(fn.LeadingTrivia()))
+
+	stmt := fn.Body().Statements()[0]
+	assert.Equal(t, "\t", triviaText(stmt.LeadingTrivia()))
+	assert.Equal(t, " // one\n", triviaText(stmt.TrailingTrivia()))
+}
+
+func Test_ParseFunctionCallNamedArguments(t *testing.T) {
+	code := `main: () {
+		move(x = 1, y = 2)
+	}`
+	cu := parseCode(t, "Test_ParseFunctionCallNamedArguments", code)
+	body := cu.Declarations()[0].(FunctionDeclaration).Body()
+	call := body.Statements()[0].(StatementExpression).Expression().(ExpressionFunctionInvocation)
+
+	args := call.Arguments().FunctionArguments()
+	require.Len(t, args, 2)
+	require.NotNil(t, args[0].Name())
+	assert.Equal(t, "x", args[0].Name().Text())
+	require.NotNil(t, args[1].Name())
+	assert.Equal(t, "y", args[1].Name().Text())
+
+	// the expressions are still available in source order
+	exprs := call.Arguments().Arguments()
+	require.Len(t, exprs, 2)
+	assert.Equal(t, "1", exprs[0].(ExpressionLiteral).Value().Text())
+	assert.Equal(t, "2", exprs[1].(ExpressionLiteral).Value().Text())
+}
+
+func Test_ParseFunctionCallMixedArguments(t *testing.T) {
+	code := `main: () {
+		move(a + 1, y = b, c == 3)
+	}`
+	cu := parseCode(t, "Test_ParseFunctionCallMixedArguments", code)
+	body := cu.Declarations()[0].(FunctionDeclaration).Body()
+	call := body.Statements()[0].(StatementExpression).Expression().(ExpressionFunctionInvocation)
+
+	args := call.Arguments().FunctionArguments()
+	require.Len(t, args, 3)
+	assert.Nil(t, args[0].Name())
+	assert.Equal(t, ExprBinaryArithmetic, args[0].Expression().ExpressionKind())
+	require.NotNil(t, args[1].Name())
+	assert.Equal(t, "y", args[1].Name().Text())
+	assert.Equal(t, ExprIdentifier, args[1].Expression().ExpressionKind())
+	// a comparison stays positional
+	assert.Nil(t, args[2].Name())
+	assert.Equal(t, ExprBinaryComparison, args[2].Expression().ExpressionKind())
+}
+
+func Test_ParseInlineAsm(t *testing.T) {
+	code := `main: () {
+		asm {
+			ld a, (ix+2)
+			out (0x10), a
+		}
+		ret
+	}`
+	cu := parseCode(t, "Test_ParseInlineAsm", code)
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+	body := funcDecl.Body()
+	require.Equal(t, 2, len(body.Statements()))
+
+	asmStmt, ok := body.Statements()[0].(InlineAsm)
+	require.True(t, ok)
+	assert.Equal(t, "\n\t\t\tld a, (ix+2)\n\t\t\tout (0x10), a\n\t\t", asmStmt.Text())
+
+	_, ok = body.Statements()[1].(StatementReturn)
+	assert.True(t, ok)
+}
+
+func Test_ParseInlineAsm_MissingBrace(t *testing.T) {
+	code := `main: () {
+		asm nop
+	}`
+	_, errors := parseCodeError(t, "Test_ParseInlineAsm_MissingBrace", code)
+	assert.NotEmpty(t, errors)
+}
+
+func Test_CollectDiagnostics_NestedIf(t *testing.T) {
+	code := `main: () {
+	z: = [1, 2
+	if true {
+		if false {
+			x: = Point{a = }
+			y: = Point{b = }
+		}
+	}
+}`
+	cu, _ := parseCodeError(t, "Test_CollectDiagnostics_NestedIf", code)
+
+	// all errors, in source order
+	diagnostics := CollectDiagnostics(cu)
+	require.Len(t, diagnostics, 3)
+	assert.Equal(t, "Test_CollectDiagnostics_NestedIf:3:2: expected ']' to close array initializer", diagnostics[0].Error())
+	assert.Equal(t, "Test_CollectDiagnostics_NestedIf:5:19: expected expression after '=", diagnostics[1].Error())
+	assert.Equal(t, "Test_CollectDiagnostics_NestedIf:6:19: expected expression after '=", diagnostics[2].Error())
+
+	// the errors buried in the nested if body
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+	ifStmt := funcDecl.Body().Statements()[1].(StatementIf)
+	nested := CollectDiagnostics(ifStmt)
+	require.Len(t, nested, 2)
+	assert.Same(t, diagnostics[1], nested[0])
+	assert.Same(t, diagnostics[2], nested[1])
+}
+
+func Test_Span_FunctionDeclaration(t *testing.T) {
+	code := "// comment\nmain: () {\n\tx: = 1\n}\n"
+	cu := parseCode(t, "Test_Span_FunctionDeclaration", code)
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+
+	// from the label to (past) the closing brace, the comment and line endings are not included
+	start, end := funcDecl.Span()
+	assert.Equal(t, compiler.Location{Index: 11, Line: 2, Column: 1}, start)
+	assert.Equal(t, compiler.Location{Index: 31, Line: 4, Column: 2}, end)
+	assert.Equal(t, "main", code[start.Index:start.Index+4])
+	assert.Equal(t, "}", code[end.Index-1:end.Index])
+}
+
+func Test_Span_InlineAsm(t *testing.T) {
+	code := "main: () {\n\tasm {\n\t\tnop\n\t}\n}"
+	cu := parseCode(t, "Test_Span_InlineAsm", code)
+	funcDecl := cu.Declarations()[0].(FunctionDeclaration)
+
+	start, end := funcDecl.Span()
+	assert.Equal(t, compiler.Location{Index: 0, Line: 1, Column: 1}, start)
+	assert.Equal(t, compiler.Location{Index: len(code), Line: 5, Column: 2}, end)
+
+	// the statement spans multiple lines
+	start, end = funcDecl.Body().Statements()[0].Span()
+	assert.Equal(t, compiler.Location{Index: 12, Line: 2, Column: 2}, start)
+	assert.Equal(t, compiler.Location{Index: 26, Line: 4, Column: 3}, end)
+}
+
+func Test_Span_NoTokens(t *testing.T) {
+	cu := parseCode(t, "Test_Span_NoTokens", "a: = 1\nb: = 2")
+
+	// a node without tokens of its own spans its children
+	node := &parserNodeData{children: cu.Declarations()}
+	start, end := node.Span()
+	assert.Equal(t, compiler.Location{Index: 0, Line: 1, Column: 1}, start)
+	assert.Equal(t, compiler.Location{Index: 13, Line: 2, Column: 7}, end)
+
+	start, end = (&parserNodeData{}).Span()
+	assert.Equal(t, start, end)
+}
@@ -58,3 +58,64 @@ func (cg *CallGraph) GetAllFunctions() []string {
 func (cg *CallGraph) GetEdges() map[string][]string {
 	return cg.edges
 }
+
+// Reachable returns the functions that can be reached from the roots (including the roots)
+func (cg *CallGraph) Reachable(roots []string) map[string]bool {
+	reachable := make(map[string]bool)
+	worklist := append([]string{}, roots...)
+	for len(worklist) > 0 {
+		name := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+
+		if reachable[name] {
+			continue
+		}
+		reachable[name] = true
+		worklist = append(worklist, cg.GetCallees(name)...)
+	}
+	return reachable
+}
+
+// EliminateDeadFunctions removes the functions that cannot be reached from the roots ('main').
+// Functions entered by the CPU (interrupt handlers, '@org' placed code) are roots as well,
+// so are functions called from global initializers.
+// Nothing is removed when none of the roots is declared (a library without entry point).
+// Returns the names of the removed functions in declaration order.
+func EliminateDeadFunctions(cu *SemCompilationUnit, roots []string) []string {
+	declared := make(map[string]bool)
+	allRoots := []string{""} // global initializers
+	for _, decl := range cu.Declarations {
+		fn, ok := decl.(*SemFunctionDecl)
+		if !ok {
+			continue
+		}
+		declared[fn.Name] = true
+		if fn.HasAttribute(AttributeInterrupt) || fn.HasAttribute(AttributeNMI) || fn.Org != nil {
+			allRoots = append(allRoots, fn.Name)
+		}
+	}
+
+	hasRoot := false
+	for _, root := range roots {
+		if declared[root] {
+			hasRoot = true
+			allRoots = append(allRoots, root)
+		}
+	}
+	if !hasRoot {
+		return nil
+	}
+
+	reachable := cu.CallGraph.Reachable(allRoots)
+	var removed []string
+	kept := make([]SemDeclaration, 0, len(cu.Declarations))
+	for _, decl := range cu.Declarations {
+		if fn, ok := decl.(*SemFunctionDecl); ok && !reachable[fn.Name] {
+			removed = append(removed, fn.Name)
+			continue
+		}
+		kept = append(kept, decl)
+	}
+	cu.Declarations = kept
+	return removed
+}
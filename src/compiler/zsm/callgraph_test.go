@@ -0,0 +1,105 @@
+package zsm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func functionNames(cu *SemCompilationUnit) []string {
+	var names []string
+	for _, decl := range cu.Declarations {
+		if fn, ok := decl.(*SemFunctionDecl); ok {
+			names = append(names, fn.Name)
+		}
+	}
+	return names
+}
+
+func Test_CallGraph_Reachable(t *testing.T) {
+	cg := NewCallGraph()
+	cg.AddCall("main", "a")
+	cg.AddCall("a", "b")
+	cg.AddCall("b", "a") // recursion
+	cg.AddCall("unused", "b")
+
+	reachable := cg.Reachable([]string{"main"})
+
+	assert.Equal(t, map[string]bool{"main": true, "a": true, "b": true}, reachable)
+}
+
+func Test_EliminateDeadFunctions(t *testing.T) {
+	code := `main: () {
+		first()
+	}
+	first: () {
+		second()
+	}
+	second: () {
+	}
+	unused: () {
+		second()
+	}`
+	semCU, errors := analyzeCode(t, "Test_EliminateDeadFunctions", code)
+	requireNoErrors(t, errors)
+
+	removed := EliminateDeadFunctions(semCU, []string{"main"})
+
+	assert.Equal(t, []string{"unused"}, removed)
+	assert.Equal(t, []string{"main", "first", "second"}, functionNames(semCU))
+}
+
+func Test_EliminateDeadFunctions_KeepsCPUEntries(t *testing.T) {
+	code := `main: () {
+	}
+	@interrupt
+	onTimer: () {
+		tick()
+	}
+	@org(0x0100)
+	boot: () {
+	}
+	tick: () {
+	}`
+	semCU, errors := analyzeCode(t, "Test_EliminateDeadFunctions_KeepsCPUEntries", code)
+	requireNoErrors(t, errors)
+
+	removed := EliminateDeadFunctions(semCU, []string{"main"})
+
+	assert.Empty(t, removed)
+	assert.Equal(t, []string{"main", "onTimer", "boot", "tick"}, functionNames(semCU))
+}
+
+func Test_EliminateDeadFunctions_NoEntryPoint(t *testing.T) {
+	code := `double: (v: u8) u8 {
+		ret v + v
+	}`
+	semCU, errors := analyzeCode(t, "Test_EliminateDeadFunctions_NoEntryPoint", code)
+	requireNoErrors(t, errors)
+
+	removed := EliminateDeadFunctions(semCU, []string{"main"})
+
+	assert.Empty(t, removed)
+	require.Len(t, semCU.Declarations, 1)
+}
+
+func Test_EliminateDeadFunctions_KeepsAddressTaken(t *testing.T) {
+	code := `handlers: u16[] = [@addressof(onKey)]
+	main: () {
+		resume: = @addressof(onResume)
+	}
+	onKey: () {
+	}
+	onResume: () {
+	}
+	unused: () {
+	}`
+	semCU, errors := analyzeCode(t, "Test_EliminateDeadFunctions_KeepsAddressTaken", code)
+	requireNoErrors(t, errors)
+
+	removed := EliminateDeadFunctions(semCU, []string{"main"})
+
+	assert.Equal(t, []string{"unused"}, removed)
+	assert.Equal(t, []string{"main", "onKey", "onResume"}, functionNames(semCU))
+}
@@ -0,0 +1,170 @@
+package zsm
+
+// ConstantValue evaluates an expression at compile time.
+// Returns an int or bool value, false when the expression is not constant.
+func ConstantValue(expr SemExpression) (any, bool) {
+	value, _, ok := EvalConst(expr)
+	return value, ok
+}
+
+// EvalConst evaluates an expression at compile time (array sizes, '@assert', initializers, case labels).
+// Returns an int or bool value and its type, false when the expression is not constant.
+// Integer results wrap around within the size of their type, as they would at runtime.
+func EvalConst(expr SemExpression) (value any, typ Type, ok bool) {
+	switch e := expr.(type) {
+	case *SemConstant:
+		switch e.Value.(type) {
+		case int, bool:
+			return e.Value, e.TypeInfo, true
+		}
+	case *SemSymbolRef:
+		// a named constant evaluates to its value
+		if e.Symbol.Constant != nil {
+			return EvalConst(e.Symbol.Constant)
+		}
+	case *SemUnaryOp:
+		if value, ok := constantUnaryOp(e); ok {
+			return wrapConst(value, e.Type()), e.Type(), true
+		}
+	case *SemBinaryOp:
+		if value, ok := constantBinaryOp(e); ok {
+			return wrapConst(value, e.Type()), e.Type(), true
+		}
+	case *SemFunctionCall:
+		// the size of a type or expression is known at compile time
+		if e.Function.Name == "@sizeof" && len(e.Arguments) == 1 {
+			if typ := e.Arguments[0].Type(); typ != nil {
+				return int(typ.Size()), e.TypeInfo, true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+// wrapConst truncates an integer value to the size of its type (two's complement)
+// and sign-extends it for the signed types
+func wrapConst(value any, typ Type) any {
+	v, ok := value.(int)
+	if !ok || !isIntegerType(typ) {
+		return value
+	}
+	bits := int(typ.Size()) * 8
+	v &= 1<<bits - 1
+	if IsSigned(typ) && v >= 1<<(bits-1) {
+		v -= 1 << bits
+	}
+	return v
+}
+
+func constantUnaryOp(op *SemUnaryOp) (any, bool) {
+	operand, _, ok := EvalConst(op.Operand)
+	if !ok {
+		return nil, false
+	}
+
+	switch value := operand.(type) {
+	case int:
+		switch op.Op {
+		case OpNegate:
+			return -value, true
+		case OpBitwiseNot:
+			// invert within the operand size
+			mask := 1<<(op.Type().Size()*8) - 1
+			return ^value & mask, true
+		case OpToBool:
+			return value != 0, true
+		}
+	case bool:
+		if op.Op == OpLogicalNot {
+			return !value, true
+		}
+	}
+	return nil, false
+}
+
+func constantBinaryOp(op *SemBinaryOp) (any, bool) {
+	left, _, ok := EvalConst(op.Left)
+	if !ok {
+		return nil, false
+	}
+	right, _, ok := EvalConst(op.Right)
+	if !ok {
+		return nil, false
+	}
+
+	if l, ok := left.(bool); ok {
+		r, ok := right.(bool)
+		if !ok {
+			return nil, false
+		}
+		switch op.Op {
+		case OpLogicalAnd:
+			return l && r, true
+		case OpLogicalOr:
+			return l || r, true
+		case OpEqual:
+			return l == r, true
+		case OpNotEqual:
+			return l != r, true
+		}
+		return nil, false
+	}
+
+	l, ok := left.(int)
+	if !ok {
+		return nil, false
+	}
+	r, ok := right.(int)
+	if !ok {
+		return nil, false
+	}
+	switch op.Op {
+	case OpAdd:
+		return l + r, true
+	case OpSubtract:
+		return l - r, true
+	case OpMultiply:
+		return l * r, true
+	case OpDivide:
+		if r == 0 {
+			return nil, false
+		}
+		return l / r, true
+	case OpModulo:
+		if r == 0 {
+			return nil, false
+		}
+		return l % r, true
+	case OpBitwiseAnd:
+		return l & r, true
+	case OpBitwiseOr:
+		return l | r, true
+	case OpBitwiseXor:
+		return l ^ r, true
+	case OpShl:
+		if r < 0 {
+			return nil, false
+		}
+		return l << r, true
+	case OpShr:
+		if r < 0 {
+			return nil, false
+		}
+		// logical shift: the sign bit is not extended
+		mask := 1<<(op.Left.Type().Size()*8) - 1
+		return (l & mask) >> r, true
+	case OpEqual:
+		return l == r, true
+	case OpNotEqual:
+		return l != r, true
+	case OpLessThan:
+		return l < r, true
+	case OpLessEqual:
+		return l <= r, true
+	case OpGreaterThan:
+		return l > r, true
+	case OpGreaterEqual:
+		return l >= r, true
+	}
+	return nil, false
+}
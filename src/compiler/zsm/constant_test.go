@@ -0,0 +1,155 @@
+package zsm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ConstantValue(t *testing.T) {
+	five := &SemConstant{Value: 5, TypeInfo: U8Type}
+	three := &SemConstant{Value: 3, TypeInfo: U8Type}
+	yes := &SemConstant{Value: true, TypeInfo: BitType}
+
+	tests := []struct {
+		name     string
+		expr     SemExpression
+		expected any
+	}{
+		{"literal", five, 5},
+		{"add", &SemBinaryOp{Op: OpAdd, Left: five, Right: three, TypeInfo: U8Type}, 8},
+		{"multiply", &SemBinaryOp{Op: OpMultiply, Left: five, Right: three, TypeInfo: U16Type}, 15},
+		{"compare", &SemBinaryOp{Op: OpGreaterThan, Left: five, Right: three, TypeInfo: BitType}, true},
+		{"logical", &SemBinaryOp{Op: OpLogicalAnd, Left: yes, Right: &SemUnaryOp{Op: OpLogicalNot, Operand: yes, TypeInfo: BitType}, TypeInfo: BitType}, false},
+		{"bitwise not", &SemUnaryOp{Op: OpBitwiseNot, Operand: five, TypeInfo: U8Type}, 0xFA},
+		{"sizeof", &SemFunctionCall{Function: &Symbol{Name: "@sizeof"}, Arguments: []SemExpression{&SemConstant{Value: 300, TypeInfo: U16Type}}}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, ok := ConstantValue(tt.expr)
+			assert.True(t, ok)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func Test_SemConstant_Accessors(t *testing.T) {
+	number := &SemConstant{Value: 42, TypeInfo: U8Type}
+	flag := &SemConstant{Value: true, TypeInfo: BitType}
+	text := &SemConstant{Value: "hi", TypeInfo: NewArrayType(U8Type, 3)}
+	data := &SemConstant{Value: []byte{1, 2}, TypeInfo: NewArrayType(U8Type, 2)}
+
+	value, ok := number.AsInt()
+	assert.True(t, ok)
+	assert.Equal(t, 42, value)
+	_, ok = flag.AsInt()
+	assert.False(t, ok)
+
+	yes, ok := flag.AsBool()
+	assert.True(t, ok)
+	assert.True(t, yes)
+	_, ok = number.AsBool()
+	assert.False(t, ok)
+
+	// the characters of a string, without terminator
+	chars, ok := text.AsBytes()
+	assert.True(t, ok)
+	assert.Equal(t, []byte("hi"), chars)
+	bytes, ok := data.AsBytes()
+	assert.True(t, ok)
+	assert.Equal(t, []byte{1, 2}, bytes)
+	_, ok = number.AsBytes()
+	assert.False(t, ok)
+}
+
+func Test_ConstantValue_NotConstant(t *testing.T) {
+	variable := &SemSymbolRef{Symbol: &Symbol{Name: "x", Kind: SymbolVariable, Type: U8Type}}
+	five := &SemConstant{Value: 5, TypeInfo: U8Type}
+	zero := &SemConstant{Value: 0, TypeInfo: U8Type}
+
+	tests := []struct {
+		name string
+		expr SemExpression
+	}{
+		{"variable", variable},
+		{"variable operand", &SemBinaryOp{Op: OpAdd, Left: five, Right: variable, TypeInfo: U8Type}},
+		{"division by zero", &SemBinaryOp{Op: OpDivide, Left: five, Right: zero, TypeInfo: U8Type}},
+		{"modulo by zero", &SemBinaryOp{Op: OpModulo, Left: five, Right: zero, TypeInfo: U8Type}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := ConstantValue(tt.expr)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func Test_EvalConst(t *testing.T) {
+	five := &SemConstant{Value: 5, TypeInfo: U8Type}
+	three := &SemConstant{Value: 3, TypeInfo: U8Type}
+	max := &SemConstant{Value: 255, TypeInfo: U8Type}
+	limit := &Symbol{Name: "limit", Kind: SymbolVariable, Type: U8Type, Constant: &SemConstant{Value: 10, TypeInfo: U8Type}}
+
+	tests := []struct {
+		name     string
+		expr     SemExpression
+		expected any
+		typ      Type
+	}{
+		{"subtract", &SemBinaryOp{Op: OpSubtract, Left: five, Right: three, TypeInfo: U8Type}, 2, U8Type},
+		{"divide", &SemBinaryOp{Op: OpDivide, Left: five, Right: three, TypeInfo: U8Type}, 1, U8Type},
+		{"modulo", &SemBinaryOp{Op: OpModulo, Left: five, Right: three, TypeInfo: U8Type}, 2, U8Type},
+		{"overflow wraps", &SemBinaryOp{Op: OpAdd, Left: max, Right: three, TypeInfo: U8Type}, 2, U8Type},
+		{"underflow wraps", &SemBinaryOp{Op: OpSubtract, Left: three, Right: five, TypeInfo: U8Type}, 254, U8Type},
+		{"signed wraps", &SemBinaryOp{Op: OpAdd, Left: &SemConstant{Value: 127, TypeInfo: I8Type}, Right: &SemConstant{Value: 1, TypeInfo: I8Type}, TypeInfo: I8Type}, -128, I8Type},
+		{"widened", &SemBinaryOp{Op: OpMultiply, Left: max, Right: &SemConstant{Value: 2, TypeInfo: U16Type}, TypeInfo: U16Type}, 510, U16Type},
+		{"negate", &SemUnaryOp{Op: OpNegate, Operand: &SemConstant{Value: 5, TypeInfo: I8Type}, TypeInfo: I8Type}, -5, I8Type},
+		{"and", &SemBinaryOp{Op: OpBitwiseAnd, Left: five, Right: three, TypeInfo: U8Type}, 1, U8Type},
+		{"or", &SemBinaryOp{Op: OpBitwiseOr, Left: five, Right: three, TypeInfo: U8Type}, 7, U8Type},
+		{"xor", &SemBinaryOp{Op: OpBitwiseXor, Left: five, Right: three, TypeInfo: U8Type}, 6, U8Type},
+		{"shift left wraps", &SemBinaryOp{Op: OpShl, Left: max, Right: three, TypeInfo: U8Type}, 0xF8, U8Type},
+		{"shift right", &SemBinaryOp{Op: OpShr, Left: five, Right: &SemConstant{Value: 1, TypeInfo: U8Type}, TypeInfo: U8Type}, 2, U8Type},
+		{"shift right logical", &SemBinaryOp{Op: OpShr, Left: &SemConstant{Value: -128, TypeInfo: I8Type}, Right: three, TypeInfo: I8Type}, 16, I8Type},
+		{"not", &SemUnaryOp{Op: OpBitwiseNot, Operand: &SemConstant{Value: 0x00FF, TypeInfo: U16Type}, TypeInfo: U16Type}, 0xFF00, U16Type},
+		{"to bool", &SemUnaryOp{Op: OpToBool, Operand: three, TypeInfo: BitType}, true, BitType},
+		{"equal", &SemBinaryOp{Op: OpEqual, Left: five, Right: three, TypeInfo: BitType}, false, BitType},
+		{"less", &SemBinaryOp{Op: OpLessThan, Left: three, Right: five, TypeInfo: BitType}, true, BitType},
+		{"greater equal", &SemBinaryOp{Op: OpGreaterEqual, Left: five, Right: five, TypeInfo: BitType}, true, BitType},
+		{"named constant", &SemBinaryOp{Op: OpAdd, Left: &SemSymbolRef{Symbol: limit}, Right: five, TypeInfo: U8Type}, 15, U8Type},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, typ, ok := EvalConst(tt.expr)
+			assert.True(t, ok)
+			assert.Equal(t, tt.expected, value)
+			assert.Equal(t, tt.typ, typ)
+		})
+	}
+}
+
+func Test_EvalConst_NotConstant(t *testing.T) {
+	variable := &SemSymbolRef{Symbol: &Symbol{Name: "x", Kind: SymbolVariable, Type: U8Type}}
+	five := &SemConstant{Value: 5, TypeInfo: U8Type}
+
+	tests := []struct {
+		name string
+		expr SemExpression
+	}{
+		{"variable", variable},
+		{"comparison", &SemBinaryOp{Op: OpLessThan, Left: variable, Right: five, TypeInfo: BitType}},
+		{"bitwise not", &SemUnaryOp{Op: OpBitwiseNot, Operand: variable, TypeInfo: U8Type}},
+		{"string", &SemConstant{Value: "hello", TypeInfo: NewArrayType(U8Type, 5)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, typ, ok := EvalConst(tt.expr)
+			assert.False(t, ok)
+			assert.Nil(t, value)
+			assert.Nil(t, typ)
+		})
+	}
+}
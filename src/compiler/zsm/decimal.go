@@ -0,0 +1,79 @@
+package zsm
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Decimal is the value of a decimal fixed-point literal (1.5).
+// The decimal types store it as packed BCD with a fixed number of fraction digits:
+// d8 has one integer and one fraction digit (0.0-9.9), d16 has two of each (00.00-99.99).
+type Decimal struct {
+	Integer  string // digits before the '.'
+	Fraction string // digits after the '.'
+}
+
+// ParseDecimal splits the text of a decimal literal (1.5) at its decimal point
+// Returns false when the text has no decimal point.
+func ParseDecimal(text string) (Decimal, bool) {
+	integer, fraction, ok := strings.Cut(strings.ReplaceAll(text, "_", ""), ".")
+	if !ok {
+		return Decimal{}, false
+	}
+	return Decimal{Integer: integer, Fraction: fraction}, true
+}
+
+func (d Decimal) String() string {
+	return d.Integer + "." + d.Fraction
+}
+
+// BCD returns the packed BCD value of the decimal stored in the decimal type.
+// Returns false when the type is not a decimal type or the value does not fit.
+func (d Decimal) BCD(typ Type) (int, bool) {
+	fractionDigits := decimalFractionDigits(typ)
+	if fractionDigits == 0 {
+		return 0, false
+	}
+	integerDigits := int(typ.Size())*2 - fractionDigits
+
+	integer := strings.TrimLeft(d.Integer, "0")
+	fraction := strings.TrimRight(d.Fraction, "0")
+	if len(integer) > integerDigits || len(fraction) > fractionDigits {
+		return 0, false
+	}
+
+	// one digit per nibble: the decimal digits read as hexadecimal
+	digits := strings.Repeat("0", integerDigits-len(integer)) + integer +
+		fraction + strings.Repeat("0", fractionDigits-len(fraction))
+	value, err := strconv.ParseUint(digits, 16, 16)
+	if err != nil {
+		return 0, false
+	}
+	return int(value), true
+}
+
+// Type returns the smallest decimal type the value fits in, nil when it fits none
+func (d Decimal) Type() Type {
+	for _, typ := range []Type{D8Type, D16Type} {
+		if _, ok := d.BCD(typ); ok {
+			return typ
+		}
+	}
+	return nil
+}
+
+// IsDecimalType returns true for the decimal (BCD) types (d8, d16)
+func IsDecimalType(t Type) bool {
+	return decimalFractionDigits(t) > 0
+}
+
+// decimalFractionDigits returns the number of fraction digits of a decimal type, 0 for other types
+func decimalFractionDigits(t Type) int {
+	switch t {
+	case D8Type:
+		return 1
+	case D16Type:
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+package zsm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Decimal_BCD(t *testing.T) {
+	tests := []struct {
+		text  string
+		typ   Type
+		value int
+		ok    bool
+	}{
+		{"1.5", D8Type, 0x15, true},
+		{"9.9", D8Type, 0x99, true},
+		{"1.50", D8Type, 0x15, true},
+		{"01.5", D8Type, 0x15, true},
+		{"1.", D8Type, 0x10, true},
+		{"12.34", D16Type, 0x1234, true},
+		{"1.5", D16Type, 0x0150, true},
+		{"10.5", D8Type, 0, false},
+		{"1.25", D8Type, 0, false},
+		{"1.5", U8Type, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text+"_"+tt.typ.Name(), func(t *testing.T) {
+			decimal, ok := ParseDecimal(tt.text)
+			require.True(t, ok)
+			value, ok := decimal.BCD(tt.typ)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.value, value)
+		})
+	}
+}
+
+func Test_Decimal_Type(t *testing.T) {
+	decimal, _ := ParseDecimal("1.5")
+	assert.Equal(t, D8Type, decimal.Type())
+	decimal, _ = ParseDecimal("1.25")
+	assert.Equal(t, D16Type, decimal.Type())
+	decimal, _ = ParseDecimal("123.4")
+	assert.Nil(t, decimal.Type())
+}
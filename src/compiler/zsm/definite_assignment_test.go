@@ -0,0 +1,143 @@
+package zsm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ============================================================================
+// Definite Assignment Tests
+// ============================================================================
+
+func Test_Analyze_ReadBeforeAssignment_Error(t *testing.T) {
+	code := `main: () u8 {
+		x: u8
+		y := x + 1
+		ret y
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_ReadBeforeAssignment_Error", code)
+
+	require.Equal(t, 1, len(errors))
+	assert.Contains(t, errors[0].Error(), "variable 'x' used before assignment")
+}
+
+func Test_Analyze_AssignThenRead_Valid(t *testing.T) {
+	code := `main: () u8 {
+		x: u8
+		x = 5
+		y := x + 1
+		ret y
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_AssignThenRead_Valid", code)
+	requireNoErrors(t, errors)
+}
+
+func Test_Analyze_ConditionallyAssignedRead_Error(t *testing.T) {
+	code := `main: (c: u8) u8 {
+		x: u8
+		if c > 0 {
+			x = 1
+		}
+		ret x
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_ConditionallyAssignedRead_Error", code)
+
+	require.Equal(t, 1, len(errors))
+	assert.Contains(t, errors[0].Error(), "variable 'x' used before assignment")
+}
+
+func Test_Analyze_AssignedInAllBranches_Valid(t *testing.T) {
+	code := `main: (c: u8) u8 {
+		x: u8
+		if c > 10 {
+			x = 1
+		} elsif c > 5 {
+			x = 2
+		} else {
+			x = 3
+		}
+		ret x
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_AssignedInAllBranches_Valid", code)
+	requireNoErrors(t, errors)
+}
+
+func Test_Analyze_AssignedInLoopBody_Error(t *testing.T) {
+	code := `main: (c: u8) u8 {
+		x: u8
+		for i := 0; i < c; i++ {
+			x = i
+		}
+		ret x
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_AssignedInLoopBody_Error", code)
+
+	require.Equal(t, 1, len(errors))
+	assert.Contains(t, errors[0].Error(), "variable 'x' used before assignment")
+}
+
+func Test_Analyze_AssignedInDoWhileBody_Valid(t *testing.T) {
+	code := `main: (c: u8) u8 {
+		x: u8
+		do {
+			x = c
+			c -= 1
+		} while c > 0
+		ret x
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_AssignedInDoWhileBody_Valid", code)
+	requireNoErrors(t, errors)
+}
+
+func Test_Analyze_CompoundAssignmentBeforeAssignment_Error(t *testing.T) {
+	code := `main: () {
+		x: u8
+		x += 1
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_CompoundAssignmentBeforeAssignment_Error", code)
+
+	require.Equal(t, 1, len(errors))
+	assert.Contains(t, errors[0].Error(), "variable 'x' used before assignment")
+}
+
+func Test_Analyze_ParametersAndGlobalsInitialized_Valid(t *testing.T) {
+	code := `count: u8
+	main: (p: u8) u8 {
+		ret count + p
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_ParametersAndGlobalsInitialized_Valid", code)
+	requireNoErrors(t, errors)
+}
+
+func Test_Analyze_AssignedUnlessNoReturn_Valid(t *testing.T) {
+	code := `main: (c: u8) u8 {
+		x: u8
+		if c > 0 {
+			x = 1
+		} else {
+			panic()
+		}
+		ret x
+	}
+	@noreturn
+	panic: () {
+		@halt()
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_AssignedUnlessNoReturn_Valid", code)
+	requireNoErrors(t, errors)
+}
+
+func Test_Analyze_AssignedUnlessReturn_Valid(t *testing.T) {
+	code := `main: (c: u8) u8 {
+		x: u8
+		if c > 0 {
+			ret 0
+		}
+		x = c
+		ret x
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_AssignedUnlessReturn_Valid", code)
+	requireNoErrors(t, errors)
+}
@@ -0,0 +1,151 @@
+package zsm
+
+import (
+	"testing"
+	"zenith/compiler"
+	"zenith/compiler/lexer"
+	"zenith/compiler/parser"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseUnit parses the code of one source file
+func parseUnit(t *testing.T, name string, code string) parser.CompilationUnit {
+	astNode, parseErrors := parser.Parse(&compiler.Source{Name: name}, lexer.OpenTokenStream(code))
+	require.Empty(t, parseErrors)
+	unit, ok := astNode.(parser.CompilationUnit)
+	require.True(t, ok)
+	return unit
+}
+
+func Test_AnalyzeUnits_CrossFileCall(t *testing.T) {
+	mainUnit := parseUnit(t, "main.zen", `import "math"
+	main: () {
+		x: = double(21)
+	}`)
+	mathUnit := parseUnit(t, "math.zen", `export double: (v: u8) u8 {
+		ret v + v
+	}`)
+
+	semCU, errors := NewSemanticAnalyzer().AnalyzeUnits([]parser.CompilationUnit{mainUnit, mathUnit})
+	requireNoErrors(t, errors)
+	require.Len(t, semCU.Declarations, 2)
+
+	mainDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	varDecl := mainDecl.Body.Statements[0].(*SemVariableDecl)
+	call, ok := varDecl.Initializer.(*SemFunctionCall)
+	require.True(t, ok)
+	assert.Equal(t, "double", call.Function.Name)
+	assert.Equal(t, SymbolFunction, call.Function.Kind)
+	assert.Equal(t, U8Type, call.Type())
+}
+
+func Test_AnalyzeUnits_CrossFileType(t *testing.T) {
+	mainUnit := parseUnit(t, "main.zen", `main: () {
+		p: Point
+	}`)
+	typesUnit := parseUnit(t, "types.zen", `export struct Point {
+		x: u8,
+		y: u8
+	}`)
+
+	_, errors := NewSemanticAnalyzer().AnalyzeUnits([]parser.CompilationUnit{mainUnit, typesUnit})
+	requireNoErrors(t, errors)
+}
+
+func Test_AnalyzeUnits_CrossFileDuplicate(t *testing.T) {
+	first := parseUnit(t, "first.zen", `helper: () {
+	}`)
+	second := parseUnit(t, "second.zen", `helper: () {
+	}`)
+
+	_, errors := NewSemanticAnalyzer().AnalyzeUnits([]parser.CompilationUnit{first, second})
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "function 'helper' already declared")
+	assert.Equal(t, "second.zen", errors[0].Source.Name)
+}
+
+func Test_AnalyzeUnits_NotExported(t *testing.T) {
+	tests := []struct {
+		name     string
+		mainCode string
+		libCode  string
+		expected string
+	}{
+		{"function", `main: () {
+			helper()
+		}`, `helper: () {
+		}`, "function 'helper' is not exported"},
+		{"variable", `main: () {
+			x: = count
+		}`, `count: u8 = 42`, "variable 'count' is not exported"},
+		{"type", `main: () {
+			p: Point
+		}`, `struct Point {
+			x: u8
+		}`, "type 'Point' is not exported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mainUnit := parseUnit(t, "main.zen", tt.mainCode)
+			libUnit := parseUnit(t, "lib.zen", tt.libCode)
+
+			_, errors := NewSemanticAnalyzer().AnalyzeUnits([]parser.CompilationUnit{mainUnit, libUnit})
+
+			require.NotEmpty(t, errors)
+			assert.Contains(t, errors[0].Error(), tt.expected)
+			assert.Equal(t, "main.zen", errors[0].Source.Name)
+		})
+	}
+}
+
+func Test_AnalyzeUnits_NotExported_SameModule(t *testing.T) {
+	unit := parseUnit(t, "main.zen", `helper: () {
+	}
+	main: () {
+		helper()
+	}`)
+
+	_, errors := NewSemanticAnalyzer().AnalyzeUnits([]parser.CompilationUnit{unit})
+	requireNoErrors(t, errors)
+}
+
+func Test_AnalyzeUnits_ExportedVariable(t *testing.T) {
+	mainUnit := parseUnit(t, "main.zen", `main: () {
+		x: = count
+	}`)
+	libUnit := parseUnit(t, "lib.zen", `export count: u8 = 42`)
+
+	_, errors := NewSemanticAnalyzer().AnalyzeUnits([]parser.CompilationUnit{mainUnit, libUnit})
+	requireNoErrors(t, errors)
+}
+
+func Test_Analyze_ExportLocalVariable_Error(t *testing.T) {
+	code := `main: () {
+		export x: = 42
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_ExportLocalVariable_Error", code)
+
+	require.NotEmpty(t, errors)
+	assert.Contains(t, errors[0].Error(), "local variable 'x' cannot be exported")
+}
+
+func Test_AnalyzeUnits_UnknownModule(t *testing.T) {
+	unit := parseUnit(t, "main.zen", `import "missing"
+	main: () {
+	}`)
+
+	_, errors := NewSemanticAnalyzer().AnalyzeUnits([]parser.CompilationUnit{unit})
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "unknown module 'missing'")
+}
+
+func Test_ModuleName(t *testing.T) {
+	assert.Equal(t, "math", ModuleName(&compiler.Source{Name: "math.zen"}))
+	assert.Equal(t, "math", ModuleName(&compiler.Source{Name: "lib/math.zen"}))
+	assert.Equal(t, "math", ModuleName(&compiler.Source{Name: "math"}))
+}
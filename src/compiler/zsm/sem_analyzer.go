@@ -2,7 +2,11 @@ package zsm
 
 import (
 	"fmt"
+	"maps"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"strings"
 	"zenith/compiler"
 	"zenith/compiler/lexer"
 	"zenith/compiler/parser"
@@ -15,47 +19,236 @@ type SemanticAnalyzer struct {
 	currentFunction string // Track which function we're analyzing
 	callGraph       *CallGraph
 	errors          []*compiler.Diagnostic
+	warnings        []*compiler.Diagnostic
+	// local variables declared without initializer that are not assigned on all paths (yet)
+	unassigned map[*Symbol]bool
+	// storage format of string literals (determines their array length)
+	stringFormat StringFormat
+	// module (source file) of the declarations being analyzed
+	currentModule string
+	// name of the required entry-point function, empty when not required (library)
+	entryPoint string
+	// declared struct types whose fields are not resolved yet
+	pendingTypes map[*StructType]*pendingType
+	// reads the files embedded with '@include_bin'
+	fileResolver FileResolver
+	// identifiers bound to symbols (declarations are resolved more than once)
+	references  []*SymbolReference
+	referenced  map[referenceKey]bool
 }
 
+// referenceKey identifies an identifier in the source files
+type referenceKey struct {
+	module string
+	index  int
+}
+
+// pendingType is a struct type declaration registered by name, its fields are resolved later
+type pendingType struct {
+	node      parser.TypeDeclaration
+	module    string
+	resolving bool
+}
+
+// SemanticAnalyzerOptions configures the semantic analyzer
+type SemanticAnalyzerOptions struct {
+	// StringFormat is how the backend stores string literals
+	StringFormat StringFormat
+	// EntryPoint is the function a runnable program starts with (usually DefaultEntryPoint).
+	// Empty disables the check (library builds).
+	EntryPoint string
+	// FileResolver reads the files embedded with '@include_bin'
+	FileResolver FileResolver
+}
+
+// FileResolver returns the content of the file at the path
+type FileResolver func(path string) ([]byte, error)
+
+// DefaultEntryPoint is the name of the function a program starts with
+const DefaultEntryPoint = "main"
+
 // NewSemanticAnalyzer creates a new semantic analyzer
 func NewSemanticAnalyzer() *SemanticAnalyzer {
+	return NewSemanticAnalyzerWithOptions(SemanticAnalyzerOptions{})
+}
+
+// NewSemanticAnalyzerWithOptions creates a new semantic analyzer with the specified options
+func NewSemanticAnalyzerWithOptions(options SemanticAnalyzerOptions) *SemanticAnalyzer {
 	sa := &SemanticAnalyzer{
-		callGraph: NewCallGraph(),
-		errors:    make([]*compiler.Diagnostic, 0),
+		callGraph:    NewCallGraph(),
+		errors:       make([]*compiler.Diagnostic, 0),
+		stringFormat: options.StringFormat,
+		entryPoint:   options.EntryPoint,
+		fileResolver: options.FileResolver,
 	}
 	return sa
 }
 
 // Analyze performs semantic analysis on the AST and returns the semantic model
 func (sa *SemanticAnalyzer) Analyze(ast parser.CompilationUnit) (*SemCompilationUnit, []*compiler.Diagnostic) {
+	return sa.AnalyzeUnits([]parser.CompilationUnit{ast})
+}
+
+// AnalyzeUnits analyzes the compilation units (one per source file) of a program together.
+// All top-level declarations share the global scope, so functions, types and globals
+// can be used across files. Duplicate symbols across files are reported.
+func (sa *SemanticAnalyzer) AnalyzeUnits(units []parser.CompilationUnit) (*SemCompilationUnit, []*compiler.Diagnostic) {
 	// Initialize global scope
 	sa.globalScope = NewSymbolTable(nil, "<global>")
 	sa.currentScope = sa.globalScope
 	sa.initBuiltinTypes()
+	sa.references = nil
+	sa.referenced = make(map[referenceKey]bool)
 
 	// Pass 1: Register all top-level declarations (types, functions, globals)
-	// This allows forward references to work
-	for _, decl := range ast.Declarations() {
-		sa.registerDeclaration(decl)
+	// This allows forward (and cross-file) references to work.
+	// Type names go first, so any declaration can use a type declared after it.
+	sa.pendingTypes = make(map[*StructType]*pendingType)
+	for _, unit := range units {
+		sa.currentModule = unitModule(unit)
+		for _, decl := range compiler.OfType[parser.TypeDeclaration](unit.Declarations()) {
+			sa.declareType(decl)
+		}
+	}
+	for _, unit := range units {
+		sa.currentModule = unitModule(unit)
+		for _, decl := range unit.Declarations() {
+			sa.registerDeclaration(decl)
+		}
 	}
+	sa.checkImports(units)
 
 	// Pass 2: Build semantic model with full type checking and resolution
-	semDecls := make([]SemDeclaration, 0, len(ast.Declarations()))
-	for _, decl := range ast.Declarations() {
-		semDecl := sa.processDeclaration(decl)
-		if semDecl != nil {
-			semDecls = append(semDecls, semDecl)
+	semDecls := make([]SemDeclaration, 0)
+	for _, unit := range units {
+		sa.currentModule = unitModule(unit)
+		for _, decl := range unit.Declarations() {
+			semDecl := sa.processDeclaration(decl)
+			if semDecl != nil {
+				semDecls = append(semDecls, semDecl)
+			}
 		}
 	}
+	sa.checkEntryPoint(units, semDecls)
 
+	var ast parser.CompilationUnit
+	if len(units) > 0 {
+		ast = units[0]
+	}
 	return &SemCompilationUnit{
 		Declarations: semDecls,
 		GlobalScope:  sa.globalScope,
 		CallGraph:    sa.callGraph,
+		References:   sa.references,
+		Warnings:     sa.warnings,
 		astNode:      ast,
 	}, sa.errors
 }
 
+// checkImports reports imports of modules that are not part of the compilation
+// A module is a source file, named without its extension: 'import "math"' => math.zen
+func (sa *SemanticAnalyzer) checkImports(units []parser.CompilationUnit) {
+	modules := make(map[string]bool, len(units))
+	for _, unit := range units {
+		modules[unitModule(unit)] = true
+	}
+
+	for _, unit := range units {
+		for _, imp := range compiler.OfType[parser.ImportDeclaration](unit.Declarations()) {
+			if name := imp.ModuleName(); name != "" && !modules[name] {
+				sa.error(fmt.Sprintf("unknown module '%s'", name), imp)
+			}
+		}
+	}
+}
+
+// checkEntryPoint validates the entry-point function of a runnable program:
+// it must be declared, take no parameters and return nothing.
+func (sa *SemanticAnalyzer) checkEntryPoint(units []parser.CompilationUnit, decls []SemDeclaration) {
+	if sa.entryPoint == "" {
+		return
+	}
+
+	for _, fn := range compiler.OfType[*SemFunctionDecl](decls) {
+		if fn.Name != sa.entryPoint {
+			continue
+		}
+		if len(fn.Parameters) > 0 {
+			sa.error(fmt.Sprintf("entry point '%s' cannot have parameters", fn.Name), fn.AST())
+		}
+		if fn.ReturnType != nil {
+			sa.error(fmt.Sprintf("entry point '%s' cannot return a value", fn.Name), fn.AST())
+		}
+		return
+	}
+
+	// nothing to point at: report on the (first) program file
+	source := &compiler.Source{Name: "<program>"}
+	if len(units) > 0 && units[0].Source() != nil {
+		source = units[0].Source()
+	}
+	msg := fmt.Sprintf("entry point '%s' is not declared", sa.entryPoint)
+	err := compiler.NewDiagnostic(source, msg, compiler.Location{}, compiler.PipelineSemanticAnalysis, compiler.SeverityError)
+	sa.errors = append(sa.errors, err)
+}
+
+// unitModule returns the module name of the compilation unit
+func unitModule(unit parser.CompilationUnit) string {
+	if source := unit.Source(); source != nil {
+		return ModuleName(source)
+	}
+	return ""
+}
+
+// checkAccess reports the use of a symbol that is not exported by its module
+// Returns false when an error was reported.
+func (sa *SemanticAnalyzer) checkAccess(symbol *Symbol, node parser.ParserNode) bool {
+	if symbol.Exported || symbol.Module == "" || symbol.Module == sa.currentModule {
+		return true
+	}
+
+	kind := "variable"
+	switch symbol.Kind {
+	case SymbolFunction:
+		kind = "function"
+	case SymbolType:
+		kind = "type"
+	}
+	sa.error(fmt.Sprintf("%s '%s' is not exported", kind, symbol.Name), node)
+	return false
+}
+
+// declareTopLevel records the module of a top-level symbol and if it is exported
+func (sa *SemanticAnalyzer) declareTopLevel(symbol *Symbol, exported bool) {
+	symbol.Module = sa.currentModule
+	symbol.Exported = exported
+}
+
+// reference records the identifier token that is bound to the symbol (for tooling)
+func (sa *SemanticAnalyzer) reference(symbol *Symbol, identifier lexer.Token) {
+	if identifier == nil {
+		return
+	}
+	start, end := parser.TokenSpan(identifier)
+	key := referenceKey{sa.currentModule, start.Index}
+	if sa.referenced[key] {
+		return
+	}
+	sa.referenced[key] = true
+	sa.references = append(sa.references, &SymbolReference{
+		Symbol: symbol,
+		Module: sa.currentModule,
+		Start:  start,
+		End:    end,
+	})
+}
+
+// ModuleName returns the module name of a source: its file name without extension
+func ModuleName(source *compiler.Source) string {
+	name := filepath.Base(source.Name)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
 // ============================================================================
 // Built-in Types Initialization
 // ============================================================================
@@ -73,7 +266,26 @@ func (sa *SemanticAnalyzer) initBuiltinTypes() {
 		"bit": BitType,
 
 		// intrinsic functions
-		"@len": LenFnType,
+		"@len":      LenFnType,
+		"@bit":      BitFnType,
+		"@setbit":   SetBitFnType,
+		"@resetbit": ResetBitFnType,
+		"@peek":     PeekFnType,
+		"@poke":     PokeFnType,
+		"@memchr":   MemChrFnType,
+		"@memset":   MemSetFnType,
+		"@halt":     HaltFnType,
+		"@nop":      NopFnType,
+		"@rst":      RstFnType,
+		"@overflow": OverflowFnType,
+		"@assert":   AssertFnType,
+		"@sizeof":   SizeofFnType,
+		// replaced by the file content (processIncludeBin)
+		"@include_bin": IncludeBinFnType,
+		// replaced by the bytes (processDataBytes)
+		"@db": DataBytesFnType,
+		// resolved to the function symbol (processAddressOf)
+		"@addressof": AddressOfFnType,
 	}
 	for name, typ := range builtins {
 		sa.globalScope.Add(&Symbol{
@@ -93,29 +305,37 @@ func (sa *SemanticAnalyzer) registerDeclaration(node parser.ParserNode) {
 	case parser.VariableDeclaration:
 		// Only register if it has an explicit type (not inferred)
 		if typeRef := n.TypeRef(); typeRef != nil {
-			sa.registerVariable(n.Label().Name(), typeRef)
+			sa.registerVariable(n, typeRef)
 		}
 		// Inferred types will be resolved in pass 2
 	case parser.FunctionDeclaration:
 		sa.registerFunction(n)
 	case parser.TypeDeclaration:
 		sa.registerType(n)
+	case parser.ImportDeclaration:
+		// modules are checked when all units are registered
 	default:
 		sa.error(fmt.Sprintf("unknown declaration type: %T", node), node)
 	}
 }
 
-func (sa *SemanticAnalyzer) registerVariable(name string, typeRef parser.TypeRef) {
+func (sa *SemanticAnalyzer) registerVariable(node parser.VariableDeclaration, typeRef parser.TypeRef) {
 	typ := sa.resolveTypeRef(typeRef)
 	if typ == nil {
 		return // Error already reported
 	}
 
+	name := node.Label().Name()
+	if sa.isBuiltinType(name) {
+		return // reported when the declaration is processed
+	}
 	symbol := &Symbol{
-		Name: name,
-		Kind: SymbolVariable,
-		Type: typ,
+		Name:        name,
+		Kind:        SymbolVariable,
+		Type:        typ,
+		Declaration: node,
 	}
+	sa.declareTopLevel(symbol, node.IsExported())
 
 	if !sa.currentScope.Add(symbol) {
 		sa.error(fmt.Sprintf("symbol '%s' already declared in this scope", name), typeRef)
@@ -125,11 +345,13 @@ func (sa *SemanticAnalyzer) registerVariable(name string, typeRef parser.TypeRef
 func (sa *SemanticAnalyzer) registerFunction(node parser.FunctionDeclaration) {
 	// Parse parameter types
 	paramTypes := []Type{}
+	paramNames := []string{}
 	if params := node.Parameters(); params != nil {
 		for _, field := range params.Fields() {
 			typ := sa.resolveTypeRef(field.TypeRef())
 			if typ != nil {
 				paramTypes = append(paramTypes, typ)
+				paramNames = append(paramNames, field.Label().Name())
 			}
 		}
 	}
@@ -141,42 +363,97 @@ func (sa *SemanticAnalyzer) registerFunction(node parser.FunctionDeclaration) {
 	}
 
 	funcType := NewFunctionType(paramTypes, returnType)
+	funcType.parameterNames = paramNames
 	symbol := &Symbol{
-		Name: node.Label().Name(),
-		Kind: SymbolFunction,
-		Type: funcType,
+		Name:        node.Label().Name(),
+		Kind:        SymbolFunction,
+		Type:        funcType,
+		Declaration: node,
+	}
+	// known before the function body is analyzed: calls may precede the declaration
+	for _, attr := range node.Attributes() {
+		if attr.Text() == AttributeNoReturn {
+			symbol.NoReturn = true
+		}
 	}
+	sa.declareTopLevel(symbol, node.IsExported())
 
 	if !sa.currentScope.Add(symbol) {
 		sa.error(fmt.Sprintf("function '%s' already declared", node.Label().Name()), node)
 	}
 }
 
+// declareType adds the struct type as a symbol without its fields,
+// so declarations (and fields: 'next: Node*') can refer to it before it is declared.
+func (sa *SemanticAnalyzer) declareType(node parser.TypeDeclaration) {
+	if !sa.checkBuiltinTypeName(node.Name().Text(), node) {
+		return
+	}
+	structType := NewStructType(node.Name().Text(), nil)
+	symbol := &Symbol{
+		Name:        structType.Name(),
+		Kind:        SymbolType,
+		Type:        structType,
+		Declaration: node,
+	}
+	sa.declareTopLevel(symbol, node.IsExported())
+	if sa.currentScope.Add(symbol) {
+		sa.pendingTypes[structType] = &pendingType{node: node, module: sa.currentModule}
+	}
+}
+
 func (sa *SemanticAnalyzer) registerType(node parser.TypeDeclaration) {
-	name := node.Name().Text()
+	if symbol := sa.currentScope.Lookup(node.Name().Text()); symbol != nil {
+		if structType, ok := symbol.Type.(*StructType); ok {
+			sa.resolveStructFields(structType)
+		}
+	}
+}
+
+// resolveStructFields resolves the fields of a declared struct type and computes their offsets.
+// Structs stored by value are resolved first, their size determines the offsets.
+func (sa *SemanticAnalyzer) resolveStructFields(structType *StructType) {
+	pending, ok := sa.pendingTypes[structType]
+	if !ok || pending.resolving {
+		return
+	}
+	pending.resolving = true
+	module := sa.currentModule
+	sa.currentModule = pending.module
 
-	// Build struct fields
 	fields := []*StructField{}
-	if fieldList := node.Fields(); fieldList != nil {
+	if fieldList := pending.node.Fields(); fieldList != nil {
 		for _, field := range fieldList.Fields().Fields() {
 			fieldType := sa.resolveTypeRef(field.TypeRef())
-			if fieldType != nil {
-				fields = append(fields, &StructField{
-					Name: field.Label().Name(),
-					Type: fieldType,
-				})
+			if fieldType == nil {
+				continue
+			}
+			if inner := byValueStruct(fieldType); inner != nil {
+				if other, ok := sa.pendingTypes[inner]; ok && other.resolving {
+					sa.error(fmt.Sprintf("struct '%s' cannot contain itself by value", structType.Name()), field.TypeRef())
+					continue
+				}
+				sa.resolveStructFields(inner)
 			}
+			fields = append(fields, &StructField{
+				Name: field.Label().Name(),
+				Type: fieldType,
+			})
 		}
 	}
+	structType.setFields(fields)
 
-	structType := NewStructType(name, fields)
+	delete(sa.pendingTypes, structType)
+	sa.currentModule = module
+}
 
-	// Add type as a symbol
-	sa.currentScope.Add(&Symbol{
-		Name: name,
-		Kind: SymbolType,
-		Type: structType,
-	})
+// byValueStruct returns the struct type a field type stores by value (not through a pointer)
+func byValueStruct(fieldType Type) *StructType {
+	if arrayType, ok := fieldType.(*ArrayType); ok {
+		return byValueStruct(arrayType.ElementType())
+	}
+	structType, _ := fieldType.(*StructType)
+	return structType
 }
 
 // ============================================================================
@@ -191,6 +468,9 @@ func (sa *SemanticAnalyzer) processDeclaration(node parser.ParserNode) SemDeclar
 		return sa.processFunctionDecl(n)
 	case parser.TypeDeclaration:
 		return sa.processTypeDecl(n)
+	case parser.ImportDeclaration:
+		// all units share the global scope, nothing to declare
+		return nil
 	default:
 		sa.error(fmt.Sprintf("unknown declaration type: %T", node), node)
 		return nil
@@ -202,6 +482,14 @@ func (sa *SemanticAnalyzer) processVarDecl(node parser.VariableDeclaration) *Sem
 	typeRef := node.TypeRef()
 	initExpr := node.Initializer()
 
+	if !sa.checkBuiltinTypeName(name, node) {
+		return nil
+	}
+
+	if node.IsExported() && !sa.currentScope.IsGlobal() {
+		sa.error(fmt.Sprintf("local variable '%s' cannot be exported", name), node)
+	}
+
 	var symbol *Symbol
 	var initializer SemExpression
 
@@ -219,6 +507,9 @@ func (sa *SemanticAnalyzer) processVarDecl(node parser.VariableDeclaration) *Sem
 				sa.error(fmt.Sprintf("initializer for '%s' not valid", node.Label().Name()), node)
 				return nil
 			}
+			if initializer = sa.convertDecimal(initializer, varType, node); initializer == nil {
+				return nil
+			}
 
 			// Check that initializer type matches variable type
 			var typeIsValid bool = false
@@ -258,6 +549,7 @@ func (sa *SemanticAnalyzer) processVarDecl(node parser.VariableDeclaration) *Sem
 			QualifiedName: sa.currentScope.GetQualifiedName(name),
 			Kind:          SymbolVariable,
 			Type:          varType,
+			Declaration:   node,
 		}
 
 		// globals have been registered already
@@ -267,6 +559,12 @@ func (sa *SemanticAnalyzer) processVarDecl(node parser.VariableDeclaration) *Sem
 				sa.error(fmt.Sprintf("symbol '%s' already declared in this scope", name), node)
 				return nil
 			}
+			sa.checkShadowing(name, node)
+		} else if registered := sa.currentScope.LookupLocal(name); registered != nil && registered.Declaration == node {
+			// the declaration carries the symbol the uses are bound to
+			registered.QualifiedName = symbol.QualifiedName
+			registered.Type = varType
+			symbol = registered
 		}
 	} else {
 		// Inferred type: initializer is mandatory
@@ -280,6 +578,9 @@ func (sa *SemanticAnalyzer) processVarDecl(node parser.VariableDeclaration) *Sem
 		if initializer == nil {
 			return nil
 		}
+		if initializer = sa.convertDecimal(initializer, initializer.Type(), node); initializer == nil {
+			return nil
+		}
 
 		// Create symbol with inferred type
 		symbol = &Symbol{
@@ -287,16 +588,25 @@ func (sa *SemanticAnalyzer) processVarDecl(node parser.VariableDeclaration) *Sem
 			QualifiedName: sa.currentScope.GetQualifiedName(name),
 			Kind:          SymbolVariable,
 			Type:          initializer.Type(),
+			Declaration:   node,
+		}
+		if sa.currentScope.IsGlobal() {
+			sa.declareTopLevel(symbol, node.IsExported())
 		}
 		if !sa.currentScope.Add(symbol) {
 			sa.error(fmt.Sprintf("symbol '%s' already declared in this scope", name), node)
 			return nil
 		}
+		if !sa.currentScope.IsGlobal() {
+			sa.checkShadowing(name, node)
+		}
 	}
 
 	// Track initialization pattern
 	if initializer != nil {
 		sa.trackInitializationPattern(symbol, initializer)
+	} else if !sa.currentScope.IsGlobal() {
+		sa.declareUnassigned(symbol)
 	}
 
 	var typeInfo Type
@@ -333,16 +643,26 @@ func (sa *SemanticAnalyzer) processFunctionDecl(node parser.FunctionDeclaration)
 	sa.pushScope(funcScope)
 	defer sa.popScope()
 
+	// Parameters and globals are always initialized
+	prevUnassigned := sa.unassigned
+	sa.unassigned = make(map[*Symbol]bool)
+	defer func() { sa.unassigned = prevUnassigned }()
+
 	// Add parameters to function scope
 	parameters := make([]*Symbol, 0)
 	if params := node.Parameters(); params != nil {
 		for _, field := range params.Fields() {
 			paramType := sa.resolveTypeRef(field.TypeRef())
+			if !sa.checkBuiltinTypeName(field.Label().Name(), field) {
+				continue
+			}
 
 			paramSymbol := &Symbol{
-				Name: field.Label().Name(),
-				Kind: SymbolVariable,
-				Type: paramType,
+				Name:        field.Label().Name(),
+				Kind:        SymbolVariable,
+				Type:        paramType,
+				Parameter:   true,
+				Declaration: field,
 			}
 			funcScope.Add(paramSymbol)
 			parameters = append(parameters, paramSymbol)
@@ -360,8 +680,16 @@ func (sa *SemanticAnalyzer) processFunctionDecl(node parser.FunctionDeclaration)
 		sa.validateReturnType(returnType, node)
 	}
 
+	attributes := sa.processFunctionAttributes(node, len(parameters) > 0 || returnType != nil)
+	var org *uint16
+	if slices.Contains(attributes, AttributeOrg) {
+		org = sa.processOrgAddress(node)
+	}
+
 	return &SemFunctionDecl{
 		Name:       name,
+		Attributes: attributes,
+		Org:        org,
 		Parameters: parameters,
 		ReturnType: returnType,
 		Body:       body,
@@ -372,6 +700,9 @@ func (sa *SemanticAnalyzer) processFunctionDecl(node parser.FunctionDeclaration)
 
 func (sa *SemanticAnalyzer) processTypeDecl(node parser.TypeDeclaration) *SemTypeDecl {
 	name := node.Name().Text()
+	if sa.isBuiltinType(name) {
+		return nil // reported when declared
+	}
 	symbol := sa.currentScope.Lookup(name)
 	if symbol == nil || symbol.Kind != SymbolType {
 		sa.error(fmt.Sprintf("internal error: type '%s' not found", name), node)
@@ -395,14 +726,26 @@ func (sa *SemanticAnalyzer) processTypeDecl(node parser.TypeDeclaration) *SemTyp
 // Statement Processing
 // ============================================================================
 
+// processBlockScope processes a nested block (if, select, loop body) in a scope of its own:
+// its declarations may shadow the outer ones and are not visible after the block.
+func (sa *SemanticAnalyzer) processBlockScope(node parser.CodeBlock) *SemBlock {
+	sa.pushScope(sa.currentScope.NewBlockScope())
+	defer sa.popScope()
+	return sa.processBlock(node)
+}
+
 func (sa *SemanticAnalyzer) processBlock(node parser.CodeBlock) *SemBlock {
-	// Use current scope (function scope) - no new scope for blocks
+	// Use current scope (function scope or block scope)
 
 	statements := []SemStatement{}
 	for _, stmt := range node.Statements() {
 		semStmt := sa.processStatement(stmt)
 		if semStmt != nil {
 			statements = append(statements, semStmt)
+			// the rest of the block is unreachable: this path does not constrain the variables after the branch
+			if Terminates(semStmt) {
+				clear(sa.unassigned)
+			}
 		}
 	}
 
@@ -418,16 +761,27 @@ func (sa *SemanticAnalyzer) processStatement(node parser.ParserNode) SemStatemen
 		return sa.processVarDecl(n)
 	case parser.VariableAssignment:
 		return sa.processAssignment(n)
+	case parser.MultiAssignment:
+		return sa.processMultiAssignment(n)
 	case parser.StatementIf:
 		return sa.processIf(n)
+	case parser.StatementForRange:
+		return sa.processForRange(n)
 	case parser.StatementFor:
 		return sa.processFor(n)
+	case parser.StatementDoWhile:
+		return sa.processDoWhile(n)
 	case parser.StatementSelect:
 		return sa.processSelect(n)
 	case parser.StatementExpression:
 		return sa.processExpressionStmt(n)
 	case parser.StatementReturn:
 		return sa.processReturn(n)
+	case parser.InlineAsm:
+		return &SemInlineAsm{
+			Text:    n.Text(),
+			astNode: n,
+		}
 	default:
 		sa.error(fmt.Sprintf("unknown statement type: %T", node), node)
 		return nil
@@ -441,11 +795,36 @@ func (sa *SemanticAnalyzer) processAssignment(node parser.VariableAssignment) *S
 		sa.error(fmt.Sprintf("undefined variable '%s'", name), node)
 		return nil
 	}
+	if !sa.checkAccess(symbol, node) {
+		return nil
+	}
+	sa.reference(symbol, node.Identifier())
+
+	// compound assignment reads the target first
+	if node.Operator() != nil {
+		sa.checkAssigned(symbol, node)
+	}
 
 	value := sa.processExpression(node.Expression())
 	if value == nil {
 		return nil
 	}
+	if _, ok := node.Target().(parser.ExpressionIdentifier); ok {
+		if value = sa.convertDecimal(value, symbol.Type, node); value == nil {
+			return nil
+		}
+		sa.assigned(symbol)
+	}
+
+	// compound assignment: x |= v is stored as x = x | v
+	if operator := node.Operator(); operator != nil {
+		value = &SemBinaryOp{
+			Op:       sa.mapBinaryOperator(operator.Id()),
+			Left:     &SemSymbolRef{Symbol: symbol},
+			Right:    value,
+			TypeInfo: WidenedType(symbol.Type, value.Type()),
+		}
+	}
 
 	// TODO: Check type compatibility
 
@@ -456,15 +835,89 @@ func (sa *SemanticAnalyzer) processAssignment(node parser.VariableAssignment) *S
 	}
 }
 
+// processMultiAssignment analyzes 'a, b = 1, 2': each value is assigned to the target at the same position.
+// The values are read before any of the targets is assigned.
+func (sa *SemanticAnalyzer) processMultiAssignment(node parser.MultiAssignment) *SemMultiAssignment {
+	targets, valueNodes := node.Targets(), node.Values()
+	if len(targets) != len(valueNodes) {
+		sa.error(fmt.Sprintf("assignment to %d variables has %d values", len(targets), len(valueNodes)), node)
+		return nil
+	}
+
+	values := make([]SemExpression, 0, len(valueNodes))
+	for _, valueNode := range valueNodes {
+		value := sa.processExpression(valueNode)
+		if value == nil {
+			return nil
+		}
+		values = append(values, value)
+	}
+
+	symbols := make([]*Symbol, 0, len(targets))
+	for i, target := range targets {
+		identifier, ok := target.(parser.ExpressionIdentifier)
+		if !ok {
+			sa.error("the targets of a multiple assignment must be variables", target)
+			return nil
+		}
+		name := identifier.Identifier().Text()
+		symbol := sa.currentScope.Lookup(name)
+		if symbol == nil {
+			sa.error(fmt.Sprintf("undefined variable '%s'", name), target)
+			return nil
+		}
+		if !sa.checkAccess(symbol, target) {
+			return nil
+		}
+		sa.reference(symbol, identifier.Identifier())
+		if slices.Contains(symbols, symbol) {
+			sa.error(fmt.Sprintf("variable '%s' is assigned more than once", name), target)
+			return nil
+		}
+
+		value := sa.convertDecimal(values[i], symbol.Type, valueNodes[i])
+		if value == nil {
+			return nil
+		}
+		if !assignableType(value.Type(), symbol.Type) {
+			sa.error(fmt.Sprintf("value of type '%s' cannot be assigned to '%s' of type '%s'",
+				typeName(value.Type()), name, typeName(symbol.Type)), valueNodes[i])
+			return nil
+		}
+		values[i] = value
+		symbols = append(symbols, symbol)
+	}
+
+	for _, symbol := range symbols {
+		sa.assigned(symbol)
+	}
+
+	return &SemMultiAssignment{
+		Targets: symbols,
+		Values:  values,
+		astNode: node,
+	}
+}
+
 func (sa *SemanticAnalyzer) processIf(node parser.StatementIf) *SemIf {
 	condition := sa.processExpression(node.Condition())
-	thenBlock := sa.processBlock(node.ThenBlock())
+	sa.checkCondition(condition, node.Condition())
+
+	// each branch starts from the assignments before the if
+	before := sa.cloneUnassigned()
+	branches := []map[*Symbol]bool{}
+
+	thenBlock := sa.processBlockScope(node.ThenBlock())
+	branches = append(branches, sa.unassigned)
 
 	// Process elsif clauses
 	elsifBlocks := []*SemElsif{}
 	for _, elsifNode := range node.ElsifClauses() {
+		sa.unassigned = maps.Clone(before)
 		elsifCondition := sa.processExpression(elsifNode.Condition())
-		elsifThenBlock := sa.processBlock(elsifNode.ThenBlock())
+		sa.checkCondition(elsifCondition, elsifNode.Condition())
+		elsifThenBlock := sa.processBlockScope(elsifNode.ThenBlock())
+		branches = append(branches, sa.unassigned)
 		elsifBlocks = append(elsifBlocks, &SemElsif{
 			Condition: elsifCondition,
 			ThenBlock: elsifThenBlock,
@@ -473,9 +926,12 @@ func (sa *SemanticAnalyzer) processIf(node parser.StatementIf) *SemIf {
 	}
 
 	var elseBlock *SemBlock
+	sa.unassigned = maps.Clone(before)
 	if eb := node.ElseBlock(); eb != nil {
-		elseBlock = sa.processBlock(eb)
+		elseBlock = sa.processBlockScope(eb)
 	}
+	branches = append(branches, sa.unassigned)
+	sa.mergeUnassigned(branches)
 
 	return &SemIf{
 		Condition:   condition,
@@ -487,7 +943,9 @@ func (sa *SemanticAnalyzer) processIf(node parser.StatementIf) *SemIf {
 }
 
 func (sa *SemanticAnalyzer) processFor(node parser.StatementFor) *SemFor {
-	// No new scope for for loops - variables belong to function scope
+	// The loop variable is scoped to the loop, together with the body
+	sa.pushScope(sa.currentScope.NewBlockScope())
+	defer sa.popScope()
 
 	var initializer SemStatement
 	if init := node.Initializer(); init != nil {
@@ -503,20 +961,34 @@ func (sa *SemanticAnalyzer) processFor(node parser.StatementFor) *SemFor {
 	var condition SemExpression
 	if cond := node.Condition(); cond != nil {
 		condition = sa.processExpression(cond)
+		sa.checkCondition(condition, cond)
 		// Variables in condition are likely counters
 		sa.trackVariableUsageInExpression(condition, VarUsedCounter)
 	}
 
-	var increment SemExpression
-	if inc := node.Increment(); inc != nil {
-		increment = sa.processExpression(inc)
-		// Variables in increment are counters
-		sa.trackVariableUsageInExpression(increment, VarUsedCounter)
+	var increment SemStatement
+	switch inc := node.Increment().(type) {
+	case parser.VariableAssignment:
+		if assignment := sa.processAssignment(inc); assignment != nil {
+			// the assigned variable is a counter
+			sa.updateVariableUsage(assignment.Target, VarUsedCounter)
+			sa.trackVariableUsageInExpression(assignment.Value, VarUsedCounter)
+			increment = assignment
+		}
+	case parser.Expression:
+		if expr := sa.processExpression(inc); expr != nil {
+			// Variables in increment are counters
+			sa.trackVariableUsageInExpression(expr, VarUsedCounter)
+			increment = &SemExpressionStmt{Expression: expr}
+		}
 	}
 
 	var body *SemBlock
 	if bodyNode := node.Body(); bodyNode != nil {
+		// the body may not execute at all
+		before := sa.cloneUnassigned()
 		body = sa.processBlock(bodyNode)
+		sa.unassigned = before
 	}
 
 	return &SemFor{
@@ -528,6 +1000,86 @@ func (sa *SemanticAnalyzer) processFor(node parser.StatementFor) *SemFor {
 	}
 }
 
+func (sa *SemanticAnalyzer) processForRange(node parser.StatementForRange) *SemForRange {
+	// The loop variable is scoped to the loop, together with the body
+	sa.pushScope(sa.currentScope.NewBlockScope())
+	defer sa.popScope()
+
+	from := sa.processExpression(node.From())
+	to := sa.processExpression(node.To())
+	if from == nil || to == nil {
+		return nil
+	}
+	if !isIntegerType(from.Type()) || !isIntegerType(to.Type()) {
+		sa.error(fmt.Sprintf("for loop range must be integers, got %s..%s", typeName(from.Type()), typeName(to.Type())), node)
+		return nil
+	}
+
+	name := node.Variable().Text()
+	if !sa.checkBuiltinTypeName(name, node) {
+		return nil
+	}
+	symbol := &Symbol{
+		Name:          name,
+		QualifiedName: sa.currentScope.GetQualifiedName(name),
+		Kind:          SymbolVariable,
+		Type:          WidenedType(from.Type(), to.Type()),
+		Declaration:   node,
+	}
+	// the loop scope is new: adding cannot fail
+	sa.currentScope.Add(symbol)
+	sa.checkShadowing(name, node)
+	symbol.Usage.AddFlag(VarInitCounter | VarUsedCounter)
+	sa.trackInitializationPattern(symbol, from)
+
+	var body *SemBlock
+	if bodyNode := node.Body(); bodyNode != nil {
+		// the body may not execute at all
+		before := sa.cloneUnassigned()
+		body = sa.processBlock(bodyNode)
+		sa.unassigned = before
+	}
+
+	counter := &SemSymbolRef{Symbol: symbol}
+	loop := &SemFor{
+		Initializer: &SemVariableDecl{Symbol: symbol, Initializer: from, TypeInfo: symbol.Type},
+		Condition:   &SemBinaryOp{Op: OpLessThan, Left: counter, Right: to, TypeInfo: BitType},
+		Increment:   &SemExpressionStmt{Expression: &SemUnaryOp{Op: OpIncrement, Operand: counter, TypeInfo: symbol.Type}},
+		Body:        body,
+	}
+
+	return &SemForRange{
+		Variable: symbol,
+		From:     from,
+		To:       to,
+		Body:     body,
+		Loop:     loop,
+		astNode:  node,
+	}
+}
+
+func (sa *SemanticAnalyzer) processDoWhile(node parser.StatementDoWhile) *SemDoWhile {
+	// the body always executes once: its assignments are kept
+	var body *SemBlock
+	if bodyNode := node.Body(); bodyNode != nil {
+		body = sa.processBlockScope(bodyNode)
+	}
+
+	var condition SemExpression
+	if cond := node.Condition(); cond != nil {
+		condition = sa.processExpression(cond)
+		sa.checkCondition(condition, cond)
+		// Variables in condition are likely counters
+		sa.trackVariableUsageInExpression(condition, VarUsedCounter)
+	}
+
+	return &SemDoWhile{
+		Body:      body,
+		Condition: condition,
+		astNode:   node,
+	}
+}
+
 func (sa *SemanticAnalyzer) processSelect(node parser.StatementSelect) *SemSelect {
 	// Process the select expression
 	expr := sa.processExpression(node.Expression())
@@ -535,6 +1087,10 @@ func (sa *SemanticAnalyzer) processSelect(node parser.StatementSelect) *SemSelec
 		return nil
 	}
 
+	// each case starts from the assignments before the select
+	before := sa.cloneUnassigned()
+	branches := []map[*Symbol]bool{}
+
 	// Process cases
 	cases := []*SemSelectCase{}
 	for _, caseNode := range node.Cases() {
@@ -543,9 +1099,16 @@ func (sa *SemanticAnalyzer) processSelect(node parser.StatementSelect) *SemSelec
 		if caseValue == nil {
 			continue
 		}
+		if !comparableTypes(expr.Type(), caseValue.Type()) {
+			sa.error(fmt.Sprintf("case value of type %s does not match select expression of type %s",
+				typeKindName(caseValue.Type()), typeKindName(expr.Type())), caseNode.Expression())
+		}
 
 		// Process case body
-		caseBody := sa.processBlock(caseNode.Body())
+		sa.unassigned = maps.Clone(before)
+		caseBody := sa.processBlockScope(caseNode.Body())
+		branches = append(branches, sa.unassigned)
+		sa.unassigned = before
 
 		cases = append(cases, &SemSelectCase{
 			Value:   caseValue,
@@ -556,9 +1119,12 @@ func (sa *SemanticAnalyzer) processSelect(node parser.StatementSelect) *SemSelec
 
 	// Process optional else clause
 	var elseBody *SemBlock
+	sa.unassigned = maps.Clone(before)
 	if elseNode := node.Else(); elseNode != nil {
-		elseBody = sa.processBlock(elseNode.Body())
+		elseBody = sa.processBlockScope(elseNode.Body())
 	}
+	branches = append(branches, sa.unassigned)
+	sa.mergeUnassigned(branches)
 
 	return &SemSelect{
 		Expression: expr,
@@ -582,6 +1148,10 @@ func (sa *SemanticAnalyzer) processReturn(node parser.StatementReturn) *SemRetur
 		value = sa.processExpression(node.Value())
 	}
 
+	if fn := sa.currentScope.Lookup(sa.currentFunction); fn != nil && fn.NoReturn {
+		sa.error(fmt.Sprintf("function '%s' with '@noreturn' attribute cannot return", fn.Name), node)
+	}
+
 	// TODO: Check that the return value type is compatible with the function's declared return type
 
 	return &SemReturn{
@@ -590,6 +1160,49 @@ func (sa *SemanticAnalyzer) processReturn(node parser.StatementReturn) *SemRetur
 	}
 }
 
+// ============================================================================
+// Definite Assignment
+// ============================================================================
+
+// declareUnassigned starts tracking a local variable declared without initializer.
+// Arrays and structs are storage that is written through subscript or member access and are not tracked.
+func (sa *SemanticAnalyzer) declareUnassigned(symbol *Symbol) {
+	if sa.unassigned == nil || symbol == nil {
+		return
+	}
+	switch symbol.Type.(type) {
+	case *PrimitiveType, *PointerType:
+		sa.unassigned[symbol] = true
+	}
+}
+
+// assigned marks the variable as assigned on the current path
+func (sa *SemanticAnalyzer) assigned(symbol *Symbol) {
+	delete(sa.unassigned, symbol)
+}
+
+// checkAssigned reports a read of a variable that is not assigned on all paths.
+// The error is reported once per variable.
+func (sa *SemanticAnalyzer) checkAssigned(symbol *Symbol, node parser.ParserNode) {
+	if sa.unassigned[symbol] {
+		sa.error(fmt.Sprintf("variable '%s' used before assignment", symbol.Name), node)
+		delete(sa.unassigned, symbol)
+	}
+}
+
+func (sa *SemanticAnalyzer) cloneUnassigned() map[*Symbol]bool {
+	return maps.Clone(sa.unassigned)
+}
+
+// mergeUnassigned joins the branches: a variable is only assigned when it is assigned in every branch
+func (sa *SemanticAnalyzer) mergeUnassigned(branches []map[*Symbol]bool) {
+	merged := make(map[*Symbol]bool)
+	for _, branch := range branches {
+		maps.Copy(merged, branch)
+	}
+	sa.unassigned = merged
+}
+
 // ============================================================================
 // Expression Processing
 // ============================================================================
@@ -629,7 +1242,15 @@ func (sa *SemanticAnalyzer) processExpression(node parser.Expression) SemExpress
 	case parser.ExpressionOperatorUnary:
 		result = sa.processUnaryOp(n)
 	case parser.ExpressionFunctionInvocation:
-		result = sa.processFunctionCall(n)
+		if n.IsIntrinsic() && n.FunctionName() == "@include_bin" {
+			result = sa.processIncludeBin(n)
+		} else if n.IsIntrinsic() && n.FunctionName() == "@db" {
+			result = sa.processDataBytes(n)
+		} else if n.IsIntrinsic() && n.FunctionName() == "@addressof" {
+			result = sa.processAddressOf(n)
+		} else {
+			result = sa.processFunctionCall(n)
+		}
 	case parser.ExpressionMemberAccess:
 		result = sa.processMemberAccess(n)
 	case parser.ExpressionSubscript:
@@ -666,7 +1287,24 @@ func (sa *SemanticAnalyzer) processLiteral(node parser.ExpressionLiteral) *SemCo
 
 	switch token.Id() {
 	case lexer.TokenNumber:
+		if decimal, ok := ParseDecimal(token.Text()); ok {
+			typ = decimal.Type()
+			if typ == nil {
+				sa.error(fmt.Sprintf("decimal literal '%s' does not fit d16", decimal), node)
+				return nil
+			}
+			value = decimal
+			break
+		}
 		value = node.Number()
+		// a type suffix (42u16) pins the type
+		if suffix := node.NumberSuffix(); suffix != "" {
+			typ = sa.literalSuffixType(suffix, node.Number(), token.Text(), node)
+			if typ == nil {
+				return nil
+			}
+			break
+		}
 		// Determine type based on value range
 		numVal := node.Number()
 		if numVal < 0 {
@@ -683,9 +1321,17 @@ func (sa *SemanticAnalyzer) processLiteral(node parser.ExpressionLiteral) *SemCo
 			}
 		}
 	case lexer.TokenString:
-		value = node.String()
-		// String is u8[] array
-		typ = NewArrayType(U8Type, uint16(len(node.String())))
+		chars, err := DecodeStringLiteral(node.String())
+		if err != nil {
+			sa.error(err.Error(), node)
+		}
+		value = string(chars)
+		// String is u8[] array, sized to its storage (incl. terminator or length byte)
+		length := len(chars)
+		if sa.stringFormat == StringLengthPrefixed && length > maxLengthPrefixed {
+			sa.error(fmt.Sprintf("string literal too long for a length prefix: %d characters (max %d)", length, maxLengthPrefixed), node)
+		}
+		typ = NewArrayType(U8Type, uint16(sa.stringFormat.StorageLength(length)))
 	case lexer.TokenTrue, lexer.TokenFalse:
 		value = token.Id() == lexer.TokenTrue
 		typ = BitType
@@ -701,6 +1347,59 @@ func (sa *SemanticAnalyzer) processLiteral(node parser.ExpressionLiteral) *SemCo
 	}
 }
 
+// literalSuffixType returns the integer type of a number literal suffix (u8, u16, i8, i16).
+// Returns nil when the suffix is not an integer type or the value does not fit (the error is reported).
+func (sa *SemanticAnalyzer) literalSuffixType(suffix string, value int, text string, node parser.ParserNode) Type {
+	symbol := sa.globalScope.Lookup(suffix)
+	if symbol == nil || symbol.Kind != SymbolType || !isIntegerType(symbol.Type) {
+		sa.error(fmt.Sprintf("unknown number suffix '%s', expected u8, u16, i8 or i16", suffix), node)
+		return nil
+	}
+	if !integerFits(value, symbol.Type) {
+		sa.error(fmt.Sprintf("number literal '%s' does not fit %s", text, suffix), node)
+		return nil
+	}
+	return symbol.Type
+}
+
+// integerFits returns true when the value is in the range of the integer type
+func integerFits(value int, typ Type) bool {
+	bits := int(typ.Size()) * 8
+	if IsSigned(typ) {
+		return value >= -(1<<(bits-1)) && value < 1<<(bits-1)
+	}
+	return value >= 0 && value < 1<<bits
+}
+
+// convertDecimal stores a decimal literal (1.5) in the decimal type of its target:
+// the constant becomes the packed BCD value. Other expressions are returned as they are.
+// Returns nil when the target is not a decimal type or the value does not fit (the error is reported).
+func (sa *SemanticAnalyzer) convertDecimal(expr SemExpression, target Type, node parser.ParserNode) SemExpression {
+	constant, ok := expr.(*SemConstant)
+	if !ok {
+		return expr
+	}
+	decimal, ok := constant.Value.(Decimal)
+	if !ok {
+		return expr
+	}
+
+	if !IsDecimalType(target) {
+		sa.error(fmt.Sprintf("decimal literal '%s' requires a decimal type (d8, d16), got %s", decimal, typeName(target)), node)
+		return nil
+	}
+	value, ok := decimal.BCD(target)
+	if !ok {
+		sa.error(fmt.Sprintf("decimal literal '%s' does not fit %s", decimal, target.Name()), node)
+		return nil
+	}
+	return &SemConstant{
+		Value:    value,
+		TypeInfo: target,
+		astNode:  constant.astNode,
+	}
+}
+
 // processIdentifier handles identifier expressions (variable/parameter references)
 func (sa *SemanticAnalyzer) processIdentifier(node parser.ExpressionIdentifier) *SemSymbolRef {
 	// Get the identifier token directly from the node
@@ -716,6 +1415,11 @@ func (sa *SemanticAnalyzer) processIdentifier(node parser.ExpressionIdentifier)
 		sa.error(fmt.Sprintf("undefined identifier '%s'", name), node)
 		return nil
 	}
+	if !sa.checkAccess(symbol, node) {
+		return nil
+	}
+	sa.reference(symbol, token)
+	sa.checkAssigned(symbol, node)
 
 	return &SemSymbolRef{
 		Symbol:  symbol,
@@ -736,13 +1440,43 @@ func (sa *SemanticAnalyzer) processUnaryOp(node parser.ExpressionOperatorUnary)
 }
 
 func (sa *SemanticAnalyzer) processUnaryPrefixOp(node parser.ExpressionOperatorUnary) SemExpression {
-	operand := sa.processExpression(node.Operand())
-	if operand == nil {
-		return nil
-	}
-
+	// a negative literal with a type suffix keeps its type, the range is checked on the negated value (-128i8)
+	if literal, ok := node.Operand().(parser.ExpressionLiteral); ok && literal.NumberSuffix() != "" && node.Operator().Id() == lexer.TokenMinus {
+		value := -literal.Number()
+		typ := sa.literalSuffixType(literal.NumberSuffix(), value, "-"+literal.Value().Text(), node)
+		if typ == nil {
+			return nil
+		}
+		return &SemConstant{
+			Value:    value,
+			TypeInfo: typ,
+			astNode:  node,
+		}
+	}
+
+	operand := sa.processExpression(node.Operand())
+	if operand == nil {
+		return nil
+	}
+
 	opToken := node.Operator().Id()
 
+	switch opToken {
+	case lexer.TokenMinus, lexer.TokenPlus:
+		if !sa.checkNumericOperand(node.Operator().Text(), operand, node) {
+			return nil
+		}
+	case lexer.TokenNot:
+		if !sa.checkBoolOperand(node.Operator().Text(), operand, node) {
+			return nil
+		}
+	}
+
+	// unary plus is the identity: no code
+	if opToken == lexer.TokenPlus {
+		return operand
+	}
+
 	// Handle unary minus with constant folding for literals
 	if opToken == lexer.TokenMinus {
 		if constant, ok := operand.(*SemConstant); ok {
@@ -818,6 +1552,10 @@ func (sa *SemanticAnalyzer) processUnaryPostfixOp(node parser.ExpressionOperator
 		}
 	}
 
+	if opToken == lexer.TokenQuestion {
+		return sa.processToBoolOp(node, operand)
+	}
+
 	var unop UnaryOperator
 	switch opToken {
 	case lexer.TokenIncrement:
@@ -836,6 +1574,36 @@ func (sa *SemanticAnalyzer) processUnaryPostfixOp(node parser.ExpressionOperator
 	}
 }
 
+// processToBoolOp converts an integer or pointer to a bool: 'x?' is true when x is not zero (nil)
+func (sa *SemanticAnalyzer) processToBoolOp(node parser.ExpressionOperatorUnary, operand SemExpression) SemExpression {
+	typ := operand.Type()
+	if _, isPointer := typ.(*PointerType); !isPointer && !isIntegerType(typ) {
+		name := "unknown"
+		if typ != nil {
+			name = typ.Name()
+		}
+		sa.error(fmt.Sprintf("operator '?' requires an integer or pointer operand, got %s", name), node)
+		return nil
+	}
+
+	if constant, ok := operand.(*SemConstant); ok {
+		if numVal, ok := constant.Value.(int); ok {
+			return &SemConstant{
+				Value:    numVal != 0,
+				TypeInfo: BitType,
+				astNode:  node,
+			}
+		}
+	}
+
+	return &SemUnaryOp{
+		Op:       OpToBool,
+		Operand:  operand,
+		TypeInfo: BitType,
+		astNode:  node,
+	}
+}
+
 func (sa *SemanticAnalyzer) processBinaryOp(node parser.ExpressionOperatorBinary, opToken lexer.TokenId) *SemBinaryOp {
 	left := sa.processExpression(node.Left())
 	right := sa.processExpression(node.Right())
@@ -848,15 +1616,51 @@ func (sa *SemanticAnalyzer) processBinaryOp(node parser.ExpressionOperatorBinary
 	// Map token to operator
 	op := sa.mapBinaryOperator(opToken)
 
+	operator := node.Operator().Text()
+	switch {
+	case op == OpLogicalAnd || op == OpLogicalOr:
+		if !sa.checkBoolOperand(operator, left, node) || !sa.checkBoolOperand(operator, right, node) {
+			return nil
+		}
+	case op == OpAdd || op == OpSubtract || op == OpMultiply || op == OpDivide || op == OpModulo,
+		op == OpShl || op == OpShr:
+		if !sa.checkNumericOperand(operator, left, node) || !sa.checkNumericOperand(operator, right, node) {
+			return nil
+		}
+	}
+
+	// a constant zero divisor is known at compile time, a runtime divisor is not checked
+	if op == OpDivide || op == OpModulo {
+		if divisor, ok := ConstantValue(right); ok && divisor == 0 {
+			if op == OpDivide {
+				sa.error("division by zero", node)
+			} else {
+				sa.error("modulo by zero", node)
+			}
+			return nil
+		}
+	}
+
+	if op >= OpEqual && op <= OpGreaterEqual {
+		sa.checkMixedComparison(left, right, node)
+	}
+
 	// Track variable usage for arithmetic operations
 	if sa.isArithmeticOperator(op) {
 		sa.trackVariableUsageInExpression(left, VarUsedArithmetic)
 		sa.trackVariableUsageInExpression(right, VarUsedArithmetic)
 	}
 
-	// Determine result type
-	// TODO: Implement proper type inference/coercion
-	resultType := left.Type()
+	// Determine result type: the operands widen to the larger type
+	resultType := WidenedType(left.Type(), right.Type())
+	switch {
+	case op >= OpEqual:
+		// comparisons and logical operators
+		resultType = BitType
+	case op == OpShl || op == OpShr:
+		// the shift count does not widen the shifted value
+		resultType = left.Type()
+	}
 
 	// Special case: multiplication of two u8 values produces u16 to avoid overflow
 	if op == OpMultiply {
@@ -880,6 +1684,24 @@ func (sa *SemanticAnalyzer) processBinaryOp(node parser.ExpressionOperatorBinary
 	}
 }
 
+// checkMixedComparison warns for a comparison of a signed with an unsigned integer (i8 < u16):
+// the operands are widened to the larger type, a negative value compares as a large unsigned value.
+// Constants are not reported, their type follows from their value.
+func (sa *SemanticAnalyzer) checkMixedComparison(left, right SemExpression, node parser.ParserNode) {
+	leftType, rightType := left.Type(), right.Type()
+	if !isIntegerType(leftType) || !isIntegerType(rightType) || IsSigned(leftType) == IsSigned(rightType) {
+		return
+	}
+	if _, ok := ConstantValue(left); ok {
+		return
+	}
+	if _, ok := ConstantValue(right); ok {
+		return
+	}
+	sa.warning(fmt.Sprintf("comparison between %s and %s may be surprising; add an explicit conversion",
+		leftType.Name(), rightType.Name()), node)
+}
+
 func (sa *SemanticAnalyzer) processFunctionCall(node parser.ExpressionFunctionInvocation) *SemFunctionCall {
 	name := node.FunctionName()
 	symbol := sa.currentScope.Lookup(name)
@@ -887,22 +1709,46 @@ func (sa *SemanticAnalyzer) processFunctionCall(node parser.ExpressionFunctionIn
 		sa.error(fmt.Sprintf("undefined function '%s'", name), node)
 		return nil
 	}
+	if !sa.checkAccess(symbol, node) {
+		return nil
+	}
+	sa.reference(symbol, node.Name())
+
+	funcType := symbol.Type.(*FunctionType)
 
 	// Process arguments
 	args := []SemExpression{}
+	names := []string{} // parameter name per argument, empty when positional
+	hasNamed := false
 	if argList := node.Arguments(); argList != nil {
-		for _, arg := range argList.Arguments() {
-			semArg := sa.processExpression(arg)
+		for _, arg := range argList.FunctionArguments() {
+			argName := ""
+			if arg.Name() != nil {
+				argName = arg.Name().Text()
+				hasNamed = true
+			}
+			semArg := sa.processExpression(arg.Expression())
 			if semArg != nil {
 				args = append(args, semArg)
+				names = append(names, argName)
 			}
 		}
 	}
 
+	if hasNamed {
+		var ok bool
+		if args, ok = sa.matchNamedArguments(name, funcType, args, names, node); !ok {
+			return nil
+		}
+	}
+
+	if node.IsIntrinsic() && !sa.checkIntrinsicCall(name, args, node) {
+		return nil
+	}
+
 	// TODO: Type check arguments against function signature
 
 	// Get return type from function type
-	funcType := symbol.Type.(*FunctionType)
 	returnType := funcType.ReturnType()
 
 	// Record call in call graph
@@ -918,6 +1764,364 @@ func (sa *SemanticAnalyzer) processFunctionCall(node parser.ExpressionFunctionIn
 	}
 }
 
+// matchNamedArguments orders the call arguments by parameter position.
+// Positional arguments fill the leading parameters, named arguments are matched by parameter name.
+// Returns false when an error was reported.
+func (sa *SemanticAnalyzer) matchNamedArguments(funcName string, funcType *FunctionType, args []SemExpression, names []string, node parser.ExpressionFunctionInvocation) ([]SemExpression, bool) {
+	paramNames := funcType.ParameterNames()
+	ordered := make([]SemExpression, len(paramNames))
+	seenNamed := false
+
+	for i, arg := range args {
+		if names[i] == "" {
+			if seenNamed {
+				sa.error(fmt.Sprintf("positional argument cannot follow named arguments in call to '%s'", funcName), node)
+				return nil, false
+			}
+			if i >= len(ordered) {
+				sa.error(fmt.Sprintf("too many arguments in call to '%s'", funcName), node)
+				return nil, false
+			}
+			ordered[i] = arg
+			continue
+		}
+
+		seenNamed = true
+		index := slices.Index(paramNames, names[i])
+		if index < 0 {
+			sa.error(fmt.Sprintf("unknown parameter '%s' in call to '%s'", names[i], funcName), node)
+			return nil, false
+		}
+		if ordered[index] != nil {
+			sa.error(fmt.Sprintf("duplicate argument for parameter '%s' in call to '%s'", names[i], funcName), node)
+			return nil, false
+		}
+		ordered[index] = arg
+	}
+
+	for i, arg := range ordered {
+		if arg == nil {
+			sa.error(fmt.Sprintf("missing argument for parameter '%s' in call to '%s'", paramNames[i], funcName), node)
+			return nil, false
+		}
+	}
+	return ordered, true
+}
+
+// ============================================================================
+// Intrinsic Functions
+// ============================================================================
+
+// checkIntrinsicCall validates the arguments of intrinsics that are lowered directly to instructions.
+// Returns false when an error was reported.
+func (sa *SemanticAnalyzer) checkIntrinsicCall(name string, args []SemExpression, node parser.ExpressionFunctionInvocation) bool {
+	switch name {
+	case "@bit", "@setbit", "@resetbit":
+		if len(args) != 2 {
+			sa.error(fmt.Sprintf("'%s' expects 2 arguments, got %d", name, len(args)), node)
+			return false
+		}
+		if _, ok := BitIndex(args[0]); !ok {
+			sa.error(fmt.Sprintf("bit index of '%s' must be a constant 0-7", name), node)
+			return false
+		}
+		// set/reset modify the variable in place
+		if _, ok := args[1].(*SemSymbolRef); !ok && name != "@bit" {
+			sa.error(fmt.Sprintf("'%s' requires a variable as second argument", name), node)
+			return false
+		}
+		if typ := args[1].Type(); typ == nil || typ.Size() != 1 {
+			sa.error(fmt.Sprintf("'%s' requires an 8-bit value", name), node)
+			return false
+		}
+	case "@peek", "@poke":
+		expected := 1
+		if name == "@poke" {
+			expected = 2
+		}
+		if len(args) != expected {
+			sa.error(fmt.Sprintf("'%s' expects %d arguments, got %d", name, expected, len(args)), node)
+			return false
+		}
+		if !isAddress(args[0]) {
+			sa.error(fmt.Sprintf("address of '%s' must be a constant, u16 or pointer", name), node)
+			return false
+		}
+		if !sa.checkAddressRange(name, args[0], node) {
+			return false
+		}
+		if name == "@poke" {
+			if typ := args[1].Type(); typ == nil || typ.Size() != 1 {
+				sa.error(fmt.Sprintf("'%s' requires an 8-bit value", name), node)
+				return false
+			}
+		}
+	case "@memchr", "@memset":
+		if len(args) != 3 {
+			sa.error(fmt.Sprintf("'%s' expects 3 arguments, got %d", name, len(args)), node)
+			return false
+		}
+		if _, isArray := args[0].Type().(*ArrayType); !isArray && !isAddress(args[0]) {
+			sa.error(fmt.Sprintf("address of '%s' must be a constant, u16, pointer or array", name), node)
+			return false
+		}
+		if !sa.checkAddressRange(name, args[0], node) {
+			return false
+		}
+		if typ := args[1].Type(); typ == nil || typ.Size() != 1 {
+			sa.error(fmt.Sprintf("'%s' requires an 8-bit value", name), node)
+			return false
+		}
+		if !isIntegerType(args[2].Type()) || IsSigned(args[2].Type()) {
+			sa.error(fmt.Sprintf("length of '%s' must be u8 or u16", name), node)
+			return false
+		}
+		// a length of 0 searches all 64K
+		if length, ok := ConstantValue(args[2]); ok && length == 0 && name == "@memchr" {
+			sa.error(fmt.Sprintf("length of '%s' must not be 0", name), node)
+			return false
+		}
+	case "@halt", "@nop":
+		if len(args) != 0 {
+			sa.error(fmt.Sprintf("'%s' expects 0 arguments, got %d", name, len(args)), node)
+			return false
+		}
+	case "@overflow":
+		if len(args) != 1 {
+			sa.error(fmt.Sprintf("'%s' expects 1 argument, got %d", name, len(args)), node)
+			return false
+		}
+		// the overflow flag is tested right after the arithmetic
+		if op, ok := args[0].(*SemBinaryOp); !ok || (op.Op != OpAdd && op.Op != OpSubtract) {
+			sa.error(fmt.Sprintf("'%s' requires an addition or subtraction", name), node)
+			return false
+		}
+	case "@rst":
+		if len(args) != 1 {
+			sa.error(fmt.Sprintf("'%s' expects 1 argument, got %d", name, len(args)), node)
+			return false
+		}
+		if _, ok := RestartVector(args[0]); !ok {
+			sa.error(fmt.Sprintf("vector of '%s' must be a constant 0x00, 0x08, ... 0x38", name), node)
+			return false
+		}
+	case "@sizeof":
+		if len(args) != 1 {
+			sa.error(fmt.Sprintf("'%s' expects 1 argument, got %d", name, len(args)), node)
+			return false
+		}
+		if args[0].Type() == nil {
+			sa.error(fmt.Sprintf("'%s' requires a type or a value", name), node)
+			return false
+		}
+	case "@assert":
+		if len(args) != 1 {
+			sa.error(fmt.Sprintf("'%s' expects 1 argument, got %d", name, len(args)), node)
+			return false
+		}
+		value, ok := ConstantValue(args[0])
+		condition, isBool := value.(bool)
+		if !ok || !isBool {
+			sa.error(fmt.Sprintf("condition of '%s' must be a constant %s", name, BitType.Name()), node)
+			return false
+		}
+		if !condition {
+			sa.error("assertion failed", node)
+			return false
+		}
+	}
+	return true
+}
+
+// processIncludeBin reads the file of '@include_bin("path")' at compile time.
+// The call is replaced by a u8[] constant of the file bytes (stored in the data section).
+func (sa *SemanticAnalyzer) processIncludeBin(node parser.ExpressionFunctionInvocation) *SemConstant {
+	var args []parser.FunctionArgument
+	if argList := node.Arguments(); argList != nil {
+		args = argList.FunctionArguments()
+	}
+	if len(args) != 1 {
+		sa.error(fmt.Sprintf("'@include_bin' expects 1 argument, got %d", len(args)), node)
+		return nil
+	}
+
+	literal, ok := sa.processExpression(args[0].Expression()).(*SemConstant)
+	if !ok {
+		sa.error("path of '@include_bin' must be a string literal", node)
+		return nil
+	}
+	path, ok := literal.Value.(string)
+	if !ok {
+		sa.error("path of '@include_bin' must be a string literal", node)
+		return nil
+	}
+
+	if sa.fileResolver == nil {
+		sa.error(fmt.Sprintf("cannot include '%s': no file resolver", path), node)
+		return nil
+	}
+	data, err := sa.fileResolver(path)
+	if err != nil {
+		sa.error(fmt.Sprintf("cannot include '%s': %s", path, err), node)
+		return nil
+	}
+	if len(data) > 0xFFFF {
+		sa.error(fmt.Sprintf("included file '%s' is too large: %d bytes", path, len(data)), node)
+		return nil
+	}
+
+	return &SemConstant{
+		Value:    data,
+		TypeInfo: NewArrayType(U8Type, uint16(len(data))),
+		astNode:  node,
+	}
+}
+
+// processDataBytes evaluates the values of '@db(byte, byte, ...)' at compile time.
+// The call is replaced by a u8[] constant of the bytes (stored in the data section, as they are).
+// Negative values (down to -128) are stored as their two's complement.
+func (sa *SemanticAnalyzer) processDataBytes(node parser.ExpressionFunctionInvocation) *SemConstant {
+	var args []parser.FunctionArgument
+	if argList := node.Arguments(); argList != nil {
+		args = argList.FunctionArguments()
+	}
+	if len(args) == 0 {
+		sa.error("'@db' expects at least 1 value", node)
+		return nil
+	}
+	if len(args) > 0xFFFF {
+		sa.error(fmt.Sprintf("'@db' has too many values: %d", len(args)), node)
+		return nil
+	}
+
+	data := make([]byte, 0, len(args))
+	for _, arg := range args {
+		value, ok := ConstantValue(sa.processExpression(arg.Expression()))
+		number, isInt := value.(int)
+		if !ok || !isInt {
+			sa.error("values of '@db' must be constant numbers", arg)
+			return nil
+		}
+		if number < -128 || number > 255 {
+			sa.error(fmt.Sprintf("value %d of '@db' does not fit a byte", number), arg)
+			return nil
+		}
+		data = append(data, byte(number))
+	}
+
+	return &SemConstant{
+		Value:    data,
+		TypeInfo: NewArrayType(U8Type, uint16(len(data))),
+		astNode:  node,
+	}
+}
+
+// processAddressOf resolves the function of '@addressof(function)'.
+// The u16 address is not known until the code is laid out, the backend fills it in.
+// Taking the address keeps the function (it may be called through the address).
+func (sa *SemanticAnalyzer) processAddressOf(node parser.ExpressionFunctionInvocation) *SemFunctionCall {
+	var args []parser.FunctionArgument
+	if argList := node.Arguments(); argList != nil {
+		args = argList.FunctionArguments()
+	}
+	if len(args) != 1 {
+		sa.error(fmt.Sprintf("'@addressof' expects 1 argument, got %d", len(args)), node)
+		return nil
+	}
+
+	identifier, ok := args[0].Expression().(parser.ExpressionIdentifier)
+	if !ok || identifier.Identifier() == nil {
+		sa.error("'@addressof' requires a function name", node)
+		return nil
+	}
+	name := identifier.Identifier().Text()
+	symbol := sa.currentScope.Lookup(name)
+	if symbol == nil {
+		sa.error(fmt.Sprintf("undefined function '%s'", name), node)
+		return nil
+	}
+	if symbol.Kind != SymbolFunction {
+		sa.error(fmt.Sprintf("'@addressof' requires a function name, '%s' is not a function", name), node)
+		return nil
+	}
+	if !sa.checkAccess(symbol, node) {
+		return nil
+	}
+	sa.reference(symbol, identifier.Identifier())
+	sa.callGraph.AddCall(sa.currentFunction, name)
+
+	return &SemFunctionCall{
+		Function:  sa.globalScope.Lookup("@addressof"),
+		Arguments: []SemExpression{&SemSymbolRef{Symbol: symbol, astNode: identifier}},
+		TypeInfo:  U16Type,
+		astNode:   node,
+	}
+}
+
+// AddressOfFunction returns the function name when the expression is '@addressof(function)'
+func AddressOfFunction(expr SemExpression) (string, bool) {
+	call, ok := expr.(*SemFunctionCall)
+	if !ok || call.Function == nil || call.Function.Name != "@addressof" || len(call.Arguments) != 1 {
+		return "", false
+	}
+	ref, ok := call.Arguments[0].(*SemSymbolRef)
+	if !ok {
+		return "", false
+	}
+	return ref.Symbol.Name, true
+}
+
+// isAddress returns true when the expression can be used as a memory address
+func isAddress(expr SemExpression) bool {
+	if constant, ok := expr.(*SemConstant); ok {
+		_, isNumber := constant.Value.(int)
+		return isNumber
+	}
+	if _, ok := expr.Type().(*PointerType); ok {
+		return true
+	}
+	return expr.Type() == U16Type
+}
+
+// checkAddressRange reports a constant address outside the address space (0-0xFFFF)
+func (sa *SemanticAnalyzer) checkAddressRange(name string, address SemExpression, node parser.ParserNode) bool {
+	constant, ok := address.(*SemConstant)
+	if !ok {
+		return true
+	}
+	if value, ok := constant.Value.(int); ok && (value < 0 || value > 0xFFFF) {
+		sa.error(fmt.Sprintf("address of '%s' must be 0-0xFFFF, got %d", name, value), node)
+		return false
+	}
+	return true
+}
+
+// BitIndex returns the bit number (0-7) when the expression is a constant in range
+func BitIndex(expr SemExpression) (uint8, bool) {
+	constant, ok := expr.(*SemConstant)
+	if !ok {
+		return 0, false
+	}
+	value, ok := constant.Value.(int)
+	if !ok || value < 0 || value > 7 {
+		return 0, false
+	}
+	return uint8(value), true
+}
+
+// RestartVector returns the page-zero restart address when the expression is a valid constant vector
+func RestartVector(expr SemExpression) (uint8, bool) {
+	constant, ok := expr.(*SemConstant)
+	if !ok {
+		return 0, false
+	}
+	value, ok := constant.Value.(int)
+	if !ok || value < 0 || value > 0x38 || value%8 != 0 {
+		return 0, false
+	}
+	return uint8(value), true
+}
+
 func (sa *SemanticAnalyzer) processMemberAccess(node parser.ExpressionMemberAccess) *SemMemberAccess {
 	// Process the object expression
 	object := sa.processExpression(node.Object())
@@ -936,10 +2140,14 @@ func (sa *SemanticAnalyzer) processMemberAccess(node parser.ExpressionMemberAcce
 	}
 	memberName := memberToken.Text()
 
-	// Get the struct type from the object
-	structType, ok := object.Type().(*StructType)
+	// Get the struct type from the object (a struct pointer also uses '.')
+	objectType := object.Type()
+	if pointerType, ok := objectType.(*PointerType); ok {
+		objectType = pointerType.PointeeType()
+	}
+	structType, ok := objectType.(*StructType)
 	if !ok {
-		sa.error(fmt.Sprintf("cannot access member '%s' on non-struct type", memberName), node)
+		sa.error(fmt.Sprintf("cannot access member '%s' on non-struct type %s", memberName, typeName(object.Type())), node)
 		return nil
 	}
 
@@ -1130,6 +2338,10 @@ func (sa *SemanticAnalyzer) resolveTypeRef(typeRef parser.TypeRef) Type {
 		sa.error(fmt.Sprintf("undefined type '%s'", typeName), typeRef)
 		return nil
 	}
+	if !sa.checkAccess(symbol, typeRef) {
+		return nil
+	}
+	sa.reference(symbol, typeRef.TypeName())
 	typ := symbol.Type
 
 	// Handle array types
@@ -1139,9 +2351,12 @@ func (sa *SemanticAnalyzer) resolveTypeRef(typeRef parser.TypeRef) Type {
 			// TODO: Parse array size
 			length = 0 // Placeholder
 		}
-		return NewArrayType(typ, length)
+		typ = NewArrayType(typ, length)
 	}
 
+	if typeRef.IsPointer() {
+		typ = NewPointerType(typ)
+	}
 	return typ
 }
 
@@ -1155,13 +2370,19 @@ func (sa *SemanticAnalyzer) mapBinaryOperator(token lexer.TokenId) BinaryOperato
 		return OpMultiply
 	case lexer.TokenSlash:
 		return OpDivide
+	case lexer.TokenPercent:
+		return OpModulo
 	case lexer.TokenAmpersant:
 		return OpBitwiseAnd
 	case lexer.TokenPipe:
 		return OpBitwiseOr
 	case lexer.TokenCaret:
 		return OpBitwiseXor
-	case lexer.TokenEquals:
+	case lexer.TokenShiftLeft:
+		return OpShl
+	case lexer.TokenShiftRight:
+		return OpShr
+	case lexer.TokenEqualsEquals:
 		return OpEqual
 	case lexer.TokenNotEquals:
 		return OpNotEqual
@@ -1184,6 +2405,37 @@ func (sa *SemanticAnalyzer) mapBinaryOperator(token lexer.TokenId) BinaryOperato
 	}
 }
 
+// isBuiltinType returns true when the name is a builtin type (u8, i16, d8, bit, ...)
+func (sa *SemanticAnalyzer) isBuiltinType(name string) bool {
+	symbol := sa.globalScope.LookupLocal(name)
+	if symbol == nil || symbol.Kind != SymbolType {
+		return false
+	}
+	_, ok := symbol.Type.(*PrimitiveType)
+	return ok
+}
+
+// checkBuiltinTypeName reports a type or variable that reuses the name of a builtin type.
+// Returns false when the name is a builtin type.
+func (sa *SemanticAnalyzer) checkBuiltinTypeName(name string, node parser.ParserNode) bool {
+	if sa.isBuiltinType(name) {
+		sa.error(fmt.Sprintf("cannot redefine builtin type '%s'", name), node)
+		return false
+	}
+	return true
+}
+
+// checkShadowing warns about a local variable that hides a parameter or a local of an enclosing block.
+// Globals are not considered.
+func (sa *SemanticAnalyzer) checkShadowing(name string, node parser.ParserNode) {
+	for scope := sa.currentScope.Parent(); scope != nil && !scope.IsGlobal(); scope = scope.Parent() {
+		if scope.LookupLocal(name) != nil {
+			sa.warning(fmt.Sprintf("variable '%s' shadows a variable of an outer scope", name), node)
+			return
+		}
+	}
+}
+
 func (sa *SemanticAnalyzer) pushScope(scope *SymbolTable) {
 	sa.currentScope = scope
 }
@@ -1263,17 +2515,162 @@ func isIntegerType(t Type) bool {
 	}
 }
 
+// checkCondition reports a condition (if, elsif, for) that is not a bool.
+// Integers are not implicitly true when non-zero: compare them explicitly 'x <> 0'.
+func (sa *SemanticAnalyzer) checkCondition(condition SemExpression, node parser.ParserNode) {
+	if condition == nil {
+		return
+	}
+	if typ := condition.Type(); typ != BitType {
+		sa.error(fmt.Sprintf("condition must be %s, got %s", BitType.Name(), typeName(typ)), node)
+	}
+}
+
+// typeName returns the name of a type for diagnostics ("void" for no type)
+func typeName(typ Type) string {
+	if typ == nil {
+		return "void"
+	}
+	return typ.Name()
+}
+
+// comparableTypes checks if values of the types can be compared for equality:
+// numbers with numbers (of any size), bools with bools, pointers with pointers.
+func comparableTypes(left, right Type) bool {
+	if left == nil || right == nil {
+		// already reported
+		return true
+	}
+	leftPrim, leftOk := left.(*PrimitiveType)
+	rightPrim, rightOk := right.(*PrimitiveType)
+	if leftOk && rightOk {
+		return (leftPrim == BitType) == (rightPrim == BitType)
+	}
+	_, leftPtr := left.(*PointerType)
+	_, rightPtr := right.(*PointerType)
+	if leftPtr && rightPtr {
+		return true
+	}
+	return left == right
+}
+
+// assignableType checks if a value of the type can be assigned to a variable of the target type:
+// bools to bools, numbers (and pointers) to a type of the same size or larger.
+func assignableType(value, target Type) bool {
+	if value == nil || target == nil {
+		// already reported
+		return true
+	}
+	return comparableTypes(value, target) && value.Size() <= target.Size()
+}
+
+// typeKindName returns the name of a primitive or pointer type, the kind of an array or struct type
+func typeKindName(typ Type) string {
+	switch typ.(type) {
+	case *ArrayType:
+		return "array"
+	case *StructType:
+		return "struct"
+	}
+	return typeName(typ)
+}
+
+// checkNumericOperand reports a bool operand of an arithmetic operator
+func (sa *SemanticAnalyzer) checkNumericOperand(operator string, operand SemExpression, node parser.ParserNode) bool {
+	if operand.Type() == BitType {
+		sa.error(fmt.Sprintf("operator '%s' requires numeric operands, got %s", operator, BitType.Name()), node)
+		return false
+	}
+	return true
+}
+
+// checkBoolOperand reports a non-bool operand of a logical operator
+func (sa *SemanticAnalyzer) checkBoolOperand(operator string, operand SemExpression, node parser.ParserNode) bool {
+	if typ := operand.Type(); typ != nil && typ != BitType {
+		sa.error(fmt.Sprintf("operator '%s' requires %s operands, got %s", operator, BitType.Name(), typ.Name()), node)
+		return false
+	}
+	return true
+}
+
 // isArithmeticOperator checks if an operator is arithmetic
 func (sa *SemanticAnalyzer) isArithmeticOperator(op BinaryOperator) bool {
 	switch op {
-	case OpAdd, OpSubtract, OpMultiply, OpDivide,
-		OpBitwiseAnd, OpBitwiseOr, OpBitwiseXor:
+	case OpAdd, OpSubtract, OpMultiply, OpDivide, OpModulo,
+		OpBitwiseAnd, OpBitwiseOr, OpBitwiseXor, OpShl, OpShr:
 		return true
 	default:
 		return false
 	}
 }
 
+// processOrgAddress validates the '@org(address)' argument: a constant u16
+// Returns nil when the address is invalid (the error is reported).
+func (sa *SemanticAnalyzer) processOrgAddress(node parser.FunctionDeclaration) *uint16 {
+	arg := node.AttributeArgument(AttributeOrg)
+	if arg == nil {
+		sa.error(fmt.Sprintf("function attribute '@%s' requires an address", AttributeOrg), node)
+		return nil
+	}
+
+	constant, ok := sa.processExpression(arg).(*SemConstant)
+	if !ok {
+		sa.error(fmt.Sprintf("'@%s' address must be a constant", AttributeOrg), arg)
+		return nil
+	}
+	value, ok := constant.Value.(int)
+	if !ok || value < 0 || value > 0xFFFF {
+		sa.error(fmt.Sprintf("'@%s' address must be a u16 value: %v", AttributeOrg, constant.Value), arg)
+		return nil
+	}
+
+	address := uint16(value)
+	return &address
+}
+
+// processFunctionAttributes validates the function attributes and returns their names
+// '@fast' switches to the alternate register set (EXX), which also swaps out
+// the registers used to pass parameters and return values.
+// '@interrupt' and '@nmi' functions are entered by the CPU, not called.
+// '@org(address)' places the function at a fixed address.
+// '@noreturn' functions never return to the caller (no epilogue).
+func (sa *SemanticAnalyzer) processFunctionAttributes(node parser.FunctionDeclaration, hasSignature bool) []string {
+	attributes := make([]string, 0, len(node.Attributes()))
+	for _, token := range node.Attributes() {
+		attr := token.Text()
+		switch attr {
+		case AttributeFast, AttributeInterrupt, AttributeNMI:
+			if hasSignature {
+				sa.error(fmt.Sprintf("function '%s' with '@%s' attribute cannot have parameters or a return value", node.Label().Name(), attr), node)
+			}
+			if node.AttributeArgument(attr) != nil {
+				sa.error(fmt.Sprintf("function attribute '@%s' does not take an argument", attr), node)
+			}
+		case AttributeNoReturn:
+			if node.ReturnType() != nil {
+				sa.error(fmt.Sprintf("function '%s' with '@noreturn' attribute cannot have a return value", node.Label().Name()), node)
+			}
+			if node.AttributeArgument(attr) != nil {
+				sa.error(fmt.Sprintf("function attribute '@%s' does not take an argument", attr), node)
+			}
+		case AttributeOrg:
+			if slices.Contains(attributes, AttributeOrg) {
+				sa.error(fmt.Sprintf("function '%s' has more than one '@org' attribute", node.Label().Name()), node)
+				continue
+			}
+		default:
+			sa.error(fmt.Sprintf("unknown function attribute '@%s'", attr), node)
+			continue
+		}
+		attributes = append(attributes, attr)
+	}
+
+	if slices.Contains(attributes, AttributeInterrupt) && slices.Contains(attributes, AttributeNMI) {
+		sa.error(fmt.Sprintf("function '%s' cannot have both '@interrupt' and '@nmi' attributes", node.Label().Name()), node)
+	}
+	return attributes
+}
+
 // validateReturnType checks that a function return type is valid.
 // Only primitive types and references (pointers, unsized arrays) can be returned.
 // Structs and fixed-size arrays cannot be returned by value.
@@ -1311,3 +2708,10 @@ func (sa *SemanticAnalyzer) error(msg string, node parser.ParserNode) {
 	err := compiler.NewDiagnostic(source, msg, locaction, compiler.PipelineSemanticAnalysis, compiler.SeverityError)
 	sa.errors = append(sa.errors, err)
 }
+
+// warning reports a diagnostic that does not fail the analysis
+func (sa *SemanticAnalyzer) warning(msg string, node parser.ParserNode) {
+	location := node.Tokens()[0].Location()
+	warn := compiler.NewDiagnostic(node.Source(), msg, location, compiler.PipelineSemanticAnalysis, compiler.SeverityWarning)
+	sa.warnings = append(sa.warnings, warn)
+}
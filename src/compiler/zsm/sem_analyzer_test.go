@@ -2,6 +2,7 @@ package zsm
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"zenith/compiler"
@@ -14,6 +15,11 @@ import (
 
 // Helper function to parse code and run semantic analysis
 func analyzeCode(t *testing.T, testName string, code string) (*SemCompilationUnit, []*compiler.Diagnostic) {
+	return analyzeCodeWithOptions(t, testName, code, SemanticAnalyzerOptions{})
+}
+
+// Helper function to parse and analyze code with analyzer options
+func analyzeCodeWithOptions(t *testing.T, testName string, code string, options SemanticAnalyzerOptions) (*SemCompilationUnit, []*compiler.Diagnostic) {
 	// Tokenize
 	tokens := lexer.OpenTokenStream(code)
 
@@ -26,7 +32,7 @@ func analyzeCode(t *testing.T, testName string, code string) (*SemCompilationUni
 	require.True(t, ok, "Root node should be CompilationUnit")
 
 	// Analyze
-	analyzer := NewSemanticAnalyzer()
+	analyzer := NewSemanticAnalyzerWithOptions(options)
 	semCU, semErrors := analyzer.Analyze(cu)
 
 	return semCU, semErrors
@@ -188,6 +194,154 @@ func Test_Analyze_FunctionDuplicate_Error(t *testing.T) {
 	assert.Contains(t, errors[0].Error(), "already declared")
 }
 
+func Test_Analyze_FunctionFastAttribute(t *testing.T) {
+	code := `@fast
+	isr: () {
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_FunctionFastAttribute", code)
+	requireNoErrors(t, errors)
+
+	funcDecl, ok := semCU.Declarations[0].(*SemFunctionDecl)
+	require.True(t, ok)
+	assert.Equal(t, []string{"fast"}, funcDecl.Attributes)
+	assert.True(t, funcDecl.HasAttribute("fast"))
+}
+
+func Test_Analyze_FunctionFastAttributeWithParameters_Error(t *testing.T) {
+	code := `@fast
+	isr: (a: u8) u8 {
+		ret a
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_FunctionFastAttributeWithParameters_Error", code)
+
+	require.Greater(t, len(errors), 0, "Expected error for '@fast' function with parameters")
+	assert.Contains(t, errors[0].Error(), "cannot have parameters or a return value")
+}
+
+func Test_Analyze_FunctionNoReturnAttribute(t *testing.T) {
+	code := `main: () {
+		reset()
+	}
+	@noreturn
+	reset: () {
+		@halt()
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_FunctionNoReturnAttribute", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[1].(*SemFunctionDecl)
+	assert.True(t, funcDecl.HasAttribute(AttributeNoReturn))
+
+	// the call is known to be terminating before 'reset' is declared
+	mainDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	require.Equal(t, 1, len(mainDecl.Body.Statements))
+	assert.True(t, Terminates(mainDecl.Body.Statements[0]))
+}
+
+func Test_Analyze_FunctionNoReturnAttribute_Error(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{"return value", "@noreturn\nfn: () u8 {\n@halt()\n}", "cannot have a return value"},
+		{"return statement", "@noreturn\nfn: () {\nret\n}", "cannot return"},
+		{"argument", "@noreturn(1)\nfn: () {\n}", "does not take an argument"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errors := analyzeCode(t, "Test_Analyze_FunctionNoReturnAttribute_Error", tt.code)
+			require.NotEmpty(t, errors)
+			assert.Contains(t, errors[0].Error(), tt.expected)
+		})
+	}
+}
+
+func Test_Analyze_FunctionOrgAttribute(t *testing.T) {
+	code := `@org(0x0038) @interrupt
+	onTimer: () {
+	}
+	plain: () {
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_FunctionOrgAttribute", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	assert.True(t, funcDecl.HasAttribute(AttributeOrg))
+	require.NotNil(t, funcDecl.Org)
+	assert.Equal(t, uint16(0x0038), *funcDecl.Org)
+
+	plainDecl := semCU.Declarations[1].(*SemFunctionDecl)
+	assert.Nil(t, plainDecl.Org)
+}
+
+func Test_Analyze_FunctionOrgAttribute_Error(t *testing.T) {
+	tests := []struct {
+		name     string
+		attr     string
+		expected string
+	}{
+		{"missing address", "@org", "requires an address"},
+		{"not a constant", "x: = 0x38\n@org(x)", "must be a constant"},
+		{"negative", "@org(-1)", "must be a u16 value"},
+		{"too large", "@org(0x10000)", "must be a u16 value"},
+		{"not an address", "@org(true)", "must be a u16 value"},
+		{"argument on flag", "@fast(1)", "does not take an argument"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := tt.attr + `
+			fn: () {
+			}`
+			_, errors := analyzeCode(t, "Test_Analyze_FunctionOrgAttribute_Error", code)
+			require.NotEmpty(t, errors)
+			assert.Contains(t, errors[0].Error(), tt.expected)
+		})
+	}
+}
+
+func Test_Analyze_FunctionInterruptAttribute(t *testing.T) {
+	code := `@interrupt
+	onVBlank: () {
+	}
+	@nmi
+	onReset: () {
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_FunctionInterruptAttribute", code)
+	requireNoErrors(t, errors)
+
+	funcDecl, ok := semCU.Declarations[0].(*SemFunctionDecl)
+	require.True(t, ok)
+	assert.True(t, funcDecl.HasAttribute(AttributeInterrupt))
+	assert.False(t, funcDecl.HasAttribute(AttributeNMI))
+
+	nmiDecl, ok := semCU.Declarations[1].(*SemFunctionDecl)
+	require.True(t, ok)
+	assert.True(t, nmiDecl.HasAttribute(AttributeNMI))
+}
+
+func Test_Analyze_FunctionInterruptAndNMI_Error(t *testing.T) {
+	code := `@interrupt @nmi
+	handler: () {
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_FunctionInterruptAndNMI_Error", code)
+
+	require.Greater(t, len(errors), 0, "Expected error for conflicting attributes")
+	assert.Contains(t, errors[0].Error(), "cannot have both '@interrupt' and '@nmi'")
+}
+
+func Test_Analyze_FunctionUnknownAttribute_Error(t *testing.T) {
+	code := `@slow
+	main: () {
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_FunctionUnknownAttribute_Error", code)
+
+	require.Greater(t, len(errors), 0, "Expected error for unknown attribute")
+	assert.Contains(t, errors[0].Error(), "unknown function attribute '@slow'")
+}
+
 // ============================================================================
 // Type Declaration Tests
 // ============================================================================
@@ -241,6 +395,204 @@ func Test_Analyze_TypeDeclarationUsage(t *testing.T) {
 	assert.Equal(t, "Point", structType.Name())
 }
 
+func Test_Analyze_StructBitFieldSize(t *testing.T) {
+	code := `struct Flags {
+		enabled: bit,
+		count: u16,
+		visible: bit
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_StructBitFieldSize", code)
+	requireNoErrors(t, errors)
+
+	// a bit (bool) is stored in one byte
+	flagsType := semCU.Declarations[0].(*SemTypeDecl).TypeInfo
+	assert.Equal(t, uint16(4), flagsType.Size())
+	assert.Equal(t, uint16(1), flagsType.Field("count").Offset)
+	assert.Equal(t, uint16(3), flagsType.Field("visible").Offset)
+}
+
+func Test_Analyze_BuiltinTypeName_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		builtin string
+	}{
+		{"struct", "struct u8 {\n\tx: u16\n}", "u8"},
+		{"global", "d16: u8 = 1", "d16"},
+		{"inferred global", "bit: = 1", "bit"},
+		{"local", "main: () {\n\ti16: = 5\n}", "i16"},
+		{"parameter", "main: (u16: u8) {\n}", "u16"},
+		{"range variable", "main: () {\n\tfor i8 in 0..10 {\n\t\tx: = 1\n\t}\n}", "i8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errors := analyzeCode(t, "Test_Analyze_BuiltinTypeName_Error", tt.code)
+
+			require.Len(t, errors, 1)
+			assert.Contains(t, errors[0].Error(), "cannot redefine builtin type '"+tt.builtin+"'")
+		})
+	}
+}
+
+func Test_Analyze_StructNamedLikeBuiltin(t *testing.T) {
+	code := `struct u8Pair {
+		first: u8,
+		second: u8
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_StructNamedLikeBuiltin", code)
+	requireNoErrors(t, errors)
+
+	pairType := semCU.Declarations[0].(*SemTypeDecl).TypeInfo
+	assert.Equal(t, "u8Pair", pairType.Name())
+	assert.Equal(t, uint16(2), pairType.Size())
+}
+
+func Test_Analyze_TypeForwardReference(t *testing.T) {
+	code := `origin: Point
+	length: (line: Line) u8 {
+		ret line.end.x - line.start.x
+	}
+	struct Line {
+		start: Point,
+		end: Point
+	}
+	struct Point {
+		x: u8,
+		y: u8
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_TypeForwardReference", code)
+	requireNoErrors(t, errors)
+
+	varDecl := semCU.Declarations[0].(*SemVariableDecl)
+	pointType, ok := varDecl.Symbol.Type.(*StructType)
+	require.True(t, ok, "Variable type should be StructType")
+	assert.Equal(t, "Point", pointType.Name())
+	assert.Equal(t, uint16(2), pointType.Size())
+
+	// fields of a struct declared later are sized when the struct is
+	lineType := semCU.Declarations[2].(*SemTypeDecl).TypeInfo
+	assert.Equal(t, uint16(4), lineType.Size())
+	assert.Equal(t, uint16(2), lineType.Field("end").Offset)
+}
+
+func Test_Analyze_TypeForwardReference_Cycle(t *testing.T) {
+	code := `struct A {
+		b: B
+	}
+	struct B {
+		a: A
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_TypeForwardReference_Cycle", code)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "struct 'B' cannot contain itself by value")
+}
+
+func Test_Analyze_NestedMemberAccess(t *testing.T) {
+	code := `struct Point {
+		x: u8,
+		y: u8
+	}
+	struct Line {
+		start: Point,
+		end: Point
+	}
+	line: Line
+	main: () {
+		a: = line.start.y
+		b: = line.end.y
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_NestedMemberAccess", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[3].(*SemFunctionDecl)
+	tests := []struct {
+		index  int
+		offset uint16
+	}{
+		{0, 1}, // line.start.y
+		{1, 3}, // line.end.y
+	}
+	for _, tt := range tests {
+		varDecl := funcDecl.Body.Statements[tt.index].(*SemVariableDecl)
+		access, ok := varDecl.Initializer.(*SemMemberAccess)
+		require.True(t, ok, "Initializer should be SemMemberAccess")
+		assert.Equal(t, U8Type, access.Type())
+
+		inner, ok := (*access.Object).(*SemMemberAccess)
+		require.True(t, ok, "Object should be SemMemberAccess")
+		assert.Equal(t, "Point", inner.Type().Name())
+
+		base, offset := access.BaseOffset()
+		ref, ok := base.(*SemSymbolRef)
+		require.True(t, ok, "Base should be SemSymbolRef")
+		assert.Equal(t, "line", ref.Symbol.Name)
+		assert.Equal(t, tt.offset, offset)
+	}
+}
+
+func Test_Analyze_NestedMemberAccess_NonStruct(t *testing.T) {
+	code := `struct Point {
+		x: u8,
+		y: u8
+	}
+	struct Line {
+		start: Point,
+		end: Point
+	}
+	line: Line
+	main: () {
+		a: = line.start.y.z
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_NestedMemberAccess_NonStruct", code)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "cannot access member 'z' on non-struct type u8")
+}
+
+func Test_Analyze_SelfReferentialStruct(t *testing.T) {
+	code := `struct Node {
+		value: u8,
+		next: Node*
+	}
+	head: Node
+	main: () {
+		v: = head.next.value
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_SelfReferentialStruct", code)
+	requireNoErrors(t, errors)
+
+	typeDecl := semCU.Declarations[0].(*SemTypeDecl)
+	nodeType := typeDecl.TypeInfo
+	next := nodeType.Field("next")
+	require.NotNil(t, next)
+	pointerType, ok := next.Type.(*PointerType)
+	require.True(t, ok, "Field 'next' should be a pointer")
+	assert.Same(t, nodeType, pointerType.PointeeType())
+	assert.Equal(t, uint16(1), next.Offset)
+	assert.Equal(t, uint16(3), nodeType.Size())
+
+	// the value is loaded through the pointer, not at an offset in head
+	funcDecl := semCU.Declarations[2].(*SemFunctionDecl)
+	varDecl := funcDecl.Body.Statements[0].(*SemVariableDecl)
+	access := varDecl.Initializer.(*SemMemberAccess)
+	base, offset := access.BaseOffset()
+	assert.Same(t, *access.Object, base)
+	assert.Equal(t, uint16(0), offset)
+}
+
+func Test_Analyze_SelfReferentialStruct_ByValue(t *testing.T) {
+	code := `struct Node {
+		value: u8,
+		next: Node
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_SelfReferentialStruct_ByValue", code)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "struct 'Node' cannot contain itself by value")
+}
+
 // ============================================================================
 // Statement Tests
 // ============================================================================
@@ -259,7 +611,60 @@ func Test_Analyze_Assignment(t *testing.T) {
 	assignment, ok := funcDecl.Body.Statements[1].(*SemAssignment)
 	require.True(t, ok, "Second statement should be SemAssignment")
 	assert.Equal(t, "x", assignment.Target.Name)
-	assert.NotNil(t, assignment.Value)
+	// the value is the right-hand side, not the target
+	value, ok := assignment.Value.(*SemConstant)
+	require.True(t, ok)
+	assert.Equal(t, 20, value.Value)
+}
+
+func Test_Analyze_CompoundAssignment(t *testing.T) {
+	tests := []struct {
+		statement string
+		op        BinaryOperator
+	}{
+		{"x += 2", OpAdd},
+		{"x -= 2", OpSubtract},
+		{"x |= 2", OpBitwiseOr},
+		{"x &= 2", OpBitwiseAnd},
+		{"x ^= 2", OpBitwiseXor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.statement, func(t *testing.T) {
+			code := "main: () {\n\tx: u8 = 10\n\t" + tt.statement + "\n}"
+			semCU, errors := analyzeCode(t, "Test_Analyze_CompoundAssignment", code)
+			requireNoErrors(t, errors)
+
+			funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+			assignment := funcDecl.Body.Statements[1].(*SemAssignment)
+			// x op= v is x = x op v
+			op, ok := assignment.Value.(*SemBinaryOp)
+			require.True(t, ok)
+			assert.Equal(t, tt.op, op.Op)
+			left, ok := op.Left.(*SemSymbolRef)
+			require.True(t, ok)
+			assert.Equal(t, assignment.Target, left.Symbol)
+			assert.Equal(t, U8Type, op.Type())
+			value, ok := ConstantValue(op.Right)
+			require.True(t, ok)
+			assert.Equal(t, 2, value)
+		})
+	}
+}
+
+func Test_Analyze_GlobalAssignment_DeclarationSymbol(t *testing.T) {
+	code := `flags: u8 = 0
+	main: () {
+		flags |= 4
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_GlobalAssignment_DeclarationSymbol", code)
+	requireNoErrors(t, errors)
+
+	varDecl := semCU.Declarations[0].(*SemVariableDecl)
+	funcDecl := semCU.Declarations[1].(*SemFunctionDecl)
+	assignment := funcDecl.Body.Statements[0].(*SemAssignment)
+	// the uses are bound to the symbol of the declaration
+	assert.Same(t, varDecl.Symbol, assignment.Target)
 }
 
 func Test_Analyze_AssignmentUndefined_Error(t *testing.T) {
@@ -272,6 +677,62 @@ func Test_Analyze_AssignmentUndefined_Error(t *testing.T) {
 	assert.Contains(t, errors[0].Error(), "undefined variable")
 }
 
+func Test_Analyze_MultiAssignment(t *testing.T) {
+	code := `main: () {
+		a: u16 = 1
+		b: u16 = 2
+		a, b = b, a
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_MultiAssignment", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	assignment, ok := funcDecl.Body.Statements[2].(*SemMultiAssignment)
+	require.True(t, ok)
+	require.Len(t, assignment.Targets, 2)
+	require.Len(t, assignment.Values, 2)
+	assert.Equal(t, "a", assignment.Targets[0].Name)
+	assert.Equal(t, "b", assignment.Targets[1].Name)
+	// the values are bound to the same symbols: a swap
+	assert.Same(t, assignment.Targets[1], assignment.Values[0].(*SemSymbolRef).Symbol)
+	assert.Same(t, assignment.Targets[0], assignment.Values[1].(*SemSymbolRef).Symbol)
+}
+
+func Test_Analyze_MultiAssignment_Initializes(t *testing.T) {
+	code := `main: () u8 {
+		a: u8
+		b: u8
+		a, b = 1, 2
+		ret a + b
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_MultiAssignment_Initializes", code)
+	requireNoErrors(t, errors)
+}
+
+func Test_Analyze_MultiAssignment_Error(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		message   string
+	}{
+		{"count", "a, b = 1", "assignment to 2 variables has 1 values"},
+		{"type", "a, b = w, 2", "value of type 'u16' cannot be assigned to 'a' of type 'u8'"},
+		{"bool", "a, b = true, 2", "value of type 'bit' cannot be assigned to 'a' of type 'u8'"},
+		{"twice", "a, a = 1, 2", "variable 'a' is assigned more than once"},
+		{"undefined", "a, c = 1, 2", "undefined variable 'c'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := "main: () {\n\ta: u8 = 0\n\tb: u8 = 0\n\tw: u16 = 0x1234\n\t" + tt.statement + "\n}"
+			_, errors := analyzeCode(t, "Test_Analyze_MultiAssignment_Error", code)
+
+			require.Len(t, errors, 1)
+			assert.Contains(t, errors[0].Error(), tt.message)
+		})
+	}
+}
+
 // ============================================================================
 // If, Elfis and Else Tests
 // ============================================================================
@@ -442,6 +903,106 @@ func Test_Analyze_ForLoop_Scope(t *testing.T) {
 	assert.NotNil(t, bodyVarDecl.Initializer, "Loop variable should be accessible in body")
 }
 
+func Test_Analyze_ForRange(t *testing.T) {
+	code := `main: () {
+		for i in 0..10 {
+			j: = i
+		}
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_ForRange", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	forRange, ok := funcDecl.Body.Statements[0].(*SemForRange)
+	require.True(t, ok, "Statement should be SemForRange")
+	assert.Equal(t, "i", forRange.Variable.Name)
+	assert.Equal(t, "main.block1.i", forRange.Variable.QualifiedName)
+	assert.Equal(t, U8Type, forRange.Variable.Type)
+
+	// the body references the loop variable
+	bodyVarDecl := forRange.Body.Statements[0].(*SemVariableDecl)
+	ref, ok := bodyVarDecl.Initializer.(*SemSymbolRef)
+	require.True(t, ok)
+	assert.Same(t, forRange.Variable, ref.Symbol)
+
+	// lowered to: for i := 0; i < 10; i++
+	require.NotNil(t, forRange.Loop)
+	assert.Same(t, forRange.Variable, forRange.Loop.Initializer.(*SemVariableDecl).Symbol)
+	condition := forRange.Loop.Condition.(*SemBinaryOp)
+	assert.Equal(t, OpLessThan, condition.Op)
+	assert.Same(t, forRange.To, condition.Right)
+	assert.Equal(t, OpIncrement, forRange.Loop.Increment.(*SemExpressionStmt).Expression.(*SemUnaryOp).Op)
+	assert.Same(t, forRange.Body, forRange.Loop.Body)
+}
+
+func Test_Analyze_ForRange_WidenedType(t *testing.T) {
+	code := `main: (n: u16) {
+		for i in 1..n {
+			j: = i
+		}
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_ForRange_WidenedType", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	forRange := funcDecl.Body.Statements[0].(*SemForRange)
+	assert.Equal(t, U16Type, forRange.Variable.Type)
+}
+
+func Test_Analyze_ForRange_NotVisibleAfter_Error(t *testing.T) {
+	code := `main: () {
+		for i in 0..10 {
+		}
+		j: = i
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_ForRange_NotVisibleAfter_Error", code)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "undefined identifier 'i'")
+}
+
+func Test_Analyze_ForRange_Type_Error(t *testing.T) {
+	code := `main: (b: bit) {
+		for i in 0..b {
+			j: = i
+		}
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_ForRange_Type_Error", code)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "for loop range must be integers, got u8..bit")
+}
+
+func Test_Analyze_DoWhileLoop(t *testing.T) {
+	code := `main: () {
+		i: = 10
+		do {
+			i = i - 1
+		} while i > 0
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_DoWhileLoop", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	doWhile, ok := funcDecl.Body.Statements[1].(*SemDoWhile)
+	require.True(t, ok, "Statement should be SemDoWhile")
+	assert.Equal(t, 1, len(doWhile.Body.Statements))
+	assert.Equal(t, BitType, doWhile.Condition.Type())
+}
+
+func Test_Analyze_DoWhileLoop_NonBoolCondition(t *testing.T) {
+	code := `main: () {
+		i: = 10
+		do {
+			i = i - 1
+		} while i
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_DoWhileLoop_NonBoolCondition", code)
+
+	require.Equal(t, 1, len(errors))
+	assert.Contains(t, errors[0].Error(), "condition must be bit, got u8")
+}
+
 // ============================================================================
 // Select Tests
 // ============================================================================
@@ -559,6 +1120,47 @@ func Test_Analyze_SelectStatementMultipleCases(t *testing.T) {
 	}
 }
 
+func Test_Analyze_SelectStatementCaseType(t *testing.T) {
+	code := `main: (x: u16) {
+		select x {
+			case 1 {
+				a: = 10
+			}
+			case 0x1234 {
+				b: = 20
+			}
+		}
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_SelectStatementCaseType", code)
+	requireNoErrors(t, errors)
+}
+
+func Test_Analyze_SelectStatementCaseType_Error(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{"string", `"str"`, "case value of type array does not match select expression of type u8"},
+		{"bool", "true", "case value of type bit does not match select expression of type u8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := `main: (x: u8) {
+				select x {
+					case ` + tt.value + ` {
+						a: = 10
+					}
+				}
+			}`
+			_, errors := analyzeCode(t, "Test_Analyze_SelectStatementCaseType_Error", code)
+			require.Len(t, errors, 1)
+			assert.Contains(t, errors[0].Error(), tt.expected)
+		})
+	}
+}
+
 // ============================================================================
 // Return Statement Tests
 // ============================================================================
@@ -641,40 +1243,374 @@ func Test_Analyze_BinaryOperation(t *testing.T) {
 	assert.NotNil(t, binOp.Right)
 }
 
-func Test_Analyze_BooleanLiteral(t *testing.T) {
-	code := `flag: = true`
-	semCU, errors := analyzeCode(t, "Test_Analyze_BooleanLiteral", code)
-	requireNoErrors(t, errors)
+func Test_Analyze_BinaryOperation_BoolArithmetic(t *testing.T) {
+	code := `main: () {
+		result: = true + 1
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_BinaryOperation_BoolArithmetic", code)
 
-	varDecl := semCU.Declarations[0].(*SemVariableDecl)
-	constant, ok := varDecl.Initializer.(*SemConstant)
-	require.True(t, ok)
-	assert.Equal(t, true, constant.Value)
-	assert.Equal(t, BitType, constant.Type())
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "operator '+' requires numeric operands, got bit")
 }
 
-func Test_Analyze_NumberLiteral_U8(t *testing.T) {
-	code := `num: = 42`
-	semCU, errors := analyzeCode(t, "Test_Analyze_NumberLiteral_U8", code)
-	requireNoErrors(t, errors)
+func Test_Analyze_UnaryOperation_BoolNegate(t *testing.T) {
+	code := `main: () {
+		flag: = true
+		result: = -flag
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_UnaryOperation_BoolNegate", code)
 
-	varDecl := semCU.Declarations[0].(*SemVariableDecl)
-	constant, ok := varDecl.Initializer.(*SemConstant)
-	require.True(t, ok)
-	assert.Equal(t, 42, constant.Value)
-	assert.Equal(t, U8Type, constant.Type())
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "operator '-' requires numeric operands, got bit")
 }
 
-func Test_Analyze_NumberLiteral_U16(t *testing.T) {
-	code := `num: = 300`
-	semCU, errors := analyzeCode(t, "Test_Analyze_NumberLiteral_U16", code)
+func Test_Analyze_UnaryOperation_Plus(t *testing.T) {
+	code := `main: (x: u8) {
+		same: = +x
+		negated: = -x
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_UnaryOperation_Plus", code)
 	requireNoErrors(t, errors)
 
-	varDecl := semCU.Declarations[0].(*SemVariableDecl)
-	constant, ok := varDecl.Initializer.(*SemConstant)
-	require.True(t, ok)
-	assert.Equal(t, 300, constant.Value)
-	assert.Equal(t, U16Type, constant.Type())
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	// the operand itself
+	same := funcDecl.Body.Statements[0].(*SemVariableDecl)
+	symbolRef, ok := same.Initializer.(*SemSymbolRef)
+	require.True(t, ok, "Initializer should be SemSymbolRef")
+	assert.Equal(t, "x", symbolRef.Symbol.Name)
+	assert.Equal(t, U8Type, same.Symbol.Type)
+
+	negated := funcDecl.Body.Statements[1].(*SemVariableDecl)
+	unaryOp, ok := negated.Initializer.(*SemUnaryOp)
+	require.True(t, ok, "Initializer should be SemUnaryOp")
+	assert.Equal(t, OpNegate, unaryOp.Op)
+}
+
+func Test_Analyze_UnaryOperation_BoolPlus(t *testing.T) {
+	code := `main: () {
+		flag: = true
+		result: = +flag
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_UnaryOperation_BoolPlus", code)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "operator '+' requires numeric operands, got bit")
+}
+
+func Test_Analyze_UnaryOperation_ToBool(t *testing.T) {
+	code := `main: (x: u8, ptr: u16*) {
+		nonZero: = x?
+		notNil: = ptr?
+		always: = 5?
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_UnaryOperation_ToBool", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	nonZero := funcDecl.Body.Statements[0].(*SemVariableDecl)
+	unaryOp, ok := nonZero.Initializer.(*SemUnaryOp)
+	require.True(t, ok, "Initializer should be SemUnaryOp")
+	assert.Equal(t, OpToBool, unaryOp.Op)
+	assert.Equal(t, BitType, nonZero.Symbol.Type)
+
+	notNil := funcDecl.Body.Statements[1].(*SemVariableDecl)
+	assert.Equal(t, BitType, notNil.Symbol.Type)
+
+	// constants are folded
+	always := funcDecl.Body.Statements[2].(*SemVariableDecl)
+	constant, ok := always.Initializer.(*SemConstant)
+	require.True(t, ok, "Initializer should be SemConstant")
+	assert.Equal(t, true, constant.Value)
+}
+
+func Test_Analyze_UnaryOperation_ToBool_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		operand  string
+		expected string
+	}{
+		{"bool", "flag", "got bit"},
+		{"array", "buffer", "got u8[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := "main: (flag: bit, buffer: u8[4]) {\n\tresult: = " + tt.operand + "?\n}"
+			_, errors := analyzeCode(t, "Test_Analyze_UnaryOperation_ToBool_Errors", code)
+
+			require.Len(t, errors, 1)
+			assert.Contains(t, errors[0].Error(), "operator '?' requires an integer or pointer operand")
+			assert.Contains(t, errors[0].Error(), tt.expected)
+		})
+	}
+}
+
+func Test_Analyze_BinaryOperation_IntegerLogical(t *testing.T) {
+	code := `main: () {
+		result: = 5 and 3
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_BinaryOperation_IntegerLogical", code)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "operator 'and' requires bit operands, got u8")
+}
+
+func Test_Analyze_UnaryOperation_IntegerNot(t *testing.T) {
+	code := `main: () {
+		x: u8 = 5
+		result: = not x
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_UnaryOperation_IntegerNot", code)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "operator 'not' requires bit operands, got u8")
+}
+
+func Test_Analyze_BinaryOperation_Logical(t *testing.T) {
+	code := `main: () {
+		x: u8 = 5
+		flag: = true
+		result: = x > 3 and not flag
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_BinaryOperation_Logical", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	varDecl := funcDecl.Body.Statements[2].(*SemVariableDecl)
+	binOp, ok := varDecl.Initializer.(*SemBinaryOp)
+	require.True(t, ok, "Initializer should be SemBinaryOp")
+	assert.Equal(t, OpLogicalAnd, binOp.Op)
+	// comparisons and logical operators are bool
+	assert.Equal(t, BitType, binOp.Left.Type())
+	assert.Equal(t, BitType, binOp.Type())
+}
+
+func Test_Analyze_BinaryOperation_Widening(t *testing.T) {
+	code := `main: (a8: u8, b16: u16, c8: i8, e16: i16) {
+		sum: = a8 + b16
+		diff: = b16 - a8
+		signed: = c8 + e16
+		masked: = a8 & 0x0F
+		less: = a8 < b16
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_BinaryOperation_Widening", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	expected := []Type{U16Type, U16Type, I16Type, U8Type, BitType}
+	for i, typ := range expected {
+		varDecl := funcDecl.Body.Statements[i].(*SemVariableDecl)
+		binOp, ok := varDecl.Initializer.(*SemBinaryOp)
+		require.True(t, ok, "Initializer should be SemBinaryOp")
+		assert.Equal(t, typ, binOp.Type(), varDecl.Symbol.Name)
+		// the variable is inferred from the widened type
+		assert.Equal(t, typ, varDecl.Symbol.Type, varDecl.Symbol.Name)
+	}
+}
+
+func Test_Analyze_BinaryOperation_MixedComparison_Warning(t *testing.T) {
+	code := `main: (s: i8, u: u16) {
+		less: = s < u
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_BinaryOperation_MixedComparison_Warning", code)
+	requireNoErrors(t, errors)
+
+	require.Len(t, semCU.Warnings, 1)
+	assert.Contains(t, semCU.Warnings[0].Error(), "comparison between i8 and u16 may be surprising; add an explicit conversion")
+	assert.Equal(t, compiler.SeverityWarning, semCU.Warnings[0].Severity)
+
+	// still compiles: the operands are widened
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	less := funcDecl.Body.Statements[0].(*SemVariableDecl)
+	assert.Equal(t, BitType, less.Symbol.Type)
+}
+
+func Test_Analyze_BinaryOperation_Comparison_NoWarning(t *testing.T) {
+	code := `main: (a: u8, b: u8, c: i16, d: i16, s: i8) {
+		same: = a == b
+		signed: = c < d
+		wider: = a < 1000
+		literal: = s > 10
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_BinaryOperation_Comparison_NoWarning", code)
+	requireNoErrors(t, errors)
+
+	assert.Empty(t, semCU.Warnings)
+}
+
+func Test_Analyze_BinaryOperation_DivideByZero(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		expected string
+	}{
+		{"division", "10 / 0", "division by zero"},
+		{"modulo", "a % 0", "modulo by zero"},
+		{"typed zero", "a / 0u16", "division by zero"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := "main: (a: u8) {\n\tx: = " + tt.expr + "\n}"
+			_, errors := analyzeCode(t, "Test_Analyze_BinaryOperation_DivideByZero", code)
+
+			require.Len(t, errors, 1)
+			assert.Contains(t, errors[0].Error(), tt.expected)
+		})
+	}
+}
+
+func Test_Analyze_BinaryOperation_RuntimeDivisor(t *testing.T) {
+	code := `main: (a: u8, b: u8) {
+		quotient: = a / b
+		remainder: = a % b
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_BinaryOperation_RuntimeDivisor", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	remainder := funcDecl.Body.Statements[1].(*SemVariableDecl)
+	binOp, ok := remainder.Initializer.(*SemBinaryOp)
+	require.True(t, ok)
+	assert.Equal(t, OpModulo, binOp.Op)
+	assert.Equal(t, U8Type, binOp.Type())
+}
+
+func Test_Analyze_BinaryOperation_Shift(t *testing.T) {
+	code := `main: (x: u8, count: u16) {
+		left: = x << 2
+		right: = x >> count
+		sum: = x << 1 + 2
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_BinaryOperation_Shift", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	expected := []BinaryOperator{OpShl, OpShr, OpShl}
+	for i, op := range expected {
+		varDecl := funcDecl.Body.Statements[i].(*SemVariableDecl)
+		binOp, ok := varDecl.Initializer.(*SemBinaryOp)
+		require.True(t, ok, "Initializer should be SemBinaryOp")
+		assert.Equal(t, op, binOp.Op, varDecl.Symbol.Name)
+		// the shift count does not widen the result
+		assert.Equal(t, U8Type, binOp.Type(), varDecl.Symbol.Name)
+	}
+
+	// '+' binds tighter: x << (1 + 2)
+	sum := funcDecl.Body.Statements[2].(*SemVariableDecl).Initializer.(*SemBinaryOp)
+	count, ok := sum.Right.(*SemBinaryOp)
+	require.True(t, ok, "'1 + 2' is the shift count")
+	assert.Equal(t, OpAdd, count.Op)
+}
+
+func Test_Analyze_BinaryOperation_BoolShift(t *testing.T) {
+	code := `main: () {
+		result: = true << 1
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_BinaryOperation_BoolShift", code)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "operator '<<' requires numeric operands, got bit")
+}
+
+func Test_Analyze_DecimalLiteral(t *testing.T) {
+	code := `main: () {
+		x: d8 = 1.5
+		y: d16 = 12.34
+		z: d16 = 1.5
+		w: = 0.25
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_DecimalLiteral", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	expected := []struct {
+		typ   Type
+		value int
+	}{
+		{D8Type, 0x15},
+		{D16Type, 0x1234},
+		{D16Type, 0x0150},
+		// inferred: too many fraction digits for d8
+		{D16Type, 0x0025},
+	}
+	for i, exp := range expected {
+		varDecl := funcDecl.Body.Statements[i].(*SemVariableDecl)
+		constant, ok := varDecl.Initializer.(*SemConstant)
+		require.True(t, ok, "Initializer should be SemConstant")
+		assert.Equal(t, exp.value, constant.Value, varDecl.Symbol.Name)
+		assert.Equal(t, exp.typ, constant.Type(), varDecl.Symbol.Name)
+		assert.Equal(t, exp.typ, varDecl.Symbol.Type, varDecl.Symbol.Name)
+	}
+}
+
+func Test_Analyze_DecimalLiteral_IntegerType(t *testing.T) {
+	code := `main: () {
+		y: u8 = 1.5
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_DecimalLiteral_IntegerType", code)
+
+	require.NotEmpty(t, errors)
+	assert.Contains(t, errors[0].Error(), "decimal literal '1.5' requires a decimal type (d8, d16), got u8")
+}
+
+func Test_Analyze_DecimalLiteral_DoesNotFit(t *testing.T) {
+	code := `main: () {
+		x: d8 = 12.5
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_DecimalLiteral_DoesNotFit", code)
+
+	require.NotEmpty(t, errors)
+	assert.Contains(t, errors[0].Error(), "decimal literal '12.5' does not fit d8")
+}
+
+func Test_Analyze_DecimalLiteral_Assignment(t *testing.T) {
+	code := `main: () {
+		x: d16 = 0.0
+		x = 2.5
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_DecimalLiteral_Assignment", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	assignment := funcDecl.Body.Statements[1].(*SemAssignment)
+	assert.Equal(t, 0x0250, assignment.Value.(*SemConstant).Value)
+}
+
+func Test_Analyze_BooleanLiteral(t *testing.T) {
+	code := `flag: = true`
+	semCU, errors := analyzeCode(t, "Test_Analyze_BooleanLiteral", code)
+	requireNoErrors(t, errors)
+
+	varDecl := semCU.Declarations[0].(*SemVariableDecl)
+	constant, ok := varDecl.Initializer.(*SemConstant)
+	require.True(t, ok)
+	assert.Equal(t, true, constant.Value)
+	assert.Equal(t, BitType, constant.Type())
+}
+
+func Test_Analyze_NumberLiteral_U8(t *testing.T) {
+	code := `num: = 42`
+	semCU, errors := analyzeCode(t, "Test_Analyze_NumberLiteral_U8", code)
+	requireNoErrors(t, errors)
+
+	varDecl := semCU.Declarations[0].(*SemVariableDecl)
+	constant, ok := varDecl.Initializer.(*SemConstant)
+	require.True(t, ok)
+	assert.Equal(t, 42, constant.Value)
+	assert.Equal(t, U8Type, constant.Type())
+}
+
+func Test_Analyze_NumberLiteral_U16(t *testing.T) {
+	code := `num: = 300`
+	semCU, errors := analyzeCode(t, "Test_Analyze_NumberLiteral_U16", code)
+	requireNoErrors(t, errors)
+
+	varDecl := semCU.Declarations[0].(*SemVariableDecl)
+	constant, ok := varDecl.Initializer.(*SemConstant)
+	require.True(t, ok)
+	assert.Equal(t, 300, constant.Value)
+	assert.Equal(t, U16Type, constant.Type())
 }
 
 func Test_Analyze_NumberLiteral_I8(t *testing.T) {
@@ -701,6 +1637,56 @@ func Test_Analyze_NumberLiteral_I16(t *testing.T) {
 	assert.Equal(t, I16Type, constant.Type())
 }
 
+func Test_Analyze_NumberLiteral_Suffix(t *testing.T) {
+	tests := []struct {
+		code     string
+		value    int
+		expected Type
+	}{
+		{"42u16", 42, U16Type},
+		{"5i8", 5, I8Type},
+		{"300i16", 300, I16Type},
+		{"0xFFu8", 255, U8Type},
+		{"-5i16", -5, I16Type},
+		{"-128i8", -128, I8Type},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			semCU, errors := analyzeCode(t, "Test_Analyze_NumberLiteral_Suffix", "num: = "+tt.code)
+			requireNoErrors(t, errors)
+
+			varDecl := semCU.Declarations[0].(*SemVariableDecl)
+			constant, ok := varDecl.Initializer.(*SemConstant)
+			require.True(t, ok)
+			assert.Equal(t, tt.value, constant.Value)
+			assert.Equal(t, tt.expected, constant.Type())
+			assert.Equal(t, tt.expected, varDecl.Symbol.Type)
+		})
+	}
+}
+
+func Test_Analyze_NumberLiteral_Suffix_Errors(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"300u8", "number literal '300u8' does not fit u8"},
+		{"128i8", "number literal '128i8' does not fit i8"},
+		{"-1u16", "number literal '-1u16' does not fit u16"},
+		{"1i32", "unknown number suffix 'i32'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			_, errors := analyzeCode(t, "Test_Analyze_NumberLiteral_Suffix_Errors", "num: = "+tt.code)
+
+			require.NotEmpty(t, errors)
+			assert.Contains(t, errors[0].Error(), tt.expected)
+		})
+	}
+}
+
 func Test_Analyze_NumberLiteral_Hex(t *testing.T) {
 	code := `num: = 0xFF`
 	semCU, errors := analyzeCode(t, "Test_Analyze_NumberLiteral_Hex", code)
@@ -757,12 +1743,62 @@ func Test_Analyze_StringLiteral(t *testing.T) {
 	varDecl := semCU.Declarations[0].(*SemVariableDecl)
 	constant, ok := varDecl.Initializer.(*SemConstant)
 	require.True(t, ok)
-	assert.Equal(t, "\"hello\"", constant.Value)
+	assert.Equal(t, "hello", constant.Value)
 
 	// String should be an array type
 	arrayType, ok := constant.Type().(*ArrayType)
 	require.True(t, ok, "String type should be array")
 	assert.Equal(t, U8Type, arrayType.ElementType())
+	// null-terminated by default: 5 characters + terminator
+	assert.Equal(t, uint16(6), arrayType.Length())
+}
+
+func Test_Analyze_StringLiteral_Escapes(t *testing.T) {
+	code := `msg: = "say \"hi\"\r\n\x41\0"`
+	semCU, errors := analyzeCode(t, "Test_Analyze_StringLiteral_Escapes", code)
+	requireNoErrors(t, errors)
+
+	varDecl := semCU.Declarations[0].(*SemVariableDecl)
+	constant, ok := varDecl.Initializer.(*SemConstant)
+	require.True(t, ok)
+	chars, ok := constant.AsBytes()
+	require.True(t, ok)
+	assert.Equal(t, []byte{'s', 'a', 'y', ' ', '"', 'h', 'i', '"', '\r', '\n', 'A', 0}, chars)
+
+	// the decoded characters + terminator
+	arrayType, ok := constant.Type().(*ArrayType)
+	require.True(t, ok, "String type should be array")
+	assert.Equal(t, uint16(13), arrayType.Length())
+}
+
+func Test_Analyze_StringLiteral_UnknownEscape(t *testing.T) {
+	code := `msg: = "tab\q"`
+	_, errors := analyzeCode(t, "Test_Analyze_StringLiteral_UnknownEscape", code)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "unknown escape sequence '\\q'")
+}
+
+func Test_Analyze_StringLiteral_LengthPrefixed(t *testing.T) {
+	code := `msg: = "hello"`
+	options := SemanticAnalyzerOptions{StringFormat: StringLengthPrefixed}
+	semCU, errors := analyzeCodeWithOptions(t, "Test_Analyze_StringLiteral_LengthPrefixed", code, options)
+	requireNoErrors(t, errors)
+
+	varDecl := semCU.Declarations[0].(*SemVariableDecl)
+	arrayType, ok := varDecl.Initializer.Type().(*ArrayType)
+	require.True(t, ok, "String type should be array")
+	// length byte + 5 characters
+	assert.Equal(t, uint16(6), arrayType.Length())
+}
+
+func Test_Analyze_StringLiteral_LengthPrefixedTooLong(t *testing.T) {
+	code := `msg: = "` + strings.Repeat("x", 256) + `"`
+	options := SemanticAnalyzerOptions{StringFormat: StringLengthPrefixed}
+	_, errors := analyzeCodeWithOptions(t, "Test_Analyze_StringLiteral_LengthPrefixedTooLong", code, options)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "too long for a length prefix")
 }
 
 func Test_Analyze_FunctionCall(t *testing.T) {
@@ -810,6 +1846,286 @@ func Test_Analyze_FunctionCallUndefined_Error(t *testing.T) {
 	assert.Contains(t, errors[0].Error(), "undefined function")
 }
 
+func Test_Analyze_IntrinsicBitOperations(t *testing.T) {
+	code := `main: () {
+		flags: u8 = 0
+		@setbit(3, flags)
+		@resetbit(0, flags)
+		b := @bit(7, flags)
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_IntrinsicBitOperations", code)
+	requireNoErrors(t, errors)
+
+	mainFunc := semCU.Declarations[0].(*SemFunctionDecl)
+	setBit := mainFunc.Body.Statements[1].(*SemExpressionStmt).Expression.(*SemFunctionCall)
+	assert.Equal(t, "@setbit", setBit.Function.Name)
+	index, ok := BitIndex(setBit.Arguments[0])
+	assert.True(t, ok)
+	assert.Equal(t, uint8(3), index)
+
+	bitDecl := mainFunc.Body.Statements[3].(*SemVariableDecl)
+	assert.Equal(t, BitType, bitDecl.Symbol.Type)
+}
+
+func Test_Analyze_IntrinsicBitIndexNotConstant_Error(t *testing.T) {
+	code := `main: (n: u8) {
+		flags: u8 = 0
+		@setbit(n, flags)
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_IntrinsicBitIndexNotConstant_Error", code)
+
+	require.Greater(t, len(errors), 0, "Expected error for non-constant bit index")
+	assert.Contains(t, errors[0].Error(), "must be a constant 0-7")
+}
+
+func Test_Analyze_IntrinsicBitIndexOutOfRange_Error(t *testing.T) {
+	code := `main: () {
+		flags: u8 = 0
+		@resetbit(8, flags)
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_IntrinsicBitIndexOutOfRange_Error", code)
+
+	require.Greater(t, len(errors), 0, "Expected error for bit index out of range")
+	assert.Contains(t, errors[0].Error(), "must be a constant 0-7")
+}
+
+func Test_Analyze_IntrinsicPeekPoke(t *testing.T) {
+	code := `main: () {
+		b := @peek(0x4000)
+		@poke(0x4000, 7)
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_IntrinsicPeekPoke", code)
+	requireNoErrors(t, errors)
+
+	mainFunc := semCU.Declarations[0].(*SemFunctionDecl)
+	peekDecl := mainFunc.Body.Statements[0].(*SemVariableDecl)
+	assert.Equal(t, U8Type, peekDecl.Symbol.Type)
+
+	poke := mainFunc.Body.Statements[1].(*SemExpressionStmt).Expression.(*SemFunctionCall)
+	assert.Equal(t, "@poke", poke.Function.Name)
+	assert.Nil(t, poke.Type())
+}
+
+func Test_Analyze_IntrinsicPokeValueType_Error(t *testing.T) {
+	code := `main: () {
+		@poke(0x4000, 0x1234)
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_IntrinsicPokeValueType_Error", code)
+
+	require.Greater(t, len(errors), 0, "Expected error for 16-bit value")
+	assert.Contains(t, errors[0].Error(), "requires an 8-bit value")
+}
+
+func Test_Analyze_IntrinsicPeekAddressType_Error(t *testing.T) {
+	code := `main: (a: u8) {
+		b := @peek(a)
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_IntrinsicPeekAddressType_Error", code)
+
+	require.Greater(t, len(errors), 0, "Expected error for 8-bit address")
+	assert.Contains(t, errors[0].Error(), "address of '@peek'")
+}
+
+func Test_Analyze_IntrinsicAddressRange_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		call string
+	}{
+		{"peek", "@peek(0x10000)"},
+		{"poke", "@poke(70000, 1)"},
+		{"memset", "@memset(0x12345, 0, 4)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := "main: () {\n\t" + tt.call + "\n}"
+			_, errors := analyzeCode(t, "Test_Analyze_IntrinsicAddressRange_Error", code)
+
+			require.Greater(t, len(errors), 0, "Expected error for an address outside the address space")
+			assert.Contains(t, errors[0].Error(), "must be 0-0xFFFF")
+		})
+	}
+}
+
+func Test_Analyze_IntrinsicMemChr(t *testing.T) {
+	code := `find: (buffer: u8[16], count: u8) u16 {
+		at := @memchr(buffer, 0x0D, count)
+		ret at
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_IntrinsicMemChr", code)
+	requireNoErrors(t, errors)
+
+	fn := semCU.Declarations[0].(*SemFunctionDecl)
+	decl := fn.Body.Statements[0].(*SemVariableDecl)
+	assert.Equal(t, U16Type, decl.Symbol.Type)
+}
+
+func Test_Analyze_IntrinsicMemChr_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		call     string
+		expected string
+	}{
+		{"arguments", "@memchr(0x4000, 7)", "expects 3 arguments"},
+		{"address", "@memchr(value, 7, 10)", "address of '@memchr'"},
+		{"value", "@memchr(0x4000, 0x1234, 10)", "requires an 8-bit value"},
+		{"zero length", "@memchr(0x4000, 7, 0)", "must not be 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := "main: (value: u8) {\n\tat := " + tt.call + "\n}"
+			_, errors := analyzeCode(t, "Test_Analyze_IntrinsicMemChr_Errors", code)
+
+			require.Greater(t, len(errors), 0)
+			assert.Contains(t, errors[0].Error(), tt.expected)
+		})
+	}
+}
+
+func Test_Analyze_IntrinsicMemSet(t *testing.T) {
+	code := `clear: (buffer: u8[16], count: u16) {
+		@memset(buffer, 0, count)
+		@memset(0x4000, 0x20, 0)
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_IntrinsicMemSet", code)
+	requireNoErrors(t, errors)
+}
+
+func Test_Analyze_IntrinsicMemSet_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		call     string
+		expected string
+	}{
+		{"arguments", "@memset(0x4000, 7)", "expects 3 arguments"},
+		{"address", "@memset(value, 7, 10)", "address of '@memset'"},
+		{"value", "@memset(0x4000, 0x1234, 10)", "requires an 8-bit value"},
+		{"length", "@memset(0x4000, 7, -1)", "length of '@memset'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := "main: (value: u8) {\n\t" + tt.call + "\n}"
+			_, errors := analyzeCode(t, "Test_Analyze_IntrinsicMemSet_Errors", code)
+
+			require.Greater(t, len(errors), 0)
+			assert.Contains(t, errors[0].Error(), tt.expected)
+		})
+	}
+}
+
+func Test_Analyze_IntrinsicHaltNop(t *testing.T) {
+	code := `main: () {
+		@nop()
+		@halt()
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_IntrinsicHaltNop", code)
+	requireNoErrors(t, errors)
+
+	mainFunc := semCU.Declarations[0].(*SemFunctionDecl)
+	halt := mainFunc.Body.Statements[1].(*SemExpressionStmt).Expression.(*SemFunctionCall)
+	assert.Equal(t, "@halt", halt.Function.Name)
+	assert.Nil(t, halt.Type())
+}
+
+func Test_Analyze_IntrinsicNopArity_Error(t *testing.T) {
+	code := `main: () {
+		@nop(1)
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_IntrinsicNopArity_Error", code)
+
+	require.Greater(t, len(errors), 0, "Expected arity error")
+	assert.Contains(t, errors[0].Error(), "'@nop' expects 0 arguments, got 1")
+}
+
+func Test_Analyze_IntrinsicRestart(t *testing.T) {
+	code := `main: () {
+		@rst(0x10)
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_IntrinsicRestart", code)
+	requireNoErrors(t, errors)
+
+	mainFunc := semCU.Declarations[0].(*SemFunctionDecl)
+	rst := mainFunc.Body.Statements[0].(*SemExpressionStmt).Expression.(*SemFunctionCall)
+	vector, ok := RestartVector(rst.Arguments[0])
+	assert.True(t, ok)
+	assert.Equal(t, uint8(0x10), vector)
+}
+
+func Test_Analyze_IntrinsicRestartInvalidVector_Error(t *testing.T) {
+	code := `main: () {
+		@rst(0x05)
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_IntrinsicRestartInvalidVector_Error", code)
+
+	require.Greater(t, len(errors), 0, "Expected error for invalid restart vector")
+	assert.Contains(t, errors[0].Error(), "vector of '@rst' must be a constant")
+}
+
+func Test_Analyze_FunctionCallNamedArguments(t *testing.T) {
+	code := `sub: (a: u8, b: u8) u8 {
+		ret a - b
+	}
+	main: () {
+		sub(b = 1, a = 10)
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_FunctionCallNamedArguments", code)
+	requireNoErrors(t, errors)
+
+	mainFunc := semCU.Declarations[1].(*SemFunctionDecl)
+	funcCall := mainFunc.Body.Statements[0].(*SemExpressionStmt).Expression.(*SemFunctionCall)
+	require.Equal(t, 2, len(funcCall.Arguments))
+	// arguments are ordered by parameter
+	assert.Equal(t, 10, funcCall.Arguments[0].(*SemConstant).Value)
+	assert.Equal(t, 1, funcCall.Arguments[1].(*SemConstant).Value)
+}
+
+func Test_Analyze_FunctionCallMixedArguments(t *testing.T) {
+	code := `mix: (a: u8, b: u8, c: u8) {
+	}
+	main: () {
+		mix(1, c = 3, b = 2)
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_FunctionCallMixedArguments", code)
+	requireNoErrors(t, errors)
+
+	mainFunc := semCU.Declarations[1].(*SemFunctionDecl)
+	funcCall := mainFunc.Body.Statements[0].(*SemExpressionStmt).Expression.(*SemFunctionCall)
+	require.Equal(t, 3, len(funcCall.Arguments))
+	assert.Equal(t, 1, funcCall.Arguments[0].(*SemConstant).Value)
+	assert.Equal(t, 2, funcCall.Arguments[1].(*SemConstant).Value)
+	assert.Equal(t, 3, funcCall.Arguments[2].(*SemConstant).Value)
+}
+
+func Test_Analyze_FunctionCallNamedArguments_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		call     string
+		expected string
+	}{
+		{"unknown", "mix(1, d = 2)", "unknown parameter 'd' in call to 'mix'"},
+		{"duplicate", "mix(b = 1, b = 2)", "duplicate argument for parameter 'b'"},
+		{"duplicate positional", "mix(1, a = 2)", "duplicate argument for parameter 'a'"},
+		{"positional after named", "mix(b = 1, 2)", "positional argument cannot follow named arguments"},
+		{"missing", "mix(b = 1)", "missing argument for parameter 'a'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := `mix: (a: u8, b: u8) {
+			}
+			main: () {
+				` + tt.call + `
+			}`
+			_, errors := analyzeCode(t, "Test_Analyze_FunctionCallNamedArguments_Errors", code)
+
+			require.Greater(t, len(errors), 0, "Expected error for "+tt.call)
+			assert.Contains(t, errors[0].Error(), tt.expected)
+		})
+	}
+}
+
 // ============================================================================
 // Scope Tests
 // ============================================================================
@@ -841,6 +2157,73 @@ func Test_Analyze_ScopeGlobalAccess(t *testing.T) {
 	assert.Equal(t, 1, len(funcDecl.Body.Statements))
 }
 
+func Test_Analyze_ScopeRedeclaration_Error(t *testing.T) {
+	code := `main: (c: bit) {
+		if c {
+			x: = 1
+			x: = 2
+		}
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_ScopeRedeclaration_Error", code)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "symbol 'x' already declared in this scope")
+}
+
+func Test_Analyze_ScopeShadowing_Warning(t *testing.T) {
+	code := `main: (c: bit, p: u8) {
+		x: = 1
+		if c {
+			x: = 2
+			p: = 3
+		}
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_ScopeShadowing_Warning", code)
+	requireNoErrors(t, errors)
+
+	require.Len(t, semCU.Warnings, 2)
+	assert.Contains(t, semCU.Warnings[0].Error(), "variable 'x' shadows a variable of an outer scope")
+	assert.Contains(t, semCU.Warnings[1].Error(), "variable 'p' shadows a variable of an outer scope")
+	assert.Equal(t, compiler.SeverityWarning, semCU.Warnings[0].Severity)
+}
+
+func Test_Analyze_ScopeNearestDeclaration(t *testing.T) {
+	code := `main: (c: bit) {
+		x: u8 = 1
+		if c {
+			x: u16 = 2
+			inner: = x
+		}
+		outer: = x
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_ScopeNearestDeclaration", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	ifStmt := funcDecl.Body.Statements[1].(*SemIf)
+
+	inner := ifStmt.ThenBlock.Statements[1].(*SemVariableDecl)
+	assert.Equal(t, U16Type, inner.Symbol.Type)
+	assert.Equal(t, "main.block1.x", inner.Initializer.(*SemSymbolRef).Symbol.QualifiedName)
+
+	outer := funcDecl.Body.Statements[2].(*SemVariableDecl)
+	assert.Equal(t, U8Type, outer.Symbol.Type)
+	assert.Equal(t, "main.x", outer.Initializer.(*SemSymbolRef).Symbol.QualifiedName)
+}
+
+func Test_Analyze_ScopeBlockNotVisibleAfter_Error(t *testing.T) {
+	code := `main: (c: bit) {
+		if c {
+			y: = 1
+		}
+		z: = y
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_ScopeBlockNotVisibleAfter_Error", code)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "undefined identifier 'y'")
+}
+
 // ============================================================================
 // Built-in Types Tests
 // ============================================================================
@@ -1127,3 +2510,426 @@ func Test_Analyze_ArrayInitializerWithSubscript(t *testing.T) {
 	// Verify result type is u8
 	assert.Equal(t, U8Type, subscript.Type())
 }
+
+func Test_Analyze_InlineAsm(t *testing.T) {
+	code := `main: () {
+		asm {
+			ld a, 42
+		}
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_InlineAsm", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	require.Equal(t, 1, len(funcDecl.Body.Statements))
+
+	asmStmt, ok := funcDecl.Body.Statements[0].(*SemInlineAsm)
+	require.True(t, ok, "Statement should be SemInlineAsm")
+	assert.Equal(t, "\n\t\t\tld a, 42\n\t\t", asmStmt.Text)
+}
+
+func Test_Analyze_OverflowIntrinsic(t *testing.T) {
+	code := `checked: (a: i16, b: i16) bit {
+		ret @overflow(a - b)
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_OverflowIntrinsic", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	retStmt := funcDecl.Body.Statements[0].(*SemReturn)
+	call, ok := retStmt.Value.(*SemFunctionCall)
+	require.True(t, ok)
+	assert.Equal(t, BitType, call.Type())
+}
+
+func Test_Analyze_OverflowIntrinsic_Error(t *testing.T) {
+	code := `checked: (a: i8, b: i8) bit {
+		ret @overflow(a * b)
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_OverflowIntrinsic_Error", code)
+
+	require.NotEmpty(t, errors)
+	assert.Contains(t, errors[0].Error(), "requires an addition or subtraction")
+}
+
+func Test_Analyze_AssertIntrinsic(t *testing.T) {
+	code := `struct Point {
+		x: u8,
+		y: u8
+	}
+	main: () {
+		@assert(@sizeof(Point) == 2)
+		@assert(@sizeof(u16) * 8 == 16 and not false)
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_AssertIntrinsic", code)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[1].(*SemFunctionDecl)
+	require.Equal(t, 2, len(funcDecl.Body.Statements))
+}
+
+func Test_Analyze_AssertIntrinsic_Failed(t *testing.T) {
+	code := `struct Point {
+		x: u8,
+		y: u8
+	}
+	main: () {
+		@assert(@sizeof(Point) == 3)
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_AssertIntrinsic_Failed", code)
+
+	require.Len(t, errors, 1)
+	assert.Equal(t, "Test_Analyze_AssertIntrinsic_Failed:6:3: assertion failed", errors[0].Error())
+}
+
+func Test_Analyze_AssertIntrinsic_NotConstant(t *testing.T) {
+	code := `check: (x: u8) {
+		@assert(x > 3)
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_AssertIntrinsic_NotConstant", code)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "condition of '@assert' must be a constant bit")
+}
+
+// memoryFiles resolves '@include_bin' paths from memory
+func memoryFiles(files map[string][]byte) FileResolver {
+	return func(path string) ([]byte, error) {
+		data, ok := files[path]
+		if !ok {
+			return nil, fmt.Errorf("file not found")
+		}
+		return data, nil
+	}
+}
+
+func Test_Analyze_IncludeBin(t *testing.T) {
+	code := `main: () {
+		sprite: = @include_bin("gfx/sprite.bin")
+	}`
+	options := SemanticAnalyzerOptions{FileResolver: memoryFiles(map[string][]byte{
+		"gfx/sprite.bin": {0x18, 0x3C, 0x7E, 0xFF},
+	})}
+	semCU, errors := analyzeCodeWithOptions(t, "Test_Analyze_IncludeBin", code, options)
+	requireNoErrors(t, errors)
+
+	funcDecl := semCU.Declarations[0].(*SemFunctionDecl)
+	varDecl := funcDecl.Body.Statements[0].(*SemVariableDecl)
+	constant, ok := varDecl.Initializer.(*SemConstant)
+	require.True(t, ok, "Initializer should be SemConstant")
+	assert.Equal(t, []byte{0x18, 0x3C, 0x7E, 0xFF}, constant.Value)
+
+	arrayType, ok := varDecl.Symbol.Type.(*ArrayType)
+	require.True(t, ok, "Variable should be an array")
+	assert.Equal(t, U8Type, arrayType.ElementType())
+	assert.Equal(t, uint16(4), arrayType.Length())
+}
+
+func Test_Analyze_IncludeBin_NotFound(t *testing.T) {
+	code := `main: () {
+		sprite: = @include_bin("missing.bin")
+	}`
+	options := SemanticAnalyzerOptions{FileResolver: memoryFiles(map[string][]byte{})}
+	_, errors := analyzeCodeWithOptions(t, "Test_Analyze_IncludeBin_NotFound", code, options)
+
+	require.NotEmpty(t, errors)
+	assert.Contains(t, errors[0].Error(), "cannot include 'missing.bin': file not found")
+}
+
+func Test_Analyze_IncludeBin_PathNotString(t *testing.T) {
+	code := `main: () {
+		sprite: = @include_bin(42)
+	}`
+	options := SemanticAnalyzerOptions{FileResolver: memoryFiles(map[string][]byte{})}
+	_, errors := analyzeCodeWithOptions(t, "Test_Analyze_IncludeBin_PathNotString", code, options)
+
+	require.NotEmpty(t, errors)
+	assert.Contains(t, errors[0].Error(), "path of '@include_bin' must be a string literal")
+}
+
+func Test_Analyze_DataBytes(t *testing.T) {
+	code := `sine: = @db(0, 49, 90, 117, 127, -1)
+main: () u8 {
+	ret sine[2]
+}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_DataBytes", code)
+	requireNoErrors(t, errors)
+
+	varDecl := semCU.Declarations[0].(*SemVariableDecl)
+	constant, ok := varDecl.Initializer.(*SemConstant)
+	require.True(t, ok, "Initializer should be SemConstant")
+	assert.Equal(t, []byte{0, 49, 90, 117, 127, 0xFF}, constant.Value)
+
+	arrayType, ok := varDecl.Symbol.Type.(*ArrayType)
+	require.True(t, ok, "Variable should be an array")
+	assert.Equal(t, U8Type, arrayType.ElementType())
+	assert.Equal(t, uint16(6), arrayType.Length())
+}
+
+func Test_Analyze_DataBytes_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{"no values", "data: = @db()", "'@db' expects at least 1 value"},
+		{"too large", "data: = @db(1, 256)", "value 256 of '@db' does not fit a byte"},
+		{"not constant", "count: u8 = 1\ndata: = @db(count + 1)", "values of '@db' must be constant numbers"},
+		{"string", `data: = @db("hi")`, "values of '@db' must be constant numbers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errors := analyzeCode(t, "Test_Analyze_DataBytes_Errors", tt.code)
+
+			require.NotEmpty(t, errors)
+			assert.Contains(t, errors[0].Error(), tt.expected)
+		})
+	}
+}
+
+func Test_Analyze_AllSymbols(t *testing.T) {
+	code := `struct Point {
+	x: u8
+}
+count: u8 = 0
+add: (a: u8, b: u8) u8 {
+	sum: = a + b
+	ret sum
+}
+main: () {
+	p: Point
+	total: = add(1, 2)
+}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_AllSymbols", code)
+	requireNoErrors(t, errors)
+
+	type definition struct {
+		name      string
+		kind      SymbolKind
+		parameter bool
+		typ       string
+	}
+	var definitions []definition
+	for _, symbol := range semCU.AllSymbols() {
+		definitions = append(definitions, definition{symbol.Name, symbol.Kind, symbol.Parameter, typeName(symbol.Type)})
+	}
+	// builtins are not included
+	assert.Equal(t, []definition{
+		{"Point", SymbolType, false, "Point"},
+		{"count", SymbolVariable, false, "u8"},
+		{"add", SymbolFunction, false, "function"},
+		{"main", SymbolFunction, false, "function"},
+		{"a", SymbolVariable, true, "u8"},
+		{"b", SymbolVariable, true, "u8"},
+		{"sum", SymbolVariable, false, "u8"},
+		{"p", SymbolVariable, false, "Point"},
+		{"total", SymbolVariable, false, "u8"},
+	}, definitions)
+}
+
+func Test_Analyze_AllSymbols_Span(t *testing.T) {
+	code := `add: (a: u8) u8 {
+	ret a
+}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_AllSymbols_Span", code)
+	requireNoErrors(t, errors)
+
+	symbols := semCU.AllSymbols()
+	require.Len(t, symbols, 2)
+
+	// the function spans its declaration, the parameter its field
+	start, end := symbols[0].Span()
+	assert.Equal(t, compiler.Location{Index: 0, Line: 1, Column: 1}, start)
+	assert.Equal(t, compiler.Location{Index: len(code), Line: 3, Column: 2}, end)
+	start, end = symbols[1].Span()
+	assert.Equal(t, compiler.Location{Index: 6, Line: 1, Column: 7}, start)
+	assert.Equal(t, compiler.Location{Index: 11, Line: 1, Column: 12}, end)
+}
+
+func Test_Analyze_Resolve_FunctionCall(t *testing.T) {
+	code := `add: (a: u8) u8 {
+	ret a
+}
+main: () {
+	x: = add(1)
+}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_Resolve_FunctionCall", code)
+	requireNoErrors(t, errors)
+
+	// inside 'add' of the call
+	index := strings.Index(code, "add(1)") + 1
+	symbol, ok := semCU.Resolve(compiler.Location{Index: index})
+	require.True(t, ok)
+	assert.Equal(t, "add", symbol.Name)
+	assert.Equal(t, SymbolFunction, symbol.Kind)
+
+	start, _ := symbol.Span()
+	assert.Equal(t, 0, start.Index)
+}
+
+func Test_Analyze_Resolve_Variable(t *testing.T) {
+	code := `main: () {
+	count: u8 = 1
+	total: = count + 2
+}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_Resolve_Variable", code)
+	requireNoErrors(t, errors)
+
+	// at the last character of the 'count' use
+	index := strings.LastIndex(code, "count") + len("count") - 1
+	symbol, ok := semCU.Resolve(compiler.Location{Index: index})
+	require.True(t, ok)
+	assert.Equal(t, "count", symbol.Name)
+	assert.Equal(t, SymbolVariable, symbol.Kind)
+
+	start, _ := symbol.Span()
+	assert.Equal(t, strings.Index(code, "count"), start.Index)
+
+	// just past the 'count' use
+	_, ok = semCU.Resolve(compiler.Location{Index: index + 1})
+	assert.False(t, ok)
+}
+
+func Test_Analyze_EntryPoint(t *testing.T) {
+	code := `main: () {
+	}`
+	options := SemanticAnalyzerOptions{EntryPoint: DefaultEntryPoint}
+	_, errors := analyzeCodeWithOptions(t, "Test_Analyze_EntryPoint", code, options)
+	requireNoErrors(t, errors)
+}
+
+func Test_Analyze_EntryPoint_Missing(t *testing.T) {
+	code := `start: () {
+	}`
+	options := SemanticAnalyzerOptions{EntryPoint: DefaultEntryPoint}
+	_, errors := analyzeCodeWithOptions(t, "Test_Analyze_EntryPoint_Missing", code, options)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "entry point 'main' is not declared")
+}
+
+func Test_Analyze_EntryPoint_Parameters(t *testing.T) {
+	code := `main: (argc: u8) {
+	}`
+	options := SemanticAnalyzerOptions{EntryPoint: DefaultEntryPoint}
+	_, errors := analyzeCodeWithOptions(t, "Test_Analyze_EntryPoint_Parameters", code, options)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "entry point 'main' cannot have parameters")
+}
+
+func Test_Analyze_EntryPoint_ReturnValue(t *testing.T) {
+	code := `main: () u8 {
+		ret 0
+	}`
+	options := SemanticAnalyzerOptions{EntryPoint: DefaultEntryPoint}
+	_, errors := analyzeCodeWithOptions(t, "Test_Analyze_EntryPoint_ReturnValue", code, options)
+
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "entry point 'main' cannot return a value")
+}
+
+func Test_Analyze_EntryPoint_Library(t *testing.T) {
+	code := `helper: (x: u8) u8 {
+		ret x
+	}`
+	// no entry point configured: nothing to validate
+	_, errors := analyzeCode(t, "Test_Analyze_EntryPoint_Library", code)
+	requireNoErrors(t, errors)
+}
+
+func Test_Analyze_Condition_Bool(t *testing.T) {
+	code := `check: (flag: bit, x: u8) {
+		if flag {
+			y: = 1
+		} elsif x > 3 {
+			z: = 2
+		}
+		for x > 0 {
+			x = x - 1
+		}
+	}`
+	_, errors := analyzeCode(t, "Test_Analyze_Condition_Bool", code)
+	requireNoErrors(t, errors)
+}
+
+func Test_Analyze_Condition_Integer(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"if", `check: (x: u8) {
+			if x {
+				y: = 1
+			}
+		}`},
+		{"elsif", `check: (x: u8) {
+			if x > 3 {
+				y: = 1
+			} elsif x {
+				z: = 2
+			}
+		}`},
+		{"for", `check: (x: u8) {
+			for x - 1 {
+				x = x - 1
+			}
+		}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errors := analyzeCode(t, "Test_Analyze_Condition_Integer", tt.code)
+
+			require.Len(t, errors, 1)
+			assert.Contains(t, errors[0].Error(), "condition must be bit, got u8")
+		})
+	}
+}
+
+func Test_Analyze_AddressOf(t *testing.T) {
+	code := `table: u16[] = [@addressof(f), @addressof(g)]
+	f: () {
+	}
+	g: () {
+	}`
+	semCU, errors := analyzeCode(t, "Test_Analyze_AddressOf", code)
+	requireNoErrors(t, errors)
+
+	varDecl := semCU.Declarations[0].(*SemVariableDecl)
+	arrayInit, ok := varDecl.Initializer.(*SemArrayInitializer)
+	require.True(t, ok, "Initializer should be array initializer")
+	require.Len(t, arrayInit.Elements, 2)
+	assert.Equal(t, U16Type, arrayInit.Elements[0].Type())
+
+	names := []string{}
+	for _, element := range arrayInit.Elements {
+		name, ok := AddressOfFunction(element)
+		require.True(t, ok)
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"f", "g"}, names)
+}
+
+func Test_Analyze_AddressOf_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		argument string
+		expected string
+	}{
+		{"unknown", "h", "undefined function 'h'"},
+		{"variable", "count", "'count' is not a function"},
+		{"expression", "1 + 2", "'@addressof' requires a function name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := "count: u8 = 0\nmain: () {\n\taddress: = @addressof(" + tt.argument + ")\n}"
+			_, errors := analyzeCode(t, "Test_Analyze_AddressOf_Errors", code)
+
+			require.NotEmpty(t, errors)
+			assert.Contains(t, errors[0].Error(), tt.expected)
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package zsm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dump prints the semantic tree of the compilation unit, one node per line, indented by nesting.
+// Expressions show their resolved type ('SemBinaryOp(OpAdd):u8'),
+// symbol references the symbol they are bound to ('SemSymbolRef(a):u8').
+func Dump(unit *SemCompilationUnit) string {
+	d := &semDumper{}
+	d.line("SemCompilationUnit (%d declarations)", len(unit.Declarations))
+	d.indent++
+	for _, decl := range unit.Declarations {
+		d.declaration(decl)
+	}
+	return d.sb.String()
+}
+
+type semDumper struct {
+	sb     strings.Builder
+	indent int
+}
+
+func (d *semDumper) line(format string, args ...interface{}) {
+	d.sb.WriteString(strings.Repeat("  ", d.indent))
+	fmt.Fprintf(&d.sb, format, args...)
+	d.sb.WriteString("\n")
+}
+
+// nested dumps the children one level deeper
+func (d *semDumper) nested(children func()) {
+	d.indent++
+	children()
+	d.indent--
+}
+
+func (d *semDumper) declaration(decl SemDeclaration) {
+	switch n := decl.(type) {
+	case *SemFunctionDecl:
+		params := make([]string, len(n.Parameters))
+		for i, param := range n.Parameters {
+			params[i] = param.Name + ":" + typeName(param.Type)
+		}
+		attributes := ""
+		for _, attr := range n.Attributes {
+			attributes += " @" + attr
+		}
+		d.line("SemFunctionDecl %s(%s):%s%s", n.Name, strings.Join(params, ", "), typeName(n.ReturnType), attributes)
+		d.nested(func() { d.block(n.Body) })
+	case *SemVariableDecl:
+		d.line("SemVariableDecl %s:%s", n.Symbol.Name, typeName(n.TypeInfo))
+		if n.Initializer != nil {
+			d.nested(func() { d.expression(n.Initializer) })
+		}
+	case *SemTypeDecl:
+		d.line("SemTypeDecl %s", n.TypeInfo.Name())
+		d.nested(func() {
+			for _, field := range n.TypeInfo.Fields() {
+				d.line("%s:%s @%d", field.Name, typeName(field.Type), field.Offset)
+			}
+		})
+	default:
+		d.line("%T", decl)
+	}
+}
+
+func (d *semDumper) block(block *SemBlock) {
+	if block == nil {
+		return
+	}
+	d.line("SemBlock")
+	d.nested(func() {
+		for _, stmt := range block.Statements {
+			d.statement(stmt)
+		}
+	})
+}
+
+func (d *semDumper) statement(stmt SemStatement) {
+	switch n := stmt.(type) {
+	case *SemVariableDecl:
+		d.declaration(n)
+	case *SemAssignment:
+		d.line("SemAssignment %s:%s", n.Target.Name, typeName(n.Target.Type))
+		d.nested(func() { d.expression(n.Value) })
+	case *SemMultiAssignment:
+		targets := make([]string, len(n.Targets))
+		for i, target := range n.Targets {
+			targets[i] = target.Name + ":" + typeName(target.Type)
+		}
+		d.line("SemMultiAssignment %s", strings.Join(targets, ", "))
+		d.nested(func() {
+			for _, value := range n.Values {
+				d.expression(value)
+			}
+		})
+	case *SemIf:
+		d.line("SemIf")
+		d.nested(func() {
+			d.expression(n.Condition)
+			d.block(n.ThenBlock)
+			for _, elsif := range n.ElsifBlocks {
+				d.line("SemElsif")
+				d.nested(func() {
+					d.expression(elsif.Condition)
+					d.block(elsif.ThenBlock)
+				})
+			}
+			if n.ElseBlock != nil {
+				d.line("else")
+				d.nested(func() { d.block(n.ElseBlock) })
+			}
+		})
+	case *SemFor:
+		d.line("SemFor")
+		d.nested(func() {
+			if n.Initializer != nil {
+				d.statement(n.Initializer)
+			}
+			if n.Condition != nil {
+				d.expression(n.Condition)
+			}
+			if n.Increment != nil {
+				d.statement(n.Increment)
+			}
+			d.block(n.Body)
+		})
+	case *SemForRange:
+		d.line("SemForRange %s:%s", n.Variable.Name, typeName(n.Variable.Type))
+		d.nested(func() {
+			d.expression(n.From)
+			d.expression(n.To)
+			d.block(n.Body)
+		})
+	case *SemDoWhile:
+		d.line("SemDoWhile")
+		d.nested(func() {
+			d.block(n.Body)
+			d.expression(n.Condition)
+		})
+	case *SemSelect:
+		d.line("SemSelect")
+		d.nested(func() {
+			d.expression(n.Expression)
+			for _, c := range n.Cases {
+				d.line("SemSelectCase")
+				d.nested(func() {
+					d.expression(c.Value)
+					d.block(c.Body)
+				})
+			}
+			if n.Else != nil {
+				d.line("else")
+				d.nested(func() { d.block(n.Else) })
+			}
+		})
+	case *SemExpressionStmt:
+		d.line("SemExpressionStmt")
+		d.nested(func() { d.expression(n.Expression) })
+	case *SemReturn:
+		d.line("SemReturn")
+		if n.Value != nil {
+			d.nested(func() { d.expression(n.Value) })
+		}
+	case *SemInlineAsm:
+		d.line("SemInlineAsm %q", n.Text)
+	case *SemBlock:
+		d.block(n)
+	default:
+		d.line("%T", stmt)
+	}
+}
+
+func (d *semDumper) expression(expr SemExpression) {
+	switch n := expr.(type) {
+	case *SemConstant:
+		d.line("SemConstant(%s):%s", constantText(n.Value), typeName(n.Type()))
+	case *SemSymbolRef:
+		d.line("SemSymbolRef(%s):%s", n.Symbol.Name, typeName(n.Type()))
+	case *SemBinaryOp:
+		d.line("SemBinaryOp(%s):%s", n.Op, typeName(n.Type()))
+		d.nested(func() {
+			d.expression(n.Left)
+			d.expression(n.Right)
+		})
+	case *SemUnaryOp:
+		d.line("SemUnaryOp(%s):%s", n.Op, typeName(n.Type()))
+		d.nested(func() { d.expression(n.Operand) })
+	case *SemFunctionCall:
+		d.line("SemFunctionCall(%s):%s", n.Function.Name, typeName(n.Type()))
+		d.nested(func() {
+			for _, arg := range n.Arguments {
+				d.expression(arg)
+			}
+		})
+	case *SemMemberAccess:
+		d.line("SemMemberAccess(%s):%s", n.Field.Name, typeName(n.Type()))
+		d.nested(func() { d.expression(*n.Object) })
+	case *SemSubscript:
+		d.line("SemSubscript:%s", typeName(n.Type()))
+		d.nested(func() {
+			d.expression(n.Array)
+			d.expression(n.Index)
+		})
+	case *SemTypeInitializer:
+		d.line("SemTypeInitializer:%s", typeName(n.Type()))
+		d.nested(func() {
+			for _, field := range n.Fields {
+				d.line("%s:%s", field.Field.Name, typeName(field.Field.Type))
+				d.nested(func() { d.expression(field.Value) })
+			}
+		})
+	case *SemArrayInitializer:
+		d.line("SemArrayInitializer:%s", typeName(n.Type()))
+		d.nested(func() {
+			for _, element := range n.Elements {
+				d.expression(element)
+			}
+		})
+	default:
+		d.line("%T", expr)
+	}
+}
+
+// constantText formats the value of a constant, embedded data by its size
+func constantText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []byte:
+		return fmt.Sprintf("%d bytes", len(v))
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
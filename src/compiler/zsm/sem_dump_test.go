@@ -0,0 +1,25 @@
+package zsm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Dump_FunctionBinaryOp(t *testing.T) {
+	code := `add: (a: u8, b: u8) u8 {
+		ret a + b
+	}`
+	semCU, errors := analyzeCode(t, "Test_Dump_FunctionBinaryOp", code)
+	requireNoErrors(t, errors)
+
+	expected := `SemCompilationUnit (1 declarations)
+  SemFunctionDecl add(a:u8, b:u8):u8
+    SemBlock
+      SemReturn
+        SemBinaryOp(OpAdd):u8
+          SemSymbolRef(a):u8
+          SemSymbolRef(b):u8
+`
+	assert.Equal(t, expected, Dump(semCU))
+}
@@ -2,6 +2,7 @@ package zsm
 
 import (
 	"fmt"
+	"zenith/compiler"
 	"zenith/compiler/parser"
 )
 
@@ -33,13 +34,53 @@ type SemExpression interface {
 type SemCompilationUnit struct {
 	Declarations []SemDeclaration
 	GlobalScope  *SymbolTable
-	CallGraph    *CallGraph // Function call relationships
+	CallGraph    *CallGraph         // Function call relationships
+	References   []*SymbolReference // Identifiers bound to symbols, in analysis order
+	Warnings     []*compiler.Diagnostic
 	astNode      parser.CompilationUnit
 }
 
 func (n *SemCompilationUnit) ASTNode() parser.ParserNode  { return n.astNode }
 func (n *SemCompilationUnit) AST() parser.CompilationUnit { return n.astNode }
 
+// Resolve returns the symbol referenced at the source position:
+// inside a variable or parameter use, a function call name, an assignment target or a type name.
+// The declaration of the symbol is at its Span().
+// Returns false when no symbol is referenced at the position.
+func (n *SemCompilationUnit) Resolve(pos compiler.Location) (*Symbol, bool) {
+	for _, ref := range n.References {
+		if ref.Contains(pos) {
+			return ref.Symbol, true
+		}
+	}
+	return nil, false
+}
+
+// ResolveInModule is Resolve for a position in the source file of the module (multi-file programs)
+func (n *SemCompilationUnit) ResolveInModule(module string, pos compiler.Location) (*Symbol, bool) {
+	for _, ref := range n.References {
+		if ref.Module == module && ref.Contains(pos) {
+			return ref.Symbol, true
+		}
+	}
+	return nil, false
+}
+
+// AllSymbols returns the symbols declared in the source (for tooling):
+// the top-level declarations followed by the parameters and locals of each function.
+func (n *SemCompilationUnit) AllSymbols() []*Symbol {
+	var symbols []*Symbol
+	if n.GlobalScope != nil {
+		symbols = n.GlobalScope.Declared()
+	}
+	for _, decl := range n.Declarations {
+		if funcDecl, ok := decl.(*SemFunctionDecl); ok && funcDecl.Scope != nil {
+			symbols = append(symbols, funcDecl.Scope.DeclaredInBlocks()...)
+		}
+	}
+	return symbols
+}
+
 // ============================================================================
 // Declarations
 // ============================================================================
@@ -58,6 +99,8 @@ func (n *SemVariableDecl) AST() parser.VariableDeclaration { return n.astNode }
 // SemFunctionDecl represents a function declaration
 type SemFunctionDecl struct {
 	Name       string
+	Attributes []string // '@fast' => "fast"
+	Org        *uint16  // fixed address ('@org(address)'), nil when placed by the linker
 	Parameters []*Symbol
 	ReturnType Type // nil for void
 	Body       *SemBlock
@@ -68,6 +111,38 @@ type SemFunctionDecl struct {
 func (n *SemFunctionDecl) ASTNode() parser.ParserNode      { return n.astNode }
 func (n *SemFunctionDecl) AST() parser.FunctionDeclaration { return n.astNode }
 
+// Function attributes ('@fast' => AttributeFast)
+const (
+	AttributeFast      = "fast"      // runs on the alternate register set
+	AttributeInterrupt = "interrupt" // maskable interrupt handler (EI; RETI)
+	AttributeNMI       = "nmi"       // non-maskable interrupt handler (RETN)
+	AttributeOrg       = "org"       // placed at a fixed address: '@org(0x0038)'
+	AttributeNoReturn  = "noreturn"  // never returns to the caller (reset, panic handlers)
+)
+
+// Terminates checks if execution never continues after the statement:
+// a return or a call to a '@noreturn' function.
+func Terminates(stmt SemStatement) bool {
+	switch s := stmt.(type) {
+	case *SemReturn:
+		return true
+	case *SemExpressionStmt:
+		call, ok := s.Expression.(*SemFunctionCall)
+		return ok && call.Function != nil && call.Function.NoReturn
+	}
+	return false
+}
+
+// HasAttribute checks if the function is marked with the attribute ('fast' for '@fast')
+func (n *SemFunctionDecl) HasAttribute(name string) bool {
+	for _, attr := range n.Attributes {
+		if attr == name {
+			return true
+		}
+	}
+	return false
+}
+
 // SemTypeDecl represents a struct type declaration
 type SemTypeDecl struct {
 	TypeInfo *StructType
@@ -100,6 +175,17 @@ type SemAssignment struct {
 func (n *SemAssignment) ASTNode() parser.ParserNode     { return n.astNode }
 func (n *SemAssignment) AST() parser.VariableAssignment { return n.astNode }
 
+// SemMultiAssignment represents the assignment of multiple variables: a, b = 1, 2
+// All values are evaluated before any target is assigned (a, b = b, a swaps the values).
+type SemMultiAssignment struct {
+	Targets []*Symbol
+	Values  []SemExpression // the value of the target at the same index
+	astNode parser.MultiAssignment
+}
+
+func (n *SemMultiAssignment) ASTNode() parser.ParserNode  { return n.astNode }
+func (n *SemMultiAssignment) AST() parser.MultiAssignment { return n.astNode }
+
 // SemIf represents an if statement
 type SemIf struct {
 	Condition   SemExpression
@@ -126,7 +212,7 @@ func (n *SemElsif) AST() parser.StatementElsif { return n.astNode }
 type SemFor struct {
 	Initializer SemStatement  // nil if not present
 	Condition   SemExpression // nil if not present
-	Increment   SemExpression // nil if not present
+	Increment   SemStatement  // nil if not present (expression statement or assignment)
 	Body        *SemBlock
 	astNode     parser.StatementFor
 }
@@ -134,6 +220,31 @@ type SemFor struct {
 func (n *SemFor) ASTNode() parser.ParserNode { return n.astNode }
 func (n *SemFor) AST() parser.StatementFor   { return n.astNode }
 
+// SemForRange represents a for loop over a range of values: for i in From..To
+// The variable runs from From up to (not including) To.
+type SemForRange struct {
+	Variable *Symbol
+	From     SemExpression
+	To       SemExpression
+	Body     *SemBlock
+	// Loop is the equivalent counted loop: for i := From; i < To; i++
+	Loop    *SemFor
+	astNode parser.StatementForRange
+}
+
+func (n *SemForRange) ASTNode() parser.ParserNode    { return n.astNode }
+func (n *SemForRange) AST() parser.StatementForRange { return n.astNode }
+
+// SemDoWhile represents a do-while loop (condition tested after the body)
+type SemDoWhile struct {
+	Body      *SemBlock
+	Condition SemExpression
+	astNode   parser.StatementDoWhile
+}
+
+func (n *SemDoWhile) ASTNode() parser.ParserNode   { return n.astNode }
+func (n *SemDoWhile) AST() parser.StatementDoWhile { return n.astNode }
+
 // SemSelect represents a select statement (switch)
 type SemSelect struct {
 	Expression SemExpression
@@ -173,13 +284,23 @@ type SemReturn struct {
 func (n *SemReturn) ASTNode() parser.ParserNode  { return n.astNode }
 func (n *SemReturn) AST() parser.StatementReturn { return n.astNode }
 
+// SemInlineAsm represents an asm block, its text is emitted verbatim
+// Register clobbers are not tracked: preserving registers is up to the user.
+type SemInlineAsm struct {
+	Text    string
+	astNode parser.InlineAsm
+}
+
+func (n *SemInlineAsm) ASTNode() parser.ParserNode { return n.astNode }
+func (n *SemInlineAsm) AST() parser.InlineAsm      { return n.astNode }
+
 // ============================================================================
 // Expressions
 // ============================================================================
 
 // SemConstant represents a constant literal value
 type SemConstant struct {
-	Value    interface{} // int, string, bool
+	Value    interface{} // int, string (decoded characters), bool, []byte (embedded bytes), Decimal (fixed-point literal)
 	TypeInfo Type
 	astNode  parser.Expression
 }
@@ -188,6 +309,30 @@ func (n *SemConstant) ASTNode() parser.ParserNode { return n.astNode }
 func (n *SemConstant) AST() parser.Expression     { return n.astNode }
 func (n *SemConstant) Type() Type                 { return n.TypeInfo }
 
+// AsInt returns the value of an integer constant, false for other constants
+func (n *SemConstant) AsInt() (int, bool) {
+	value, ok := n.Value.(int)
+	return value, ok
+}
+
+// AsBool returns the value of a bool constant, false for other constants
+func (n *SemConstant) AsBool() (bool, bool) {
+	value, ok := n.Value.(bool)
+	return value, ok
+}
+
+// AsBytes returns the characters of a string constant (without terminator or length byte)
+// or the embedded bytes ('@include_bin', '@db'), false for other constants
+func (n *SemConstant) AsBytes() ([]byte, bool) {
+	switch value := n.Value.(type) {
+	case string:
+		return []byte(value), true
+	case []byte:
+		return value, true
+	}
+	return nil, false
+}
+
 // SemSymbolRef represents a reference to a symbol (variable, parameter)
 type SemSymbolRef struct {
 	Symbol  *Symbol
@@ -219,10 +364,14 @@ const (
 	OpSubtract
 	OpMultiply
 	OpDivide
+	OpModulo
 	// Bitwise
 	OpBitwiseAnd
 	OpBitwiseOr
 	OpBitwiseXor
+	// Shift
+	OpShl
+	OpShr
 	// Comparison
 	OpEqual
 	OpNotEqual
@@ -235,6 +384,22 @@ const (
 	OpLogicalOr
 )
 
+var binaryOperatorNames = [...]string{
+	OpAdd: "OpAdd", OpSubtract: "OpSubtract", OpMultiply: "OpMultiply", OpDivide: "OpDivide", OpModulo: "OpModulo",
+	OpBitwiseAnd: "OpBitwiseAnd", OpBitwiseOr: "OpBitwiseOr", OpBitwiseXor: "OpBitwiseXor",
+	OpShl: "OpShl", OpShr: "OpShr",
+	OpEqual: "OpEqual", OpNotEqual: "OpNotEqual", OpLessThan: "OpLessThan", OpLessEqual: "OpLessEqual",
+	OpGreaterThan: "OpGreaterThan", OpGreaterEqual: "OpGreaterEqual",
+	OpLogicalAnd: "OpLogicalAnd", OpLogicalOr: "OpLogicalOr",
+}
+
+func (op BinaryOperator) String() string {
+	if op >= 0 && int(op) < len(binaryOperatorNames) {
+		return binaryOperatorNames[op]
+	}
+	return fmt.Sprintf("BinaryOperator(%d)", int(op))
+}
+
 // SemUnaryOp represents unary operations
 type SemUnaryOp struct {
 	Op       UnaryOperator
@@ -257,8 +422,21 @@ const (
 	// postfix
 	OpIncrement
 	OpDecrement
+	OpToBool // x? - true when not zero (nil)
 )
 
+var unaryOperatorNames = [...]string{
+	OpNegate: "OpNegate", OpLogicalNot: "OpLogicalNot", OpBitwiseNot: "OpBitwiseNot",
+	OpIncrement: "OpIncrement", OpDecrement: "OpDecrement", OpToBool: "OpToBool",
+}
+
+func (op UnaryOperator) String() string {
+	if op >= 0 && int(op) < len(unaryOperatorNames) {
+		return unaryOperatorNames[op]
+	}
+	return fmt.Sprintf("UnaryOperator(%d)", int(op))
+}
+
 // SemFunctionCall represents a function call
 type SemFunctionCall struct {
 	Function  *Symbol
@@ -283,6 +461,25 @@ func (n *SemMemberAccess) ASTNode() parser.ParserNode         { return n.astNode
 func (n *SemMemberAccess) AST() parser.ExpressionMemberAccess { return n.astNode }
 func (n *SemMemberAccess) Type() Type                         { return n.TypeInfo }
 
+// BaseOffset returns the object the (nested) member access starts from and the byte offset of the field in it.
+// 'line.start.y' => line, offset(start) + offset(y)
+func (n *SemMemberAccess) BaseOffset() (SemExpression, uint16) {
+	offset := n.Field.Offset
+	object := *n.Object
+	for {
+		inner, ok := object.(*SemMemberAccess)
+		if !ok {
+			return object, offset
+		}
+		// a struct pointer field holds the address of another object
+		if _, byValue := inner.Type().(*StructType); !byValue {
+			return object, offset
+		}
+		offset += inner.Field.Offset
+		object = *inner.Object
+	}
+}
+
 // SemSubscript represents array subscripting (indexing)
 type SemSubscript struct {
 	Array    SemExpression
@@ -326,19 +523,5 @@ type SemFieldInit struct {
 
 func DumpSemanticModel(semCU *SemCompilationUnit) {
 	fmt.Println("========== Semantic Model ===========")
-	fmt.Printf("Semantic Compilation Unit with %d declarations\n", len(semCU.Declarations))
-	for _, decl := range semCU.Declarations {
-		switch d := decl.(type) {
-		case *SemFunctionDecl:
-			fmt.Printf("  Function: %s (params=%d)\n",
-				d.Name, len(d.Parameters))
-		case *SemVariableDecl:
-			fmt.Printf("  Variable: %s\n", d.Symbol.Name)
-		case *SemTypeDecl:
-			fmt.Printf("  Type: %s\n", d.TypeInfo.Name())
-		default:
-			fmt.Printf("  Unknown: %T\n", decl)
-		}
-	}
-	fmt.Println()
+	fmt.Println(Dump(semCU))
 }
@@ -0,0 +1,104 @@
+package zsm
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// StringFormat defines how string literals are stored in memory
+type StringFormat int
+
+const (
+	// StringNullTerminated stores the characters followed by a 0 byte (C-style)
+	StringNullTerminated StringFormat = iota
+	// StringLengthPrefixed stores a length byte followed by the characters (Pascal-style)
+	StringLengthPrefixed
+)
+
+// maxLengthPrefixed is the longest string a length byte can describe
+const maxLengthPrefixed = 255
+
+// StorageLength returns the number of bytes a string of length characters occupies
+func (f StringFormat) StorageLength(length int) int {
+	// both formats add a single byte: the terminator or the length
+	return length + 1
+}
+
+// Encode returns the stored bytes for the characters of a string
+func (f StringFormat) Encode(chars []byte) []byte {
+	data := make([]byte, 0, f.StorageLength(len(chars)))
+	switch f {
+	case StringLengthPrefixed:
+		data = append(data, byte(len(chars)))
+		data = append(data, chars...)
+	default:
+		data = append(data, chars...)
+		data = append(data, 0)
+	}
+	return data
+}
+
+func (f StringFormat) String() string {
+	switch f {
+	case StringLengthPrefixed:
+		return "length-prefixed"
+	default:
+		return "null-terminated"
+	}
+}
+
+// StringLiteralChars returns the characters of a string literal (without the quotes, escapes decoded).
+// An invalid escape sequence is kept as it is.
+func StringLiteralChars(literal string) []byte {
+	chars, _ := DecodeStringLiteral(literal)
+	return chars
+}
+
+// DecodeStringLiteral returns the characters of a string literal without the quotes,
+// with the escape sequences replaced: \n \r \t \0 \\ \" \' and \xHH (a hex byte).
+// Returns an error for an unknown or incomplete escape sequence, the characters keep it as it is.
+func DecodeStringLiteral(literal string) ([]byte, error) {
+	if len(literal) >= 2 && literal[0] == '"' && literal[len(literal)-1] == '"' {
+		literal = literal[1 : len(literal)-1]
+	}
+
+	var err error
+	chars := make([]byte, 0, len(literal))
+	for i := 0; i < len(literal); i++ {
+		if literal[i] != '\\' {
+			chars = append(chars, literal[i])
+			continue
+		}
+		if i+1 >= len(literal) {
+			err = fmt.Errorf("incomplete escape sequence at the end of the string")
+			chars = append(chars, literal[i])
+			continue
+		}
+		switch escaped := literal[i+1]; escaped {
+		case 'n':
+			chars = append(chars, '\n')
+		case 'r':
+			chars = append(chars, '\r')
+		case 't':
+			chars = append(chars, '\t')
+		case '0':
+			chars = append(chars, 0)
+		case '\\', '"', '\'':
+			chars = append(chars, escaped)
+		case 'x':
+			value, parseErr := strconv.ParseUint(literal[i+2:min(i+4, len(literal))], 16, 8)
+			if i+4 > len(literal) || parseErr != nil {
+				err = fmt.Errorf("escape sequence '\\x' requires 2 hex digits")
+				chars = append(chars, literal[i:i+2]...)
+				break
+			}
+			chars = append(chars, byte(value))
+			i += 2
+		default:
+			err = fmt.Errorf("unknown escape sequence '\\%c'", escaped)
+			chars = append(chars, literal[i:i+2]...)
+		}
+		i++
+	}
+	return chars, err
+}
@@ -0,0 +1,50 @@
+package zsm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StringFormat_Encode(t *testing.T) {
+	assert.Equal(t, []byte{'h', 'i', 0}, StringNullTerminated.Encode([]byte("hi")))
+	assert.Equal(t, []byte{2, 'h', 'i'}, StringLengthPrefixed.Encode([]byte("hi")))
+	assert.Equal(t, []byte{0}, StringNullTerminated.Encode([]byte{}))
+	assert.Equal(t, []byte{0}, StringLengthPrefixed.Encode([]byte{}))
+}
+
+func Test_StringFormat_StorageLength(t *testing.T) {
+	assert.Equal(t, 3, StringNullTerminated.StorageLength(2))
+	assert.Equal(t, 3, StringLengthPrefixed.StorageLength(2))
+}
+
+func Test_StringLiteralChars(t *testing.T) {
+	assert.Equal(t, []byte("hello"), StringLiteralChars(`"hello"`))
+	assert.Equal(t, []byte{}, StringLiteralChars(`""`))
+	assert.Equal(t, []byte("raw"), StringLiteralChars("raw"))
+	assert.Equal(t, []byte("a\tb\\"), StringLiteralChars(`"a\tb\\"`))
+}
+
+func Test_DecodeStringLiteral_Errors(t *testing.T) {
+	tests := []struct {
+		literal  string
+		chars    []byte
+		expected string
+	}{
+		{`"a\q"`, []byte(`a\q`), "unknown escape sequence '\\q'"},
+		{`"\x4"`, []byte(`\x4`), "escape sequence '\\x' requires 2 hex digits"},
+		{`"\xZZ"`, []byte(`\xZZ`), "escape sequence '\\x' requires 2 hex digits"},
+		{`"a\"`, []byte(`a\`), "incomplete escape sequence"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.literal, func(t *testing.T) {
+			chars, err := DecodeStringLiteral(tt.literal)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+			assert.Equal(t, tt.chars, chars)
+		})
+	}
+}
@@ -36,8 +36,11 @@ func (sl *SymbolLookup) collectSymbols(scope *SymbolTable) {
 		sl.symbols[symbol.QualifiedName] = symbol
 	}
 
-	// Note: We don't recursively walk children because function scopes
-	// are collected separately in NewSymbolLookup
+	// Nested code blocks have qualified names of their own ("main.block1.i").
+	// Function scopes are not blocks of the global scope, they are collected separately in NewSymbolLookup
+	for _, block := range scope.Blocks() {
+		sl.collectSymbols(block)
+	}
 }
 
 // GetTypeSize returns the size of a symbol's type in bits (8 or 16)
@@ -1,5 +1,13 @@
 package zsm
 
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"zenith/compiler"
+	"zenith/compiler/parser"
+)
+
 // SymbolKind represents the kind of symbol
 type SymbolKind int
 
@@ -45,14 +53,42 @@ type Symbol struct {
 	Name          string
 	QualifiedName string // Fully qualified name (e.g., "main.x", "<global>.count")
 	Kind          SymbolKind
-	Type          Type          // For variables/functions: their type. For type symbols: the type itself
-	Usage         VariableUsage // How the variable is used (for register allocation hints)
+	Type          Type              // For variables/functions: their type. For type symbols: the type itself
+	Usage         VariableUsage     // How the variable is used (for register allocation hints)
+	Module        string            // Module (source file) of a top-level declaration, empty for builtins and locals
+	Exported      bool              // Top-level declaration can be used by other modules
+	Parameter     bool              // Variable is a function parameter
+	Declaration   parser.ParserNode // Declaring node (nil for builtins)
+	Constant      SemExpression     // Value of a named constant (nil for variables)
+	NoReturn      bool              // Function never returns to the caller ('@noreturn')
+}
+
+// Span returns the source positions of the symbol's declaration (zero for builtins)
+func (s *Symbol) Span() (start, end compiler.Location) {
+	if s.Declaration == nil {
+		return compiler.Location{}, compiler.Location{}
+	}
+	return s.Declaration.Span()
+}
+
+// SymbolReference is a use of a symbol in the source: the identifier that is bound to the symbol
+type SymbolReference struct {
+	Symbol *Symbol
+	Module string // module (source file) of the use
+	Start  compiler.Location
+	End    compiler.Location // just past the identifier
+}
+
+// Contains checks if the source position is inside the identifier
+func (r *SymbolReference) Contains(pos compiler.Location) bool {
+	return r.Start.Index <= pos.Index && pos.Index < r.End.Index
 }
 
 // SymbolTable maintains symbols in a particular scope
 type SymbolTable struct {
 	symbols   map[string]*Symbol
 	parent    *SymbolTable
+	blocks    []*SymbolTable // scopes of the nested code blocks
 	ScopeName string
 }
 
@@ -65,6 +101,18 @@ func NewSymbolTable(parent *SymbolTable, scopeName string) *SymbolTable {
 	}
 }
 
+// NewBlockScope creates the scope of a nested code block: "main.block1", "main.block2", ...
+func (st *SymbolTable) NewBlockScope() *SymbolTable {
+	block := NewSymbolTable(st, fmt.Sprintf("%s.block%d", st.ScopeName, len(st.blocks)+1))
+	st.blocks = append(st.blocks, block)
+	return block
+}
+
+// Blocks returns the scopes of the nested code blocks
+func (st *SymbolTable) Blocks() []*SymbolTable {
+	return st.blocks
+}
+
 // Add adds a symbol to this scope
 func (st *SymbolTable) Add(symbol *Symbol) bool {
 	if _, exists := st.symbols[symbol.Name]; exists {
@@ -105,6 +153,31 @@ func (st *SymbolTable) Symbols() map[string]*Symbol {
 	return st.symbols
 }
 
+// Declared returns the symbols declared in the source (builtins excluded) in declaration order
+func (st *SymbolTable) Declared() []*Symbol {
+	declared := make([]*Symbol, 0, len(st.symbols))
+	for _, symbol := range st.symbols {
+		if symbol.Declaration != nil {
+			declared = append(declared, symbol)
+		}
+	}
+	slices.SortFunc(declared, func(a, b *Symbol) int {
+		aStart, _ := a.Span()
+		bStart, _ := b.Span()
+		return cmp.Or(cmp.Compare(a.Module, b.Module), cmp.Compare(aStart.Index, bStart.Index))
+	})
+	return declared
+}
+
+// DeclaredInBlocks returns the symbols declared in this scope followed by those of its nested blocks
+func (st *SymbolTable) DeclaredInBlocks() []*Symbol {
+	declared := st.Declared()
+	for _, block := range st.blocks {
+		declared = append(declared, block.DeclaredInBlocks()...)
+	}
+	return declared
+}
+
 // GetQualifiedName returns the fully qualified name for a variable in this scope
 // e.g., "main.x", "helper.y", "main.block1.i"
 func (st *SymbolTable) GetQualifiedName(variableName string) string {
@@ -86,8 +86,9 @@ func (t *PointerType) PointeeType() Type { return t.pointeeType }
 
 // FunctionType represents function signatures (for function pointers)
 type FunctionType struct {
-	parameters []Type
-	returnType Type // nil for void
+	parameters     []Type
+	parameterNames []string // empty for intrinsics
+	returnType     Type     // nil for void
 }
 
 func (t *FunctionType) Name() string {
@@ -99,8 +100,9 @@ func (t *FunctionType) Size() uint16 {
 	return 2 // Function pointer size
 }
 
-func (t *FunctionType) Parameters() []Type { return t.parameters }
-func (t *FunctionType) ReturnType() Type   { return t.returnType }
+func (t *FunctionType) Parameters() []Type       { return t.parameters }
+func (t *FunctionType) ParameterNames() []string { return t.parameterNames }
+func (t *FunctionType) ReturnType() Type         { return t.returnType }
 
 // Built-in primitive types
 var (
@@ -116,7 +118,7 @@ var (
 	D8Type  = &PrimitiveType{"d8", 1}
 	D16Type = &PrimitiveType{"d16", 2}
 
-	// Bit type
+	// Bit type: a bool stored in one byte, 0 is false and 1 is true (no other values are valid)
 	BitType = &PrimitiveType{"bit", 1}
 
 	// Len(Array<T>)
@@ -124,8 +126,115 @@ var (
 		parameters: []Type{&ArrayType{elementType: nil, length: 0}},
 		returnType: U16Type,
 	}
+
+	// Bit(index, u8) bit
+	BitFnType = &FunctionType{
+		parameters: []Type{U8Type, U8Type},
+		returnType: BitType,
+	}
+	// SetBit(index, u8) - modifies the variable
+	SetBitFnType = &FunctionType{
+		parameters: []Type{U8Type, U8Type},
+		returnType: nil,
+	}
+	// ResetBit(index, u8) - modifies the variable
+	ResetBitFnType = &FunctionType{
+		parameters: []Type{U8Type, U8Type},
+		returnType: nil,
+	}
+
+	// Peek(address) u8
+	PeekFnType = &FunctionType{
+		parameters: []Type{U16Type},
+		returnType: U8Type,
+	}
+	// Poke(address, u8)
+	PokeFnType = &FunctionType{
+		parameters: []Type{U16Type, U8Type},
+		returnType: nil,
+	}
+	// MemChr(address, u8, length) u16 - address of the first match or 0
+	MemChrFnType = &FunctionType{
+		parameters: []Type{U16Type, U8Type, U16Type},
+		returnType: U16Type,
+	}
+	// MemSet(address, u8, length)
+	MemSetFnType = &FunctionType{
+		parameters: []Type{U16Type, U8Type, U16Type},
+		returnType: nil,
+	}
+
+	// Halt() and Nop()
+	HaltFnType = &FunctionType{
+		parameters: []Type{},
+		returnType: nil,
+	}
+	NopFnType = &FunctionType{
+		parameters: []Type{},
+		returnType: nil,
+	}
+
+	// Rst(vector)
+	RstFnType = &FunctionType{
+		parameters: []Type{U8Type},
+		returnType: nil,
+	}
+
+	// Overflow(a + b) bit - signed (two's complement) overflow of the addition/subtraction
+	OverflowFnType = &FunctionType{
+		parameters: []Type{U16Type},
+		returnType: BitType,
+	}
+
+	// Assert(condition) - checked at compile time, produces no code
+	AssertFnType = &FunctionType{
+		parameters: []Type{BitType},
+		returnType: nil,
+	}
+	// Sizeof(type or expression) u16 - size in bytes, a compile-time constant
+	SizeofFnType = &FunctionType{
+		parameters: []Type{U16Type},
+		returnType: U16Type,
+	}
+	// AddressOf(function) u16 - the address of the function, filled in after the code is laid out
+	AddressOfFnType = &FunctionType{
+		parameters: []Type{U16Type},
+		returnType: U16Type,
+	}
+	// DataBytes(byte, ...) u8[] - the bytes, evaluated at compile time
+	DataBytesFnType = &FunctionType{
+		parameters: []Type{U8Type},
+		returnType: &ArrayType{elementType: U8Type, length: 0},
+	}
+	// IncludeBin(path) u8[] - the bytes of the file, read at compile time
+	IncludeBinFnType = &FunctionType{
+		parameters: []Type{&ArrayType{elementType: U8Type, length: 0}},
+		returnType: &ArrayType{elementType: U8Type, length: 0},
+	}
 )
 
+// BoolValue returns the stored value of a bool: 1 for true, 0 for false
+func BoolValue(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// IsSigned returns true for the signed integer types (i8, i16)
+func IsSigned(t Type) bool {
+	return t == I8Type || t == I16Type
+}
+
+// WidenedType returns the result type of an arithmetic operation on two operand types:
+// the larger integer type (u8 + u16 is u16), the left type when both have the same size.
+func WidenedType(left, right Type) Type {
+	if isIntegerType(left) && isIntegerType(right) && right.Size() > left.Size() {
+		return right
+	}
+	return left
+}
+
 // NewArrayType creates a new array type
 func NewArrayType(elementType Type, length uint16) *ArrayType {
 	return &ArrayType{
@@ -143,16 +252,20 @@ func NewPointerType(pointeeType Type) *PointerType {
 
 // NewStructType creates a new struct type with computed field offsets
 func NewStructType(name string, fields []*StructField) *StructType {
+	structType := &StructType{name: name}
+	structType.setFields(fields)
+	return structType
+}
+
+// setFields assigns the fields and computes their offsets and the struct size
+func (t *StructType) setFields(fields []*StructField) {
 	offset := uint16(0)
 	for _, field := range fields {
 		field.Offset = offset
 		offset += field.Type.Size()
 	}
-	return &StructType{
-		name:   name,
-		fields: fields,
-		size:   offset,
-	}
+	t.fields = fields
+	t.size = offset
 }
 
 // NewFunctionType creates a new function type (for function pointers)